@@ -141,6 +141,81 @@ info "  → 003_add_parent_id_to_tasks.sql"
 $PSQL_CMD -d $DB_NAME -f "$SCRIPT_DIR/schema/003_add_parent_id_to_tasks.sql" > /dev/null
 info "  ✓ Parent ID field added to tasks"
 
+# 004: Recurring task series
+info "  → 004_add_task_series.sql"
+$PSQL_CMD -d $DB_NAME -f "$SCRIPT_DIR/schema/004_add_task_series.sql" > /dev/null
+info "  ✓ Task series added"
+
+# 005: Task templates
+info "  → 005_add_task_templates.sql"
+$PSQL_CMD -d $DB_NAME -f "$SCRIPT_DIR/schema/005_add_task_templates.sql" > /dev/null
+info "  ✓ Task templates added"
+
+# 006: Bulk audit action
+info "  → 006_add_bulk_audit_action.sql"
+$PSQL_CMD -d $DB_NAME -f "$SCRIPT_DIR/schema/006_add_bulk_audit_action.sql" > /dev/null
+info "  ✓ Bulk audit action added"
+
+# 007: Task references
+info "  → 007_add_task_references.sql"
+$PSQL_CMD -d $DB_NAME -f "$SCRIPT_DIR/schema/007_add_task_references.sql" > /dev/null
+info "  ✓ Task references added"
+
+# 008: Notifications
+info "  → 008_add_notifications.sql"
+$PSQL_CMD -d $DB_NAME -f "$SCRIPT_DIR/schema/008_add_notifications.sql" > /dev/null
+info "  ✓ Notifications added"
+
+# 009: Subscriptions
+info "  → 009_add_subscriptions.sql"
+$PSQL_CMD -d $DB_NAME -f "$SCRIPT_DIR/schema/009_add_subscriptions.sql" > /dev/null
+info "  ✓ Subscriptions added"
+
+# 010: Webhooks
+info "  → 010_add_webhooks.sql"
+$PSQL_CMD -d $DB_NAME -f "$SCRIPT_DIR/schema/010_add_webhooks.sql" > /dev/null
+info "  ✓ Webhooks added"
+
+# 011: Ingest tokens
+info "  → 011_add_ingest_tokens.sql"
+$PSQL_CMD -d $DB_NAME -f "$SCRIPT_DIR/schema/011_add_ingest_tokens.sql" > /dev/null
+info "  ✓ Ingest tokens added"
+
+# 012: Idempotency keys
+info "  → 012_add_idempotency_keys.sql"
+$PSQL_CMD -d $DB_NAME -f "$SCRIPT_DIR/schema/012_add_idempotency_keys.sql" > /dev/null
+info "  ✓ Idempotency keys added"
+
+# 013: Sessions
+info "  → 013_add_sessions.sql"
+$PSQL_CMD -d $DB_NAME -f "$SCRIPT_DIR/schema/013_add_sessions.sql" > /dev/null
+info "  ✓ Sessions added"
+
+# 014: Access tokens
+info "  → 014_add_access_tokens.sql"
+$PSQL_CMD -d $DB_NAME -f "$SCRIPT_DIR/schema/014_add_access_tokens.sql" > /dev/null
+info "  ✓ Access tokens added"
+
+# 015: Account tokens
+info "  → 015_add_account_tokens.sql"
+$PSQL_CMD -d $DB_NAME -f "$SCRIPT_DIR/schema/015_add_account_tokens.sql" > /dev/null
+info "  ✓ Account tokens added"
+
+# 016: Two-factor authentication
+info "  → 016_add_two_factor.sql"
+$PSQL_CMD -d $DB_NAME -f "$SCRIPT_DIR/schema/016_add_two_factor.sql" > /dev/null
+info "  ✓ Two-factor authentication added"
+
+# 017: WebSocket tickets
+info "  → 017_add_ws_tickets.sql"
+$PSQL_CMD -d $DB_NAME -f "$SCRIPT_DIR/schema/017_add_ws_tickets.sql" > /dev/null
+info "  ✓ WebSocket tickets added"
+
+# 018: WebSocket streams
+info "  → 018_add_ws_streams.sql"
+$PSQL_CMD -d $DB_NAME -f "$SCRIPT_DIR/schema/018_add_ws_streams.sql" > /dev/null
+info "  ✓ WebSocket streams added"
+
 info "✓ All migrations applied"
 
 # Load seed data if requested
@@ -0,0 +1,62 @@
+-- Recurring task series
+-- Version: 004
+-- Description: Add task_series table and link tasks to the series that generated them
+
+-- Series status enum
+CREATE TYPE series_status AS ENUM (
+  'active',
+  'cancelled',
+  'finished'
+);
+
+-- Task series table
+CREATE TABLE task_series (
+  id                TEXT PRIMARY KEY,
+  project_id        TEXT NOT NULL REFERENCES projects(id) ON DELETE CASCADE,
+
+  -- Task that declared the recurrence in its frontmatter
+  template_task_id  TEXT NOT NULL,
+
+  -- Recurrence rule (RRULE subset, e.g. FREQ=WEEKLY;BYDAY=MO)
+  recurrence        TEXT NOT NULL,
+
+  -- Markdown snapshot used to generate new instances
+  template_markdown TEXT NOT NULL,
+
+  status            series_status NOT NULL DEFAULT 'active',
+
+  -- Generation state
+  occurrence_count  INTEGER NOT NULL DEFAULT 1,
+  next_run_at       DATE,
+  last_instance_id  TEXT,
+
+  -- Timestamps
+  created_at        TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+  updated_at        TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+  cancelled_at      TIMESTAMPTZ,
+
+  created_by        TEXT REFERENCES users(id) ON DELETE SET NULL
+);
+
+CREATE INDEX idx_task_series_project ON task_series(project_id);
+CREATE UNIQUE INDEX idx_task_series_template ON task_series(template_task_id);
+CREATE INDEX idx_task_series_due ON task_series(next_run_at) WHERE status = 'active';
+
+CREATE TRIGGER update_task_series_updated_at
+  BEFORE UPDATE ON task_series
+  FOR EACH ROW
+  EXECUTE FUNCTION update_updated_at_column();
+
+-- Link generated tasks back to their series
+ALTER TABLE tasks ADD COLUMN IF NOT EXISTS series_id TEXT;
+
+ALTER TABLE tasks ADD CONSTRAINT fk_tasks_series
+  FOREIGN KEY (series_id)
+  REFERENCES task_series(id)
+  ON DELETE SET NULL;
+
+CREATE INDEX IF NOT EXISTS idx_tasks_series_id ON tasks(series_id) WHERE series_id IS NOT NULL;
+
+COMMENT ON TABLE task_series IS 'Recurring task series generated from a recurrence rule in task frontmatter';
+COMMENT ON COLUMN task_series.next_run_at IS 'Date of the next occurrence that has not been generated yet';
+COMMENT ON COLUMN tasks.series_id IS 'ID of the recurring series this task belongs to';
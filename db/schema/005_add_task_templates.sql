@@ -0,0 +1,182 @@
+-- Task Templates
+-- Version: 005
+-- Description: Add project-scoped task templates and default bug/feature/spike templates
+
+-- Task templates table
+CREATE TABLE task_templates (
+  id           TEXT PRIMARY KEY,
+  project_id   TEXT NOT NULL REFERENCES projects(id) ON DELETE CASCADE,
+
+  name         TEXT NOT NULL,
+  description  TEXT,
+
+  -- Markdown with placeholders ({{title}}, {{today}}, {{assignee}}, ...)
+  markdown     TEXT NOT NULL,
+
+  -- Prefix used to allocate the ID of tasks created from this template
+  id_prefix    TEXT NOT NULL DEFAULT 'T',
+
+  -- Timestamps
+  created_at   TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+  updated_at   TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+  created_by   TEXT REFERENCES users(id) ON DELETE SET NULL,
+
+  UNIQUE (project_id, name)
+);
+
+CREATE INDEX idx_task_templates_project ON task_templates(project_id);
+
+CREATE TRIGGER update_task_templates_updated_at
+  BEFORE UPDATE ON task_templates
+  FOR EACH ROW
+  EXECUTE FUNCTION update_updated_at_column();
+
+-- ============================================================================
+-- Function: Create Default Templates for a Project
+-- ============================================================================
+
+CREATE OR REPLACE FUNCTION create_default_task_templates(
+  p_project_id TEXT
+)
+RETURNS VOID AS $$
+BEGIN
+  -- Template 1: Bug
+  INSERT INTO task_templates (
+    id,
+    project_id,
+    name,
+    description,
+    markdown
+  ) VALUES (
+    p_project_id || '-tmpl-bug',
+    p_project_id,
+    'Bug',
+    'Bug report with reproduction steps',
+    $tmpl$## {{id}}: {{title}}
+
+```yaml
+id: {{id}}
+status: open
+priority: P1
+assignees: [{{assignee}}]
+labels: [bug]
+start_date: {{today}}
+```
+
+### Summary
+
+### Steps to Reproduce
+
+1.
+
+### Expected Behavior
+
+### Actual Behavior
+
+### Acceptance Criteria
+
+- [ ] The bug no longer reproduces
+- [ ] A regression test is added
+$tmpl$
+  ) ON CONFLICT DO NOTHING;
+
+  -- Template 2: Feature
+  INSERT INTO task_templates (
+    id,
+    project_id,
+    name,
+    description,
+    markdown
+  ) VALUES (
+    p_project_id || '-tmpl-feature',
+    p_project_id,
+    'Feature',
+    'New feature with background and acceptance criteria',
+    $tmpl$## {{id}}: {{title}}
+
+```yaml
+id: {{id}}
+status: open
+priority: P2
+assignees: [{{assignee}}]
+labels: [feature]
+start_date: {{today}}
+```
+
+### Background
+
+### Tasks
+
+1.
+
+### Acceptance Criteria
+
+- [ ]
+$tmpl$
+  ) ON CONFLICT DO NOTHING;
+
+  -- Template 3: Spike
+  INSERT INTO task_templates (
+    id,
+    project_id,
+    name,
+    description,
+    markdown
+  ) VALUES (
+    p_project_id || '-tmpl-spike',
+    p_project_id,
+    'Spike',
+    'Time-boxed investigation',
+    $tmpl$## {{id}}: {{title}}
+
+```yaml
+id: {{id}}
+status: open
+priority: P2
+assignees: [{{assignee}}]
+labels: [spike]
+start_date: {{today}}
+```
+
+### Question
+
+### Time Box
+
+### Findings
+
+### Acceptance Criteria
+
+- [ ] Findings are documented
+- [ ] Follow-up tasks are created
+$tmpl$
+  ) ON CONFLICT DO NOTHING;
+END;
+$$ LANGUAGE plpgsql;
+
+-- ============================================================================
+-- Trigger: Auto-create default templates for new projects
+-- ============================================================================
+
+CREATE OR REPLACE FUNCTION auto_create_default_task_templates()
+RETURNS TRIGGER AS $$
+BEGIN
+  PERFORM create_default_task_templates(NEW.id);
+  RETURN NEW;
+END;
+$$ LANGUAGE plpgsql;
+
+CREATE TRIGGER trigger_auto_create_default_task_templates
+  AFTER INSERT ON projects
+  FOR EACH ROW
+  EXECUTE FUNCTION auto_create_default_task_templates();
+
+-- Backfill existing projects
+SELECT create_default_task_templates(id) FROM projects;
+
+-- ============================================================================
+-- Comments
+-- ============================================================================
+
+COMMENT ON TABLE task_templates IS 'Project-scoped Markdown templates used to create tasks';
+COMMENT ON FUNCTION create_default_task_templates IS 'Creates the default bug, feature and spike templates for a project';
+COMMENT ON FUNCTION auto_create_default_task_templates IS 'Automatically creates default templates when a new project is created';
@@ -0,0 +1,7 @@
+-- Bulk Audit Action
+-- Version: 006
+-- Description: Add audit action for query-driven bulk edits recorded as a single batch
+
+ALTER TYPE audit_action ADD VALUE IF NOT EXISTS 'task.bulk_update';
+
+CREATE INDEX IF NOT EXISTS idx_audit_logs_action_created ON audit_logs(action, created_at DESC);
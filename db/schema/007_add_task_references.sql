@@ -0,0 +1,26 @@
+-- Task References
+-- Version: 007
+-- Description: Track task IDs mentioned in Markdown bodies as relations and flag references to missing or deleted tasks
+
+-- Where a relation comes from: created explicitly or extracted from the Markdown body
+ALTER TABLE task_relations
+  ADD COLUMN IF NOT EXISTS origin TEXT NOT NULL DEFAULT 'manual'
+  CHECK (origin IN ('manual', 'markdown'));
+
+CREATE INDEX IF NOT EXISTS idx_task_relations_source_origin ON task_relations(source_task_id, origin);
+
+-- References whose target task does not exist or has been deleted
+CREATE TABLE IF NOT EXISTS task_dangling_references (
+  source_task_id TEXT NOT NULL REFERENCES tasks(id) ON DELETE CASCADE,
+  target_task_id TEXT NOT NULL,
+  relation_type  relation_type NOT NULL,
+
+  -- missing: the target never existed, deleted: the target was deleted after it was referenced
+  reason         TEXT NOT NULL CHECK (reason IN ('missing', 'deleted')),
+
+  detected_at    TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+
+  PRIMARY KEY (source_task_id, target_task_id, relation_type)
+);
+
+CREATE INDEX IF NOT EXISTS idx_task_dangling_references_target ON task_dangling_references(target_task_id);
@@ -0,0 +1,37 @@
+-- Notifications
+-- Version: 008
+-- Description: Add per-user notification inbox for mentions, assignments, and changes and comments on watched tasks
+
+-- Notification type enum
+CREATE TYPE notification_type AS ENUM (
+  'mention',
+  'assigned',
+  'task_updated',
+  'task_commented'
+);
+
+-- Notifications table
+CREATE TABLE notifications (
+  id            BIGSERIAL PRIMARY KEY,
+  user_id       TEXT NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+  type          notification_type NOT NULL,
+
+  -- What the notification is about
+  project_id    TEXT REFERENCES projects(id) ON DELETE CASCADE,
+  task_id       TEXT REFERENCES tasks(id) ON DELETE CASCADE,
+  comment_id    TEXT REFERENCES task_comments(id) ON DELETE CASCADE,
+
+  -- Who caused it
+  actor_user_id TEXT REFERENCES users(id) ON DELETE SET NULL,
+
+  -- Display text and type-specific details
+  title         TEXT NOT NULL,
+  detail        JSONB NOT NULL DEFAULT '{}'::JSONB,
+
+  -- Timestamps
+  created_at    TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+  read_at       TIMESTAMPTZ
+);
+
+CREATE INDEX idx_notifications_user_created ON notifications(user_id, created_at DESC);
+CREATE INDEX idx_notifications_user_unread ON notifications(user_id) WHERE read_at IS NULL;
@@ -0,0 +1,60 @@
+-- Subscriptions
+-- Version: 009
+-- Description: Let users watch tasks, saved views and projects, and collect watched events into digests
+
+-- New notification types
+ALTER TYPE notification_type ADD VALUE IF NOT EXISTS 'task_created';
+ALTER TYPE notification_type ADD VALUE IF NOT EXISTS 'task_deleted';
+ALTER TYPE notification_type ADD VALUE IF NOT EXISTS 'view_entered';
+ALTER TYPE notification_type ADD VALUE IF NOT EXISTS 'view_left';
+ALTER TYPE notification_type ADD VALUE IF NOT EXISTS 'digest';
+
+-- Subscription target enum
+CREATE TYPE subscription_target AS ENUM (
+  'task',
+  'view',
+  'project'
+);
+
+-- Subscriptions table
+CREATE TABLE subscriptions (
+  user_id     TEXT NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+  target_type subscription_target NOT NULL,
+  target_id   TEXT NOT NULL,
+  project_id  TEXT NOT NULL REFERENCES projects(id) ON DELETE CASCADE,
+
+  -- Why the user is subscribed: watched explicitly, or created / assigned to the task
+  reason      TEXT NOT NULL DEFAULT 'manual' CHECK (reason IN ('manual', 'creator', 'assignee')),
+
+  -- Unwatching keeps the row inactive so that automatic subscriptions are not recreated
+  active      BOOLEAN NOT NULL DEFAULT TRUE,
+
+  -- Also collect the events of this subscription into the periodic digest
+  digest      BOOLEAN NOT NULL DEFAULT FALSE,
+
+  -- Timestamps
+  created_at  TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+  updated_at  TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+
+  PRIMARY KEY (user_id, target_type, target_id)
+);
+
+CREATE INDEX idx_subscriptions_target ON subscriptions(target_type, target_id) WHERE active;
+CREATE INDEX idx_subscriptions_project ON subscriptions(project_id) WHERE active;
+
+CREATE TRIGGER update_subscriptions_updated_at
+  BEFORE UPDATE ON subscriptions
+  FOR EACH ROW
+  EXECUTE FUNCTION update_updated_at_column();
+
+-- Last known result set of watched views, used to detect tasks entering or leaving a view
+CREATE TABLE view_watch_results (
+  view_id    TEXT PRIMARY KEY REFERENCES saved_views(id) ON DELETE CASCADE,
+  task_ids   TEXT[] NOT NULL DEFAULT '{}',
+  checked_at TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
+
+-- Notifications waiting to be collected into a digest
+ALTER TABLE notifications ADD COLUMN IF NOT EXISTS digest_pending BOOLEAN NOT NULL DEFAULT FALSE;
+
+CREATE INDEX IF NOT EXISTS idx_notifications_digest_pending ON notifications(user_id, created_at) WHERE digest_pending;
@@ -0,0 +1,75 @@
+-- Webhooks
+-- Version: 010
+-- Description: Add project webhooks with a durable delivery queue and a log of delivery attempts
+
+-- Webhook registrations
+CREATE TABLE webhooks (
+  id          TEXT PRIMARY KEY,
+  project_id  TEXT NOT NULL REFERENCES projects(id) ON DELETE CASCADE,
+  url         TEXT NOT NULL,
+
+  -- Event types to deliver (e.g. task.created, task.updated)
+  events      TEXT[] NOT NULL,
+
+  -- HMAC-SHA256 key for the payload signature
+  secret      TEXT NOT NULL,
+
+  description TEXT,
+  active      BOOLEAN NOT NULL DEFAULT TRUE,
+
+  -- Metadata
+  created_by  TEXT REFERENCES users(id) ON DELETE SET NULL,
+  created_at  TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+  updated_at  TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
+
+CREATE INDEX idx_webhooks_project ON webhooks(project_id) WHERE active;
+
+CREATE TRIGGER update_webhooks_updated_at
+  BEFORE UPDATE ON webhooks
+  FOR EACH ROW
+  EXECUTE FUNCTION update_updated_at_column();
+
+-- Delivery queue: one row per event and webhook
+CREATE TABLE webhook_deliveries (
+  id              BIGSERIAL PRIMARY KEY,
+  webhook_id      TEXT NOT NULL REFERENCES webhooks(id) ON DELETE CASCADE,
+  event_type      TEXT NOT NULL,
+
+  -- Exact request body that is signed and sent
+  payload         JSONB NOT NULL,
+
+  -- pending until a 2xx response, failed after the last attempt
+  status          TEXT NOT NULL DEFAULT 'pending' CHECK (status IN ('pending', 'succeeded', 'failed')),
+  attempts        INTEGER NOT NULL DEFAULT 0,
+
+  -- Claimed deliveries are pushed into the future so that a crashed worker's deliveries are retried
+  next_attempt_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+
+  -- Result of the last attempt
+  response_status INTEGER,
+  last_error      TEXT,
+
+  -- Set for manual redeliveries
+  redelivery_of   BIGINT REFERENCES webhook_deliveries(id) ON DELETE SET NULL,
+
+  created_at      TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+  delivered_at    TIMESTAMPTZ
+);
+
+CREATE INDEX idx_webhook_deliveries_due ON webhook_deliveries(next_attempt_at) WHERE status = 'pending';
+CREATE INDEX idx_webhook_deliveries_webhook ON webhook_deliveries(webhook_id, created_at DESC);
+
+-- Delivery log: one row per HTTP attempt
+CREATE TABLE webhook_delivery_attempts (
+  id              BIGSERIAL PRIMARY KEY,
+  delivery_id     BIGINT NOT NULL REFERENCES webhook_deliveries(id) ON DELETE CASCADE,
+  attempt         INTEGER NOT NULL,
+  response_status INTEGER,
+  response_body   TEXT,
+  error           TEXT,
+  duration_ms     INTEGER NOT NULL,
+  attempted_at    TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
+
+CREATE INDEX idx_webhook_delivery_attempts_delivery ON webhook_delivery_attempts(delivery_id, attempt);
@@ -0,0 +1,38 @@
+-- Ingest Tokens
+-- Version: 011
+-- Description: Let alerting systems and forms create tasks through per-project ingestion tokens
+
+-- Ingestion tokens table
+CREATE TABLE ingest_tokens (
+  id          TEXT PRIMARY KEY,
+  project_id  TEXT NOT NULL REFERENCES projects(id) ON DELETE CASCADE,
+  name        TEXT NOT NULL,
+
+  -- SHA-256 of the token; the token itself is only shown when it is created
+  token_hash  TEXT NOT NULL UNIQUE,
+
+  -- How JSON payloads become tasks: a task template and template variables mapped from payload paths
+  template_id TEXT REFERENCES task_templates(id) ON DELETE SET NULL,
+  mapping     JSONB NOT NULL DEFAULT '{}'::JSONB,
+
+  -- ID prefix of tasks created without a template
+  id_prefix   TEXT NOT NULL DEFAULT 'T' CHECK (id_prefix ~ '^[A-Z]+$'),
+
+  -- Metadata
+  created_by   TEXT REFERENCES users(id) ON DELETE SET NULL,
+  created_at   TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+  updated_at   TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+  last_used_at TIMESTAMPTZ
+);
+
+CREATE INDEX idx_ingest_tokens_project ON ingest_tokens(project_id);
+
+CREATE TRIGGER update_ingest_tokens_updated_at
+  BEFORE UPDATE ON ingest_tokens
+  FOR EACH ROW
+  EXECUTE FUNCTION update_updated_at_column();
+
+-- At most one live task per idempotency key, so that repeated events comment on the existing task
+CREATE UNIQUE INDEX idx_tasks_idempotency_key
+  ON tasks(project_id, (extra_meta->>'idempotency_key'))
+  WHERE archived_at IS NULL AND extra_meta ? 'idempotency_key';
@@ -0,0 +1,26 @@
+-- Idempotency Keys
+-- Version: 012
+-- Description: Store responses of mutating requests by Idempotency-Key so that retried requests are replayed
+
+-- Idempotency keys table
+CREATE TABLE idempotency_keys (
+  -- Keys are unique per client: the method and path of the request and the authenticated user
+  scope         TEXT NOT NULL,
+  key           TEXT NOT NULL,
+
+  -- SHA-256 of the request body; a key cannot be reused for a different request
+  request_hash  TEXT NOT NULL,
+
+  -- Stored response, NULL while the first request is still being processed
+  status_code   INTEGER,
+  content_type  TEXT,
+  response_body BYTEA,
+
+  created_at    TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+  completed_at  TIMESTAMPTZ,
+  expires_at    TIMESTAMPTZ NOT NULL,
+
+  PRIMARY KEY (scope, key)
+);
+
+CREATE INDEX idx_idempotency_keys_expires ON idempotency_keys(expires_at);
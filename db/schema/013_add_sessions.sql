@@ -0,0 +1,29 @@
+-- Sessions
+-- Version: 013
+-- Description: Server-side login sessions with rotating refresh tokens, so that logins can be revoked
+
+-- Sessions table
+CREATE TABLE sessions (
+  id                  TEXT PRIMARY KEY,
+  user_id             TEXT NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+
+  -- SHA-256 of the current refresh token; the token itself is only given to the client
+  refresh_token_hash  TEXT NOT NULL UNIQUE,
+
+  -- SHA-256 of the refresh token it replaced. Presenting it again means the token was
+  -- stolen, and the session is revoked.
+  previous_token_hash TEXT,
+
+  -- Client the session was started from
+  user_agent          TEXT,
+  ip_address          TEXT,
+
+  created_at          TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+  last_used_at        TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+  expires_at          TIMESTAMPTZ NOT NULL,
+  revoked_at          TIMESTAMPTZ
+);
+
+CREATE INDEX idx_sessions_user ON sessions(user_id) WHERE revoked_at IS NULL;
+CREATE INDEX idx_sessions_previous_token ON sessions(previous_token_hash) WHERE previous_token_hash IS NOT NULL;
+CREATE INDEX idx_sessions_expires ON sessions(expires_at);
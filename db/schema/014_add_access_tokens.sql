@@ -0,0 +1,35 @@
+-- Access Tokens
+-- Version: 014
+-- Description: Personal access tokens and project service accounts for scripts and CI
+
+-- Service accounts are users that belong to a project rather than a person.
+-- They have no password or OIDC identity, so they can only authenticate with access tokens.
+ALTER TABLE users
+  ADD COLUMN service_project_id TEXT REFERENCES projects(id) ON DELETE CASCADE;
+
+CREATE INDEX idx_users_service_project ON users(service_project_id) WHERE service_project_id IS NOT NULL;
+
+-- Access tokens table
+CREATE TABLE access_tokens (
+  id           TEXT PRIMARY KEY,
+  user_id      TEXT NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+  name         TEXT NOT NULL,
+
+  -- SHA-256 of the token; the token itself is only shown when it is created
+  token_hash   TEXT NOT NULL UNIQUE,
+
+  -- Restricts the token to one project; NULL allows every project of the owner
+  project_id   TEXT REFERENCES projects(id) ON DELETE CASCADE,
+
+  -- 'read' tokens can only make safe (GET/HEAD) requests; 'write' tokens can also modify
+  scope        TEXT NOT NULL DEFAULT 'read' CHECK (scope IN ('read', 'write')),
+
+  -- Metadata
+  created_by   TEXT REFERENCES users(id) ON DELETE SET NULL,
+  created_at   TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+  expires_at   TIMESTAMPTZ,
+  last_used_at TIMESTAMPTZ,
+  revoked_at   TIMESTAMPTZ
+);
+
+CREATE INDEX idx_access_tokens_user ON access_tokens(user_id) WHERE revoked_at IS NULL;
@@ -0,0 +1,27 @@
+-- Account Tokens
+-- Version: 015
+-- Description: Email verification and password reset tokens
+
+-- When the user proved they own their email address
+ALTER TABLE users
+  ADD COLUMN email_verified_at TIMESTAMPTZ;
+
+-- Single-use tokens sent by email
+CREATE TABLE account_tokens (
+  id         TEXT PRIMARY KEY,
+  user_id    TEXT NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+  purpose    TEXT NOT NULL CHECK (purpose IN ('verify_email', 'reset_password')),
+
+  -- SHA-256 of the token; the token itself is only sent by email
+  token_hash TEXT NOT NULL UNIQUE,
+
+  -- Address the token was sent to. Verification only applies while the user still has it.
+  email      TEXT NOT NULL,
+
+  created_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+  expires_at TIMESTAMPTZ NOT NULL,
+  used_at    TIMESTAMPTZ
+);
+
+CREATE INDEX idx_account_tokens_user ON account_tokens(user_id, purpose) WHERE used_at IS NULL;
+CREATE INDEX idx_account_tokens_expires ON account_tokens(expires_at);
@@ -0,0 +1,24 @@
+-- Two-Factor Authentication
+-- Version: 016
+-- Description: TOTP secrets and recovery codes
+
+-- TOTP secret of the user's authenticator app. It is pending until a first code has
+-- been verified, which sets totp_enabled_at.
+ALTER TABLE users
+  ADD COLUMN totp_secret TEXT,
+  ADD COLUMN totp_enabled_at TIMESTAMPTZ,
+  -- Time step of the last accepted code, so that a code cannot be used twice
+  ADD COLUMN totp_last_step BIGINT;
+
+-- Single-use codes for signing in without the authenticator app
+CREATE TABLE recovery_codes (
+  user_id   TEXT NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+
+  -- SHA-256 of the code; the code itself is only shown once
+  code_hash TEXT NOT NULL,
+
+  created_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+  used_at    TIMESTAMPTZ,
+
+  PRIMARY KEY (user_id, code_hash)
+);
@@ -0,0 +1,22 @@
+-- WebSocket Tickets
+-- Version: 017
+-- Description: One-time tickets for authenticating WebSocket connections
+
+-- Browsers cannot set headers on a WebSocket upgrade, so clients exchange their session
+-- for a short-lived ticket and pass it in the URL instead
+CREATE TABLE ws_tickets (
+  -- SHA-256 of the ticket; the ticket itself is only returned once
+  ticket_hash TEXT PRIMARY KEY,
+  user_id     TEXT NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+
+  -- Session the ticket was issued from; the connection lives only as long as the session
+  session_id  TEXT NOT NULL REFERENCES sessions(id) ON DELETE CASCADE,
+
+  -- Optional project the ticket is limited to
+  project_id  TEXT REFERENCES projects(id) ON DELETE CASCADE,
+
+  created_at  TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+  expires_at  TIMESTAMPTZ NOT NULL
+);
+
+CREATE INDEX idx_ws_tickets_expires ON ws_tickets(expires_at);
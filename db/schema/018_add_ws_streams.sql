@@ -0,0 +1,20 @@
+-- WebSocket Streams
+-- Version: 018
+-- Description: Message sequence numbers and large payloads shared by server replicas
+
+-- Last sequence number of the live messages of each project. Replicas number messages
+-- here so that a client can resume on any of them.
+-- Not tied to projects: messages about a deleted project are still numbered.
+CREATE TABLE ws_streams (
+  project_id TEXT PRIMARY KEY,
+  seq        BIGINT NOT NULL
+);
+
+-- Messages too large for a NOTIFY payload, which carries their id instead
+CREATE TABLE ws_payloads (
+  id         BIGSERIAL PRIMARY KEY,
+  payload    JSONB NOT NULL,
+  created_at TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
+
+CREATE INDEX idx_ws_payloads_created ON ws_payloads(created_at);
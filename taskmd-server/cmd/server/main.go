@@ -11,8 +11,10 @@ import (
 	"time"
 
 	"github.com/tktomaru/taskai/taskai-server/internal/api"
+	"github.com/tktomaru/taskai/taskai-server/internal/auth"
 	"github.com/tktomaru/taskai/taskai-server/internal/config"
 	"github.com/tktomaru/taskai/taskai-server/internal/database"
+	"github.com/tktomaru/taskai/taskai-server/internal/mail"
 	"github.com/tktomaru/taskai/taskai-server/internal/search"
 	"github.com/tktomaru/taskai/taskai-server/internal/websocket"
 )
@@ -72,13 +74,68 @@ func main() {
 	// Initialize WebSocket hub
 	log.Println("Initializing WebSocket hub...")
 	wsHub := websocket.NewHub()
+	wsHub.ReplayLimit = cfg.WebSocket.ReplayBuffer
+	if cfg.WebSocket.Backend == "postgres" {
+		backend, err := websocket.NewPostgresBackend(db, cfg.Database.GetDSN())
+		if err != nil {
+			log.Fatalf("Failed to set up WebSocket backend: %v", err)
+		}
+		wsHub.Backend = backend
+	}
 	go wsHub.Run()
-	log.Println("WebSocket hub started")
+	log.Printf("WebSocket hub started (backend: %s)", cfg.WebSocket.Backend)
 
 	// Create HTTP server
 	log.Println("Initializing HTTP server...")
 	apiServer := api.NewServer(cfg, db, meili, wsHub)
 
+	// Account emails and password rules
+	mailSender, err := mail.NewSender(mail.Config{
+		Driver:       cfg.Mail.Driver,
+		From:         cfg.Mail.From,
+		SMTPHost:     cfg.Mail.SMTPHost,
+		SMTPPort:     cfg.Mail.SMTPPort,
+		SMTPUsername: cfg.Mail.SMTPUsername,
+		SMTPPassword: cfg.Mail.SMTPPassword,
+		Dir:          cfg.Mail.Dir,
+	})
+	if err != nil {
+		log.Fatalf("Failed to set up mail: %v", err)
+	}
+	apiServer.SetMailSender(mailSender)
+	log.Printf("Mail driver: %s", cfg.Mail.Driver)
+
+	passwordPolicy, err := auth.NewPasswordPolicy(cfg.Auth.PasswordMinLength, cfg.Auth.BreachedPasswordsFile)
+	if err != nil {
+		log.Fatalf("Failed to load password policy: %v", err)
+	}
+	apiServer.SetPasswordPolicy(passwordPolicy)
+
+	// Start the WebSocket backend listener, the scheduler for recurring tasks and the maintenance
+	// of watched views, digests and expired credentials, which always runs
+	schedulerCtx, stopScheduler := context.WithCancel(context.Background())
+	defer stopScheduler()
+	if wsHub.Backend != nil {
+		go wsHub.Listen(schedulerCtx)
+	}
+	if cfg.Scheduler.Enabled {
+		go apiServer.StartScheduler(schedulerCtx, cfg.Scheduler.Interval)
+		log.Printf("Scheduler started (interval: %s)", cfg.Scheduler.Interval)
+	}
+
+	go apiServer.StartMaintenance(schedulerCtx, cfg.Scheduler.Interval, cfg.Scheduler.DigestInterval)
+	log.Printf("Maintenance started (interval: %s, digest interval: %s)", cfg.Scheduler.Interval, cfg.Scheduler.DigestInterval)
+
+	if cfg.Webhooks.Enabled {
+		go apiServer.StartWebhookWorker(schedulerCtx, cfg.Webhooks.PollInterval)
+		log.Printf("Webhook delivery worker started (poll interval: %s)", cfg.Webhooks.PollInterval)
+	}
+
+	go apiServer.StartViewRefresh(schedulerCtx)
+
+	go apiServer.StartWebSocketRevalidation(schedulerCtx, cfg.WebSocket.RevalidateInterval)
+	log.Printf("WebSocket revalidation started (interval: %s)", cfg.WebSocket.RevalidateInterval)
+
 	addr := fmt.Sprintf("%s:%d", cfg.Server.Host, cfg.Server.Port)
 	server := &http.Server{
 		Addr:         addr,
@@ -104,6 +161,7 @@ func main() {
 	<-quit
 
 	log.Println("Shutting down server...")
+	stopScheduler()
 
 	// Graceful shutdown
 	ctx, cancel := context.WithTimeout(context.Background(), cfg.Server.ShutdownTimeout)
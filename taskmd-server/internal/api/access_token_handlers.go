@@ -0,0 +1,276 @@
+package api
+
+import (
+	"log"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/tktomaru/taskai/taskai-server/internal/repository"
+	"github.com/tktomaru/taskai/taskai-server/internal/service"
+	ws "github.com/tktomaru/taskai/taskai-server/internal/websocket"
+)
+
+// accessTokenService creates an access token service
+func (s *Server) accessTokenService() *service.AccessTokenService {
+	return service.NewAccessTokenService(
+		repository.NewAccessTokenRepository(s.db.DB),
+		repository.NewUserRepository(s.db.DB),
+		repository.NewProjectRepository(s.db.DB),
+	)
+}
+
+// requireSession rejects requests authenticated with an access token, so that tokens
+// cannot be used to mint further tokens
+func requireSession(c *gin.Context) bool {
+	if _, exists := c.Get("access_token"); exists {
+		c.JSON(http.StatusForbidden, gin.H{
+			"error":   "session_required",
+			"message": "This endpoint cannot be used with an access token",
+		})
+		return false
+	}
+
+	return true
+}
+
+// handleListAccessTokens handles GET /api/v1/me/tokens
+func (s *Server) handleListAccessTokens(c *gin.Context) {
+	userID, ok := currentUserID(c)
+	if !ok {
+		return
+	}
+
+	tokens, err := s.accessTokenService().ListTokens(c.Request.Context(), userID)
+	if err != nil {
+		log.Printf("ERROR: Failed to list access tokens of user %s: %v", userID, err)
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error":   "internal_server_error",
+			"message": "Failed to list access tokens",
+			"details": err.Error(),
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"data": tokens,
+	})
+}
+
+// handleCreateAccessToken handles POST /api/v1/me/tokens
+func (s *Server) handleCreateAccessToken(c *gin.Context) {
+	userID, ok := currentUserID(c)
+	if !ok || !requireSession(c) {
+		return
+	}
+
+	var req service.CreateAccessTokenRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error":   "invalid_request",
+			"message": "Invalid request body",
+			"details": err.Error(),
+		})
+		return
+	}
+	req.CreatedBy = userID
+
+	token, err := s.accessTokenService().CreateToken(c.Request.Context(), userID, &req)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error":   "validation_error",
+			"message": "Failed to create access token",
+			"details": err.Error(),
+		})
+		return
+	}
+
+	// The token is only returned here
+	c.JSON(http.StatusCreated, gin.H{
+		"data": token,
+	})
+}
+
+// handleRevokeAccessToken handles DELETE /api/v1/me/tokens/:tokenId
+func (s *Server) handleRevokeAccessToken(c *gin.Context) {
+	userID, ok := currentUserID(c)
+	if !ok {
+		return
+	}
+	tokenID := c.Param("tokenId")
+
+	if err := s.accessTokenService().RevokeToken(c.Request.Context(), userID, tokenID); err != nil {
+		c.JSON(http.StatusNotFound, gin.H{
+			"error":   "not_found",
+			"message": "Access token not found",
+			"details": err.Error(),
+		})
+		return
+	}
+
+	s.revokeWebSockets(func(client *ws.Client) bool {
+		return client.UserID == userID && client.AccessTokenID == tokenID
+	}, closeTokenRevoked)
+
+	c.JSON(http.StatusOK, gin.H{
+		"message": "Access token revoked",
+	})
+}
+
+// handleListServiceAccounts handles GET /api/v1/projects/:projectId/service-accounts
+func (s *Server) handleListServiceAccounts(c *gin.Context) {
+	projectID := c.Param("projectId")
+
+	accounts, err := s.accessTokenService().ListServiceAccounts(c.Request.Context(), projectID)
+	if err != nil {
+		log.Printf("ERROR: Failed to list service accounts for project %s: %v", projectID, err)
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error":   "internal_server_error",
+			"message": "Failed to list service accounts",
+			"details": err.Error(),
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"data": accounts,
+	})
+}
+
+// handleCreateServiceAccount handles POST /api/v1/projects/:projectId/service-accounts
+func (s *Server) handleCreateServiceAccount(c *gin.Context) {
+	projectID := c.Param("projectId")
+	if !requireSession(c) || !s.requireProjectMaintainer(c, projectID) {
+		return
+	}
+
+	var req service.CreateServiceAccountRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error":   "invalid_request",
+			"message": "Invalid request body",
+			"details": err.Error(),
+		})
+		return
+	}
+
+	account, err := s.accessTokenService().CreateServiceAccount(c.Request.Context(), projectID, &req)
+	if err != nil {
+		log.Printf("ERROR: Failed to create service account for project %s: %v", projectID, err)
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error":   "validation_error",
+			"message": "Failed to create service account",
+			"details": err.Error(),
+		})
+		return
+	}
+
+	c.JSON(http.StatusCreated, gin.H{
+		"data": account,
+	})
+}
+
+// handleDeleteServiceAccount handles DELETE /api/v1/projects/:projectId/service-accounts/:accountId
+func (s *Server) handleDeleteServiceAccount(c *gin.Context) {
+	projectID := c.Param("projectId")
+	accountID := c.Param("accountId")
+	if !requireSession(c) || !s.requireProjectMaintainer(c, projectID) {
+		return
+	}
+
+	if err := s.accessTokenService().DeleteServiceAccount(c.Request.Context(), projectID, accountID); err != nil {
+		c.JSON(http.StatusNotFound, gin.H{
+			"error":   "not_found",
+			"message": "Service account not found",
+			"details": err.Error(),
+		})
+		return
+	}
+
+	s.revokeWebSockets(func(client *ws.Client) bool { return client.UserID == accountID }, closeTokenRevoked)
+
+	c.JSON(http.StatusOK, gin.H{
+		"message": "Service account deleted",
+	})
+}
+
+// handleListServiceAccountTokens handles GET /api/v1/projects/:projectId/service-accounts/:accountId/tokens
+func (s *Server) handleListServiceAccountTokens(c *gin.Context) {
+	projectID := c.Param("projectId")
+	accountID := c.Param("accountId")
+
+	tokens, err := s.accessTokenService().ListServiceAccountTokens(c.Request.Context(), projectID, accountID)
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{
+			"error":   "not_found",
+			"message": "Service account not found",
+			"details": err.Error(),
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"data": tokens,
+	})
+}
+
+// handleCreateServiceAccountToken handles POST /api/v1/projects/:projectId/service-accounts/:accountId/tokens
+func (s *Server) handleCreateServiceAccountToken(c *gin.Context) {
+	projectID := c.Param("projectId")
+	accountID := c.Param("accountId")
+	if !requireSession(c) || !s.requireProjectMaintainer(c, projectID) {
+		return
+	}
+
+	var req service.CreateAccessTokenRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error":   "invalid_request",
+			"message": "Invalid request body",
+			"details": err.Error(),
+		})
+		return
+	}
+	req.CreatedBy = c.GetString("user_id")
+
+	token, err := s.accessTokenService().CreateServiceAccountToken(c.Request.Context(), projectID, accountID, &req)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error":   "validation_error",
+			"message": "Failed to create access token",
+			"details": err.Error(),
+		})
+		return
+	}
+
+	// The token is only returned here
+	c.JSON(http.StatusCreated, gin.H{
+		"data": token,
+	})
+}
+
+// handleRevokeServiceAccountToken handles DELETE /api/v1/projects/:projectId/service-accounts/:accountId/tokens/:tokenId
+func (s *Server) handleRevokeServiceAccountToken(c *gin.Context) {
+	projectID := c.Param("projectId")
+	accountID := c.Param("accountId")
+	tokenID := c.Param("tokenId")
+	if !s.requireProjectMaintainer(c, projectID) {
+		return
+	}
+
+	if err := s.accessTokenService().RevokeServiceAccountToken(c.Request.Context(), projectID, accountID, tokenID); err != nil {
+		c.JSON(http.StatusNotFound, gin.H{
+			"error":   "not_found",
+			"message": "Access token not found",
+			"details": err.Error(),
+		})
+		return
+	}
+
+	s.revokeWebSockets(func(client *ws.Client) bool {
+		return client.UserID == accountID && client.AccessTokenID == tokenID
+	}, closeTokenRevoked)
+
+	c.JSON(http.StatusOK, gin.H{
+		"message": "Access token revoked",
+	})
+}
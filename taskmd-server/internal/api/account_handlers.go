@@ -0,0 +1,142 @@
+package api
+
+import (
+	"errors"
+	"log"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/tktomaru/taskai/taskai-server/internal/auth"
+	"github.com/tktomaru/taskai/taskai-server/internal/repository"
+)
+
+// AccountTokenRequest represents a request carrying a token sent by email
+type AccountTokenRequest struct {
+	Token string `json:"token" binding:"required"`
+}
+
+// EmailRequest represents a request naming an email address
+type EmailRequest struct {
+	Email string `json:"email" binding:"required"`
+}
+
+// ResetPasswordRequest represents a request to set a new password with a reset token
+type ResetPasswordRequest struct {
+	Token    string `json:"token" binding:"required"`
+	Password string `json:"password" binding:"required"`
+}
+
+// handleVerifyEmail handles POST /api/v1/auth/verify-email
+func (s *Server) handleVerifyEmail(c *gin.Context) {
+	var req AccountTokenRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error":   "invalid_request",
+			"message": "Invalid request body",
+			"details": err.Error(),
+		})
+		return
+	}
+
+	user, err := s.accountService().VerifyEmail(c.Request.Context(), req.Token)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error":   "invalid_token",
+			"message": err.Error(),
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"data": user,
+	})
+}
+
+// handleResendVerification handles POST /api/v1/auth/verify-email/resend
+// It answers the same way whether or not the address belongs to an account.
+func (s *Server) handleResendVerification(c *gin.Context) {
+	var req EmailRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error":   "invalid_request",
+			"message": "Invalid request body",
+			"details": err.Error(),
+		})
+		return
+	}
+
+	if err := s.accountService().ResendVerification(c.Request.Context(), req.Email); err != nil {
+		log.Printf("ERROR: Failed to resend verification email: %v", err)
+	}
+
+	c.JSON(http.StatusAccepted, gin.H{
+		"message": "If the address belongs to an unverified account, a verification email has been sent",
+	})
+}
+
+// handleForgotPassword handles POST /api/v1/auth/password/forgot
+// It answers the same way whether or not the address belongs to an account.
+func (s *Server) handleForgotPassword(c *gin.Context) {
+	var req EmailRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error":   "invalid_request",
+			"message": "Invalid request body",
+			"details": err.Error(),
+		})
+		return
+	}
+
+	if err := s.accountService().RequestPasswordReset(c.Request.Context(), req.Email); err != nil {
+		log.Printf("ERROR: Failed to send password reset email: %v", err)
+	}
+
+	c.JSON(http.StatusAccepted, gin.H{
+		"message": "If the address belongs to an account, a password reset email has been sent",
+	})
+}
+
+// handleResetPassword handles POST /api/v1/auth/password/reset
+func (s *Server) handleResetPassword(c *gin.Context) {
+	var req ResetPasswordRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error":   "invalid_request",
+			"message": "Invalid request body",
+			"details": err.Error(),
+		})
+		return
+	}
+
+	err := s.accountService().ResetPassword(c.Request.Context(), req.Token, req.Password)
+	switch {
+	case err == nil:
+	case errors.Is(err, repository.ErrAccountTokenNotFound):
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error":   "invalid_token",
+			"message": err.Error(),
+		})
+		return
+	case errors.Is(err, auth.ErrPasswordTooShort), errors.Is(err, auth.ErrPasswordBreached), errors.Is(err, auth.ErrPasswordIsEmail):
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error":   "weak_password",
+			"message": err.Error(),
+		})
+		return
+	default:
+		log.Printf("ERROR: Failed to reset password: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error":   "internal_server_error",
+			"message": "Failed to reset password",
+			"details": err.Error(),
+		})
+		return
+	}
+
+	// Every session was logged out, including this browser's
+	clearSessionCookies(c)
+
+	c.JSON(http.StatusOK, gin.H{
+		"message": "Password has been reset, please log in again",
+	})
+}
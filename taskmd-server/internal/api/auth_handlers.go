@@ -1,14 +1,128 @@
 package api
 
 import (
+	"errors"
+	"log"
 	"net/http"
 	"strings"
+	"time"
 
 	"github.com/gin-gonic/gin"
+	"github.com/tktomaru/taskai/taskai-server/internal/ratelimit"
 	"github.com/tktomaru/taskai/taskai-server/internal/repository"
 	"github.com/tktomaru/taskai/taskai-server/internal/service"
+	ws "github.com/tktomaru/taskai/taskai-server/internal/websocket"
 )
 
+// Cookies holding the access token and the refresh token
+const (
+	accessTokenCookie  = "token"
+	refreshTokenCookie = "refresh_token"
+
+	// The refresh token is only sent to the auth endpoints
+	refreshTokenCookiePath = "/api/v1/auth"
+)
+
+// authService creates an auth service using the configured token lifetimes, password policy
+// and two-factor authentication
+func (s *Server) authService() *service.AuthService {
+	authService := service.NewAuthService(
+		repository.NewUserRepository(s.db.DB),
+		repository.NewSessionRepository(s.db.DB),
+		s.cfg.Auth.JWTSecret,
+		s.cfg.Auth.JWTExpiresIn,
+		s.cfg.Auth.RefreshTokenTTL,
+	)
+	authService.SetPasswordPolicy(s.passwordPolicy)
+	authService.SetTwoFactor(s.twoFactorService(), s.cfg.Auth.TwoFactorChallengeTTL)
+
+	return authService
+}
+
+// accountService creates a service for email verification and password resets
+func (s *Server) accountService() *service.AccountService {
+	return service.NewAccountService(
+		repository.NewUserRepository(s.db.DB),
+		repository.NewAccountTokenRepository(s.db.DB),
+		repository.NewSessionRepository(s.db.DB),
+		s.passwordPolicy,
+		s.mailSender,
+		s.cfg.Mail.LinkBaseURL,
+		s.cfg.Auth.EmailVerificationTTL,
+		s.cfg.Auth.PasswordResetTTL,
+	)
+}
+
+// loginLockout returns the lockout applied to failed logins, or nil when rate limiting is disabled
+func (s *Server) loginLockout() *ratelimit.Lockout {
+	if !s.cfg.RateLimit.Enabled {
+		return nil
+	}
+
+	return &ratelimit.Lockout{
+		Store:     s.rateLimits,
+		Threshold: s.cfg.RateLimit.LoginLockoutThreshold,
+		Base:      s.cfg.RateLimit.LoginLockoutBase,
+		Max:       s.cfg.RateLimit.LoginLockoutMax,
+	}
+}
+
+// clientInfo describes the client of a request, for the session list
+func clientInfo(c *gin.Context) service.ClientInfo {
+	return service.ClientInfo{
+		UserAgent: c.Request.UserAgent(),
+		IPAddress: c.ClientIP(),
+	}
+}
+
+// setSessionCookies stores the access and refresh tokens of a session in cookies
+func (s *Server) setSessionCookies(c *gin.Context, response *service.AuthResponse) {
+	c.SetCookie(
+		accessTokenCookie,
+		response.Token,
+		int(s.cfg.Auth.JWTExpiresIn.Seconds()),
+		"/",
+		"",
+		false, // secure (set to true in production with HTTPS)
+		true,  // httpOnly
+	)
+	c.SetCookie(
+		refreshTokenCookie,
+		response.RefreshToken,
+		int(s.cfg.Auth.RefreshTokenTTL.Seconds()),
+		refreshTokenCookiePath,
+		"",
+		false, // secure (set to true in production with HTTPS)
+		true,  // httpOnly
+	)
+}
+
+// clearSessionCookies removes the session cookies
+func clearSessionCookies(c *gin.Context) {
+	c.SetCookie(accessTokenCookie, "", -1, "/", "", false, true)
+	c.SetCookie(refreshTokenCookie, "", -1, refreshTokenCookiePath, "", false, true)
+}
+
+// requestToken returns the access token of a request, from the cookie or the Authorization header
+func requestToken(c *gin.Context) (string, bool) {
+	if token, err := c.Cookie(accessTokenCookie); err == nil && token != "" {
+		return token, true
+	}
+
+	authHeader := c.GetHeader("Authorization")
+	if authHeader == "" {
+		return "", false
+	}
+
+	// Extract Bearer token
+	parts := strings.SplitN(authHeader, " ", 2)
+	if len(parts) != 2 || parts[0] != "Bearer" {
+		return "", false
+	}
+
+	return parts[1], true
+}
+
 // handleLogin handles POST /api/v1/auth/login
 func (s *Server) handleLogin(c *gin.Context) {
 	var req service.LoginRequest
@@ -20,15 +134,28 @@ func (s *Server) handleLogin(c *gin.Context) {
 		})
 		return
 	}
+	req.Client = clientInfo(c)
 
-	authService := service.NewAuthService(
-		repository.NewUserRepository(s.db.DB),
-		s.cfg.Auth.JWTSecret,
-		s.cfg.Auth.JWTExpiresIn,
-	)
+	// Logins of an email from a client IP are locked out for longer with every failure.
+	// Keying on the IP as well keeps others from locking the owner of the email out.
+	lockout := s.loginLockout()
+	lockoutKey := "login:" + c.ClientIP() + ":" + strings.ToLower(strings.TrimSpace(req.Email))
+	if lockout != nil {
+		if wait, err := lockout.Check(c.Request.Context(), lockoutKey, time.Now()); err != nil {
+			log.Printf("WARNING: Failed to check login lockout: %v", err)
+		} else if wait > 0 {
+			abortTooManyRequests(c, wait, "too_many_attempts", "Too many failed login attempts, please retry later")
+			return
+		}
+	}
 
-	response, err := authService.Login(c.Request.Context(), &req)
+	response, err := s.authService().Login(c.Request.Context(), &req)
 	if err != nil {
+		if lockout != nil {
+			if _, lockErr := lockout.Fail(c.Request.Context(), lockoutKey, time.Now()); lockErr != nil {
+				log.Printf("WARNING: Failed to record failed login: %v", lockErr)
+			}
+		}
 		c.JSON(http.StatusUnauthorized, gin.H{
 			"error":   "authentication_failed",
 			"message": err.Error(),
@@ -36,16 +163,71 @@ func (s *Server) handleLogin(c *gin.Context) {
 		return
 	}
 
-	// Set cookie
-	c.SetCookie(
-		"token",
-		response.Token,
-		int(s.cfg.Auth.JWTExpiresIn.Seconds()),
-		"/",
-		"",
-		false, // secure (set to true in production with HTTPS)
-		true,  // httpOnly
-	)
+	if lockout != nil {
+		if err := lockout.Succeed(c.Request.Context(), lockoutKey); err != nil {
+			log.Printf("WARNING: Failed to reset login lockout: %v", err)
+		}
+	}
+
+	// The session starts once the second factor has been sent to /auth/login/2fa
+	if response.TwoFactorRequired {
+		c.JSON(http.StatusOK, gin.H{
+			"data": response,
+		})
+		return
+	}
+
+	s.setSessionCookies(c, response)
+
+	c.JSON(http.StatusOK, gin.H{
+		"data": response,
+	})
+}
+
+// RefreshRequest represents a request to refresh an access token.
+// The refresh token can also be sent in its cookie.
+type RefreshRequest struct {
+	RefreshToken string `json:"refresh_token"`
+}
+
+// handleRefresh handles POST /api/v1/auth/refresh
+func (s *Server) handleRefresh(c *gin.Context) {
+	var req RefreshRequest
+	if c.Request.ContentLength > 0 {
+		if err := c.ShouldBindJSON(&req); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{
+				"error":   "invalid_request",
+				"message": "Invalid request body",
+				"details": err.Error(),
+			})
+			return
+		}
+	}
+	if req.RefreshToken == "" {
+		req.RefreshToken, _ = c.Cookie(refreshTokenCookie)
+	}
+	if req.RefreshToken == "" {
+		c.JSON(http.StatusUnauthorized, gin.H{
+			"error":   "unauthorized",
+			"message": "Refresh token required",
+		})
+		return
+	}
+
+	response, err := s.authService().Refresh(c.Request.Context(), req.RefreshToken)
+	if err != nil {
+		if !errors.Is(err, service.ErrInvalidRefreshToken) {
+			log.Printf("ERROR: Failed to refresh session: %v", err)
+		}
+		clearSessionCookies(c)
+		c.JSON(http.StatusUnauthorized, gin.H{
+			"error":   "invalid_refresh_token",
+			"message": err.Error(),
+		})
+		return
+	}
+
+	s.setSessionCookies(c, response)
 
 	c.JSON(http.StatusOK, gin.H{
 		"data": response,
@@ -53,23 +235,115 @@ func (s *Server) handleLogin(c *gin.Context) {
 }
 
 // handleLogout handles POST /api/v1/auth/logout
+// It revokes the session of the access token, or of the refresh token when the access token has expired.
 func (s *Server) handleLogout(c *gin.Context) {
-	// Clear cookie
-	c.SetCookie(
-		"token",
-		"",
-		-1,
-		"/",
-		"",
-		false,
-		true,
-	)
+	authService := s.authService()
+
+	if sessionID, exists := c.Get("session_id"); exists {
+		if err := authService.Logout(c.Request.Context(), c.GetString("user_id"), sessionID.(string)); err != nil && !errors.Is(err, repository.ErrSessionNotFound) {
+			log.Printf("ERROR: Failed to revoke session %s: %v", sessionID, err)
+		}
+		s.revokeWebSockets(func(client *ws.Client) bool { return client.SessionID == sessionID }, closeSessionRevoked)
+	} else {
+		var req RefreshRequest
+		if c.Request.ContentLength > 0 {
+			_ = c.ShouldBindJSON(&req)
+		}
+		if req.RefreshToken == "" {
+			req.RefreshToken, _ = c.Cookie(refreshTokenCookie)
+		}
+		if req.RefreshToken != "" {
+			_ = authService.LogoutByRefreshToken(c.Request.Context(), req.RefreshToken)
+		}
+	}
+
+	// Clear cookies
+	clearSessionCookies(c)
 
 	c.JSON(http.StatusOK, gin.H{
 		"message": "Logged out successfully",
 	})
 }
 
+// handleLogoutEverywhere handles POST /api/v1/auth/logout-all
+func (s *Server) handleLogoutEverywhere(c *gin.Context) {
+	userID, ok := currentUserID(c)
+	if !ok || !requireSession(c) {
+		return
+	}
+
+	revoked, err := s.authService().LogoutEverywhere(c.Request.Context(), userID)
+	if err != nil {
+		log.Printf("ERROR: Failed to revoke sessions of user %s: %v", userID, err)
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error":   "internal_server_error",
+			"message": "Failed to log out",
+			"details": err.Error(),
+		})
+		return
+	}
+
+	clearSessionCookies(c)
+
+	// Connections opened with access tokens are not sessions and stay open
+	s.revokeWebSockets(func(client *ws.Client) bool {
+		return client.UserID == userID && client.SessionID != ""
+	}, closeSessionRevoked)
+
+	c.JSON(http.StatusOK, gin.H{
+		"data": gin.H{"revoked": revoked},
+	})
+}
+
+// handleListSessions handles GET /api/v1/me/sessions
+func (s *Server) handleListSessions(c *gin.Context) {
+	userID, ok := currentUserID(c)
+	if !ok {
+		return
+	}
+
+	sessions, err := s.authService().ListSessions(c.Request.Context(), userID, c.GetString("session_id"))
+	if err != nil {
+		log.Printf("ERROR: Failed to list sessions of user %s: %v", userID, err)
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error":   "internal_server_error",
+			"message": "Failed to list sessions",
+			"details": err.Error(),
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"data": sessions,
+	})
+}
+
+// handleRevokeSession handles DELETE /api/v1/me/sessions/:sessionId
+func (s *Server) handleRevokeSession(c *gin.Context) {
+	userID, ok := currentUserID(c)
+	if !ok || !requireSession(c) {
+		return
+	}
+	sessionID := c.Param("sessionId")
+
+	if err := s.authService().Logout(c.Request.Context(), userID, sessionID); err != nil {
+		c.JSON(http.StatusNotFound, gin.H{
+			"error":   "not_found",
+			"message": "Session not found",
+			"details": err.Error(),
+		})
+		return
+	}
+
+	s.revokeWebSockets(func(client *ws.Client) bool {
+		return client.UserID == userID && client.SessionID == sessionID
+	}, closeSessionRevoked)
+
+	c.JSON(http.StatusOK, gin.H{
+		"message": "Session revoked",
+	})
+}
+
 // handleGetCurrentUser handles GET /api/v1/auth/me
 func (s *Server) handleGetCurrentUser(c *gin.Context) {
 	// Get user from context (set by auth middleware)
@@ -82,13 +356,7 @@ func (s *Server) handleGetCurrentUser(c *gin.Context) {
 		return
 	}
 
-	authService := service.NewAuthService(
-		repository.NewUserRepository(s.db.DB),
-		s.cfg.Auth.JWTSecret,
-		s.cfg.Auth.JWTExpiresIn,
-	)
-
-	user, err := authService.GetUserByID(c.Request.Context(), userID.(string))
+	user, err := s.authService().GetUserByID(c.Request.Context(), userID.(string))
 	if err != nil {
 		c.JSON(http.StatusNotFound, gin.H{
 			"error":   "user_not_found",
@@ -113,14 +381,9 @@ func (s *Server) handleRegister(c *gin.Context) {
 		})
 		return
 	}
+	req.Client = clientInfo(c)
 
-	authService := service.NewAuthService(
-		repository.NewUserRepository(s.db.DB),
-		s.cfg.Auth.JWTSecret,
-		s.cfg.Auth.JWTExpiresIn,
-	)
-
-	response, err := authService.Register(c.Request.Context(), &req)
+	response, err := s.authService().Register(c.Request.Context(), &req)
 	if err != nil {
 		c.JSON(http.StatusBadRequest, gin.H{
 			"error":   "registration_failed",
@@ -129,61 +392,95 @@ func (s *Server) handleRegister(c *gin.Context) {
 		return
 	}
 
-	// Set cookie
-	c.SetCookie(
-		"token",
-		response.Token,
-		int(s.cfg.Auth.JWTExpiresIn.Seconds()),
-		"/",
-		"",
-		false, // secure
-		true,  // httpOnly
-	)
+	if err := s.accountService().SendVerification(c.Request.Context(), response.User); err != nil {
+		log.Printf("ERROR: Failed to send verification email to user %s: %v", response.User.ID, err)
+	}
+
+	s.setSessionCookies(c, response)
 
 	c.JSON(http.StatusCreated, gin.H{
 		"data": response,
 	})
 }
 
-// AuthMiddleware validates JWT tokens
+// authenticate resolves the JWT or access token of a request and sets the user in the context.
+// Access tokens are also checked against the method and project of the request.
+func (s *Server) authenticate(c *gin.Context, token string) error {
+	ctx := c.Request.Context()
+
+	if service.IsAccessToken(token) {
+		user, accessToken, err := s.accessTokenService().Authenticate(ctx, token)
+		if err != nil {
+			return err
+		}
+		if err := service.CheckAccessTokenScope(accessToken, c.Request.Method, c.Param("projectId")); err != nil {
+			return err
+		}
+
+		c.Set("user_id", user.ID)
+		c.Set("user_email", user.Email)
+		c.Set("user", user)
+		c.Set("access_token", accessToken)
+		return nil
+	}
+
+	// Validate token and its session
+	user, claims, err := s.authService().Authenticate(ctx, token)
+	if err != nil {
+		return err
+	}
+
+	c.Set("user_id", user.ID)
+	c.Set("user_email", user.Email)
+	c.Set("user", user)
+	c.Set("session_id", claims.SessionID)
+	return nil
+}
+
+// logoutAuth identifies the session being logged out when it can. Unlike
+// OptionalAuthMiddleware it ignores expired or revoked credentials, so that handleLogout
+// can still revoke the session by its refresh token and clear the cookies.
+func (s *Server) logoutAuth(c *gin.Context) {
+	if token, ok := requestToken(c); ok {
+		_ = s.authenticate(c, token)
+	}
+
+	c.Next()
+}
+
+// abortOutOfScope rejects a request made with an access token outside its scope
+func abortOutOfScope(c *gin.Context, err error) bool {
+	if !errors.Is(err, service.ErrAccessTokenReadOnly) && !errors.Is(err, service.ErrAccessTokenOtherProject) {
+		return false
+	}
+
+	c.AbortWithStatusJSON(http.StatusForbidden, gin.H{
+		"error":   "insufficient_scope",
+		"message": err.Error(),
+	})
+	return true
+}
+
+// AuthMiddleware validates JWTs and access tokens
 func (s *Server) AuthMiddleware() gin.HandlerFunc {
 	return func(c *gin.Context) {
-		// Try to get token from cookie first
-		token, err := c.Cookie("token")
-
-		// If not in cookie, try Authorization header
-		if err != nil || token == "" {
-			authHeader := c.GetHeader("Authorization")
-			if authHeader == "" {
-				c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{
-					"error":   "unauthorized",
-					"message": "Authentication required",
-				})
-				return
+		token, ok := requestToken(c)
+		if !ok {
+			message := "Authentication required"
+			if c.GetHeader("Authorization") != "" {
+				message = "Invalid authorization header format"
 			}
+			c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{
+				"error":   "unauthorized",
+				"message": message,
+			})
+			return
+		}
 
-			// Extract Bearer token
-			parts := strings.SplitN(authHeader, " ", 2)
-			if len(parts) != 2 || parts[0] != "Bearer" {
-				c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{
-					"error":   "unauthorized",
-					"message": "Invalid authorization header format",
-				})
+		if err := s.authenticate(c, token); err != nil {
+			if abortOutOfScope(c, err) {
 				return
 			}
-
-			token = parts[1]
-		}
-
-		// Validate token
-		authService := service.NewAuthService(
-			repository.NewUserRepository(s.db.DB),
-			s.cfg.Auth.JWTSecret,
-			s.cfg.Auth.JWTExpiresIn,
-		)
-
-		user, err := authService.ValidateToken(token)
-		if err != nil {
 			c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{
 				"error":   "unauthorized",
 				"message": "Invalid or expired token",
@@ -191,45 +488,37 @@ func (s *Server) AuthMiddleware() gin.HandlerFunc {
 			return
 		}
 
-		// Set user in context
-		c.Set("user_id", user.ID)
-		c.Set("user_email", user.Email)
-		c.Set("user", user)
-
 		c.Next()
 	}
 }
 
-// OptionalAuthMiddleware validates JWT tokens but doesn't require them
+// OptionalAuthMiddleware validates JWTs and access tokens but doesn't require them.
+// Requests without credentials continue anonymously; credentials that are present but
+// malformed, invalid, revoked or used outside their scope are rejected rather than ignored.
 func (s *Server) OptionalAuthMiddleware() gin.HandlerFunc {
 	return func(c *gin.Context) {
-		// Try to get token
-		token, _ := c.Cookie("token")
-
-		if token == "" {
-			authHeader := c.GetHeader("Authorization")
-			if authHeader != "" {
-				parts := strings.SplitN(authHeader, " ", 2)
-				if len(parts) == 2 && parts[0] == "Bearer" {
-					token = parts[1]
-				}
+		token, ok := requestToken(c)
+		if !ok {
+			if c.GetHeader("Authorization") != "" {
+				c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{
+					"error":   "unauthorized",
+					"message": "Invalid authorization header format",
+				})
+				return
 			}
+			c.Next()
+			return
 		}
 
-		// If token exists, validate it
-		if token != "" {
-			authService := service.NewAuthService(
-				repository.NewUserRepository(s.db.DB),
-				s.cfg.Auth.JWTSecret,
-				s.cfg.Auth.JWTExpiresIn,
-			)
-
-			user, err := authService.ValidateToken(token)
-			if err == nil {
-				c.Set("user_id", user.ID)
-				c.Set("user_email", user.Email)
-				c.Set("user", user)
+		if err := s.authenticate(c, token); err != nil {
+			if abortOutOfScope(c, err) {
+				return
 			}
+			c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{
+				"error":   "unauthorized",
+				"message": "Invalid or expired token",
+			})
+			return
 		}
 
 		c.Next()
@@ -0,0 +1,126 @@
+package api
+
+import (
+	"context"
+	"log"
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/tktomaru/taskai/taskai-server/internal/calendar"
+	"github.com/tktomaru/taskai/taskai-server/internal/repository"
+	"github.com/tktomaru/taskai/taskai-server/internal/service"
+)
+
+// projectCalendar loads the working-day calendar of a project.
+// Falls back to the default calendar so that a broken setting never blocks task edits.
+func (s *Server) projectCalendar(ctx context.Context, projectID string) *calendar.Calendar {
+	projectService := service.NewProjectService(repository.NewProjectRepository(s.db.DB))
+	cal, err := projectService.GetCalendar(ctx, projectID)
+	if err != nil {
+		log.Printf("WARNING: Failed to load calendar for project %s: %v", projectID, err)
+		return calendar.Default()
+	}
+	return cal
+}
+
+// handleGetCalendar handles GET /api/v1/projects/:projectId/calendar
+func (s *Server) handleGetCalendar(c *gin.Context) {
+	projectID := c.Param("projectId")
+
+	projectService := service.NewProjectService(repository.NewProjectRepository(s.db.DB))
+	cal, err := projectService.GetCalendar(c.Request.Context(), projectID)
+	if err != nil {
+		log.Printf("ERROR: Failed to get calendar for project %s: %v", projectID, err)
+		c.JSON(http.StatusNotFound, gin.H{
+			"error":   "not_found",
+			"message": "Project not found",
+			"details": err.Error(),
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"data": cal.ToSettings(),
+	})
+}
+
+// handleUpdateCalendar handles PUT /api/v1/projects/:projectId/calendar
+func (s *Server) handleUpdateCalendar(c *gin.Context) {
+	projectID := c.Param("projectId")
+
+	var req calendar.Settings
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error":   "invalid_request",
+			"message": "Invalid request body",
+			"details": err.Error(),
+		})
+		return
+	}
+
+	projectService := service.NewProjectService(repository.NewProjectRepository(s.db.DB))
+	cal, err := projectService.UpdateCalendar(c.Request.Context(), projectID, &req)
+	if err != nil {
+		log.Printf("ERROR: Failed to update calendar for project %s: %v", projectID, err)
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error":   "validation_error",
+			"message": "Failed to update calendar",
+			"details": err.Error(),
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"data": cal.ToSettings(),
+	})
+}
+
+// handleImportHolidays handles POST /api/v1/projects/:projectId/calendar/holidays/import
+// The request body is an iCalendar (.ics) document. Pass ?replace=true to drop existing holidays.
+func (s *Server) handleImportHolidays(c *gin.Context) {
+	projectID := c.Param("projectId")
+	replace := c.Query("replace") == "true"
+
+	projectService := service.NewProjectService(repository.NewProjectRepository(s.db.DB))
+	cal, imported, err := projectService.ImportHolidays(c.Request.Context(), projectID, c.Request.Body, replace)
+	if err != nil {
+		log.Printf("ERROR: Failed to import holidays for project %s: %v", projectID, err)
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error":   "validation_error",
+			"message": "Failed to import holidays",
+			"details": err.Error(),
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"data": gin.H{
+			"imported_count": imported,
+			"calendar":       cal.ToSettings(),
+		},
+	})
+}
+
+// handleGetTaskSchedule handles GET /api/v1/projects/:projectId/tasks/:taskId/schedule
+func (s *Server) handleGetTaskSchedule(c *gin.Context) {
+	projectID := c.Param("projectId")
+	taskID := c.Param("taskId")
+
+	taskService := service.NewTaskService(repository.NewTaskRepository(s.db.DB))
+	taskService.SetCalendar(s.projectCalendar(c.Request.Context(), projectID))
+
+	task, err := taskService.GetByID(c.Request.Context(), projectID, taskID)
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{
+			"error":   "not_found",
+			"message": "Task not found",
+			"details": err.Error(),
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"data": taskService.Schedule(task, time.Now()),
+	})
+}
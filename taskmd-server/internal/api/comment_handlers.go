@@ -0,0 +1,78 @@
+package api
+
+import (
+	"log"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/tktomaru/taskai/taskai-server/internal/repository"
+	"github.com/tktomaru/taskai/taskai-server/internal/service"
+)
+
+// commentService creates a comment service with notifications enabled
+func (s *Server) commentService() *service.CommentService {
+	commentService := service.NewCommentService(
+		repository.NewTaskRepository(s.db.DB),
+		repository.NewCommentRepository(s.db.DB),
+	)
+	commentService.SetNotifications(s.notificationService())
+
+	return commentService
+}
+
+// handleListComments handles GET /api/v1/projects/:projectId/tasks/:taskId/comments
+func (s *Server) handleListComments(c *gin.Context) {
+	projectID := c.Param("projectId")
+	taskID := c.Param("taskId")
+
+	comments, err := s.commentService().List(c.Request.Context(), projectID, taskID)
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{
+			"error":   "not_found",
+			"message": "Task not found",
+			"details": err.Error(),
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"data": comments,
+	})
+}
+
+// handleCreateComment handles POST /api/v1/projects/:projectId/tasks/:taskId/comments
+func (s *Server) handleCreateComment(c *gin.Context) {
+	projectID := c.Param("projectId")
+	taskID := c.Param("taskId")
+
+	var req service.CreateCommentRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error":   "invalid_request",
+			"message": "Invalid request body",
+			"details": err.Error(),
+		})
+		return
+	}
+
+	// Comments by unauthenticated clients are attributed to the system user
+	req.AuthorUserID = "system"
+	if userID, exists := c.Get("user_id"); exists {
+		req.AuthorUserID = userID.(string)
+	}
+
+	comment, err := s.commentService().Create(c.Request.Context(), projectID, taskID, &req)
+	if err != nil {
+		log.Printf("ERROR: Failed to comment on task %s in project %s: %v", taskID, projectID, err)
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error":   "validation_error",
+			"message": "Failed to create comment",
+			"details": err.Error(),
+		})
+		return
+	}
+
+	c.JSON(http.StatusCreated, gin.H{
+		"data": comment,
+	})
+}
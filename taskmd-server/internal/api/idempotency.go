@@ -0,0 +1,146 @@
+package api
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"io"
+	"log"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/tktomaru/taskai/taskai-server/internal/repository"
+	"github.com/tktomaru/taskai/taskai-server/internal/service"
+)
+
+// Idempotency headers
+const (
+	HeaderIdempotencyKey      = "Idempotency-Key"
+	HeaderIdempotencyReplayed = "Idempotent-Replayed"
+)
+
+// idempotencyService creates an idempotency service using the configured replay window
+func (s *Server) idempotencyService() *service.IdempotencyService {
+	return service.NewIdempotencyService(
+		repository.NewIdempotencyRepository(s.db.DB),
+		s.cfg.Idempotency.TTL,
+	)
+}
+
+// responseRecorder keeps a copy of the response body written by a handler
+type responseRecorder struct {
+	gin.ResponseWriter
+	body bytes.Buffer
+}
+
+func (w *responseRecorder) Write(data []byte) (int, error) {
+	w.body.Write(data)
+	return w.ResponseWriter.Write(data)
+}
+
+func (w *responseRecorder) WriteString(data string) (int, error) {
+	w.body.WriteString(data)
+	return w.ResponseWriter.WriteString(data)
+}
+
+// IdempotencyMiddleware honors the Idempotency-Key header. The response to the first request
+// with a key is stored and returned again for retries within the replay window, without
+// running the handler. Requests without the header are processed as usual.
+func (s *Server) IdempotencyMiddleware() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		key := c.GetHeader(HeaderIdempotencyKey)
+		if key == "" {
+			c.Next()
+			return
+		}
+
+		if err := service.ValidateIdempotencyKey(key); err != nil {
+			c.AbortWithStatusJSON(http.StatusBadRequest, gin.H{
+				"error":   "invalid_request",
+				"message": "Invalid Idempotency-Key header",
+				"details": err.Error(),
+			})
+			return
+		}
+
+		body, err := io.ReadAll(c.Request.Body)
+		if err != nil {
+			c.AbortWithStatusJSON(http.StatusBadRequest, gin.H{
+				"error":   "invalid_request",
+				"message": "Failed to read request body",
+				"details": err.Error(),
+			})
+			return
+		}
+		c.Request.Body = io.NopCloser(bytes.NewReader(body))
+
+		userID, _ := c.Get("user_id")
+		caller, _ := userID.(string)
+		scope := service.IdempotencyScope(c.Request.Method, c.Request.URL.Path, service.IdempotencyCaller(caller, c.ClientIP()))
+		request := append([]byte(c.Request.URL.RawQuery+"\n"), body...)
+
+		idempotencyService := s.idempotencyService()
+		record, err := idempotencyService.Begin(c.Request.Context(), scope, key, request)
+		switch {
+		case errors.Is(err, service.ErrIdempotencyInProgress):
+			c.AbortWithStatusJSON(http.StatusConflict, gin.H{
+				"error":   "idempotency_conflict",
+				"message": "A request with this Idempotency-Key is still being processed",
+			})
+			return
+		case errors.Is(err, service.ErrIdempotencyKeyReused):
+			c.AbortWithStatusJSON(http.StatusUnprocessableEntity, gin.H{
+				"error":   "idempotency_key_reused",
+				"message": "Idempotency-Key was already used for a different request",
+			})
+			return
+		case err != nil:
+			log.Printf("ERROR: Failed to check idempotency key for %s: %v", scope, err)
+			c.AbortWithStatusJSON(http.StatusInternalServerError, gin.H{
+				"error":   "internal_server_error",
+				"message": "Failed to check Idempotency-Key",
+				"details": err.Error(),
+			})
+			return
+		}
+
+		// Replay the stored response
+		if record != nil {
+			contentType := "application/json; charset=utf-8"
+			if record.ContentType != nil {
+				contentType = *record.ContentType
+			}
+			c.Header(HeaderIdempotencyReplayed, "true")
+			c.Data(*record.StatusCode, contentType, record.ResponseBody)
+			c.Abort()
+			return
+		}
+
+		recorder := &responseRecorder{ResponseWriter: c.Writer}
+		c.Writer = recorder
+
+		// The response is stored even if the client has gone away, since that is when it retries
+		stored := false
+		defer func() {
+			if !stored {
+				if err := idempotencyService.Release(context.Background(), scope, key); err != nil {
+					log.Printf("ERROR: Failed to release idempotency key for %s: %v", scope, err)
+				}
+			}
+		}()
+
+		c.Next()
+
+		// Server errors are not stored so that the request can be retried
+		status := recorder.Status()
+		if status >= http.StatusInternalServerError {
+			return
+		}
+
+		if err := idempotencyService.Complete(context.Background(), scope, key, status, recorder.Header().Get("Content-Type"), recorder.body.Bytes()); err != nil {
+			log.Printf("ERROR: Failed to store idempotent response for %s: %v", scope, err)
+			return
+		}
+		stored = true
+	}
+}
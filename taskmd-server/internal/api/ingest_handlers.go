@@ -0,0 +1,207 @@
+package api
+
+import (
+	"context"
+	"errors"
+	"io"
+	"log"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/tktomaru/taskai/taskai-server/internal/repository"
+	"github.com/tktomaru/taskai/taskai-server/internal/service"
+)
+
+// maxIngestBody bounds the size of an ingested event
+const maxIngestBody = 1 << 20
+
+// ingestService creates an ingestion service whose tasks are created like those of handleCreateTask
+func (s *Server) ingestService() *service.IngestService {
+	ingestService := service.NewIngestService(
+		repository.NewIngestRepository(s.db.DB),
+		repository.NewTaskRepository(s.db.DB),
+		s.templateService(),
+		s.commentService(),
+	)
+	ingestService.TasksFor = func(ctx context.Context, projectID string) *service.TaskService {
+		taskService := service.NewTaskService(repository.NewTaskRepository(s.db.DB))
+		taskService.SetCalendar(s.projectCalendar(ctx, projectID))
+		taskService.SetRecurrence(s.recurrenceService())
+		taskService.SetReferences(service.NewReferenceService(s.db))
+		taskService.SetNotifications(s.notificationService())
+		return taskService
+	}
+
+	return ingestService
+}
+
+// handleIngest handles POST /api/v1/ingest/:token
+// JSON bodies are mapped onto the token's template; any other body is read as task Markdown.
+func (s *Server) handleIngest(c *gin.Context) {
+	ctx := c.Request.Context()
+
+	ingestService := s.ingestService()
+
+	token, err := ingestService.Authenticate(ctx, c.Param("token"))
+	if err != nil {
+		c.JSON(http.StatusUnauthorized, gin.H{
+			"error":   "unauthorized",
+			"message": "Invalid ingestion token",
+		})
+		return
+	}
+
+	body, err := io.ReadAll(http.MaxBytesReader(c.Writer, c.Request.Body, maxIngestBody))
+	if err != nil {
+		c.JSON(http.StatusRequestEntityTooLarge, gin.H{
+			"error":   "invalid_request",
+			"message": "Failed to read request body",
+			"details": err.Error(),
+		})
+		return
+	}
+
+	var result *service.IngestResult
+	if c.ContentType() == "application/json" {
+		result, err = ingestService.IngestJSON(ctx, token, body)
+	} else {
+		result, err = ingestService.IngestMarkdown(ctx, token, string(body))
+	}
+	if err != nil {
+		log.Printf("ERROR: Failed to ingest event with token %s in project %s: %v", token.ID, token.ProjectID, err)
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error":   "validation_error",
+			"message": "Failed to ingest event",
+			"details": err.Error(),
+		})
+		return
+	}
+
+	if result.Result == service.IngestResultCommented {
+		c.JSON(http.StatusOK, gin.H{
+			"data": result,
+		})
+		return
+	}
+
+	s.publishTaskCreated(result.Task)
+
+	c.JSON(http.StatusCreated, gin.H{
+		"data": result,
+	})
+}
+
+// handleListIngestTokens handles GET /api/v1/projects/:projectId/ingest-tokens
+func (s *Server) handleListIngestTokens(c *gin.Context) {
+	projectID := c.Param("projectId")
+
+	tokens, err := s.ingestService().ListTokens(c.Request.Context(), projectID)
+	if err != nil {
+		log.Printf("ERROR: Failed to list ingestion tokens for project %s: %v", projectID, err)
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error":   "internal_server_error",
+			"message": "Failed to list ingestion tokens",
+			"details": err.Error(),
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"data": tokens,
+	})
+}
+
+// handleCreateIngestToken handles POST /api/v1/projects/:projectId/ingest-tokens
+func (s *Server) handleCreateIngestToken(c *gin.Context) {
+	projectID := c.Param("projectId")
+
+	var req service.CreateIngestTokenRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error":   "invalid_request",
+			"message": "Invalid request body",
+			"details": err.Error(),
+		})
+		return
+	}
+
+	userID, ok := currentUserID(c)
+	if !ok {
+		return
+	}
+	req.CreatedBy = userID
+
+	token, err := s.ingestService().CreateToken(c.Request.Context(), projectID, &req)
+	if err != nil {
+		log.Printf("ERROR: Failed to create ingestion token for project %s: %v", projectID, err)
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error":   "validation_error",
+			"message": "Failed to create ingestion token",
+			"details": err.Error(),
+		})
+		return
+	}
+
+	// The token is only returned here
+	c.JSON(http.StatusCreated, gin.H{
+		"data": token,
+	})
+}
+
+// handleUpdateIngestToken handles PUT /api/v1/projects/:projectId/ingest-tokens/:tokenId
+func (s *Server) handleUpdateIngestToken(c *gin.Context) {
+	projectID := c.Param("projectId")
+	tokenID := c.Param("tokenId")
+
+	var req service.UpdateIngestTokenRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error":   "invalid_request",
+			"message": "Invalid request body",
+			"details": err.Error(),
+		})
+		return
+	}
+
+	token, err := s.ingestService().UpdateToken(c.Request.Context(), projectID, tokenID, &req)
+	if err != nil {
+		log.Printf("ERROR: Failed to update ingestion token %s in project %s: %v", tokenID, projectID, err)
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error":   "validation_error",
+			"message": "Failed to update ingestion token",
+			"details": err.Error(),
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"data": token,
+	})
+}
+
+// handleDeleteIngestToken handles DELETE /api/v1/projects/:projectId/ingest-tokens/:tokenId
+func (s *Server) handleDeleteIngestToken(c *gin.Context) {
+	projectID := c.Param("projectId")
+	tokenID := c.Param("tokenId")
+
+	if err := s.ingestService().DeleteToken(c.Request.Context(), projectID, tokenID); err != nil {
+		if errors.Is(err, repository.ErrIngestTokenNotFound) {
+			c.JSON(http.StatusNotFound, gin.H{
+				"error":   "not_found",
+				"message": "Ingestion token not found",
+			})
+			return
+		}
+		log.Printf("ERROR: Failed to delete ingestion token %s in project %s: %v", tokenID, projectID, err)
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error":   "internal_server_error",
+			"message": "Failed to delete ingestion token",
+			"details": err.Error(),
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"message": "Ingestion token revoked",
+	})
+}
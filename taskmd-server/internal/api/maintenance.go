@@ -0,0 +1,49 @@
+package api
+
+import (
+	"context"
+	"log"
+	"time"
+
+	"github.com/tktomaru/taskai/taskai-server/internal/repository"
+)
+
+// StartMaintenance runs the periodic maintenance jobs until the context is cancelled.
+// Unlike the recurring task scheduler it cannot be disabled: each tick re-evaluates watched
+// views, whose results can change as time passes, compiles notification digests older than
+// digestInterval and removes expired idempotency keys, sessions, account tokens and
+// WebSocket tickets.
+func (s *Server) StartMaintenance(ctx context.Context, interval, digestInterval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		notificationService := s.notificationService()
+		if err := s.viewWatchService(notificationService).RefreshAll(ctx); err != nil {
+			log.Printf("ERROR: Failed to refresh watched views: %v", err)
+		}
+		if digests, err := notificationService.CompileDigests(ctx, time.Now(), digestInterval); err != nil {
+			log.Printf("ERROR: Failed to compile notification digests: %v", err)
+		} else if digests > 0 {
+			log.Printf("Maintenance compiled %d notification digest(s)", digests)
+		}
+		if _, err := s.idempotencyService().Cleanup(ctx, time.Now()); err != nil {
+			log.Printf("ERROR: Failed to clean up idempotency keys: %v", err)
+		}
+		if _, err := repository.NewSessionRepository(s.db.DB).DeleteExpired(ctx, time.Now()); err != nil {
+			log.Printf("ERROR: Failed to clean up sessions: %v", err)
+		}
+		if _, err := s.accountService().Cleanup(ctx, time.Now()); err != nil {
+			log.Printf("ERROR: Failed to clean up account tokens: %v", err)
+		}
+		if _, err := s.realtimeService().Cleanup(ctx, time.Now()); err != nil {
+			log.Printf("ERROR: Failed to clean up WebSocket tickets: %v", err)
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
@@ -2,10 +2,16 @@ package api
 
 import (
 	"fmt"
+	"log"
+	"net/http"
+	"strconv"
 	"time"
 
 	"github.com/gin-gonic/gin"
+	"github.com/tktomaru/taskai/taskai-server/internal/auth"
 	"github.com/tktomaru/taskai/taskai-server/internal/config"
+	"github.com/tktomaru/taskai/taskai-server/internal/models"
+	"github.com/tktomaru/taskai/taskai-server/internal/ratelimit"
 )
 
 // LoggerMiddleware logs HTTP requests
@@ -72,8 +78,9 @@ func CORSMiddleware(cfg *config.Config) gin.HandlerFunc {
 			}
 
 			c.Writer.Header().Set("Access-Control-Allow-Credentials", "true")
-			c.Writer.Header().Set("Access-Control-Allow-Headers", "Content-Type, Content-Length, Accept-Encoding, X-CSRF-Token, Authorization, accept, origin, Cache-Control, X-Requested-With")
+			c.Writer.Header().Set("Access-Control-Allow-Headers", "Content-Type, Content-Length, Accept-Encoding, X-CSRF-Token, Authorization, Idempotency-Key, accept, origin, Cache-Control, X-Requested-With")
 			c.Writer.Header().Set("Access-Control-Allow-Methods", "POST, OPTIONS, GET, PUT, DELETE, PATCH")
+			c.Writer.Header().Set("Access-Control-Expose-Headers", "RateLimit-Limit, RateLimit-Remaining, RateLimit-Reset, RateLimit-Policy, Retry-After, Idempotent-Replayed")
 			c.Writer.Header().Set("Access-Control-Max-Age", "86400")
 		}
 
@@ -95,10 +102,76 @@ func AuthMiddleware() gin.HandlerFunc {
 	}
 }
 
-// RateLimitMiddleware implements rate limiting (placeholder)
-func RateLimitMiddleware() gin.HandlerFunc {
+// RateLimitMiddleware limits requests with a token bucket per key. Routes sharing a budget
+// name share buckets. Requests are let through when the store fails.
+func (s *Server) RateLimitMiddleware(budget, spec string, key func(c *gin.Context) string) gin.HandlerFunc {
+	// Validated when the configuration is loaded
+	limit, _ := ratelimit.ParseLimit(spec)
+
 	return func(c *gin.Context) {
-		// TODO: Implement rate limiting using Redis or in-memory store
+		if !s.cfg.RateLimit.Enabled {
+			c.Next()
+			return
+		}
+
+		result, err := s.rateLimits.Take(c.Request.Context(), budget+":"+key(c), limit, time.Now())
+		if err != nil {
+			log.Printf("WARNING: Rate limit store failed, allowing request: %v", err)
+			c.Next()
+			return
+		}
+
+		header := c.Writer.Header()
+		header.Set("RateLimit-Limit", strconv.Itoa(result.Limit))
+		header.Set("RateLimit-Remaining", strconv.Itoa(result.Remaining))
+		header.Set("RateLimit-Reset", strconv.Itoa(ceilSeconds(result.Reset)))
+		header.Set("RateLimit-Policy", fmt.Sprintf("%d;w=%d", limit.Requests, ceilSeconds(limit.Per)))
+
+		if !result.Allowed {
+			abortTooManyRequests(c, result.RetryAfter, "rate_limited", "Too many requests, please retry later")
+			return
+		}
+
 		c.Next()
 	}
 }
+
+// clientIPKey keys rate limits by client IP
+func clientIPKey(c *gin.Context) string {
+	return "ip:" + c.ClientIP()
+}
+
+// identityKey keys rate limits by access token, then by user, then by client IP.
+// It must run after the auth middleware.
+func identityKey(c *gin.Context) string {
+	if accessToken, exists := c.Get("access_token"); exists {
+		return "token:" + accessToken.(*models.AccessToken).ID
+	}
+	if userID := c.GetString("user_id"); userID != "" {
+		return "user:" + userID
+	}
+	return clientIPKey(c)
+}
+
+// ingestTokenKey keys rate limits by the ingestion token in the URL
+func ingestTokenKey(c *gin.Context) string {
+	return "token:" + auth.HashToken(c.Param("token"))
+}
+
+// abortTooManyRequests rejects a request with 429 and a Retry-After header
+func abortTooManyRequests(c *gin.Context, retryAfter time.Duration, code, message string) {
+	c.Header("Retry-After", strconv.Itoa(ceilSeconds(retryAfter)))
+	c.AbortWithStatusJSON(http.StatusTooManyRequests, gin.H{
+		"error":   code,
+		"message": message,
+	})
+}
+
+// ceilSeconds rounds a duration up to whole seconds, and to at least one second
+func ceilSeconds(d time.Duration) int {
+	seconds := int((d + time.Second - 1) / time.Second)
+	if seconds < 1 {
+		return 1
+	}
+	return seconds
+}
@@ -0,0 +1,183 @@
+package api
+
+import (
+	"context"
+	"log"
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+	"github.com/tktomaru/taskai/taskai-server/internal/models"
+	"github.com/tktomaru/taskai/taskai-server/internal/repository"
+	"github.com/tktomaru/taskai/taskai-server/internal/service"
+	ws "github.com/tktomaru/taskai/taskai-server/internal/websocket"
+)
+
+// notificationService creates a notification service that delivers new notifications
+// live on the per-user WebSocket channel and alerts the watchers of saved views
+func (s *Server) notificationService() *service.NotificationService {
+	notificationService := service.NewNotificationService(
+		repository.NewNotificationRepository(s.db.DB),
+		repository.NewProjectRepository(s.db.DB),
+		repository.NewSubscriptionRepository(s.db.DB),
+	)
+
+	notificationService.OnCreated = func(notification *models.Notification) {
+		s.wsHub.SendToUser(ws.EventNotificationCreated, notification.UserID, notification)
+	}
+
+	notificationService.SetViewWatch(s.viewWatchService(notificationService))
+	notificationService.QueueViewRefresh = s.viewRefresh.Enqueue
+
+	return notificationService
+}
+
+// viewWatchService creates a view watch service that evaluates views with their project calendar
+func (s *Server) viewWatchService(notificationService *service.NotificationService) *service.ViewWatchService {
+	viewWatch := service.NewViewWatchService(
+		repository.NewViewRepository(s.db.DB),
+		repository.NewSubscriptionRepository(s.db.DB),
+		notificationService,
+	)
+	viewWatch.CalendarFor = s.projectCalendar
+
+	return viewWatch
+}
+
+// StartViewRefresh re-evaluates the watched views of projects whose tasks changed until the context is cancelled
+func (s *Server) StartViewRefresh(ctx context.Context) {
+	s.viewRefresh.Run(ctx)
+}
+
+// currentUserID returns the ID of the authenticated user, or responds with 401
+func currentUserID(c *gin.Context) (string, bool) {
+	userID, exists := c.Get("user_id")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{
+			"error":   "unauthorized",
+			"message": "Authentication required",
+		})
+		return "", false
+	}
+
+	return userID.(string), true
+}
+
+// handleListNotifications handles GET /api/v1/me/notifications
+func (s *Server) handleListNotifications(c *gin.Context) {
+	userID, ok := currentUserID(c)
+	if !ok {
+		return
+	}
+
+	filters := &repository.NotificationFilters{
+		UnreadOnly: c.Query("unread") == "true",
+	}
+	if limit, err := strconv.Atoi(c.Query("limit")); err == nil {
+		filters.Limit = limit
+	}
+	if offset, err := strconv.Atoi(c.Query("offset")); err == nil && offset > 0 {
+		filters.Offset = offset
+	}
+
+	inbox, err := s.notificationService().Inbox(c.Request.Context(), userID, filters)
+	if err != nil {
+		log.Printf("ERROR: Failed to list notifications for user %s: %v", userID, err)
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error":   "internal_server_error",
+			"message": "Failed to list notifications",
+			"details": err.Error(),
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"data": inbox,
+	})
+}
+
+// handleMarkNotificationRead handles POST /api/v1/me/notifications/:notificationId/read
+func (s *Server) handleMarkNotificationRead(c *gin.Context) {
+	s.setNotificationRead(c, true)
+}
+
+// handleMarkNotificationUnread handles POST /api/v1/me/notifications/:notificationId/unread
+func (s *Server) handleMarkNotificationUnread(c *gin.Context) {
+	s.setNotificationRead(c, false)
+}
+
+func (s *Server) setNotificationRead(c *gin.Context, read bool) {
+	userID, ok := currentUserID(c)
+	if !ok {
+		return
+	}
+
+	notificationID, err := strconv.ParseInt(c.Param("notificationId"), 10, 64)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error":   "invalid_request",
+			"message": "Invalid notification ID",
+			"details": err.Error(),
+		})
+		return
+	}
+
+	if err := s.notificationService().SetRead(c.Request.Context(), userID, notificationID, read); err != nil {
+		c.JSON(http.StatusNotFound, gin.H{
+			"error":   "not_found",
+			"message": "Notification not found",
+			"details": err.Error(),
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"data": gin.H{"id": notificationID, "read": read},
+	})
+}
+
+// handleMarkAllNotificationsRead handles POST /api/v1/me/notifications/read-all
+func (s *Server) handleMarkAllNotificationsRead(c *gin.Context) {
+	userID, ok := currentUserID(c)
+	if !ok {
+		return
+	}
+
+	count, err := s.notificationService().MarkAllRead(c.Request.Context(), userID)
+	if err != nil {
+		log.Printf("ERROR: Failed to mark notifications as read for user %s: %v", userID, err)
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error":   "internal_server_error",
+			"message": "Failed to mark notifications as read",
+			"details": err.Error(),
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"data": gin.H{"marked_count": count},
+	})
+}
+
+// handleUserWebSocket handles the per-user WebSocket channel for live notifications
+// GET /api/v1/me/ws
+func (s *Server) handleUserWebSocket(c *gin.Context) {
+	userID, ok := currentUserID(c)
+	if !ok {
+		return
+	}
+
+	conn, err := s.upgrader().Upgrade(c.Writer, c.Request, nil)
+	if err != nil {
+		log.Printf("Failed to upgrade WebSocket: %v", err)
+		return
+	}
+
+	// A client without a project only receives messages addressed to its user
+	client := s.newWebSocketClient(c, conn, "")
+	s.wsHub.Register <- client
+
+	client.Start()
+
+	log.Printf("WebSocket connection established for user %s", userID)
+}
@@ -0,0 +1,156 @@
+package api
+
+import (
+	"context"
+	"log"
+	"net/http"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"github.com/tktomaru/taskai/taskai-server/internal/oidc"
+	"github.com/tktomaru/taskai/taskai-server/internal/repository"
+	"github.com/tktomaru/taskai/taskai-server/internal/service"
+)
+
+// oidcFlowCookie keeps the sealed login flow between the redirect to the issuer and the callback
+const oidcFlowCookie = "oidc_flow"
+
+// oidcService creates an OIDC login service. The issuer is discovered on first use
+// and the client is kept for later requests.
+func (s *Server) oidcService(ctx context.Context) (*service.OIDCService, error) {
+	s.oidcMu.Lock()
+	defer s.oidcMu.Unlock()
+
+	if s.oidcClient == nil {
+		client, err := oidc.NewClient(ctx, oidc.Config{
+			Issuer:       s.cfg.Auth.OIDCIssuer,
+			ClientID:     s.cfg.Auth.OIDCClientID,
+			ClientSecret: s.cfg.Auth.OIDCClientSecret,
+			RedirectURL:  s.cfg.Auth.OIDCRedirectURL,
+			Scopes:       s.cfg.Auth.OIDCScopes,
+			GroupsClaim:  s.cfg.Auth.OIDCGroupsClaim,
+		})
+		if err != nil {
+			return nil, err
+		}
+		s.oidcClient = client
+	}
+
+	// Validated when the configuration is loaded
+	groupRoles, _ := oidc.ParseGroupRoles(s.cfg.Auth.OIDCGroupRoles)
+
+	return service.NewOIDCService(
+		s.oidcClient,
+		repository.NewUserRepository(s.db.DB),
+		repository.NewProjectRepository(s.db.DB),
+		s.authService(),
+		s.cfg.Auth.JWTSecret,
+		groupRoles,
+	), nil
+}
+
+// handleOIDCLogin handles GET /api/v1/auth/oidc/login
+// It redirects the browser to the issuer. ?redirect=/path selects the page shown after login.
+func (s *Server) handleOIDCLogin(c *gin.Context) {
+	if s.cfg.Auth.Mode != "oidc" {
+		c.JSON(http.StatusNotFound, gin.H{
+			"error":   "oidc_disabled",
+			"message": "OIDC login is not enabled",
+		})
+		return
+	}
+
+	oidcService, err := s.oidcService(c.Request.Context())
+	if err != nil {
+		log.Printf("ERROR: Failed to set up OIDC login: %v", err)
+		c.JSON(http.StatusBadGateway, gin.H{
+			"error":   "oidc_unavailable",
+			"message": "Identity provider is unavailable",
+			"details": err.Error(),
+		})
+		return
+	}
+
+	authURL, sealedFlow, err := oidcService.Begin(c.Query("redirect"))
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error":   "internal_server_error",
+			"message": "Failed to start login",
+			"details": err.Error(),
+		})
+		return
+	}
+
+	c.SetCookie(
+		oidcFlowCookie,
+		sealedFlow,
+		int(oidc.FlowTTL.Seconds()),
+		"/api/v1/auth/oidc",
+		"",
+		false, // secure (set to true in production with HTTPS)
+		true,  // httpOnly
+	)
+
+	c.Redirect(http.StatusFound, authURL)
+}
+
+// handleOIDCCallback handles GET /api/v1/auth/oidc/callback
+func (s *Server) handleOIDCCallback(c *gin.Context) {
+	if s.cfg.Auth.Mode != "oidc" {
+		c.JSON(http.StatusNotFound, gin.H{
+			"error":   "oidc_disabled",
+			"message": "OIDC login is not enabled",
+		})
+		return
+	}
+
+	if errCode := c.Query("error"); errCode != "" {
+		c.JSON(http.StatusUnauthorized, gin.H{
+			"error":   "authentication_failed",
+			"message": "Login was rejected by the identity provider",
+			"details": strings.TrimSpace(errCode + ": " + c.Query("error_description")),
+		})
+		return
+	}
+
+	sealedFlow, err := c.Cookie(oidcFlowCookie)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error":   "invalid_request",
+			"message": "Login session expired, please try again",
+		})
+		return
+	}
+
+	// The flow can only be used once
+	c.SetCookie(oidcFlowCookie, "", -1, "/api/v1/auth/oidc", "", false, true)
+
+	oidcService, err := s.oidcService(c.Request.Context())
+	if err != nil {
+		log.Printf("ERROR: Failed to set up OIDC login: %v", err)
+		c.JSON(http.StatusBadGateway, gin.H{
+			"error":   "oidc_unavailable",
+			"message": "Identity provider is unavailable",
+			"details": err.Error(),
+		})
+		return
+	}
+
+	login, err := oidcService.Complete(c.Request.Context(), sealedFlow, c.Query("state"), c.Query("code"), clientInfo(c))
+	if err != nil {
+		log.Printf("WARNING: OIDC login failed: %v", err)
+		c.JSON(http.StatusUnauthorized, gin.H{
+			"error":   "authentication_failed",
+			"message": err.Error(),
+		})
+		return
+	}
+
+	s.setSessionCookies(c, login.AuthResponse)
+
+	redirectTo := login.RedirectTo
+	if redirectTo == "" {
+		redirectTo = "/"
+	}
+	c.Redirect(http.StatusFound, strings.TrimSuffix(s.cfg.Auth.OIDCPostLoginURL, "/")+redirectTo)
+}
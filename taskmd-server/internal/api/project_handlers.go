@@ -1,12 +1,15 @@
 package api
 
 import (
+	"context"
 	"log"
 	"net/http"
 
 	"github.com/gin-gonic/gin"
+	"github.com/tktomaru/taskai/taskai-server/internal/models"
 	"github.com/tktomaru/taskai/taskai-server/internal/repository"
 	"github.com/tktomaru/taskai/taskai-server/internal/service"
+	"github.com/tktomaru/taskai/taskai-server/internal/websocket"
 )
 
 // handleListProjects handles GET /api/v1/projects
@@ -41,7 +44,8 @@ func (s *Server) handleCreateProject(c *gin.Context) {
 		return
 	}
 
-	projectService := service.NewProjectService(repository.NewProjectRepository(s.db.DB))
+	projectRepo := repository.NewProjectRepository(s.db.DB)
+	projectService := service.NewProjectService(projectRepo)
 	project, err := projectService.Create(c.Request.Context(), &req)
 	if err != nil {
 		log.Printf("ERROR: Failed to create project: %v", err)
@@ -53,6 +57,14 @@ func (s *Server) handleCreateProject(c *gin.Context) {
 		return
 	}
 
+	// The creator owns the project, so that it stays visible to them once it is private
+	value, _ := c.Get("user")
+	if user, ok := value.(*models.User); ok && user.ServiceProjectID == nil {
+		if err := projectRepo.GrantMemberRole(c.Request.Context(), project.ID, user.ID, "owner"); err != nil {
+			log.Printf("ERROR: Failed to add creator as owner of project %s: %v", project.ID, err)
+		}
+	}
+
 	c.JSON(http.StatusCreated, gin.H{
 		"data": project,
 	})
@@ -94,6 +106,18 @@ func (s *Server) handleUpdateProject(c *gin.Context) {
 		return
 	}
 
+	// Requiring two-factor authentication must not lock out the user who turns it on
+	if req.RequireTwoFactor != nil && *req.RequireTwoFactor {
+		value, _ := c.Get("user")
+		if user, _ := value.(*models.User); user == nil || user.TOTPEnabledAt == nil {
+			c.JSON(http.StatusForbidden, gin.H{
+				"error":   "two_factor_required",
+				"message": "Enable two-factor authentication for your account before requiring it for the project",
+			})
+			return
+		}
+	}
+
 	projectService := service.NewProjectService(repository.NewProjectRepository(s.db.DB))
 	project, err := projectService.Update(c.Request.Context(), projectID, &req)
 	if err != nil {
@@ -106,6 +130,12 @@ func (s *Server) handleUpdateProject(c *gin.Context) {
 		return
 	}
 
+	// Broadcast WebSocket event
+	s.wsHub.Broadcast(websocket.EventProjectUpdated, projectID, "", project)
+
+	// A change of visibility or of the two-factor requirement can remove access
+	go s.revalidateWebSockets(context.Background(), func(client *websocket.Client) bool { return client.ProjectID == projectID })
+
 	c.JSON(http.StatusOK, gin.H{
 		"data": project,
 	})
@@ -127,5 +157,60 @@ func (s *Server) handleDeleteProject(c *gin.Context) {
 		return
 	}
 
+	s.revokeWebSockets(func(client *websocket.Client) bool { return client.ProjectID == projectID }, closeProjectDeleted)
+
 	c.JSON(http.StatusNoContent, nil)
 }
+
+// requireProjectMaintainer checks that the authenticated user is an owner or maintainer of a
+// project, or responds with 401 or 403
+func (s *Server) requireProjectMaintainer(c *gin.Context, projectID string) bool {
+	userID, ok := currentUserID(c)
+	if !ok {
+		return false
+	}
+
+	role, err := repository.NewProjectRepository(s.db.DB).MemberRole(c.Request.Context(), projectID, userID)
+	if err != nil {
+		log.Printf("ERROR: Failed to get role of user %s in project %s: %v", userID, projectID, err)
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error":   "internal_server_error",
+			"message": "Failed to check project access",
+			"details": err.Error(),
+		})
+		return false
+	}
+
+	if role != "owner" && role != "maintainer" {
+		c.JSON(http.StatusForbidden, gin.H{
+			"error":   "forbidden",
+			"message": "This action requires the owner or maintainer role in the project",
+		})
+		return false
+	}
+
+	return true
+}
+
+// ProjectMaintainerMiddleware limits routes to owners and maintainers of the project in the path
+func (s *Server) ProjectMaintainerMiddleware() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if !s.requireProjectMaintainer(c, c.Param("projectId")) {
+			c.Abort()
+			return
+		}
+
+		c.Next()
+	}
+}
+
+// authorizeProjectWrite returns a check that the caller of a request may change the tasks of a project
+func (s *Server) authorizeProjectWrite(c *gin.Context) func(ctx context.Context, projectID string) error {
+	value, _ := c.Get("user")
+	user, _ := value.(*models.User)
+	projectService := service.NewProjectService(repository.NewProjectRepository(s.db.DB))
+
+	return func(ctx context.Context, projectID string) error {
+		return projectService.AuthorizeWrite(ctx, projectID, user)
+	}
+}
@@ -0,0 +1,63 @@
+package api
+
+import (
+	"log"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/tktomaru/taskai/taskai-server/internal/repository"
+	"github.com/tktomaru/taskai/taskai-server/internal/service"
+)
+
+// handleGetTaskBacklinks handles GET /api/v1/projects/:projectId/tasks/:taskId/backlinks
+func (s *Server) handleGetTaskBacklinks(c *gin.Context) {
+	projectID := c.Param("projectId")
+	taskID := c.Param("taskId")
+
+	taskService := service.NewTaskService(repository.NewTaskRepository(s.db.DB))
+	if _, err := taskService.GetByID(c.Request.Context(), projectID, taskID); err != nil {
+		c.JSON(http.StatusNotFound, gin.H{
+			"error":   "not_found",
+			"message": "Task not found",
+			"details": err.Error(),
+		})
+		return
+	}
+
+	referenceService := service.NewReferenceService(s.db)
+	backlinks, err := referenceService.Backlinks(c.Request.Context(), projectID, taskID)
+	if err != nil {
+		log.Printf("ERROR: Failed to get backlinks of task %s: %v", taskID, err)
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error":   "internal_server_error",
+			"message": "Failed to get backlinks",
+			"details": err.Error(),
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"data": backlinks,
+	})
+}
+
+// handleListDanglingReferences handles GET /api/v1/projects/:projectId/dangling-references
+func (s *Server) handleListDanglingReferences(c *gin.Context) {
+	projectID := c.Param("projectId")
+
+	referenceService := service.NewReferenceService(s.db)
+	refs, err := referenceService.ListDangling(c.Request.Context(), projectID)
+	if err != nil {
+		log.Printf("ERROR: Failed to list dangling references for project %s: %v", projectID, err)
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error":   "internal_server_error",
+			"message": "Failed to list dangling references",
+			"details": err.Error(),
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"data": refs,
+	})
+}
@@ -1,13 +1,20 @@
 package api
 
 import (
+	"context"
 	"net/http"
+	"sync"
 	"time"
 
 	"github.com/gin-gonic/gin"
+	"github.com/tktomaru/taskai/taskai-server/internal/auth"
 	"github.com/tktomaru/taskai/taskai-server/internal/config"
 	"github.com/tktomaru/taskai/taskai-server/internal/database"
+	"github.com/tktomaru/taskai/taskai-server/internal/mail"
+	"github.com/tktomaru/taskai/taskai-server/internal/oidc"
+	"github.com/tktomaru/taskai/taskai-server/internal/ratelimit"
 	"github.com/tktomaru/taskai/taskai-server/internal/search"
+	"github.com/tktomaru/taskai/taskai-server/internal/service"
 	"github.com/tktomaru/taskai/taskai-server/internal/websocket"
 )
 
@@ -18,6 +25,20 @@ type Server struct {
 	meili  *search.MeilisearchClient
 	wsHub  *websocket.Hub
 	router *gin.Engine
+
+	// OIDC client, created on the first OIDC login
+	oidcMu     sync.Mutex
+	oidcClient *oidc.Client
+
+	// Rate limit buckets and login failure counts
+	rateLimits ratelimit.Store
+
+	// Account emails and the rules for new passwords
+	mailSender     mail.Sender
+	passwordPolicy *auth.PasswordPolicy
+
+	// Watched views to re-evaluate after task changes
+	viewRefresh *service.ViewRefreshQueue
 }
 
 // NewServer creates a new HTTP server
@@ -42,13 +63,43 @@ func NewServer(cfg *config.Config, db *database.DB, meili *search.MeilisearchCli
 		meili:  meili,
 		wsHub:  wsHub,
 		router: router,
+
+		rateLimits: ratelimit.NewMemoryStore(),
+
+		mailSender:     mail.LogSender{},
+		passwordPolicy: &auth.PasswordPolicy{MinLength: cfg.Auth.PasswordMinLength},
 	}
 
+	// Queue webhook deliveries for every project event broadcast to WebSocket clients
+	if cfg.Webhooks.Enabled {
+		wsHub.OnBroadcast = s.enqueueWebhooks
+	}
+	wsHub.ResolveView = s.resolveWebSocketView
+	wsHub.CheckTask = s.checkWebSocketTask
+	s.viewRefresh = service.NewViewRefreshQueue(func(ctx context.Context, projectID, actorID string) error {
+		return s.viewWatchService(s.notificationService()).Refresh(ctx, projectID, actorID)
+	})
+
 	s.setupRoutes()
 
 	return s
 }
 
+// SetRateLimitStore replaces the in-memory rate limit store, e.g. with one shared by all replicas
+func (s *Server) SetRateLimitStore(store ratelimit.Store) {
+	s.rateLimits = store
+}
+
+// SetMailSender sets the sender of account emails. Emails are logged by default.
+func (s *Server) SetMailSender(sender mail.Sender) {
+	s.mailSender = sender
+}
+
+// SetPasswordPolicy sets the policy new passwords must pass, e.g. one with a breached password list
+func (s *Server) SetPasswordPolicy(policy *auth.PasswordPolicy) {
+	s.passwordPolicy = policy
+}
+
 // setupRoutes configures all API routes
 func (s *Server) setupRoutes() {
 	// Health check
@@ -61,60 +112,154 @@ func (s *Server) setupRoutes() {
 		// Public auth endpoints
 		auth := v1.Group("/auth")
 		{
-			auth.POST("/register", s.handleRegister)
-			auth.POST("/login", s.handleLogin)
-			auth.POST("/logout", s.handleLogout)
+			authLimit := s.RateLimitMiddleware("auth", s.cfg.RateLimit.Auth, clientIPKey)
+			auth.POST("/register", authLimit, s.handleRegister)
+			auth.POST("/login", authLimit, s.handleLogin)
+			auth.POST("/login/2fa", authLimit, s.handleLoginTwoFactor)
+			auth.POST("/refresh", authLimit, s.handleRefresh)
+			auth.POST("/verify-email", authLimit, s.handleVerifyEmail)
+			auth.POST("/verify-email/resend", authLimit, s.handleResendVerification)
+			auth.POST("/password/forgot", authLimit, s.handleForgotPassword)
+			auth.POST("/password/reset", authLimit, s.handleResetPassword)
+			auth.POST("/logout", s.logoutAuth, s.handleLogout)
+			auth.POST("/logout-all", s.AuthMiddleware(), s.handleLogoutEverywhere)
 			auth.GET("/me", s.AuthMiddleware(), s.handleGetCurrentUser)
+			auth.GET("/oidc/login", s.handleOIDCLogin)
+			auth.GET("/oidc/callback", s.handleOIDCCallback)
 		}
 
+		// Inbound events from external systems, authenticated by the ingestion token in the URL
+		v1.POST("/ingest/:token", s.RateLimitMiddleware("ingest", s.cfg.RateLimit.Ingest, ingestTokenKey), s.handleIngest)
+
 		// Protected routes (require authentication)
 		protected := v1.Group("")
 		protected.Use(s.OptionalAuthMiddleware()) // Optional for now, can be changed to AuthMiddleware() for strict auth
+		protected.Use(s.RateLimitMiddleware("api", s.cfg.RateLimit.API, identityKey))
 		{
 			// Projects
 			projects := protected.Group("/projects")
+			projects.Use(s.ProjectTwoFactorMiddleware())
 			{
 				projects.GET("", s.handleListProjects)
 				projects.POST("", s.handleCreateProject)
 				projects.GET("/:projectId", s.handleGetProject)
 				projects.PUT("/:projectId", s.handleUpdateProject)
 				projects.DELETE("/:projectId", s.handleDeleteProject)
+				projects.POST("/:projectId/watch", s.handleWatchProject)
+				projects.DELETE("/:projectId/watch", s.handleUnwatchProject)
+
+				// Working-day calendar
+				projects.GET("/:projectId/calendar", s.handleGetCalendar)
+				projects.PUT("/:projectId/calendar", s.handleUpdateCalendar)
+				projects.POST("/:projectId/calendar/holidays/import", s.handleImportHolidays)
 
 				// Tasks
 				tasks := projects.Group("/:projectId/tasks")
 				{
 					tasks.GET("", s.handleListTasks)
-					tasks.POST("", s.handleCreateTask)
-					tasks.POST("/bulk-update", s.handleBulkUpdateTasks)
+					tasks.POST("", s.IdempotencyMiddleware(), s.handleCreateTask)
+					tasks.POST("/bulk-update", s.IdempotencyMiddleware(), s.handleBulkUpdateTasks)
+					tasks.POST("/bulk-by-query", s.IdempotencyMiddleware(), s.handleBulkUpdateByQuery)
+					tasks.POST("/import-markdown", s.IdempotencyMiddleware(), s.handleImportMarkdown)
+					tasks.POST("/validate", s.handleValidateTask)
+					tasks.POST("/from-template/:templateId", s.IdempotencyMiddleware(), s.handleCreateTaskFromTemplate)
 					tasks.GET("/:taskId", s.handleGetTask)
 					tasks.PUT("/:taskId", s.handleUpdateTask)
+					tasks.PATCH("/:taskId", s.IdempotencyMiddleware(), s.handlePatchTask)
 					tasks.DELETE("/:taskId", s.handleDeleteTask)
+					tasks.GET("/:taskId/schedule", s.handleGetTaskSchedule)
+					tasks.GET("/:taskId/backlinks", s.handleGetTaskBacklinks)
+					tasks.POST("/:taskId/watch", s.handleWatchTask)
+					tasks.DELETE("/:taskId/watch", s.handleUnwatchTask)
+
+					// Task Comments
+					tasks.GET("/:taskId/comments", s.handleListComments)
+					tasks.POST("/:taskId/comments", s.handleCreateComment)
 
 					// Task Revisions
 					tasks.GET("/:taskId/revisions", s.handleGetTaskRevisions)
 					tasks.GET("/:taskId/revisions/:revId/compare", s.handleCompareWithCurrent)
 				}
 
+				// References to missing or deleted tasks
+				projects.GET("/:projectId/dangling-references", s.handleListDanglingReferences)
+
+				// Task templates
+				templates := projects.Group("/:projectId/templates")
+				{
+					templates.GET("", s.handleListTemplates)
+					templates.POST("", s.handleCreateTemplate)
+					templates.POST("/defaults", s.handleRestoreDefaultTemplates)
+					templates.GET("/:templateId", s.handleGetTemplate)
+					templates.PUT("/:templateId", s.handleUpdateTemplate)
+					templates.DELETE("/:templateId", s.handleDeleteTemplate)
+				}
+
+				// Recurring task series
+				series := projects.Group("/:projectId/series")
+				{
+					series.GET("", s.handleListSeries)
+					series.GET("/:seriesId", s.handleGetSeries)
+					series.POST("/:seriesId/cancel", s.handleCancelSeries)
+				}
+
+				// Outbound webhooks, managed by project owners and maintainers
+				webhooks := projects.Group("/:projectId/webhooks")
+				webhooks.Use(s.ProjectMaintainerMiddleware())
+				{
+					webhooks.GET("", s.handleListWebhooks)
+					webhooks.POST("", s.handleCreateWebhook)
+					webhooks.GET("/:webhookId", s.handleGetWebhook)
+					webhooks.PUT("/:webhookId", s.handleUpdateWebhook)
+					webhooks.DELETE("/:webhookId", s.handleDeleteWebhook)
+					webhooks.GET("/:webhookId/deliveries", s.handleListWebhookDeliveries)
+					webhooks.GET("/:webhookId/deliveries/:deliveryId", s.handleGetWebhookDelivery)
+					webhooks.POST("/:webhookId/deliveries/:deliveryId/redeliver", s.handleRedeliverWebhook)
+				}
+
+				// Inbound event ingestion tokens, managed by project owners and maintainers
+				ingestTokens := projects.Group("/:projectId/ingest-tokens")
+				ingestTokens.Use(s.ProjectMaintainerMiddleware())
+				{
+					ingestTokens.GET("", s.handleListIngestTokens)
+					ingestTokens.POST("", s.handleCreateIngestToken)
+					ingestTokens.PUT("/:tokenId", s.handleUpdateIngestToken)
+					ingestTokens.DELETE("/:tokenId", s.handleDeleteIngestToken)
+				}
+
+				// Service accounts and their access tokens
+				serviceAccounts := projects.Group("/:projectId/service-accounts")
+				{
+					serviceAccounts.GET("", s.handleListServiceAccounts)
+					serviceAccounts.POST("", s.handleCreateServiceAccount)
+					serviceAccounts.DELETE("/:accountId", s.handleDeleteServiceAccount)
+					serviceAccounts.GET("/:accountId/tokens", s.handleListServiceAccountTokens)
+					serviceAccounts.POST("/:accountId/tokens", s.handleCreateServiceAccountToken)
+					serviceAccounts.DELETE("/:accountId/tokens/:tokenId", s.handleRevokeServiceAccountToken)
+				}
+
 				// Saved Views
 				views := projects.Group("/:projectId/views")
 				{
 					views.GET("", s.handleListViews)
-					views.POST("", s.handleCreateView)
+					views.POST("", s.IdempotencyMiddleware(), s.handleCreateView)
 					views.GET("/:viewId", s.handleGetView)
 					views.PUT("/:viewId", s.handleUpdateView)
 					views.DELETE("/:viewId", s.handleDeleteView)
 					views.POST("/:viewId/execute", s.handleExecuteView)
+					views.POST("/:viewId/watch", s.handleWatchView)
+					views.DELETE("/:viewId/watch", s.handleUnwatchView)
 				}
 			}
 
 			// Task Packs (AI handoff)
-			protected.POST("/task-packs", s.handleGenerateTaskPack)
+			protected.POST("/task-packs", s.IdempotencyMiddleware(), s.handleGenerateTaskPack)
 
 			// Search
 			protected.POST("/search", s.handleSearch)
 
 			// Reindex
-			protected.POST("/projects/:projectId/reindex", s.handleReindexProject)
+			protected.POST("/projects/:projectId/reindex", s.ProjectTwoFactorMiddleware(), s.handleReindexProject)
 
 			// Revisions (standalone)
 			revisions := protected.Group("/revisions")
@@ -125,10 +270,34 @@ func (s *Server) setupRoutes() {
 
 			// WebSocket
 			protected.GET("/ws/stats", s.handleWebSocketStats)
+			protected.POST("/ws/ticket", s.handleCreateWebSocketTicket)
+
+			// Current user's sessions, tokens, two-factor authentication, notification inbox and subscriptions
+			me := protected.Group("/me")
+			{
+				me.GET("/sessions", s.handleListSessions)
+				me.DELETE("/sessions/:sessionId", s.handleRevokeSession)
+				me.GET("/tokens", s.handleListAccessTokens)
+				me.POST("/tokens", s.handleCreateAccessToken)
+				me.DELETE("/tokens/:tokenId", s.handleRevokeAccessToken)
+				me.GET("/2fa", s.handleGetTwoFactor)
+				me.POST("/2fa/enroll", s.handleEnrollTwoFactor)
+				me.POST("/2fa/confirm", s.handleConfirmTwoFactor)
+				me.POST("/2fa/recovery-codes", s.handleRegenerateRecoveryCodes)
+				me.POST("/2fa/disable", s.handleDisableTwoFactor)
+				me.GET("/subscriptions", s.handleListSubscriptions)
+				me.GET("/notifications", s.handleListNotifications)
+				me.POST("/notifications/read-all", s.handleMarkAllNotificationsRead)
+				me.POST("/notifications/:notificationId/read", s.handleMarkNotificationRead)
+				me.POST("/notifications/:notificationId/unread", s.handleMarkNotificationUnread)
+			}
 		}
 
-		// WebSocket endpoint (per project)
-		v1.GET("/projects/:projectId/ws", s.OptionalAuthMiddleware(), s.handleWebSocket)
+		// WebSocket endpoint (per project), authenticated by token, cookie or one-time ticket
+		v1.GET("/projects/:projectId/ws", s.WebSocketAuthMiddleware(), s.ProjectTwoFactorMiddleware(), s.handleWebSocket)
+
+		// WebSocket endpoint (per user, for notifications)
+		v1.GET("/me/ws", s.WebSocketAuthMiddleware(), s.handleUserWebSocket)
 	}
 }
 
@@ -4,6 +4,7 @@ import (
 	"net/http"
 
 	"github.com/gin-gonic/gin"
+	"github.com/tktomaru/taskai/taskai-server/internal/models"
 	"github.com/tktomaru/taskai/taskai-server/internal/repository"
 	"github.com/tktomaru/taskai/taskai-server/internal/service"
 )
@@ -20,6 +21,10 @@ func (s *Server) handleSearch(c *gin.Context) {
 		return
 	}
 
+	if !s.requireProjectTwoFactor(c, req.ProjectID) {
+		return
+	}
+
 	// Use search service if Meilisearch is configured
 	var searchService *service.SearchService
 	if s.meili != nil {
@@ -44,11 +49,38 @@ func (s *Server) handleSearch(c *gin.Context) {
 		return
 	}
 
+	// Searches across projects leave out the projects whose two-factor requirement the user does not meet
+	if req.ProjectID == "" {
+		s.filterTwoFactorResults(c, results)
+	}
+
 	c.JSON(http.StatusOK, gin.H{
 		"data": results,
 	})
 }
 
+// filterTwoFactorResults removes the tasks of projects that require two-factor authentication the user lacks
+func (s *Server) filterTwoFactorResults(c *gin.Context, results *service.SearchResponse) {
+	value, _ := c.Get("user")
+	user, _ := value.(*models.User)
+
+	blocked := make(map[string]bool)
+	kept := results.Results[:0]
+	for _, task := range results.Results {
+		isBlocked, checked := blocked[task.ProjectID]
+		if !checked {
+			isBlocked = s.twoFactorBlocks(c.Request.Context(), task.ProjectID, user)
+			blocked[task.ProjectID] = isBlocked
+		}
+		if isBlocked {
+			results.Total--
+			continue
+		}
+		kept = append(kept, task)
+	}
+	results.Results = kept
+}
+
 // handleReindexProject handles POST /api/v1/projects/:projectId/reindex
 func (s *Server) handleReindexProject(c *gin.Context) {
 	projectID := c.Param("projectId")
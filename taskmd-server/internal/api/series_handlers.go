@@ -0,0 +1,109 @@
+package api
+
+import (
+	"context"
+	"log"
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/tktomaru/taskai/taskai-server/internal/models"
+	"github.com/tktomaru/taskai/taskai-server/internal/service"
+)
+
+// recurrenceService creates a recurrence service that indexes and broadcasts generated tasks
+func (s *Server) recurrenceService() *service.RecurrenceService {
+	recurrenceService := service.NewRecurrenceService(s.db)
+
+	recurrenceService.OnInstanceCreated = func(task *models.Task) {
+		if err := service.NewReferenceService(s.db).HandleCreated(context.Background(), task, ""); err != nil {
+			log.Printf("WARNING: Failed to sync references for task %s: %v", task.ID, err)
+		}
+		s.publishTaskCreated(task)
+	}
+
+	return recurrenceService
+}
+
+// StartScheduler runs the recurring task scheduler until the context is cancelled
+func (s *Server) StartScheduler(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		created, err := s.recurrenceService().Tick(ctx, time.Now())
+		if err != nil {
+			log.Printf("ERROR: Scheduler tick failed: %v", err)
+		} else if len(created) > 0 {
+			log.Printf("Scheduler generated %d recurring task(s)", len(created))
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// handleListSeries handles GET /api/v1/projects/:projectId/series
+func (s *Server) handleListSeries(c *gin.Context) {
+	projectID := c.Param("projectId")
+
+	series, err := s.recurrenceService().List(c.Request.Context(), projectID)
+	if err != nil {
+		log.Printf("ERROR: Failed to list series for project %s: %v", projectID, err)
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error":   "internal_server_error",
+			"message": "Failed to list series",
+			"details": err.Error(),
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"data": series,
+	})
+}
+
+// handleGetSeries handles GET /api/v1/projects/:projectId/series/:seriesId
+func (s *Server) handleGetSeries(c *gin.Context) {
+	projectID := c.Param("projectId")
+	seriesID := c.Param("seriesId")
+
+	detail, err := s.recurrenceService().Get(c.Request.Context(), projectID, seriesID)
+	if err != nil {
+		log.Printf("ERROR: Failed to get series %s in project %s: %v", seriesID, projectID, err)
+		c.JSON(http.StatusNotFound, gin.H{
+			"error":   "not_found",
+			"message": "Series not found",
+			"details": err.Error(),
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"data": detail,
+	})
+}
+
+// handleCancelSeries handles POST /api/v1/projects/:projectId/series/:seriesId/cancel
+func (s *Server) handleCancelSeries(c *gin.Context) {
+	projectID := c.Param("projectId")
+	seriesID := c.Param("seriesId")
+
+	series, err := s.recurrenceService().Cancel(c.Request.Context(), projectID, seriesID)
+	if err != nil {
+		log.Printf("ERROR: Failed to cancel series %s in project %s: %v", seriesID, projectID, err)
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error":   "cancel_failed",
+			"message": "Failed to cancel series",
+			"details": err.Error(),
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"data": series,
+	})
+}
@@ -0,0 +1,132 @@
+package api
+
+import (
+	"io"
+	"log"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/tktomaru/taskai/taskai-server/internal/models"
+	"github.com/tktomaru/taskai/taskai-server/internal/repository"
+	"github.com/tktomaru/taskai/taskai-server/internal/service"
+)
+
+// subscriptionService creates a subscription service that snapshots views when they are first watched
+func (s *Server) subscriptionService() *service.SubscriptionService {
+	subscriptionService := service.NewSubscriptionService(
+		repository.NewSubscriptionRepository(s.db.DB),
+		repository.NewTaskRepository(s.db.DB),
+		repository.NewViewRepository(s.db.DB),
+		repository.NewProjectRepository(s.db.DB),
+	)
+	subscriptionService.SetViewWatch(s.viewWatchService(s.notificationService()))
+
+	return subscriptionService
+}
+
+// handleWatchTask handles POST /api/v1/projects/:projectId/tasks/:taskId/watch
+func (s *Server) handleWatchTask(c *gin.Context) {
+	s.watch(c, models.SubscriptionTargetTask, c.Param("taskId"))
+}
+
+// handleUnwatchTask handles DELETE /api/v1/projects/:projectId/tasks/:taskId/watch
+func (s *Server) handleUnwatchTask(c *gin.Context) {
+	s.unwatch(c, models.SubscriptionTargetTask, c.Param("taskId"))
+}
+
+// handleWatchView handles POST /api/v1/projects/:projectId/views/:viewId/watch
+func (s *Server) handleWatchView(c *gin.Context) {
+	s.watch(c, models.SubscriptionTargetView, c.Param("viewId"))
+}
+
+// handleUnwatchView handles DELETE /api/v1/projects/:projectId/views/:viewId/watch
+func (s *Server) handleUnwatchView(c *gin.Context) {
+	s.unwatch(c, models.SubscriptionTargetView, c.Param("viewId"))
+}
+
+// handleWatchProject handles POST /api/v1/projects/:projectId/watch
+func (s *Server) handleWatchProject(c *gin.Context) {
+	s.watch(c, models.SubscriptionTargetProject, c.Param("projectId"))
+}
+
+// handleUnwatchProject handles DELETE /api/v1/projects/:projectId/watch
+func (s *Server) handleUnwatchProject(c *gin.Context) {
+	s.unwatch(c, models.SubscriptionTargetProject, c.Param("projectId"))
+}
+
+// handleListSubscriptions handles GET /api/v1/me/subscriptions
+func (s *Server) handleListSubscriptions(c *gin.Context) {
+	userID, ok := currentUserID(c)
+	if !ok {
+		return
+	}
+
+	subs, err := s.subscriptionService().List(c.Request.Context(), userID)
+	if err != nil {
+		log.Printf("ERROR: Failed to list subscriptions for user %s: %v", userID, err)
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error":   "internal_server_error",
+			"message": "Failed to list subscriptions",
+			"details": err.Error(),
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"data": subs,
+	})
+}
+
+func (s *Server) watch(c *gin.Context, targetType models.SubscriptionTarget, targetID string) {
+	userID, ok := currentUserID(c)
+	if !ok {
+		return
+	}
+	projectID := c.Param("projectId")
+
+	// The body is optional
+	var req service.WatchRequest
+	if err := c.ShouldBindJSON(&req); err != nil && err != io.EOF {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error":   "invalid_request",
+			"message": "Invalid request body",
+			"details": err.Error(),
+		})
+		return
+	}
+
+	sub, err := s.subscriptionService().Watch(c.Request.Context(), userID, targetType, projectID, targetID, &req)
+	if err != nil {
+		log.Printf("ERROR: Failed to watch %s %s in project %s: %v", targetType, targetID, projectID, err)
+		c.JSON(http.StatusNotFound, gin.H{
+			"error":   "not_found",
+			"message": "Failed to watch " + string(targetType),
+			"details": err.Error(),
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"data": sub,
+	})
+}
+
+func (s *Server) unwatch(c *gin.Context, targetType models.SubscriptionTarget, targetID string) {
+	userID, ok := currentUserID(c)
+	if !ok {
+		return
+	}
+	projectID := c.Param("projectId")
+
+	if err := s.subscriptionService().Unwatch(c.Request.Context(), userID, targetType, projectID, targetID); err != nil {
+		log.Printf("ERROR: Failed to unwatch %s %s in project %s: %v", targetType, targetID, projectID, err)
+		c.JSON(http.StatusNotFound, gin.H{
+			"error":   "not_found",
+			"message": "Failed to unwatch " + string(targetType),
+			"details": err.Error(),
+		})
+		return
+	}
+
+	c.JSON(http.StatusNoContent, nil)
+}
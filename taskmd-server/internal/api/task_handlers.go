@@ -1,10 +1,13 @@
 package api
 
 import (
+	"context"
+	"errors"
 	"log"
 	"net/http"
 
 	"github.com/gin-gonic/gin"
+	"github.com/tktomaru/taskai/taskai-server/internal/models"
 	"github.com/tktomaru/taskai/taskai-server/internal/repository"
 	"github.com/tktomaru/taskai/taskai-server/internal/service"
 	"github.com/tktomaru/taskai/taskai-server/internal/websocket"
@@ -71,6 +74,10 @@ func (s *Server) handleCreateTask(c *gin.Context) {
 
 	// Create task
 	taskService := service.NewTaskService(repository.NewTaskRepository(s.db.DB))
+	taskService.SetCalendar(s.projectCalendar(c.Request.Context(), projectID))
+	taskService.SetRecurrence(s.recurrenceService())
+	taskService.SetReferences(service.NewReferenceService(s.db))
+	taskService.SetNotifications(s.notificationService())
 	task, err := taskService.Create(c.Request.Context(), projectID, &req)
 	if err != nil {
 		log.Printf("ERROR: Failed to create task in project %s: %v", projectID, err)
@@ -82,20 +89,25 @@ func (s *Server) handleCreateTask(c *gin.Context) {
 		return
 	}
 
+	s.publishTaskCreated(task)
+
+	c.JSON(http.StatusCreated, gin.H{
+		"data": task,
+	})
+}
+
+// publishTaskCreated indexes a new task and broadcasts it to project subscribers
+func (s *Server) publishTaskCreated(task *models.Task) {
 	// Index in search engine (async)
 	if s.meili != nil {
 		go func() {
 			searchService := service.NewSearchService(repository.NewTaskRepository(s.db.DB), s.meili)
-			_ = searchService.IndexTask(c.Request.Context(), task)
+			_ = searchService.IndexTask(context.Background(), task)
 		}()
 	}
 
 	// Broadcast WebSocket event
-	s.wsHub.Broadcast(websocket.EventTaskCreated, projectID, task.ID, task)
-
-	c.JSON(http.StatusCreated, gin.H{
-		"data": task,
-	})
+	s.wsHub.Broadcast(websocket.EventTaskCreated, task.ProjectID, task.ID, task)
 }
 
 // handleGetTask handles GET /api/v1/projects/:projectId/tasks/:taskId
@@ -141,6 +153,10 @@ func (s *Server) handleUpdateTask(c *gin.Context) {
 
 	// Update task
 	taskService := service.NewTaskService(repository.NewTaskRepository(s.db.DB))
+	taskService.SetCalendar(s.projectCalendar(c.Request.Context(), projectID))
+	taskService.SetRecurrence(s.recurrenceService())
+	taskService.SetReferences(service.NewReferenceService(s.db))
+	taskService.SetNotifications(s.notificationService())
 	task, err := taskService.Update(c.Request.Context(), projectID, taskID, &req)
 	if err != nil {
 		log.Printf("ERROR: Failed to update task %s in project %s: %v", taskID, projectID, err)
@@ -168,13 +184,64 @@ func (s *Server) handleUpdateTask(c *gin.Context) {
 	})
 }
 
+// handlePatchTask handles PATCH /api/v1/projects/:projectId/tasks/:taskId
+// The body is a JSON merge patch of frontmatter fields (RFC 7396).
+func (s *Server) handlePatchTask(c *gin.Context) {
+	projectID := c.Param("projectId")
+	taskID := c.Param("taskId")
+
+	var patch map[string]interface{}
+	if err := c.ShouldBindJSON(&patch); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error":   "invalid_request",
+			"message": "Invalid request body",
+			"details": err.Error(),
+		})
+		return
+	}
+
+	// TODO: Get user ID from authentication context
+	taskService := service.NewTaskService(repository.NewTaskRepository(s.db.DB))
+	taskService.SetCalendar(s.projectCalendar(c.Request.Context(), projectID))
+	taskService.SetRecurrence(s.recurrenceService())
+	taskService.SetReferences(service.NewReferenceService(s.db))
+	taskService.SetNotifications(s.notificationService())
+	task, err := taskService.Patch(c.Request.Context(), projectID, taskID, patch, "system")
+	if err != nil {
+		log.Printf("ERROR: Failed to patch task %s in project %s: %v", taskID, projectID, err)
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error":   "validation_error",
+			"message": "Failed to patch task",
+			"details": err.Error(),
+		})
+		return
+	}
+
+	// Update search index (async)
+	if s.meili != nil {
+		go func() {
+			searchService := service.NewSearchService(repository.NewTaskRepository(s.db.DB), s.meili)
+			_ = searchService.UpdateTaskIndex(context.Background(), task)
+		}()
+	}
+
+	s.wsHub.Broadcast(websocket.EventTaskUpdated, projectID, task.ID, task)
+
+	c.JSON(http.StatusOK, gin.H{
+		"data": task,
+	})
+}
+
 // handleDeleteTask handles DELETE /api/v1/projects/:projectId/tasks/:taskId
 func (s *Server) handleDeleteTask(c *gin.Context) {
 	projectID := c.Param("projectId")
 	taskID := c.Param("taskId")
 
 	taskService := service.NewTaskService(repository.NewTaskRepository(s.db.DB))
-	err := taskService.Delete(c.Request.Context(), projectID, taskID)
+	taskService.SetReferences(service.NewReferenceService(s.db))
+	taskService.SetNotifications(s.notificationService())
+	// TODO: Get user ID from authentication context
+	err := taskService.Delete(c.Request.Context(), projectID, taskID, "system")
 	if err != nil {
 		log.Printf("ERROR: Failed to delete task %s in project %s: %v", taskID, projectID, err)
 		c.JSON(http.StatusNotFound, gin.H{
@@ -217,9 +284,11 @@ func (s *Server) handleBulkUpdateTasks(c *gin.Context) {
 	// TODO: Get user ID from authentication context
 	req.UpdatedBy = "system"
 
-	// Bulk update tasks
-	taskService := service.NewTaskService(repository.NewTaskRepository(s.db.DB))
-	updatedCount, err := taskService.BulkUpdate(c.Request.Context(), projectID, &req)
+	bulkService := service.NewBulkService(s.db)
+	bulkService.AuthorizeMove = s.authorizeProjectWrite(c)
+	bulkService.SetReferences(service.NewReferenceService(s.db))
+	bulkService.SetNotifications(s.notificationService())
+	result, err := bulkService.Apply(c.Request.Context(), projectID, &req)
 	if err != nil {
 		log.Printf("ERROR: Failed to bulk update tasks in project %s: %v", projectID, err)
 		c.JSON(http.StatusBadRequest, gin.H{
@@ -230,30 +299,187 @@ func (s *Server) handleBulkUpdateTasks(c *gin.Context) {
 		return
 	}
 
-	// Update search index for all updated tasks (async)
+	if result.Committed {
+		s.publishBulkResults(projectID, result)
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"data": result,
+	})
+}
+
+// handleBulkUpdateByQuery handles POST /api/v1/projects/:projectId/tasks/bulk-by-query
+func (s *Server) handleBulkUpdateByQuery(c *gin.Context) {
+	projectID := c.Param("projectId")
+
+	var req service.BulkByQueryRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		log.Printf("ERROR: Failed to bind JSON for bulk-by-query in project %s: %v", projectID, err)
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error":   "invalid_request",
+			"message": "Invalid request body",
+			"details": err.Error(),
+		})
+		return
+	}
+
+	// TODO: Get user ID from authentication context
+	req.UpdatedBy = "system"
+
+	bulkService := service.NewBulkService(s.db)
+	bulkService.AuthorizeMove = s.authorizeProjectWrite(c)
+	bulkService.SetReferences(service.NewReferenceService(s.db))
+	bulkService.SetNotifications(s.notificationService())
+	result, err := bulkService.ApplyByQuery(c.Request.Context(), projectID, &req, s.projectCalendar(c.Request.Context(), projectID))
+	if err != nil {
+		var exceeded *service.MaxAffectedExceededError
+		if errors.As(err, &exceeded) {
+			c.JSON(http.StatusConflict, gin.H{
+				"error":   "max_affected_exceeded",
+				"message": "Query matches more tasks than allowed",
+				"details": err.Error(),
+			})
+			return
+		}
+
+		log.Printf("ERROR: Failed to bulk update by query in project %s: %v", projectID, err)
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error":   "validation_error",
+			"message": "Failed to bulk update tasks",
+			"details": err.Error(),
+		})
+		return
+	}
+
+	if result.Committed {
+		s.publishBulkResults(projectID, result.BulkUpdateResult)
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"data": result,
+	})
+}
+
+// publishBulkResults updates the search index and broadcasts the tasks changed by a bulk request
+func (s *Server) publishBulkResults(projectID string, result *service.BulkUpdateResult) {
+	var searchService *service.SearchService
 	if s.meili != nil {
-		go func() {
-			searchService := service.NewSearchService(repository.NewTaskRepository(s.db.DB), s.meili)
-			for _, taskID := range req.TaskIDs {
-				task, err := taskService.GetByID(c.Request.Context(), projectID, taskID)
-				if err == nil {
-					_ = searchService.UpdateTaskIndex(c.Request.Context(), task)
-				}
+		searchService = service.NewSearchService(repository.NewTaskRepository(s.db.DB), s.meili)
+	}
+
+	for _, r := range result.Results {
+		switch r.Status {
+		case service.BulkResultUpdated:
+			if searchService != nil {
+				task := r.Task
+				go func() { _ = searchService.UpdateTaskIndex(context.Background(), task) }()
 			}
-		}()
+			s.wsHub.Broadcast(websocket.EventTaskUpdated, projectID, r.TaskID, r.Task)
+
+		case service.BulkResultMoved:
+			if searchService != nil {
+				task := r.Task
+				go func() { _ = searchService.UpdateTaskIndex(context.Background(), task) }()
+			}
+			s.wsHub.Broadcast(websocket.EventTaskDeleted, projectID, r.TaskID, gin.H{"id": r.TaskID})
+			s.wsHub.Broadcast(websocket.EventTaskCreated, r.ProjectID, r.TaskID, r.Task)
+
+		case service.BulkResultDeleted:
+			if searchService != nil {
+				taskID := r.TaskID
+				go func() { _ = searchService.DeleteTaskIndex(context.Background(), taskID) }()
+			}
+			s.wsHub.Broadcast(websocket.EventTaskDeleted, projectID, r.TaskID, gin.H{"id": r.TaskID})
+		}
+	}
+}
+
+// handleImportMarkdown handles POST /api/v1/projects/:projectId/tasks/import-markdown
+func (s *Server) handleImportMarkdown(c *gin.Context) {
+	projectID := c.Param("projectId")
+
+	var req service.ImportMarkdownRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		log.Printf("ERROR: Failed to bind JSON for markdown import in project %s: %v", projectID, err)
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error":   "invalid_request",
+			"message": "Invalid request body",
+			"details": err.Error(),
+		})
+		return
 	}
 
-	// Broadcast WebSocket event for each updated task
-	for _, taskID := range req.TaskIDs {
-		task, err := taskService.GetByID(c.Request.Context(), projectID, taskID)
-		if err == nil {
-			s.wsHub.Broadcast(websocket.EventTaskUpdated, projectID, task.ID, task)
+	// TODO: Get user ID from authentication context
+	req.ImportedBy = "system"
+
+	bulkService := service.NewBulkService(s.db)
+	bulkService.SetRecurrence(s.recurrenceService())
+	bulkService.SetReferences(service.NewReferenceService(s.db))
+	bulkService.SetNotifications(s.notificationService())
+	result, err := bulkService.ImportMarkdown(c.Request.Context(), projectID, &req, s.projectCalendar(c.Request.Context(), projectID))
+	if err != nil {
+		log.Printf("ERROR: Failed to import markdown in project %s: %v", projectID, err)
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error":   "validation_error",
+			"message": "Failed to import markdown",
+			"details": err.Error(),
+		})
+		return
+	}
+
+	if result.Committed {
+		for _, r := range result.Results {
+			switch r.Status {
+			case service.BulkResultCreated:
+				s.publishTaskCreated(r.Task)
+			case service.BulkResultUpdated:
+				if s.meili != nil {
+					task := r.Task
+					go func() {
+						searchService := service.NewSearchService(repository.NewTaskRepository(s.db.DB), s.meili)
+						_ = searchService.UpdateTaskIndex(context.Background(), task)
+					}()
+				}
+				s.wsHub.Broadcast(websocket.EventTaskUpdated, projectID, r.TaskID, r.Task)
+			}
 		}
 	}
 
 	c.JSON(http.StatusOK, gin.H{
-		"data": gin.H{
-			"updated_count": updatedCount,
-		},
+		"data": result,
+	})
+}
+
+// handleValidateTask handles POST /api/v1/projects/:projectId/tasks/validate
+func (s *Server) handleValidateTask(c *gin.Context) {
+	projectID := c.Param("projectId")
+
+	var req service.ValidateTaskRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error":   "invalid_request",
+			"message": "Invalid request body",
+			"details": err.Error(),
+		})
+		return
+	}
+
+	lintService := service.NewLintService(
+		repository.NewTaskRepository(s.db.DB),
+		repository.NewProjectRepository(s.db.DB),
+	)
+	result, err := lintService.Validate(c.Request.Context(), projectID, req.MarkdownBody)
+	if err != nil {
+		log.Printf("ERROR: Failed to validate task markdown in project %s: %v", projectID, err)
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error":   "internal_server_error",
+			"message": "Failed to validate task",
+			"details": err.Error(),
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"data": result,
 	})
 }
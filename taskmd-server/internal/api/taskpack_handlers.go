@@ -2,6 +2,7 @@ package api
 
 import (
 	"fmt"
+	"log"
 	"net/http"
 	"strings"
 	"time"
@@ -13,16 +14,18 @@ import (
 
 // TaskPackRequest represents the request to generate a task pack
 type TaskPackRequest struct {
-	ProjectID      string   `json:"project_id" binding:"required"`
-	TaskIDs        []string `json:"task_ids" binding:"required,min=1"`
-	Template       string   `json:"template" binding:"required,oneof=IMPLEMENT BUGFIX RESEARCH REVIEW"`
-	IncludeRelated bool     `json:"include_related"`
+	ProjectID string   `json:"project_id" binding:"required"`
+	TaskIDs   []string `json:"task_ids" binding:"required,min=1"`
+	Template  string   `json:"template" binding:"required,oneof=IMPLEMENT BUGFIX RESEARCH REVIEW"`
+	// IncludeRelated adds the tasks referenced by the requested tasks
+	IncludeRelated bool `json:"include_related"`
 }
 
 // TaskPackResponse represents the generated task pack
 type TaskPackResponse struct {
-	Markdown  string `json:"markdown"`
-	TaskCount int    `json:"task_count"`
+	Markdown       string   `json:"markdown"`
+	TaskCount      int      `json:"task_count"`
+	RelatedTaskIDs []string `json:"related_task_ids,omitempty"`
 }
 
 func (s *Server) handleGenerateTaskPack(c *gin.Context) {
@@ -35,6 +38,10 @@ func (s *Server) handleGenerateTaskPack(c *gin.Context) {
 		return
 	}
 
+	if !s.requireProjectTwoFactor(c, req.ProjectID) {
+		return
+	}
+
 	// Log request
 	fmt.Printf("Task Pack request: ProjectID=%s, TaskIDs=%v, Template=%s\n", req.ProjectID, req.TaskIDs, req.Template)
 
@@ -58,19 +65,40 @@ func (s *Server) handleGenerateTaskPack(c *gin.Context) {
 
 	fmt.Printf("Found %d tasks\n", len(tasks))
 
+	// Fetch the tasks referenced by the requested tasks
+	var related []models.Task
+	var relatedIDs []string
+	if req.IncludeRelated {
+		relationRepo := repository.NewRelationRepository(s.db.DB)
+		referenced, err := relationRepo.ListReferencedTasks(c.Request.Context(), req.ProjectID, req.TaskIDs)
+		if err != nil {
+			log.Printf("ERROR: Failed to get referenced tasks of project %s: %v", req.ProjectID, err)
+			c.JSON(http.StatusInternalServerError, gin.H{
+				"error":   "Internal server error",
+				"message": "Failed to get referenced tasks",
+			})
+			return
+		}
+		for _, task := range referenced {
+			related = append(related, *task)
+			relatedIDs = append(relatedIDs, task.ID)
+		}
+	}
+
 	// Generate task pack based on template
-	markdown := generateTaskPackMarkdown(tasks, req.Template)
+	markdown := generateTaskPackMarkdown(tasks, related, req.Template)
 	fmt.Printf("Generated markdown: %d bytes\n", len(markdown))
 
 	c.JSON(http.StatusOK, gin.H{
 		"data": TaskPackResponse{
-			Markdown:  markdown,
-			TaskCount: len(tasks),
+			Markdown:       markdown,
+			TaskCount:      len(tasks),
+			RelatedTaskIDs: relatedIDs,
 		},
 	})
 }
 
-func generateTaskPackMarkdown(tasks []models.Task, template string) string {
+func generateTaskPackMarkdown(tasks, related []models.Task, template string) string {
 	var sb strings.Builder
 
 	// Header
@@ -168,6 +196,21 @@ func generateTaskPackMarkdown(tasks []models.Task, template string) string {
 		}
 	}
 
+	// Referenced tasks as background information
+	if len(related) > 0 {
+		sb.WriteString("## Referenced Tasks\n\n")
+		sb.WriteString("以下は上記のタスクから参照されているタスクです。背景情報として参照してください：\n\n")
+		for _, task := range related {
+			sb.WriteString(fmt.Sprintf("### %s: %s\n\n", task.ID, task.Title))
+			sb.WriteString(fmt.Sprintf("- **Status**: %s\n", task.Status))
+			sb.WriteString(fmt.Sprintf("- **Priority**: %s\n\n", task.Priority))
+			if task.MarkdownBody != "" {
+				sb.WriteString(task.MarkdownBody)
+				sb.WriteString("\n\n")
+			}
+		}
+	}
+
 	// Footer
 	sb.WriteString("## Next Steps\n\n")
 	sb.WriteString("1. このタスクパックを確認し、全体像を把握する\n")
@@ -0,0 +1,214 @@
+package api
+
+import (
+	"log"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/tktomaru/taskai/taskai-server/internal/repository"
+	"github.com/tktomaru/taskai/taskai-server/internal/service"
+)
+
+// templateService creates a template service for the current request
+func (s *Server) templateService() *service.TemplateService {
+	return service.NewTemplateService(
+		repository.NewTemplateRepository(s.db.DB),
+		repository.NewTaskRepository(s.db.DB),
+	)
+}
+
+// handleListTemplates handles GET /api/v1/projects/:projectId/templates
+func (s *Server) handleListTemplates(c *gin.Context) {
+	projectID := c.Param("projectId")
+
+	templates, err := s.templateService().List(c.Request.Context(), projectID)
+	if err != nil {
+		log.Printf("ERROR: Failed to list templates for project %s: %v", projectID, err)
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error":   "internal_server_error",
+			"message": "Failed to list templates",
+			"details": err.Error(),
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"data": templates,
+	})
+}
+
+// handleCreateTemplate handles POST /api/v1/projects/:projectId/templates
+func (s *Server) handleCreateTemplate(c *gin.Context) {
+	projectID := c.Param("projectId")
+
+	var req service.CreateTemplateRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error":   "invalid_request",
+			"message": "Invalid request body",
+			"details": err.Error(),
+		})
+		return
+	}
+
+	// TODO: Get user ID from authentication context
+	req.CreatedBy = "system"
+
+	template, err := s.templateService().Create(c.Request.Context(), projectID, &req)
+	if err != nil {
+		log.Printf("ERROR: Failed to create template in project %s: %v", projectID, err)
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error":   "validation_error",
+			"message": "Failed to create template",
+			"details": err.Error(),
+		})
+		return
+	}
+
+	c.JSON(http.StatusCreated, gin.H{
+		"data": template,
+	})
+}
+
+// handleGetTemplate handles GET /api/v1/projects/:projectId/templates/:templateId
+func (s *Server) handleGetTemplate(c *gin.Context) {
+	projectID := c.Param("projectId")
+	templateID := c.Param("templateId")
+
+	template, err := s.templateService().GetByID(c.Request.Context(), projectID, templateID)
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{
+			"error":   "not_found",
+			"message": "Template not found",
+			"details": err.Error(),
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"data": template,
+	})
+}
+
+// handleUpdateTemplate handles PUT /api/v1/projects/:projectId/templates/:templateId
+func (s *Server) handleUpdateTemplate(c *gin.Context) {
+	projectID := c.Param("projectId")
+	templateID := c.Param("templateId")
+
+	var req service.UpdateTemplateRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error":   "invalid_request",
+			"message": "Invalid request body",
+			"details": err.Error(),
+		})
+		return
+	}
+
+	template, err := s.templateService().Update(c.Request.Context(), projectID, templateID, &req)
+	if err != nil {
+		log.Printf("ERROR: Failed to update template %s in project %s: %v", templateID, projectID, err)
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error":   "validation_error",
+			"message": "Failed to update template",
+			"details": err.Error(),
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"data": template,
+	})
+}
+
+// handleDeleteTemplate handles DELETE /api/v1/projects/:projectId/templates/:templateId
+func (s *Server) handleDeleteTemplate(c *gin.Context) {
+	projectID := c.Param("projectId")
+	templateID := c.Param("templateId")
+
+	if err := s.templateService().Delete(c.Request.Context(), projectID, templateID); err != nil {
+		c.JSON(http.StatusNotFound, gin.H{
+			"error":   "not_found",
+			"message": "Template not found",
+			"details": err.Error(),
+		})
+		return
+	}
+
+	c.JSON(http.StatusNoContent, nil)
+}
+
+// handleRestoreDefaultTemplates handles POST /api/v1/projects/:projectId/templates/defaults
+func (s *Server) handleRestoreDefaultTemplates(c *gin.Context) {
+	projectID := c.Param("projectId")
+
+	templates, err := s.templateService().RestoreDefaults(c.Request.Context(), projectID)
+	if err != nil {
+		log.Printf("ERROR: Failed to restore default templates for project %s: %v", projectID, err)
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error":   "internal_server_error",
+			"message": "Failed to restore default templates",
+			"details": err.Error(),
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"data": templates,
+	})
+}
+
+// handleCreateTaskFromTemplate handles POST /api/v1/projects/:projectId/tasks/from-template/:templateId
+func (s *Server) handleCreateTaskFromTemplate(c *gin.Context) {
+	projectID := c.Param("projectId")
+	templateID := c.Param("templateId")
+
+	var req service.RenderTemplateRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error":   "invalid_request",
+			"message": "Invalid request body",
+			"details": err.Error(),
+		})
+		return
+	}
+
+	markdown, err := s.templateService().Render(c.Request.Context(), projectID, templateID, &req)
+	if err != nil {
+		log.Printf("ERROR: Failed to render template %s in project %s: %v", templateID, projectID, err)
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error":   "template_error",
+			"message": "Failed to render template",
+			"details": err.Error(),
+		})
+		return
+	}
+
+	// TODO: Get user ID from authentication context
+	createReq := service.CreateTaskRequest{
+		MarkdownBody: markdown,
+		CreatedBy:    "system",
+	}
+
+	taskService := service.NewTaskService(repository.NewTaskRepository(s.db.DB))
+	taskService.SetCalendar(s.projectCalendar(c.Request.Context(), projectID))
+	taskService.SetRecurrence(s.recurrenceService())
+	taskService.SetReferences(service.NewReferenceService(s.db))
+	taskService.SetNotifications(s.notificationService())
+	task, err := taskService.Create(c.Request.Context(), projectID, &createReq)
+	if err != nil {
+		log.Printf("ERROR: Failed to create task from template %s in project %s: %v", templateID, projectID, err)
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error":   "validation_error",
+			"message": "Failed to create task from template",
+			"details": err.Error(),
+		})
+		return
+	}
+
+	s.publishTaskCreated(task)
+
+	c.JSON(http.StatusCreated, gin.H{
+		"data": task,
+	})
+}
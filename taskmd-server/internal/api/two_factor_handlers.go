@@ -0,0 +1,356 @@
+package api
+
+import (
+	"context"
+	"errors"
+	"log"
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/tktomaru/taskai/taskai-server/internal/models"
+	"github.com/tktomaru/taskai/taskai-server/internal/repository"
+	"github.com/tktomaru/taskai/taskai-server/internal/service"
+	ws "github.com/tktomaru/taskai/taskai-server/internal/websocket"
+)
+
+// TwoFactorCodeRequest represents a request confirmed with a TOTP code or, where
+// accepted, a recovery code
+type TwoFactorCodeRequest struct {
+	Code         string `json:"code"`
+	RecoveryCode string `json:"recovery_code"`
+}
+
+// twoFactorService creates a service for TOTP enrollment and verification
+func (s *Server) twoFactorService() *service.TwoFactorService {
+	return service.NewTwoFactorService(
+		repository.NewUserRepository(s.db.DB),
+		repository.NewTwoFactorRepository(s.db.DB),
+		s.cfg.Auth.TOTPIssuer,
+	)
+}
+
+// checkTwoFactorLockout rejects the request when the user has entered too many wrong
+// codes. Codes are only six digits, so guesses are locked out like failed logins.
+func (s *Server) checkTwoFactorLockout(c *gin.Context, userID string) bool {
+	lockout := s.loginLockout()
+	if lockout == nil {
+		return true
+	}
+
+	wait, err := lockout.Check(c.Request.Context(), "2fa:"+userID, time.Now())
+	if err != nil {
+		log.Printf("WARNING: Failed to check two-factor lockout: %v", err)
+		return true
+	}
+	if wait > 0 {
+		abortTooManyRequests(c, wait, "too_many_attempts", "Too many invalid codes, please retry later")
+		return false
+	}
+
+	return true
+}
+
+// recordTwoFactorAttempt counts a wrong code towards the lockout of a user and clears it after a right one
+func (s *Server) recordTwoFactorAttempt(c *gin.Context, userID string, err error) {
+	lockout := s.loginLockout()
+	if lockout == nil {
+		return
+	}
+
+	key := "2fa:" + userID
+	if errors.Is(err, service.ErrInvalidTwoFactorCode) {
+		if _, lockErr := lockout.Fail(c.Request.Context(), key, time.Now()); lockErr != nil {
+			log.Printf("WARNING: Failed to record invalid two-factor code: %v", lockErr)
+		}
+	} else if err == nil {
+		if lockErr := lockout.Succeed(c.Request.Context(), key); lockErr != nil {
+			log.Printf("WARNING: Failed to reset two-factor lockout: %v", lockErr)
+		}
+	}
+}
+
+// respondTwoFactorError reports an error of a two-factor operation
+func respondTwoFactorError(c *gin.Context, err error, action string) {
+	switch {
+	case errors.Is(err, service.ErrInvalidTwoFactorCode):
+		c.JSON(http.StatusUnauthorized, gin.H{
+			"error":   "invalid_code",
+			"message": err.Error(),
+		})
+	case errors.Is(err, service.ErrTwoFactorAlreadyEnabled), errors.Is(err, service.ErrTwoFactorNotEnabled), errors.Is(err, service.ErrTwoFactorNotEnrolled):
+		c.JSON(http.StatusConflict, gin.H{
+			"error":   "conflict",
+			"message": err.Error(),
+		})
+	default:
+		log.Printf("ERROR: Failed to %s: %v", action, err)
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error":   "internal_server_error",
+			"message": "Failed to " + action,
+			"details": err.Error(),
+		})
+	}
+}
+
+// handleLoginTwoFactor handles POST /api/v1/auth/login/2fa
+// It exchanges the challenge token of a login and a TOTP or recovery code for a session.
+func (s *Server) handleLoginTwoFactor(c *gin.Context) {
+	var req service.TwoFactorLoginRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error":   "invalid_request",
+			"message": "Invalid request body",
+			"details": err.Error(),
+		})
+		return
+	}
+	req.Client = clientInfo(c)
+
+	authService := s.authService()
+
+	userID, err := authService.ChallengeUserID(req.ChallengeToken)
+	if err != nil {
+		c.JSON(http.StatusUnauthorized, gin.H{
+			"error":   "invalid_challenge",
+			"message": err.Error(),
+		})
+		return
+	}
+
+	if !s.checkTwoFactorLockout(c, userID) {
+		return
+	}
+
+	response, err := authService.CompleteTwoFactorLogin(c.Request.Context(), &req)
+	s.recordTwoFactorAttempt(c, userID, err)
+	if err != nil {
+		switch {
+		case errors.Is(err, service.ErrInvalidChallenge):
+			c.JSON(http.StatusUnauthorized, gin.H{
+				"error":   "invalid_challenge",
+				"message": err.Error(),
+			})
+		case errors.Is(err, service.ErrInvalidTwoFactorCode):
+			c.JSON(http.StatusUnauthorized, gin.H{
+				"error":   "invalid_code",
+				"message": err.Error(),
+			})
+		default:
+			log.Printf("ERROR: Failed to complete two-factor login of user %s: %v", userID, err)
+			c.JSON(http.StatusInternalServerError, gin.H{
+				"error":   "internal_server_error",
+				"message": "Failed to log in",
+				"details": err.Error(),
+			})
+		}
+		return
+	}
+
+	s.setSessionCookies(c, response)
+
+	c.JSON(http.StatusOK, gin.H{
+		"data": response,
+	})
+}
+
+// handleGetTwoFactor handles GET /api/v1/me/2fa
+func (s *Server) handleGetTwoFactor(c *gin.Context) {
+	userID, ok := currentUserID(c)
+	if !ok {
+		return
+	}
+
+	status, err := s.twoFactorService().Status(c.Request.Context(), userID)
+	if err != nil {
+		respondTwoFactorError(c, err, "get two-factor status")
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"data": status,
+	})
+}
+
+// handleEnrollTwoFactor handles POST /api/v1/me/2fa/enroll
+// It returns a new secret and its otpauth:// URI, to be confirmed with a first code.
+func (s *Server) handleEnrollTwoFactor(c *gin.Context) {
+	userID, ok := currentUserID(c)
+	if !ok || !requireSession(c) {
+		return
+	}
+
+	enrollment, err := s.twoFactorService().Enroll(c.Request.Context(), userID)
+	if err != nil {
+		respondTwoFactorError(c, err, "start two-factor enrollment")
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"data": enrollment,
+	})
+}
+
+// handleConfirmTwoFactor handles POST /api/v1/me/2fa/confirm
+// It enables two-factor authentication and returns the recovery codes, which are not shown again.
+func (s *Server) handleConfirmTwoFactor(c *gin.Context) {
+	userID, ok := currentUserID(c)
+	if !ok || !requireSession(c) {
+		return
+	}
+
+	var req TwoFactorCodeRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error":   "invalid_request",
+			"message": "Invalid request body",
+			"details": err.Error(),
+		})
+		return
+	}
+
+	if !s.checkTwoFactorLockout(c, userID) {
+		return
+	}
+
+	codes, err := s.twoFactorService().Confirm(c.Request.Context(), userID, req.Code)
+	s.recordTwoFactorAttempt(c, userID, err)
+	if err != nil {
+		respondTwoFactorError(c, err, "enable two-factor authentication")
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"data": gin.H{"recovery_codes": codes},
+	})
+}
+
+// handleRegenerateRecoveryCodes handles POST /api/v1/me/2fa/recovery-codes
+// It replaces all recovery codes after checking a TOTP code.
+func (s *Server) handleRegenerateRecoveryCodes(c *gin.Context) {
+	userID, ok := currentUserID(c)
+	if !ok || !requireSession(c) {
+		return
+	}
+
+	var req TwoFactorCodeRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error":   "invalid_request",
+			"message": "Invalid request body",
+			"details": err.Error(),
+		})
+		return
+	}
+
+	if !s.checkTwoFactorLockout(c, userID) {
+		return
+	}
+
+	codes, err := s.twoFactorService().RegenerateRecoveryCodes(c.Request.Context(), userID, req.Code)
+	s.recordTwoFactorAttempt(c, userID, err)
+	if err != nil {
+		respondTwoFactorError(c, err, "regenerate recovery codes")
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"data": gin.H{"recovery_codes": codes},
+	})
+}
+
+// handleDisableTwoFactor handles POST /api/v1/me/2fa/disable
+// It turns off two-factor authentication after checking a TOTP or recovery code.
+func (s *Server) handleDisableTwoFactor(c *gin.Context) {
+	userID, ok := currentUserID(c)
+	if !ok || !requireSession(c) {
+		return
+	}
+
+	var req TwoFactorCodeRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error":   "invalid_request",
+			"message": "Invalid request body",
+			"details": err.Error(),
+		})
+		return
+	}
+
+	if !s.checkTwoFactorLockout(c, userID) {
+		return
+	}
+
+	err := s.twoFactorService().Disable(c.Request.Context(), userID, req.Code, req.RecoveryCode)
+	s.recordTwoFactorAttempt(c, userID, err)
+	if err != nil {
+		respondTwoFactorError(c, err, "disable two-factor authentication")
+		return
+	}
+
+	// Projects requiring two-factor authentication no longer accept the user
+	go s.revalidateWebSockets(context.Background(), func(client *ws.Client) bool { return client.UserID == userID })
+
+	c.JSON(http.StatusOK, gin.H{
+		"message": "Two-factor authentication disabled",
+	})
+}
+
+// ProjectTwoFactorMiddleware rejects requests to a project that requires two-factor
+// authentication unless the user has it enabled. Service accounts, which cannot log in
+// interactively, are exempt.
+func (s *Server) ProjectTwoFactorMiddleware() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if !s.requireProjectTwoFactor(c, c.Param("projectId")) {
+			return
+		}
+
+		c.Next()
+	}
+}
+
+// requireProjectTwoFactor applies the project two-factor requirement to a request whose
+// project is only known to the handler, such as one taken from the request body. It aborts
+// the request and returns false when the user does not meet it.
+func (s *Server) requireProjectTwoFactor(c *gin.Context, projectID string) bool {
+	value, _ := c.Get("user")
+	user, _ := value.(*models.User)
+	if !s.twoFactorBlocks(c.Request.Context(), projectID, user) {
+		return true
+	}
+
+	if user == nil {
+		c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{
+			"error":   "unauthorized",
+			"message": "Authentication required",
+		})
+		return false
+	}
+
+	c.AbortWithStatusJSON(http.StatusForbidden, gin.H{
+		"error":   "two_factor_required",
+		"message": "This project requires two-factor authentication; enable it for your account to continue",
+	})
+	return false
+}
+
+// twoFactorBlocks reports whether a project requires two-factor authentication that the
+// user (nil when anonymous) does not have. Unknown projects are reported by the handlers.
+func (s *Server) twoFactorBlocks(ctx context.Context, projectID string, user *models.User) bool {
+	if projectID == "" {
+		return false
+	}
+
+	project, err := repository.NewProjectRepository(s.db.DB).GetByID(ctx, projectID)
+	if err != nil || !service.ProjectRequiresTwoFactor(project) {
+		return false
+	}
+
+	if user == nil {
+		return true
+	}
+
+	// Service accounts cannot enroll, so they are only exempt in their own project
+	serviceAccount := user.ServiceProjectID != nil && *user.ServiceProjectID == projectID
+	return user.TOTPEnabledAt == nil && !serviceAccount
+}
@@ -165,6 +165,7 @@ func (s *Server) handleExecuteView(c *gin.Context) {
 	}
 
 	viewService := service.NewViewService(repository.NewViewRepository(s.db.DB), s.cfg.Logging.Debug)
+	viewService.SetCalendar(s.projectCalendar(c.Request.Context(), projectID))
 	tasks, err := viewService.Execute(c.Request.Context(), projectID, viewID)
 	if err != nil {
 		c.JSON(http.StatusBadRequest, gin.H{
@@ -0,0 +1,272 @@
+package api
+
+import (
+	"context"
+	"log"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/tktomaru/taskai/taskai-server/internal/repository"
+	"github.com/tktomaru/taskai/taskai-server/internal/service"
+	"github.com/tktomaru/taskai/taskai-server/internal/webhook"
+	ws "github.com/tktomaru/taskai/taskai-server/internal/websocket"
+)
+
+// webhookBatchSize is the number of deliveries claimed per query by the worker
+const webhookBatchSize = 20
+
+// webhookService creates a webhook service using the configured timeout and retry limit
+func (s *Server) webhookService() *service.WebhookService {
+	return service.NewWebhookService(
+		repository.NewWebhookRepository(s.db.DB),
+		webhook.NewSender(s.cfg.Webhooks.Timeout),
+		s.cfg.Webhooks.MaxAttempts,
+	)
+}
+
+// enqueueWebhooks queues a broadcast event for the webhooks of its project
+func (s *Server) enqueueWebhooks(eventType ws.EventType, projectID, taskID string, data interface{}) {
+	if _, err := s.webhookService().Enqueue(context.Background(), eventType, projectID, taskID, data); err != nil {
+		log.Printf("ERROR: Failed to queue webhook deliveries for %s in project %s: %v", eventType, projectID, err)
+	}
+}
+
+// StartWebhookWorker sends queued webhook deliveries until the context is cancelled
+func (s *Server) StartWebhookWorker(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		// Drain the queue before waiting for the next tick
+		for ctx.Err() == nil {
+			processed, err := s.webhookService().ProcessDue(ctx, webhookBatchSize)
+			if err != nil {
+				log.Printf("ERROR: Webhook delivery failed: %v", err)
+				break
+			}
+			if processed < webhookBatchSize {
+				break
+			}
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// handleListWebhooks handles GET /api/v1/projects/:projectId/webhooks
+func (s *Server) handleListWebhooks(c *gin.Context) {
+	projectID := c.Param("projectId")
+
+	webhooks, err := s.webhookService().List(c.Request.Context(), projectID)
+	if err != nil {
+		log.Printf("ERROR: Failed to list webhooks for project %s: %v", projectID, err)
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error":   "internal_server_error",
+			"message": "Failed to list webhooks",
+			"details": err.Error(),
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"data": webhooks,
+	})
+}
+
+// handleCreateWebhook handles POST /api/v1/projects/:projectId/webhooks
+func (s *Server) handleCreateWebhook(c *gin.Context) {
+	projectID := c.Param("projectId")
+
+	var req service.CreateWebhookRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error":   "invalid_request",
+			"message": "Invalid request body",
+			"details": err.Error(),
+		})
+		return
+	}
+
+	userID, ok := currentUserID(c)
+	if !ok {
+		return
+	}
+	req.CreatedBy = userID
+
+	wh, err := s.webhookService().Create(c.Request.Context(), projectID, &req)
+	if err != nil {
+		log.Printf("ERROR: Failed to create webhook for project %s: %v", projectID, err)
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error":   "validation_error",
+			"message": "Failed to create webhook",
+			"details": err.Error(),
+		})
+		return
+	}
+
+	// The secret is only returned here
+	c.JSON(http.StatusCreated, gin.H{
+		"data": wh,
+	})
+}
+
+// handleGetWebhook handles GET /api/v1/projects/:projectId/webhooks/:webhookId
+func (s *Server) handleGetWebhook(c *gin.Context) {
+	projectID := c.Param("projectId")
+	webhookID := c.Param("webhookId")
+
+	wh, err := s.webhookService().Get(c.Request.Context(), projectID, webhookID)
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{
+			"error":   "not_found",
+			"message": "Webhook not found",
+			"details": err.Error(),
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"data": wh,
+	})
+}
+
+// handleUpdateWebhook handles PUT /api/v1/projects/:projectId/webhooks/:webhookId
+func (s *Server) handleUpdateWebhook(c *gin.Context) {
+	projectID := c.Param("projectId")
+	webhookID := c.Param("webhookId")
+
+	var req service.UpdateWebhookRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error":   "invalid_request",
+			"message": "Invalid request body",
+			"details": err.Error(),
+		})
+		return
+	}
+
+	wh, err := s.webhookService().Update(c.Request.Context(), projectID, webhookID, &req)
+	if err != nil {
+		log.Printf("ERROR: Failed to update webhook %s in project %s: %v", webhookID, projectID, err)
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error":   "validation_error",
+			"message": "Failed to update webhook",
+			"details": err.Error(),
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"data": wh,
+	})
+}
+
+// handleDeleteWebhook handles DELETE /api/v1/projects/:projectId/webhooks/:webhookId
+func (s *Server) handleDeleteWebhook(c *gin.Context) {
+	projectID := c.Param("projectId")
+	webhookID := c.Param("webhookId")
+
+	if err := s.webhookService().Delete(c.Request.Context(), projectID, webhookID); err != nil {
+		c.JSON(http.StatusNotFound, gin.H{
+			"error":   "not_found",
+			"message": "Webhook not found",
+			"details": err.Error(),
+		})
+		return
+	}
+
+	c.JSON(http.StatusNoContent, nil)
+}
+
+// handleListWebhookDeliveries handles GET /api/v1/projects/:projectId/webhooks/:webhookId/deliveries
+func (s *Server) handleListWebhookDeliveries(c *gin.Context) {
+	projectID := c.Param("projectId")
+	webhookID := c.Param("webhookId")
+
+	limit, _ := strconv.Atoi(c.Query("limit"))
+	offset, _ := strconv.Atoi(c.Query("offset"))
+
+	deliveries, err := s.webhookService().Deliveries(c.Request.Context(), projectID, webhookID, limit, offset)
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{
+			"error":   "not_found",
+			"message": "Webhook not found",
+			"details": err.Error(),
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"data": deliveries,
+	})
+}
+
+// handleGetWebhookDelivery handles GET /api/v1/projects/:projectId/webhooks/:webhookId/deliveries/:deliveryId
+func (s *Server) handleGetWebhookDelivery(c *gin.Context) {
+	projectID := c.Param("projectId")
+	webhookID := c.Param("webhookId")
+
+	deliveryID, ok := parseDeliveryID(c)
+	if !ok {
+		return
+	}
+
+	delivery, err := s.webhookService().Delivery(c.Request.Context(), projectID, webhookID, deliveryID)
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{
+			"error":   "not_found",
+			"message": "Webhook delivery not found",
+			"details": err.Error(),
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"data": delivery,
+	})
+}
+
+// handleRedeliverWebhook handles POST /api/v1/projects/:projectId/webhooks/:webhookId/deliveries/:deliveryId/redeliver
+func (s *Server) handleRedeliverWebhook(c *gin.Context) {
+	projectID := c.Param("projectId")
+	webhookID := c.Param("webhookId")
+
+	deliveryID, ok := parseDeliveryID(c)
+	if !ok {
+		return
+	}
+
+	delivery, err := s.webhookService().Redeliver(c.Request.Context(), projectID, webhookID, deliveryID)
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{
+			"error":   "not_found",
+			"message": "Webhook delivery not found",
+			"details": err.Error(),
+		})
+		return
+	}
+
+	c.JSON(http.StatusAccepted, gin.H{
+		"data": delivery,
+	})
+}
+
+func parseDeliveryID(c *gin.Context) (int64, bool) {
+	deliveryID, err := strconv.ParseInt(c.Param("deliveryId"), 10, 64)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error":   "invalid_request",
+			"message": "Invalid delivery ID",
+			"details": err.Error(),
+		})
+		return 0, false
+	}
+
+	return deliveryID, true
+}
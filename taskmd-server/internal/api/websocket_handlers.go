@@ -1,26 +1,157 @@
 package api
 
 import (
+	"context"
+	"errors"
+	"fmt"
 	"log"
 	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
 
 	"github.com/gin-gonic/gin"
 	"github.com/gorilla/websocket"
+	"github.com/tktomaru/taskai/taskai-server/internal/models"
+	"github.com/tktomaru/taskai/taskai-server/internal/repository"
+	"github.com/tktomaru/taskai/taskai-server/internal/service"
 	ws "github.com/tktomaru/taskai/taskai-server/internal/websocket"
 )
 
-var upgrader = websocket.Upgrader{
-	ReadBufferSize:  1024,
-	WriteBufferSize: 1024,
-	CheckOrigin: func(r *http.Request) bool {
-		// Allow all origins for now
-		// In production, this should check against allowed origins
+// Reasons sent in the close frame of revoked connections
+const (
+	closeSessionRevoked = "session revoked"
+	closeTokenRevoked   = "access token revoked"
+	closeAccessRemoved  = "access removed"
+	closeProjectDeleted = "project deleted"
+)
+
+// WebSocketTicketRequest represents a request for a WebSocket ticket
+type WebSocketTicketRequest struct {
+	ProjectID string `json:"project_id"`
+}
+
+// realtimeService creates a service for WebSocket access checks and tickets
+func (s *Server) realtimeService() *service.RealtimeService {
+	return service.NewRealtimeService(
+		repository.NewProjectRepository(s.db.DB),
+		repository.NewUserRepository(s.db.DB),
+		repository.NewSessionRepository(s.db.DB),
+		repository.NewAccessTokenRepository(s.db.DB),
+		repository.NewWebSocketTicketRepository(s.db.DB),
+		s.cfg.WebSocket.TicketTTL,
+	)
+}
+
+// upgrader returns the WebSocket upgrader, which checks the origin of browser connections
+func (s *Server) upgrader() *websocket.Upgrader {
+	return &websocket.Upgrader{
+		ReadBufferSize:  1024,
+		WriteBufferSize: 1024,
+		CheckOrigin:     s.checkOrigin,
+	}
+}
+
+// checkOrigin accepts connections from the server's own origin and from CORS_ORIGINS.
+// Requests without an Origin header do not come from a browser, so a page on another
+// site cannot ride on the user's cookies; they still have to authenticate.
+func (s *Server) checkOrigin(r *http.Request) bool {
+	origin := r.Header.Get("Origin")
+	if origin == "" {
 		return true
-	},
+	}
+
+	if u, err := url.Parse(origin); err == nil && strings.EqualFold(u.Host, r.Host) {
+		return true
+	}
+
+	for _, allowedOrigin := range s.cfg.Server.CORSOrigins {
+		if allowedOrigin == "*" || strings.EqualFold(strings.TrimSuffix(allowedOrigin, "/"), origin) {
+			return true
+		}
+	}
+
+	log.Printf("WARNING: Rejected WebSocket connection from origin %s", origin)
+	return false
+}
+
+// WebSocketAuthMiddleware authenticates a WebSocket upgrade with a one-time ticket from
+// the "ticket" query parameter, or like AuthMiddleware with the cookie or Authorization header
+func (s *Server) WebSocketAuthMiddleware() gin.HandlerFunc {
+	authMiddleware := s.AuthMiddleware()
+
+	return func(c *gin.Context) {
+		secret := c.Query("ticket")
+		if secret == "" {
+			authMiddleware(c)
+			return
+		}
+
+		user, ticket, err := s.realtimeService().RedeemTicket(c.Request.Context(), secret)
+		if err != nil {
+			if !errors.Is(err, repository.ErrWebSocketTicketNotFound) {
+				log.Printf("ERROR: Failed to redeem WebSocket ticket: %v", err)
+			}
+			c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{
+				"error":   "invalid_ticket",
+				"message": "Invalid or expired ticket",
+			})
+			return
+		}
+
+		if ticket.ProjectID != nil && *ticket.ProjectID != c.Param("projectId") {
+			c.AbortWithStatusJSON(http.StatusForbidden, gin.H{
+				"error":   "forbidden",
+				"message": "The ticket was issued for another project",
+			})
+			return
+		}
+
+		c.Set("user_id", user.ID)
+		c.Set("user_email", user.Email)
+		c.Set("user", user)
+		c.Set("session_id", ticket.SessionID)
+
+		c.Next()
+	}
+}
+
+// handleCreateWebSocketTicket handles POST /api/v1/ws/ticket
+// It returns a short-lived, one-time ticket for opening a WebSocket from a browser.
+func (s *Server) handleCreateWebSocketTicket(c *gin.Context) {
+	if _, ok := currentUserID(c); !ok || !requireSession(c) {
+		return
+	}
+
+	var req WebSocketTicketRequest
+	if c.Request.ContentLength > 0 {
+		if err := c.ShouldBindJSON(&req); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{
+				"error":   "invalid_request",
+				"message": "Invalid request body",
+				"details": err.Error(),
+			})
+			return
+		}
+	}
+
+	user := c.MustGet("user").(*models.User)
+	ticket, err := s.realtimeService().IssueTicket(c.Request.Context(), user, c.GetString("session_id"), req.ProjectID)
+	if err != nil {
+		respondRealtimeError(c, err, "issue WebSocket ticket")
+		return
+	}
+
+	c.JSON(http.StatusCreated, gin.H{
+		"data": ticket,
+	})
 }
 
 // handleWebSocket handles WebSocket connections for real-time updates
 // GET /api/v1/projects/:projectId/ws
+// A client that reconnects passes the seq of the last message it received in "since"
+// and is sent the messages it missed, or told to resync.
 func (s *Server) handleWebSocket(c *gin.Context) {
 	projectID := c.Param("projectId")
 	if projectID == "" {
@@ -31,31 +162,150 @@ func (s *Server) handleWebSocket(c *gin.Context) {
 		return
 	}
 
-	// Get user ID from context (set by auth middleware)
-	userID := ""
-	if userIDVal, exists := c.Get("user_id"); exists {
-		userID = userIDVal.(string)
+	var since *uint64
+	if value := c.Query("since"); value != "" {
+		seq, err := strconv.ParseUint(value, 10, 64)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{
+				"error":   "invalid_request",
+				"message": "since must be a message sequence number",
+				"details": err.Error(),
+			})
+			return
+		}
+		since = &seq
+	}
+
+	user := c.MustGet("user").(*models.User)
+	if err := s.realtimeService().AuthorizeProject(c.Request.Context(), projectID, user); err != nil {
+		respondRealtimeError(c, err, "authorize WebSocket connection")
+		return
 	}
 
 	// Upgrade HTTP connection to WebSocket
-	conn, err := upgrader.Upgrade(c.Writer, c.Request, nil)
+	conn, err := s.upgrader().Upgrade(c.Writer, c.Request, nil)
 	if err != nil {
 		log.Printf("Failed to upgrade WebSocket: %v", err)
 		return
 	}
 
 	// Create and register client
-	client := ws.NewClient(s.wsHub, conn, projectID, userID)
+	client := s.newWebSocketClient(c, conn, projectID)
+	if since != nil {
+		client.Resume(*since)
+	}
 	s.wsHub.Register <- client
 
 	// Start client goroutines
 	client.Start()
 
-	log.Printf("WebSocket connection established for project %s (user: %s)", projectID, userID)
+	log.Printf("WebSocket connection established for project %s (user: %s)", projectID, user.ID)
+}
+
+// newWebSocketClient creates a client for the authenticated user of a request, remembering
+// the session or access token it used
+func (s *Server) newWebSocketClient(c *gin.Context, conn *websocket.Conn, projectID string) *ws.Client {
+	client := ws.NewClient(s.wsHub, conn, projectID, c.GetString("user_id"))
+	client.SessionID = c.GetString("session_id")
+	if value, exists := c.Get("access_token"); exists {
+		client.AccessTokenID = value.(*models.AccessToken).ID
+	}
+
+	return client
+}
+
+// respondRealtimeError reports an error of a WebSocket access check
+func respondRealtimeError(c *gin.Context, err error, action string) {
+	if errors.Is(err, service.ErrProjectAccessDenied) {
+		c.JSON(http.StatusForbidden, gin.H{
+			"error":   "forbidden",
+			"message": err.Error(),
+		})
+		return
+	}
+
+	log.Printf("ERROR: Failed to %s: %v", action, err)
+	c.JSON(http.StatusInternalServerError, gin.H{
+		"error":   "internal_server_error",
+		"message": "Failed to " + action,
+		"details": err.Error(),
+	})
+}
+
+// revokeWebSockets closes the live connections that match
+func (s *Server) revokeWebSockets(match func(*ws.Client) bool, reason string) {
+	s.wsHub.Revoke(match, reason)
+}
+
+// revalidateWebSockets re-checks the access of the live connections that match and closes
+// those that lost it
+func (s *Server) revalidateWebSockets(ctx context.Context, match func(*ws.Client) bool) {
+	realtimeService := s.realtimeService()
+
+	for _, client := range s.wsHub.Clients() {
+		if !match(client) {
+			continue
+		}
+
+		err := realtimeService.Revalidate(ctx, service.Connection{
+			UserID:        client.UserID,
+			SessionID:     client.SessionID,
+			AccessTokenID: client.AccessTokenID,
+			ProjectID:     client.ProjectID,
+		})
+		switch {
+		case err == nil:
+		case errors.Is(err, service.ErrCredentialRevoked):
+			s.revokeWebSockets(func(c *ws.Client) bool { return c == client }, closeSessionRevoked)
+		case errors.Is(err, service.ErrProjectAccessDenied):
+			s.revokeWebSockets(func(c *ws.Client) bool { return c == client }, closeAccessRemoved)
+		default:
+			log.Printf("ERROR: Failed to revalidate WebSocket connection of user %s: %v", client.UserID, err)
+		}
+	}
+}
+
+// StartWebSocketRevalidation periodically closes live connections whose session or token
+// has been revoked or whose user has lost access to the project, until the context is cancelled.
+// Changes made through the API close connections right away; this catches the rest, such
+// as expiry and membership changes made directly in the database.
+func (s *Server) StartWebSocketRevalidation(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			s.revalidateWebSockets(ctx, func(*ws.Client) bool { return true })
+		}
+	}
+}
+
+// resolveWebSocketView returns the tasks of a saved view for clients of a user subscribed to it.
+// The private views of other users are not found.
+func (s *Server) resolveWebSocketView(ctx context.Context, projectID, viewID, userID string) ([]string, error) {
+	view, err := repository.NewViewRepository(s.db.DB).GetByID(ctx, projectID, viewID)
+	if err != nil {
+		return nil, err
+	}
+	if view.Scope == models.ViewScopePrivate && (view.OwnerUserID == nil || *view.OwnerUserID != userID) {
+		return nil, fmt.Errorf("view not found: %s", viewID)
+	}
+
+	return s.viewWatchService(s.notificationService()).Evaluate(ctx, view)
+}
+
+// checkWebSocketTask fails for tasks that are not in a project
+func (s *Server) checkWebSocketTask(ctx context.Context, projectID, taskID string) error {
+	_, err := repository.NewTaskRepository(s.db.DB).GetByID(ctx, projectID, taskID)
+	return err
 }
 
 // handleWebSocketStats returns WebSocket connection statistics
 // GET /api/v1/ws/stats
+// For a project, it also reports who is viewing and editing each task.
 func (s *Server) handleWebSocketStats(c *gin.Context) {
 	projectID := c.Query("project_id")
 
@@ -64,8 +314,23 @@ func (s *Server) handleWebSocketStats(c *gin.Context) {
 	}
 
 	if projectID != "" {
+		value, _ := c.Get("user")
+		user, ok := value.(*models.User)
+		if !ok || user == nil {
+			c.JSON(http.StatusUnauthorized, gin.H{
+				"error":   "unauthorized",
+				"message": "Authentication required",
+			})
+			return
+		}
+		if err := s.realtimeService().AuthorizeProject(c.Request.Context(), projectID, user); err != nil {
+			respondRealtimeError(c, err, "authorize WebSocket statistics")
+			return
+		}
+
 		stats["project_id"] = projectID
 		stats["client_count"] = s.wsHub.GetClientCount(projectID)
+		stats["presence"] = s.wsHub.Presence(projectID)
 	}
 
 	c.JSON(http.StatusOK, stats)
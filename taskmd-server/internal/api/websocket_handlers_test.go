@@ -0,0 +1,25 @@
+package api
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+)
+
+func TestHandleWebSocketStats_AnonymousProjectStats(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	s := &Server{}
+	router := gin.New()
+	router.GET("/api/v1/ws/stats", s.handleWebSocketStats)
+
+	recorder := httptest.NewRecorder()
+	request := httptest.NewRequest(http.MethodGet, "/api/v1/ws/stats?project_id=web", nil)
+	router.ServeHTTP(recorder, request)
+
+	if recorder.Code != http.StatusUnauthorized {
+		t.Errorf("status = %d, want %d: %s", recorder.Code, http.StatusUnauthorized, recorder.Body.String())
+	}
+}
@@ -0,0 +1,66 @@
+package auth
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+)
+
+// twoFactorPurpose marks challenge tokens of logins waiting for a second factor
+const twoFactorPurpose = "2fa"
+
+// ChallengeClaims represents the claims of a challenge token. A challenge token proves
+// that a password was correct and is exchanged for a session once the second factor has
+// been checked. It carries no session and is rejected as an access token.
+type ChallengeClaims struct {
+	UserID  string `json:"user_id"`
+	Purpose string `json:"purpose"`
+	jwt.RegisteredClaims
+}
+
+// GenerateChallengeToken generates a challenge token for a user, valid for ttl
+func (m *JWTManager) GenerateChallengeToken(userID string, ttl time.Duration) (string, error) {
+	now := time.Now()
+	claims := &ChallengeClaims{
+		UserID:  userID,
+		Purpose: twoFactorPurpose,
+		RegisteredClaims: jwt.RegisteredClaims{
+			ExpiresAt: jwt.NewNumericDate(now.Add(ttl)),
+			IssuedAt:  jwt.NewNumericDate(now),
+			NotBefore: jwt.NewNumericDate(now),
+		},
+	}
+
+	token := jwt.NewWithClaims(jwt.SigningMethodHS256, claims)
+	tokenString, err := token.SignedString([]byte(m.secret))
+	if err != nil {
+		return "", fmt.Errorf("failed to sign challenge token: %w", err)
+	}
+
+	return tokenString, nil
+}
+
+// ValidateChallengeToken validates a challenge token and returns its claims
+func (m *JWTManager) ValidateChallengeToken(tokenString string) (*ChallengeClaims, error) {
+	token, err := jwt.ParseWithClaims(tokenString, &ChallengeClaims{}, func(token *jwt.Token) (interface{}, error) {
+		if _, ok := token.Method.(*jwt.SigningMethodHMAC); !ok {
+			return nil, fmt.Errorf("unexpected signing method: %v", token.Header["alg"])
+		}
+		return []byte(m.secret), nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse challenge token: %w", err)
+	}
+
+	claims, ok := token.Claims.(*ChallengeClaims)
+	if !ok || !token.Valid {
+		return nil, fmt.Errorf("invalid challenge token")
+	}
+
+	if claims.Purpose != twoFactorPurpose || claims.UserID == "" {
+		return nil, fmt.Errorf("token is not a two-factor challenge")
+	}
+
+	return claims, nil
+}
@@ -0,0 +1,56 @@
+package auth
+
+import (
+	"testing"
+	"time"
+
+	"github.com/tktomaru/taskai/taskai-server/internal/models"
+)
+
+func TestJWTManager_ChallengeToken(t *testing.T) {
+	manager := NewJWTManager("test-secret-key-for-jwt", 15*time.Minute)
+
+	token, err := manager.GenerateChallengeToken("user123", 5*time.Minute)
+	if err != nil {
+		t.Fatalf("GenerateChallengeToken() error: %v", err)
+	}
+
+	claims, err := manager.ValidateChallengeToken(token)
+	if err != nil {
+		t.Fatalf("ValidateChallengeToken() error: %v", err)
+	}
+	if claims.UserID != "user123" {
+		t.Errorf("ValidateChallengeToken() claims = %+v", claims)
+	}
+
+	// A challenge token is no access token: it names no session
+	if accessClaims, err := manager.ValidateToken(token); err == nil && accessClaims.SessionID != "" {
+		t.Errorf("challenge token validated as a session token with session %q", accessClaims.SessionID)
+	}
+}
+
+func TestJWTManager_ChallengeTokenRejectsAccessTokens(t *testing.T) {
+	manager := NewJWTManager("test-secret-key-for-jwt", 15*time.Minute)
+
+	accessToken, err := manager.GenerateSessionToken(&models.User{ID: "user123", Email: "test@example.com"}, "sess-1")
+	if err != nil {
+		t.Fatalf("GenerateSessionToken() error: %v", err)
+	}
+
+	if _, err := manager.ValidateChallengeToken(accessToken); err == nil {
+		t.Error("ValidateChallengeToken() accepted an access token")
+	}
+}
+
+func TestJWTManager_ChallengeTokenExpiration(t *testing.T) {
+	manager := NewJWTManager("test-secret-key-for-jwt", 15*time.Minute)
+
+	token, err := manager.GenerateChallengeToken("user123", -time.Second)
+	if err != nil {
+		t.Fatalf("GenerateChallengeToken() error: %v", err)
+	}
+
+	if _, err := manager.ValidateChallengeToken(token); err == nil {
+		t.Error("ValidateChallengeToken() accepted an expired token")
+	}
+}
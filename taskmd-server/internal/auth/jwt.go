@@ -10,8 +10,9 @@ import (
 
 // Claims represents JWT claims
 type Claims struct {
-	UserID string `json:"user_id"`
-	Email  string `json:"email"`
+	UserID    string `json:"user_id"`
+	Email     string `json:"email"`
+	SessionID string `json:"sid,omitempty"`
 	jwt.RegisteredClaims
 }
 
@@ -31,10 +32,16 @@ func NewJWTManager(secret string, expiresIn time.Duration) *JWTManager {
 
 // GenerateToken generates a JWT token for a user
 func (m *JWTManager) GenerateToken(user *models.User) (string, error) {
+	return m.GenerateSessionToken(user, "")
+}
+
+// GenerateSessionToken generates a JWT access token bound to a server-side session
+func (m *JWTManager) GenerateSessionToken(user *models.User, sessionID string) (string, error) {
 	now := time.Now()
 	claims := &Claims{
-		UserID: user.ID,
-		Email:  user.Email,
+		UserID:    user.ID,
+		Email:     user.Email,
+		SessionID: sessionID,
 		RegisteredClaims: jwt.RegisteredClaims{
 			ExpiresAt: jwt.NewNumericDate(now.Add(m.expiresIn)),
 			IssuedAt:  jwt.NewNumericDate(now),
@@ -51,6 +58,11 @@ func (m *JWTManager) GenerateToken(user *models.User) (string, error) {
 	return tokenString, nil
 }
 
+// ExpiresIn returns the lifetime of generated tokens
+func (m *JWTManager) ExpiresIn() time.Duration {
+	return m.expiresIn
+}
+
 // ValidateToken validates a JWT token and returns claims
 func (m *JWTManager) ValidateToken(tokenString string) (*Claims, error) {
 	token, err := jwt.ParseWithClaims(tokenString, &Claims{}, func(token *jwt.Token) (interface{}, error) {
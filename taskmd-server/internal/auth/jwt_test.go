@@ -123,3 +123,38 @@ func TestJWTManager_DifferentSecrets(t *testing.T) {
 		t.Error("ValidateToken() should fail when using different secret")
 	}
 }
+
+func TestJWTManager_GenerateSessionToken(t *testing.T) {
+	manager := NewJWTManager("test-secret-key", time.Minute)
+	user := &models.User{
+		ID:    "user-123",
+		Email: "test@example.com",
+		Name:  "Test User",
+	}
+
+	token, err := manager.GenerateSessionToken(user, "sess-1-abcdef")
+	if err != nil {
+		t.Fatalf("Failed to generate token: %v", err)
+	}
+
+	claims, err := manager.ValidateToken(token)
+	if err != nil {
+		t.Fatalf("Failed to validate token: %v", err)
+	}
+	if claims.SessionID != "sess-1-abcdef" {
+		t.Errorf("Expected session ID sess-1-abcdef, got %s", claims.SessionID)
+	}
+
+	// Tokens issued without a session carry no session ID
+	token, err = manager.GenerateToken(user)
+	if err != nil {
+		t.Fatalf("Failed to generate token: %v", err)
+	}
+	claims, err = manager.ValidateToken(token)
+	if err != nil {
+		t.Fatalf("Failed to validate token: %v", err)
+	}
+	if claims.SessionID != "" {
+		t.Errorf("Expected no session ID, got %s", claims.SessionID)
+	}
+}
@@ -0,0 +1,102 @@
+package auth
+
+import (
+	"bufio"
+	"crypto/sha1"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+	"unicode/utf8"
+)
+
+// Password policy violations
+var (
+	ErrPasswordTooShort = errors.New("password is too short")
+	ErrPasswordBreached = errors.New("password appears in a list of breached passwords")
+	ErrPasswordIsEmail  = errors.New("password must not be the email address")
+)
+
+// PasswordPolicy decides which passwords users may choose
+type PasswordPolicy struct {
+	MinLength int
+
+	// SHA-1 hashes (upper-case hex) of known breached passwords
+	breached map[string]struct{}
+}
+
+// NewPasswordPolicy creates a password policy. breachedFile, when set, lists breached
+// passwords one per line, either in plain text or as SHA-1 hashes in the
+// "HASH" or "HASH:count" format of breach corpus downloads.
+func NewPasswordPolicy(minLength int, breachedFile string) (*PasswordPolicy, error) {
+	policy := &PasswordPolicy{MinLength: minLength, breached: make(map[string]struct{})}
+	if breachedFile == "" {
+		return policy, nil
+	}
+
+	f, err := os.Open(breachedFile)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open breached password list: %w", err)
+	}
+	defer f.Close()
+
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		policy.AddBreached(scanner.Text())
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("failed to read breached password list: %w", err)
+	}
+
+	return policy, nil
+}
+
+// AddBreached adds a line of a breached password list to the policy
+func (p *PasswordPolicy) AddBreached(line string) {
+	line = strings.TrimRight(line, "\r")
+	if line == "" {
+		return
+	}
+
+	if hash, _, _ := strings.Cut(line, ":"); isSHA1Hex(hash) {
+		p.breached[strings.ToUpper(hash)] = struct{}{}
+		return
+	}
+
+	p.breached[sha1Hex(line)] = struct{}{}
+}
+
+// Validate checks a password chosen by the owner of an email address
+func (p *PasswordPolicy) Validate(password, email string) error {
+	if utf8.RuneCountInString(password) < p.MinLength {
+		return fmt.Errorf("%w: at least %d characters are required", ErrPasswordTooShort, p.MinLength)
+	}
+
+	email = strings.TrimSpace(email)
+	localPart, _, _ := strings.Cut(email, "@")
+	if email != "" && (strings.EqualFold(password, email) || strings.EqualFold(password, localPart)) {
+		return ErrPasswordIsEmail
+	}
+
+	if _, ok := p.breached[sha1Hex(password)]; ok {
+		return ErrPasswordBreached
+	}
+
+	return nil
+}
+
+// sha1Hex returns the upper-case hex SHA-1 of a password, as used by breach corpora
+func sha1Hex(password string) string {
+	sum := sha1.Sum([]byte(password))
+	return strings.ToUpper(hex.EncodeToString(sum[:]))
+}
+
+// isSHA1Hex reports whether a string is a hex-encoded SHA-1 hash
+func isSHA1Hex(s string) bool {
+	if len(s) != 2*sha1.Size {
+		return false
+	}
+	_, err := hex.DecodeString(s)
+	return err == nil
+}
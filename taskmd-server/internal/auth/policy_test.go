@@ -0,0 +1,55 @@
+package auth
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestPasswordPolicy_Validate(t *testing.T) {
+	dir := t.TempDir()
+	list := filepath.Join(dir, "breached.txt")
+	content := "password123456\r\n" +
+		"\n" +
+		// Hashed entries carry a breach count
+		sha1Hex("letmein-please") + ":42\n"
+	if err := os.WriteFile(list, []byte(content), 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	policy, err := NewPasswordPolicy(10, list)
+	if err != nil {
+		t.Fatalf("NewPasswordPolicy() error: %v", err)
+	}
+
+	tests := []struct {
+		name     string
+		password string
+		email    string
+		want     error
+	}{
+		{"valid", "correct horse battery", "alice@example.com", nil},
+		{"too short", "short", "alice@example.com", ErrPasswordTooShort},
+		{"multi-byte characters count once", "パスワード", "alice@example.com", ErrPasswordTooShort},
+		{"plain-text breached", "password123456", "alice@example.com", ErrPasswordBreached},
+		{"hashed breached", "letmein-please", "alice@example.com", ErrPasswordBreached},
+		{"same as email", "Alice.Smith@Example.com", "alice.smith@example.com", ErrPasswordIsEmail},
+		{"same as local part", "alice.smith", "alice.smith@example.com", ErrPasswordIsEmail},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := policy.Validate(tt.password, tt.email)
+			if !errors.Is(err, tt.want) {
+				t.Errorf("Validate(%q) = %v, want %v", tt.password, err, tt.want)
+			}
+		})
+	}
+}
+
+func TestNewPasswordPolicy_MissingFile(t *testing.T) {
+	if _, err := NewPasswordPolicy(10, filepath.Join(t.TempDir(), "missing.txt")); err == nil {
+		t.Error("NewPasswordPolicy() should fail when the list cannot be read")
+	}
+}
@@ -0,0 +1,25 @@
+package auth
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+)
+
+// GenerateOpaqueToken generates a random bearer token with a prefix that tells its kind
+func GenerateOpaqueToken(prefix string) (string, error) {
+	b := make([]byte, 32)
+	if _, err := rand.Read(b); err != nil {
+		return "", fmt.Errorf("failed to generate token: %w", err)
+	}
+
+	return prefix + hex.EncodeToString(b), nil
+}
+
+// HashToken hashes an opaque token for storage and lookup.
+// Tokens carry 256 bits of entropy, so a plain SHA-256 is sufficient.
+func HashToken(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:])
+}
@@ -0,0 +1,33 @@
+package auth
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestGenerateOpaqueToken(t *testing.T) {
+	first, err := GenerateOpaqueToken("rt_")
+	if err != nil {
+		t.Fatalf("GenerateOpaqueToken() error: %v", err)
+	}
+	second, _ := GenerateOpaqueToken("rt_")
+
+	if !strings.HasPrefix(first, "rt_") || len(first) != len("rt_")+64 {
+		t.Errorf("GenerateOpaqueToken() = %q, want rt_ followed by 64 hex characters", first)
+	}
+	if first == second {
+		t.Error("GenerateOpaqueToken() returned the same token twice")
+	}
+}
+
+func TestHashToken(t *testing.T) {
+	if HashToken("rt_a") != HashToken("rt_a") {
+		t.Error("HashToken() must be deterministic")
+	}
+	if HashToken("rt_a") == HashToken("rt_b") {
+		t.Error("different tokens must have different hashes")
+	}
+	if HashToken("rt_a") == "rt_a" {
+		t.Error("HashToken() must not return the token")
+	}
+}
@@ -0,0 +1,258 @@
+package calendar
+
+import (
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+)
+
+const dateLayout = "2006-01-02"
+
+// SettingsKey is the key under which a calendar is stored in project settings
+const SettingsKey = "calendar"
+
+// maxScanDays bounds searches for the next working day so that a
+// misconfigured calendar (e.g. every weekday marked as weekend) cannot loop forever
+const maxScanDays = 3660
+
+// Calendar describes the working days of a project
+type Calendar struct {
+	// Weekends are the days of the week that are never working days
+	Weekends map[time.Weekday]bool
+
+	// Holidays are non-working dates, keyed by YYYY-MM-DD
+	Holidays map[string]string
+
+	// RollForward moves dates from task metadata off non-working days
+	RollForward bool
+
+	// SLA maps a priority (P0..P4) to the number of business days allowed
+	SLA map[string]int
+}
+
+// New creates a calendar with the given weekends and no holidays
+func New(weekends ...time.Weekday) *Calendar {
+	cal := &Calendar{
+		Weekends: make(map[time.Weekday]bool),
+		Holidays: make(map[string]string),
+		SLA:      make(map[string]int),
+	}
+	for _, wd := range weekends {
+		cal.Weekends[wd] = true
+	}
+	return cal
+}
+
+// Default returns a calendar with Saturday and Sunday as weekends
+func Default() *Calendar {
+	return New(time.Saturday, time.Sunday)
+}
+
+// AddHoliday marks a date as a non-working day
+func (c *Calendar) AddHoliday(date time.Time, name string) {
+	c.Holidays[date.Format(dateLayout)] = name
+}
+
+// IsWorkingDay reports whether the given date is a working day
+func (c *Calendar) IsWorkingDay(date time.Time) bool {
+	if c.Weekends[date.Weekday()] {
+		return false
+	}
+	if _, ok := c.Holidays[date.Format(dateLayout)]; ok {
+		return false
+	}
+	return true
+}
+
+// NextWorkingDay returns the date itself if it is a working day,
+// otherwise the first working day after it
+func (c *Calendar) NextWorkingDay(date time.Time) time.Time {
+	d := date
+	for i := 0; i < maxScanDays; i++ {
+		if c.IsWorkingDay(d) {
+			return d
+		}
+		d = d.AddDate(0, 0, 1)
+	}
+	return date
+}
+
+// AddBusinessDays moves date by n working days (n may be negative).
+// Adding zero business days rolls the date forward to a working day.
+func (c *Calendar) AddBusinessDays(date time.Time, n int) time.Time {
+	if n == 0 {
+		return c.NextWorkingDay(date)
+	}
+
+	step := 1
+	if n < 0 {
+		step = -1
+		n = -n
+	}
+
+	d := date
+	for scanned := 0; n > 0 && scanned < maxScanDays; scanned++ {
+		d = d.AddDate(0, 0, step)
+		if c.IsWorkingDay(d) {
+			n--
+		}
+	}
+	return d
+}
+
+// BusinessDaysBetween counts the working days in (from, to].
+// The result is negative when to is before from.
+func (c *Calendar) BusinessDaysBetween(from, to time.Time) int {
+	from = truncateToDate(from)
+	to = truncateToDate(to)
+
+	sign := 1
+	if to.Before(from) {
+		from, to = to, from
+		sign = -1
+	}
+
+	count := 0
+	for d := from.AddDate(0, 0, 1); !d.After(to); d = d.AddDate(0, 0, 1) {
+		if c.IsWorkingDay(d) {
+			count++
+		}
+	}
+	return sign * count
+}
+
+// OverdueBusinessDays returns how many working days have passed since the due date.
+// It returns 0 when the task is not overdue.
+func (c *Calendar) OverdueBusinessDays(due, now time.Time) int {
+	days := c.BusinessDaysBetween(due, now)
+	if days < 0 {
+		return 0
+	}
+	return days
+}
+
+// SLADueDate returns the SLA deadline for a priority counted from start.
+// ok is false when the calendar has no SLA for the priority.
+func (c *Calendar) SLADueDate(priority string, start time.Time) (deadline time.Time, ok bool) {
+	days, ok := c.SLA[priority]
+	if !ok {
+		return time.Time{}, false
+	}
+	return c.AddBusinessDays(truncateToDate(start), days), true
+}
+
+// HolidayList returns the holidays sorted by date
+func (c *Calendar) HolidayList() []Holiday {
+	holidays := make([]Holiday, 0, len(c.Holidays))
+	for date, name := range c.Holidays {
+		holidays = append(holidays, Holiday{Date: date, Name: name})
+	}
+	sort.Slice(holidays, func(i, j int) bool {
+		return holidays[i].Date < holidays[j].Date
+	})
+	return holidays
+}
+
+// Holiday represents a single non-working date
+type Holiday struct {
+	Date string `json:"date"`
+	Name string `json:"name,omitempty"`
+}
+
+// Settings is the JSON representation of a calendar stored in project settings
+type Settings struct {
+	Weekends    []string       `json:"weekends"`
+	Holidays    []Holiday      `json:"holidays"`
+	RollForward bool           `json:"roll_forward"`
+	SLA         map[string]int `json:"sla_business_days,omitempty"`
+}
+
+// FromSettings builds a calendar from its settings representation
+func FromSettings(s *Settings) (*Calendar, error) {
+	cal := New()
+
+	for _, name := range s.Weekends {
+		wd, err := parseWeekday(name)
+		if err != nil {
+			return nil, err
+		}
+		cal.Weekends[wd] = true
+	}
+
+	for _, h := range s.Holidays {
+		date, err := time.Parse(dateLayout, h.Date)
+		if err != nil {
+			return nil, fmt.Errorf("invalid holiday date %q: %w", h.Date, err)
+		}
+		cal.AddHoliday(date, h.Name)
+	}
+
+	for priority, days := range s.SLA {
+		if days < 0 {
+			return nil, fmt.Errorf("invalid SLA for %s: must not be negative", priority)
+		}
+		cal.SLA[priority] = days
+	}
+
+	cal.RollForward = s.RollForward
+
+	return cal, nil
+}
+
+// FromProjectSettings loads the calendar stored in a project's settings.
+// Projects without a calendar get the Default calendar.
+func FromProjectSettings(settings map[string]interface{}) (*Calendar, error) {
+	raw, ok := settings[SettingsKey]
+	if !ok || raw == nil {
+		return Default(), nil
+	}
+
+	data, err := json.Marshal(raw)
+	if err != nil {
+		return nil, fmt.Errorf("failed to encode calendar settings: %w", err)
+	}
+
+	var s Settings
+	if err := json.Unmarshal(data, &s); err != nil {
+		return nil, fmt.Errorf("invalid calendar settings: %w", err)
+	}
+
+	return FromSettings(&s)
+}
+
+// ToSettings converts the calendar to its settings representation
+func (c *Calendar) ToSettings() *Settings {
+	s := &Settings{
+		Weekends:    []string{},
+		Holidays:    c.HolidayList(),
+		RollForward: c.RollForward,
+		SLA:         c.SLA,
+	}
+
+	for wd := time.Sunday; wd <= time.Saturday; wd++ {
+		if c.Weekends[wd] {
+			s.Weekends = append(s.Weekends, strings.ToLower(wd.String()))
+		}
+	}
+
+	return s
+}
+
+// parseWeekday converts a weekday name (e.g. "saturday" or "sat") to time.Weekday
+func parseWeekday(name string) (time.Weekday, error) {
+	name = strings.ToLower(strings.TrimSpace(name))
+	for wd := time.Sunday; wd <= time.Saturday; wd++ {
+		full := strings.ToLower(wd.String())
+		if name == full || name == full[:3] {
+			return wd, nil
+		}
+	}
+	return time.Sunday, fmt.Errorf("invalid weekday: %s", name)
+}
+
+// truncateToDate strips the time of day, keeping the location
+func truncateToDate(t time.Time) time.Time {
+	return time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, t.Location())
+}
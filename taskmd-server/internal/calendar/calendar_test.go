@@ -0,0 +1,193 @@
+package calendar
+
+import (
+	"strings"
+	"testing"
+	"time"
+)
+
+func date(s string) time.Time {
+	d, err := time.Parse("2006-01-02", s)
+	if err != nil {
+		panic(err)
+	}
+	return d
+}
+
+func TestCalendar_AddBusinessDays(t *testing.T) {
+	cal := Default()
+	cal.AddHoliday(date("2026-01-01"), "New Year")
+
+	tests := []struct {
+		name  string
+		start string
+		days  int
+		want  string
+	}{
+		{name: "within week", start: "2025-12-22", days: 2, want: "2025-12-24"},
+		{name: "skip weekend", start: "2025-12-26", days: 1, want: "2025-12-29"},
+		{name: "skip holiday", start: "2025-12-31", days: 1, want: "2026-01-02"},
+		{name: "five business days", start: "2025-12-29", days: 5, want: "2026-01-06"},
+		{name: "backwards over weekend", start: "2025-12-29", days: -1, want: "2025-12-26"},
+		{name: "zero rolls forward", start: "2025-12-27", days: 0, want: "2025-12-29"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := cal.AddBusinessDays(date(tt.start), tt.days).Format("2006-01-02")
+			if got != tt.want {
+				t.Errorf("AddBusinessDays(%s, %d) = %s, want %s", tt.start, tt.days, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestCalendar_BusinessDaysBetween(t *testing.T) {
+	cal := Default()
+
+	tests := []struct {
+		name string
+		from string
+		to   string
+		want int
+	}{
+		{name: "same day", from: "2025-12-22", to: "2025-12-22", want: 0},
+		{name: "over weekend", from: "2025-12-26", to: "2025-12-29", want: 1},
+		{name: "full week", from: "2025-12-22", to: "2025-12-29", want: 5},
+		{name: "reverse", from: "2025-12-29", to: "2025-12-26", want: -1},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := cal.BusinessDaysBetween(date(tt.from), date(tt.to))
+			if got != tt.want {
+				t.Errorf("BusinessDaysBetween(%s, %s) = %d, want %d", tt.from, tt.to, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestCalendar_OverdueAndSLA(t *testing.T) {
+	cal := Default()
+	cal.SLA["P1"] = 2
+
+	if got := cal.OverdueBusinessDays(date("2025-12-26"), date("2025-12-30")); got != 2 {
+		t.Errorf("OverdueBusinessDays() = %d, want 2", got)
+	}
+
+	if got := cal.OverdueBusinessDays(date("2025-12-30"), date("2025-12-26")); got != 0 {
+		t.Errorf("OverdueBusinessDays() for future due date = %d, want 0", got)
+	}
+
+	deadline, ok := cal.SLADueDate("P1", date("2025-12-25"))
+	if !ok {
+		t.Fatal("SLADueDate() ok = false, want true")
+	}
+	if got := deadline.Format("2006-01-02"); got != "2025-12-29" {
+		t.Errorf("SLADueDate() = %s, want 2025-12-29", got)
+	}
+
+	if _, ok := cal.SLADueDate("P3", date("2025-12-25")); ok {
+		t.Error("SLADueDate() ok = true for priority without SLA")
+	}
+}
+
+func TestFromProjectSettings(t *testing.T) {
+	cal, err := FromProjectSettings(map[string]interface{}{})
+	if err != nil {
+		t.Fatalf("FromProjectSettings() error: %v", err)
+	}
+	if cal.IsWorkingDay(date("2025-12-27")) {
+		t.Error("default calendar should treat Saturday as non-working")
+	}
+
+	settings := map[string]interface{}{
+		"calendar": map[string]interface{}{
+			"weekends":     []interface{}{"fri", "saturday"},
+			"holidays":     []interface{}{map[string]interface{}{"date": "2025-12-25", "name": "Christmas"}},
+			"roll_forward": true,
+		},
+	}
+
+	cal, err = FromProjectSettings(settings)
+	if err != nil {
+		t.Fatalf("FromProjectSettings() error: %v", err)
+	}
+	if !cal.RollForward {
+		t.Error("RollForward = false, want true")
+	}
+	if cal.IsWorkingDay(date("2025-12-26")) {
+		t.Error("Friday should be a weekend")
+	}
+	if !cal.IsWorkingDay(date("2025-12-28")) {
+		t.Error("Sunday should be a working day")
+	}
+	if cal.IsWorkingDay(date("2025-12-25")) {
+		t.Error("holiday should be non-working")
+	}
+
+	_, err = FromProjectSettings(map[string]interface{}{
+		"calendar": map[string]interface{}{"weekends": []interface{}{"someday"}},
+	})
+	if err == nil {
+		t.Error("FromProjectSettings() expected error for invalid weekday")
+	}
+}
+
+func TestParseICal(t *testing.T) {
+	ics := strings.Join([]string{
+		"BEGIN:VCALENDAR",
+		"VERSION:2.0",
+		"BEGIN:VEVENT",
+		"DTSTART;VALUE=DATE:20260101",
+		"DTEND;VALUE=DATE:20260104",
+		"SUMMARY:New Year\\, holidays",
+		"END:VEVENT",
+		"BEGIN:VEVENT",
+		"DTSTART:20260211T000000Z",
+		"SUMMARY:Foundation",
+		"  Day",
+		"END:VEVENT",
+		"END:VCALENDAR",
+	}, "\r\n")
+
+	holidays, err := ParseICal(strings.NewReader(ics))
+	if err != nil {
+		t.Fatalf("ParseICal() error: %v", err)
+	}
+
+	want := []Holiday{
+		{Date: "2026-01-01", Name: "New Year, holidays"},
+		{Date: "2026-01-02", Name: "New Year, holidays"},
+		{Date: "2026-01-03", Name: "New Year, holidays"},
+		{Date: "2026-02-11", Name: "Foundation Day"},
+	}
+
+	if len(holidays) != len(want) {
+		t.Fatalf("ParseICal() got %d holidays, want %d: %+v", len(holidays), len(want), holidays)
+	}
+	for i := range want {
+		if holidays[i] != want[i] {
+			t.Errorf("holiday[%d] = %+v, want %+v", i, holidays[i], want[i])
+		}
+	}
+}
+
+func TestParseICal_Invalid(t *testing.T) {
+	tests := []struct {
+		name string
+		ics  string
+	}{
+		{name: "missing DTSTART", ics: "BEGIN:VEVENT\nSUMMARY:x\nEND:VEVENT"},
+		{name: "bad date", ics: "BEGIN:VEVENT\nDTSTART:2026\nEND:VEVENT"},
+		{name: "unterminated", ics: "BEGIN:VEVENT\nDTSTART:20260101"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := ParseICal(strings.NewReader(tt.ics)); err == nil {
+				t.Error("ParseICal() expected error")
+			}
+		})
+	}
+}
@@ -0,0 +1,145 @@
+package calendar
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"strings"
+	"time"
+)
+
+// maxEventDays limits how many days a single all-day event may span
+const maxEventDays = 366
+
+// ParseICal extracts holidays from an iCalendar (RFC 5545) document.
+// Every VEVENT becomes one holiday per day between DTSTART and DTEND (exclusive).
+func ParseICal(r io.Reader) ([]Holiday, error) {
+	lines, err := unfoldLines(r)
+	if err != nil {
+		return nil, err
+	}
+
+	var holidays []Holiday
+	var inEvent bool
+	var start, end, summary string
+
+	for i, line := range lines {
+		name, value := splitContentLine(line)
+
+		switch {
+		case name == "BEGIN" && value == "VEVENT":
+			inEvent = true
+			start, end, summary = "", "", ""
+
+		case name == "END" && value == "VEVENT":
+			if !inEvent {
+				return nil, fmt.Errorf("line %d: END:VEVENT without BEGIN:VEVENT", i+1)
+			}
+			inEvent = false
+
+			if start == "" {
+				return nil, fmt.Errorf("line %d: VEVENT without DTSTART", i+1)
+			}
+			dates, err := expandEvent(start, end)
+			if err != nil {
+				return nil, fmt.Errorf("line %d: %w", i+1, err)
+			}
+			for _, d := range dates {
+				holidays = append(holidays, Holiday{Date: d.Format(dateLayout), Name: summary})
+			}
+
+		case inEvent && name == "DTSTART":
+			start = value
+
+		case inEvent && name == "DTEND":
+			end = value
+
+		case inEvent && name == "SUMMARY":
+			summary = unescapeText(value)
+		}
+	}
+
+	if inEvent {
+		return nil, fmt.Errorf("unterminated VEVENT")
+	}
+
+	return holidays, nil
+}
+
+// unfoldLines reads content lines, joining folded continuation lines
+func unfoldLines(r io.Reader) ([]string, error) {
+	var lines []string
+	scanner := bufio.NewScanner(r)
+	for scanner.Scan() {
+		line := strings.TrimRight(scanner.Text(), "\r")
+		if (strings.HasPrefix(line, " ") || strings.HasPrefix(line, "\t")) && len(lines) > 0 {
+			lines[len(lines)-1] += line[1:]
+			continue
+		}
+		lines = append(lines, line)
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("failed to read calendar: %w", err)
+	}
+	return lines, nil
+}
+
+// splitContentLine splits "NAME;PARAM=x:VALUE" into its name and value.
+// Parameters such as VALUE=DATE or TZID are ignored since only the date matters.
+func splitContentLine(line string) (name, value string) {
+	colon := strings.Index(line, ":")
+	if colon < 0 {
+		return strings.ToUpper(line), ""
+	}
+	head := line[:colon]
+	value = strings.TrimSpace(line[colon+1:])
+
+	if semi := strings.Index(head, ";"); semi >= 0 {
+		head = head[:semi]
+	}
+	return strings.ToUpper(head), value
+}
+
+// expandEvent returns every date covered by an event
+func expandEvent(start, end string) ([]time.Time, error) {
+	startDate, err := parseICalDate(start)
+	if err != nil {
+		return nil, err
+	}
+
+	if end == "" {
+		return []time.Time{startDate}, nil
+	}
+
+	endDate, err := parseICalDate(end)
+	if err != nil {
+		return nil, err
+	}
+
+	var dates []time.Time
+	for d := startDate; d.Before(endDate) && len(dates) < maxEventDays; d = d.AddDate(0, 0, 1) {
+		dates = append(dates, d)
+	}
+	if len(dates) == 0 {
+		dates = append(dates, startDate)
+	}
+	return dates, nil
+}
+
+// parseICalDate parses DATE (20260101) and DATE-TIME (20260101T090000Z) values
+func parseICalDate(value string) (time.Time, error) {
+	if len(value) < 8 {
+		return time.Time{}, fmt.Errorf("invalid date: %s", value)
+	}
+	date, err := time.Parse("20060102", value[:8])
+	if err != nil {
+		return time.Time{}, fmt.Errorf("invalid date: %s", value)
+	}
+	return date, nil
+}
+
+// unescapeText reverses RFC 5545 TEXT escaping
+func unescapeText(s string) string {
+	replacer := strings.NewReplacer(`\,`, ",", `\;`, ";", `\n`, " ", `\N`, " ", `\\`, `\`)
+	return replacer.Replace(s)
+}
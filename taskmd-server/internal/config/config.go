@@ -4,17 +4,26 @@ import (
 	"fmt"
 	"os"
 	"strconv"
+	"strings"
 	"time"
 
 	"github.com/joho/godotenv"
+	"github.com/tktomaru/taskai/taskai-server/internal/oidc"
+	"github.com/tktomaru/taskai/taskai-server/internal/ratelimit"
 )
 
 // Config holds all configuration for the application
 type Config struct {
-	Server   ServerConfig
-	Database DatabaseConfig
-	Auth     AuthConfig
-	Logging  LoggingConfig
+	Server      ServerConfig
+	Database    DatabaseConfig
+	Auth        AuthConfig
+	Logging     LoggingConfig
+	Scheduler   SchedulerConfig
+	Webhooks    WebhookConfig
+	Idempotency IdempotencyConfig
+	RateLimit   RateLimitConfig
+	Mail        MailConfig
+	WebSocket   WebSocketConfig
 }
 
 // ServerConfig holds server configuration
@@ -41,14 +50,30 @@ type DatabaseConfig struct {
 
 // AuthConfig holds authentication configuration
 type AuthConfig struct {
-	Mode         string // "oidc" or "password"
-	JWTSecret    string
-	JWTExpiresIn time.Duration
+	Mode            string // "oidc" or "password"
+	JWTSecret       string
+	JWTExpiresIn    time.Duration // Lifetime of access tokens
+	RefreshTokenTTL time.Duration // Sessions expire when not refreshed for this long
 
 	// OIDC settings
 	OIDCIssuer       string
 	OIDCClientID     string
 	OIDCClientSecret string
+	OIDCRedirectURL  string   // Callback URL registered at the issuer
+	OIDCScopes       []string // Requested scopes
+	OIDCGroupsClaim  string   // ID token claim listing the user's groups
+	OIDCGroupRoles   string   // Optional "group=project:role" mappings, comma-separated
+	OIDCPostLoginURL string   // Where the browser is sent after logging in
+
+	// Password accounts
+	PasswordMinLength     int
+	BreachedPasswordsFile string        // Optional list of passwords that may not be used
+	EmailVerificationTTL  time.Duration // Lifetime of email verification links
+	PasswordResetTTL      time.Duration // Lifetime of password reset links
+
+	// Two-factor authentication
+	TOTPIssuer            string        // Name shown in authenticator apps
+	TwoFactorChallengeTTL time.Duration // Time allowed for entering the code after the password
 }
 
 // LoggingConfig holds logging configuration
@@ -58,6 +83,61 @@ type LoggingConfig struct {
 	Debug  bool   // Enable debug logging
 }
 
+// SchedulerConfig holds background scheduler configuration. Enabled only controls the
+// recurring task scheduler; the maintenance jobs always run every Interval.
+type SchedulerConfig struct {
+	Enabled        bool
+	Interval       time.Duration
+	DigestInterval time.Duration
+}
+
+// WebhookConfig holds outbound webhook delivery configuration
+type WebhookConfig struct {
+	Enabled      bool
+	PollInterval time.Duration
+	Timeout      time.Duration
+	MaxAttempts  int
+}
+
+// IdempotencyConfig holds Idempotency-Key replay configuration
+type IdempotencyConfig struct {
+	TTL time.Duration
+}
+
+// RateLimitConfig holds request rate limits, written as "requests/duration", and the login lockout
+type RateLimitConfig struct {
+	Enabled bool
+	Auth    string // Per client IP on login, registration and token refresh
+	API     string // Per access token, user or client IP on other API routes
+	Ingest  string // Per ingestion token
+
+	// Logins of an email from a client IP are locked out after LoginLockoutThreshold consecutive failures,
+	// for LoginLockoutBase doubled with every further failure up to LoginLockoutMax
+	LoginLockoutThreshold int
+	LoginLockoutBase      time.Duration
+	LoginLockoutMax       time.Duration
+}
+
+// MailConfig holds outbound email configuration
+type MailConfig struct {
+	Driver       string // "smtp", "file" or "log"
+	From         string
+	SMTPHost     string
+	SMTPPort     int
+	SMTPUsername string
+	SMTPPassword string
+	Dir          string // Where the file driver writes messages
+	LinkBaseURL  string // Web UI address used in links sent by email
+}
+
+// WebSocketConfig holds live connection configuration
+type WebSocketConfig struct {
+	TicketTTL          time.Duration // Lifetime of one-time connection tickets
+	RevalidateInterval time.Duration // How often open connections are checked against revoked access
+	ReplayBuffer       int           // Recent messages kept per project for clients that resume or fall behind
+	Backend            string        // "memory" for a single replica or "postgres" to share messages between replicas
+}
+
 // Load loads configuration from environment variables
 func Load() (*Config, error) {
 	// Load .env file if it exists (ignore error if file doesn't exist)
@@ -83,19 +163,72 @@ func Load() (*Config, error) {
 			MinConns: getEnvAsInt("DB_MIN_CONNS", 5),
 		},
 		Auth: AuthConfig{
-			Mode:         getEnv("AUTH_MODE", "password"),
-			JWTSecret:    getEnv("JWT_SECRET", "change-me-in-production"),
-			JWTExpiresIn: getEnvAsDuration("JWT_EXPIRES_IN", 24*time.Hour),
+			Mode:            getEnv("AUTH_MODE", "password"),
+			JWTSecret:       getEnv("JWT_SECRET", "change-me-in-production"),
+			JWTExpiresIn:    getEnvAsDuration("JWT_EXPIRES_IN", 15*time.Minute),
+			RefreshTokenTTL: getEnvAsDuration("REFRESH_TOKEN_TTL", 30*24*time.Hour),
 
 			OIDCIssuer:       getEnv("OIDC_ISSUER", ""),
 			OIDCClientID:     getEnv("OIDC_CLIENT_ID", ""),
 			OIDCClientSecret: getEnv("OIDC_CLIENT_SECRET", ""),
+			OIDCRedirectURL:  getEnv("OIDC_REDIRECT_URL", ""),
+			OIDCScopes:       getEnvAsSlice("OIDC_SCOPES", []string{"openid", "email", "profile"}),
+			OIDCGroupsClaim:  getEnv("OIDC_GROUPS_CLAIM", "groups"),
+			OIDCGroupRoles:   getEnv("OIDC_GROUP_ROLES", ""),
+			OIDCPostLoginURL: getEnv("OIDC_POST_LOGIN_URL", "/"),
+
+			PasswordMinLength:     getEnvAsInt("PASSWORD_MIN_LENGTH", 10),
+			BreachedPasswordsFile: getEnv("BREACHED_PASSWORDS_FILE", ""),
+			EmailVerificationTTL:  getEnvAsDuration("EMAIL_VERIFICATION_TTL", 48*time.Hour),
+			PasswordResetTTL:      getEnvAsDuration("PASSWORD_RESET_TTL", time.Hour),
+
+			TOTPIssuer:            getEnv("TOTP_ISSUER", "TaskMD"),
+			TwoFactorChallengeTTL: getEnvAsDuration("TWO_FACTOR_CHALLENGE_TTL", 5*time.Minute),
 		},
 		Logging: LoggingConfig{
 			Level:  getEnv("LOG_LEVEL", "info"),
 			Format: getEnv("LOG_FORMAT", "json"),
 			Debug:  getEnv("DEBUG", "false") == "true",
 		},
+		Scheduler: SchedulerConfig{
+			Enabled:        getEnv("SCHEDULER_ENABLED", "true") == "true",
+			Interval:       getEnvAsDuration("SCHEDULER_INTERVAL", time.Minute),
+			DigestInterval: getEnvAsDuration("DIGEST_INTERVAL", 24*time.Hour),
+		},
+		Webhooks: WebhookConfig{
+			Enabled:      getEnv("WEBHOOKS_ENABLED", "true") == "true",
+			PollInterval: getEnvAsDuration("WEBHOOK_POLL_INTERVAL", 5*time.Second),
+			Timeout:      getEnvAsDuration("WEBHOOK_TIMEOUT", 10*time.Second),
+			MaxAttempts:  getEnvAsInt("WEBHOOK_MAX_ATTEMPTS", 8),
+		},
+		Idempotency: IdempotencyConfig{
+			TTL: getEnvAsDuration("IDEMPOTENCY_TTL", 24*time.Hour),
+		},
+		RateLimit: RateLimitConfig{
+			Enabled:               getEnv("RATE_LIMIT_ENABLED", "true") == "true",
+			Auth:                  getEnv("RATE_LIMIT_AUTH", "10/m"),
+			API:                   getEnv("RATE_LIMIT_API", "600/m"),
+			Ingest:                getEnv("RATE_LIMIT_INGEST", "60/m"),
+			LoginLockoutThreshold: getEnvAsInt("LOGIN_LOCKOUT_THRESHOLD", 5),
+			LoginLockoutBase:      getEnvAsDuration("LOGIN_LOCKOUT_BASE", 30*time.Second),
+			LoginLockoutMax:       getEnvAsDuration("LOGIN_LOCKOUT_MAX", time.Hour),
+		},
+		Mail: MailConfig{
+			Driver:       getEnv("MAIL_DRIVER", "log"),
+			From:         getEnv("MAIL_FROM", "TaskMD <noreply@localhost>"),
+			SMTPHost:     getEnv("SMTP_HOST", ""),
+			SMTPPort:     getEnvAsInt("SMTP_PORT", 587),
+			SMTPUsername: getEnv("SMTP_USERNAME", ""),
+			SMTPPassword: getEnv("SMTP_PASSWORD", ""),
+			Dir:          getEnv("MAIL_DIR", "./mail"),
+			LinkBaseURL:  getEnv("MAIL_LINK_BASE_URL", "http://localhost:5173"),
+		},
+		WebSocket: WebSocketConfig{
+			TicketTTL:          getEnvAsDuration("WS_TICKET_TTL", 30*time.Second),
+			RevalidateInterval: getEnvAsDuration("WS_REVALIDATE_INTERVAL", time.Minute),
+			ReplayBuffer:       getEnvAsInt("WS_REPLAY_BUFFER", 1000),
+			Backend:            getEnv("WS_BACKEND", "memory"),
+		},
 	}
 
 	// Validate configuration
@@ -116,6 +249,63 @@ func (c *Config) Validate() error {
 		if c.Auth.OIDCIssuer == "" || c.Auth.OIDCClientID == "" || c.Auth.OIDCClientSecret == "" {
 			return fmt.Errorf("OIDC configuration is incomplete")
 		}
+		if c.Auth.OIDCRedirectURL == "" {
+			return fmt.Errorf("OIDC_REDIRECT_URL is required when AUTH_MODE=oidc")
+		}
+		if _, err := oidc.ParseGroupRoles(c.Auth.OIDCGroupRoles); err != nil {
+			return fmt.Errorf("OIDC_GROUP_ROLES: %w", err)
+		}
+	}
+
+	if c.Auth.JWTExpiresIn <= 0 || c.Auth.RefreshTokenTTL < c.Auth.JWTExpiresIn {
+		return fmt.Errorf("JWT_EXPIRES_IN must be positive and no longer than REFRESH_TOKEN_TTL")
+	}
+
+	// Claimed deliveries are retried by other workers after 5 minutes
+	if c.Webhooks.Timeout <= 0 || c.Webhooks.Timeout >= 5*time.Minute {
+		return fmt.Errorf("WEBHOOK_TIMEOUT must be between 0 and 5m")
+	}
+
+	if c.Idempotency.TTL <= 0 {
+		return fmt.Errorf("IDEMPOTENCY_TTL must be positive")
+	}
+
+	for name, spec := range map[string]string{
+		"RATE_LIMIT_AUTH":   c.RateLimit.Auth,
+		"RATE_LIMIT_API":    c.RateLimit.API,
+		"RATE_LIMIT_INGEST": c.RateLimit.Ingest,
+	} {
+		if _, err := ratelimit.ParseLimit(spec); err != nil {
+			return fmt.Errorf("%s: %w", name, err)
+		}
+	}
+	if c.RateLimit.LoginLockoutThreshold <= 0 || c.RateLimit.LoginLockoutBase <= 0 || c.RateLimit.LoginLockoutMax < c.RateLimit.LoginLockoutBase {
+		return fmt.Errorf("LOGIN_LOCKOUT_THRESHOLD and LOGIN_LOCKOUT_BASE must be positive and LOGIN_LOCKOUT_MAX at least LOGIN_LOCKOUT_BASE")
+	}
+
+	if c.Auth.PasswordMinLength < 8 {
+		return fmt.Errorf("PASSWORD_MIN_LENGTH must be at least 8")
+	}
+	if c.Auth.EmailVerificationTTL <= 0 || c.Auth.PasswordResetTTL <= 0 {
+		return fmt.Errorf("EMAIL_VERIFICATION_TTL and PASSWORD_RESET_TTL must be positive")
+	}
+	if c.Auth.TOTPIssuer == "" || strings.Contains(c.Auth.TOTPIssuer, ":") {
+		return fmt.Errorf("TOTP_ISSUER must be set and must not contain a colon")
+	}
+	if c.Auth.TwoFactorChallengeTTL <= 0 {
+		return fmt.Errorf("TWO_FACTOR_CHALLENGE_TTL must be positive")
+	}
+
+	if c.WebSocket.TicketTTL <= 0 || c.WebSocket.RevalidateInterval <= 0 {
+		return fmt.Errorf("WS_TICKET_TTL and WS_REVALIDATE_INTERVAL must be positive")
+	}
+
+	if c.WebSocket.ReplayBuffer < 0 {
+		return fmt.Errorf("WS_REPLAY_BUFFER must not be negative")
+	}
+
+	if c.WebSocket.Backend != "memory" && c.WebSocket.Backend != "postgres" {
+		return fmt.Errorf("WS_BACKEND must be memory or postgres")
 	}
 
 	if c.Auth.JWTSecret == "change-me-in-production" {
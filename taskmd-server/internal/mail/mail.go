@@ -0,0 +1,146 @@
+// Package mail sends account emails such as verification and password reset links
+// through a pluggable sender.
+package mail
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"log"
+	"mime"
+	"net"
+	"net/smtp"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync/atomic"
+	"time"
+)
+
+// Message is a plain-text email
+type Message struct {
+	To      string
+	Subject string
+	Body    string
+}
+
+// Sender sends email
+type Sender interface {
+	Send(ctx context.Context, msg Message) error
+}
+
+// Config selects and configures a sender
+type Config struct {
+	Driver string // "smtp", "file" or "log"
+	From   string
+
+	// SMTP relay; authentication is used when a username is set
+	SMTPHost     string
+	SMTPPort     int
+	SMTPUsername string
+	SMTPPassword string
+
+	// Directory the file sender writes messages to
+	Dir string
+}
+
+// NewSender creates the sender selected by the configuration
+func NewSender(cfg Config) (Sender, error) {
+	switch cfg.Driver {
+	case "smtp":
+		if cfg.SMTPHost == "" || cfg.From == "" {
+			return nil, fmt.Errorf("SMTP mail requires a host and a sender address")
+		}
+		return &SMTPSender{
+			Addr:     net.JoinHostPort(cfg.SMTPHost, strconv.Itoa(cfg.SMTPPort)),
+			Host:     cfg.SMTPHost,
+			Username: cfg.SMTPUsername,
+			Password: cfg.SMTPPassword,
+			From:     cfg.From,
+		}, nil
+	case "file":
+		if err := os.MkdirAll(cfg.Dir, 0o755); err != nil {
+			return nil, fmt.Errorf("failed to create mail directory: %w", err)
+		}
+		return &FileSender{Dir: cfg.Dir, From: cfg.From}, nil
+	case "log", "":
+		return LogSender{}, nil
+	default:
+		return nil, fmt.Errorf("unknown mail driver %q (expected smtp, file or log)", cfg.Driver)
+	}
+}
+
+// SMTPSender sends email through an SMTP relay, using STARTTLS when the relay offers it
+type SMTPSender struct {
+	Addr     string
+	Host     string
+	Username string
+	Password string
+	From     string
+}
+
+// Send sends a message
+func (s *SMTPSender) Send(ctx context.Context, msg Message) error {
+	var auth smtp.Auth
+	if s.Username != "" {
+		auth = smtp.PlainAuth("", s.Username, s.Password, s.Host)
+	}
+
+	if err := smtp.SendMail(s.Addr, auth, s.From, []string{msg.To}, Format(s.From, msg, time.Now())); err != nil {
+		return fmt.Errorf("failed to send mail to %s: %w", msg.To, err)
+	}
+
+	return nil
+}
+
+// FileSender writes each message to a file in a directory, for development and tests
+type FileSender struct {
+	Dir  string
+	From string
+
+	seq atomic.Int64
+}
+
+// Send writes a message to <Dir>/<unix nanoseconds>-<sequence>.eml
+func (s *FileSender) Send(ctx context.Context, msg Message) error {
+	now := time.Now()
+	name := fmt.Sprintf("%d-%d.eml", now.UnixNano(), s.seq.Add(1))
+
+	if err := os.WriteFile(filepath.Join(s.Dir, name), Format(s.From, msg, now), 0o600); err != nil {
+		return fmt.Errorf("failed to write mail: %w", err)
+	}
+
+	return nil
+}
+
+// LogSender writes messages to the server log instead of sending them
+type LogSender struct{}
+
+// Send logs a message
+func (LogSender) Send(ctx context.Context, msg Message) error {
+	log.Printf("Mail to %s: %s\n%s", msg.To, msg.Subject, msg.Body)
+	return nil
+}
+
+// Format renders a message as an RFC 5322 email
+func Format(from string, msg Message, now time.Time) []byte {
+	var b bytes.Buffer
+
+	fmt.Fprintf(&b, "From: %s\r\n", headerValue(from))
+	fmt.Fprintf(&b, "To: %s\r\n", headerValue(msg.To))
+	fmt.Fprintf(&b, "Subject: %s\r\n", mime.QEncoding.Encode("utf-8", msg.Subject))
+	fmt.Fprintf(&b, "Date: %s\r\n", now.Format(time.RFC1123Z))
+	b.WriteString("MIME-Version: 1.0\r\n")
+	b.WriteString("Content-Type: text/plain; charset=utf-8\r\n")
+	b.WriteString("Content-Transfer-Encoding: 8bit\r\n")
+	b.WriteString("\r\n")
+	b.WriteString(strings.ReplaceAll(strings.ReplaceAll(msg.Body, "\r\n", "\n"), "\n", "\r\n"))
+
+	return b.Bytes()
+}
+
+// headerValue removes line breaks, so that a value cannot add headers
+func headerValue(s string) string {
+	return strings.NewReplacer("\r", "", "\n", "").Replace(s)
+}
@@ -0,0 +1,81 @@
+package mail
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestFormat(t *testing.T) {
+	now := time.Date(2025, 1, 2, 3, 4, 5, 0, time.UTC)
+	msg := Message{
+		To:      "alice@example.com",
+		Subject: "パスワードの再設定",
+		Body:    "line 1\nline 2",
+	}
+
+	got := string(Format("TaskMD <noreply@example.com>", msg, now))
+
+	for _, want := range []string{
+		"From: TaskMD <noreply@example.com>\r\n",
+		"To: alice@example.com\r\n",
+		"Subject: =?utf-8?q?",
+		"Date: Thu, 02 Jan 2025 03:04:05 +0000\r\n",
+		"Content-Type: text/plain; charset=utf-8\r\n",
+		"\r\n\r\nline 1\r\nline 2",
+	} {
+		if !strings.Contains(got, want) {
+			t.Errorf("Format() missing %q in:\n%s", want, got)
+		}
+	}
+}
+
+func TestFileSender(t *testing.T) {
+	dir := t.TempDir()
+
+	sender, err := NewSender(Config{Driver: "file", Dir: dir, From: "noreply@example.com"})
+	if err != nil {
+		t.Fatalf("NewSender() error: %v", err)
+	}
+
+	for i := 0; i < 2; i++ {
+		if err := sender.Send(context.Background(), Message{To: "bob@example.com", Subject: "Hello", Body: "Hi"}); err != nil {
+			t.Fatalf("Send() error: %v", err)
+		}
+	}
+
+	files, _ := filepath.Glob(filepath.Join(dir, "*.eml"))
+	if len(files) != 2 {
+		t.Fatalf("Send() wrote %d files, want 2", len(files))
+	}
+
+	content, _ := os.ReadFile(files[0])
+	if !strings.Contains(string(content), "To: bob@example.com") {
+		t.Errorf("written mail = %q, want recipient header", content)
+	}
+}
+
+func TestNewSender(t *testing.T) {
+	tests := []struct {
+		name    string
+		cfg     Config
+		wantErr bool
+	}{
+		{"log by default", Config{}, false},
+		{"smtp", Config{Driver: "smtp", SMTPHost: "mail.example.com", SMTPPort: 587, From: "noreply@example.com"}, false},
+		{"smtp without host", Config{Driver: "smtp", From: "noreply@example.com"}, true},
+		{"unknown driver", Config{Driver: "carrier-pigeon"}, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := NewSender(tt.cfg)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("NewSender() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
@@ -12,6 +12,7 @@ type Task struct {
 	ID           string         `json:"id" db:"id"`
 	ProjectID    string         `json:"project_id" db:"project_id"`
 	ParentID     *string        `json:"parent_id,omitempty" db:"parent_id"`
+	SeriesID     *string        `json:"series_id,omitempty" db:"series_id"`
 	Title        string         `json:"title" db:"title"`
 	Status       TaskStatus     `json:"status" db:"status"`
 	Priority     TaskPriority   `json:"priority" db:"priority"`
@@ -30,6 +31,36 @@ type Task struct {
 	UpdatedBy    *string        `json:"updated_by,omitempty" db:"updated_by"`
 }
 
+// TaskSeries represents a recurring task series
+type TaskSeries struct {
+	ID               string       `json:"id" db:"id"`
+	ProjectID        string       `json:"project_id" db:"project_id"`
+	TemplateTaskID   string       `json:"template_task_id" db:"template_task_id"`
+	Recurrence       string       `json:"recurrence" db:"recurrence"`
+	TemplateMarkdown string       `json:"template_markdown" db:"template_markdown"`
+	Status           SeriesStatus `json:"status" db:"status"`
+	OccurrenceCount  int          `json:"occurrence_count" db:"occurrence_count"`
+	NextRunAt        *time.Time   `json:"next_run_at,omitempty" db:"next_run_at"`
+	LastInstanceID   *string      `json:"last_instance_id,omitempty" db:"last_instance_id"`
+	CreatedAt        time.Time    `json:"created_at" db:"created_at"`
+	UpdatedAt        time.Time    `json:"updated_at" db:"updated_at"`
+	CancelledAt      *time.Time   `json:"cancelled_at,omitempty" db:"cancelled_at"`
+	CreatedBy        *string      `json:"created_by,omitempty" db:"created_by"`
+}
+
+// TaskTemplate represents a project-scoped Markdown template for new tasks
+type TaskTemplate struct {
+	ID          string    `json:"id" db:"id"`
+	ProjectID   string    `json:"project_id" db:"project_id"`
+	Name        string    `json:"name" db:"name"`
+	Description *string   `json:"description,omitempty" db:"description"`
+	Markdown    string    `json:"markdown" db:"markdown"`
+	IDPrefix    string    `json:"id_prefix" db:"id_prefix"`
+	CreatedAt   time.Time `json:"created_at" db:"created_at"`
+	UpdatedAt   time.Time `json:"updated_at" db:"updated_at"`
+	CreatedBy   *string   `json:"created_by,omitempty" db:"created_by"`
+}
+
 // SavedView represents a saved query view
 type SavedView struct {
 	ID               string    `json:"id" db:"id"`
@@ -72,6 +103,16 @@ type User struct {
 	CreatedAt    time.Time  `json:"created_at" db:"created_at"`
 	UpdatedAt    time.Time  `json:"updated_at" db:"updated_at"`
 	LastLoginAt  *time.Time `json:"last_login_at,omitempty" db:"last_login_at"`
+
+	// ServiceProjectID is set for service accounts, which belong to a project rather than a person
+	ServiceProjectID *string `json:"service_project_id,omitempty" db:"service_project_id"`
+
+	EmailVerifiedAt *time.Time `json:"email_verified_at,omitempty" db:"email_verified_at"`
+
+	// TOTP two-factor authentication; the secret is pending until TOTPEnabledAt is set
+	TOTPSecret    *string    `json:"-" db:"totp_secret"`
+	TOTPEnabledAt *time.Time `json:"two_factor_enabled_at,omitempty" db:"totp_enabled_at"`
+	TOTPLastStep  *int64     `json:"-" db:"totp_last_step"`
 }
 
 // TaskRelation represents a relation between tasks
@@ -79,10 +120,216 @@ type TaskRelation struct {
 	SourceTaskID string       `json:"source_task_id" db:"source_task_id"`
 	TargetTaskID string       `json:"target_task_id" db:"target_task_id"`
 	RelationType RelationType `json:"relation_type" db:"relation_type"`
+	Origin       string       `json:"origin" db:"origin"`
 	CreatedAt    time.Time    `json:"created_at" db:"created_at"`
 	CreatedBy    *string      `json:"created_by,omitempty" db:"created_by"`
 }
 
+// TaskBacklink represents a task that refers to another task
+type TaskBacklink struct {
+	TaskID       string       `json:"task_id" db:"task_id"`
+	ProjectID    string       `json:"project_id" db:"project_id"`
+	Title        string       `json:"title" db:"title"`
+	Status       TaskStatus   `json:"status" db:"status"`
+	RelationType RelationType `json:"relation_type" db:"relation_type"`
+	Origin       string       `json:"origin" db:"origin"`
+}
+
+// DanglingReference represents a reference to a task that is missing or deleted
+type DanglingReference struct {
+	SourceTaskID string       `json:"source_task_id" db:"source_task_id"`
+	TargetTaskID string       `json:"target_task_id" db:"target_task_id"`
+	RelationType RelationType `json:"relation_type" db:"relation_type"`
+	Reason       string       `json:"reason" db:"reason"`
+	DetectedAt   time.Time    `json:"detected_at" db:"detected_at"`
+}
+
+// TaskComment represents a comment on a task
+type TaskComment struct {
+	ID           string     `json:"id" db:"id"`
+	TaskID       string     `json:"task_id" db:"task_id"`
+	MarkdownBody string     `json:"markdown_body" db:"markdown_body"`
+	AuthorUserID string     `json:"author_user_id" db:"author_user_id"`
+	CreatedAt    time.Time  `json:"created_at" db:"created_at"`
+	UpdatedAt    time.Time  `json:"updated_at" db:"updated_at"`
+	DeletedAt    *time.Time `json:"deleted_at,omitempty" db:"deleted_at"`
+}
+
+// Notification represents an entry in a user's notification inbox
+type Notification struct {
+	ID          int64            `json:"id" db:"id"`
+	UserID      string           `json:"user_id" db:"user_id"`
+	Type        NotificationType `json:"type" db:"type"`
+	ProjectID   *string          `json:"project_id,omitempty" db:"project_id"`
+	TaskID      *string          `json:"task_id,omitempty" db:"task_id"`
+	CommentID   *string          `json:"comment_id,omitempty" db:"comment_id"`
+	ActorUserID *string          `json:"actor_user_id,omitempty" db:"actor_user_id"`
+	Title       string           `json:"title" db:"title"`
+	Detail      JSONB            `json:"detail" db:"detail"`
+	CreatedAt   time.Time        `json:"created_at" db:"created_at"`
+	ReadAt      *time.Time       `json:"read_at,omitempty" db:"read_at"`
+
+	// DigestPending marks notifications to be collected into the next digest
+	DigestPending bool `json:"-" db:"digest_pending"`
+}
+
+// Subscription represents a user watching a task, saved view or project
+type Subscription struct {
+	UserID     string             `json:"user_id" db:"user_id"`
+	TargetType SubscriptionTarget `json:"target_type" db:"target_type"`
+	TargetID   string             `json:"target_id" db:"target_id"`
+	ProjectID  string             `json:"project_id" db:"project_id"`
+	Reason     string             `json:"reason" db:"reason"`
+	Active     bool               `json:"active" db:"active"`
+	Digest     bool               `json:"digest" db:"digest"`
+	CreatedAt  time.Time          `json:"created_at" db:"created_at"`
+	UpdatedAt  time.Time          `json:"updated_at" db:"updated_at"`
+}
+
+// Webhook represents a project webhook registration
+type Webhook struct {
+	ID          string      `json:"id" db:"id"`
+	ProjectID   string      `json:"project_id" db:"project_id"`
+	URL         string      `json:"url" db:"url"`
+	Events      StringArray `json:"events" db:"events"`
+	Secret      string      `json:"secret,omitempty" db:"secret"`
+	Description *string     `json:"description,omitempty" db:"description"`
+	Active      bool        `json:"active" db:"active"`
+	CreatedBy   *string     `json:"created_by,omitempty" db:"created_by"`
+	CreatedAt   time.Time   `json:"created_at" db:"created_at"`
+	UpdatedAt   time.Time   `json:"updated_at" db:"updated_at"`
+}
+
+// WebhookDelivery represents an event queued for delivery to a webhook
+type WebhookDelivery struct {
+	ID             int64                 `json:"id" db:"id"`
+	WebhookID      string                `json:"webhook_id" db:"webhook_id"`
+	EventType      string                `json:"event_type" db:"event_type"`
+	Payload        json.RawMessage       `json:"payload" db:"payload"`
+	Status         WebhookDeliveryStatus `json:"status" db:"status"`
+	Attempts       int                   `json:"attempts" db:"attempts"`
+	NextAttemptAt  time.Time             `json:"next_attempt_at" db:"next_attempt_at"`
+	ResponseStatus *int                  `json:"response_status,omitempty" db:"response_status"`
+	LastError      *string               `json:"last_error,omitempty" db:"last_error"`
+	RedeliveryOf   *int64                `json:"redelivery_of,omitempty" db:"redelivery_of"`
+	CreatedAt      time.Time             `json:"created_at" db:"created_at"`
+	DeliveredAt    *time.Time            `json:"delivered_at,omitempty" db:"delivered_at"`
+
+	Log []*WebhookDeliveryAttempt `json:"log,omitempty" db:"-"`
+}
+
+// WebhookDeliveryAttempt represents a single HTTP request of a webhook delivery
+type WebhookDeliveryAttempt struct {
+	ID             int64     `json:"id" db:"id"`
+	DeliveryID     int64     `json:"delivery_id" db:"delivery_id"`
+	Attempt        int       `json:"attempt" db:"attempt"`
+	ResponseStatus *int      `json:"response_status,omitempty" db:"response_status"`
+	ResponseBody   *string   `json:"response_body,omitempty" db:"response_body"`
+	Error          *string   `json:"error,omitempty" db:"error"`
+	DurationMs     int       `json:"duration_ms" db:"duration_ms"`
+	AttemptedAt    time.Time `json:"attempted_at" db:"attempted_at"`
+}
+
+// IngestToken represents a per-project token for creating tasks from external events
+type IngestToken struct {
+	ID         string     `json:"id" db:"id"`
+	ProjectID  string     `json:"project_id" db:"project_id"`
+	Name       string     `json:"name" db:"name"`
+	TokenHash  string     `json:"-" db:"token_hash"`
+	TemplateID *string    `json:"template_id,omitempty" db:"template_id"`
+	Mapping    JSONB      `json:"mapping" db:"mapping"`
+	IDPrefix   string     `json:"id_prefix" db:"id_prefix"`
+	CreatedBy  *string    `json:"created_by,omitempty" db:"created_by"`
+	CreatedAt  time.Time  `json:"created_at" db:"created_at"`
+	UpdatedAt  time.Time  `json:"updated_at" db:"updated_at"`
+	LastUsedAt *time.Time `json:"last_used_at,omitempty" db:"last_used_at"`
+
+	// Token is only set when the token is created
+	Token string `json:"token,omitempty" db:"-"`
+}
+
+// Session represents a login session. Access tokens name their session, so revoking it logs them out.
+type Session struct {
+	ID                string     `json:"id" db:"id"`
+	UserID            string     `json:"user_id" db:"user_id"`
+	RefreshTokenHash  string     `json:"-" db:"refresh_token_hash"`
+	PreviousTokenHash *string    `json:"-" db:"previous_token_hash"`
+	UserAgent         *string    `json:"user_agent,omitempty" db:"user_agent"`
+	IPAddress         *string    `json:"ip_address,omitempty" db:"ip_address"`
+	CreatedAt         time.Time  `json:"created_at" db:"created_at"`
+	LastUsedAt        time.Time  `json:"last_used_at" db:"last_used_at"`
+	ExpiresAt         time.Time  `json:"expires_at" db:"expires_at"`
+	RevokedAt         *time.Time `json:"revoked_at,omitempty" db:"revoked_at"`
+
+	// Current marks the session of the request
+	Current bool `json:"current" db:"-"`
+}
+
+// Access token scopes
+const (
+	AccessTokenScopeRead  = "read"
+	AccessTokenScopeWrite = "write"
+)
+
+// AccessToken represents a personal access token or a token of a service account
+type AccessToken struct {
+	ID         string     `json:"id" db:"id"`
+	UserID     string     `json:"user_id" db:"user_id"`
+	Name       string     `json:"name" db:"name"`
+	TokenHash  string     `json:"-" db:"token_hash"`
+	ProjectID  *string    `json:"project_id,omitempty" db:"project_id"`
+	Scope      string     `json:"scope" db:"scope"`
+	CreatedBy  *string    `json:"created_by,omitempty" db:"created_by"`
+	CreatedAt  time.Time  `json:"created_at" db:"created_at"`
+	ExpiresAt  *time.Time `json:"expires_at,omitempty" db:"expires_at"`
+	LastUsedAt *time.Time `json:"last_used_at,omitempty" db:"last_used_at"`
+	RevokedAt  *time.Time `json:"revoked_at,omitempty" db:"revoked_at"`
+
+	// Token is only set when the token is created
+	Token string `json:"token,omitempty" db:"-"`
+}
+
+// Account token purposes
+const (
+	AccountTokenVerifyEmail   = "verify_email"
+	AccountTokenResetPassword = "reset_password"
+)
+
+// AccountToken represents a single-use token sent by email to verify an address or reset a password
+type AccountToken struct {
+	ID        string     `json:"id" db:"id"`
+	UserID    string     `json:"user_id" db:"user_id"`
+	Purpose   string     `json:"purpose" db:"purpose"`
+	TokenHash string     `json:"-" db:"token_hash"`
+	Email     string     `json:"email" db:"email"`
+	CreatedAt time.Time  `json:"created_at" db:"created_at"`
+	ExpiresAt time.Time  `json:"expires_at" db:"expires_at"`
+	UsedAt    *time.Time `json:"used_at,omitempty" db:"used_at"`
+}
+
+// WebSocketTicket represents a one-time ticket that authenticates a WebSocket connection
+type WebSocketTicket struct {
+	TicketHash string    `json:"-" db:"ticket_hash"`
+	UserID     string    `json:"user_id" db:"user_id"`
+	SessionID  string    `json:"session_id" db:"session_id"`
+	ProjectID  *string   `json:"project_id,omitempty" db:"project_id"`
+	CreatedAt  time.Time `json:"created_at" db:"created_at"`
+	ExpiresAt  time.Time `json:"expires_at" db:"expires_at"`
+}
+
+// IdempotencyKey represents the stored response of a request sent with an Idempotency-Key header
+type IdempotencyKey struct {
+	Scope        string     `json:"scope" db:"scope"`
+	Key          string     `json:"key" db:"key"`
+	RequestHash  string     `json:"request_hash" db:"request_hash"`
+	StatusCode   *int       `json:"status_code,omitempty" db:"status_code"`
+	ContentType  *string    `json:"content_type,omitempty" db:"content_type"`
+	ResponseBody []byte     `json:"-" db:"response_body"`
+	CreatedAt    time.Time  `json:"created_at" db:"created_at"`
+	CompletedAt  *time.Time `json:"completed_at,omitempty" db:"completed_at"`
+	ExpiresAt    time.Time  `json:"expires_at" db:"expires_at"`
+}
+
 // TaskRevision represents a task revision
 type TaskRevision struct {
 	RevID         int64     `json:"rev_id" db:"rev_id"`
@@ -129,6 +376,14 @@ const (
 	TaskPriorityP4 TaskPriority = "P4"
 )
 
+type SeriesStatus string
+
+const (
+	SeriesStatusActive    SeriesStatus = "active"
+	SeriesStatusCancelled SeriesStatus = "cancelled"
+	SeriesStatusFinished  SeriesStatus = "finished"
+)
+
 type ProjectVisibility string
 
 const (
@@ -156,6 +411,55 @@ const (
 	RelationTypeDuplicatedBy RelationType = "duplicated_by"
 )
 
+// Relation origins
+const (
+	RelationOriginManual   = "manual"
+	RelationOriginMarkdown = "markdown"
+)
+
+type NotificationType string
+
+const (
+	NotificationTypeMention       NotificationType = "mention"
+	NotificationTypeAssigned      NotificationType = "assigned"
+	NotificationTypeTaskUpdated   NotificationType = "task_updated"
+	NotificationTypeTaskCommented NotificationType = "task_commented"
+	NotificationTypeTaskCreated   NotificationType = "task_created"
+	NotificationTypeTaskDeleted   NotificationType = "task_deleted"
+	NotificationTypeViewEntered   NotificationType = "view_entered"
+	NotificationTypeViewLeft      NotificationType = "view_left"
+	NotificationTypeDigest        NotificationType = "digest"
+)
+
+type SubscriptionTarget string
+
+const (
+	SubscriptionTargetTask    SubscriptionTarget = "task"
+	SubscriptionTargetView    SubscriptionTarget = "view"
+	SubscriptionTargetProject SubscriptionTarget = "project"
+)
+
+type WebhookDeliveryStatus string
+
+const (
+	WebhookDeliveryPending   WebhookDeliveryStatus = "pending"
+	WebhookDeliverySucceeded WebhookDeliveryStatus = "succeeded"
+	WebhookDeliveryFailed    WebhookDeliveryStatus = "failed"
+)
+
+// Subscription reasons
+const (
+	SubscriptionReasonManual   = "manual"
+	SubscriptionReasonCreator  = "creator"
+	SubscriptionReasonAssignee = "assignee"
+)
+
+// Dangling reference reasons
+const (
+	DanglingReasonMissing = "missing"
+	DanglingReasonDeleted = "deleted"
+)
+
 type AuditAction string
 
 const (
@@ -164,6 +468,7 @@ const (
 	AuditActionTaskDelete               AuditAction = "task.delete"
 	AuditActionTaskStatusChange         AuditAction = "task.status_change"
 	AuditActionTaskAssign               AuditAction = "task.assign"
+	AuditActionTaskBulkUpdate           AuditAction = "task.bulk_update"
 	AuditActionViewCreate               AuditAction = "view.create"
 	AuditActionViewUpdate               AuditAction = "view.update"
 	AuditActionViewDelete               AuditAction = "view.delete"
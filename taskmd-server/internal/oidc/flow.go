@@ -0,0 +1,167 @@
+package oidc
+
+import (
+	"crypto/sha256"
+	"fmt"
+	"io"
+	"strings"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+	"golang.org/x/crypto/hkdf"
+)
+
+// FlowTTL is how long a user has to log in at the issuer
+const FlowTTL = 10 * time.Minute
+
+// flowKeyPurpose labels the key derived for sealing flows, so that a sealed flow can never
+// be accepted as a session token signed with the same secret, or the other way around
+const flowKeyPurpose = "taskmd oidc login flow"
+
+// Flow is the state of an authorization request, kept by the browser between the
+// redirect to the issuer and the callback
+type Flow struct {
+	State        string `json:"state"`
+	Nonce        string `json:"nonce"`
+	CodeVerifier string `json:"code_verifier"`
+	RedirectTo   string `json:"redirect_to,omitempty"`
+	jwt.RegisteredClaims
+}
+
+// NewFlow creates the state, nonce and PKCE verifier of a new authorization request
+func NewFlow(redirectTo string) (*Flow, error) {
+	values := make([]string, 3)
+	for i := range values {
+		value, err := RandomString()
+		if err != nil {
+			return nil, err
+		}
+		values[i] = value
+	}
+
+	return &Flow{
+		State:        values[0],
+		Nonce:        values[1],
+		CodeVerifier: values[2],
+		RedirectTo:   redirectTo,
+	}, nil
+}
+
+// Seal signs a flow so that it can be stored in a cookie
+func (f *Flow) Seal(secret string, now time.Time) (string, error) {
+	f.IssuedAt = jwt.NewNumericDate(now)
+	f.ExpiresAt = jwt.NewNumericDate(now.Add(FlowTTL))
+
+	key, err := flowKey(secret)
+	if err != nil {
+		return "", err
+	}
+
+	sealed, err := jwt.NewWithClaims(jwt.SigningMethodHS256, f).SignedString(key)
+	if err != nil {
+		return "", fmt.Errorf("failed to seal login flow: %w", err)
+	}
+	return sealed, nil
+}
+
+// OpenFlow verifies a sealed flow and checks that it belongs to the callback's state
+func OpenFlow(secret, sealed, state string, now time.Time) (*Flow, error) {
+	key, err := flowKey(secret)
+	if err != nil {
+		return nil, err
+	}
+
+	flow := &Flow{}
+	_, err = jwt.ParseWithClaims(sealed, flow, func(token *jwt.Token) (interface{}, error) {
+		return key, nil
+	}, jwt.WithValidMethods([]string{"HS256"}), jwt.WithExpirationRequired(), jwt.WithTimeFunc(func() time.Time { return now }))
+	if err != nil {
+		return nil, fmt.Errorf("invalid login flow: %w", err)
+	}
+
+	if state == "" || flow.State != state {
+		return nil, fmt.Errorf("invalid login flow: state mismatch")
+	}
+
+	return flow, nil
+}
+
+// flowKey derives the key that seals flows from the server secret with HKDF-SHA256
+func flowKey(secret string) ([]byte, error) {
+	key := make([]byte, sha256.Size)
+	if _, err := io.ReadFull(hkdf.New(sha256.New, []byte(secret), nil, []byte(flowKeyPurpose)), key); err != nil {
+		return nil, fmt.Errorf("failed to derive login flow key: %w", err)
+	}
+	return key, nil
+}
+
+// Project roles, from least to most privileged
+var roleRanks = map[string]int{
+	"viewer":     1,
+	"member":     2,
+	"maintainer": 3,
+	"owner":      4,
+}
+
+// GroupRole grants a project role to the members of an issuer group
+type GroupRole struct {
+	Group     string
+	ProjectID string
+	Role      string
+}
+
+// ParseGroupRoles parses a comma-separated list of "group=project:role" mappings
+func ParseGroupRoles(spec string) ([]GroupRole, error) {
+	var mappings []GroupRole
+
+	for _, entry := range strings.Split(spec, ",") {
+		entry = strings.TrimSpace(entry)
+		if entry == "" {
+			continue
+		}
+
+		group, grant, ok := strings.Cut(entry, "=")
+		if !ok {
+			return nil, fmt.Errorf("invalid group role mapping %q (expected group=project:role)", entry)
+		}
+		projectID, role, ok := strings.Cut(grant, ":")
+		if !ok || strings.TrimSpace(group) == "" || strings.TrimSpace(projectID) == "" {
+			return nil, fmt.Errorf("invalid group role mapping %q (expected group=project:role)", entry)
+		}
+
+		role = strings.TrimSpace(role)
+		if _, ok := roleRanks[role]; !ok {
+			return nil, fmt.Errorf("invalid role %q in group role mapping %q", role, entry)
+		}
+
+		mappings = append(mappings, GroupRole{
+			Group:     strings.TrimSpace(group),
+			ProjectID: strings.TrimSpace(projectID),
+			Role:      role,
+		})
+	}
+
+	return mappings, nil
+}
+
+// ProjectRoles returns the role granted in each project to a user in the given groups.
+// When several groups grant roles in the same project, the most privileged one wins.
+func ProjectRoles(groups []string, mappings []GroupRole) map[string]string {
+	member := make(map[string]bool, len(groups))
+	for _, group := range groups {
+		member[group] = true
+	}
+
+	roles := make(map[string]string)
+	for _, mapping := range mappings {
+		if !member[mapping.Group] {
+			continue
+		}
+		if current, ok := roles[mapping.ProjectID]; ok && roleRanks[current] >= roleRanks[mapping.Role] {
+			continue
+		}
+		roles[mapping.ProjectID] = mapping.Role
+	}
+
+	return roles
+}
@@ -0,0 +1,117 @@
+package oidc
+
+import (
+	"testing"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+)
+
+func TestSealAndOpenFlow(t *testing.T) {
+	now := time.Date(2026, 3, 2, 10, 0, 0, 0, time.UTC)
+
+	flow, err := NewFlow("/projects/web")
+	if err != nil {
+		t.Fatalf("NewFlow() error: %v", err)
+	}
+	sealed, err := flow.Seal("secret", now)
+	if err != nil {
+		t.Fatalf("Seal() error: %v", err)
+	}
+
+	opened, err := OpenFlow("secret", sealed, flow.State, now.Add(time.Minute))
+	if err != nil {
+		t.Fatalf("OpenFlow() error: %v", err)
+	}
+	if opened.Nonce != flow.Nonce || opened.CodeVerifier != flow.CodeVerifier || opened.RedirectTo != "/projects/web" {
+		t.Errorf("OpenFlow() = %+v, want %+v", opened, flow)
+	}
+
+	tests := []struct {
+		name   string
+		secret string
+		state  string
+		at     time.Time
+	}{
+		{"wrong state", "secret", "other-state", now},
+		{"missing state", "secret", "", now},
+		{"wrong secret", "other-secret", flow.State, now},
+		{"expired", "secret", flow.State, now.Add(FlowTTL + time.Minute)},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := OpenFlow(tt.secret, sealed, tt.state, tt.at); err == nil {
+				t.Error("OpenFlow() should fail")
+			}
+		})
+	}
+}
+
+func TestOpenFlow_RejectsTokensSignedWithTheSecret(t *testing.T) {
+	now := time.Date(2026, 3, 2, 10, 0, 0, 0, time.UTC)
+
+	flow, err := NewFlow("")
+	if err != nil {
+		t.Fatalf("NewFlow() error: %v", err)
+	}
+	flow.ExpiresAt = jwt.NewNumericDate(now.Add(FlowTTL))
+
+	// A token signed with the secret itself, like a session token, is not a sealed flow
+	signed, err := jwt.NewWithClaims(jwt.SigningMethodHS256, flow).SignedString([]byte("secret"))
+	if err != nil {
+		t.Fatalf("SignedString() error: %v", err)
+	}
+
+	if _, err := OpenFlow("secret", signed, flow.State, now); err == nil {
+		t.Error("OpenFlow() should fail")
+	}
+}
+
+func TestParseGroupRoles(t *testing.T) {
+	mappings, err := ParseGroupRoles(" eng = web:member, web-admins=web:maintainer ,, ops=infra:viewer")
+	if err != nil {
+		t.Fatalf("ParseGroupRoles() error: %v", err)
+	}
+
+	want := []GroupRole{
+		{"eng", "web", "member"},
+		{"web-admins", "web", "maintainer"},
+		{"ops", "infra", "viewer"},
+	}
+	if len(mappings) != len(want) {
+		t.Fatalf("ParseGroupRoles() = %v, want %v", mappings, want)
+	}
+	for i := range want {
+		if mappings[i] != want[i] {
+			t.Errorf("mapping %d = %v, want %v", i, mappings[i], want[i])
+		}
+	}
+
+	for _, spec := range []string{"eng", "eng=web", "=web:member", "eng=web:admin"} {
+		if _, err := ParseGroupRoles(spec); err == nil {
+			t.Errorf("ParseGroupRoles(%q) should fail", spec)
+		}
+	}
+}
+
+func TestProjectRoles(t *testing.T) {
+	mappings := []GroupRole{
+		{"web-admins", "web", "maintainer"},
+		{"eng", "web", "member"},
+		{"eng", "api", "member"},
+		{"ops", "infra", "owner"},
+	}
+
+	roles := ProjectRoles([]string{"eng", "web-admins"}, mappings)
+
+	want := map[string]string{"web": "maintainer", "api": "member"}
+	if len(roles) != len(want) {
+		t.Fatalf("ProjectRoles() = %v, want %v", roles, want)
+	}
+	for projectID, role := range want {
+		if roles[projectID] != role {
+			t.Errorf("role in %s = %q, want %q", projectID, roles[projectID], role)
+		}
+	}
+}
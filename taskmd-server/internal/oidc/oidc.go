@@ -0,0 +1,450 @@
+package oidc
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"io"
+	"math/big"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+)
+
+// signingMethods lists the ID token algorithms accepted from the issuer
+var signingMethods = []string{"RS256", "RS384", "RS512", "ES256", "ES384", "ES512"}
+
+// clockSkew is the leeway allowed when checking ID token times
+const clockSkew = time.Minute
+
+// maxResponseBody bounds the size of discovery, JWKS and token responses
+const maxResponseBody = 1 << 20
+
+// Provider holds the endpoints of an issuer, read from its discovery document
+type Provider struct {
+	Issuer                string `json:"issuer"`
+	AuthorizationEndpoint string `json:"authorization_endpoint"`
+	TokenEndpoint         string `json:"token_endpoint"`
+	JWKSURI               string `json:"jwks_uri"`
+	UserinfoEndpoint      string `json:"userinfo_endpoint,omitempty"`
+}
+
+// Discover reads the discovery document of an issuer
+func Discover(ctx context.Context, client *http.Client, issuer string) (*Provider, error) {
+	wellKnown := strings.TrimSuffix(issuer, "/") + "/.well-known/openid-configuration"
+
+	var provider Provider
+	if err := getJSON(ctx, client, wellKnown, &provider); err != nil {
+		return nil, fmt.Errorf("failed to discover OIDC provider: %w", err)
+	}
+
+	// The issuer must match exactly, since ID tokens are checked against it
+	if provider.Issuer != issuer {
+		return nil, fmt.Errorf("issuer mismatch: discovery document is for %q, expected %q", provider.Issuer, issuer)
+	}
+	if provider.AuthorizationEndpoint == "" || provider.TokenEndpoint == "" || provider.JWKSURI == "" {
+		return nil, fmt.Errorf("discovery document of %s is missing required endpoints", issuer)
+	}
+
+	return &provider, nil
+}
+
+// Config holds the client registration at the issuer
+type Config struct {
+	Issuer       string
+	ClientID     string
+	ClientSecret string
+	RedirectURL  string
+	Scopes       []string
+
+	// GroupsClaim is the ID token claim that lists the user's groups
+	GroupsClaim string
+
+	// HTTPClient is used for all requests to the issuer; http.DefaultClient when nil
+	HTTPClient *http.Client
+}
+
+// Client runs the authorization code flow with PKCE against an issuer
+type Client struct {
+	cfg      Config
+	provider *Provider
+	keys     *keySet
+	now      func() time.Time
+}
+
+// NewClient discovers the issuer and creates a client for it
+func NewClient(ctx context.Context, cfg Config) (*Client, error) {
+	if cfg.HTTPClient == nil {
+		cfg.HTTPClient = http.DefaultClient
+	}
+	if len(cfg.Scopes) == 0 {
+		cfg.Scopes = []string{"openid", "email", "profile"}
+	}
+	if cfg.GroupsClaim == "" {
+		cfg.GroupsClaim = "groups"
+	}
+
+	provider, err := Discover(ctx, cfg.HTTPClient, cfg.Issuer)
+	if err != nil {
+		return nil, err
+	}
+
+	return &Client{
+		cfg:      cfg,
+		provider: provider,
+		keys:     &keySet{client: cfg.HTTPClient, uri: provider.JWKSURI},
+		now:      time.Now,
+	}, nil
+}
+
+// Issuer returns the issuer identifier, which qualifies the subjects of its users
+func (c *Client) Issuer() string {
+	return c.provider.Issuer
+}
+
+// AuthCodeURL returns the URL of the issuer's login page for a new authorization request
+func (c *Client) AuthCodeURL(state, nonce, codeVerifier string) string {
+	params := url.Values{
+		"response_type":         {"code"},
+		"client_id":             {c.cfg.ClientID},
+		"redirect_uri":          {c.cfg.RedirectURL},
+		"scope":                 {strings.Join(ensureOpenID(c.cfg.Scopes), " ")},
+		"state":                 {state},
+		"nonce":                 {nonce},
+		"code_challenge":        {CodeChallenge(codeVerifier)},
+		"code_challenge_method": {"S256"},
+	}
+
+	separator := "?"
+	if strings.Contains(c.provider.AuthorizationEndpoint, "?") {
+		separator = "&"
+	}
+	return c.provider.AuthorizationEndpoint + separator + params.Encode()
+}
+
+// Token is the response of the token endpoint
+type Token struct {
+	AccessToken string `json:"access_token"`
+	TokenType   string `json:"token_type"`
+	IDToken     string `json:"id_token"`
+	ExpiresIn   int    `json:"expires_in,omitempty"`
+}
+
+// tokenError is the error response of the token endpoint
+type tokenError struct {
+	Error       string `json:"error"`
+	Description string `json:"error_description,omitempty"`
+}
+
+// Exchange redeems an authorization code at the token endpoint
+func (c *Client) Exchange(ctx context.Context, code, codeVerifier string) (*Token, error) {
+	form := url.Values{
+		"grant_type":    {"authorization_code"},
+		"code":          {code},
+		"redirect_uri":  {c.cfg.RedirectURL},
+		"code_verifier": {codeVerifier},
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, c.provider.TokenEndpoint, strings.NewReader(form.Encode()))
+	if err != nil {
+		return nil, fmt.Errorf("invalid token request: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	req.Header.Set("Accept", "application/json")
+	req.SetBasicAuth(url.QueryEscape(c.cfg.ClientID), url.QueryEscape(c.cfg.ClientSecret))
+
+	resp, err := c.cfg.HTTPClient.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("token request failed: %w", err)
+	}
+	defer resp.Body.Close()
+
+	body, err := io.ReadAll(io.LimitReader(resp.Body, maxResponseBody))
+	if err != nil {
+		return nil, fmt.Errorf("failed to read token response: %w", err)
+	}
+
+	if resp.StatusCode != http.StatusOK {
+		var tokenErr tokenError
+		if json.Unmarshal(body, &tokenErr) == nil && tokenErr.Error != "" {
+			return nil, fmt.Errorf("token endpoint returned %s: %s", tokenErr.Error, tokenErr.Description)
+		}
+		return nil, fmt.Errorf("token endpoint responded with status %d", resp.StatusCode)
+	}
+
+	var token Token
+	if err := json.Unmarshal(body, &token); err != nil {
+		return nil, fmt.Errorf("invalid token response: %w", err)
+	}
+	if token.IDToken == "" {
+		return nil, fmt.Errorf("token response has no id_token")
+	}
+
+	return &token, nil
+}
+
+// Identity holds the verified claims of an ID token
+type Identity struct {
+	Issuer        string
+	Subject       string
+	Email         string
+	EmailVerified bool
+	Name          string
+	Picture       string
+	Groups        []string
+}
+
+// idTokenClaims are the ID token claims read by the client
+type idTokenClaims struct {
+	Nonce         string `json:"nonce"`
+	Email         string `json:"email"`
+	EmailVerified bool   `json:"email_verified"`
+	Name          string `json:"name"`
+	Picture       string `json:"picture"`
+	jwt.RegisteredClaims
+
+	raw map[string]interface{}
+}
+
+// UnmarshalJSON keeps all claims so that the configured groups claim can be read
+func (c *idTokenClaims) UnmarshalJSON(data []byte) error {
+	type plain idTokenClaims
+	if err := json.Unmarshal(data, (*plain)(c)); err != nil {
+		return err
+	}
+	return json.Unmarshal(data, &c.raw)
+}
+
+// VerifyIDToken checks the signature, issuer, audience, expiry and nonce of an ID token
+func (c *Client) VerifyIDToken(ctx context.Context, rawIDToken, nonce string) (*Identity, error) {
+	parser := jwt.NewParser(
+		jwt.WithValidMethods(signingMethods),
+		jwt.WithIssuer(c.provider.Issuer),
+		jwt.WithAudience(c.cfg.ClientID),
+		jwt.WithExpirationRequired(),
+		jwt.WithLeeway(clockSkew),
+		jwt.WithTimeFunc(c.now),
+	)
+
+	claims := &idTokenClaims{}
+	_, err := parser.ParseWithClaims(rawIDToken, claims, func(token *jwt.Token) (interface{}, error) {
+		kid, _ := token.Header["kid"].(string)
+		return c.keys.get(ctx, kid)
+	})
+	if err != nil {
+		return nil, fmt.Errorf("invalid ID token: %w", err)
+	}
+
+	if claims.Subject == "" {
+		return nil, fmt.Errorf("invalid ID token: missing subject")
+	}
+	if claims.Nonce != nonce {
+		return nil, fmt.Errorf("invalid ID token: nonce mismatch")
+	}
+
+	return &Identity{
+		Issuer:        claims.Issuer,
+		Subject:       claims.Subject,
+		Email:         claims.Email,
+		EmailVerified: claims.EmailVerified,
+		Name:          claims.Name,
+		Picture:       claims.Picture,
+		Groups:        stringsClaim(claims.raw[c.cfg.GroupsClaim]),
+	}, nil
+}
+
+// stringsClaim reads a claim that is either a list of strings or a single string
+func stringsClaim(value interface{}) []string {
+	switch v := value.(type) {
+	case string:
+		return []string{v}
+	case []interface{}:
+		values := make([]string, 0, len(v))
+		for _, item := range v {
+			if s, ok := item.(string); ok {
+				values = append(values, s)
+			}
+		}
+		return values
+	default:
+		return nil
+	}
+}
+
+// ensureOpenID makes sure the openid scope is requested
+func ensureOpenID(scopes []string) []string {
+	for _, scope := range scopes {
+		if scope == "openid" {
+			return scopes
+		}
+	}
+	return append([]string{"openid"}, scopes...)
+}
+
+// RandomString returns a URL-safe random string, used for states, nonces and PKCE verifiers
+func RandomString() (string, error) {
+	b := make([]byte, 32)
+	if _, err := rand.Read(b); err != nil {
+		return "", fmt.Errorf("failed to generate random string: %w", err)
+	}
+	return base64.RawURLEncoding.EncodeToString(b), nil
+}
+
+// CodeChallenge derives the S256 PKCE challenge of a code verifier
+func CodeChallenge(codeVerifier string) string {
+	sum := sha256.Sum256([]byte(codeVerifier))
+	return base64.RawURLEncoding.EncodeToString(sum[:])
+}
+
+// keySet caches the issuer's signing keys. Unknown key IDs trigger a refetch, so that
+// rotated keys are picked up, but at most once per minute.
+type keySet struct {
+	client *http.Client
+	uri    string
+
+	mu        sync.Mutex
+	keys      map[string]interface{}
+	fetchedAt time.Time
+}
+
+// jwk is a JSON Web Key
+type jwk struct {
+	Kty string `json:"kty"`
+	Kid string `json:"kid"`
+	Use string `json:"use"`
+	N   string `json:"n"`
+	E   string `json:"e"`
+	Crv string `json:"crv"`
+	X   string `json:"x"`
+	Y   string `json:"y"`
+}
+
+// get returns the public key with the given ID
+func (s *keySet) get(ctx context.Context, kid string) (interface{}, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if key, ok := s.lookup(kid); ok {
+		return key, nil
+	}
+
+	if s.keys != nil && time.Since(s.fetchedAt) < time.Minute {
+		return nil, fmt.Errorf("unknown signing key %q", kid)
+	}
+
+	if err := s.fetch(ctx); err != nil {
+		return nil, err
+	}
+
+	if key, ok := s.lookup(kid); ok {
+		return key, nil
+	}
+	return nil, fmt.Errorf("unknown signing key %q", kid)
+}
+
+// lookup finds a cached key. Tokens without a key ID are accepted when the set has a single key.
+func (s *keySet) lookup(kid string) (interface{}, bool) {
+	if kid == "" && len(s.keys) == 1 {
+		for _, key := range s.keys {
+			return key, true
+		}
+	}
+	key, ok := s.keys[kid]
+	return key, ok
+}
+
+// fetch replaces the cached keys with the issuer's current JWKS
+func (s *keySet) fetch(ctx context.Context) error {
+	var document struct {
+		Keys []jwk `json:"keys"`
+	}
+	if err := getJSON(ctx, s.client, s.uri, &document); err != nil {
+		return fmt.Errorf("failed to fetch signing keys: %w", err)
+	}
+
+	keys := make(map[string]interface{}, len(document.Keys))
+	for _, k := range document.Keys {
+		if k.Use != "" && k.Use != "sig" {
+			continue
+		}
+		key, err := k.publicKey()
+		if err != nil {
+			continue
+		}
+		keys[k.Kid] = key
+	}
+
+	s.keys = keys
+	s.fetchedAt = time.Now()
+	return nil
+}
+
+// publicKey decodes an RSA or EC public key
+func (k *jwk) publicKey() (interface{}, error) {
+	switch k.Kty {
+	case "RSA":
+		n, err := base64.RawURLEncoding.DecodeString(k.N)
+		if err != nil {
+			return nil, err
+		}
+		e, err := base64.RawURLEncoding.DecodeString(k.E)
+		if err != nil {
+			return nil, err
+		}
+		return &rsa.PublicKey{N: new(big.Int).SetBytes(n), E: int(new(big.Int).SetBytes(e).Int64())}, nil
+	case "EC":
+		var curve elliptic.Curve
+		switch k.Crv {
+		case "P-256":
+			curve = elliptic.P256()
+		case "P-384":
+			curve = elliptic.P384()
+		case "P-521":
+			curve = elliptic.P521()
+		default:
+			return nil, fmt.Errorf("unsupported curve %q", k.Crv)
+		}
+		x, err := base64.RawURLEncoding.DecodeString(k.X)
+		if err != nil {
+			return nil, err
+		}
+		y, err := base64.RawURLEncoding.DecodeString(k.Y)
+		if err != nil {
+			return nil, err
+		}
+		return &ecdsa.PublicKey{Curve: curve, X: new(big.Int).SetBytes(x), Y: new(big.Int).SetBytes(y)}, nil
+	default:
+		return nil, fmt.Errorf("unsupported key type %q", k.Kty)
+	}
+}
+
+// getJSON fetches and decodes a JSON document
+func getJSON(ctx context.Context, client *http.Client, uri string, v interface{}) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, uri, nil)
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Accept", "application/json")
+
+	resp, err := client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("%s responded with status %d", uri, resp.StatusCode)
+	}
+
+	return json.NewDecoder(io.LimitReader(resp.Body, maxResponseBody)).Decode(v)
+}
@@ -0,0 +1,383 @@
+package oidc
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/rsa"
+	"encoding/base64"
+	"encoding/json"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+)
+
+const (
+	testClientID     = "taskmd"
+	testClientSecret = "s3cret"
+	testRedirectURL  = "http://localhost:8080/api/v1/auth/oidc/callback"
+)
+
+// mockIssuer is a minimal OIDC issuer serving discovery, JWKS and the token endpoint
+type mockIssuer struct {
+	t      *testing.T
+	server *httptest.Server
+
+	mu         sync.Mutex
+	key        *rsa.PrivateKey
+	kid        string
+	codes      map[string]mockGrant
+	jwksHits   int
+	idTokenFor func(grant mockGrant) jwt.MapClaims
+}
+
+// mockGrant is an authorization code issued by the mock login page
+type mockGrant struct {
+	challenge string
+	nonce     string
+	subject   string
+}
+
+func newMockIssuer(t *testing.T) *mockIssuer {
+	t.Helper()
+
+	m := &mockIssuer{t: t, codes: make(map[string]mockGrant)}
+	m.rotateKey("key-1")
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("/.well-known/openid-configuration", func(w http.ResponseWriter, r *http.Request) {
+		writeJSON(w, http.StatusOK, map[string]string{
+			"issuer":                 m.server.URL,
+			"authorization_endpoint": m.server.URL + "/authorize",
+			"token_endpoint":         m.server.URL + "/token",
+			"jwks_uri":               m.server.URL + "/jwks",
+		})
+	})
+	mux.HandleFunc("/jwks", func(w http.ResponseWriter, r *http.Request) {
+		m.mu.Lock()
+		defer m.mu.Unlock()
+		m.jwksHits++
+		writeJSON(w, http.StatusOK, map[string]interface{}{
+			"keys": []map[string]string{{
+				"kty": "RSA",
+				"kid": m.kid,
+				"use": "sig",
+				"alg": "RS256",
+				"n":   base64.RawURLEncoding.EncodeToString(m.key.N.Bytes()),
+				"e":   base64.RawURLEncoding.EncodeToString(big.NewInt(int64(m.key.E)).Bytes()),
+			}},
+		})
+	})
+	mux.HandleFunc("/token", m.handleToken)
+
+	m.server = httptest.NewServer(mux)
+	t.Cleanup(m.server.Close)
+
+	return m
+}
+
+func (m *mockIssuer) rotateKey(kid string) {
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		m.t.Fatalf("failed to generate key: %v", err)
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.key = key
+	m.kid = kid
+}
+
+// authorize simulates a user logging in at the issuer and returns the authorization code
+func (m *mockIssuer) authorize(authURL, subject string) (code, state string) {
+	m.t.Helper()
+
+	u, err := url.Parse(authURL)
+	if err != nil {
+		m.t.Fatalf("invalid authorization URL: %v", err)
+	}
+	q := u.Query()
+	if q.Get("client_id") != testClientID || q.Get("redirect_uri") != testRedirectURL || q.Get("code_challenge_method") != "S256" {
+		m.t.Fatalf("unexpected authorization request: %s", authURL)
+	}
+
+	code = "code-" + subject
+	m.mu.Lock()
+	m.codes[code] = mockGrant{challenge: q.Get("code_challenge"), nonce: q.Get("nonce"), subject: subject}
+	m.mu.Unlock()
+
+	return code, q.Get("state")
+}
+
+func (m *mockIssuer) handleToken(w http.ResponseWriter, r *http.Request) {
+	clientID, clientSecret, ok := r.BasicAuth()
+	if !ok || clientID != testClientID || clientSecret != testClientSecret {
+		writeJSON(w, http.StatusUnauthorized, map[string]string{"error": "invalid_client"})
+		return
+	}
+
+	if err := r.ParseForm(); err != nil || r.PostForm.Get("grant_type") != "authorization_code" {
+		writeJSON(w, http.StatusBadRequest, map[string]string{"error": "unsupported_grant_type"})
+		return
+	}
+
+	m.mu.Lock()
+	grant, ok := m.codes[r.PostForm.Get("code")]
+	delete(m.codes, r.PostForm.Get("code"))
+	m.mu.Unlock()
+
+	if !ok || r.PostForm.Get("redirect_uri") != testRedirectURL {
+		writeJSON(w, http.StatusBadRequest, map[string]string{"error": "invalid_grant"})
+		return
+	}
+	if CodeChallenge(r.PostForm.Get("code_verifier")) != grant.challenge {
+		writeJSON(w, http.StatusBadRequest, map[string]string{"error": "invalid_grant", "error_description": "PKCE verification failed"})
+		return
+	}
+
+	claims := jwt.MapClaims{
+		"iss":            m.server.URL,
+		"sub":            grant.subject,
+		"aud":            testClientID,
+		"exp":            time.Now().Add(time.Hour).Unix(),
+		"iat":            time.Now().Unix(),
+		"nonce":          grant.nonce,
+		"email":          grant.subject + "@example.com",
+		"email_verified": true,
+		"name":           "User " + grant.subject,
+		"groups":         []string{"eng", "web-admins"},
+	}
+	if m.idTokenFor != nil {
+		claims = m.idTokenFor(grant)
+	}
+
+	writeJSON(w, http.StatusOK, map[string]interface{}{
+		"access_token": "access-" + grant.subject,
+		"token_type":   "Bearer",
+		"expires_in":   3600,
+		"id_token":     m.sign(claims),
+	})
+}
+
+func (m *mockIssuer) sign(claims jwt.MapClaims) string {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	token := jwt.NewWithClaims(jwt.SigningMethodRS256, claims)
+	token.Header["kid"] = m.kid
+	signed, err := token.SignedString(m.key)
+	if err != nil {
+		m.t.Fatalf("failed to sign ID token: %v", err)
+	}
+	return signed
+}
+
+func writeJSON(w http.ResponseWriter, status int, v interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	_ = json.NewEncoder(w).Encode(v)
+}
+
+func newTestClient(t *testing.T, issuer *mockIssuer) *Client {
+	t.Helper()
+
+	client, err := NewClient(context.Background(), Config{
+		Issuer:       issuer.server.URL,
+		ClientID:     testClientID,
+		ClientSecret: testClientSecret,
+		RedirectURL:  testRedirectURL,
+		HTTPClient:   issuer.server.Client(),
+	})
+	if err != nil {
+		t.Fatalf("NewClient() error: %v", err)
+	}
+	return client
+}
+
+// login runs the authorization code flow and returns the verified identity
+func login(t *testing.T, client *Client, issuer *mockIssuer, subject string) (*Identity, error) {
+	t.Helper()
+
+	flow, err := NewFlow("/")
+	if err != nil {
+		t.Fatalf("NewFlow() error: %v", err)
+	}
+
+	code, state := issuer.authorize(client.AuthCodeURL(flow.State, flow.Nonce, flow.CodeVerifier), subject)
+	if state != flow.State {
+		t.Fatalf("state = %q, want %q", state, flow.State)
+	}
+
+	token, err := client.Exchange(context.Background(), code, flow.CodeVerifier)
+	if err != nil {
+		return nil, err
+	}
+	return client.VerifyIDToken(context.Background(), token.IDToken, flow.Nonce)
+}
+
+func TestAuthorizationCodeFlow(t *testing.T) {
+	issuer := newMockIssuer(t)
+	client := newTestClient(t, issuer)
+
+	identity, err := login(t, client, issuer, "alice")
+	if err != nil {
+		t.Fatalf("login error: %v", err)
+	}
+
+	if identity.Issuer != issuer.server.URL || identity.Subject != "alice" {
+		t.Errorf("identity = %s/%s, want %s/alice", identity.Issuer, identity.Subject, issuer.server.URL)
+	}
+	if identity.Email != "alice@example.com" || !identity.EmailVerified || identity.Name != "User alice" {
+		t.Errorf("unexpected profile claims: %+v", identity)
+	}
+	if strings.Join(identity.Groups, ",") != "eng,web-admins" {
+		t.Errorf("groups = %v, want [eng web-admins]", identity.Groups)
+	}
+}
+
+func TestExchangeRejectsWrongCodeVerifier(t *testing.T) {
+	issuer := newMockIssuer(t)
+	client := newTestClient(t, issuer)
+
+	flow, _ := NewFlow("/")
+	code, _ := issuer.authorize(client.AuthCodeURL(flow.State, flow.Nonce, flow.CodeVerifier), "alice")
+
+	_, err := client.Exchange(context.Background(), code, "another-verifier")
+	if err == nil || !strings.Contains(err.Error(), "invalid_grant") {
+		t.Errorf("Exchange() error = %v, want invalid_grant", err)
+	}
+}
+
+func TestVerifyIDTokenRejectsInvalidTokens(t *testing.T) {
+	tests := []struct {
+		name   string
+		mutate func(claims jwt.MapClaims)
+		want   string
+	}{
+		{"wrong audience", func(c jwt.MapClaims) { c["aud"] = "another-client" }, "audience"},
+		{"wrong issuer", func(c jwt.MapClaims) { c["iss"] = "https://evil.example.com" }, "issuer"},
+		{"expired", func(c jwt.MapClaims) { c["exp"] = time.Now().Add(-time.Hour).Unix() }, "expired"},
+		{"missing expiry", func(c jwt.MapClaims) { delete(c, "exp") }, "exp"},
+		{"replayed nonce", func(c jwt.MapClaims) { c["nonce"] = "old-nonce" }, "nonce"},
+		{"missing subject", func(c jwt.MapClaims) { delete(c, "sub") }, "subject"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			issuer := newMockIssuer(t)
+			client := newTestClient(t, issuer)
+			issuer.idTokenFor = func(grant mockGrant) jwt.MapClaims {
+				claims := jwt.MapClaims{
+					"iss":   issuer.server.URL,
+					"sub":   grant.subject,
+					"aud":   testClientID,
+					"exp":   time.Now().Add(time.Hour).Unix(),
+					"nonce": grant.nonce,
+				}
+				tt.mutate(claims)
+				return claims
+			}
+
+			_, err := login(t, client, issuer, "alice")
+			if err == nil || !strings.Contains(err.Error(), tt.want) {
+				t.Errorf("login error = %v, want error containing %q", err, tt.want)
+			}
+		})
+	}
+}
+
+func TestVerifyIDTokenRejectsForgedSignature(t *testing.T) {
+	issuer := newMockIssuer(t)
+	client := newTestClient(t, issuer)
+
+	forger, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatalf("failed to generate key: %v", err)
+	}
+	token := jwt.NewWithClaims(jwt.SigningMethodRS256, jwt.MapClaims{
+		"iss":   issuer.server.URL,
+		"sub":   "mallory",
+		"aud":   testClientID,
+		"exp":   time.Now().Add(time.Hour).Unix(),
+		"nonce": "n",
+	})
+	token.Header["kid"] = "key-1"
+	forged, _ := token.SignedString(forger)
+
+	if _, err := client.VerifyIDToken(context.Background(), forged, "n"); err == nil {
+		t.Error("VerifyIDToken() accepted a token signed with an unknown key")
+	}
+
+	hmacToken, _ := jwt.NewWithClaims(jwt.SigningMethodHS256, jwt.MapClaims{
+		"iss": issuer.server.URL, "sub": "mallory", "aud": testClientID, "exp": time.Now().Add(time.Hour).Unix(), "nonce": "n",
+	}).SignedString([]byte(testClientSecret))
+	if _, err := client.VerifyIDToken(context.Background(), hmacToken, "n"); err == nil {
+		t.Error("VerifyIDToken() accepted an HS256 token")
+	}
+}
+
+func TestKeyRotation(t *testing.T) {
+	issuer := newMockIssuer(t)
+	client := newTestClient(t, issuer)
+
+	if _, err := login(t, client, issuer, "alice"); err != nil {
+		t.Fatalf("login error: %v", err)
+	}
+	if _, err := login(t, client, issuer, "bob"); err != nil {
+		t.Fatalf("login error: %v", err)
+	}
+	if issuer.jwksHits != 1 {
+		t.Errorf("JWKS fetched %d times, want 1 (keys are cached)", issuer.jwksHits)
+	}
+
+	// A new key ID is fetched once the refetch interval has passed
+	issuer.rotateKey("key-2")
+	client.keys.fetchedAt = time.Now().Add(-2 * time.Minute)
+
+	if _, err := login(t, client, issuer, "carol"); err != nil {
+		t.Fatalf("login after key rotation error: %v", err)
+	}
+	if issuer.jwksHits != 2 {
+		t.Errorf("JWKS fetched %d times, want 2", issuer.jwksHits)
+	}
+}
+
+func TestDiscoverRejectsIssuerMismatch(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		writeJSON(w, http.StatusOK, map[string]string{
+			"issuer":                 "https://other.example.com",
+			"authorization_endpoint": "https://other.example.com/authorize",
+			"token_endpoint":         "https://other.example.com/token",
+			"jwks_uri":               "https://other.example.com/jwks",
+		})
+	}))
+	defer server.Close()
+
+	if _, err := Discover(context.Background(), server.Client(), server.URL); err == nil {
+		t.Error("Discover() accepted a discovery document for another issuer")
+	}
+}
+
+func TestAuthCodeURLUsesS256Challenge(t *testing.T) {
+	issuer := newMockIssuer(t)
+	client := newTestClient(t, issuer)
+
+	u, err := url.Parse(client.AuthCodeURL("state-1", "nonce-1", "verifier-1"))
+	if err != nil {
+		t.Fatalf("invalid URL: %v", err)
+	}
+	q := u.Query()
+
+	if q.Get("code_challenge") != CodeChallenge("verifier-1") || q.Get("code_verifier") != "" {
+		t.Error("authorization URL must carry the challenge, not the verifier")
+	}
+	if q.Get("scope") != "openid email profile" || q.Get("response_type") != "code" {
+		t.Errorf("unexpected scope or response type: %s", u.RawQuery)
+	}
+}
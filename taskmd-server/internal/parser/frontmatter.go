@@ -0,0 +1,222 @@
+package parser
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/pelletier/go-toml/v2"
+	"gopkg.in/yaml.v3"
+)
+
+// FrontmatterDialect identifies how the metadata block of a task is written
+type FrontmatterDialect string
+
+const (
+	// DialectFenced is a ```yaml fenced code block (the native taskmd format)
+	DialectFenced FrontmatterDialect = "fenced_yaml"
+	// DialectYAML is standard --- YAML frontmatter (Obsidian, Jekyll, GitHub)
+	DialectYAML FrontmatterDialect = "yaml"
+	// DialectTOML is +++ TOML frontmatter (Hugo)
+	DialectTOML FrontmatterDialect = "toml"
+)
+
+// MarkdownFormat records the style a task document was written in,
+// so it can be written back the same way
+type MarkdownFormat struct {
+	Dialect FrontmatterDialect `json:"dialect"`
+	// HeadingLevel is the level of the title heading, or 0 when the title is in the frontmatter
+	HeadingLevel int `json:"heading_level"`
+	// HeadingID is true when the title heading is written as "ID: Title"
+	HeadingID bool `json:"heading_id"`
+}
+
+// DefaultFormat is the native taskmd format: ## ID: Title followed by a ```yaml block
+var DefaultFormat = MarkdownFormat{Dialect: DialectFenced, HeadingLevel: 2, HeadingID: true}
+
+var (
+	yamlFrontmatterPattern = regexp.MustCompile(`(?s)\A\s*---[ \t]*\n(.*?)\n---[ \t]*(?:\n|\z)`)
+	tomlFrontmatterPattern = regexp.MustCompile(`(?s)\A\s*\+\+\+[ \t]*\n(.*?)\n\+\+\+[ \t]*(?:\n|\z)`)
+	headingPattern         = regexp.MustCompile(`^(#{1,6})[ \t]+(.+?)[ \t]*$`)
+	headingIDPattern       = regexp.MustCompile(`^([A-Z]+-\d+):\s+(.+)$`)
+)
+
+// frontmatterBlock is the location of the metadata block in a document
+type frontmatterBlock struct {
+	dialect FrontmatterDialect
+	// contentStart and contentEnd delimit the metadata content (without delimiters)
+	contentStart int
+	contentEnd   int
+	// end is the offset just after the closing delimiter
+	end int
+}
+
+// locateFrontmatter finds the metadata block of a document.
+// --- and +++ frontmatter must start the document; otherwise a ```yaml block is used.
+func locateFrontmatter(markdown string) (*frontmatterBlock, error) {
+	if loc := tomlFrontmatterPattern.FindStringSubmatchIndex(markdown); loc != nil {
+		return &frontmatterBlock{dialect: DialectTOML, contentStart: loc[2], contentEnd: loc[3], end: loc[1]}, nil
+	}
+
+	if loc := yamlFrontmatterPattern.FindStringSubmatchIndex(markdown); loc != nil {
+		return &frontmatterBlock{dialect: DialectYAML, contentStart: loc[2], contentEnd: loc[3], end: loc[1]}, nil
+	}
+
+	if loc := yamlBlockPattern.FindStringSubmatchIndex(markdown); loc != nil {
+		return &frontmatterBlock{dialect: DialectFenced, contentStart: loc[2], contentEnd: loc[3], end: loc[1]}, nil
+	}
+
+	return nil, fmt.Errorf("frontmatter not found (expected ```yaml ... ```, --- YAML --- or +++ TOML +++)")
+}
+
+// HasFrontmatter reports whether markdown contains a metadata block
+func HasFrontmatter(markdown string) bool {
+	_, err := locateFrontmatter(markdown)
+	return err == nil
+}
+
+// FrontmatterBounds returns the dialect and the offsets of the metadata content of a document.
+// ok is false when the document has no metadata block.
+func FrontmatterBounds(markdown string) (dialect FrontmatterDialect, start, end int, ok bool) {
+	block, err := locateFrontmatter(markdown)
+	if err != nil {
+		return "", 0, 0, false
+	}
+	return block.dialect, block.contentStart, block.contentEnd, true
+}
+
+// decodeFrontmatter decodes metadata content of the given dialect
+func decodeFrontmatter(dialect FrontmatterDialect, content string, metadata *TaskMetadata) error {
+	if dialect != DialectTOML {
+		if err := yaml.Unmarshal([]byte(content), metadata); err != nil {
+			return fmt.Errorf("failed to parse YAML metadata: %w", err)
+		}
+		return nil
+	}
+
+	var raw map[string]interface{}
+	if err := toml.Unmarshal([]byte(content), &raw); err != nil {
+		return fmt.Errorf("failed to parse TOML metadata: %w", err)
+	}
+
+	// Convert through YAML so both dialects share the same field mapping
+	converted, err := yaml.Marshal(normalizeTOML(raw))
+	if err != nil {
+		return fmt.Errorf("failed to convert TOML metadata: %w", err)
+	}
+	if err := yaml.Unmarshal(converted, metadata); err != nil {
+		return fmt.Errorf("failed to parse TOML metadata: %w", err)
+	}
+
+	return nil
+}
+
+// normalizeTOML converts TOML date and time values into strings
+func normalizeTOML(value interface{}) interface{} {
+	switch v := value.(type) {
+	case map[string]interface{}:
+		result := make(map[string]interface{}, len(v))
+		for key, item := range v {
+			result[key] = normalizeTOML(item)
+		}
+		return result
+	case []interface{}:
+		result := make([]interface{}, len(v))
+		for i, item := range v {
+			result[i] = normalizeTOML(item)
+		}
+		return result
+	case toml.LocalDate:
+		return v.String()
+	case toml.LocalDateTime:
+		return v.String()
+	case toml.LocalTime:
+		return v.String()
+	case time.Time:
+		return v.Format(time.RFC3339)
+	}
+	return value
+}
+
+// titleHeading is a heading that provides the task title
+type titleHeading struct {
+	level int
+	id    string
+	title string
+	// start and end delimit the heading line, including its newline
+	start int
+	end   int
+}
+
+// findTitleHeading returns the heading that carries the task title.
+// A heading written as "ID: Title" wins; otherwise the first heading of any level is used.
+// Headings inside fenced code blocks are ignored.
+func findTitleHeading(markdown string) *titleHeading {
+	var first *titleHeading
+	inFence := false
+	offset := 0
+
+	for _, line := range strings.SplitAfter(markdown, "\n") {
+		start := offset
+		offset += len(line)
+		text := strings.TrimRight(line, "\r\n")
+
+		trimmed := strings.TrimSpace(text)
+		if strings.HasPrefix(trimmed, "```") || strings.HasPrefix(trimmed, "~~~") {
+			inFence = !inFence
+			continue
+		}
+		if inFence {
+			continue
+		}
+
+		match := headingPattern.FindStringSubmatch(text)
+		if match == nil {
+			continue
+		}
+
+		heading := &titleHeading{level: len(match[1]), title: match[2], start: start, end: offset}
+		if idMatch := headingIDPattern.FindStringSubmatch(match[2]); idMatch != nil {
+			heading.id = idMatch[1]
+			heading.title = strings.TrimSpace(idMatch[2])
+			return heading
+		}
+
+		if first == nil {
+			first = heading
+		}
+	}
+
+	return first
+}
+
+// DetectFormat reports the style a task document is written in.
+// DefaultFormat is returned for documents without recognizable frontmatter.
+func DetectFormat(markdown string) MarkdownFormat {
+	parts, err := NewMarkdownParser().extractParts(markdown)
+	if err != nil {
+		return DefaultFormat
+	}
+	return parts.format
+}
+
+// tomlFrontmatter defines the field order used when writing TOML frontmatter
+type tomlFrontmatter struct {
+	ID         string                 `toml:"id"`
+	Title      string                 `toml:"title,omitempty"`
+	Status     string                 `toml:"status"`
+	Priority   string                 `toml:"priority"`
+	ParentID   string                 `toml:"parent_id,omitempty"`
+	Assignees  []string               `toml:"assignees,omitempty"`
+	StartDate  interface{}            `toml:"start_date,omitempty"`
+	DueDate    interface{}            `toml:"due_date,omitempty"`
+	Labels     []string               `toml:"labels,omitempty"`
+	Recurrence string                 `toml:"recurrence,omitempty"`
+	ExtraMeta  map[string]interface{} `toml:"extra_meta,omitempty"`
+}
+
+// tomlDate converts a time into a TOML local date
+func tomlDate(t time.Time) toml.LocalDate {
+	return toml.LocalDate{Year: t.Year(), Month: int(t.Month()), Day: t.Day()}
+}
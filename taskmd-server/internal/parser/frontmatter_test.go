@@ -0,0 +1,189 @@
+package parser
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestMarkdownParser_ParseDialects(t *testing.T) {
+	tests := []struct {
+		name       string
+		markdown   string
+		wantErr    bool
+		wantTitle  string
+		wantDue    string
+		wantFormat MarkdownFormat
+		wantBody   string
+	}{
+		{
+			name:       "native fenced yaml",
+			markdown:   "## T-1: Native task\n\n```yaml\n# owner: core team\nid: T-1\nstatus: open\npriority: P1\n```\n\nBody text\n",
+			wantTitle:  "Native task",
+			wantFormat: DefaultFormat,
+			wantBody:   "Body text",
+		},
+		{
+			name:       "yaml frontmatter with h1 title",
+			markdown:   "---\n# owner: core team\nid: T-2\nstatus: open\npriority: P2\ndue_date: 2026-01-20\n---\n\n# Obsidian note\n\n## Notes\n\nBody text\n",
+			wantTitle:  "Obsidian note",
+			wantDue:    "2026-01-20",
+			wantFormat: MarkdownFormat{Dialect: DialectYAML, HeadingLevel: 1},
+			wantBody:   "## Notes\n\nBody text",
+		},
+		{
+			name:       "yaml frontmatter with title field",
+			markdown:   "---\nid: T-3\ntitle: \"GitHub issue: login fails\"\nstatus: review\npriority: P0\n---\n\n## Steps\n\n1. Open the page\n",
+			wantTitle:  "GitHub issue: login fails",
+			wantFormat: MarkdownFormat{Dialect: DialectYAML},
+			wantBody:   "## Steps\n\n1. Open the page",
+		},
+		{
+			name:       "toml frontmatter",
+			markdown:   "+++\nid = \"T-4\"\ntitle = \"Hugo page\"\nstatus = \"done\"\npriority = \"P3\"\nlabels = [\"docs\"]\ndue_date = 2026-02-01\nrecurrence = \"FREQ=MONTHLY\"\n\n[extra_meta]\nweight = 10\n+++\n\nBody text\n",
+			wantTitle:  "Hugo page",
+			wantDue:    "2026-02-01",
+			wantFormat: MarkdownFormat{Dialect: DialectTOML},
+			wantBody:   "Body text",
+		},
+		{
+			name:       "toml frontmatter with id heading",
+			markdown:   "+++\nid = \"T-5\"\nstatus = \"open\"\npriority = \"P2\"\n+++\n\n### T-5: Heading task\n\nBody text\n",
+			wantTitle:  "Heading task",
+			wantFormat: MarkdownFormat{Dialect: DialectTOML, HeadingLevel: 3, HeadingID: true},
+			wantBody:   "Body text",
+		},
+		{
+			name:     "invalid toml",
+			markdown: "+++\nid = \n+++\n\n# Broken\n",
+			wantErr:  true,
+		},
+		{
+			name:     "no title anywhere",
+			markdown: "---\nid: T-6\nstatus: open\npriority: P2\n---\n\nJust text\n",
+			wantErr:  true,
+		},
+	}
+
+	parser := NewMarkdownParser()
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			result, err := parser.Parse(tt.markdown)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("Parse() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if tt.wantErr {
+				return
+			}
+
+			if result.Title != tt.wantTitle {
+				t.Errorf("Parse() title = %q, want %q", result.Title, tt.wantTitle)
+			}
+			if result.Format != tt.wantFormat {
+				t.Errorf("Parse() format = %+v, want %+v", result.Format, tt.wantFormat)
+			}
+			if tt.wantDue != "" && (result.Metadata.DueDate == nil || *result.Metadata.DueDate != tt.wantDue) {
+				t.Errorf("Parse() due_date = %v, want %s", result.Metadata.DueDate, tt.wantDue)
+			}
+			if body := ExtractBody(tt.markdown); body != tt.wantBody {
+				t.Errorf("ExtractBody() = %q, want %q", body, tt.wantBody)
+			}
+		})
+	}
+}
+
+func TestGenerateMarkdown_RoundTripsDialect(t *testing.T) {
+	documents := map[string]string{
+		"fenced":           "## T-1: Native task\n\n```yaml\nid: T-1\nstatus: open\npriority: P1\nlabels: [backend]\n```\n\nBody text\n",
+		"yaml h1":          "---\nid: T-2\nstatus: open\npriority: P2\ndue_date: 2026-01-20\nrecurrence: FREQ=WEEKLY\n---\n\n# Obsidian note\n\nBody text\n",
+		"yaml title field": "---\nid: T-3\ntitle: \"GitHub issue: login fails\"\nstatus: review\npriority: P0\n---\n\nBody text\n",
+		"toml":             "+++\nid = \"T-4\"\ntitle = \"Hugo page\"\nstatus = \"done\"\npriority = \"P3\"\ndue_date = 2026-02-01\n\n[extra_meta]\nweight = 10\n+++\n\nBody text\n",
+	}
+
+	parser := NewMarkdownParser()
+
+	for name, markdown := range documents {
+		t.Run(name, func(t *testing.T) {
+			original, err := parser.Parse(markdown)
+			if err != nil {
+				t.Fatalf("Parse() error = %v", err)
+			}
+			task, err := original.ToTask("proj")
+			if err != nil {
+				t.Fatalf("ToTask() error = %v", err)
+			}
+			task.Title = task.Title + " (edited)"
+
+			generated := GenerateMarkdown(task)
+
+			regenerated, err := parser.Parse(generated)
+			if err != nil {
+				t.Fatalf("Parse(GenerateMarkdown()) error = %v\n%s", err, generated)
+			}
+			if regenerated.Format != original.Format {
+				t.Errorf("format = %+v, want %+v\n%s", regenerated.Format, original.Format, generated)
+			}
+			if regenerated.Title != task.Title {
+				t.Errorf("title = %q, want %q", regenerated.Title, task.Title)
+			}
+			if regenerated.Metadata.Recurrence != original.Metadata.Recurrence {
+				t.Errorf("recurrence = %q, want %q", regenerated.Metadata.Recurrence, original.Metadata.Recurrence)
+			}
+			if !strings.HasSuffix(generated, "\n\nBody text") {
+				t.Errorf("body not preserved:\n%s", generated)
+			}
+
+			retask, err := regenerated.ToTask("proj")
+			if err != nil {
+				t.Fatalf("ToTask() error = %v", err)
+			}
+			if (task.DueDate == nil) != (retask.DueDate == nil) || (task.DueDate != nil && !task.DueDate.Equal(*retask.DueDate)) {
+				t.Errorf("due date = %v, want %v", retask.DueDate, task.DueDate)
+			}
+		})
+	}
+}
+
+func TestPatchFrontmatter_Dialects(t *testing.T) {
+	tests := []struct {
+		name     string
+		markdown string
+		want     []string
+		notWant  []string
+	}{
+		{
+			name:     "yaml frontmatter",
+			markdown: "---\nid: T-1 # keep\nstatus: open\npriority: P2\ndue_date: 2026-01-20\n---\n\n# Note\n\nBody\n",
+			want:     []string{"---\nid: T-1 # keep\nstatus: done\n", "---\n\n# Note\n\nBody\n"},
+			notWant:  []string{"due_date"},
+		},
+		{
+			name:     "toml frontmatter",
+			markdown: "+++\nid = \"T-1\"\nstatus = \"open\"\npriority = \"P2\"\ndue_date = 2026-01-20\n+++\n\n# Note\n\nBody\n",
+			want:     []string{"status = 'done'", "+++\n\n# Note\n\nBody\n"},
+			notWant:  []string{"due_date"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := PatchFrontmatter(tt.markdown, map[string]interface{}{"status": "done", "due_date": nil})
+			if err != nil {
+				t.Fatalf("PatchFrontmatter() error = %v", err)
+			}
+			for _, w := range tt.want {
+				if !strings.Contains(got, w) {
+					t.Errorf("PatchFrontmatter() missing %q:\n%s", w, got)
+				}
+			}
+			for _, w := range tt.notWant {
+				if strings.Contains(got, w) {
+					t.Errorf("PatchFrontmatter() should not contain %q:\n%s", w, got)
+				}
+			}
+			if _, err := NewMarkdownParser().Parse(got); err != nil {
+				t.Errorf("patched markdown does not parse: %v", err)
+			}
+		})
+	}
+}
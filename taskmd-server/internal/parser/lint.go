@@ -0,0 +1,477 @@
+package parser
+
+import (
+	"errors"
+	"fmt"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"github.com/pelletier/go-toml/v2"
+	"gopkg.in/yaml.v3"
+
+	"github.com/tktomaru/taskai/taskai-server/internal/recurrence"
+)
+
+// Severity represents how serious a diagnostic is
+type Severity string
+
+const (
+	// SeverityError marks problems that prevent the task from being saved
+	SeverityError Severity = "error"
+	// SeverityWarning marks problems that are saved but probably unintended
+	SeverityWarning Severity = "warning"
+)
+
+// Lint rules
+const (
+	RuleFrontmatterMissing = "frontmatter-missing"
+	RuleFrontmatterSyntax  = "frontmatter-syntax"
+	RuleTitleMissing       = "title-missing"
+	RuleRequiredField      = "required-field"
+	RuleUnknownKey         = "unknown-key"
+	RuleInvalidType        = "invalid-type"
+	RuleInvalidEnum        = "invalid-enum"
+	RuleInvalidDate        = "invalid-date"
+	RuleDateOrder          = "date-order"
+	RuleInvalidRecurrence  = "invalid-recurrence"
+	RuleUnknownAssignee    = "unknown-assignee"
+	RuleDuplicateChecklist = "duplicate-checklist-item"
+	RuleBrokenReference    = "broken-reference"
+)
+
+// Diagnostic is a problem found in a task document.
+// Line and Column are 1-based; columns count characters.
+type Diagnostic struct {
+	Severity Severity `json:"severity"`
+	Line     int      `json:"line"`
+	Column   int      `json:"column"`
+	Rule     string   `json:"rule"`
+	Message  string   `json:"message"`
+}
+
+// LintOptions provides project context for the checks that need it
+type LintOptions struct {
+	// Members holds the identifiers of project members; nil skips the assignee check
+	Members map[string]bool
+	// TaskExists reports whether a task exists in the project; nil skips the reference checks
+	TaskExists func(taskID string) bool
+	// TaskPrefixes limits body reference checks to IDs with these prefixes (e.g. "T");
+	// nil checks every reference
+	TaskPrefixes map[string]bool
+}
+
+var (
+	knownFields = map[string]bool{
+		"id": true, "title": true, "parent_id": true, "status": true, "priority": true,
+		"assignees": true, "labels": true, "start_date": true, "due_date": true,
+		"recurrence": true, "extra_meta": true,
+	}
+	statusValues   = []string{"open", "in_progress", "review", "blocked", "done", "archived"}
+	priorityValues = []string{"P0", "P1", "P2", "P3", "P4"}
+
+	yamlErrorLinePattern = regexp.MustCompile(`^yaml: line (\d+): `)
+	checklistPattern     = regexp.MustCompile(`^(\s*[-*+]\s+\[[ xX]\]\s+)(.+?)\s*$`)
+)
+
+// frontmatterField is a top-level frontmatter field with its position
+type frontmatterField struct {
+	key         string
+	line        int
+	column      int
+	valueLine   int
+	valueColumn int
+	// kind is "scalar", "sequence", "mapping" or "null"
+	kind  string
+	value string
+	items []frontmatterItem
+}
+
+// frontmatterItem is a scalar item of a sequence field
+type frontmatterItem struct {
+	value  string
+	line   int
+	column int
+}
+
+// linter collects the diagnostics of one document
+type linter struct {
+	markdown    string
+	opts        *LintOptions
+	diagnostics []Diagnostic
+}
+
+// Lint checks a task document and returns its diagnostics ordered by position.
+// It never fails: problems that stop Parse are reported as error diagnostics.
+func Lint(markdown string, opts *LintOptions) []Diagnostic {
+	if opts == nil {
+		opts = &LintOptions{}
+	}
+
+	l := &linter{markdown: markdown, opts: opts}
+	l.run()
+
+	sort.SliceStable(l.diagnostics, func(i, j int) bool {
+		if l.diagnostics[i].Line != l.diagnostics[j].Line {
+			return l.diagnostics[i].Line < l.diagnostics[j].Line
+		}
+		return l.diagnostics[i].Column < l.diagnostics[j].Column
+	})
+
+	return l.diagnostics
+}
+
+func (l *linter) add(severity Severity, line, column int, rule, format string, args ...interface{}) {
+	l.diagnostics = append(l.diagnostics, Diagnostic{
+		Severity: severity,
+		Line:     line,
+		Column:   column,
+		Rule:     rule,
+		Message:  fmt.Sprintf(format, args...),
+	})
+}
+
+func (l *linter) run() {
+	block, err := locateFrontmatter(l.markdown)
+	if err != nil {
+		l.add(SeverityError, 1, 1, RuleFrontmatterMissing, "%s", err.Error())
+		l.checkChecklist(0)
+		l.checkReferences("")
+		return
+	}
+
+	// Line of the opening delimiter, where document-level problems are reported
+	blockLine := lineAt(l.markdown, block.contentStart) - 1
+
+	ownID := ""
+	if fields, ok := l.readFields(block); ok {
+		ownID = l.checkFields(fields, blockLine)
+	}
+
+	if parts, err := NewMarkdownParser().extractParts(l.markdown); err == nil && parts.title == "" {
+		l.add(SeverityError, 1, 1, RuleTitleMissing, "task title not found (expected a title in frontmatter or a heading such as ## ID: Title)")
+	}
+
+	bodyStart := 0
+	if block.dialect != DialectFenced {
+		bodyStart = block.end
+	}
+	l.checkChecklist(bodyStart)
+	l.checkReferences(ownID)
+}
+
+// readFields decodes the frontmatter into positioned fields
+func (l *linter) readFields(block *frontmatterBlock) ([]*frontmatterField, bool) {
+	content := l.markdown[block.contentStart:block.contentEnd]
+	contentLine := lineAt(l.markdown, block.contentStart)
+
+	if block.dialect == DialectTOML {
+		return l.readTOMLFields(content, contentLine)
+	}
+
+	var doc yaml.Node
+	if err := yaml.Unmarshal([]byte(content), &doc); err != nil {
+		line, message := contentLine, err.Error()
+		if match := yamlErrorLinePattern.FindStringSubmatch(message); match != nil {
+			n, _ := strconv.Atoi(match[1])
+			message = strings.TrimPrefix(message, match[0])
+			// yaml.v3 reports 0-based lines for parser errors and 1-based lines for scanner errors
+			if strings.HasPrefix(message, "did not find expected") {
+				n++
+			}
+			line = contentLine + n - 1
+		}
+		l.add(SeverityError, line, 1, RuleFrontmatterSyntax, "invalid YAML: %s", strings.TrimPrefix(message, "yaml: "))
+		return nil, false
+	}
+
+	if len(doc.Content) == 0 || doc.Content[0].Kind != yaml.MappingNode {
+		l.add(SeverityError, contentLine, 1, RuleFrontmatterSyntax, "frontmatter must be a mapping of fields")
+		return nil, false
+	}
+
+	mapping := doc.Content[0]
+	var fields []*frontmatterField
+
+	for i := 0; i+1 < len(mapping.Content); i += 2 {
+		key, value := mapping.Content[i], mapping.Content[i+1]
+		field := &frontmatterField{
+			key:         key.Value,
+			line:        contentLine + key.Line - 1,
+			column:      key.Column,
+			valueLine:   contentLine + value.Line - 1,
+			valueColumn: value.Column,
+		}
+
+		switch {
+		case value.Kind == yaml.ScalarNode && value.Tag == "!!null":
+			field.kind = "null"
+		case value.Kind == yaml.ScalarNode:
+			field.kind = "scalar"
+			field.value = value.Value
+		case value.Kind == yaml.SequenceNode:
+			field.kind = "sequence"
+			for _, item := range value.Content {
+				field.items = append(field.items, frontmatterItem{
+					value:  item.Value,
+					line:   contentLine + item.Line - 1,
+					column: item.Column,
+				})
+			}
+		default:
+			field.kind = "mapping"
+		}
+
+		fields = append(fields, field)
+	}
+
+	return fields, true
+}
+
+// readTOMLFields decodes TOML frontmatter. TOML values carry no positions,
+// so fields are located by searching for their key.
+func (l *linter) readTOMLFields(content string, contentLine int) ([]*frontmatterField, bool) {
+	var raw map[string]interface{}
+	if err := toml.Unmarshal([]byte(content), &raw); err != nil {
+		line, column := contentLine, 1
+		var decodeErr *toml.DecodeError
+		if errors.As(err, &decodeErr) {
+			row, col := decodeErr.Position()
+			line, column = contentLine+row-1, col
+		}
+		l.add(SeverityError, line, column, RuleFrontmatterSyntax, "invalid TOML: %s", err.Error())
+		return nil, false
+	}
+
+	lines := strings.Split(content, "\n")
+	var fields []*frontmatterField
+
+	for key, value := range normalizeTOML(raw).(map[string]interface{}) {
+		field := &frontmatterField{key: key, line: contentLine, column: 1}
+
+		keyPattern := regexp.MustCompile(`^(\s*)"?` + regexp.QuoteMeta(key) + `"?\s*=\s*`)
+		for i, line := range lines {
+			if match := keyPattern.FindStringSubmatchIndex(line); match != nil {
+				field.line = contentLine + i
+				field.column = utf8.RuneCountInString(line[:match[3]]) + 1
+				field.valueColumn = utf8.RuneCountInString(line[:match[1]]) + 1
+				break
+			}
+		}
+		field.valueLine = field.line
+		if field.valueColumn == 0 {
+			field.valueColumn = field.column
+		}
+
+		switch v := value.(type) {
+		case []interface{}:
+			field.kind = "sequence"
+			for _, item := range v {
+				field.items = append(field.items, frontmatterItem{value: fmt.Sprint(item), line: field.valueLine, column: field.valueColumn})
+			}
+		case map[string]interface{}:
+			field.kind = "mapping"
+		default:
+			field.kind = "scalar"
+			field.value = fmt.Sprint(v)
+		}
+
+		fields = append(fields, field)
+	}
+
+	sort.Slice(fields, func(i, j int) bool { return fields[i].line < fields[j].line })
+
+	return fields, true
+}
+
+// checkFields validates the frontmatter fields and returns the task ID
+func (l *linter) checkFields(fields []*frontmatterField, blockLine int) string {
+	present := make(map[string]*frontmatterField)
+	var startDate, dueDate *time.Time
+
+	for _, field := range fields {
+		present[field.key] = field
+
+		if !knownFields[field.key] {
+			l.add(SeverityWarning, field.line, field.column, RuleUnknownKey, "unknown field %q (custom fields belong in extra_meta)", field.key)
+			continue
+		}
+
+		if field.kind == "null" {
+			continue
+		}
+
+		switch field.key {
+		case "id", "title", "parent_id", "status", "priority", "start_date", "due_date", "recurrence":
+			if field.kind != "scalar" {
+				l.add(SeverityError, field.valueLine, field.valueColumn, RuleInvalidType, "%s must be a single value", field.key)
+				continue
+			}
+		case "assignees", "labels":
+			if field.kind != "sequence" {
+				l.add(SeverityError, field.valueLine, field.valueColumn, RuleInvalidType, "%s must be a list (e.g. [a, b])", field.key)
+				continue
+			}
+		case "extra_meta":
+			if field.kind != "mapping" {
+				l.add(SeverityError, field.valueLine, field.valueColumn, RuleInvalidType, "extra_meta must be a mapping")
+			}
+			continue
+		}
+
+		switch field.key {
+		case "status":
+			if field.value != "" && !containsValue(statusValues, field.value) {
+				l.add(SeverityError, field.valueLine, field.valueColumn, RuleInvalidEnum, "invalid status %q (must be one of: %s)", field.value, strings.Join(statusValues, ", "))
+			}
+
+		case "priority":
+			if field.value != "" && !containsValue(priorityValues, field.value) {
+				l.add(SeverityError, field.valueLine, field.valueColumn, RuleInvalidEnum, "invalid priority %q (must be one of: %s)", field.value, strings.Join(priorityValues, ", "))
+			}
+
+		case "start_date", "due_date":
+			date, err := parseDate(field.value)
+			if err != nil {
+				l.add(SeverityError, field.valueLine, field.valueColumn, RuleInvalidDate, "invalid %s %q (expected YYYY-MM-DD)", field.key, field.value)
+				continue
+			}
+			if field.key == "start_date" {
+				startDate = &date
+			} else {
+				dueDate = &date
+			}
+
+		case "recurrence":
+			if _, err := recurrence.Parse(field.value); err != nil {
+				l.add(SeverityError, field.valueLine, field.valueColumn, RuleInvalidRecurrence, "invalid recurrence: %v", err)
+			}
+
+		case "assignees":
+			if l.opts.Members == nil {
+				continue
+			}
+			for _, item := range field.items {
+				if !l.opts.Members[item.value] {
+					l.add(SeverityWarning, item.line, item.column, RuleUnknownAssignee, "%q is not a member of this project", item.value)
+				}
+			}
+
+		case "parent_id":
+			if l.opts.TaskExists != nil && field.value != "" && !l.opts.TaskExists(field.value) {
+				l.add(SeverityError, field.valueLine, field.valueColumn, RuleBrokenReference, "parent task %s does not exist", field.value)
+			}
+		}
+	}
+
+	for _, key := range []string{"id", "status", "priority"} {
+		if field, ok := present[key]; !ok || field.kind == "null" || field.value == "" {
+			line, column := blockLine, 1
+			if ok {
+				line, column = field.line, field.column
+			}
+			l.add(SeverityError, line, column, RuleRequiredField, "%s is required", key)
+		}
+	}
+
+	if startDate != nil && dueDate != nil && dueDate.Before(*startDate) {
+		due := present["due_date"]
+		l.add(SeverityWarning, due.valueLine, due.valueColumn, RuleDateOrder, "due_date %s is before start_date %s", due.value, present["start_date"].value)
+	}
+
+	if id, ok := present["id"]; ok {
+		return id.value
+	}
+	return ""
+}
+
+// checkChecklist reports checklist items that appear more than once
+func (l *linter) checkChecklist(bodyStart int) {
+	firstSeen := make(map[string]int)
+	inFence := false
+	offset := 0
+
+	for i, line := range strings.SplitAfter(l.markdown, "\n") {
+		start := offset
+		offset += len(line)
+		if start < bodyStart {
+			continue
+		}
+
+		text := strings.TrimRight(line, "\r\n")
+		trimmed := strings.TrimSpace(text)
+		if strings.HasPrefix(trimmed, "```") || strings.HasPrefix(trimmed, "~~~") {
+			inFence = !inFence
+			continue
+		}
+		if inFence {
+			continue
+		}
+
+		match := checklistPattern.FindStringSubmatch(text)
+		if match == nil {
+			continue
+		}
+
+		item := strings.ToLower(strings.Join(strings.Fields(match[2]), " "))
+		if first, ok := firstSeen[item]; ok {
+			l.add(SeverityWarning, i+1, utf8.RuneCountInString(match[1])+1, RuleDuplicateChecklist, "duplicate checklist item %q (first at line %d)", match[2], first)
+			continue
+		}
+		firstSeen[item] = i + 1
+	}
+}
+
+// checkReferences reports task references in the body that point to missing tasks
+func (l *linter) checkReferences(ownID string) {
+	if l.opts.TaskExists == nil {
+		return
+	}
+
+	exists := make(map[string]bool)
+
+	for _, ref := range ExtractTaskReferences(l.markdown) {
+		if ref.TaskID == ownID {
+			continue
+		}
+
+		if l.opts.TaskPrefixes != nil && !l.opts.TaskPrefixes[idPrefixOf(ref.TaskID)] {
+			continue
+		}
+
+		found, ok := exists[ref.TaskID]
+		if !ok {
+			found = l.opts.TaskExists(ref.TaskID)
+			exists[ref.TaskID] = found
+		}
+
+		if !found {
+			l.add(SeverityWarning, ref.Line, ref.Column, RuleBrokenReference, "referenced task %s does not exist", ref.TaskID)
+		}
+	}
+}
+
+// lineAt returns the 1-based line number of a byte offset
+func lineAt(markdown string, offset int) int {
+	return strings.Count(markdown[:offset], "\n") + 1
+}
+
+// idPrefixOf returns the prefix of a task ID ("T" for "T-12")
+func idPrefixOf(taskID string) string {
+	if idx := strings.LastIndex(taskID, "-"); idx > 0 {
+		return taskID[:idx]
+	}
+	return taskID
+}
+
+func containsValue(values []string, value string) bool {
+	for _, v := range values {
+		if v == value {
+			return true
+		}
+	}
+	return false
+}
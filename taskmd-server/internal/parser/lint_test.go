@@ -0,0 +1,125 @@
+package parser
+
+import (
+	"testing"
+
+	"github.com/tktomaru/taskai/taskai-server/internal/models"
+)
+
+func TestLint(t *testing.T) {
+	opts := &LintOptions{
+		Members:      map[string]bool{"taku": true, "hana": true},
+		TaskExists:   func(id string) bool { return id == "T-1" || id == "T-2" },
+		TaskPrefixes: map[string]bool{"T": true},
+	}
+
+	tests := []struct {
+		name     string
+		markdown string
+		want     []Diagnostic
+	}{
+		{
+			name: "valid task",
+			markdown: "## T-1: Valid\n\n```yaml\nid: T-1\nstatus: open\npriority: P1\nassignees: [taku]\nparent_id: T-2\n```\n\n" +
+				"Depends on #T-2, uses UTF-8 and `T-404`.\n\n- [ ] First\n- [ ] Second\n",
+		},
+		{
+			name: "invalid values",
+			markdown: "## T-1: Broken\n\n```yaml\nid: T-1\nstatus: someday\npriority: P9\nassignees: [taku, ghost]\n" +
+				"start_date: 2026-01-10\ndue_date: 2026-01-05\nowner: taku\n```\n",
+			want: []Diagnostic{
+				{SeverityError, 5, 9, RuleInvalidEnum, `invalid status "someday" (must be one of: open, in_progress, review, blocked, done, archived)`},
+				{SeverityError, 6, 11, RuleInvalidEnum, `invalid priority "P9" (must be one of: P0, P1, P2, P3, P4)`},
+				{SeverityWarning, 7, 19, RuleUnknownAssignee, `"ghost" is not a member of this project`},
+				{SeverityWarning, 9, 11, RuleDateOrder, "due_date 2026-01-05 is before start_date 2026-01-10"},
+				{SeverityWarning, 10, 1, RuleUnknownKey, `unknown field "owner" (custom fields belong in extra_meta)`},
+			},
+		},
+		{
+			name:     "bad date and missing priority",
+			markdown: "## T-1: Dates\n\n```yaml\nid: T-1\nstatus: open\ndue_date: 2026-13-01\n```\n",
+			want: []Diagnostic{
+				{SeverityError, 3, 1, RuleRequiredField, "priority is required"},
+				{SeverityError, 6, 11, RuleInvalidDate, `invalid due_date "2026-13-01" (expected YYYY-MM-DD)`},
+			},
+		},
+		{
+			name:     "yaml syntax error",
+			markdown: "## T-1: Syntax\n\n```yaml\nid: T-1\nstatus: [open\npriority: P1\n```\n",
+			want: []Diagnostic{
+				{SeverityError, 5, 1, RuleFrontmatterSyntax, "invalid YAML: did not find expected ',' or ']'"},
+			},
+		},
+		{
+			name: "duplicate checklist items and broken references",
+			markdown: "## T-1: Body\n\n```yaml\nid: T-1\nstatus: open\npriority: P1\nparent_id: T-9\n```\n\n" +
+				"See T-1 and T-404.\n\n- [ ] Write tests\n- [x]  write   tests\n",
+			want: []Diagnostic{
+				{SeverityError, 7, 12, RuleBrokenReference, "parent task T-9 does not exist"},
+				{SeverityWarning, 10, 13, RuleBrokenReference, "referenced task T-404 does not exist"},
+				{SeverityWarning, 13, 8, RuleDuplicateChecklist, `duplicate checklist item "write   tests" (first at line 12)`},
+			},
+		},
+		{
+			name:     "toml frontmatter",
+			markdown: "+++\nid = \"T-1\"\nstatus = \"later\"\npriority = \"P1\"\n+++\n\n# Hugo\n",
+			want: []Diagnostic{
+				{SeverityError, 3, 10, RuleInvalidEnum, `invalid status "later" (must be one of: open, in_progress, review, blocked, done, archived)`},
+			},
+		},
+		{
+			name:     "missing frontmatter",
+			markdown: "# Just a note\n",
+			want: []Diagnostic{
+				{SeverityError, 1, 1, RuleFrontmatterMissing, "frontmatter not found (expected ```yaml ... ```, --- YAML --- or +++ TOML +++)"},
+			},
+		},
+		{
+			name:     "missing title",
+			markdown: "---\nid: T-1\nstatus: open\npriority: P1\n---\n\nNo heading\n",
+			want: []Diagnostic{
+				{SeverityError, 1, 1, RuleTitleMissing, "task title not found (expected a title in frontmatter or a heading such as ## ID: Title)"},
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := Lint(tt.markdown, opts)
+			if len(got) != len(tt.want) {
+				t.Fatalf("Lint() = %+v, want %+v", got, tt.want)
+			}
+			for i := range got {
+				if got[i] != tt.want[i] {
+					t.Errorf("Lint()[%d] = %+v, want %+v", i, got[i], tt.want[i])
+				}
+			}
+		})
+	}
+}
+
+func TestExtractTaskReferences(t *testing.T) {
+	markdown := "---\nparent: T-100\n---\n\n# T-1: Self\n\nBlocked by #T-2 and T-3, not UTF8-x or `T-4`.\n\n```\nT-5\n```\n関連: T-6\n" +
+		"This duplicates T-7. See T-8; it depends on T-9.\nBlocked by: T-10\n"
+
+	got := ExtractTaskReferences(markdown)
+	want := []TaskReference{
+		{TaskID: "T-1", RelationType: models.RelationTypeRelated, Line: 5, Column: 3},
+		{TaskID: "T-2", RelationType: models.RelationTypeBlockedBy, Line: 7, Column: 13},
+		{TaskID: "T-3", RelationType: models.RelationTypeBlockedBy, Line: 7, Column: 21},
+		{TaskID: "T-6", RelationType: models.RelationTypeRelated, Line: 12, Column: 5},
+		{TaskID: "T-7", RelationType: models.RelationTypeDuplicates, Line: 13, Column: 17},
+		{TaskID: "T-8", RelationType: models.RelationTypeRelated, Line: 13, Column: 26},
+		{TaskID: "T-9", RelationType: models.RelationTypeBlockedBy, Line: 13, Column: 45},
+		{TaskID: "T-10", RelationType: models.RelationTypeBlockedBy, Line: 14, Column: 13},
+	}
+
+	if len(got) != len(want) {
+		t.Fatalf("ExtractTaskReferences() = %+v, want %+v", got, want)
+	}
+	for i := range got {
+		if got[i] != want[i] {
+			t.Errorf("ExtractTaskReferences()[%d] = %+v, want %+v", i, got[i], want[i])
+		}
+	}
+}
@@ -6,22 +6,26 @@ import (
 	"strings"
 	"time"
 
+	"github.com/pelletier/go-toml/v2"
 	"gopkg.in/yaml.v3"
 
 	"github.com/tktomaru/taskai/taskai-server/internal/models"
+	"github.com/tktomaru/taskai/taskai-server/internal/recurrence"
 )
 
-// TaskMetadata represents the YAML frontmatter metadata
+// TaskMetadata represents the frontmatter metadata
 type TaskMetadata struct {
-	ID        string                 `yaml:"id"`
-	ParentID  *string                `yaml:"parent_id"`
-	Status    string                 `yaml:"status"`
-	Priority  string                 `yaml:"priority"`
-	Assignees []string               `yaml:"assignees"`
-	Labels    []string               `yaml:"labels"`
-	StartDate *string                `yaml:"start_date"`
-	DueDate   *string                `yaml:"due_date"`
-	ExtraMeta map[string]interface{} `yaml:"extra_meta"`
+	ID         string                 `yaml:"id"`
+	Title      string                 `yaml:"title"`
+	ParentID   *string                `yaml:"parent_id"`
+	Status     string                 `yaml:"status"`
+	Priority   string                 `yaml:"priority"`
+	Assignees  []string               `yaml:"assignees"`
+	Labels     []string               `yaml:"labels"`
+	StartDate  *string                `yaml:"start_date"`
+	DueDate    *string                `yaml:"due_date"`
+	Recurrence string                 `yaml:"recurrence"`
+	ExtraMeta  map[string]interface{} `yaml:"extra_meta"`
 }
 
 // ParsedTask represents a parsed task with metadata and body
@@ -29,6 +33,7 @@ type ParsedTask struct {
 	Metadata     TaskMetadata
 	MarkdownBody string
 	Title        string
+	Format       MarkdownFormat
 }
 
 // MarkdownParser handles parsing of task markdown
@@ -39,60 +44,80 @@ func NewMarkdownParser() *MarkdownParser {
 	return &MarkdownParser{}
 }
 
-// Parse parses a markdown document with YAML frontmatter
+// Parse parses a markdown document with ```yaml, --- YAML or +++ TOML frontmatter
 func (p *MarkdownParser) Parse(markdown string) (*ParsedTask, error) {
 	// Extract title, metadata, and body
-	title, yamlContent, _, err := p.extractParts(markdown)
+	parts, err := p.extractParts(markdown)
 	if err != nil {
 		return nil, fmt.Errorf("failed to extract parts: %w", err)
 	}
 
-	// Parse YAML metadata
-	var metadata TaskMetadata
-	if err := yaml.Unmarshal([]byte(yamlContent), &metadata); err != nil {
-		return nil, fmt.Errorf("failed to parse YAML metadata: %w", err)
+	if parts.title == "" {
+		return nil, fmt.Errorf("task title not found (expected a title in frontmatter or a heading such as ## ID: Title)")
 	}
 
 	// Validate required fields
-	if err := p.validateMetadata(&metadata); err != nil {
+	if err := p.validateMetadata(&parts.metadata); err != nil {
 		return nil, fmt.Errorf("validation failed: %w", err)
 	}
 
 	return &ParsedTask{
-		Metadata:     metadata,
+		Metadata:     parts.metadata,
 		MarkdownBody: markdown, // Store original markdown
-		Title:        title,
+		Title:        parts.title,
+		Format:       parts.format,
 	}, nil
 }
 
-// extractParts extracts title, YAML frontmatter, and body from markdown
-func (p *MarkdownParser) extractParts(markdown string) (title, yamlContent, body string, err error) {
-	// Pattern: ## ID: Title
-	titlePattern := regexp.MustCompile(`(?m)^##\s+([A-Z]+-\d+):\s+(.+)$`)
-	titleMatch := titlePattern.FindStringSubmatch(markdown)
+// documentParts holds the parts of a task document
+type documentParts struct {
+	title    string
+	metadata TaskMetadata
+	body     string
+	format   MarkdownFormat
+}
 
-	if titleMatch == nil {
-		return "", "", "", fmt.Errorf("task title not found (expected format: ## ID: Title)")
+// extractParts extracts title, frontmatter metadata, and body from markdown.
+// The title comes from the frontmatter "title" field, or else from a heading of any level.
+func (p *MarkdownParser) extractParts(markdown string) (*documentParts, error) {
+	block, err := locateFrontmatter(markdown)
+	if err != nil {
+		return nil, err
 	}
 
-	title = strings.TrimSpace(titleMatch[2])
+	parts := &documentParts{format: MarkdownFormat{Dialect: block.dialect}}
+	if err := decodeFrontmatter(block.dialect, markdown[block.contentStart:block.contentEnd], &parts.metadata); err != nil {
+		return nil, err
+	}
 
-	// Extract YAML frontmatter from code block
-	// Pattern: ```yaml ... ```
-	yamlPattern := regexp.MustCompile("(?s)```yaml\n(.*?)\n```")
-	yamlMatch := yamlPattern.FindStringSubmatch(markdown)
+	// Body is everything after the frontmatter
+	body := markdown[block.end:]
 
-	if yamlMatch == nil {
-		return "", "", "", fmt.Errorf("YAML frontmatter not found (expected ```yaml ... ```)")
-	}
+	if title := strings.TrimSpace(parts.metadata.Title); title != "" {
+		parts.title = title
+	} else {
+		// --- and +++ frontmatter may contain # comments, so only look for headings after it
+		scanStart := 0
+		if block.dialect != DialectFenced {
+			scanStart = block.end
+		}
 
-	yamlContent = yamlMatch[1]
+		if heading := findTitleHeading(markdown[scanStart:]); heading != nil {
+			parts.title = heading.title
+			parts.format.HeadingLevel = heading.level
+			parts.format.HeadingID = heading.id != ""
 
-	// Body is everything after the YAML block
-	yamlEndIdx := strings.Index(markdown, yamlMatch[0]) + len(yamlMatch[0])
-	body = strings.TrimSpace(markdown[yamlEndIdx:])
+			// Drop the title heading from the body when it follows the frontmatter
+			headingStart, headingEnd := scanStart+heading.start, scanStart+heading.end
+			if headingStart >= block.end {
+				body = markdown[block.end:headingStart] + markdown[headingEnd:]
+			}
+		}
+	}
+
+	parts.body = strings.TrimSpace(body)
 
-	return title, yamlContent, body, nil
+	return parts, nil
 }
 
 // validateMetadata validates required fields
@@ -126,6 +151,13 @@ func (p *MarkdownParser) validateMetadata(meta *TaskMetadata) error {
 		return fmt.Errorf("invalid priority: %s (must be one of: P0, P1, P2, P3, P4)", meta.Priority)
 	}
 
+	// Validate recurrence rule
+	if meta.Recurrence != "" {
+		if _, err := recurrence.Parse(meta.Recurrence); err != nil {
+			return fmt.Errorf("invalid recurrence: %w", err)
+		}
+	}
+
 	return nil
 }
 
@@ -191,16 +223,67 @@ func ExtractAcceptanceCriteria(markdown string) []string {
 	return criteria
 }
 
-// GenerateMarkdown generates markdown from task data (for editing)
+// GenerateMarkdown generates markdown from task data (for editing).
+// The task is written back in the format its current markdown uses (see DetectFormat).
 func GenerateMarkdown(task *models.Task) string {
 	var sb strings.Builder
 
-	// Title
-	sb.WriteString(fmt.Sprintf("## %s: %s\n\n", task.ID, task.Title))
+	format := DetectFormat(task.MarkdownBody)
+
+	// Body (extract from original markdown_body, skipping title and frontmatter)
+	body := ExtractBody(task.MarkdownBody)
+
+	// The recurrence rule is not stored on the task, so carry it over from the original frontmatter
+	rule := ""
+	if parts, err := NewMarkdownParser().extractParts(task.MarkdownBody); err == nil {
+		rule = parts.metadata.Recurrence
+	}
+
+	switch format.Dialect {
+	case DialectYAML:
+		sb.WriteString("---\n")
+		writeYAMLMetadata(&sb, task, rule, format.HeadingLevel == 0)
+		sb.WriteString("---\n\n")
+		writeTitleHeading(&sb, task, format)
+
+	case DialectTOML:
+		sb.WriteString("+++\n")
+		writeTOMLMetadata(&sb, task, rule, format.HeadingLevel == 0)
+		sb.WriteString("+++\n\n")
+		writeTitleHeading(&sb, task, format)
+
+	default:
+		writeTitleHeading(&sb, task, format)
+		sb.WriteString("```yaml\n")
+		writeYAMLMetadata(&sb, task, rule, format.HeadingLevel == 0)
+		sb.WriteString("```\n\n")
+	}
+
+	sb.WriteString(body)
+
+	return sb.String()
+}
+
+// writeTitleHeading writes the title heading, unless the title lives in the frontmatter
+func writeTitleHeading(sb *strings.Builder, task *models.Task, format MarkdownFormat) {
+	if format.HeadingLevel == 0 {
+		return
+	}
+
+	sb.WriteString(strings.Repeat("#", format.HeadingLevel))
+	if format.HeadingID {
+		sb.WriteString(fmt.Sprintf(" %s: %s\n\n", task.ID, task.Title))
+	} else {
+		sb.WriteString(fmt.Sprintf(" %s\n\n", task.Title))
+	}
+}
 
-	// YAML frontmatter
-	sb.WriteString("```yaml\n")
+// writeYAMLMetadata writes task metadata as YAML
+func writeYAMLMetadata(sb *strings.Builder, task *models.Task, rule string, includeTitle bool) {
 	sb.WriteString(fmt.Sprintf("id: %s\n", task.ID))
+	if includeTitle {
+		sb.WriteString(fmt.Sprintf("title: %s\n", yamlString(task.Title)))
+	}
 	sb.WriteString(fmt.Sprintf("status: %s\n", task.Status))
 	sb.WriteString(fmt.Sprintf("priority: %s\n", task.Priority))
 
@@ -227,6 +310,10 @@ func GenerateMarkdown(task *models.Task) string {
 		sb.WriteString(fmt.Sprintf("due_date: %s\n", task.DueDate.Format("2006-01-02")))
 	}
 
+	if rule != "" {
+		sb.WriteString(fmt.Sprintf("recurrence: %s\n", yamlString(rule)))
+	}
+
 	if len(task.Labels) > 0 {
 		sb.WriteString("labels: [")
 		for i, label := range task.Labels {
@@ -252,26 +339,57 @@ func GenerateMarkdown(task *models.Task) string {
 		}
 		sb.WriteString("}\n")
 	}
+}
 
-	sb.WriteString("```\n\n")
-
-	// Body (extract from original markdown_body, skipping title and YAML)
-	body := extractBody(task.MarkdownBody)
-	sb.WriteString(body)
+// writeTOMLMetadata writes task metadata as TOML
+func writeTOMLMetadata(sb *strings.Builder, task *models.Task, rule string, includeTitle bool) {
+	meta := tomlFrontmatter{
+		ID:         task.ID,
+		Status:     string(task.Status),
+		Priority:   string(task.Priority),
+		Assignees:  task.Assignees,
+		Labels:     task.Labels,
+		Recurrence: rule,
+	}
+	if includeTitle {
+		meta.Title = task.Title
+	}
+	if task.ParentID != nil {
+		meta.ParentID = *task.ParentID
+	}
+	if task.StartDate != nil {
+		meta.StartDate = tomlDate(*task.StartDate)
+	}
+	if task.DueDate != nil {
+		meta.DueDate = tomlDate(*task.DueDate)
+	}
+	if len(task.ExtraMeta) > 0 {
+		meta.ExtraMeta = task.ExtraMeta
+	}
 
-	return sb.String()
+	encoded, err := toml.Marshal(meta)
+	if err != nil {
+		// Fall back to the required fields only
+		encoded = []byte(fmt.Sprintf("id = %q\nstatus = %q\npriority = %q\n", task.ID, task.Status, task.Priority))
+	}
+	sb.Write(encoded)
 }
 
-// extractBody extracts the body part from markdown (after YAML block)
-func extractBody(markdown string) string {
-	// Find the end of YAML block
-	yamlPattern := regexp.MustCompile("(?s)```yaml\n.*?\n```")
-	match := yamlPattern.FindStringIndex(markdown)
+// yamlString formats a string as a YAML scalar, quoting it when needed
+func yamlString(value string) string {
+	encoded, err := yaml.Marshal(value)
+	if err != nil {
+		return fmt.Sprintf("%q", value)
+	}
+	return strings.TrimSuffix(string(encoded), "\n")
+}
 
-	if match == nil {
+// ExtractBody extracts the body part from markdown (after the frontmatter, without the title heading)
+func ExtractBody(markdown string) string {
+	parts, err := NewMarkdownParser().extractParts(markdown)
+	if err != nil {
 		return markdown
 	}
 
-	// Return everything after the YAML block
-	return strings.TrimSpace(markdown[match[1]:])
+	return parts.body
 }
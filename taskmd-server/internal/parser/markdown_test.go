@@ -93,6 +93,21 @@ status: open
 priority: P99
 ` + "```" + `
 
+Body
+`,
+			wantErr: true,
+		},
+		{
+			name: "invalid recurrence",
+			markdown: `## T-1042: Test Task
+
+` + "```yaml" + `
+id: T-1042
+status: open
+priority: P1
+recurrence: FREQ=HOURLY
+` + "```" + `
+
 Body
 `,
 			wantErr: true,
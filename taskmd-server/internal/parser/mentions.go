@@ -0,0 +1,53 @@
+package parser
+
+import (
+	"regexp"
+	"strings"
+	"unicode/utf8"
+)
+
+// mentionPattern matches @handles that are not part of an email address or a longer word
+var mentionPattern = regexp.MustCompile(`(?:^|[^A-Za-z0-9_.@])@([A-Za-z0-9](?:[A-Za-z0-9._\-]*[A-Za-z0-9_])?)`)
+
+// Mention is an @handle in a Markdown document
+type Mention struct {
+	Handle string `json:"handle"`
+	// Line and Column are 1-based positions of the @; columns count characters
+	Line   int `json:"line"`
+	Column int `json:"column"`
+}
+
+// ExtractMentions finds the @handles in a document.
+// Frontmatter, fenced code blocks and inline code are skipped, as are email addresses.
+func ExtractMentions(markdown string) []Mention {
+	var mentions []Mention
+
+	scanProse(markdown, func(lineNo int, text string) {
+		for _, match := range mentionPattern.FindAllStringSubmatchIndex(text, -1) {
+			mentions = append(mentions, Mention{
+				Handle: text[match[2]:match[3]],
+				Line:   lineNo,
+				Column: utf8.RuneCountInString(text[:match[2]]),
+			})
+		}
+	})
+
+	return mentions
+}
+
+// MentionHandles returns the distinct handles mentioned in a document, lowercased, in document order
+func MentionHandles(markdown string) []string {
+	seen := make(map[string]bool)
+	var handles []string
+
+	for _, mention := range ExtractMentions(markdown) {
+		handle := strings.ToLower(mention.Handle)
+		if seen[handle] {
+			continue
+		}
+		seen[handle] = true
+		handles = append(handles, handle)
+	}
+
+	return handles
+}
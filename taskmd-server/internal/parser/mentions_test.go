@@ -0,0 +1,28 @@
+package parser
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestExtractMentions(t *testing.T) {
+	markdown := "---\nowner: \"@nobody\"\n---\n\n# T-1: Review\n\n@alice please check with @Bob.\n" +
+		"Mail taku@example.com or `@code`.\n\n```\n@fenced\n```\ncc: @hana-s, @alice\n"
+
+	got := ExtractMentions(markdown)
+	want := []Mention{
+		{Handle: "alice", Line: 7, Column: 1},
+		{Handle: "Bob", Line: 7, Column: 26},
+		{Handle: "hana-s", Line: 13, Column: 5},
+		{Handle: "alice", Line: 13, Column: 14},
+	}
+
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("ExtractMentions() = %+v, want %+v", got, want)
+	}
+
+	handles := MentionHandles(markdown)
+	if wantHandles := []string{"alice", "bob", "hana-s"}; !reflect.DeepEqual(handles, wantHandles) {
+		t.Errorf("MentionHandles() = %v, want %v", handles, wantHandles)
+	}
+}
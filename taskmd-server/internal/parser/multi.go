@@ -0,0 +1,104 @@
+package parser
+
+import (
+	"fmt"
+	"strings"
+)
+
+// TaskSection is one task section of a multi-task document
+type TaskSection struct {
+	// StartLine and EndLine are the 1-based line range of the section (inclusive)
+	StartLine int
+	EndLine   int
+	// TaskID is the ID from the section heading
+	TaskID   string
+	Markdown string
+	Task     *ParsedTask
+	Err      error
+}
+
+// ParseMany splits a document into its "## ID: Title" task sections and parses each one.
+// A section runs until the next task heading of the same or a higher level, or until a
+// heading of a higher level. Text before the first task heading is ignored.
+// Errors are reported per section, so one broken section does not hide the others.
+func (p *MarkdownParser) ParseMany(markdown string) []*TaskSection {
+	lines := strings.SplitAfter(markdown, "\n")
+
+	var sections []*TaskSection
+	var current *TaskSection
+	var currentLines []string
+	sectionLevel := 0
+	inFence := false
+
+	closeSection := func() {
+		if current == nil {
+			return
+		}
+
+		// Trailing blank lines belong to the gap between sections
+		for len(currentLines) > 1 && strings.TrimSpace(currentLines[len(currentLines)-1]) == "" {
+			currentLines = currentLines[:len(currentLines)-1]
+		}
+		current.EndLine = current.StartLine + len(currentLines) - 1
+		current.Markdown = strings.TrimRight(strings.Join(currentLines, ""), "\r\n") + "\n"
+
+		sections = append(sections, current)
+		current = nil
+		currentLines = nil
+	}
+
+	for i, line := range lines {
+		text := strings.TrimRight(line, "\r\n")
+
+		trimmed := strings.TrimSpace(text)
+		if strings.HasPrefix(trimmed, "```") || strings.HasPrefix(trimmed, "~~~") {
+			inFence = !inFence
+		} else if !inFence {
+			if match := headingPattern.FindStringSubmatch(text); match != nil {
+				level := len(match[1])
+				idMatch := headingIDPattern.FindStringSubmatch(match[2])
+
+				switch {
+				case idMatch != nil && (sectionLevel == 0 || level <= sectionLevel):
+					closeSection()
+					if sectionLevel == 0 {
+						sectionLevel = level
+					}
+					current = &TaskSection{StartLine: i + 1, TaskID: idMatch[1]}
+				case sectionLevel != 0 && level < sectionLevel:
+					// A higher-level heading ends the task list it belongs to
+					closeSection()
+				}
+			}
+		}
+
+		if current != nil {
+			currentLines = append(currentLines, line)
+		}
+	}
+	closeSection()
+
+	seen := make(map[string]int)
+	for _, section := range sections {
+		section.Task, section.Err = p.Parse(section.Markdown)
+		if section.Err != nil {
+			continue
+		}
+
+		id := section.Task.Metadata.ID
+		if id != section.TaskID {
+			section.Task = nil
+			section.Err = fmt.Errorf("heading ID %s does not match metadata id %s", section.TaskID, id)
+			continue
+		}
+
+		if line, ok := seen[id]; ok {
+			section.Task = nil
+			section.Err = fmt.Errorf("duplicate task ID %s (first defined at line %d)", id, line)
+			continue
+		}
+		seen[id] = section.StartLine
+	}
+
+	return sections
+}
@@ -0,0 +1,103 @@
+package parser
+
+import (
+	"strings"
+	"testing"
+)
+
+const planningDocument = "# Sprint 12 planning\n" +
+	"\n" +
+	"Notes from the planning meeting.\n" +
+	"\n" +
+	"## T-101: Login page\n" +
+	"\n" +
+	"```yaml\n" +
+	"id: T-101\n" +
+	"status: open\n" +
+	"priority: P1\n" +
+	"```\n" +
+	"\n" +
+	"### Background\n" +
+	"\n" +
+	"```markdown\n" +
+	"## T-999: Not a section heading\n" +
+	"```\n" +
+	"\n" +
+	"## T-102: Broken task\n" +
+	"\n" +
+	"```yaml\n" +
+	"id: T-102\n" +
+	"status: someday\n" +
+	"priority: P2\n" +
+	"```\n" +
+	"\n" +
+	"## T-103: Logout\n" +
+	"\n" +
+	"```yaml\n" +
+	"id: T-103\n" +
+	"status: open\n" +
+	"priority: P3\n" +
+	"```\n" +
+	"\n" +
+	"## T-101: Login page again\n" +
+	"\n" +
+	"```yaml\n" +
+	"id: T-101\n" +
+	"status: done\n" +
+	"priority: P1\n" +
+	"```\n" +
+	"\n" +
+	"# Action items\n" +
+	"\n" +
+	"- Book the room\n"
+
+func TestMarkdownParser_ParseMany(t *testing.T) {
+	sections := NewMarkdownParser().ParseMany(planningDocument)
+
+	want := []struct {
+		taskID    string
+		startLine int
+		endLine   int
+		wantErr   string
+	}{
+		{taskID: "T-101", startLine: 5, endLine: 17},
+		{taskID: "T-102", startLine: 19, endLine: 25, wantErr: "invalid status"},
+		{taskID: "T-103", startLine: 27, endLine: 33},
+		{taskID: "T-101", startLine: 35, endLine: 41, wantErr: "duplicate task ID T-101 (first defined at line 5)"},
+	}
+
+	if len(sections) != len(want) {
+		t.Fatalf("ParseMany() returned %d sections, want %d", len(sections), len(want))
+	}
+
+	for i, w := range want {
+		section := sections[i]
+		if section.TaskID != w.taskID || section.StartLine != w.startLine || section.EndLine != w.endLine {
+			t.Errorf("section %d = %s lines %d-%d, want %s lines %d-%d",
+				i, section.TaskID, section.StartLine, section.EndLine, w.taskID, w.startLine, w.endLine)
+		}
+
+		if w.wantErr == "" {
+			if section.Err != nil || section.Task == nil {
+				t.Errorf("section %d error = %v, want parsed task", i, section.Err)
+			}
+			continue
+		}
+		if section.Err == nil || !strings.Contains(section.Err.Error(), w.wantErr) {
+			t.Errorf("section %d error = %v, want %q", i, section.Err, w.wantErr)
+		}
+	}
+
+	if !strings.Contains(sections[0].Markdown, "## T-999: Not a section heading") {
+		t.Errorf("headings inside code blocks must stay in their section:\n%s", sections[0].Markdown)
+	}
+	if strings.Contains(sections[3].Markdown, "Action items") {
+		t.Errorf("a higher-level heading must end the last section:\n%s", sections[3].Markdown)
+	}
+}
+
+func TestMarkdownParser_ParseMany_NoSections(t *testing.T) {
+	if sections := NewMarkdownParser().ParseMany("# Notes\n\nNothing to import.\n"); len(sections) != 0 {
+		t.Errorf("ParseMany() returned %d sections, want 0", len(sections))
+	}
+}
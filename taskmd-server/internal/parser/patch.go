@@ -0,0 +1,266 @@
+package parser
+
+import (
+	"bytes"
+	"fmt"
+	"regexp"
+	"sort"
+	"strings"
+
+	"github.com/pelletier/go-toml/v2"
+	"gopkg.in/yaml.v3"
+)
+
+// patchableFields lists the frontmatter fields that can be changed with PatchFrontmatter.
+// The task ID is intentionally missing: it identifies the task and cannot be patched.
+var patchableFields = map[string]bool{
+	"status":     true,
+	"priority":   true,
+	"parent_id":  true,
+	"assignees":  true,
+	"labels":     true,
+	"start_date": true,
+	"due_date":   true,
+	"recurrence": true,
+	"extra_meta": true,
+}
+
+// yamlBlockPattern matches the fenced YAML block and captures its content
+var yamlBlockPattern = regexp.MustCompile("(?s)```yaml\n(.*?)\n```")
+
+// datePattern matches YYYY-MM-DD values, which are written unquoted like GenerateMarkdown does
+var datePattern = regexp.MustCompile(`^\d{4}-\d{2}-\d{2}$`)
+
+// PatchFrontmatter applies a JSON merge patch (RFC 7396) to the frontmatter of a task.
+// Only the frontmatter is rewritten; everything before and after it is kept byte-for-byte.
+// A nil value removes the field; nested objects (extra_meta) are merged recursively.
+func PatchFrontmatter(markdown string, patch map[string]interface{}) (string, error) {
+	if err := validatePatch(patch); err != nil {
+		return "", err
+	}
+
+	block, err := locateFrontmatter(markdown)
+	if err != nil {
+		return "", err
+	}
+	contentStart, contentEnd := block.contentStart, block.contentEnd
+
+	if block.dialect == DialectTOML {
+		content, err := patchTOML(markdown[contentStart:contentEnd], patch)
+		if err != nil {
+			return "", err
+		}
+		return markdown[:contentStart] + content + markdown[contentEnd:], nil
+	}
+
+	var doc yaml.Node
+	if err := yaml.Unmarshal([]byte(markdown[contentStart:contentEnd]), &doc); err != nil {
+		return "", fmt.Errorf("failed to parse YAML metadata: %w", err)
+	}
+
+	var mapping *yaml.Node
+	if doc.Kind == yaml.DocumentNode && len(doc.Content) > 0 {
+		mapping = doc.Content[0]
+	}
+	if mapping == nil || mapping.Kind != yaml.MappingNode {
+		return "", fmt.Errorf("YAML frontmatter must be a mapping")
+	}
+
+	if err := mergePatch(mapping, patch); err != nil {
+		return "", err
+	}
+
+	var buf bytes.Buffer
+	encoder := yaml.NewEncoder(&buf)
+	encoder.SetIndent(2)
+	if err := encoder.Encode(&doc); err != nil {
+		return "", fmt.Errorf("failed to encode YAML metadata: %w", err)
+	}
+	if err := encoder.Close(); err != nil {
+		return "", fmt.Errorf("failed to encode YAML metadata: %w", err)
+	}
+
+	content := strings.TrimSuffix(buf.String(), "\n")
+
+	return markdown[:contentStart] + content + markdown[contentEnd:], nil
+}
+
+// patchTOML applies a merge patch to TOML frontmatter.
+// TOML has no comment-preserving encoder, so the frontmatter is re-encoded with sorted keys.
+func patchTOML(content string, patch map[string]interface{}) (string, error) {
+	var raw map[string]interface{}
+	if err := toml.Unmarshal([]byte(content), &raw); err != nil {
+		return "", fmt.Errorf("failed to parse TOML metadata: %w", err)
+	}
+	if raw == nil {
+		raw = make(map[string]interface{})
+	}
+
+	mergeTOML(raw, patch)
+
+	encoded, err := toml.Marshal(raw)
+	if err != nil {
+		return "", fmt.Errorf("failed to encode TOML metadata: %w", err)
+	}
+
+	return strings.TrimSuffix(string(encoded), "\n"), nil
+}
+
+// mergeTOML applies a merge patch to decoded TOML values
+func mergeTOML(target map[string]interface{}, patch map[string]interface{}) {
+	for key, value := range patch {
+		if value == nil {
+			delete(target, key)
+			continue
+		}
+
+		if nested, ok := value.(map[string]interface{}); ok {
+			existing, ok := target[key].(map[string]interface{})
+			if !ok {
+				existing = make(map[string]interface{})
+			}
+			mergeTOML(existing, nested)
+			target[key] = existing
+			continue
+		}
+
+		target[key] = tomlValue(value)
+	}
+}
+
+// tomlValue converts a JSON value into a TOML value, writing YYYY-MM-DD strings as dates
+func tomlValue(value interface{}) interface{} {
+	switch v := value.(type) {
+	case string:
+		if datePattern.MatchString(v) {
+			if date, err := parseDate(v); err == nil {
+				return tomlDate(date)
+			}
+		}
+	case []interface{}:
+		result := make([]interface{}, len(v))
+		for i, item := range v {
+			result[i] = tomlValue(item)
+		}
+		return result
+	}
+	return value
+}
+
+// validatePatch rejects fields that cannot be patched
+func validatePatch(patch map[string]interface{}) error {
+	if len(patch) == 0 {
+		return fmt.Errorf("patch is empty")
+	}
+
+	var invalid []string
+	for key := range patch {
+		if !patchableFields[key] {
+			invalid = append(invalid, key)
+		}
+	}
+
+	if len(invalid) > 0 {
+		sort.Strings(invalid)
+		return fmt.Errorf("fields cannot be patched: %s", strings.Join(invalid, ", "))
+	}
+
+	return nil
+}
+
+// mergePatch applies a merge patch to a YAML mapping node, keeping key order and comments
+func mergePatch(mapping *yaml.Node, patch map[string]interface{}) error {
+	// Apply keys in a stable order so new fields are appended deterministically
+	keys := make([]string, 0, len(patch))
+	for key := range patch {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	for _, key := range keys {
+		value := patch[key]
+		idx := mappingIndex(mapping, key)
+
+		if value == nil {
+			if idx >= 0 {
+				mapping.Content = append(mapping.Content[:idx], mapping.Content[idx+2:]...)
+			}
+			continue
+		}
+
+		// Nested objects are merged into an existing mapping
+		if nested, ok := value.(map[string]interface{}); ok && idx >= 0 && mapping.Content[idx+1].Kind == yaml.MappingNode {
+			if err := mergePatch(mapping.Content[idx+1], nested); err != nil {
+				return err
+			}
+			continue
+		}
+
+		node, err := valueNode(value)
+		if err != nil {
+			return fmt.Errorf("invalid value for %s: %w", key, err)
+		}
+
+		if idx >= 0 {
+			// Keep the style of an existing sequence (e.g. [a, b])
+			old := mapping.Content[idx+1]
+			if old.Kind == node.Kind && node.Kind == yaml.SequenceNode {
+				node.Style = old.Style
+			}
+			node.LineComment = old.LineComment
+			mapping.Content[idx+1] = node
+			continue
+		}
+
+		mapping.Content = append(mapping.Content,
+			&yaml.Node{Kind: yaml.ScalarNode, Value: key},
+			node,
+		)
+	}
+
+	return nil
+}
+
+// mappingIndex returns the index of a key node in a mapping, or -1
+func mappingIndex(mapping *yaml.Node, key string) int {
+	for i := 0; i+1 < len(mapping.Content); i += 2 {
+		if mapping.Content[i].Value == key {
+			return i
+		}
+	}
+	return -1
+}
+
+// valueNode converts a JSON value into a YAML node
+func valueNode(value interface{}) (*yaml.Node, error) {
+	switch v := value.(type) {
+	case string:
+		if datePattern.MatchString(v) {
+			return &yaml.Node{Kind: yaml.ScalarNode, Value: v}, nil
+		}
+
+	case []interface{}:
+		seq := &yaml.Node{Kind: yaml.SequenceNode, Style: yaml.FlowStyle}
+		for _, item := range v {
+			child, err := valueNode(item)
+			if err != nil {
+				return nil, err
+			}
+			seq.Content = append(seq.Content, child)
+		}
+		return seq, nil
+
+	case map[string]interface{}:
+		m := &yaml.Node{Kind: yaml.MappingNode}
+		if err := mergePatch(m, v); err != nil {
+			return nil, err
+		}
+		return m, nil
+	}
+
+	var node yaml.Node
+	if err := node.Encode(value); err != nil {
+		return nil, err
+	}
+	return &node, nil
+}
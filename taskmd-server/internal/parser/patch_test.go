@@ -0,0 +1,127 @@
+package parser
+
+import (
+	"strings"
+	"testing"
+)
+
+const patchTestProse = `
+
+### Background
+
+Keep  this   prose *exactly*   as written.
+` + "```go" + `
+fmt.Println("code")
+` + "```" + `
+`
+
+const patchTestMarkdown = `## T-1: Patch target
+
+` + "```yaml" + `
+id: T-1
+status: open # set by triage
+priority: P2
+assignees: [taku, hana]
+labels: [backend]
+due_date: 2026-01-20
+extra_meta:
+  estimate: 3
+  team: core
+` + "```" + patchTestProse
+
+func TestPatchFrontmatter(t *testing.T) {
+	tests := []struct {
+		name    string
+		patch   map[string]interface{}
+		wantErr bool
+		want    []string
+		notWant []string
+	}{
+		{
+			name:  "replace status keeps comment",
+			patch: map[string]interface{}{"status": "in_progress"},
+			want:  []string{"status: in_progress # set by triage", "priority: P2"},
+		},
+		{
+			name:  "replace sequence keeps flow style",
+			patch: map[string]interface{}{"labels": []interface{}{"backend", "urgent"}},
+			want:  []string{"labels: [backend, urgent]", "assignees: [taku, hana]"},
+		},
+		{
+			name:    "null removes field",
+			patch:   map[string]interface{}{"due_date": nil},
+			notWant: []string{"due_date"},
+		},
+		{
+			name:  "new date is unquoted",
+			patch: map[string]interface{}{"start_date": "2026-01-05"},
+			want:  []string{"start_date: 2026-01-05"},
+		},
+		{
+			name:    "extra_meta is merged",
+			patch:   map[string]interface{}{"extra_meta": map[string]interface{}{"estimate": float64(5), "team": nil, "risk": "low"}},
+			want:    []string{"  estimate: 5", "  risk: low"},
+			notWant: []string{"team: core"},
+		},
+		{
+			name:  "string that looks like a boolean stays a string",
+			patch: map[string]interface{}{"extra_meta": map[string]interface{}{"flag": "true"}},
+			want:  []string{`  flag: "true"`},
+		},
+		{
+			name:    "id cannot be patched",
+			patch:   map[string]interface{}{"id": "T-2"},
+			wantErr: true,
+		},
+		{
+			name:    "unknown field",
+			patch:   map[string]interface{}{"title": "New"},
+			wantErr: true,
+		},
+		{
+			name:    "empty patch",
+			patch:   map[string]interface{}{},
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := PatchFrontmatter(patchTestMarkdown, tt.patch)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("PatchFrontmatter() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if tt.wantErr {
+				return
+			}
+
+			if !strings.HasPrefix(got, "## T-1: Patch target\n\n```yaml\n") {
+				t.Errorf("PatchFrontmatter() changed the heading:\n%s", got)
+			}
+			if !strings.HasSuffix(got, "```"+patchTestProse) {
+				t.Errorf("PatchFrontmatter() changed the prose:\n%s", got)
+			}
+			for _, w := range tt.want {
+				if !strings.Contains(got, w) {
+					t.Errorf("PatchFrontmatter() missing %q:\n%s", w, got)
+				}
+			}
+			for _, w := range tt.notWant {
+				if strings.Contains(got, w) {
+					t.Errorf("PatchFrontmatter() should not contain %q:\n%s", w, got)
+				}
+			}
+
+			// The result must still parse
+			if _, err := NewMarkdownParser().Parse(got); err != nil {
+				t.Errorf("patched markdown does not parse: %v", err)
+			}
+		})
+	}
+}
+
+func TestPatchFrontmatter_NoYAMLBlock(t *testing.T) {
+	if _, err := PatchFrontmatter("## T-1: No metadata\n", map[string]interface{}{"status": "done"}); err == nil {
+		t.Error("PatchFrontmatter() expected error for markdown without YAML block")
+	}
+}
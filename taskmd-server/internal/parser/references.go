@@ -0,0 +1,114 @@
+package parser
+
+import (
+	"regexp"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/tktomaru/taskai/taskai-server/internal/models"
+)
+
+// taskReferencePattern matches task IDs such as T-1042 or #T-1042 that are not part of a longer word
+var taskReferencePattern = regexp.MustCompile(`(?:^|[^A-Za-z0-9_\-])#?([A-Z]+-\d+)\b`)
+
+// inlineCodePattern matches `inline code` spans
+var inlineCodePattern = regexp.MustCompile("`[^`\n]*`")
+
+// relationKeywordPattern matches phrases that give a reference a relation type, as in "Blocked by T-2"
+var relationKeywordPattern = regexp.MustCompile(`(?i)\b(blocked by|depends on|blocks|blocking|duplicate of|duplicates|duplicated by)\b`)
+
+// relationKeywords maps relation keywords to the relation type of the references following them
+var relationKeywords = map[string]models.RelationType{
+	"blocked by":    models.RelationTypeBlockedBy,
+	"depends on":    models.RelationTypeBlockedBy,
+	"blocks":        models.RelationTypeBlocks,
+	"blocking":      models.RelationTypeBlocks,
+	"duplicate of":  models.RelationTypeDuplicates,
+	"duplicates":    models.RelationTypeDuplicates,
+	"duplicated by": models.RelationTypeDuplicatedBy,
+}
+
+// TaskReference is a mention of another task in a Markdown body
+type TaskReference struct {
+	TaskID string `json:"task_id"`
+	// RelationType is blocked_by, blocks, duplicates or duplicated_by when a keyword
+	// precedes the reference in the same sentence, and related otherwise
+	RelationType models.RelationType `json:"relation_type"`
+	// Line and Column are 1-based positions in the document; columns count characters
+	Line   int `json:"line"`
+	Column int `json:"column"`
+}
+
+// ExtractTaskReferences finds the task IDs mentioned in a document.
+// Frontmatter, fenced code blocks and inline code are skipped. Every mention is
+// returned in document order, including repeated and self references.
+func ExtractTaskReferences(markdown string) []TaskReference {
+	var refs []TaskReference
+
+	scanProse(markdown, func(lineNo int, text string) {
+		for _, match := range taskReferencePattern.FindAllStringSubmatchIndex(text, -1) {
+			refs = append(refs, TaskReference{
+				TaskID:       text[match[2]:match[3]],
+				RelationType: relationTypeBefore(text[:match[2]]),
+				Line:         lineNo,
+				Column:       utf8.RuneCountInString(text[:match[2]]) + 1,
+			})
+		}
+	})
+
+	return refs
+}
+
+// scanProse calls fn with every prose line of a document and its 1-based line number.
+// Frontmatter and fenced code blocks are skipped, and inline code is blanked out
+// so that columns of the rest of the line stay the same.
+func scanProse(markdown string, fn func(lineNo int, text string)) {
+	// --- and +++ frontmatter is not part of the body; a ```yaml block is skipped as a fence
+	skipUntil := 0
+	if block, err := locateFrontmatter(markdown); err == nil && block.dialect != DialectFenced {
+		skipUntil = block.end
+	}
+
+	inFence := false
+	offset := 0
+
+	for i, line := range strings.SplitAfter(markdown, "\n") {
+		start := offset
+		offset += len(line)
+		if start < skipUntil {
+			continue
+		}
+
+		text := strings.TrimRight(line, "\r\n")
+		trimmed := strings.TrimSpace(text)
+		if strings.HasPrefix(trimmed, "```") || strings.HasPrefix(trimmed, "~~~") {
+			inFence = !inFence
+			continue
+		}
+		if inFence {
+			continue
+		}
+
+		text = inlineCodePattern.ReplaceAllStringFunc(text, func(code string) string {
+			return strings.Repeat(" ", utf8.RuneCountInString(code))
+		})
+
+		fn(i+1, text)
+	}
+}
+
+// relationTypeBefore returns the relation type given by the last keyword of the
+// sentence preceding a reference. Lists such as "Blocked by T-2, T-3 and T-4"
+// share the keyword.
+func relationTypeBefore(prefix string) models.RelationType {
+	if i := strings.LastIndexAny(prefix, ".;!?。"); i >= 0 {
+		prefix = prefix[i+1:]
+	}
+
+	matches := relationKeywordPattern.FindAllString(prefix, -1)
+	if len(matches) == 0 {
+		return models.RelationTypeRelated
+	}
+
+	return relationKeywords[strings.ToLower(matches[len(matches)-1])]
+}
@@ -5,14 +5,27 @@ import (
 	"regexp"
 	"strings"
 	"time"
+
+	"github.com/tktomaru/taskai/taskai-server/internal/calendar"
 )
 
 // QueryParser parses SavedView query strings
-type QueryParser struct{}
+type QueryParser struct {
+	calendar *calendar.Calendar
+}
 
 // NewQueryParser creates a new query parser
 func NewQueryParser() *QueryParser {
-	return &QueryParser{}
+	return &QueryParser{
+		calendar: calendar.Default(),
+	}
+}
+
+// SetCalendar sets the calendar used to resolve business-day expressions (e.g. +5bd)
+func (p *QueryParser) SetCalendar(cal *calendar.Calendar) {
+	if cal != nil {
+		p.calendar = cal
+	}
 }
 
 // ParsedQuery represents a parsed query
@@ -355,6 +368,17 @@ func (p *QueryParser) resolveRelativeDate(expr string) (string, error) {
 		return now.AddDate(0, 0, days).Format("2006-01-02"), nil
 	}
 
+	// +Nbd / -Nbd (N business days from now / ago)
+	businessDaysPattern := regexp.MustCompile(`^([+-])(\d+)bd$`)
+	if matches := businessDaysPattern.FindStringSubmatch(expr); matches != nil {
+		var days int
+		fmt.Sscanf(matches[2], "%d", &days)
+		if matches[1] == "-" {
+			days = -days
+		}
+		return p.calendar.AddBusinessDays(now, days).Format("2006-01-02"), nil
+	}
+
 	// last_weekday (e.g., last_monday, last_sunday)
 	lastWeekdayPattern := regexp.MustCompile(`^last_(monday|tuesday|wednesday|thursday|friday|saturday|sunday)$`)
 	if matches := lastWeekdayPattern.FindStringSubmatch(expr); matches != nil {
@@ -2,6 +2,7 @@ package query
 
 import (
 	"testing"
+	"time"
 )
 
 func TestQueryParser_Parse(t *testing.T) {
@@ -130,3 +131,26 @@ func TestFilter_Validation(t *testing.T) {
 		})
 	}
 }
+
+func TestQueryParser_BusinessDays(t *testing.T) {
+	parser := NewQueryParser()
+
+	result, err := parser.Parse("due:<+5bd")
+	if err != nil {
+		t.Fatalf("Parse() error = %v", err)
+	}
+
+	if len(result.Filters) != 1 {
+		t.Fatalf("Parse() got %d filters, want 1", len(result.Filters))
+	}
+
+	filter := result.Filters[0]
+	if filter.Operator != "<" {
+		t.Errorf("Parse() operator = %v, want <", filter.Operator)
+	}
+
+	want := parser.calendar.AddBusinessDays(time.Now(), 5).Format("2006-01-02")
+	if filter.Value != want {
+		t.Errorf("Parse() value = %v, want %v", filter.Value, want)
+	}
+}
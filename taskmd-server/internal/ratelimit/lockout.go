@@ -0,0 +1,69 @@
+package ratelimit
+
+import (
+	"context"
+	"time"
+)
+
+// Lockout locks a key out after repeated failures, doubling the lockout with every
+// further failure
+type Lockout struct {
+	Store Store
+	// Threshold is the number of failures allowed before the first lockout
+	Threshold int
+	// Base is the length of the first lockout
+	Base time.Duration
+	// Max caps the length of a lockout. Failures are forgotten after this long without one.
+	Max time.Duration
+}
+
+// Duration returns how long a key is locked out after a number of consecutive failures
+func (l *Lockout) Duration(failures int) time.Duration {
+	if failures < l.Threshold {
+		return 0
+	}
+
+	d := l.Base
+	for i := l.Threshold; i < failures && d < l.Max; i++ {
+		d *= 2
+	}
+	if d > l.Max {
+		d = l.Max
+	}
+
+	return d
+}
+
+// Check returns how long a key remains locked out, or zero when it may try again
+func (l *Lockout) Check(ctx context.Context, key string, now time.Time) (time.Duration, error) {
+	failures, last, err := l.Store.Failures(ctx, key, now)
+	if err != nil {
+		return 0, err
+	}
+
+	return l.remaining(failures, last, now), nil
+}
+
+// Fail records a failed attempt and returns how long the key is now locked out
+func (l *Lockout) Fail(ctx context.Context, key string, now time.Time) (time.Duration, error) {
+	failures, last, err := l.Store.RecordFailure(ctx, key, l.Max, now)
+	if err != nil {
+		return 0, err
+	}
+
+	return l.remaining(failures, last, now), nil
+}
+
+// Succeed forgets the failures of a key
+func (l *Lockout) Succeed(ctx context.Context, key string) error {
+	return l.Store.ResetFailures(ctx, key)
+}
+
+// remaining returns how much of the lockout following the last failure is left
+func (l *Lockout) remaining(failures int, last, now time.Time) time.Duration {
+	if until := last.Add(l.Duration(failures)); until.After(now) {
+		return until.Sub(now)
+	}
+
+	return 0
+}
@@ -0,0 +1,69 @@
+package ratelimit
+
+import (
+	"context"
+	"testing"
+	"time"
+)
+
+func TestLockout_Duration(t *testing.T) {
+	lockout := &Lockout{Threshold: 3, Base: 30 * time.Second, Max: 5 * time.Minute}
+
+	tests := []struct {
+		failures int
+		want     time.Duration
+	}{
+		{0, 0},
+		{2, 0},
+		{3, 30 * time.Second},
+		{4, time.Minute},
+		{5, 2 * time.Minute},
+		{6, 4 * time.Minute},
+		{7, 5 * time.Minute},
+		{50, 5 * time.Minute},
+	}
+
+	for _, tt := range tests {
+		if got := lockout.Duration(tt.failures); got != tt.want {
+			t.Errorf("Duration(%d) = %v, want %v", tt.failures, got, tt.want)
+		}
+	}
+}
+
+func TestLockout_FailAndSucceed(t *testing.T) {
+	ctx := context.Background()
+	lockout := &Lockout{Store: NewMemoryStore(), Threshold: 2, Base: 10 * time.Second, Max: time.Minute}
+	now := time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC)
+
+	if wait, _ := lockout.Fail(ctx, "login:a", now); wait != 0 {
+		t.Fatalf("Fail() locked out after the first failure for %v", wait)
+	}
+	if wait, _ := lockout.Fail(ctx, "login:a", now); wait != 10*time.Second {
+		t.Fatalf("Fail() = %v, want 10s", wait)
+	}
+	if wait, _ := lockout.Check(ctx, "login:a", now.Add(4*time.Second)); wait != 6*time.Second {
+		t.Errorf("Check() = %v, want 6s", wait)
+	}
+	if wait, _ := lockout.Check(ctx, "login:b", now); wait != 0 {
+		t.Errorf("Check() locked out another key for %v", wait)
+	}
+
+	// The next failure after the lockout doubles it
+	later := now.Add(10 * time.Second)
+	if wait, _ := lockout.Check(ctx, "login:a", later); wait != 0 {
+		t.Errorf("Check() = %v after the lockout, want 0", wait)
+	}
+	if wait, _ := lockout.Fail(ctx, "login:a", later); wait != 20*time.Second {
+		t.Errorf("Fail() = %v, want 20s", wait)
+	}
+
+	// Failures are forgotten after a success, or after Max without a failure
+	lockout.Succeed(ctx, "login:a")
+	if wait, _ := lockout.Fail(ctx, "login:a", later); wait != 0 {
+		t.Errorf("Fail() after Succeed() = %v, want 0", wait)
+	}
+	lockout.Fail(ctx, "login:a", later)
+	if wait, _ := lockout.Fail(ctx, "login:a", later.Add(2*time.Minute)); wait != 0 {
+		t.Errorf("Fail() after the failures expired = %v, want 0", wait)
+	}
+}
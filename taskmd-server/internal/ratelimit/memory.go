@@ -0,0 +1,109 @@
+package ratelimit
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+// sweepInterval is how often idle entries are removed from a memory store
+const sweepInterval = time.Minute
+
+// failureRecord counts the consecutive failures of a key
+type failureRecord struct {
+	count   int
+	last    time.Time
+	expires time.Time
+}
+
+// MemoryStore keeps buckets in process memory. Limits are per replica.
+type MemoryStore struct {
+	mu        sync.Mutex
+	buckets   map[string]*bucket
+	failures  map[string]*failureRecord
+	lastSweep time.Time
+}
+
+// NewMemoryStore creates an in-memory store
+func NewMemoryStore() *MemoryStore {
+	return &MemoryStore{
+		buckets:  make(map[string]*bucket),
+		failures: make(map[string]*failureRecord),
+	}
+}
+
+// Take takes a token from the bucket of a key
+func (m *MemoryStore) Take(ctx context.Context, key string, limit Limit, now time.Time) (Result, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	m.sweep(now)
+
+	b, ok := m.buckets[key]
+	if !ok || b.limit != limit {
+		b = &bucket{tokens: float64(limit.Requests), updated: now, limit: limit}
+		m.buckets[key] = b
+	}
+
+	return b.take(now), nil
+}
+
+// RecordFailure counts a failed attempt and returns the number of consecutive failures
+func (m *MemoryStore) RecordFailure(ctx context.Context, key string, ttl time.Duration, now time.Time) (int, time.Time, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	m.sweep(now)
+
+	record, ok := m.failures[key]
+	if !ok || !now.Before(record.expires) {
+		record = &failureRecord{}
+		m.failures[key] = record
+	}
+	record.count++
+	record.last = now
+	record.expires = now.Add(ttl)
+
+	return record.count, record.last, nil
+}
+
+// Failures returns the number of consecutive failures of a key and when the last one happened
+func (m *MemoryStore) Failures(ctx context.Context, key string, now time.Time) (int, time.Time, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	record, ok := m.failures[key]
+	if !ok || !now.Before(record.expires) {
+		return 0, time.Time{}, nil
+	}
+
+	return record.count, record.last, nil
+}
+
+// ResetFailures forgets the failures of a key
+func (m *MemoryStore) ResetFailures(ctx context.Context, key string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	delete(m.failures, key)
+	return nil
+}
+
+// sweep removes full buckets and expired failure counts. The caller holds the lock.
+func (m *MemoryStore) sweep(now time.Time) {
+	if now.Sub(m.lastSweep) < sweepInterval {
+		return
+	}
+	m.lastSweep = now
+
+	for key, b := range m.buckets {
+		if b.full(now) {
+			delete(m.buckets, key)
+		}
+	}
+	for key, record := range m.failures {
+		if !now.Before(record.expires) {
+			delete(m.failures, key)
+		}
+	}
+}
@@ -0,0 +1,129 @@
+// Package ratelimit implements token-bucket rate limits and exponential lockouts
+// on top of a pluggable store.
+package ratelimit
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Limit allows Requests requests per Per, in bursts of up to Requests
+type Limit struct {
+	Requests int
+	Per      time.Duration
+}
+
+// ParseLimit parses a limit written as "requests/duration", e.g. "10/1m".
+// A bare unit such as "300/m" or "5/s" means one of that unit.
+func ParseLimit(spec string) (Limit, error) {
+	requests, per, ok := strings.Cut(strings.TrimSpace(spec), "/")
+	if !ok {
+		return Limit{}, fmt.Errorf("invalid rate limit %q (expected requests/duration)", spec)
+	}
+
+	n, err := strconv.Atoi(strings.TrimSpace(requests))
+	if err != nil || n <= 0 {
+		return Limit{}, fmt.Errorf("invalid rate limit %q: requests must be a positive integer", spec)
+	}
+
+	per = strings.TrimSpace(per)
+	if per != "" && (per[0] < '0' || per[0] > '9') {
+		per = "1" + per
+	}
+	d, err := time.ParseDuration(per)
+	if err != nil || d <= 0 {
+		return Limit{}, fmt.Errorf("invalid rate limit %q: invalid duration", spec)
+	}
+
+	return Limit{Requests: n, Per: d}, nil
+}
+
+// String formats a limit the way ParseLimit reads it
+func (l Limit) String() string {
+	return fmt.Sprintf("%d/%s", l.Requests, l.Per)
+}
+
+// rate returns the number of tokens added to a bucket per second
+func (l Limit) rate() float64 {
+	return float64(l.Requests) / l.Per.Seconds()
+}
+
+// Result describes the state of a bucket after a request
+type Result struct {
+	Allowed bool
+	// Limit is the size of the bucket
+	Limit int
+	// Remaining is the number of requests that can be made right away
+	Remaining int
+	// Reset is the time until the bucket is full again
+	Reset time.Duration
+	// RetryAfter is the time until the next request is allowed, when it was denied
+	RetryAfter time.Duration
+}
+
+// Store keeps rate limit buckets and failure counters. Implementations must be safe for
+// concurrent use; stores shared by several replicas must update each key atomically.
+type Store interface {
+	// Take takes a token from the bucket of a key
+	Take(ctx context.Context, key string, limit Limit, now time.Time) (Result, error)
+
+	// RecordFailure counts a failed attempt and returns the number of consecutive failures.
+	// The count is forgotten ttl after the last failure.
+	RecordFailure(ctx context.Context, key string, ttl time.Duration, now time.Time) (int, time.Time, error)
+
+	// Failures returns the number of consecutive failures of a key and when the last one happened
+	Failures(ctx context.Context, key string, now time.Time) (int, time.Time, error)
+
+	// ResetFailures forgets the failures of a key
+	ResetFailures(ctx context.Context, key string) error
+}
+
+// bucket is the state of a token bucket
+type bucket struct {
+	tokens  float64
+	updated time.Time
+	limit   Limit
+}
+
+// refill adds the tokens earned since the last update
+func (b *bucket) refill(now time.Time) {
+	if elapsed := now.Sub(b.updated).Seconds(); elapsed > 0 {
+		b.tokens = math.Min(float64(b.limit.Requests), b.tokens+elapsed*b.limit.rate())
+		b.updated = now
+	}
+}
+
+// take takes a token from a bucket if one is available
+func (b *bucket) take(now time.Time) Result {
+	b.refill(now)
+
+	rate := b.limit.rate()
+	result := Result{Limit: b.limit.Requests}
+
+	if b.tokens >= 1 {
+		b.tokens--
+		result.Allowed = true
+	} else {
+		result.RetryAfter = seconds((1 - b.tokens) / rate)
+	}
+
+	result.Remaining = int(b.tokens)
+	result.Reset = seconds((float64(b.limit.Requests) - b.tokens) / rate)
+
+	return result
+}
+
+// full reports whether a bucket has refilled, so that forgetting it changes nothing
+func (b *bucket) full(now time.Time) bool {
+	b.refill(now)
+	return b.tokens >= float64(b.limit.Requests)
+}
+
+// seconds converts a number of seconds to a duration
+func seconds(s float64) time.Duration {
+	return time.Duration(s * float64(time.Second))
+}
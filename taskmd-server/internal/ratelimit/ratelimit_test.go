@@ -0,0 +1,92 @@
+package ratelimit
+
+import (
+	"context"
+	"testing"
+	"time"
+)
+
+func TestParseLimit(t *testing.T) {
+	tests := []struct {
+		spec    string
+		want    Limit
+		wantErr bool
+	}{
+		{"10/1m", Limit{Requests: 10, Per: time.Minute}, false},
+		{"300/m", Limit{Requests: 300, Per: time.Minute}, false},
+		{" 5 / s ", Limit{Requests: 5, Per: time.Second}, false},
+		{"100/15m", Limit{Requests: 100, Per: 15 * time.Minute}, false},
+		{"10", Limit{}, true},
+		{"0/1m", Limit{}, true},
+		{"ten/1m", Limit{}, true},
+		{"10/soon", Limit{}, true},
+		{"10/-1m", Limit{}, true},
+	}
+
+	for _, tt := range tests {
+		got, err := ParseLimit(tt.spec)
+		if (err != nil) != tt.wantErr {
+			t.Errorf("ParseLimit(%q) error = %v, wantErr %v", tt.spec, err, tt.wantErr)
+			continue
+		}
+		if got != tt.want {
+			t.Errorf("ParseLimit(%q) = %v, want %v", tt.spec, got, tt.want)
+		}
+	}
+}
+
+func TestMemoryStore_Take(t *testing.T) {
+	ctx := context.Background()
+	store := NewMemoryStore()
+	limit := Limit{Requests: 3, Per: 3 * time.Second}
+	now := time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC)
+
+	// The bucket starts full
+	for i := 2; i >= 0; i-- {
+		result, _ := store.Take(ctx, "ip:1", limit, now)
+		if !result.Allowed || result.Remaining != i {
+			t.Fatalf("Take() = %+v, want allowed with %d remaining", result, i)
+		}
+	}
+
+	result, _ := store.Take(ctx, "ip:1", limit, now)
+	if result.Allowed {
+		t.Fatal("Take() allowed a request from an empty bucket")
+	}
+	if result.RetryAfter != time.Second {
+		t.Errorf("RetryAfter = %v, want 1s", result.RetryAfter)
+	}
+	if result.Reset != 3*time.Second {
+		t.Errorf("Reset = %v, want 3s", result.Reset)
+	}
+
+	// Other keys have their own bucket
+	if result, _ := store.Take(ctx, "ip:2", limit, now); !result.Allowed {
+		t.Error("Take() denied a request for another key")
+	}
+
+	// One token is added per second
+	result, _ = store.Take(ctx, "ip:1", limit, now.Add(time.Second))
+	if !result.Allowed || result.Remaining != 0 {
+		t.Errorf("Take() after refill = %+v, want allowed with 0 remaining", result)
+	}
+}
+
+func TestMemoryStore_Sweep(t *testing.T) {
+	ctx := context.Background()
+	store := NewMemoryStore()
+	limit := Limit{Requests: 1, Per: time.Second}
+	now := time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC)
+
+	store.Take(ctx, "ip:1", limit, now)
+	store.RecordFailure(ctx, "login:a", time.Minute, now)
+
+	store.Take(ctx, "ip:2", limit, now.Add(2*time.Minute))
+
+	if _, ok := store.buckets["ip:1"]; ok {
+		t.Error("sweep kept a full bucket")
+	}
+	if _, ok := store.failures["login:a"]; ok {
+		t.Error("sweep kept an expired failure count")
+	}
+}
@@ -0,0 +1,279 @@
+package recurrence
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Frequency represents the FREQ part of a recurrence rule
+type Frequency string
+
+const (
+	FrequencyDaily   Frequency = "DAILY"
+	FrequencyWeekly  Frequency = "WEEKLY"
+	FrequencyMonthly Frequency = "MONTHLY"
+	FrequencyYearly  Frequency = "YEARLY"
+)
+
+// maxSearchDays bounds the search for the next occurrence
+const maxSearchDays = 366 * 10
+
+// Rule is a subset of the iCalendar RRULE (RFC 5545) used for recurring tasks.
+// Supported parts: FREQ, INTERVAL, BYDAY, BYMONTHDAY, COUNT and UNTIL.
+type Rule struct {
+	Freq       Frequency
+	Interval   int
+	ByDay      []time.Weekday
+	ByMonthDay []int
+	Count      int
+	Until      *time.Time
+}
+
+var weekdayCodes = map[string]time.Weekday{
+	"SU": time.Sunday,
+	"MO": time.Monday,
+	"TU": time.Tuesday,
+	"WE": time.Wednesday,
+	"TH": time.Thursday,
+	"FR": time.Friday,
+	"SA": time.Saturday,
+}
+
+// Parse parses an RRULE-like string such as "FREQ=WEEKLY;BYDAY=MO,TH"
+func Parse(s string) (*Rule, error) {
+	s = strings.TrimSpace(strings.TrimPrefix(strings.TrimSpace(s), "RRULE:"))
+	if s == "" {
+		return nil, fmt.Errorf("recurrence rule is empty")
+	}
+
+	rule := &Rule{Interval: 1}
+
+	for _, part := range strings.Split(s, ";") {
+		if part == "" {
+			continue
+		}
+
+		kv := strings.SplitN(part, "=", 2)
+		if len(kv) != 2 {
+			return nil, fmt.Errorf("invalid rule part: %s", part)
+		}
+		key := strings.ToUpper(strings.TrimSpace(kv[0]))
+		value := strings.ToUpper(strings.TrimSpace(kv[1]))
+
+		switch key {
+		case "FREQ":
+			freq := Frequency(value)
+			switch freq {
+			case FrequencyDaily, FrequencyWeekly, FrequencyMonthly, FrequencyYearly:
+				rule.Freq = freq
+			default:
+				return nil, fmt.Errorf("unsupported FREQ: %s (must be one of: DAILY, WEEKLY, MONTHLY, YEARLY)", value)
+			}
+
+		case "INTERVAL":
+			n, err := strconv.Atoi(value)
+			if err != nil || n < 1 {
+				return nil, fmt.Errorf("invalid INTERVAL: %s", value)
+			}
+			rule.Interval = n
+
+		case "BYDAY":
+			for _, code := range strings.Split(value, ",") {
+				wd, ok := weekdayCodes[strings.TrimSpace(code)]
+				if !ok {
+					return nil, fmt.Errorf("invalid BYDAY value: %s", code)
+				}
+				rule.ByDay = append(rule.ByDay, wd)
+			}
+
+		case "BYMONTHDAY":
+			for _, v := range strings.Split(value, ",") {
+				n, err := strconv.Atoi(strings.TrimSpace(v))
+				if err != nil || n == 0 || n < -31 || n > 31 {
+					return nil, fmt.Errorf("invalid BYMONTHDAY value: %s", v)
+				}
+				rule.ByMonthDay = append(rule.ByMonthDay, n)
+			}
+
+		case "COUNT":
+			n, err := strconv.Atoi(value)
+			if err != nil || n < 1 {
+				return nil, fmt.Errorf("invalid COUNT: %s", value)
+			}
+			rule.Count = n
+
+		case "UNTIL":
+			until, err := parseUntil(value)
+			if err != nil {
+				return nil, err
+			}
+			rule.Until = &until
+
+		default:
+			return nil, fmt.Errorf("unsupported rule part: %s", key)
+		}
+	}
+
+	if rule.Freq == "" {
+		return nil, fmt.Errorf("FREQ is required")
+	}
+
+	return rule, nil
+}
+
+// Next returns the first occurrence strictly after `after` for a series anchored at `anchor`.
+// ok is false when the rule has no further occurrences (UNTIL reached).
+// COUNT is not evaluated here since it depends on how many instances were already generated.
+func (r *Rule) Next(anchor, after time.Time) (next time.Time, ok bool) {
+	anchor = truncateToDate(anchor)
+	d := truncateToDate(after).AddDate(0, 0, 1)
+	if d.Before(anchor) {
+		d = anchor
+	}
+
+	for i := 0; i < maxSearchDays*r.Interval; i++ {
+		if r.Until != nil && d.After(*r.Until) {
+			return time.Time{}, false
+		}
+		if r.matches(anchor, d) {
+			return d, true
+		}
+		d = d.AddDate(0, 0, 1)
+	}
+
+	return time.Time{}, false
+}
+
+// String returns the canonical RRULE representation
+func (r *Rule) String() string {
+	parts := []string{"FREQ=" + string(r.Freq)}
+
+	if r.Interval > 1 {
+		parts = append(parts, fmt.Sprintf("INTERVAL=%d", r.Interval))
+	}
+
+	if len(r.ByDay) > 0 {
+		codes := make([]string, len(r.ByDay))
+		for i, wd := range r.ByDay {
+			codes[i] = strings.ToUpper(wd.String()[:2])
+		}
+		parts = append(parts, "BYDAY="+strings.Join(codes, ","))
+	}
+
+	if len(r.ByMonthDay) > 0 {
+		days := make([]string, len(r.ByMonthDay))
+		for i, n := range r.ByMonthDay {
+			days[i] = strconv.Itoa(n)
+		}
+		parts = append(parts, "BYMONTHDAY="+strings.Join(days, ","))
+	}
+
+	if r.Count > 0 {
+		parts = append(parts, fmt.Sprintf("COUNT=%d", r.Count))
+	}
+
+	if r.Until != nil {
+		parts = append(parts, "UNTIL="+r.Until.Format("20060102"))
+	}
+
+	return strings.Join(parts, ";")
+}
+
+// matches reports whether d is an occurrence of the rule anchored at anchor
+func (r *Rule) matches(anchor, d time.Time) bool {
+	switch r.Freq {
+	case FrequencyDaily:
+		days := daysBetween(anchor, d)
+		if days%r.Interval != 0 {
+			return false
+		}
+		return len(r.ByDay) == 0 || containsWeekday(r.ByDay, d.Weekday())
+
+	case FrequencyWeekly:
+		weeks := daysBetween(weekStart(anchor), weekStart(d)) / 7
+		if weeks%r.Interval != 0 {
+			return false
+		}
+		if len(r.ByDay) == 0 {
+			return d.Weekday() == anchor.Weekday()
+		}
+		return containsWeekday(r.ByDay, d.Weekday())
+
+	case FrequencyMonthly:
+		months := (d.Year()-anchor.Year())*12 + int(d.Month()-anchor.Month())
+		if months%r.Interval != 0 {
+			return false
+		}
+		if len(r.ByMonthDay) > 0 {
+			return matchesMonthDay(r.ByMonthDay, d)
+		}
+		if len(r.ByDay) > 0 {
+			return containsWeekday(r.ByDay, d.Weekday())
+		}
+		return d.Day() == anchor.Day()
+
+	case FrequencyYearly:
+		years := d.Year() - anchor.Year()
+		if years%r.Interval != 0 {
+			return false
+		}
+		return d.Month() == anchor.Month() && d.Day() == anchor.Day()
+	}
+
+	return false
+}
+
+// parseUntil parses UNTIL values in DATE or DATE-TIME form
+func parseUntil(value string) (time.Time, error) {
+	if len(value) < 8 {
+		return time.Time{}, fmt.Errorf("invalid UNTIL: %s", value)
+	}
+	until, err := time.Parse("20060102", value[:8])
+	if err != nil {
+		return time.Time{}, fmt.Errorf("invalid UNTIL: %s", value)
+	}
+	return until, nil
+}
+
+// matchesMonthDay checks BYMONTHDAY values, where negative values count from the month end
+func matchesMonthDay(days []int, d time.Time) bool {
+	lastDay := time.Date(d.Year(), d.Month()+1, 0, 0, 0, 0, 0, d.Location()).Day()
+	for _, n := range days {
+		if n > 0 && d.Day() == n {
+			return true
+		}
+		if n < 0 && d.Day() == lastDay+n+1 {
+			return true
+		}
+	}
+	return false
+}
+
+func containsWeekday(days []time.Weekday, wd time.Weekday) bool {
+	for _, d := range days {
+		if d == wd {
+			return true
+		}
+	}
+	return false
+}
+
+// weekStart returns the Monday of the week containing t
+func weekStart(t time.Time) time.Time {
+	offset := (int(t.Weekday()) + 6) % 7
+	return t.AddDate(0, 0, -offset)
+}
+
+// daysBetween returns the number of calendar days from a to b
+func daysBetween(a, b time.Time) int {
+	a = time.Date(a.Year(), a.Month(), a.Day(), 0, 0, 0, 0, time.UTC)
+	b = time.Date(b.Year(), b.Month(), b.Day(), 0, 0, 0, 0, time.UTC)
+	return int(b.Sub(a).Hours() / 24)
+}
+
+// truncateToDate strips the time of day, keeping the location
+func truncateToDate(t time.Time) time.Time {
+	return time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, t.Location())
+}
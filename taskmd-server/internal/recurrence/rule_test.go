@@ -0,0 +1,85 @@
+package recurrence
+
+import (
+	"testing"
+	"time"
+)
+
+func date(s string) time.Time {
+	d, err := time.Parse("2006-01-02", s)
+	if err != nil {
+		panic(err)
+	}
+	return d
+}
+
+func TestParse(t *testing.T) {
+	tests := []struct {
+		name    string
+		rule    string
+		wantErr bool
+		want    string
+	}{
+		{name: "weekly on monday", rule: "FREQ=WEEKLY;BYDAY=MO", want: "FREQ=WEEKLY;BYDAY=MO"},
+		{name: "rrule prefix", rule: "RRULE:FREQ=DAILY;INTERVAL=2", want: "FREQ=DAILY;INTERVAL=2"},
+		{name: "lowercase", rule: "freq=monthly;bymonthday=-1", want: "FREQ=MONTHLY;BYMONTHDAY=-1"},
+		{name: "count and until", rule: "FREQ=WEEKLY;COUNT=4;UNTIL=20261231", want: "FREQ=WEEKLY;COUNT=4;UNTIL=20261231"},
+		{name: "empty", rule: "", wantErr: true},
+		{name: "missing freq", rule: "BYDAY=MO", wantErr: true},
+		{name: "unsupported freq", rule: "FREQ=HOURLY", wantErr: true},
+		{name: "invalid byday", rule: "FREQ=WEEKLY;BYDAY=XX", wantErr: true},
+		{name: "invalid interval", rule: "FREQ=DAILY;INTERVAL=0", wantErr: true},
+		{name: "unknown part", rule: "FREQ=DAILY;BYHOUR=9", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rule, err := Parse(tt.rule)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("Parse() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if !tt.wantErr && rule.String() != tt.want {
+				t.Errorf("Parse().String() = %v, want %v", rule.String(), tt.want)
+			}
+		})
+	}
+}
+
+func TestRule_Next(t *testing.T) {
+	tests := []struct {
+		name   string
+		rule   string
+		anchor string
+		after  string
+		want   string
+		wantOK bool
+	}{
+		{name: "weekly monday", rule: "FREQ=WEEKLY;BYDAY=MO", anchor: "2025-12-22", after: "2025-12-22", want: "2025-12-29", wantOK: true},
+		{name: "weekly multiple days", rule: "FREQ=WEEKLY;BYDAY=MO,TH", anchor: "2025-12-22", after: "2025-12-22", want: "2025-12-25", wantOK: true},
+		{name: "biweekly", rule: "FREQ=WEEKLY;INTERVAL=2;BYDAY=MO", anchor: "2025-12-22", after: "2025-12-22", want: "2026-01-05", wantOK: true},
+		{name: "weekly default weekday", rule: "FREQ=WEEKLY", anchor: "2025-12-24", after: "2025-12-24", want: "2025-12-31", wantOK: true},
+		{name: "daily", rule: "FREQ=DAILY;INTERVAL=3", anchor: "2025-12-22", after: "2025-12-23", want: "2025-12-25", wantOK: true},
+		{name: "monthly same day", rule: "FREQ=MONTHLY", anchor: "2025-12-15", after: "2025-12-15", want: "2026-01-15", wantOK: true},
+		{name: "monthly last day", rule: "FREQ=MONTHLY;BYMONTHDAY=-1", anchor: "2026-01-31", after: "2026-01-31", want: "2026-02-28", wantOK: true},
+		{name: "yearly", rule: "FREQ=YEARLY", anchor: "2025-04-01", after: "2025-04-01", want: "2026-04-01", wantOK: true},
+		{name: "until reached", rule: "FREQ=WEEKLY;UNTIL=20251228", anchor: "2025-12-22", after: "2025-12-22", wantOK: false},
+		{name: "after before anchor", rule: "FREQ=WEEKLY;BYDAY=MO", anchor: "2025-12-22", after: "2025-12-01", want: "2025-12-22", wantOK: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rule, err := Parse(tt.rule)
+			if err != nil {
+				t.Fatalf("Parse() error: %v", err)
+			}
+
+			got, ok := rule.Next(date(tt.anchor), date(tt.after))
+			if ok != tt.wantOK {
+				t.Fatalf("Next() ok = %v, want %v", ok, tt.wantOK)
+			}
+			if ok && got.Format("2006-01-02") != tt.want {
+				t.Errorf("Next() = %s, want %s", got.Format("2006-01-02"), tt.want)
+			}
+		})
+	}
+}
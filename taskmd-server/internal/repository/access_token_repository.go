@@ -0,0 +1,145 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+
+	"github.com/jmoiron/sqlx"
+	"github.com/tktomaru/taskai/taskai-server/internal/models"
+)
+
+// ErrAccessTokenNotFound is returned when an access token does not exist
+var ErrAccessTokenNotFound = errors.New("access token not found")
+
+// AccessTokenRepository handles access token data access
+type AccessTokenRepository struct {
+	db *sqlx.DB
+}
+
+// NewAccessTokenRepository creates a new access token repository
+func NewAccessTokenRepository(db *sqlx.DB) *AccessTokenRepository {
+	return &AccessTokenRepository{db: db}
+}
+
+// Create creates a new access token
+func (r *AccessTokenRepository) Create(ctx context.Context, token *models.AccessToken) error {
+	query := `
+		INSERT INTO access_tokens (
+			id, user_id, name, token_hash, project_id, scope, created_by, expires_at
+		) VALUES (
+			$1, $2, $3, $4, $5, $6, $7, $8
+		)
+		RETURNING created_at
+	`
+
+	err := r.db.QueryRowxContext(ctx, query,
+		token.ID,
+		token.UserID,
+		token.Name,
+		token.TokenHash,
+		token.ProjectID,
+		token.Scope,
+		token.CreatedBy,
+		token.ExpiresAt,
+	).Scan(&token.CreatedAt)
+	if err != nil {
+		return fmt.Errorf("failed to create access token: %w", err)
+	}
+
+	return nil
+}
+
+// GetActiveByHash retrieves an access token by its hash, unless it was revoked or has expired
+func (r *AccessTokenRepository) GetActiveByHash(ctx context.Context, tokenHash string) (*models.AccessToken, error) {
+	query := `
+		SELECT * FROM access_tokens
+		WHERE token_hash = $1 AND revoked_at IS NULL
+			AND (expires_at IS NULL OR expires_at > NOW())
+	`
+
+	var token models.AccessToken
+	err := r.db.GetContext(ctx, &token, query, tokenHash)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, ErrAccessTokenNotFound
+		}
+		return nil, fmt.Errorf("failed to get access token: %w", err)
+	}
+
+	return &token, nil
+}
+
+// IsActive reports whether a token exists and has neither been revoked nor expired
+func (r *AccessTokenRepository) IsActive(ctx context.Context, tokenID string) (bool, error) {
+	query := `
+		SELECT EXISTS (
+			SELECT 1 FROM access_tokens
+			WHERE id = $1 AND revoked_at IS NULL
+				AND (expires_at IS NULL OR expires_at > NOW())
+		)
+	`
+
+	var active bool
+	if err := r.db.GetContext(ctx, &active, query, tokenID); err != nil {
+		return false, fmt.Errorf("failed to check access token: %w", err)
+	}
+
+	return active, nil
+}
+
+// ListByUser retrieves the tokens of a user, including revoked and expired ones
+func (r *AccessTokenRepository) ListByUser(ctx context.Context, userID string) ([]*models.AccessToken, error) {
+	query := `
+		SELECT * FROM access_tokens
+		WHERE user_id = $1
+		ORDER BY created_at DESC
+	`
+
+	tokens := []*models.AccessToken{}
+	if err := r.db.SelectContext(ctx, &tokens, query, userID); err != nil {
+		return nil, fmt.Errorf("failed to list access tokens: %w", err)
+	}
+
+	return tokens, nil
+}
+
+// Revoke revokes a token of a user
+func (r *AccessTokenRepository) Revoke(ctx context.Context, userID, tokenID string) error {
+	query := `
+		UPDATE access_tokens SET revoked_at = NOW()
+		WHERE id = $1 AND user_id = $2 AND revoked_at IS NULL
+	`
+
+	result, err := r.db.ExecContext(ctx, query, tokenID, userID)
+	if err != nil {
+		return fmt.Errorf("failed to revoke access token: %w", err)
+	}
+
+	rows, err := result.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("failed to get rows affected: %w", err)
+	}
+
+	if rows == 0 {
+		return ErrAccessTokenNotFound
+	}
+
+	return nil
+}
+
+// TouchLastUsed records that a token was used. The timestamp is kept to the minute,
+// so that busy tokens don't write on every request.
+func (r *AccessTokenRepository) TouchLastUsed(ctx context.Context, tokenID string) error {
+	query := `
+		UPDATE access_tokens SET last_used_at = NOW()
+		WHERE id = $1 AND (last_used_at IS NULL OR last_used_at < NOW() - INTERVAL '1 minute')
+	`
+
+	if _, err := r.db.ExecContext(ctx, query, tokenID); err != nil {
+		return fmt.Errorf("failed to update access token: %w", err)
+	}
+
+	return nil
+}
@@ -0,0 +1,121 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/jmoiron/sqlx"
+	"github.com/tktomaru/taskai/taskai-server/internal/models"
+)
+
+// ErrAccountTokenNotFound is returned when an account token is unknown, used or expired
+var ErrAccountTokenNotFound = errors.New("invalid or expired token")
+
+// AccountTokenRepository handles email verification and password reset token data access
+type AccountTokenRepository struct {
+	db *sqlx.DB
+}
+
+// NewAccountTokenRepository creates a new account token repository
+func NewAccountTokenRepository(db *sqlx.DB) *AccountTokenRepository {
+	return &AccountTokenRepository{db: db}
+}
+
+// Create creates a new token
+func (r *AccountTokenRepository) Create(ctx context.Context, token *models.AccountToken) error {
+	query := `
+		INSERT INTO account_tokens (
+			id, user_id, purpose, token_hash, email, expires_at
+		) VALUES (
+			$1, $2, $3, $4, $5, $6
+		)
+		RETURNING created_at
+	`
+
+	err := r.db.QueryRowxContext(ctx, query,
+		token.ID,
+		token.UserID,
+		token.Purpose,
+		token.TokenHash,
+		token.Email,
+		token.ExpiresAt,
+	).Scan(&token.CreatedAt)
+	if err != nil {
+		return fmt.Errorf("failed to create account token: %w", err)
+	}
+
+	return nil
+}
+
+// GetActive retrieves an unused, unexpired token
+func (r *AccountTokenRepository) GetActive(ctx context.Context, purpose, tokenHash string) (*models.AccountToken, error) {
+	query := `
+		SELECT * FROM account_tokens
+		WHERE token_hash = $1 AND purpose = $2
+			AND used_at IS NULL AND expires_at > NOW()
+	`
+
+	var token models.AccountToken
+	err := r.db.GetContext(ctx, &token, query, tokenHash, purpose)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, ErrAccountTokenNotFound
+		}
+		return nil, fmt.Errorf("failed to get account token: %w", err)
+	}
+
+	return &token, nil
+}
+
+// Consume marks an unused, unexpired token as used and returns it.
+// A token can only be consumed once, even by concurrent requests.
+func (r *AccountTokenRepository) Consume(ctx context.Context, purpose, tokenHash string) (*models.AccountToken, error) {
+	query := `
+		UPDATE account_tokens SET used_at = NOW()
+		WHERE token_hash = $1 AND purpose = $2
+			AND used_at IS NULL AND expires_at > NOW()
+		RETURNING *
+	`
+
+	var token models.AccountToken
+	err := r.db.GetContext(ctx, &token, query, tokenHash, purpose)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, ErrAccountTokenNotFound
+		}
+		return nil, fmt.Errorf("failed to consume account token: %w", err)
+	}
+
+	return &token, nil
+}
+
+// InvalidateAll marks the outstanding tokens of a user for a purpose as used
+func (r *AccountTokenRepository) InvalidateAll(ctx context.Context, userID, purpose string) error {
+	query := `
+		UPDATE account_tokens SET used_at = NOW()
+		WHERE user_id = $1 AND purpose = $2 AND used_at IS NULL
+	`
+
+	if _, err := r.db.ExecContext(ctx, query, userID, purpose); err != nil {
+		return fmt.Errorf("failed to invalidate account tokens: %w", err)
+	}
+
+	return nil
+}
+
+// DeleteExpired removes tokens that expired before the given time
+func (r *AccountTokenRepository) DeleteExpired(ctx context.Context, before time.Time) (int64, error) {
+	query := `
+		DELETE FROM account_tokens WHERE expires_at <= $1
+	`
+
+	result, err := r.db.ExecContext(ctx, query, before)
+	if err != nil {
+		return 0, fmt.Errorf("failed to delete expired account tokens: %w", err)
+	}
+
+	return result.RowsAffected()
+}
@@ -0,0 +1,44 @@
+package repository
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/jmoiron/sqlx"
+	"github.com/tktomaru/taskai/taskai-server/internal/models"
+)
+
+// AuditRepository handles audit log data access
+type AuditRepository struct {
+	db DBTX
+}
+
+// NewAuditRepository creates a new audit repository
+func NewAuditRepository(db *sqlx.DB) *AuditRepository {
+	return &AuditRepository{db: db}
+}
+
+// WithTx returns an audit repository that runs its queries inside the given transaction
+func (r *AuditRepository) WithTx(tx *sqlx.Tx) *AuditRepository {
+	return &AuditRepository{db: tx}
+}
+
+// Create records an audit log entry and sets its ID and timestamp
+func (r *AuditRepository) Create(ctx context.Context, entry *models.AuditLog) error {
+	query := `
+		INSERT INTO audit_logs (
+			actor_user_id, actor_ip, action, target_type, target_id, detail
+		) VALUES (
+			$1, $2, $3, $4, $5, $6
+		)
+		RETURNING *
+	`
+
+	err := r.db.GetContext(ctx, entry, query,
+		entry.ActorUserID, entry.ActorIP, entry.Action, entry.TargetType, entry.TargetID, entry.Detail)
+	if err != nil {
+		return fmt.Errorf("failed to create audit log: %w", err)
+	}
+
+	return nil
+}
@@ -0,0 +1,61 @@
+package repository
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/jmoiron/sqlx"
+	"github.com/tktomaru/taskai/taskai-server/internal/models"
+)
+
+// CommentRepository handles task comment data access
+type CommentRepository struct {
+	db *sqlx.DB
+}
+
+// NewCommentRepository creates a new comment repository
+func NewCommentRepository(db *sqlx.DB) *CommentRepository {
+	return &CommentRepository{db: db}
+}
+
+// Create creates a new comment
+func (r *CommentRepository) Create(ctx context.Context, comment *models.TaskComment) error {
+	query := `
+		INSERT INTO task_comments (
+			id, task_id, markdown_body, author_user_id
+		) VALUES (
+			:id, :task_id, :markdown_body, :author_user_id
+		)
+		RETURNING created_at, updated_at
+	`
+
+	rows, err := r.db.NamedQueryContext(ctx, query, comment)
+	if err != nil {
+		return fmt.Errorf("failed to create comment: %w", err)
+	}
+	defer rows.Close()
+
+	if rows.Next() {
+		if err := rows.Scan(&comment.CreatedAt, &comment.UpdatedAt); err != nil {
+			return fmt.Errorf("failed to read comment timestamps: %w", err)
+		}
+	}
+
+	return nil
+}
+
+// ListByTask retrieves the comments of a task, oldest first
+func (r *CommentRepository) ListByTask(ctx context.Context, taskID string) ([]*models.TaskComment, error) {
+	query := `
+		SELECT * FROM task_comments
+		WHERE task_id = $1 AND deleted_at IS NULL
+		ORDER BY created_at
+	`
+
+	comments := []*models.TaskComment{}
+	if err := r.db.SelectContext(ctx, &comments, query, taskID); err != nil {
+		return nil, fmt.Errorf("failed to list comments: %w", err)
+	}
+
+	return comments, nil
+}
@@ -0,0 +1,15 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+)
+
+// DBTX is the subset of sqlx methods shared by *sqlx.DB and *sqlx.Tx,
+// so that repositories can run either directly or inside a transaction
+type DBTX interface {
+	GetContext(ctx context.Context, dest interface{}, query string, args ...interface{}) error
+	SelectContext(ctx context.Context, dest interface{}, query string, args ...interface{}) error
+	ExecContext(ctx context.Context, query string, args ...interface{}) (sql.Result, error)
+	NamedExecContext(ctx context.Context, query string, arg interface{}) (sql.Result, error)
+}
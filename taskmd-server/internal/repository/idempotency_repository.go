@@ -0,0 +1,115 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"time"
+
+	"github.com/jmoiron/sqlx"
+	"github.com/tktomaru/taskai/taskai-server/internal/models"
+)
+
+// IdempotencyRepository handles stored responses of idempotent requests
+type IdempotencyRepository struct {
+	db *sqlx.DB
+}
+
+// NewIdempotencyRepository creates a new idempotency key repository
+func NewIdempotencyRepository(db *sqlx.DB) *IdempotencyRepository {
+	return &IdempotencyRepository{db: db}
+}
+
+// Reserve claims a key for a request that is about to be processed, until record.ExpiresAt.
+// It returns false without error when an unexpired record for the key already exists.
+func (r *IdempotencyRepository) Reserve(ctx context.Context, record *models.IdempotencyKey) (bool, error) {
+	query := `
+		INSERT INTO idempotency_keys (scope, key, request_hash, expires_at)
+		VALUES ($1, $2, $3, $4)
+		ON CONFLICT (scope, key) DO UPDATE SET
+			request_hash = EXCLUDED.request_hash,
+			status_code = NULL,
+			content_type = NULL,
+			response_body = NULL,
+			created_at = NOW(),
+			completed_at = NULL,
+			expires_at = EXCLUDED.expires_at
+		WHERE idempotency_keys.expires_at <= NOW()
+	`
+
+	result, err := r.db.ExecContext(ctx, query, record.Scope, record.Key, record.RequestHash, record.ExpiresAt)
+	if err != nil {
+		return false, fmt.Errorf("failed to reserve idempotency key: %w", err)
+	}
+
+	rows, err := result.RowsAffected()
+	if err != nil {
+		return false, fmt.Errorf("failed to get rows affected: %w", err)
+	}
+
+	return rows == 1, nil
+}
+
+// Get retrieves the unexpired record of a key
+func (r *IdempotencyRepository) Get(ctx context.Context, scope, key string) (*models.IdempotencyKey, error) {
+	query := `
+		SELECT * FROM idempotency_keys
+		WHERE scope = $1 AND key = $2 AND expires_at > NOW()
+	`
+
+	var record models.IdempotencyKey
+	err := r.db.GetContext(ctx, &record, query, scope, key)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, fmt.Errorf("idempotency key not found")
+		}
+		return nil, fmt.Errorf("failed to get idempotency key: %w", err)
+	}
+
+	return &record, nil
+}
+
+// Complete stores the response of a reserved key and keeps it until expiresAt
+func (r *IdempotencyRepository) Complete(ctx context.Context, scope, key string, statusCode int, contentType string, body []byte, expiresAt time.Time) error {
+	query := `
+		UPDATE idempotency_keys SET
+			status_code = $3,
+			content_type = $4,
+			response_body = $5,
+			completed_at = NOW(),
+			expires_at = $6
+		WHERE scope = $1 AND key = $2
+	`
+
+	if _, err := r.db.ExecContext(ctx, query, scope, key, statusCode, contentType, body, expiresAt); err != nil {
+		return fmt.Errorf("failed to store idempotent response: %w", err)
+	}
+
+	return nil
+}
+
+// Release removes a reserved key so that the request can be retried
+func (r *IdempotencyRepository) Release(ctx context.Context, scope, key string) error {
+	query := `
+		DELETE FROM idempotency_keys
+		WHERE scope = $1 AND key = $2 AND completed_at IS NULL
+	`
+
+	if _, err := r.db.ExecContext(ctx, query, scope, key); err != nil {
+		return fmt.Errorf("failed to release idempotency key: %w", err)
+	}
+
+	return nil
+}
+
+// DeleteExpired removes records whose replay window has passed
+func (r *IdempotencyRepository) DeleteExpired(ctx context.Context, now time.Time) (int64, error) {
+	query := `DELETE FROM idempotency_keys WHERE expires_at <= $1`
+
+	result, err := r.db.ExecContext(ctx, query, now)
+	if err != nil {
+		return 0, fmt.Errorf("failed to delete expired idempotency keys: %w", err)
+	}
+
+	return result.RowsAffected()
+}
@@ -0,0 +1,166 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+
+	"github.com/jmoiron/sqlx"
+	"github.com/tktomaru/taskai/taskai-server/internal/models"
+)
+
+// ErrIngestTokenNotFound is returned when an ingestion token does not exist
+var ErrIngestTokenNotFound = errors.New("ingest token not found")
+
+// IngestRepository handles ingestion token data access
+type IngestRepository struct {
+	db *sqlx.DB
+}
+
+// NewIngestRepository creates a new ingestion token repository
+func NewIngestRepository(db *sqlx.DB) *IngestRepository {
+	return &IngestRepository{db: db}
+}
+
+// Create creates a new ingestion token
+func (r *IngestRepository) Create(ctx context.Context, token *models.IngestToken) error {
+	query := `
+		INSERT INTO ingest_tokens (
+			id, project_id, name, token_hash, template_id, mapping, id_prefix, created_by
+		) VALUES (
+			$1, $2, $3, $4, $5, $6, $7, $8
+		)
+		RETURNING created_at, updated_at
+	`
+
+	err := r.db.QueryRowxContext(ctx, query,
+		token.ID,
+		token.ProjectID,
+		token.Name,
+		token.TokenHash,
+		token.TemplateID,
+		token.Mapping,
+		token.IDPrefix,
+		token.CreatedBy,
+	).Scan(&token.CreatedAt, &token.UpdatedAt)
+	if err != nil {
+		return fmt.Errorf("failed to create ingest token: %w", err)
+	}
+
+	return nil
+}
+
+// GetByID retrieves an ingestion token of a project
+func (r *IngestRepository) GetByID(ctx context.Context, projectID, tokenID string) (*models.IngestToken, error) {
+	query := `
+		SELECT * FROM ingest_tokens
+		WHERE id = $1 AND project_id = $2
+	`
+
+	var token models.IngestToken
+	err := r.db.GetContext(ctx, &token, query, tokenID, projectID)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, ErrIngestTokenNotFound
+		}
+		return nil, fmt.Errorf("failed to get ingest token: %w", err)
+	}
+
+	return &token, nil
+}
+
+// GetByHash retrieves an ingestion token by the hash of its secret and records its use
+func (r *IngestRepository) GetByHash(ctx context.Context, tokenHash string) (*models.IngestToken, error) {
+	query := `
+		UPDATE ingest_tokens SET last_used_at = NOW()
+		WHERE token_hash = $1
+		RETURNING *
+	`
+
+	var token models.IngestToken
+	err := r.db.GetContext(ctx, &token, query, tokenHash)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, ErrIngestTokenNotFound
+		}
+		return nil, fmt.Errorf("failed to get ingest token: %w", err)
+	}
+
+	return &token, nil
+}
+
+// List retrieves the ingestion tokens of a project
+func (r *IngestRepository) List(ctx context.Context, projectID string) ([]*models.IngestToken, error) {
+	query := `
+		SELECT * FROM ingest_tokens
+		WHERE project_id = $1
+		ORDER BY created_at ASC
+	`
+
+	tokens := []*models.IngestToken{}
+	if err := r.db.SelectContext(ctx, &tokens, query, projectID); err != nil {
+		return nil, fmt.Errorf("failed to list ingest tokens: %w", err)
+	}
+
+	return tokens, nil
+}
+
+// Update updates the name and payload handling of an ingestion token
+func (r *IngestRepository) Update(ctx context.Context, token *models.IngestToken) error {
+	query := `
+		UPDATE ingest_tokens SET
+			name = $3,
+			template_id = $4,
+			mapping = $5,
+			id_prefix = $6
+		WHERE id = $1 AND project_id = $2
+	`
+
+	result, err := r.db.ExecContext(ctx, query,
+		token.ID,
+		token.ProjectID,
+		token.Name,
+		token.TemplateID,
+		token.Mapping,
+		token.IDPrefix,
+	)
+	if err != nil {
+		return fmt.Errorf("failed to update ingest token: %w", err)
+	}
+
+	rows, err := result.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("failed to get rows affected: %w", err)
+	}
+
+	if rows == 0 {
+		return ErrIngestTokenNotFound
+	}
+
+	return nil
+}
+
+// Delete revokes an ingestion token
+func (r *IngestRepository) Delete(ctx context.Context, projectID, tokenID string) error {
+	query := `
+		DELETE FROM ingest_tokens
+		WHERE id = $1 AND project_id = $2
+	`
+
+	result, err := r.db.ExecContext(ctx, query, tokenID, projectID)
+	if err != nil {
+		return fmt.Errorf("failed to delete ingest token: %w", err)
+	}
+
+	rows, err := result.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("failed to get rows affected: %w", err)
+	}
+
+	if rows == 0 {
+		return ErrIngestTokenNotFound
+	}
+
+	return nil
+}
@@ -0,0 +1,177 @@
+package repository
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/jmoiron/sqlx"
+	"github.com/tktomaru/taskai/taskai-server/internal/models"
+)
+
+// NotificationFilters represents filters for listing notifications
+type NotificationFilters struct {
+	UnreadOnly bool
+	Limit      int
+	Offset     int
+}
+
+// NotificationRepository handles notification inbox data access
+type NotificationRepository struct {
+	db *sqlx.DB
+}
+
+// NewNotificationRepository creates a new notification repository
+func NewNotificationRepository(db *sqlx.DB) *NotificationRepository {
+	return &NotificationRepository{db: db}
+}
+
+// Create creates a new notification and sets its ID and creation time
+func (r *NotificationRepository) Create(ctx context.Context, notification *models.Notification) error {
+	query := `
+		INSERT INTO notifications (
+			user_id, type, project_id, task_id, comment_id, actor_user_id, title, detail, digest_pending
+		) VALUES (
+			$1, $2, $3, $4, $5, $6, $7, $8, $9
+		)
+		RETURNING id, created_at
+	`
+
+	err := r.db.QueryRowxContext(ctx, query,
+		notification.UserID,
+		notification.Type,
+		notification.ProjectID,
+		notification.TaskID,
+		notification.CommentID,
+		notification.ActorUserID,
+		notification.Title,
+		notification.Detail,
+		notification.DigestPending,
+	).Scan(&notification.ID, &notification.CreatedAt)
+	if err != nil {
+		return fmt.Errorf("failed to create notification: %w", err)
+	}
+
+	return nil
+}
+
+// ListByUser retrieves the notifications of a user, newest first
+func (r *NotificationRepository) ListByUser(ctx context.Context, userID string, filters *NotificationFilters) ([]*models.Notification, error) {
+	query := `
+		SELECT * FROM notifications
+		WHERE user_id = $1
+			AND ($2 = FALSE OR read_at IS NULL)
+		ORDER BY created_at DESC, id DESC
+		LIMIT $3 OFFSET $4
+	`
+
+	notifications := []*models.Notification{}
+	err := r.db.SelectContext(ctx, &notifications, query, userID, filters.UnreadOnly, filters.Limit, filters.Offset)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list notifications: %w", err)
+	}
+
+	return notifications, nil
+}
+
+// CountUnread returns the number of unread notifications of a user
+func (r *NotificationRepository) CountUnread(ctx context.Context, userID string) (int, error) {
+	query := `
+		SELECT COUNT(*) FROM notifications
+		WHERE user_id = $1 AND read_at IS NULL
+	`
+
+	var count int
+	if err := r.db.GetContext(ctx, &count, query, userID); err != nil {
+		return 0, fmt.Errorf("failed to count unread notifications: %w", err)
+	}
+
+	return count, nil
+}
+
+// SetRead marks a notification of a user as read or unread
+func (r *NotificationRepository) SetRead(ctx context.Context, userID string, notificationID int64, read bool) error {
+	query := `
+		UPDATE notifications
+		SET read_at = CASE WHEN $3 THEN COALESCE(read_at, NOW()) ELSE NULL END
+		WHERE id = $1 AND user_id = $2
+	`
+
+	result, err := r.db.ExecContext(ctx, query, notificationID, userID, read)
+	if err != nil {
+		return fmt.Errorf("failed to update notification: %w", err)
+	}
+
+	rows, err := result.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("failed to get rows affected: %w", err)
+	}
+
+	if rows == 0 {
+		return fmt.Errorf("notification not found")
+	}
+
+	return nil
+}
+
+// MarkAllRead marks every unread notification of a user as read
+func (r *NotificationRepository) MarkAllRead(ctx context.Context, userID string) (int64, error) {
+	query := `
+		UPDATE notifications SET read_at = NOW()
+		WHERE user_id = $1 AND read_at IS NULL
+	`
+
+	result, err := r.db.ExecContext(ctx, query, userID)
+	if err != nil {
+		return 0, fmt.Errorf("failed to mark notifications as read: %w", err)
+	}
+
+	return result.RowsAffected()
+}
+
+// ListDigestUsers returns the users whose oldest notification waiting for a digest was created before a time
+func (r *NotificationRepository) ListDigestUsers(ctx context.Context, before time.Time) ([]string, error) {
+	query := `
+		SELECT user_id FROM notifications
+		WHERE digest_pending
+		GROUP BY user_id
+		HAVING MIN(created_at) < $1
+	`
+
+	var userIDs []string
+	if err := r.db.SelectContext(ctx, &userIDs, query, before); err != nil {
+		return nil, fmt.Errorf("failed to list digest users: %w", err)
+	}
+
+	return userIDs, nil
+}
+
+// ListPendingDigest retrieves the notifications of a user waiting for a digest, oldest first
+func (r *NotificationRepository) ListPendingDigest(ctx context.Context, userID string) ([]*models.Notification, error) {
+	query := `
+		SELECT * FROM notifications
+		WHERE user_id = $1 AND digest_pending
+		ORDER BY created_at, id
+	`
+
+	notifications := []*models.Notification{}
+	if err := r.db.SelectContext(ctx, &notifications, query, userID); err != nil {
+		return nil, fmt.Errorf("failed to list pending digest notifications: %w", err)
+	}
+
+	return notifications, nil
+}
+
+// ClearDigestPending marks the notifications of a user up to an ID as collected into a digest
+func (r *NotificationRepository) ClearDigestPending(ctx context.Context, userID string, maxID int64) error {
+	query := `
+		UPDATE notifications SET digest_pending = FALSE
+		WHERE user_id = $1 AND digest_pending AND id <= $2
+	`
+
+	if _, err := r.db.ExecContext(ctx, query, userID, maxID); err != nil {
+		return fmt.Errorf("failed to clear pending digest notifications: %w", err)
+	}
+
+	return nil
+}
@@ -56,6 +56,77 @@ func (r *ProjectRepository) GetByID(ctx context.Context, projectID string) (*mod
 	return &project, nil
 }
 
+// ListMembers retrieves the users who are members of a project
+func (r *ProjectRepository) ListMembers(ctx context.Context, projectID string) ([]*models.User, error) {
+	query := `
+		SELECT u.* FROM users u
+		JOIN project_members pm ON pm.user_id = u.id
+		WHERE pm.project_id = $1
+		ORDER BY u.name ASC
+	`
+
+	var users []*models.User
+	err := r.db.SelectContext(ctx, &users, query, projectID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list project members: %w", err)
+	}
+
+	return users, nil
+}
+
+// GrantMemberRole adds a user to a project with a role, or raises the role of an existing member.
+// Roles are never lowered; project_role values are declared from most to least privileged.
+func (r *ProjectRepository) GrantMemberRole(ctx context.Context, projectID, userID, role string) error {
+	query := `
+		INSERT INTO project_members (project_id, user_id, role)
+		VALUES ($1, $2, $3::project_role)
+		ON CONFLICT (project_id, user_id) DO UPDATE SET role = EXCLUDED.role
+		WHERE EXCLUDED.role < project_members.role
+	`
+
+	if _, err := r.db.ExecContext(ctx, query, projectID, userID, role); err != nil {
+		return fmt.Errorf("failed to grant project member role: %w", err)
+	}
+
+	return nil
+}
+
+// Membership reports whether a user is a member of a project, and whether the project has
+// any members at all
+func (r *ProjectRepository) Membership(ctx context.Context, projectID, userID string) (bool, bool, error) {
+	query := `
+		SELECT
+			EXISTS (SELECT 1 FROM project_members WHERE project_id = $1 AND user_id = $2) AS member,
+			EXISTS (SELECT 1 FROM project_members WHERE project_id = $1) AS has_members
+	`
+
+	var result struct {
+		Member     bool `db:"member"`
+		HasMembers bool `db:"has_members"`
+	}
+	if err := r.db.GetContext(ctx, &result, query, projectID, userID); err != nil {
+		return false, false, fmt.Errorf("failed to check project membership: %w", err)
+	}
+
+	return result.Member, result.HasMembers, nil
+}
+
+// MemberRole returns the role of a user in a project, or an empty string if the user is not a member
+func (r *ProjectRepository) MemberRole(ctx context.Context, projectID, userID string) (string, error) {
+	query := `SELECT role FROM project_members WHERE project_id = $1 AND user_id = $2`
+
+	var role string
+	err := r.db.GetContext(ctx, &role, query, projectID, userID)
+	if err == sql.ErrNoRows {
+		return "", nil
+	}
+	if err != nil {
+		return "", fmt.Errorf("failed to get project member role: %w", err)
+	}
+
+	return role, nil
+}
+
 // List retrieves all projects
 func (r *ProjectRepository) List(ctx context.Context) ([]*models.Project, error) {
 	query := `
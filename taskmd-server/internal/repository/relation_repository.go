@@ -0,0 +1,201 @@
+package repository
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/jmoiron/sqlx"
+	"github.com/lib/pq"
+	"github.com/tktomaru/taskai/taskai-server/internal/models"
+)
+
+// RelationRepository handles task relation and reference data access
+type RelationRepository struct {
+	db DBTX
+}
+
+// NewRelationRepository creates a new relation repository
+func NewRelationRepository(db *sqlx.DB) *RelationRepository {
+	return &RelationRepository{db: db}
+}
+
+// WithTx returns a relation repository that runs its queries inside the given transaction
+func (r *RelationRepository) WithTx(tx *sqlx.Tx) *RelationRepository {
+	return &RelationRepository{db: tx}
+}
+
+// ClearMarkdownReferences removes the relations and dangling references extracted from a task's Markdown body.
+// Manually created relations are kept.
+func (r *RelationRepository) ClearMarkdownReferences(ctx context.Context, sourceTaskID string) error {
+	query := `
+		DELETE FROM task_relations
+		WHERE source_task_id = $1 AND origin = 'markdown'
+	`
+
+	if _, err := r.db.ExecContext(ctx, query, sourceTaskID); err != nil {
+		return fmt.Errorf("failed to clear task references: %w", err)
+	}
+
+	query = `
+		DELETE FROM task_dangling_references
+		WHERE source_task_id = $1
+	`
+
+	if _, err := r.db.ExecContext(ctx, query, sourceTaskID); err != nil {
+		return fmt.Errorf("failed to clear dangling references: %w", err)
+	}
+
+	return nil
+}
+
+// AddMarkdownReference stores a reference extracted from a task's Markdown body.
+// References to active tasks become relations; references to missing or deleted
+// tasks are recorded as dangling. It reports whether the reference is dangling.
+func (r *RelationRepository) AddMarkdownReference(ctx context.Context, sourceTaskID, targetTaskID string, relationType models.RelationType, createdBy *string) (bool, error) {
+	query := `
+		INSERT INTO task_relations (source_task_id, target_task_id, relation_type, origin, created_by)
+		SELECT $1, id, $3, 'markdown', $4
+		FROM tasks
+		WHERE id = $2 AND archived_at IS NULL
+		ON CONFLICT DO NOTHING
+	`
+
+	if _, err := r.db.ExecContext(ctx, query, sourceTaskID, targetTaskID, relationType, createdBy); err != nil {
+		return false, fmt.Errorf("failed to add task reference: %w", err)
+	}
+
+	query = `
+		INSERT INTO task_dangling_references (source_task_id, target_task_id, relation_type, reason)
+		SELECT $1, $2, $3,
+			CASE WHEN EXISTS (SELECT 1 FROM tasks WHERE id = $2) THEN 'deleted' ELSE 'missing' END
+		WHERE NOT EXISTS (SELECT 1 FROM tasks WHERE id = $2 AND archived_at IS NULL)
+		ON CONFLICT DO NOTHING
+	`
+
+	result, err := r.db.ExecContext(ctx, query, sourceTaskID, targetTaskID, relationType)
+	if err != nil {
+		return false, fmt.Errorf("failed to add dangling reference: %w", err)
+	}
+
+	rows, err := result.RowsAffected()
+	if err != nil {
+		return false, fmt.Errorf("failed to get rows affected: %w", err)
+	}
+
+	return rows > 0, nil
+}
+
+// MarkTargetDeleted flags the Markdown references to a deleted task as dangling
+func (r *RelationRepository) MarkTargetDeleted(ctx context.Context, targetTaskID string) (int64, error) {
+	query := `
+		INSERT INTO task_dangling_references (source_task_id, target_task_id, relation_type, reason)
+		SELECT source_task_id, target_task_id, relation_type, 'deleted'
+		FROM task_relations
+		WHERE target_task_id = $1 AND origin = 'markdown'
+		ON CONFLICT (source_task_id, target_task_id, relation_type)
+		DO UPDATE SET reason = 'deleted', detected_at = NOW()
+	`
+
+	result, err := r.db.ExecContext(ctx, query, targetTaskID)
+	if err != nil {
+		return 0, fmt.Errorf("failed to flag dangling references: %w", err)
+	}
+
+	rows, err := result.RowsAffected()
+	if err != nil {
+		return 0, fmt.Errorf("failed to get rows affected: %w", err)
+	}
+
+	return rows, nil
+}
+
+// ResolveMissing turns the dangling references to a newly created task into relations
+func (r *RelationRepository) ResolveMissing(ctx context.Context, targetTaskID string) (int64, error) {
+	query := `
+		INSERT INTO task_relations (source_task_id, target_task_id, relation_type, origin)
+		SELECT source_task_id, target_task_id, relation_type, 'markdown'
+		FROM task_dangling_references
+		WHERE target_task_id = $1 AND source_task_id != target_task_id
+		ON CONFLICT DO NOTHING
+	`
+
+	if _, err := r.db.ExecContext(ctx, query, targetTaskID); err != nil {
+		return 0, fmt.Errorf("failed to resolve dangling references: %w", err)
+	}
+
+	query = `
+		DELETE FROM task_dangling_references
+		WHERE target_task_id = $1
+	`
+
+	result, err := r.db.ExecContext(ctx, query, targetTaskID)
+	if err != nil {
+		return 0, fmt.Errorf("failed to resolve dangling references: %w", err)
+	}
+
+	rows, err := result.RowsAffected()
+	if err != nil {
+		return 0, fmt.Errorf("failed to get rows affected: %w", err)
+	}
+
+	return rows, nil
+}
+
+// ListBacklinks retrieves the active tasks of a project that refer to a task
+func (r *RelationRepository) ListBacklinks(ctx context.Context, projectID, targetTaskID string) ([]*models.TaskBacklink, error) {
+	query := `
+		SELECT t.id AS task_id, t.project_id, t.title, t.status, r.relation_type, r.origin
+		FROM task_relations r
+		JOIN tasks t ON t.id = r.source_task_id
+		WHERE r.target_task_id = $1 AND t.project_id = $2 AND t.archived_at IS NULL
+		ORDER BY t.id, r.relation_type
+	`
+
+	backlinks := []*models.TaskBacklink{}
+	if err := r.db.SelectContext(ctx, &backlinks, query, targetTaskID, projectID); err != nil {
+		return nil, fmt.Errorf("failed to list backlinks: %w", err)
+	}
+
+	return backlinks, nil
+}
+
+// ListDangling retrieves the dangling references of the active tasks in a project
+func (r *RelationRepository) ListDangling(ctx context.Context, projectID string) ([]*models.DanglingReference, error) {
+	query := `
+		SELECT d.*
+		FROM task_dangling_references d
+		JOIN tasks t ON t.id = d.source_task_id
+		WHERE t.project_id = $1 AND t.archived_at IS NULL
+		ORDER BY d.source_task_id, d.target_task_id
+	`
+
+	refs := []*models.DanglingReference{}
+	if err := r.db.SelectContext(ctx, &refs, query, projectID); err != nil {
+		return nil, fmt.Errorf("failed to list dangling references: %w", err)
+	}
+
+	return refs, nil
+}
+
+// ListReferencedTasks retrieves the active tasks of a project that the given tasks refer to,
+// excluding the given tasks themselves
+func (r *RelationRepository) ListReferencedTasks(ctx context.Context, projectID string, sourceTaskIDs []string) ([]*models.Task, error) {
+	query := `
+		SELECT t.* FROM tasks t
+		WHERE t.id IN (
+			SELECT target_task_id FROM task_relations
+			WHERE source_task_id = ANY($1)
+		)
+			AND t.id != ALL($1)
+			AND t.project_id = $2
+			AND t.archived_at IS NULL
+		ORDER BY t.id
+	`
+
+	var tasks []*models.Task
+	if err := r.db.SelectContext(ctx, &tasks, query, pq.Array(sourceTaskIDs), projectID); err != nil {
+		return nil, fmt.Errorf("failed to list referenced tasks: %w", err)
+	}
+
+	return tasks, nil
+}
@@ -0,0 +1,198 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/jmoiron/sqlx"
+	"github.com/tktomaru/taskai/taskai-server/internal/models"
+)
+
+// ErrSeriesNotFound is returned when a series does not exist
+var ErrSeriesNotFound = errors.New("series not found")
+
+// SeriesRepository handles recurring task series data access
+type SeriesRepository struct {
+	db DBTX
+}
+
+// NewSeriesRepository creates a new series repository
+func NewSeriesRepository(db *sqlx.DB) *SeriesRepository {
+	return &SeriesRepository{db: db}
+}
+
+// WithTx returns a series repository that runs its queries inside the given transaction
+func (r *SeriesRepository) WithTx(tx *sqlx.Tx) *SeriesRepository {
+	return &SeriesRepository{db: tx}
+}
+
+// Create creates a new series
+func (r *SeriesRepository) Create(ctx context.Context, series *models.TaskSeries) error {
+	query := `
+		INSERT INTO task_series (
+			id, project_id, template_task_id, recurrence, template_markdown,
+			status, occurrence_count, next_run_at, last_instance_id, created_by
+		) VALUES (
+			:id, :project_id, :template_task_id, :recurrence, :template_markdown,
+			:status, :occurrence_count, :next_run_at, :last_instance_id, :created_by
+		)
+	`
+
+	_, err := r.db.NamedExecContext(ctx, query, series)
+	if err != nil {
+		return fmt.Errorf("failed to create series: %w", err)
+	}
+
+	return nil
+}
+
+// GetByID retrieves a series by ID
+func (r *SeriesRepository) GetByID(ctx context.Context, projectID, seriesID string) (*models.TaskSeries, error) {
+	query := `
+		SELECT * FROM task_series
+		WHERE id = $1 AND project_id = $2
+	`
+
+	var series models.TaskSeries
+	err := r.db.GetContext(ctx, &series, query, seriesID, projectID)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, ErrSeriesNotFound
+		}
+		return nil, fmt.Errorf("failed to get series: %w", err)
+	}
+
+	return &series, nil
+}
+
+// GetByTemplateTask retrieves the series declared by a template task
+func (r *SeriesRepository) GetByTemplateTask(ctx context.Context, taskID string) (*models.TaskSeries, error) {
+	query := `
+		SELECT * FROM task_series
+		WHERE template_task_id = $1
+	`
+
+	var series models.TaskSeries
+	err := r.db.GetContext(ctx, &series, query, taskID)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, ErrSeriesNotFound
+		}
+		return nil, fmt.Errorf("failed to get series: %w", err)
+	}
+
+	return &series, nil
+}
+
+// List retrieves all series for a project
+func (r *SeriesRepository) List(ctx context.Context, projectID string) ([]*models.TaskSeries, error) {
+	query := `
+		SELECT * FROM task_series
+		WHERE project_id = $1
+		ORDER BY created_at DESC
+	`
+
+	var series []*models.TaskSeries
+	err := r.db.SelectContext(ctx, &series, query, projectID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list series: %w", err)
+	}
+
+	return series, nil
+}
+
+// ListDue retrieves active series whose next occurrence is on or before the given date
+func (r *SeriesRepository) ListDue(ctx context.Context, now time.Time) ([]*models.TaskSeries, error) {
+	query := `
+		SELECT * FROM task_series
+		WHERE status = 'active'
+			AND next_run_at IS NOT NULL
+			AND next_run_at <= $1::date
+		ORDER BY next_run_at ASC
+	`
+
+	var series []*models.TaskSeries
+	err := r.db.SelectContext(ctx, &series, query, now.Format("2006-01-02"))
+	if err != nil {
+		return nil, fmt.Errorf("failed to list due series: %w", err)
+	}
+
+	return series, nil
+}
+
+// Update updates the rule and template of a series
+func (r *SeriesRepository) Update(ctx context.Context, series *models.TaskSeries) error {
+	query := `
+		UPDATE task_series SET
+			recurrence = :recurrence,
+			template_markdown = :template_markdown,
+			status = :status,
+			next_run_at = :next_run_at,
+			cancelled_at = :cancelled_at
+		WHERE id = :id AND project_id = :project_id
+	`
+
+	result, err := r.db.NamedExecContext(ctx, query, series)
+	if err != nil {
+		return fmt.Errorf("failed to update series: %w", err)
+	}
+
+	rows, err := result.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("failed to get rows affected: %w", err)
+	}
+
+	if rows == 0 {
+		return ErrSeriesNotFound
+	}
+
+	return nil
+}
+
+// ClaimOccurrence atomically advances a series past its current occurrence.
+// It returns false if another caller already claimed the occurrence.
+func (r *SeriesRepository) ClaimOccurrence(ctx context.Context, seriesID string, current time.Time, next *time.Time, status models.SeriesStatus) (bool, error) {
+	query := `
+		UPDATE task_series SET
+			next_run_at = $3,
+			status = $4,
+			occurrence_count = occurrence_count + 1
+		WHERE id = $1 AND next_run_at = $2::date AND status = 'active'
+	`
+
+	// Dates are passed as strings so the comparison does not depend on the session time zone
+	var nextDate *string
+	if next != nil {
+		d := next.Format("2006-01-02")
+		nextDate = &d
+	}
+
+	result, err := r.db.ExecContext(ctx, query, seriesID, current.Format("2006-01-02"), nextDate, status)
+	if err != nil {
+		return false, fmt.Errorf("failed to claim occurrence: %w", err)
+	}
+
+	rows, err := result.RowsAffected()
+	if err != nil {
+		return false, fmt.Errorf("failed to get rows affected: %w", err)
+	}
+
+	return rows == 1, nil
+}
+
+// SetLastInstance records the most recently generated task of a series
+func (r *SeriesRepository) SetLastInstance(ctx context.Context, seriesID, taskID string) error {
+	query := `
+		UPDATE task_series SET last_instance_id = $2 WHERE id = $1
+	`
+
+	_, err := r.db.ExecContext(ctx, query, seriesID, taskID)
+	if err != nil {
+		return fmt.Errorf("failed to set last instance: %w", err)
+	}
+
+	return nil
+}
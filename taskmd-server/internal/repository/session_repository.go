@@ -0,0 +1,205 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/jmoiron/sqlx"
+	"github.com/tktomaru/taskai/taskai-server/internal/models"
+)
+
+// ErrSessionNotFound is returned when a session does not exist
+var ErrSessionNotFound = errors.New("session not found")
+
+// SessionRepository handles login session data access
+type SessionRepository struct {
+	db *sqlx.DB
+}
+
+// NewSessionRepository creates a new session repository
+func NewSessionRepository(db *sqlx.DB) *SessionRepository {
+	return &SessionRepository{db: db}
+}
+
+// Create creates a new session
+func (r *SessionRepository) Create(ctx context.Context, session *models.Session) error {
+	query := `
+		INSERT INTO sessions (
+			id, user_id, refresh_token_hash, user_agent, ip_address, expires_at
+		) VALUES (
+			$1, $2, $3, $4, $5, $6
+		)
+		RETURNING created_at, last_used_at
+	`
+
+	err := r.db.QueryRowxContext(ctx, query,
+		session.ID,
+		session.UserID,
+		session.RefreshTokenHash,
+		session.UserAgent,
+		session.IPAddress,
+		session.ExpiresAt,
+	).Scan(&session.CreatedAt, &session.LastUsedAt)
+	if err != nil {
+		return fmt.Errorf("failed to create session: %w", err)
+	}
+
+	return nil
+}
+
+// GetByID retrieves a session
+func (r *SessionRepository) GetByID(ctx context.Context, sessionID string) (*models.Session, error) {
+	query := `SELECT * FROM sessions WHERE id = $1`
+
+	var session models.Session
+	err := r.db.GetContext(ctx, &session, query, sessionID)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, ErrSessionNotFound
+		}
+		return nil, fmt.Errorf("failed to get session: %w", err)
+	}
+
+	return &session, nil
+}
+
+// GetByRefreshToken retrieves the session holding a refresh token hash, either as its
+// current token or as the token it replaced. The second result tells which.
+func (r *SessionRepository) GetByRefreshToken(ctx context.Context, tokenHash string) (*models.Session, bool, error) {
+	query := `
+		SELECT * FROM sessions
+		WHERE refresh_token_hash = $1 OR previous_token_hash = $1
+		LIMIT 1
+	`
+
+	var session models.Session
+	err := r.db.GetContext(ctx, &session, query, tokenHash)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, false, ErrSessionNotFound
+		}
+		return nil, false, fmt.Errorf("failed to get session: %w", err)
+	}
+
+	return &session, session.RefreshTokenHash == tokenHash, nil
+}
+
+// Rotate replaces the refresh token of an active session. It fails with ErrSessionNotFound
+// when the session was revoked, has expired or its token was rotated concurrently.
+func (r *SessionRepository) Rotate(ctx context.Context, sessionID, currentHash, newHash string, expiresAt time.Time) error {
+	query := `
+		UPDATE sessions SET
+			refresh_token_hash = $3,
+			previous_token_hash = refresh_token_hash,
+			last_used_at = NOW(),
+			expires_at = $4
+		WHERE id = $1 AND refresh_token_hash = $2
+			AND revoked_at IS NULL AND expires_at > NOW()
+	`
+
+	result, err := r.db.ExecContext(ctx, query, sessionID, currentHash, newHash, expiresAt)
+	if err != nil {
+		return fmt.Errorf("failed to rotate refresh token: %w", err)
+	}
+
+	rows, err := result.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("failed to get rows affected: %w", err)
+	}
+
+	if rows == 0 {
+		return ErrSessionNotFound
+	}
+
+	return nil
+}
+
+// IsActive reports whether a session exists, has not expired and has not been revoked
+func (r *SessionRepository) IsActive(ctx context.Context, sessionID string) (bool, error) {
+	query := `
+		SELECT EXISTS (
+			SELECT 1 FROM sessions
+			WHERE id = $1 AND revoked_at IS NULL AND expires_at > NOW()
+		)
+	`
+
+	var active bool
+	if err := r.db.GetContext(ctx, &active, query, sessionID); err != nil {
+		return false, fmt.Errorf("failed to check session: %w", err)
+	}
+
+	return active, nil
+}
+
+// ListActive retrieves the active sessions of a user, most recently used first
+func (r *SessionRepository) ListActive(ctx context.Context, userID string) ([]*models.Session, error) {
+	query := `
+		SELECT * FROM sessions
+		WHERE user_id = $1 AND revoked_at IS NULL AND expires_at > NOW()
+		ORDER BY last_used_at DESC
+	`
+
+	sessions := []*models.Session{}
+	if err := r.db.SelectContext(ctx, &sessions, query, userID); err != nil {
+		return nil, fmt.Errorf("failed to list sessions: %w", err)
+	}
+
+	return sessions, nil
+}
+
+// Revoke revokes a session of a user
+func (r *SessionRepository) Revoke(ctx context.Context, userID, sessionID string) error {
+	query := `
+		UPDATE sessions SET revoked_at = NOW()
+		WHERE id = $1 AND user_id = $2 AND revoked_at IS NULL
+	`
+
+	result, err := r.db.ExecContext(ctx, query, sessionID, userID)
+	if err != nil {
+		return fmt.Errorf("failed to revoke session: %w", err)
+	}
+
+	rows, err := result.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("failed to get rows affected: %w", err)
+	}
+
+	if rows == 0 {
+		return ErrSessionNotFound
+	}
+
+	return nil
+}
+
+// RevokeAll revokes every active session of a user and returns how many were revoked
+func (r *SessionRepository) RevokeAll(ctx context.Context, userID string) (int64, error) {
+	query := `
+		UPDATE sessions SET revoked_at = NOW()
+		WHERE user_id = $1 AND revoked_at IS NULL
+	`
+
+	result, err := r.db.ExecContext(ctx, query, userID)
+	if err != nil {
+		return 0, fmt.Errorf("failed to revoke sessions: %w", err)
+	}
+
+	return result.RowsAffected()
+}
+
+// DeleteExpired removes sessions that expired or were revoked before the given time
+func (r *SessionRepository) DeleteExpired(ctx context.Context, before time.Time) (int64, error) {
+	query := `
+		DELETE FROM sessions
+		WHERE expires_at <= $1 OR revoked_at <= $1
+	`
+
+	result, err := r.db.ExecContext(ctx, query, before)
+	if err != nil {
+		return 0, fmt.Errorf("failed to delete expired sessions: %w", err)
+	}
+
+	return result.RowsAffected()
+}
@@ -0,0 +1,202 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+
+	"github.com/jmoiron/sqlx"
+	"github.com/lib/pq"
+	"github.com/tktomaru/taskai/taskai-server/internal/models"
+)
+
+// SubscriptionRepository handles subscription data access
+type SubscriptionRepository struct {
+	db *sqlx.DB
+}
+
+// NewSubscriptionRepository creates a new subscription repository
+func NewSubscriptionRepository(db *sqlx.DB) *SubscriptionRepository {
+	return &SubscriptionRepository{db: db}
+}
+
+// Watch creates or reactivates a subscription and updates its digest setting
+func (r *SubscriptionRepository) Watch(ctx context.Context, sub *models.Subscription) error {
+	query := `
+		INSERT INTO subscriptions (user_id, target_type, target_id, project_id, reason, active, digest)
+		VALUES (:user_id, :target_type, :target_id, :project_id, :reason, TRUE, :digest)
+		ON CONFLICT (user_id, target_type, target_id)
+		DO UPDATE SET active = TRUE, digest = EXCLUDED.digest
+	`
+
+	if _, err := r.db.NamedExecContext(ctx, query, sub); err != nil {
+		return fmt.Errorf("failed to watch %s: %w", sub.TargetType, err)
+	}
+
+	return nil
+}
+
+// Unwatch deactivates a subscription. An inactive row is stored even if the user
+// was not subscribed, so that automatic subscriptions are not created later.
+func (r *SubscriptionRepository) Unwatch(ctx context.Context, sub *models.Subscription) error {
+	query := `
+		INSERT INTO subscriptions (user_id, target_type, target_id, project_id, reason, active)
+		VALUES (:user_id, :target_type, :target_id, :project_id, :reason, FALSE)
+		ON CONFLICT (user_id, target_type, target_id)
+		DO UPDATE SET active = FALSE
+	`
+
+	if _, err := r.db.NamedExecContext(ctx, query, sub); err != nil {
+		return fmt.Errorf("failed to unwatch %s: %w", sub.TargetType, err)
+	}
+
+	return nil
+}
+
+// Subscribe creates an automatic subscription unless the user already has one or unwatched the target
+func (r *SubscriptionRepository) Subscribe(ctx context.Context, sub *models.Subscription) error {
+	query := `
+		INSERT INTO subscriptions (user_id, target_type, target_id, project_id, reason)
+		VALUES (:user_id, :target_type, :target_id, :project_id, :reason)
+		ON CONFLICT (user_id, target_type, target_id) DO NOTHING
+	`
+
+	if _, err := r.db.NamedExecContext(ctx, query, sub); err != nil {
+		return fmt.Errorf("failed to subscribe to %s: %w", sub.TargetType, err)
+	}
+
+	return nil
+}
+
+// Get retrieves the subscription of a user to a target
+func (r *SubscriptionRepository) Get(ctx context.Context, userID string, targetType models.SubscriptionTarget, targetID string) (*models.Subscription, error) {
+	query := `
+		SELECT * FROM subscriptions
+		WHERE user_id = $1 AND target_type = $2 AND target_id = $3
+	`
+
+	var sub models.Subscription
+	err := r.db.GetContext(ctx, &sub, query, userID, targetType, targetID)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, fmt.Errorf("subscription not found")
+		}
+		return nil, fmt.Errorf("failed to get subscription: %w", err)
+	}
+
+	return &sub, nil
+}
+
+// ListByUser retrieves the active subscriptions of a user
+func (r *SubscriptionRepository) ListByUser(ctx context.Context, userID string) ([]*models.Subscription, error) {
+	query := `
+		SELECT * FROM subscriptions
+		WHERE user_id = $1 AND active
+		ORDER BY project_id, target_type, target_id
+	`
+
+	subs := []*models.Subscription{}
+	if err := r.db.SelectContext(ctx, &subs, query, userID); err != nil {
+		return nil, fmt.Errorf("failed to list subscriptions: %w", err)
+	}
+
+	return subs, nil
+}
+
+// ListTaskWatchers retrieves the active subscriptions to a task and to its project.
+// Users who unwatched the task are excluded even if they watch the project.
+func (r *SubscriptionRepository) ListTaskWatchers(ctx context.Context, projectID, taskID string) ([]*models.Subscription, error) {
+	query := `
+		SELECT s.* FROM subscriptions s
+		WHERE s.active
+			AND (
+				(s.target_type = 'task' AND s.target_id = $2)
+				OR (s.target_type = 'project' AND s.target_id = $1)
+			)
+			AND NOT EXISTS (
+				SELECT 1 FROM subscriptions u
+				WHERE u.user_id = s.user_id
+					AND u.target_type = 'task' AND u.target_id = $2
+					AND NOT u.active
+			)
+		ORDER BY s.target_type, s.created_at
+	`
+
+	subs := []*models.Subscription{}
+	if err := r.db.SelectContext(ctx, &subs, query, projectID, taskID); err != nil {
+		return nil, fmt.Errorf("failed to list task watchers: %w", err)
+	}
+
+	return subs, nil
+}
+
+// ListViewWatchers retrieves the active subscriptions to the views of a project
+func (r *SubscriptionRepository) ListViewWatchers(ctx context.Context, projectID string) ([]*models.Subscription, error) {
+	query := `
+		SELECT * FROM subscriptions
+		WHERE project_id = $1 AND target_type = 'view' AND active
+		ORDER BY target_id, created_at
+	`
+
+	subs := []*models.Subscription{}
+	if err := r.db.SelectContext(ctx, &subs, query, projectID); err != nil {
+		return nil, fmt.Errorf("failed to list view watchers: %w", err)
+	}
+
+	return subs, nil
+}
+
+// ListProjectsWithWatchedViews returns the projects that have at least one watched view
+func (r *SubscriptionRepository) ListProjectsWithWatchedViews(ctx context.Context) ([]string, error) {
+	query := `
+		SELECT DISTINCT project_id FROM subscriptions
+		WHERE target_type = 'view' AND active
+	`
+
+	var projectIDs []string
+	if err := r.db.SelectContext(ctx, &projectIDs, query); err != nil {
+		return nil, fmt.Errorf("failed to list projects with watched views: %w", err)
+	}
+
+	return projectIDs, nil
+}
+
+// GetViewResults returns the last known task IDs in the result set of a watched view.
+// found is false if the view has not been checked yet.
+func (r *SubscriptionRepository) GetViewResults(ctx context.Context, viewID string) (taskIDs []string, found bool, err error) {
+	query := `
+		SELECT task_ids FROM view_watch_results
+		WHERE view_id = $1
+	`
+
+	var ids pq.StringArray
+	if err := r.db.GetContext(ctx, &ids, query, viewID); err != nil {
+		if err == sql.ErrNoRows {
+			return nil, false, nil
+		}
+		return nil, false, fmt.Errorf("failed to get view results: %w", err)
+	}
+
+	return ids, true, nil
+}
+
+// SaveViewResults stores the current result set of a watched view
+func (r *SubscriptionRepository) SaveViewResults(ctx context.Context, viewID string, taskIDs []string) error {
+	query := `
+		INSERT INTO view_watch_results (view_id, task_ids, checked_at)
+		VALUES ($1, $2, NOW())
+		ON CONFLICT (view_id)
+		DO UPDATE SET task_ids = EXCLUDED.task_ids, checked_at = NOW()
+	`
+
+	// A nil slice would be stored as NULL
+	if taskIDs == nil {
+		taskIDs = []string{}
+	}
+
+	if _, err := r.db.ExecContext(ctx, query, viewID, pq.Array(taskIDs)); err != nil {
+		return fmt.Errorf("failed to store view results: %w", err)
+	}
+
+	return nil
+}
@@ -3,15 +3,24 @@ package repository
 import (
 	"context"
 	"database/sql"
+	"errors"
 	"fmt"
+	"regexp"
 
 	"github.com/jmoiron/sqlx"
+	"github.com/lib/pq"
 	"github.com/tktomaru/taskai/taskai-server/internal/models"
 )
 
+// ErrTaskNotFound is returned when a task does not exist in the project
+var ErrTaskNotFound = errors.New("task not found")
+
+// ErrDuplicateIdempotencyKey is returned when a live task of the project already has the idempotency key
+var ErrDuplicateIdempotencyKey = errors.New("a task with this idempotency key already exists")
+
 // TaskRepository handles task data access
 type TaskRepository struct {
-	db *sqlx.DB
+	db DBTX
 }
 
 // NewTaskRepository creates a new task repository
@@ -19,15 +28,20 @@ func NewTaskRepository(db *sqlx.DB) *TaskRepository {
 	return &TaskRepository{db: db}
 }
 
+// WithTx returns a task repository that runs its queries inside the given transaction
+func (r *TaskRepository) WithTx(tx *sqlx.Tx) *TaskRepository {
+	return &TaskRepository{db: tx}
+}
+
 // Create creates a new task
 func (r *TaskRepository) Create(ctx context.Context, task *models.Task) error {
 	query := `
 		INSERT INTO tasks (
-			id, project_id, parent_id, title, status, priority,
+			id, project_id, parent_id, series_id, title, status, priority,
 			assignees, labels, start_date, due_date,
 			markdown_body, extra_meta, created_by, updated_by
 		) VALUES (
-			:id, :project_id, :parent_id, :title, :status, :priority,
+			:id, :project_id, :parent_id, :series_id, :title, :status, :priority,
 			:assignees, :labels, :start_date, :due_date,
 			:markdown_body, :extra_meta, :created_by, :updated_by
 		)
@@ -35,12 +49,36 @@ func (r *TaskRepository) Create(ctx context.Context, task *models.Task) error {
 
 	_, err := r.db.NamedExecContext(ctx, query, task)
 	if err != nil {
+		var pqErr *pq.Error
+		if errors.As(err, &pqErr) && pqErr.Constraint == "idx_tasks_idempotency_key" {
+			return ErrDuplicateIdempotencyKey
+		}
 		return fmt.Errorf("failed to create task: %w", err)
 	}
 
 	return nil
 }
 
+// GetByIdempotencyKey retrieves the live task of a project whose extra_meta holds the idempotency key
+func (r *TaskRepository) GetByIdempotencyKey(ctx context.Context, projectID, key string) (*models.Task, error) {
+	query := `
+		SELECT * FROM tasks
+		WHERE project_id = $1 AND archived_at IS NULL
+			AND extra_meta ? 'idempotency_key' AND extra_meta->>'idempotency_key' = $2
+	`
+
+	var task models.Task
+	err := r.db.GetContext(ctx, &task, query, projectID, key)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, ErrTaskNotFound
+		}
+		return nil, fmt.Errorf("failed to get task: %w", err)
+	}
+
+	return &task, nil
+}
+
 // GetByID retrieves a task by ID
 func (r *TaskRepository) GetByID(ctx context.Context, projectID, taskID string) (*models.Task, error) {
 	query := `
@@ -52,7 +90,27 @@ func (r *TaskRepository) GetByID(ctx context.Context, projectID, taskID string)
 	err := r.db.GetContext(ctx, &task, query, taskID, projectID)
 	if err != nil {
 		if err == sql.ErrNoRows {
-			return nil, fmt.Errorf("task not found")
+			return nil, ErrTaskNotFound
+		}
+		return nil, fmt.Errorf("failed to get task: %w", err)
+	}
+
+	return &task, nil
+}
+
+// GetByIDForUpdate retrieves a task by ID and locks its row until the transaction ends
+func (r *TaskRepository) GetByIDForUpdate(ctx context.Context, projectID, taskID string) (*models.Task, error) {
+	query := `
+		SELECT * FROM tasks
+		WHERE id = $1 AND project_id = $2 AND archived_at IS NULL
+		FOR UPDATE
+	`
+
+	var task models.Task
+	err := r.db.GetContext(ctx, &task, query, taskID, projectID)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, ErrTaskNotFound
 		}
 		return nil, fmt.Errorf("failed to get task: %w", err)
 	}
@@ -120,6 +178,7 @@ func (r *TaskRepository) Update(ctx context.Context, task *models.Task) error {
 	query := `
 		UPDATE tasks SET
 			parent_id = :parent_id,
+			series_id = :series_id,
 			title = :title,
 			status = :status,
 			priority = :priority,
@@ -175,6 +234,30 @@ func (r *TaskRepository) Delete(ctx context.Context, projectID, taskID string) e
 	return nil
 }
 
+// Move moves a task to another project
+func (r *TaskRepository) Move(ctx context.Context, projectID, taskID, targetProjectID string) error {
+	query := `
+		UPDATE tasks SET project_id = $3, updated_at = NOW()
+		WHERE id = $1 AND project_id = $2 AND archived_at IS NULL
+	`
+
+	result, err := r.db.ExecContext(ctx, query, taskID, projectID, targetProjectID)
+	if err != nil {
+		return fmt.Errorf("failed to move task: %w", err)
+	}
+
+	rows, err := result.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("failed to get rows affected: %w", err)
+	}
+
+	if rows == 0 {
+		return fmt.Errorf("task not found")
+	}
+
+	return nil
+}
+
 // Search performs full-text search on tasks
 func (r *TaskRepository) Search(ctx context.Context, projectID, searchQuery string, limit int) ([]*models.Task, error) {
 	query := `
@@ -195,6 +278,78 @@ func (r *TaskRepository) Search(ctx context.Context, projectID, searchQuery stri
 	return tasks, nil
 }
 
+// NextID returns the next free task ID for a prefix (e.g. "T" -> "T-1043").
+// Task IDs are global, so all projects are considered. The prefix is locked until the
+// transaction ends, so callers that create the task in the same transaction cannot be
+// handed the same ID.
+func (r *TaskRepository) NextID(ctx context.Context, prefix string) (string, error) {
+	lock := `SELECT pg_advisory_xact_lock(hashtext('task_id:' || $1))`
+	if _, err := r.db.ExecContext(ctx, lock, prefix); err != nil {
+		return "", fmt.Errorf("failed to lock task ID prefix: %w", err)
+	}
+
+	query := `
+		SELECT COALESCE(MAX(CAST(SUBSTRING(id FROM '-([0-9]+)$') AS BIGINT)), 0)
+		FROM tasks
+		WHERE id ~ $1
+	`
+
+	var maxNum int64
+	err := r.db.GetContext(ctx, &maxNum, query, "^"+regexp.QuoteMeta(prefix)+"-[0-9]+$")
+	if err != nil {
+		return "", fmt.Errorf("failed to get next task ID: %w", err)
+	}
+
+	return fmt.Sprintf("%s-%d", prefix, maxNum+1), nil
+}
+
+// Exists reports whether a task exists. Task IDs are global, so all projects are considered.
+func (r *TaskRepository) Exists(ctx context.Context, taskID string) (bool, error) {
+	query := `
+		SELECT EXISTS (SELECT 1 FROM tasks WHERE id = $1 AND archived_at IS NULL)
+	`
+
+	var exists bool
+	if err := r.db.GetContext(ctx, &exists, query, taskID); err != nil {
+		return false, fmt.Errorf("failed to check task: %w", err)
+	}
+
+	return exists, nil
+}
+
+// ListIDPrefixes returns the distinct prefixes of task IDs (e.g. "T" for "T-1042")
+func (r *TaskRepository) ListIDPrefixes(ctx context.Context) ([]string, error) {
+	query := `
+		SELECT DISTINCT SUBSTRING(id FROM '^(.*)-[0-9]+$')
+		FROM tasks
+		WHERE id ~ '^[A-Z]+-[0-9]+$'
+	`
+
+	var prefixes []string
+	if err := r.db.SelectContext(ctx, &prefixes, query); err != nil {
+		return nil, fmt.Errorf("failed to list task ID prefixes: %w", err)
+	}
+
+	return prefixes, nil
+}
+
+// ListBySeries retrieves all tasks generated by a recurring series
+func (r *TaskRepository) ListBySeries(ctx context.Context, projectID, seriesID string) ([]*models.Task, error) {
+	query := `
+		SELECT * FROM tasks
+		WHERE project_id = $1 AND series_id = $2 AND archived_at IS NULL
+		ORDER BY COALESCE(start_date, due_date) ASC, created_at ASC
+	`
+
+	var tasks []*models.Task
+	err := r.db.SelectContext(ctx, &tasks, query, projectID, seriesID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list series tasks: %w", err)
+	}
+
+	return tasks, nil
+}
+
 // TaskFilters represents filters for task listing
 type TaskFilters struct {
 	Statuses   []string
@@ -0,0 +1,137 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+
+	"github.com/jmoiron/sqlx"
+	"github.com/tktomaru/taskai/taskai-server/internal/models"
+)
+
+// TemplateRepository handles task template data access
+type TemplateRepository struct {
+	db *sqlx.DB
+}
+
+// NewTemplateRepository creates a new template repository
+func NewTemplateRepository(db *sqlx.DB) *TemplateRepository {
+	return &TemplateRepository{db: db}
+}
+
+// Create creates a new template
+func (r *TemplateRepository) Create(ctx context.Context, template *models.TaskTemplate) error {
+	query := `
+		INSERT INTO task_templates (
+			id, project_id, name, description, markdown, id_prefix, created_by
+		) VALUES (
+			:id, :project_id, :name, :description, :markdown, :id_prefix, :created_by
+		)
+	`
+
+	_, err := r.db.NamedExecContext(ctx, query, template)
+	if err != nil {
+		return fmt.Errorf("failed to create template: %w", err)
+	}
+
+	return nil
+}
+
+// GetByID retrieves a template by ID
+func (r *TemplateRepository) GetByID(ctx context.Context, projectID, templateID string) (*models.TaskTemplate, error) {
+	query := `
+		SELECT * FROM task_templates
+		WHERE id = $1 AND project_id = $2
+	`
+
+	var template models.TaskTemplate
+	err := r.db.GetContext(ctx, &template, query, templateID, projectID)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, fmt.Errorf("template not found")
+		}
+		return nil, fmt.Errorf("failed to get template: %w", err)
+	}
+
+	return &template, nil
+}
+
+// List retrieves all templates for a project
+func (r *TemplateRepository) List(ctx context.Context, projectID string) ([]*models.TaskTemplate, error) {
+	query := `
+		SELECT * FROM task_templates
+		WHERE project_id = $1
+		ORDER BY name ASC
+	`
+
+	var templates []*models.TaskTemplate
+	err := r.db.SelectContext(ctx, &templates, query, projectID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list templates: %w", err)
+	}
+
+	return templates, nil
+}
+
+// Update updates an existing template
+func (r *TemplateRepository) Update(ctx context.Context, template *models.TaskTemplate) error {
+	query := `
+		UPDATE task_templates SET
+			name = :name,
+			description = :description,
+			markdown = :markdown,
+			id_prefix = :id_prefix
+		WHERE id = :id AND project_id = :project_id
+	`
+
+	result, err := r.db.NamedExecContext(ctx, query, template)
+	if err != nil {
+		return fmt.Errorf("failed to update template: %w", err)
+	}
+
+	rows, err := result.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("failed to get rows affected: %w", err)
+	}
+
+	if rows == 0 {
+		return fmt.Errorf("template not found")
+	}
+
+	return nil
+}
+
+// Delete deletes a template
+func (r *TemplateRepository) Delete(ctx context.Context, projectID, templateID string) error {
+	query := `
+		DELETE FROM task_templates
+		WHERE id = $1 AND project_id = $2
+	`
+
+	result, err := r.db.ExecContext(ctx, query, templateID, projectID)
+	if err != nil {
+		return fmt.Errorf("failed to delete template: %w", err)
+	}
+
+	rows, err := result.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("failed to get rows affected: %w", err)
+	}
+
+	if rows == 0 {
+		return fmt.Errorf("template not found")
+	}
+
+	return nil
+}
+
+// CreateDefaults (re)creates the default bug, feature and spike templates of a project.
+// Existing templates are left untouched.
+func (r *TemplateRepository) CreateDefaults(ctx context.Context, projectID string) error {
+	_, err := r.db.ExecContext(ctx, `SELECT create_default_task_templates($1)`, projectID)
+	if err != nil {
+		return fmt.Errorf("failed to create default templates: %w", err)
+	}
+
+	return nil
+}
@@ -0,0 +1,184 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/jmoiron/sqlx"
+	"github.com/lib/pq"
+)
+
+// ErrTwoFactorState is returned when two-factor authentication of a user is not in the
+// state an operation requires, e.g. enabling it twice
+var ErrTwoFactorState = errors.New("two-factor authentication is not in the expected state")
+
+// TwoFactorRepository handles TOTP secret and recovery code data access
+type TwoFactorRepository struct {
+	db *sqlx.DB
+}
+
+// NewTwoFactorRepository creates a new two-factor repository
+func NewTwoFactorRepository(db *sqlx.DB) *TwoFactorRepository {
+	return &TwoFactorRepository{db: db}
+}
+
+// SetPendingSecret stores a TOTP secret that still has to be confirmed with a code.
+// It fails with ErrTwoFactorState when two-factor authentication is already enabled.
+func (r *TwoFactorRepository) SetPendingSecret(ctx context.Context, userID, secret string) error {
+	query := `
+		UPDATE users SET totp_secret = $2, totp_last_step = NULL
+		WHERE id = $1 AND totp_enabled_at IS NULL
+	`
+
+	result, err := r.db.ExecContext(ctx, query, userID, secret)
+	if err != nil {
+		return fmt.Errorf("failed to store TOTP secret: %w", err)
+	}
+
+	rows, err := result.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("failed to get rows affected: %w", err)
+	}
+
+	if rows == 0 {
+		return ErrTwoFactorState
+	}
+
+	return nil
+}
+
+// Enable turns on two-factor authentication with the pending secret, records the time
+// step of the confirming code and stores the first recovery codes
+func (r *TwoFactorRepository) Enable(ctx context.Context, userID string, step int64, codeHashes []string) error {
+	query := `
+		WITH enabled AS (
+			UPDATE users SET totp_enabled_at = NOW(), totp_last_step = $2
+			WHERE id = $1 AND totp_secret IS NOT NULL AND totp_enabled_at IS NULL
+			RETURNING id
+		), removed AS (
+			DELETE FROM recovery_codes WHERE user_id IN (SELECT id FROM enabled)
+		)
+		INSERT INTO recovery_codes (user_id, code_hash)
+		SELECT enabled.id, code_hash FROM enabled, unnest($3::text[]) AS code_hash
+	`
+
+	result, err := r.db.ExecContext(ctx, query, userID, step, pq.Array(codeHashes))
+	if err != nil {
+		return fmt.Errorf("failed to enable two-factor authentication: %w", err)
+	}
+
+	rows, err := result.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("failed to get rows affected: %w", err)
+	}
+
+	if rows == 0 {
+		return ErrTwoFactorState
+	}
+
+	return nil
+}
+
+// Disable turns off two-factor authentication and removes the secret and recovery codes
+func (r *TwoFactorRepository) Disable(ctx context.Context, userID string) error {
+	query := `
+		WITH removed AS (
+			DELETE FROM recovery_codes WHERE user_id = $1
+		)
+		UPDATE users SET totp_secret = NULL, totp_enabled_at = NULL, totp_last_step = NULL
+		WHERE id = $1
+	`
+
+	if _, err := r.db.ExecContext(ctx, query, userID); err != nil {
+		return fmt.Errorf("failed to disable two-factor authentication: %w", err)
+	}
+
+	return nil
+}
+
+// UseStep records that the code of a time step was accepted. It returns false when a
+// code of that or a later step was accepted before, i.e. the code is being replayed.
+func (r *TwoFactorRepository) UseStep(ctx context.Context, userID string, step int64) (bool, error) {
+	query := `
+		UPDATE users SET totp_last_step = $2
+		WHERE id = $1 AND totp_enabled_at IS NOT NULL
+			AND (totp_last_step IS NULL OR totp_last_step < $2)
+	`
+
+	result, err := r.db.ExecContext(ctx, query, userID, step)
+	if err != nil {
+		return false, fmt.Errorf("failed to record TOTP code: %w", err)
+	}
+
+	rows, err := result.RowsAffected()
+	if err != nil {
+		return false, fmt.Errorf("failed to get rows affected: %w", err)
+	}
+
+	return rows > 0, nil
+}
+
+// ReplaceRecoveryCodes replaces all recovery codes of a user with enabled two-factor authentication
+func (r *TwoFactorRepository) ReplaceRecoveryCodes(ctx context.Context, userID string, codeHashes []string) error {
+	query := `
+		WITH owner AS (
+			SELECT id FROM users WHERE id = $1 AND totp_enabled_at IS NOT NULL
+		), removed AS (
+			DELETE FROM recovery_codes WHERE user_id IN (SELECT id FROM owner)
+		)
+		INSERT INTO recovery_codes (user_id, code_hash)
+		SELECT owner.id, code_hash FROM owner, unnest($2::text[]) AS code_hash
+	`
+
+	result, err := r.db.ExecContext(ctx, query, userID, pq.Array(codeHashes))
+	if err != nil {
+		return fmt.Errorf("failed to replace recovery codes: %w", err)
+	}
+
+	rows, err := result.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("failed to get rows affected: %w", err)
+	}
+
+	if rows == 0 {
+		return ErrTwoFactorState
+	}
+
+	return nil
+}
+
+// UseRecoveryCode marks an unused recovery code as used. It returns false when the code
+// is unknown or has been used before.
+func (r *TwoFactorRepository) UseRecoveryCode(ctx context.Context, userID, codeHash string) (bool, error) {
+	query := `
+		UPDATE recovery_codes SET used_at = NOW()
+		WHERE user_id = $1 AND code_hash = $2 AND used_at IS NULL
+	`
+
+	result, err := r.db.ExecContext(ctx, query, userID, codeHash)
+	if err != nil {
+		return false, fmt.Errorf("failed to use recovery code: %w", err)
+	}
+
+	rows, err := result.RowsAffected()
+	if err != nil {
+		return false, fmt.Errorf("failed to get rows affected: %w", err)
+	}
+
+	return rows > 0, nil
+}
+
+// CountRecoveryCodes returns how many unused recovery codes a user has left
+func (r *TwoFactorRepository) CountRecoveryCodes(ctx context.Context, userID string) (int, error) {
+	query := `
+		SELECT COUNT(*) FROM recovery_codes WHERE user_id = $1 AND used_at IS NULL
+	`
+
+	var count int
+	if err := r.db.GetContext(ctx, &count, query, userID); err != nil {
+		return 0, fmt.Errorf("failed to count recovery codes: %w", err)
+	}
+
+	return count, nil
+}
@@ -23,9 +23,11 @@ func NewUserRepository(db *sqlx.DB) *UserRepository {
 func (r *UserRepository) Create(ctx context.Context, user *models.User) error {
 	query := `
 		INSERT INTO users (
-			id, email, name, avatar_url, password_hash, preferences
+			id, email, name, avatar_url, password_hash, oidc_provider, oidc_subject, preferences,
+			service_project_id
 		) VALUES (
-			:id, :email, :name, :avatar_url, :password_hash, :preferences
+			:id, :email, :name, :avatar_url, :password_hash, :oidc_provider, :oidc_subject, :preferences,
+			:service_project_id
 		)
 	`
 
@@ -73,6 +75,113 @@ func (r *UserRepository) GetByEmail(ctx context.Context, email string) (*models.
 	return &user, nil
 }
 
+// GetByOIDCSubject retrieves the user linked to a subject of an OIDC issuer
+func (r *UserRepository) GetByOIDCSubject(ctx context.Context, provider, subject string) (*models.User, error) {
+	query := `
+		SELECT * FROM users WHERE oidc_provider = $1 AND oidc_subject = $2
+	`
+
+	var user models.User
+	err := r.db.GetContext(ctx, &user, query, provider, subject)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, fmt.Errorf("user not found")
+		}
+		return nil, fmt.Errorf("failed to get user: %w", err)
+	}
+
+	return &user, nil
+}
+
+// UpdateOIDCProfile links a user to an OIDC subject and refreshes the profile claimed by the issuer
+func (r *UserRepository) UpdateOIDCProfile(ctx context.Context, user *models.User) error {
+	query := `
+		UPDATE users SET
+			email = :email,
+			name = :name,
+			avatar_url = :avatar_url,
+			oidc_provider = :oidc_provider,
+			oidc_subject = :oidc_subject
+		WHERE id = :id
+	`
+
+	if _, err := r.db.NamedExecContext(ctx, query, user); err != nil {
+		return fmt.Errorf("failed to update user: %w", err)
+	}
+
+	return nil
+}
+
+// ListServiceAccounts retrieves the service accounts of a project
+func (r *UserRepository) ListServiceAccounts(ctx context.Context, projectID string) ([]*models.User, error) {
+	query := `
+		SELECT * FROM users WHERE service_project_id = $1 ORDER BY created_at
+	`
+
+	users := []*models.User{}
+	if err := r.db.SelectContext(ctx, &users, query, projectID); err != nil {
+		return nil, fmt.Errorf("failed to list service accounts: %w", err)
+	}
+
+	return users, nil
+}
+
+// DeleteServiceAccount deletes a service account of a project, along with its tokens
+func (r *UserRepository) DeleteServiceAccount(ctx context.Context, projectID, userID string) error {
+	query := `
+		DELETE FROM users WHERE id = $1 AND service_project_id = $2
+	`
+
+	result, err := r.db.ExecContext(ctx, query, userID, projectID)
+	if err != nil {
+		return fmt.Errorf("failed to delete service account: %w", err)
+	}
+
+	rows, err := result.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("failed to get rows affected: %w", err)
+	}
+
+	if rows == 0 {
+		return fmt.Errorf("service account not found")
+	}
+
+	return nil
+}
+
+// UpdatePassword replaces the password hash of a user
+func (r *UserRepository) UpdatePassword(ctx context.Context, userID, passwordHash string) error {
+	query := `
+		UPDATE users SET password_hash = $2 WHERE id = $1
+	`
+
+	if _, err := r.db.ExecContext(ctx, query, userID, passwordHash); err != nil {
+		return fmt.Errorf("failed to update password: %w", err)
+	}
+
+	return nil
+}
+
+// MarkEmailVerified records that a user owns an email address, if it is still their address
+func (r *UserRepository) MarkEmailVerified(ctx context.Context, userID, email string) (bool, error) {
+	query := `
+		UPDATE users SET email_verified_at = COALESCE(email_verified_at, NOW())
+		WHERE id = $1 AND email = $2
+	`
+
+	result, err := r.db.ExecContext(ctx, query, userID, email)
+	if err != nil {
+		return false, fmt.Errorf("failed to verify email: %w", err)
+	}
+
+	rows, err := result.RowsAffected()
+	if err != nil {
+		return false, fmt.Errorf("failed to get rows affected: %w", err)
+	}
+
+	return rows > 0, nil
+}
+
 // UpdateLastLogin updates the last login timestamp
 func (r *UserRepository) UpdateLastLogin(ctx context.Context, userID string) error {
 	query := `
@@ -0,0 +1,307 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"time"
+
+	"github.com/jmoiron/sqlx"
+	"github.com/tktomaru/taskai/taskai-server/internal/models"
+)
+
+// WebhookRepository handles webhook registrations and their delivery queue
+type WebhookRepository struct {
+	db *sqlx.DB
+}
+
+// NewWebhookRepository creates a new webhook repository
+func NewWebhookRepository(db *sqlx.DB) *WebhookRepository {
+	return &WebhookRepository{db: db}
+}
+
+// Create creates a new webhook
+func (r *WebhookRepository) Create(ctx context.Context, webhook *models.Webhook) error {
+	query := `
+		INSERT INTO webhooks (
+			id, project_id, url, events, secret, description, active, created_by
+		) VALUES (
+			:id, :project_id, :url, :events, :secret, :description, :active, :created_by
+		)
+		RETURNING created_at, updated_at
+	`
+
+	rows, err := r.db.NamedQueryContext(ctx, query, webhook)
+	if err != nil {
+		return fmt.Errorf("failed to create webhook: %w", err)
+	}
+	defer rows.Close()
+
+	if rows.Next() {
+		if err := rows.Scan(&webhook.CreatedAt, &webhook.UpdatedAt); err != nil {
+			return fmt.Errorf("failed to create webhook: %w", err)
+		}
+	}
+
+	return nil
+}
+
+// GetByID retrieves a webhook of a project
+func (r *WebhookRepository) GetByID(ctx context.Context, projectID, webhookID string) (*models.Webhook, error) {
+	query := `
+		SELECT * FROM webhooks
+		WHERE id = $1 AND project_id = $2
+	`
+
+	var webhook models.Webhook
+	err := r.db.GetContext(ctx, &webhook, query, webhookID, projectID)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, fmt.Errorf("webhook not found")
+		}
+		return nil, fmt.Errorf("failed to get webhook: %w", err)
+	}
+
+	return &webhook, nil
+}
+
+// List retrieves the webhooks of a project
+func (r *WebhookRepository) List(ctx context.Context, projectID string) ([]*models.Webhook, error) {
+	query := `
+		SELECT * FROM webhooks
+		WHERE project_id = $1
+		ORDER BY created_at ASC
+	`
+
+	webhooks := []*models.Webhook{}
+	if err := r.db.SelectContext(ctx, &webhooks, query, projectID); err != nil {
+		return nil, fmt.Errorf("failed to list webhooks: %w", err)
+	}
+
+	return webhooks, nil
+}
+
+// ListSubscribed retrieves the active webhooks of a project that subscribe to an event type
+func (r *WebhookRepository) ListSubscribed(ctx context.Context, projectID, eventType string) ([]*models.Webhook, error) {
+	query := `
+		SELECT * FROM webhooks
+		WHERE project_id = $1 AND active AND $2 = ANY(events)
+	`
+
+	webhooks := []*models.Webhook{}
+	if err := r.db.SelectContext(ctx, &webhooks, query, projectID, eventType); err != nil {
+		return nil, fmt.Errorf("failed to list subscribed webhooks: %w", err)
+	}
+
+	return webhooks, nil
+}
+
+// Update updates an existing webhook
+func (r *WebhookRepository) Update(ctx context.Context, webhook *models.Webhook) error {
+	query := `
+		UPDATE webhooks SET
+			url = :url,
+			events = :events,
+			secret = :secret,
+			description = :description,
+			active = :active
+		WHERE id = :id AND project_id = :project_id
+	`
+
+	result, err := r.db.NamedExecContext(ctx, query, webhook)
+	if err != nil {
+		return fmt.Errorf("failed to update webhook: %w", err)
+	}
+
+	rows, err := result.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("failed to get rows affected: %w", err)
+	}
+
+	if rows == 0 {
+		return fmt.Errorf("webhook not found")
+	}
+
+	return nil
+}
+
+// Delete deletes a webhook together with its deliveries
+func (r *WebhookRepository) Delete(ctx context.Context, projectID, webhookID string) error {
+	query := `
+		DELETE FROM webhooks
+		WHERE id = $1 AND project_id = $2
+	`
+
+	result, err := r.db.ExecContext(ctx, query, webhookID, projectID)
+	if err != nil {
+		return fmt.Errorf("failed to delete webhook: %w", err)
+	}
+
+	rows, err := result.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("failed to get rows affected: %w", err)
+	}
+
+	if rows == 0 {
+		return fmt.Errorf("webhook not found")
+	}
+
+	return nil
+}
+
+// Enqueue adds a delivery to the queue and sets its ID
+func (r *WebhookRepository) Enqueue(ctx context.Context, delivery *models.WebhookDelivery) error {
+	query := `
+		INSERT INTO webhook_deliveries (webhook_id, event_type, payload, redelivery_of)
+		VALUES ($1, $2, $3, $4)
+		RETURNING id, status, attempts, next_attempt_at, created_at
+	`
+
+	err := r.db.QueryRowxContext(ctx, query,
+		delivery.WebhookID,
+		delivery.EventType,
+		[]byte(delivery.Payload),
+		delivery.RedeliveryOf,
+	).Scan(&delivery.ID, &delivery.Status, &delivery.Attempts, &delivery.NextAttemptAt, &delivery.CreatedAt)
+	if err != nil {
+		return fmt.Errorf("failed to enqueue webhook delivery: %w", err)
+	}
+
+	return nil
+}
+
+// ClaimDue claims pending deliveries of active webhooks that are due. Claimed deliveries are
+// pushed back by the lease so that concurrent workers skip them and a crashed worker's
+// deliveries are retried once the lease expires.
+func (r *WebhookRepository) ClaimDue(ctx context.Context, limit int, lease time.Duration) ([]*models.WebhookDelivery, error) {
+	query := `
+		UPDATE webhook_deliveries SET
+			next_attempt_at = NOW() + ($2 * INTERVAL '1 second')
+		WHERE id IN (
+			SELECT d.id FROM webhook_deliveries d
+			JOIN webhooks w ON w.id = d.webhook_id
+			WHERE d.status = 'pending' AND d.next_attempt_at <= NOW() AND w.active
+			ORDER BY d.next_attempt_at ASC
+			LIMIT $1
+			FOR UPDATE OF d SKIP LOCKED
+		)
+		RETURNING *
+	`
+
+	deliveries := []*models.WebhookDelivery{}
+	if err := r.db.SelectContext(ctx, &deliveries, query, limit, lease.Seconds()); err != nil {
+		return nil, fmt.Errorf("failed to claim webhook deliveries: %w", err)
+	}
+
+	return deliveries, nil
+}
+
+// RecordAttempt stores the outcome of an attempt in the delivery log and updates the delivery
+func (r *WebhookRepository) RecordAttempt(ctx context.Context, delivery *models.WebhookDelivery, attempt *models.WebhookDeliveryAttempt) error {
+	query := `
+		WITH logged AS (
+			INSERT INTO webhook_delivery_attempts (
+				delivery_id, attempt, response_status, response_body, error, duration_ms
+			) VALUES (
+				$1, $2, $3, $4, $5, $6
+			)
+		)
+		UPDATE webhook_deliveries SET
+			status = $7,
+			attempts = $2,
+			next_attempt_at = $8,
+			response_status = $3,
+			last_error = $5,
+			delivered_at = $9
+		WHERE id = $1
+	`
+
+	_, err := r.db.ExecContext(ctx, query,
+		delivery.ID,
+		attempt.Attempt,
+		attempt.ResponseStatus,
+		attempt.ResponseBody,
+		attempt.Error,
+		attempt.DurationMs,
+		delivery.Status,
+		delivery.NextAttemptAt,
+		delivery.DeliveredAt,
+	)
+	if err != nil {
+		return fmt.Errorf("failed to record webhook delivery attempt: %w", err)
+	}
+
+	delivery.Attempts = attempt.Attempt
+	delivery.ResponseStatus = attempt.ResponseStatus
+	delivery.LastError = attempt.Error
+
+	return nil
+}
+
+// GetDelivery retrieves a delivery of a webhook
+func (r *WebhookRepository) GetDelivery(ctx context.Context, webhookID string, deliveryID int64) (*models.WebhookDelivery, error) {
+	query := `
+		SELECT * FROM webhook_deliveries
+		WHERE id = $1 AND webhook_id = $2
+	`
+
+	var delivery models.WebhookDelivery
+	err := r.db.GetContext(ctx, &delivery, query, deliveryID, webhookID)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, fmt.Errorf("webhook delivery not found")
+		}
+		return nil, fmt.Errorf("failed to get webhook delivery: %w", err)
+	}
+
+	return &delivery, nil
+}
+
+// ListDeliveries retrieves the deliveries of a webhook, newest first
+func (r *WebhookRepository) ListDeliveries(ctx context.Context, webhookID string, limit, offset int) ([]*models.WebhookDelivery, error) {
+	query := `
+		SELECT * FROM webhook_deliveries
+		WHERE webhook_id = $1
+		ORDER BY created_at DESC, id DESC
+		LIMIT $2 OFFSET $3
+	`
+
+	deliveries := []*models.WebhookDelivery{}
+	if err := r.db.SelectContext(ctx, &deliveries, query, webhookID, limit, offset); err != nil {
+		return nil, fmt.Errorf("failed to list webhook deliveries: %w", err)
+	}
+
+	return deliveries, nil
+}
+
+// ListAttempts retrieves the delivery log of a delivery
+func (r *WebhookRepository) ListAttempts(ctx context.Context, deliveryID int64) ([]*models.WebhookDeliveryAttempt, error) {
+	query := `
+		SELECT * FROM webhook_delivery_attempts
+		WHERE delivery_id = $1
+		ORDER BY attempt ASC
+	`
+
+	attempts := []*models.WebhookDeliveryAttempt{}
+	if err := r.db.SelectContext(ctx, &attempts, query, deliveryID); err != nil {
+		return nil, fmt.Errorf("failed to list webhook delivery attempts: %w", err)
+	}
+
+	return attempts, nil
+}
+
+// GetWebhook retrieves a webhook by ID regardless of project, for the delivery worker
+func (r *WebhookRepository) GetWebhook(ctx context.Context, webhookID string) (*models.Webhook, error) {
+	query := `SELECT * FROM webhooks WHERE id = $1`
+
+	var webhook models.Webhook
+	err := r.db.GetContext(ctx, &webhook, query, webhookID)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, fmt.Errorf("webhook not found")
+		}
+		return nil, fmt.Errorf("failed to get webhook: %w", err)
+	}
+
+	return &webhook, nil
+}
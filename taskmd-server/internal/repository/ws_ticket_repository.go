@@ -0,0 +1,84 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/jmoiron/sqlx"
+	"github.com/tktomaru/taskai/taskai-server/internal/models"
+)
+
+// ErrWebSocketTicketNotFound is returned when a WebSocket ticket is unknown, used or expired
+var ErrWebSocketTicketNotFound = errors.New("invalid or expired ticket")
+
+// WebSocketTicketRepository handles WebSocket ticket data access
+type WebSocketTicketRepository struct {
+	db *sqlx.DB
+}
+
+// NewWebSocketTicketRepository creates a new WebSocket ticket repository
+func NewWebSocketTicketRepository(db *sqlx.DB) *WebSocketTicketRepository {
+	return &WebSocketTicketRepository{db: db}
+}
+
+// Create creates a new ticket
+func (r *WebSocketTicketRepository) Create(ctx context.Context, ticket *models.WebSocketTicket) error {
+	query := `
+		INSERT INTO ws_tickets (
+			ticket_hash, user_id, session_id, project_id, expires_at
+		) VALUES (
+			$1, $2, $3, $4, $5
+		)
+		RETURNING created_at
+	`
+
+	err := r.db.QueryRowxContext(ctx, query,
+		ticket.TicketHash,
+		ticket.UserID,
+		ticket.SessionID,
+		ticket.ProjectID,
+		ticket.ExpiresAt,
+	).Scan(&ticket.CreatedAt)
+	if err != nil {
+		return fmt.Errorf("failed to create WebSocket ticket: %w", err)
+	}
+
+	return nil
+}
+
+// Consume deletes an unexpired ticket and returns it, so that each ticket works only once
+func (r *WebSocketTicketRepository) Consume(ctx context.Context, ticketHash string) (*models.WebSocketTicket, error) {
+	query := `
+		DELETE FROM ws_tickets
+		WHERE ticket_hash = $1 AND expires_at > NOW()
+		RETURNING *
+	`
+
+	var ticket models.WebSocketTicket
+	err := r.db.GetContext(ctx, &ticket, query, ticketHash)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, ErrWebSocketTicketNotFound
+		}
+		return nil, fmt.Errorf("failed to consume WebSocket ticket: %w", err)
+	}
+
+	return &ticket, nil
+}
+
+// DeleteExpired removes tickets that expired before the given time
+func (r *WebSocketTicketRepository) DeleteExpired(ctx context.Context, before time.Time) (int64, error) {
+	query := `
+		DELETE FROM ws_tickets WHERE expires_at <= $1
+	`
+
+	result, err := r.db.ExecContext(ctx, query, before)
+	if err != nil {
+		return 0, fmt.Errorf("failed to delete expired WebSocket tickets: %w", err)
+	}
+
+	return result.RowsAffected()
+}
@@ -0,0 +1,288 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math/rand"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/tktomaru/taskai/taskai-server/internal/auth"
+	"github.com/tktomaru/taskai/taskai-server/internal/models"
+	"github.com/tktomaru/taskai/taskai-server/internal/repository"
+)
+
+// AccessTokenPrefix marks personal access tokens and service account tokens, so that they
+// can be told apart from JWTs
+const AccessTokenPrefix = "tmd_pat_"
+
+// Errors returned when an access token is used outside its scope
+var (
+	ErrAccessTokenReadOnly     = errors.New("access token is read-only")
+	ErrAccessTokenOtherProject = errors.New("access token is not valid for this project")
+)
+
+// serviceAccountRoles are the project roles a service account can be given
+var serviceAccountRoles = map[string]bool{
+	"maintainer": true,
+	"member":     true,
+	"viewer":     true,
+}
+
+// generateAccessTokenID generates a unique access token ID
+func generateAccessTokenID() string {
+	const charset = "abcdefghijklmnopqrstuvwxyz0123456789"
+	timestamp := time.Now().Unix()
+
+	b := make([]byte, 6)
+	for i := range b {
+		b[i] = charset[rand.Intn(len(charset))]
+	}
+
+	return fmt.Sprintf("pat-%d-%s", timestamp, string(b))
+}
+
+// generateServiceAccountID generates a unique service account ID
+func generateServiceAccountID() string {
+	const charset = "abcdefghijklmnopqrstuvwxyz0123456789"
+	timestamp := time.Now().Unix()
+
+	b := make([]byte, 6)
+	for i := range b {
+		b[i] = charset[rand.Intn(len(charset))]
+	}
+
+	return fmt.Sprintf("svc-%d-%s", timestamp, string(b))
+}
+
+// IsAccessToken reports whether a bearer token is an access token rather than a JWT
+func IsAccessToken(token string) bool {
+	return strings.HasPrefix(token, AccessTokenPrefix)
+}
+
+// CheckAccessTokenScope checks that a request may be made with an access token.
+// Read tokens may only make safe requests, and project tokens may only reach their project.
+// projectID is empty for routes outside a project.
+func CheckAccessTokenScope(token *models.AccessToken, method, projectID string) error {
+	if token.Scope != models.AccessTokenScopeWrite {
+		switch method {
+		case http.MethodGet, http.MethodHead, http.MethodOptions:
+		default:
+			return ErrAccessTokenReadOnly
+		}
+	}
+
+	if token.ProjectID != nil && *token.ProjectID != projectID {
+		return ErrAccessTokenOtherProject
+	}
+
+	return nil
+}
+
+// CreateAccessTokenRequest represents a request to create an access token
+type CreateAccessTokenRequest struct {
+	Name      string     `json:"name" binding:"required"`
+	ProjectID string     `json:"project_id,omitempty"`
+	Scope     string     `json:"scope,omitempty"`
+	ExpiresAt *time.Time `json:"expires_at,omitempty"`
+	CreatedBy string     `json:"-"`
+}
+
+// CreateServiceAccountRequest represents a request to create a service account
+type CreateServiceAccountRequest struct {
+	Name string `json:"name" binding:"required"`
+	Role string `json:"role,omitempty"`
+}
+
+// AccessTokenService manages access tokens for scripts and CI, owned either by a user or
+// by a project service account
+type AccessTokenService struct {
+	repo        *repository.AccessTokenRepository
+	userRepo    *repository.UserRepository
+	projectRepo *repository.ProjectRepository
+}
+
+// NewAccessTokenService creates a new access token service
+func NewAccessTokenService(repo *repository.AccessTokenRepository, userRepo *repository.UserRepository, projectRepo *repository.ProjectRepository) *AccessTokenService {
+	return &AccessTokenService{
+		repo:        repo,
+		userRepo:    userRepo,
+		projectRepo: projectRepo,
+	}
+}
+
+// CreateToken creates a token owned by a user. The token is only returned here.
+func (s *AccessTokenService) CreateToken(ctx context.Context, userID string, req *CreateAccessTokenRequest) (*models.AccessToken, error) {
+	name := strings.TrimSpace(req.Name)
+	if name == "" {
+		return nil, fmt.Errorf("name is required")
+	}
+
+	scope := req.Scope
+	if scope == "" {
+		scope = models.AccessTokenScopeRead
+	}
+	if scope != models.AccessTokenScopeRead && scope != models.AccessTokenScopeWrite {
+		return nil, fmt.Errorf("invalid scope %q (expected read or write)", scope)
+	}
+
+	if req.ExpiresAt != nil && !req.ExpiresAt.After(time.Now()) {
+		return nil, fmt.Errorf("expires_at must be in the future")
+	}
+
+	token := &models.AccessToken{
+		ID:        generateAccessTokenID(),
+		UserID:    userID,
+		Name:      name,
+		Scope:     scope,
+		ExpiresAt: req.ExpiresAt,
+	}
+	if req.ProjectID != "" {
+		if _, err := s.projectRepo.GetByID(ctx, req.ProjectID); err != nil {
+			return nil, fmt.Errorf("project %s not found", req.ProjectID)
+		}
+		token.ProjectID = &req.ProjectID
+	}
+	if req.CreatedBy != "" {
+		token.CreatedBy = &req.CreatedBy
+	}
+
+	secret, err := auth.GenerateOpaqueToken(AccessTokenPrefix)
+	if err != nil {
+		return nil, err
+	}
+	token.TokenHash = auth.HashToken(secret)
+
+	if err := s.repo.Create(ctx, token); err != nil {
+		return nil, err
+	}
+
+	token.Token = secret
+	return token, nil
+}
+
+// ListTokens retrieves the tokens of a user
+func (s *AccessTokenService) ListTokens(ctx context.Context, userID string) ([]*models.AccessToken, error) {
+	return s.repo.ListByUser(ctx, userID)
+}
+
+// RevokeToken revokes a token of a user
+func (s *AccessTokenService) RevokeToken(ctx context.Context, userID, tokenID string) error {
+	return s.repo.Revoke(ctx, userID, tokenID)
+}
+
+// Authenticate resolves an access token to its owner and records its use
+func (s *AccessTokenService) Authenticate(ctx context.Context, secret string) (*models.User, *models.AccessToken, error) {
+	token, err := s.repo.GetActiveByHash(ctx, auth.HashToken(secret))
+	if err != nil {
+		return nil, nil, err
+	}
+
+	user, err := s.userRepo.GetByID(ctx, token.UserID)
+	if err != nil {
+		return nil, nil, fmt.Errorf("user not found")
+	}
+
+	if err := s.repo.TouchLastUsed(ctx, token.ID); err != nil {
+		return nil, nil, err
+	}
+
+	// Remove password hash
+	user.PasswordHash = nil
+
+	return user, token, nil
+}
+
+// CreateServiceAccount creates a service account and adds it to its project with a role
+// (member by default)
+func (s *AccessTokenService) CreateServiceAccount(ctx context.Context, projectID string, req *CreateServiceAccountRequest) (*models.User, error) {
+	name := strings.TrimSpace(req.Name)
+	if name == "" {
+		return nil, fmt.Errorf("name is required")
+	}
+
+	role := req.Role
+	if role == "" {
+		role = "member"
+	}
+	if !serviceAccountRoles[role] {
+		return nil, fmt.Errorf("invalid role %q (expected maintainer, member or viewer)", role)
+	}
+
+	if _, err := s.projectRepo.GetByID(ctx, projectID); err != nil {
+		return nil, fmt.Errorf("project %s not found", projectID)
+	}
+
+	id := generateServiceAccountID()
+	account := &models.User{
+		ID: id,
+		// Emails are unique and required; service accounts get one that cannot receive mail
+		Email:            id + "@service.taskmd.local",
+		Name:             name,
+		Preferences:      make(models.JSONB),
+		ServiceProjectID: &projectID,
+		CreatedAt:        time.Now(),
+		UpdatedAt:        time.Now(),
+	}
+
+	if err := s.userRepo.Create(ctx, account); err != nil {
+		return nil, err
+	}
+
+	if err := s.projectRepo.GrantMemberRole(ctx, projectID, account.ID, role); err != nil {
+		return nil, err
+	}
+
+	return account, nil
+}
+
+// ListServiceAccounts retrieves the service accounts of a project
+func (s *AccessTokenService) ListServiceAccounts(ctx context.Context, projectID string) ([]*models.User, error) {
+	return s.userRepo.ListServiceAccounts(ctx, projectID)
+}
+
+// DeleteServiceAccount deletes a service account, which revokes its tokens
+func (s *AccessTokenService) DeleteServiceAccount(ctx context.Context, projectID, accountID string) error {
+	return s.userRepo.DeleteServiceAccount(ctx, projectID, accountID)
+}
+
+// CreateServiceAccountToken creates a token for a service account. Its tokens are always
+// limited to the account's project.
+func (s *AccessTokenService) CreateServiceAccountToken(ctx context.Context, projectID, accountID string, req *CreateAccessTokenRequest) (*models.AccessToken, error) {
+	if _, err := s.serviceAccount(ctx, projectID, accountID); err != nil {
+		return nil, err
+	}
+
+	req.ProjectID = projectID
+	return s.CreateToken(ctx, accountID, req)
+}
+
+// ListServiceAccountTokens retrieves the tokens of a service account
+func (s *AccessTokenService) ListServiceAccountTokens(ctx context.Context, projectID, accountID string) ([]*models.AccessToken, error) {
+	if _, err := s.serviceAccount(ctx, projectID, accountID); err != nil {
+		return nil, err
+	}
+
+	return s.repo.ListByUser(ctx, accountID)
+}
+
+// RevokeServiceAccountToken revokes a token of a service account
+func (s *AccessTokenService) RevokeServiceAccountToken(ctx context.Context, projectID, accountID, tokenID string) error {
+	if _, err := s.serviceAccount(ctx, projectID, accountID); err != nil {
+		return err
+	}
+
+	return s.repo.Revoke(ctx, accountID, tokenID)
+}
+
+// serviceAccount retrieves a service account of a project
+func (s *AccessTokenService) serviceAccount(ctx context.Context, projectID, accountID string) (*models.User, error) {
+	account, err := s.userRepo.GetByID(ctx, accountID)
+	if err != nil || account.ServiceProjectID == nil || *account.ServiceProjectID != projectID {
+		return nil, fmt.Errorf("service account not found")
+	}
+
+	return account, nil
+}
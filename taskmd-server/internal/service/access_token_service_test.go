@@ -0,0 +1,94 @@
+package service
+
+import (
+	"errors"
+	"net/http"
+	"testing"
+
+	"github.com/tktomaru/taskai/taskai-server/internal/models"
+)
+
+func TestIsAccessToken(t *testing.T) {
+	tests := []struct {
+		token string
+		want  bool
+	}{
+		{AccessTokenPrefix + "0123abcd", true},
+		{"eyJhbGciOiJIUzI1NiIsInR5cCI6IkpXVCJ9.e30.sig", false},
+		{"rt_0123abcd", false},
+		{"", false},
+	}
+
+	for _, tt := range tests {
+		if got := IsAccessToken(tt.token); got != tt.want {
+			t.Errorf("IsAccessToken(%q) = %v, want %v", tt.token, got, tt.want)
+		}
+	}
+}
+
+func TestCheckAccessTokenScope(t *testing.T) {
+	web := "web"
+
+	tests := []struct {
+		name      string
+		token     models.AccessToken
+		method    string
+		projectID string
+		want      error
+	}{
+		{
+			name:      "read token can read",
+			token:     models.AccessToken{Scope: models.AccessTokenScopeRead},
+			method:    http.MethodGet,
+			projectID: "web",
+		},
+		{
+			name:      "read token cannot write",
+			token:     models.AccessToken{Scope: models.AccessTokenScopeRead},
+			method:    http.MethodPost,
+			projectID: "web",
+			want:      ErrAccessTokenReadOnly,
+		},
+		{
+			name:      "write token can write",
+			token:     models.AccessToken{Scope: models.AccessTokenScopeWrite},
+			method:    http.MethodDelete,
+			projectID: "web",
+		},
+		{
+			name:      "project token in its project",
+			token:     models.AccessToken{Scope: models.AccessTokenScopeWrite, ProjectID: &web},
+			method:    http.MethodPatch,
+			projectID: "web",
+		},
+		{
+			name:      "project token in another project",
+			token:     models.AccessToken{Scope: models.AccessTokenScopeWrite, ProjectID: &web},
+			method:    http.MethodGet,
+			projectID: "api",
+			want:      ErrAccessTokenOtherProject,
+		},
+		{
+			name:      "project token outside projects",
+			token:     models.AccessToken{Scope: models.AccessTokenScopeRead, ProjectID: &web},
+			method:    http.MethodGet,
+			projectID: "",
+			want:      ErrAccessTokenOtherProject,
+		},
+		{
+			name:      "unscoped token outside projects",
+			token:     models.AccessToken{Scope: models.AccessTokenScopeRead},
+			method:    http.MethodGet,
+			projectID: "",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := CheckAccessTokenScope(&tt.token, tt.method, tt.projectID)
+			if !errors.Is(err, tt.want) {
+				t.Errorf("CheckAccessTokenScope() = %v, want %v", err, tt.want)
+			}
+		})
+	}
+}
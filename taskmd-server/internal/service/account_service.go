@@ -0,0 +1,229 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"math/rand"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/tktomaru/taskai/taskai-server/internal/auth"
+	"github.com/tktomaru/taskai/taskai-server/internal/mail"
+	"github.com/tktomaru/taskai/taskai-server/internal/models"
+	"github.com/tktomaru/taskai/taskai-server/internal/repository"
+)
+
+// generateAccountTokenID generates a unique account token ID
+func generateAccountTokenID() string {
+	const charset = "abcdefghijklmnopqrstuvwxyz0123456789"
+	timestamp := time.Now().Unix()
+
+	b := make([]byte, 6)
+	for i := range b {
+		b[i] = charset[rand.Intn(len(charset))]
+	}
+
+	return fmt.Sprintf("acct-%d-%s", timestamp, string(b))
+}
+
+// AccountService handles email verification and password resets. Both send a single-use,
+// expiring link by email.
+type AccountService struct {
+	userRepo       *repository.UserRepository
+	tokenRepo      *repository.AccountTokenRepository
+	sessionRepo    *repository.SessionRepository
+	passwordHasher *auth.PasswordHasher
+	policy         *auth.PasswordPolicy
+	sender         mail.Sender
+
+	// linkBaseURL is the web UI address that links in emails point to
+	linkBaseURL string
+	verifyTTL   time.Duration
+	resetTTL    time.Duration
+}
+
+// NewAccountService creates a new account service
+func NewAccountService(userRepo *repository.UserRepository, tokenRepo *repository.AccountTokenRepository, sessionRepo *repository.SessionRepository, policy *auth.PasswordPolicy, sender mail.Sender, linkBaseURL string, verifyTTL, resetTTL time.Duration) *AccountService {
+	return &AccountService{
+		userRepo:       userRepo,
+		tokenRepo:      tokenRepo,
+		sessionRepo:    sessionRepo,
+		passwordHasher: auth.NewPasswordHasher(),
+		policy:         policy,
+		sender:         sender,
+		linkBaseURL:    strings.TrimSuffix(linkBaseURL, "/"),
+		verifyTTL:      verifyTTL,
+		resetTTL:       resetTTL,
+	}
+}
+
+// SendVerification emails a verification link to a user whose address is not verified yet.
+// Earlier links stop working.
+func (s *AccountService) SendVerification(ctx context.Context, user *models.User) error {
+	if user.EmailVerifiedAt != nil || user.ServiceProjectID != nil {
+		return nil
+	}
+
+	token, err := s.issue(ctx, user, models.AccountTokenVerifyEmail, s.verifyTTL)
+	if err != nil {
+		return err
+	}
+
+	return s.sender.Send(ctx, mail.Message{
+		To:      user.Email,
+		Subject: "Verify your email address",
+		Body: fmt.Sprintf("Hello %s,\n\n"+
+			"Please confirm that this is your email address by opening the link below:\n\n"+
+			"%s\n\n"+
+			"The link expires in %s. If you did not create an account, you can ignore this email.\n",
+			user.Name, s.link("/verify-email", token), s.verifyTTL),
+	})
+}
+
+// ResendVerification emails a new verification link to the owner of an address.
+// Unknown and already verified addresses are ignored, so that callers cannot probe for accounts.
+func (s *AccountService) ResendVerification(ctx context.Context, email string) error {
+	user, err := s.userRepo.GetByEmail(ctx, strings.TrimSpace(email))
+	if err != nil {
+		return nil
+	}
+
+	return s.SendVerification(ctx, user)
+}
+
+// VerifyEmail consumes a verification token and marks the address it was sent to as verified
+func (s *AccountService) VerifyEmail(ctx context.Context, token string) (*models.User, error) {
+	accountToken, err := s.tokenRepo.Consume(ctx, models.AccountTokenVerifyEmail, auth.HashToken(token))
+	if err != nil {
+		return nil, err
+	}
+
+	verified, err := s.userRepo.MarkEmailVerified(ctx, accountToken.UserID, accountToken.Email)
+	if err != nil {
+		return nil, err
+	}
+	if !verified {
+		return nil, fmt.Errorf("the email address of this account has changed since the link was sent")
+	}
+
+	user, err := s.userRepo.GetByID(ctx, accountToken.UserID)
+	if err != nil {
+		return nil, err
+	}
+
+	// Remove password hash
+	user.PasswordHash = nil
+
+	return user, nil
+}
+
+// RequestPasswordReset emails a password reset link to the owner of an address.
+// Unknown addresses and accounts without a password are ignored, so that callers cannot
+// probe for accounts.
+func (s *AccountService) RequestPasswordReset(ctx context.Context, email string) error {
+	user, err := s.userRepo.GetByEmail(ctx, strings.TrimSpace(email))
+	if err != nil || user.PasswordHash == nil || user.ServiceProjectID != nil {
+		return nil
+	}
+
+	token, err := s.issue(ctx, user, models.AccountTokenResetPassword, s.resetTTL)
+	if err != nil {
+		return err
+	}
+
+	return s.sender.Send(ctx, mail.Message{
+		To:      user.Email,
+		Subject: "Reset your password",
+		Body: fmt.Sprintf("Hello %s,\n\n"+
+			"A password reset was requested for your account. Open the link below to choose a new password:\n\n"+
+			"%s\n\n"+
+			"The link expires in %s and can only be used once. If you did not request a reset, you can ignore this email.\n",
+			user.Name, s.link("/reset-password", token), s.resetTTL),
+	})
+}
+
+// ResetPassword sets a new password with a reset token and logs out every session of the user.
+// The token is only used up once the new password has passed the policy.
+func (s *AccountService) ResetPassword(ctx context.Context, token, newPassword string) error {
+	tokenHash := auth.HashToken(token)
+
+	accountToken, err := s.tokenRepo.GetActive(ctx, models.AccountTokenResetPassword, tokenHash)
+	if err != nil {
+		return err
+	}
+
+	user, err := s.userRepo.GetByID(ctx, accountToken.UserID)
+	if err != nil {
+		return repository.ErrAccountTokenNotFound
+	}
+
+	if err := s.policy.Validate(newPassword, user.Email); err != nil {
+		return err
+	}
+
+	if _, err := s.tokenRepo.Consume(ctx, models.AccountTokenResetPassword, tokenHash); err != nil {
+		return err
+	}
+
+	passwordHash, err := s.passwordHasher.HashPassword(newPassword)
+	if err != nil {
+		return fmt.Errorf("failed to hash password: %w", err)
+	}
+
+	if err := s.userRepo.UpdatePassword(ctx, user.ID, passwordHash); err != nil {
+		return err
+	}
+
+	if err := s.tokenRepo.InvalidateAll(ctx, user.ID, models.AccountTokenResetPassword); err != nil {
+		return err
+	}
+
+	if _, err := s.sessionRepo.RevokeAll(ctx, user.ID); err != nil {
+		return err
+	}
+
+	// The link reached the user's inbox, which proves they own the address
+	if _, err := s.userRepo.MarkEmailVerified(ctx, user.ID, accountToken.Email); err != nil {
+		log.Printf("WARNING: Failed to mark email of user %s as verified: %v", user.ID, err)
+	}
+
+	return nil
+}
+
+// Cleanup removes expired tokens
+func (s *AccountService) Cleanup(ctx context.Context, now time.Time) (int64, error) {
+	return s.tokenRepo.DeleteExpired(ctx, now)
+}
+
+// issue creates a token for a purpose, invalidating earlier ones
+func (s *AccountService) issue(ctx context.Context, user *models.User, purpose string, ttl time.Duration) (string, error) {
+	if err := s.tokenRepo.InvalidateAll(ctx, user.ID, purpose); err != nil {
+		return "", err
+	}
+
+	token, err := auth.GenerateOpaqueToken("")
+	if err != nil {
+		return "", err
+	}
+
+	accountToken := &models.AccountToken{
+		ID:        generateAccountTokenID(),
+		UserID:    user.ID,
+		Purpose:   purpose,
+		TokenHash: auth.HashToken(token),
+		Email:     user.Email,
+		ExpiresAt: time.Now().Add(ttl),
+	}
+	if err := s.tokenRepo.Create(ctx, accountToken); err != nil {
+		return "", err
+	}
+
+	return token, nil
+}
+
+// link builds a web UI link carrying a token
+func (s *AccountService) link(path, token string) string {
+	return s.linkBaseURL + path + "?token=" + url.QueryEscape(token)
+}
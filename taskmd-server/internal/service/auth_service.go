@@ -2,7 +2,10 @@ package service
 
 import (
 	"context"
+	"errors"
 	"fmt"
+	"log"
+	"math/rand"
 	"time"
 
 	"github.com/tktomaru/taskai/taskai-server/internal/auth"
@@ -10,40 +13,110 @@ import (
 	"github.com/tktomaru/taskai/taskai-server/internal/repository"
 )
 
-// AuthService handles authentication business logic
+// refreshTokenPrefix marks refresh tokens
+const refreshTokenPrefix = "rt_"
+
+// ErrInvalidRefreshToken is returned when a refresh token is unknown, expired, revoked or reused
+var ErrInvalidRefreshToken = errors.New("invalid or expired refresh token")
+
+// ErrInvalidChallenge is returned when a two-factor challenge token is invalid or expired
+var ErrInvalidChallenge = errors.New("invalid or expired two-factor challenge")
+
+// generateSessionID generates a unique session ID
+func generateSessionID() string {
+	const charset = "abcdefghijklmnopqrstuvwxyz0123456789"
+	timestamp := time.Now().Unix()
+
+	b := make([]byte, 6)
+	for i := range b {
+		b[i] = charset[rand.Intn(len(charset))]
+	}
+
+	return fmt.Sprintf("sess-%d-%s", timestamp, string(b))
+}
+
+// AuthService handles authentication business logic.
+// Logins start a session; access tokens are short-lived and name their session, and
+// refresh tokens rotate on every use. Users with two-factor authentication first receive
+// a challenge token, which is exchanged for a session together with a TOTP code.
 type AuthService struct {
 	userRepo       *repository.UserRepository
+	sessionRepo    *repository.SessionRepository
 	passwordHasher *auth.PasswordHasher
 	jwtManager     *auth.JWTManager
+	refreshTTL     time.Duration
+	policy         *auth.PasswordPolicy
+	twoFactor      *TwoFactorService
+	challengeTTL   time.Duration
 }
 
-// NewAuthService creates a new auth service
-func NewAuthService(userRepo *repository.UserRepository, jwtSecret string, jwtExpiresIn time.Duration) *AuthService {
+// NewAuthService creates a new auth service. Access tokens expire after accessTTL and
+// sessions after refreshTTL without a refresh.
+func NewAuthService(userRepo *repository.UserRepository, sessionRepo *repository.SessionRepository, jwtSecret string, accessTTL, refreshTTL time.Duration) *AuthService {
 	return &AuthService{
 		userRepo:       userRepo,
+		sessionRepo:    sessionRepo,
 		passwordHasher: auth.NewPasswordHasher(),
-		jwtManager:     auth.NewJWTManager(jwtSecret, jwtExpiresIn),
+		jwtManager:     auth.NewJWTManager(jwtSecret, accessTTL),
+		refreshTTL:     refreshTTL,
 	}
 }
 
+// SetPasswordPolicy sets the policy new passwords must pass
+func (s *AuthService) SetPasswordPolicy(policy *auth.PasswordPolicy) {
+	s.policy = policy
+}
+
+// SetTwoFactor enables the second login step for users with two-factor authentication.
+// Challenge tokens expire after challengeTTL.
+func (s *AuthService) SetTwoFactor(twoFactor *TwoFactorService, challengeTTL time.Duration) {
+	s.twoFactor = twoFactor
+	s.challengeTTL = challengeTTL
+}
+
+// ClientInfo describes the client a session is started from
+type ClientInfo struct {
+	UserAgent string
+	IPAddress string
+}
+
 // RegisterRequest represents a user registration request
 type RegisterRequest struct {
-	ID       string `json:"id"`
-	Email    string `json:"email"`
-	Name     string `json:"name"`
-	Password string `json:"password"`
+	ID       string     `json:"id"`
+	Email    string     `json:"email"`
+	Name     string     `json:"name"`
+	Password string     `json:"password"`
+	Client   ClientInfo `json:"-"`
 }
 
 // LoginRequest represents a login request
 type LoginRequest struct {
-	Email    string `json:"email"`
-	Password string `json:"password"`
+	Email    string     `json:"email"`
+	Password string     `json:"password"`
+	Client   ClientInfo `json:"-"`
+}
+
+// TwoFactorLoginRequest represents the second step of a login: the challenge token
+// and either a TOTP code or a recovery code
+type TwoFactorLoginRequest struct {
+	ChallengeToken string     `json:"challenge_token"`
+	Code           string     `json:"code"`
+	RecoveryCode   string     `json:"recovery_code"`
+	Client         ClientInfo `json:"-"`
 }
 
-// AuthResponse represents an authentication response
+// AuthResponse represents an authentication response. When a second factor is required,
+// only TwoFactorRequired and ChallengeToken are set.
 type AuthResponse struct {
-	User  *models.User `json:"user"`
-	Token string       `json:"token"`
+	User         *models.User `json:"user,omitempty"`
+	Token        string       `json:"token,omitempty"`
+	ExpiresIn    int          `json:"expires_in,omitempty"`
+	RefreshToken string       `json:"refresh_token,omitempty"`
+	SessionID    string       `json:"session_id,omitempty"`
+
+	TwoFactorRequired bool   `json:"two_factor_required,omitempty"`
+	ChallengeToken    string `json:"challenge_token,omitempty"`
+	ChallengeExpires  int    `json:"challenge_expires_in,omitempty"`
 }
 
 // Register registers a new user
@@ -58,6 +131,11 @@ func (s *AuthService) Register(ctx context.Context, req *RegisterRequest) (*Auth
 	if req.Name == "" {
 		return nil, fmt.Errorf("name is required")
 	}
+	if s.policy != nil {
+		if err := s.policy.Validate(req.Password, req.Email); err != nil {
+			return nil, err
+		}
+	}
 
 	// Check if user already exists
 	existingUser, _ := s.userRepo.GetByEmail(ctx, req.Email)
@@ -86,25 +164,11 @@ func (s *AuthService) Register(ctx context.Context, req *RegisterRequest) (*Auth
 		return nil, fmt.Errorf("failed to create user: %w", err)
 	}
 
-	// Generate JWT token
-	token, err := s.jwtManager.GenerateToken(user)
-	if err != nil {
-		return nil, fmt.Errorf("failed to generate token: %w", err)
-	}
-
-	// Update last login
-	_ = s.userRepo.UpdateLastLogin(ctx, user.ID)
-
-	// Remove password hash from response
-	user.PasswordHash = nil
-
-	return &AuthResponse{
-		User:  user,
-		Token: token,
-	}, nil
+	return s.IssueToken(ctx, user, req.Client)
 }
 
-// Login authenticates a user
+// Login authenticates a user. Users with two-factor authentication get a challenge token
+// instead of a session, to be completed with CompleteTwoFactorLogin.
 func (s *AuthService) Login(ctx context.Context, req *LoginRequest) (*AuthResponse, error) {
 	// Get user by email
 	user, err := s.userRepo.GetByEmail(ctx, req.Email)
@@ -126,40 +190,201 @@ func (s *AuthService) Login(ctx context.Context, req *LoginRequest) (*AuthRespon
 		return nil, fmt.Errorf("invalid email or password")
 	}
 
-	// Generate JWT token
-	token, err := s.jwtManager.GenerateToken(user)
+	if user.TOTPEnabledAt != nil && s.twoFactor != nil {
+		challenge, err := s.jwtManager.GenerateChallengeToken(user.ID, s.challengeTTL)
+		if err != nil {
+			return nil, err
+		}
+
+		return &AuthResponse{
+			TwoFactorRequired: true,
+			ChallengeToken:    challenge,
+			ChallengeExpires:  int(s.challengeTTL.Seconds()),
+		}, nil
+	}
+
+	return s.IssueToken(ctx, user, req.Client)
+}
+
+// ChallengeUserID returns the user a two-factor challenge token was issued to
+func (s *AuthService) ChallengeUserID(challengeToken string) (string, error) {
+	claims, err := s.jwtManager.ValidateChallengeToken(challengeToken)
 	if err != nil {
-		return nil, fmt.Errorf("failed to generate token: %w", err)
+		return "", ErrInvalidChallenge
+	}
+
+	return claims.UserID, nil
+}
+
+// CompleteTwoFactorLogin checks the second factor of a login and starts the session
+func (s *AuthService) CompleteTwoFactorLogin(ctx context.Context, req *TwoFactorLoginRequest) (*AuthResponse, error) {
+	if s.twoFactor == nil {
+		return nil, ErrInvalidChallenge
+	}
+
+	userID, err := s.ChallengeUserID(req.ChallengeToken)
+	if err != nil {
+		return nil, err
+	}
+
+	user, err := s.userRepo.GetByID(ctx, userID)
+	if err != nil {
+		return nil, ErrInvalidChallenge
+	}
+
+	if err := s.twoFactor.Verify(ctx, user, req.Code, req.RecoveryCode); err != nil {
+		if errors.Is(err, ErrTwoFactorNotEnabled) {
+			return nil, ErrInvalidChallenge
+		}
+		return nil, err
+	}
+
+	return s.IssueToken(ctx, user, req.Client)
+}
+
+// IssueToken starts a session for a user who has been authenticated, e.g. by password or
+// by an OIDC issuer
+func (s *AuthService) IssueToken(ctx context.Context, user *models.User, client ClientInfo) (*AuthResponse, error) {
+	refreshToken, err := auth.GenerateOpaqueToken(refreshTokenPrefix)
+	if err != nil {
+		return nil, err
+	}
+
+	session := &models.Session{
+		ID:               generateSessionID(),
+		UserID:           user.ID,
+		RefreshTokenHash: auth.HashToken(refreshToken),
+		UserAgent:        optionalString(client.UserAgent),
+		IPAddress:        optionalString(client.IPAddress),
+		ExpiresAt:        time.Now().Add(s.refreshTTL),
+	}
+	if err := s.sessionRepo.Create(ctx, session); err != nil {
+		return nil, err
 	}
 
 	// Update last login
 	_ = s.userRepo.UpdateLastLogin(ctx, user.ID)
 
-	// Remove password hash from response
-	user.PasswordHash = nil
-
-	return &AuthResponse{
-		User:  user,
-		Token: token,
-	}, nil
+	return s.respond(user, session.ID, refreshToken)
 }
 
-// ValidateToken validates a JWT token and returns the user
-func (s *AuthService) ValidateToken(tokenString string) (*models.User, error) {
-	claims, err := s.jwtManager.ValidateToken(tokenString)
+// Refresh exchanges a refresh token for a new access token and a new refresh token.
+// Presenting a refresh token that has already been exchanged revokes the session,
+// since either the client or an attacker holds a stolen copy.
+func (s *AuthService) Refresh(ctx context.Context, refreshToken string) (*AuthResponse, error) {
+	tokenHash := auth.HashToken(refreshToken)
+
+	session, current, err := s.sessionRepo.GetByRefreshToken(ctx, tokenHash)
+	if errors.Is(err, repository.ErrSessionNotFound) {
+		return nil, ErrInvalidRefreshToken
+	}
 	if err != nil {
 		return nil, err
 	}
 
-	user, err := s.userRepo.GetByID(context.Background(), claims.UserID)
+	if !current {
+		if session.RevokedAt == nil {
+			log.Printf("WARNING: Refresh token of session %s was reused; revoking the session", session.ID)
+			if err := s.sessionRepo.Revoke(ctx, session.UserID, session.ID); err != nil {
+				return nil, err
+			}
+		}
+		return nil, ErrInvalidRefreshToken
+	}
+
+	newToken, err := auth.GenerateOpaqueToken(refreshTokenPrefix)
+	if err != nil {
+		return nil, err
+	}
+
+	err = s.sessionRepo.Rotate(ctx, session.ID, tokenHash, auth.HashToken(newToken), time.Now().Add(s.refreshTTL))
+	if errors.Is(err, repository.ErrSessionNotFound) {
+		return nil, ErrInvalidRefreshToken
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	user, err := s.userRepo.GetByID(ctx, session.UserID)
 	if err != nil {
 		return nil, fmt.Errorf("user not found")
 	}
 
+	return s.respond(user, session.ID, newToken)
+}
+
+// Logout revokes a session of a user
+func (s *AuthService) Logout(ctx context.Context, userID, sessionID string) error {
+	return s.sessionRepo.Revoke(ctx, userID, sessionID)
+}
+
+// LogoutByRefreshToken revokes the session of a refresh token, for clients whose access token has expired
+func (s *AuthService) LogoutByRefreshToken(ctx context.Context, refreshToken string) error {
+	session, current, err := s.sessionRepo.GetByRefreshToken(ctx, auth.HashToken(refreshToken))
+	if err != nil {
+		return err
+	}
+	if !current {
+		return ErrInvalidRefreshToken
+	}
+
+	return s.sessionRepo.Revoke(ctx, session.UserID, session.ID)
+}
+
+// LogoutEverywhere revokes all sessions of a user and returns how many were revoked
+func (s *AuthService) LogoutEverywhere(ctx context.Context, userID string) (int64, error) {
+	return s.sessionRepo.RevokeAll(ctx, userID)
+}
+
+// ListSessions retrieves the active sessions of a user, marking the current one
+func (s *AuthService) ListSessions(ctx context.Context, userID, currentSessionID string) ([]*models.Session, error) {
+	sessions, err := s.sessionRepo.ListActive(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, session := range sessions {
+		session.Current = session.ID == currentSessionID
+	}
+
+	return sessions, nil
+}
+
+// Authenticate validates an access token and returns its user and claims.
+// Tokens of revoked or expired sessions, and tokens without a session, are rejected.
+func (s *AuthService) Authenticate(ctx context.Context, tokenString string) (*models.User, *auth.Claims, error) {
+	claims, err := s.jwtManager.ValidateToken(tokenString)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	if claims.SessionID == "" {
+		return nil, nil, fmt.Errorf("token is not bound to a session")
+	}
+
+	active, err := s.sessionRepo.IsActive(ctx, claims.SessionID)
+	if err != nil {
+		return nil, nil, err
+	}
+	if !active {
+		return nil, nil, fmt.Errorf("session has been revoked")
+	}
+
+	user, err := s.userRepo.GetByID(ctx, claims.UserID)
+	if err != nil {
+		return nil, nil, fmt.Errorf("user not found")
+	}
+
 	// Remove password hash
 	user.PasswordHash = nil
 
-	return user, nil
+	return user, claims, nil
+}
+
+// ValidateToken validates a JWT token and returns the user
+func (s *AuthService) ValidateToken(tokenString string) (*models.User, error) {
+	user, _, err := s.Authenticate(context.Background(), tokenString)
+	return user, err
 }
 
 // GetUserByID retrieves a user by ID
@@ -174,3 +399,30 @@ func (s *AuthService) GetUserByID(ctx context.Context, userID string) (*models.U
 
 	return user, nil
 }
+
+// respond issues an access token for a session
+func (s *AuthService) respond(user *models.User, sessionID, refreshToken string) (*AuthResponse, error) {
+	token, err := s.jwtManager.GenerateSessionToken(user, sessionID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to generate token: %w", err)
+	}
+
+	// Remove password hash from response
+	user.PasswordHash = nil
+
+	return &AuthResponse{
+		User:         user,
+		Token:        token,
+		ExpiresIn:    int(s.jwtManager.ExpiresIn().Seconds()),
+		RefreshToken: refreshToken,
+		SessionID:    sessionID,
+	}, nil
+}
+
+// optionalString returns nil for an empty string
+func optionalString(value string) *string {
+	if value == "" {
+		return nil
+	}
+	return &value
+}
@@ -0,0 +1,248 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/jmoiron/sqlx"
+	"github.com/tktomaru/taskai/taskai-server/internal/calendar"
+	"github.com/tktomaru/taskai/taskai-server/internal/models"
+	"github.com/tktomaru/taskai/taskai-server/internal/query"
+	"github.com/tktomaru/taskai/taskai-server/internal/repository"
+)
+
+// DefaultMaxAffected is the max-affected safeguard used when a request does not set one
+const DefaultMaxAffected = 100
+
+// MaxAffectedExceededError is returned when a query matches more tasks than the safeguard allows
+type MaxAffectedExceededError struct {
+	Matched     int
+	MaxAffected int
+}
+
+func (e *MaxAffectedExceededError) Error() string {
+	return fmt.Sprintf("query matches %d tasks, which exceeds max_affected (%d)", e.Matched, e.MaxAffected)
+}
+
+// BulkByQueryRequest represents a request to apply operations to every task matching a query.
+// Either Query or ViewID must be set.
+type BulkByQueryRequest struct {
+	Query       string                 `json:"query"`
+	ViewID      string                 `json:"view_id"`
+	Operations  []BulkOperation        `json:"operations"`
+	Updates     map[string]interface{} `json:"updates,omitempty"`
+	Mode        BulkMode               `json:"mode"`
+	Preview     bool                   `json:"preview"`
+	MaxAffected int                    `json:"max_affected"`
+	UpdatedBy   string                 `json:"updated_by,omitempty"`
+}
+
+// MatchedTask is a summary of a task matched by a bulk query
+type MatchedTask struct {
+	ID       string              `json:"id"`
+	Title    string              `json:"title"`
+	Status   models.TaskStatus   `json:"status"`
+	Priority models.TaskPriority `json:"priority"`
+}
+
+// BulkByQueryResult represents the outcome of a query-driven bulk request
+type BulkByQueryResult struct {
+	*BulkUpdateResult
+	Query       string         `json:"query"`
+	ViewID      string         `json:"view_id,omitempty"`
+	Preview     bool           `json:"preview"`
+	Matched     int            `json:"matched"`
+	MaxAffected int            `json:"max_affected"`
+	Exceeded    bool           `json:"exceeds_max_affected"`
+	Tasks       []*MatchedTask `json:"tasks"`
+	AuditLogID  *int64         `json:"audit_log_id,omitempty"`
+}
+
+// ApplyByQuery resolves a query (or saved view) and applies the operations to every matched task.
+// Matching and applying run in the same transaction, and a committed request is recorded
+// as a single audit log entry. A preview never changes data and reports the matched set
+// even when it exceeds the max-affected safeguard.
+func (s *BulkService) ApplyByQuery(ctx context.Context, projectID string, req *BulkByQueryRequest, cal *calendar.Calendar) (*BulkByQueryResult, error) {
+	ops, err := normalizeBulkOperations(&req.Mode, req.Operations, req.Updates)
+	if err != nil {
+		return nil, err
+	}
+	if err := s.authorizeMoves(ctx, ops); err != nil {
+		return nil, err
+	}
+
+	rawQuery, err := s.resolveQuery(ctx, projectID, req)
+	if err != nil {
+		return nil, err
+	}
+
+	buildResult, err := buildBulkQuery(projectID, rawQuery, cal)
+	if err != nil {
+		return nil, err
+	}
+
+	maxAffected := req.MaxAffected
+	if maxAffected <= 0 {
+		maxAffected = DefaultMaxAffected
+	}
+
+	result := &BulkByQueryResult{
+		BulkUpdateResult: &BulkUpdateResult{
+			Mode:    req.Mode,
+			DryRun:  req.Preview,
+			Results: []*BulkTaskResult{},
+		},
+		Query:       rawQuery,
+		ViewID:      req.ViewID,
+		Preview:     req.Preview,
+		MaxAffected: maxAffected,
+		Tasks:       []*MatchedTask{},
+	}
+
+	err = s.db.Transaction(ctx, func(tx *sqlx.Tx) error {
+		// Lock the matched rows so the set cannot change between preview and apply
+		var matched []*models.Task
+		if err := tx.SelectContext(ctx, &matched, buildResult.SQL+" FOR UPDATE", buildResult.Args...); err != nil {
+			return fmt.Errorf("failed to execute query: %w", err)
+		}
+
+		taskIDs := result.match(matched)
+
+		if result.Exceeded && !req.Preview {
+			return &MaxAffectedExceededError{Matched: len(matched), MaxAffected: maxAffected}
+		}
+
+		if err := s.applyInTx(ctx, tx, projectID, taskIDs, ops, req.UpdatedBy, result.BulkUpdateResult); err != nil {
+			return err
+		}
+
+		if req.Preview || (req.Mode == BulkModeAllOrNothing && result.FailedCount > 0) {
+			return errBulkRollback
+		}
+
+		// Record the whole request as one batch
+		entry := bulkAuditEntry(projectID, req, rawQuery, ops, result)
+		if err := repository.NewAuditRepository(s.db.DB).WithTx(tx).Create(ctx, entry); err != nil {
+			return err
+		}
+		result.AuditLogID = &entry.ID
+
+		return nil
+	})
+
+	if err := finishBulkResult(result.BulkUpdateResult, err); err != nil {
+		return nil, err
+	}
+
+	if result.Committed {
+		s.flagDeletedReferences(ctx, result.BulkUpdateResult)
+		s.notifyBulk(ctx, result.BulkUpdateResult, req.UpdatedBy)
+	}
+
+	return result, nil
+}
+
+// buildBulkQuery builds the SQL selecting every task matched by a query. The row limit
+// of the query language does not apply, as bulk requests act on the whole matched set and
+// are bounded by max_affected instead.
+func buildBulkQuery(projectID, rawQuery string, cal *calendar.Calendar) (*query.BuildResult, error) {
+	queryParser := query.NewQueryParser()
+	queryParser.SetCalendar(cal)
+	parsed, err := queryParser.Parse(rawQuery)
+	if err != nil {
+		return nil, fmt.Errorf("invalid query: %w", err)
+	}
+	parsed.Limit = 0
+
+	buildResult, err := query.NewSQLBuilder().Build(projectID, parsed)
+	if err != nil {
+		return nil, fmt.Errorf("failed to build SQL: %w", err)
+	}
+
+	return buildResult, nil
+}
+
+// match records the matched tasks in the result, checks them against the max-affected
+// safeguard and returns their IDs
+func (r *BulkByQueryResult) match(matched []*models.Task) []string {
+	r.Matched = len(matched)
+	r.Exceeded = len(matched) > r.MaxAffected
+
+	taskIDs := make([]string, len(matched))
+	for i, task := range matched {
+		taskIDs[i] = task.ID
+		r.Tasks = append(r.Tasks, &MatchedTask{
+			ID:       task.ID,
+			Title:    task.Title,
+			Status:   task.Status,
+			Priority: task.Priority,
+		})
+	}
+
+	return taskIDs
+}
+
+// resolveQuery returns the query string of a request, loading it from the saved view if needed
+func (s *BulkService) resolveQuery(ctx context.Context, projectID string, req *BulkByQueryRequest) (string, error) {
+	if req.Query != "" && req.ViewID != "" {
+		return "", fmt.Errorf("only one of query or view_id can be set")
+	}
+
+	if req.ViewID != "" {
+		view, err := repository.NewViewRepository(s.db.DB).GetByID(ctx, projectID, req.ViewID)
+		if err != nil {
+			return "", err
+		}
+		return view.RawQuery, nil
+	}
+
+	if strings.TrimSpace(req.Query) == "" {
+		return "", fmt.Errorf("query or view_id is required")
+	}
+
+	return req.Query, nil
+}
+
+// bulkAuditEntry builds the audit log entry for a committed query-driven bulk request
+func bulkAuditEntry(projectID string, req *BulkByQueryRequest, rawQuery string, ops []BulkOperation, result *BulkByQueryResult) *models.AuditLog {
+	taskResults := make([]map[string]interface{}, 0, len(result.Results))
+	for _, r := range result.Results {
+		entry := map[string]interface{}{
+			"task_id": r.TaskID,
+			"status":  r.Status,
+		}
+		if len(r.Changes) > 0 {
+			entry["changes"] = r.Changes
+		}
+		if r.Error != "" {
+			entry["error"] = r.Error
+		}
+		taskResults = append(taskResults, entry)
+	}
+
+	detail := models.JSONB{
+		"query":        rawQuery,
+		"operations":   ops,
+		"mode":         req.Mode,
+		"matched":      result.Matched,
+		"max_affected": result.MaxAffected,
+		"failed_count": result.FailedCount,
+		"results":      taskResults,
+	}
+	if req.ViewID != "" {
+		detail["view_id"] = req.ViewID
+	}
+
+	entry := &models.AuditLog{
+		Action:     models.AuditActionTaskBulkUpdate,
+		TargetType: "project",
+		TargetID:   projectID,
+		Detail:     detail,
+	}
+	if req.UpdatedBy != "" {
+		entry.ActorUserID = &req.UpdatedBy
+	}
+
+	return entry
+}
@@ -0,0 +1,647 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"time"
+
+	"github.com/jmoiron/sqlx"
+	"github.com/tktomaru/taskai/taskai-server/internal/database"
+	"github.com/tktomaru/taskai/taskai-server/internal/models"
+	"github.com/tktomaru/taskai/taskai-server/internal/parser"
+	"github.com/tktomaru/taskai/taskai-server/internal/repository"
+)
+
+// BulkMode controls how a bulk operation handles per-task failures
+type BulkMode string
+
+const (
+	// BulkModeAllOrNothing rolls back every change if any task fails
+	BulkModeAllOrNothing BulkMode = "all_or_nothing"
+	// BulkModeBestEffort keeps the changes of the tasks that succeeded
+	BulkModeBestEffort BulkMode = "best_effort"
+)
+
+// BulkOperationType represents a single change applied to every task of a bulk request
+type BulkOperationType string
+
+const (
+	BulkOpSetStatus      BulkOperationType = "set_status"
+	BulkOpSetPriority    BulkOperationType = "set_priority"
+	BulkOpSetAssignees   BulkOperationType = "set_assignees"
+	BulkOpSetLabels      BulkOperationType = "set_labels"
+	BulkOpAddLabel       BulkOperationType = "add_label"
+	BulkOpRemoveLabel    BulkOperationType = "remove_label"
+	BulkOpAddAssignee    BulkOperationType = "add_assignee"
+	BulkOpRemoveAssignee BulkOperationType = "remove_assignee"
+	BulkOpSetDueDate     BulkOperationType = "set_due_date"
+	BulkOpMoveProject    BulkOperationType = "move_project"
+	BulkOpArchive        BulkOperationType = "archive"
+	BulkOpDelete         BulkOperationType = "delete"
+)
+
+// BulkResultStatus represents the outcome for a single task
+type BulkResultStatus string
+
+const (
+	BulkResultCreated    BulkResultStatus = "created"
+	BulkResultUpdated    BulkResultStatus = "updated"
+	BulkResultMoved      BulkResultStatus = "moved"
+	BulkResultDeleted    BulkResultStatus = "deleted"
+	BulkResultUnchanged  BulkResultStatus = "unchanged"
+	BulkResultFailed     BulkResultStatus = "failed"
+	BulkResultRolledBack BulkResultStatus = "rolled_back"
+)
+
+// errBulkRollback is returned from the transaction function to discard the changes
+// of a dry run or a failed all-or-nothing request
+var errBulkRollback = errors.New("bulk operation rolled back")
+
+// BulkOperation represents one change of a bulk request.
+// Value holds a single value (status, priority, label, assignee, date, project ID);
+// Values holds the list for set_assignees and set_labels.
+type BulkOperation struct {
+	Op     BulkOperationType `json:"op"`
+	Value  string            `json:"value,omitempty"`
+	Values []string          `json:"values,omitempty"`
+}
+
+// BulkUpdateRequest represents a request to apply operations to multiple tasks.
+// Updates is the legacy field map (status, priority, assignees, labels) and is
+// converted to operations.
+type BulkUpdateRequest struct {
+	TaskIDs    []string               `json:"task_ids"`
+	Operations []BulkOperation        `json:"operations"`
+	Updates    map[string]interface{} `json:"updates,omitempty"`
+	Mode       BulkMode               `json:"mode"`
+	DryRun     bool                   `json:"dry_run"`
+	UpdatedBy  string                 `json:"updated_by,omitempty"`
+}
+
+// BulkTaskResult represents the outcome of a bulk request for one task
+type BulkTaskResult struct {
+	TaskID    string           `json:"task_id"`
+	Status    BulkResultStatus `json:"status"`
+	Changes   []string         `json:"changes,omitempty"`
+	Error     string           `json:"error,omitempty"`
+	ProjectID string           `json:"project_id,omitempty"`
+	Task      *models.Task     `json:"task,omitempty"`
+
+	// previous is the task before the operations, used for notifications
+	previous *models.Task
+}
+
+// BulkUpdateResult represents the outcome of a bulk request
+type BulkUpdateResult struct {
+	Mode         BulkMode          `json:"mode"`
+	DryRun       bool              `json:"dry_run"`
+	Committed    bool              `json:"committed"`
+	UpdatedCount int               `json:"updated_count"`
+	FailedCount  int               `json:"failed_count"`
+	Results      []*BulkTaskResult `json:"results"`
+}
+
+// BulkService applies operations to multiple tasks inside a single transaction
+type BulkService struct {
+	db            *database.DB
+	recurrence    *RecurrenceService
+	references    *ReferenceService
+	notifications *NotificationService
+
+	// AuthorizeMove checks that the caller may move tasks into a project.
+	// Requests with move_project are refused when it is not set.
+	AuthorizeMove func(ctx context.Context, projectID string) error
+}
+
+// NewBulkService creates a new bulk service
+func NewBulkService(db *database.DB) *BulkService {
+	return &BulkService{db: db}
+}
+
+// SetRecurrence sets the recurrence service used to sync series of imported tasks
+func (s *BulkService) SetRecurrence(recurrence *RecurrenceService) {
+	s.recurrence = recurrence
+}
+
+// SetReferences sets the reference service used to sync references of imported tasks and
+// to flag references to tasks removed by bulk requests
+func (s *BulkService) SetReferences(references *ReferenceService) {
+	s.references = references
+}
+
+// SetNotifications sets the notification service used to notify about imported and bulk-updated tasks
+func (s *BulkService) SetNotifications(notifications *NotificationService) {
+	s.notifications = notifications
+}
+
+// Apply applies the operations of a request to every task.
+// The whole transaction is rolled back for dry runs and for all-or-nothing
+// requests with at least one failure.
+func (s *BulkService) Apply(ctx context.Context, projectID string, req *BulkUpdateRequest) (*BulkUpdateResult, error) {
+	ops, err := normalizeBulkRequest(req)
+	if err != nil {
+		return nil, err
+	}
+	if err := s.authorizeMoves(ctx, ops); err != nil {
+		return nil, err
+	}
+
+	result := &BulkUpdateResult{
+		Mode:    req.Mode,
+		DryRun:  req.DryRun,
+		Results: make([]*BulkTaskResult, 0, len(req.TaskIDs)),
+	}
+
+	err = s.db.Transaction(ctx, func(tx *sqlx.Tx) error {
+		if err := s.applyInTx(ctx, tx, projectID, req.TaskIDs, ops, req.UpdatedBy, result); err != nil {
+			return err
+		}
+
+		if req.DryRun || (req.Mode == BulkModeAllOrNothing && result.FailedCount > 0) {
+			return errBulkRollback
+		}
+
+		return nil
+	})
+
+	if err := finishBulkResult(result, err); err != nil {
+		return nil, err
+	}
+
+	if result.Committed {
+		s.flagDeletedReferences(ctx, result)
+		s.notifyBulk(ctx, result, req.UpdatedBy)
+	}
+
+	return result, nil
+}
+
+// flagDeletedReferences flags the references to tasks deleted or archived by a committed
+// bulk request as dangling, as deleting a single task does
+func (s *BulkService) flagDeletedReferences(ctx context.Context, result *BulkUpdateResult) {
+	if s.references == nil {
+		return
+	}
+
+	for _, taskID := range removedTaskIDs(result) {
+		if err := s.references.HandleDeleted(ctx, taskID); err != nil {
+			log.Printf("WARNING: Failed to flag references to task %s: %v", taskID, err)
+		}
+	}
+}
+
+// removedTaskIDs returns the tasks a bulk request deleted or archived
+func removedTaskIDs(result *BulkUpdateResult) []string {
+	var taskIDs []string
+	for _, r := range result.Results {
+		switch {
+		case r.Status == BulkResultDeleted:
+			taskIDs = append(taskIDs, r.TaskID)
+		case (r.Status == BulkResultUpdated || r.Status == BulkResultMoved) && r.Task != nil && r.Task.ArchivedAt != nil:
+			taskIDs = append(taskIDs, r.TaskID)
+		}
+	}
+	return taskIDs
+}
+
+// notifyBulk sends the notifications of changed tasks after a bulk request is committed
+func (s *BulkService) notifyBulk(ctx context.Context, result *BulkUpdateResult, updatedBy string) {
+	if s.notifications == nil {
+		return
+	}
+
+	for _, r := range result.Results {
+		var err error
+		switch r.Status {
+		case BulkResultUpdated, BulkResultMoved:
+			err = s.notifications.NotifyTaskSaved(ctx, r.previous, r.Task, updatedBy)
+		case BulkResultDeleted:
+			err = s.notifications.NotifyTaskDeleted(ctx, r.previous, updatedBy)
+		default:
+			continue
+		}
+		if err != nil {
+			log.Printf("WARNING: Failed to send notifications for task %s: %v", r.TaskID, err)
+		}
+	}
+}
+
+// authorizeMoves checks the target projects of the move_project operations
+func (s *BulkService) authorizeMoves(ctx context.Context, ops []BulkOperation) error {
+	for _, op := range ops {
+		if op.Op != BulkOpMoveProject {
+			continue
+		}
+		if s.AuthorizeMove == nil {
+			return fmt.Errorf("moving tasks to another project is not supported here")
+		}
+		if err := s.AuthorizeMove(ctx, op.Value); err != nil {
+			return fmt.Errorf("cannot move tasks to project %s: %w", op.Value, err)
+		}
+	}
+
+	return nil
+}
+
+// applyInTx applies the operations to every task inside a transaction.
+// Each task runs inside its own savepoint so that a failing task does not abort the transaction.
+func (s *BulkService) applyInTx(ctx context.Context, tx *sqlx.Tx, projectID string, taskIDs []string, ops []BulkOperation, updatedBy string, result *BulkUpdateResult) error {
+	taskRepo := repository.NewTaskRepository(s.db.DB).WithTx(tx)
+
+	for _, taskID := range taskIDs {
+		if _, err := tx.ExecContext(ctx, "SAVEPOINT bulk_task"); err != nil {
+			return fmt.Errorf("failed to create savepoint: %w", err)
+		}
+
+		taskResult := applyBulkToTask(ctx, taskRepo, projectID, taskID, ops, updatedBy)
+		result.Results = append(result.Results, taskResult)
+
+		if taskResult.Status == BulkResultFailed {
+			result.FailedCount++
+			if _, err := tx.ExecContext(ctx, "ROLLBACK TO SAVEPOINT bulk_task"); err != nil {
+				return fmt.Errorf("failed to roll back savepoint: %w", err)
+			}
+			continue
+		}
+
+		if _, err := tx.ExecContext(ctx, "RELEASE SAVEPOINT bulk_task"); err != nil {
+			return fmt.Errorf("failed to release savepoint: %w", err)
+		}
+	}
+
+	return nil
+}
+
+// finishBulkResult sets the commit state and counts from the transaction outcome
+func finishBulkResult(result *BulkUpdateResult, txErr error) error {
+	switch {
+	case txErr == nil:
+		result.Committed = true
+	case errors.Is(txErr, errBulkRollback):
+		result.Committed = false
+	default:
+		return txErr
+	}
+
+	for _, r := range result.Results {
+		if r.Status == BulkResultFailed || r.Status == BulkResultUnchanged {
+			continue
+		}
+		// A failed all-or-nothing request discards the changes of successful tasks
+		if !result.Committed && !result.DryRun {
+			r.Status = BulkResultRolledBack
+			continue
+		}
+		result.UpdatedCount++
+	}
+
+	return nil
+}
+
+// applyBulkToTask applies the operations to a single task
+func applyBulkToTask(ctx context.Context, taskRepo *repository.TaskRepository, projectID, taskID string, ops []BulkOperation, updatedBy string) *BulkTaskResult {
+	result := &BulkTaskResult{TaskID: taskID, ProjectID: projectID}
+
+	fail := func(err error) *BulkTaskResult {
+		result.Status = BulkResultFailed
+		result.Error = err.Error()
+		result.Task = nil
+		return result
+	}
+
+	task, err := taskRepo.GetByIDForUpdate(ctx, projectID, taskID)
+	if err != nil {
+		return fail(err)
+	}
+
+	result.previous = snapshotTask(task)
+
+	// Delete takes precedence over every other operation
+	for _, op := range ops {
+		if op.Op == BulkOpDelete {
+			if err := taskRepo.Delete(ctx, projectID, taskID); err != nil {
+				return fail(err)
+			}
+			result.Status = BulkResultDeleted
+			result.Changes = []string{"deleted"}
+			return result
+		}
+	}
+
+	changes, targetProjectID, err := applyBulkOperations(task, ops, time.Now())
+	if err != nil {
+		return fail(err)
+	}
+
+	if len(changes) == 0 {
+		result.Status = BulkResultUnchanged
+		result.Task = task
+		return result
+	}
+
+	task.UpdatedBy = &updatedBy
+	task.UpdatedAt = time.Now()
+
+	// Rewrite only the frontmatter so the prose and unknown keys stay untouched. Only a
+	// task without frontmatter is regenerated, as it has no keys to lose.
+	markdown, err := parser.PatchFrontmatter(task.MarkdownBody, bulkFrontmatterPatch(task))
+	if err != nil {
+		if parser.HasFrontmatter(task.MarkdownBody) {
+			return fail(fmt.Errorf("failed to update frontmatter: %w", err))
+		}
+		markdown = parser.GenerateMarkdown(task)
+	}
+	task.MarkdownBody = markdown
+
+	if err := taskRepo.Update(ctx, task); err != nil {
+		return fail(err)
+	}
+
+	result.Status = BulkResultUpdated
+
+	if targetProjectID != "" {
+		if err := taskRepo.Move(ctx, projectID, taskID, targetProjectID); err != nil {
+			return fail(err)
+		}
+		task.ProjectID = targetProjectID
+		result.ProjectID = targetProjectID
+		result.Status = BulkResultMoved
+	}
+
+	// Archived tasks are soft-deleted like deleted ones, so that lists and queries skip them
+	if task.ArchivedAt != nil {
+		if err := taskRepo.Delete(ctx, task.ProjectID, taskID); err != nil {
+			return fail(err)
+		}
+	}
+
+	result.Changes = changes
+	result.Task = task
+	return result
+}
+
+// snapshotTask copies a task before bulk operations change it, for notifications.
+// The slices are copied too, as the operations append to them in place.
+func snapshotTask(task *models.Task) *models.Task {
+	snapshot := *task
+	snapshot.Assignees = append(models.StringArray{}, task.Assignees...)
+	snapshot.Labels = append(models.StringArray{}, task.Labels...)
+	return &snapshot
+}
+
+// bulkFrontmatterPatch builds the frontmatter merge patch for the fields bulk operations can change
+func bulkFrontmatterPatch(task *models.Task) map[string]interface{} {
+	patch := map[string]interface{}{
+		"status":    string(task.Status),
+		"priority":  string(task.Priority),
+		"assignees": toInterfaces(task.Assignees),
+		"labels":    toInterfaces(task.Labels),
+		"due_date":  nil,
+	}
+	if task.DueDate != nil {
+		patch["due_date"] = task.DueDate.Format("2006-01-02")
+	}
+	return patch
+}
+
+// toInterfaces converts a string slice into a JSON-style array
+func toInterfaces(values []string) []interface{} {
+	result := make([]interface{}, len(values))
+	for i, v := range values {
+		result[i] = v
+	}
+	return result
+}
+
+// applyBulkOperations applies field operations to a task in memory.
+// It returns a description of each change and the target project of a move.
+func applyBulkOperations(task *models.Task, ops []BulkOperation, now time.Time) ([]string, string, error) {
+	var changes []string
+	targetProjectID := ""
+
+	for _, op := range ops {
+		switch op.Op {
+		case BulkOpSetStatus:
+			if task.Status != models.TaskStatus(op.Value) {
+				changes = append(changes, fmt.Sprintf("status: %s -> %s", task.Status, op.Value))
+				task.Status = models.TaskStatus(op.Value)
+			}
+
+		case BulkOpArchive:
+			if task.Status != models.TaskStatusArchived {
+				changes = append(changes, fmt.Sprintf("status: %s -> %s", task.Status, models.TaskStatusArchived))
+				task.Status = models.TaskStatusArchived
+			} else if task.ArchivedAt == nil {
+				changes = append(changes, "archived")
+			}
+			if task.ArchivedAt == nil {
+				task.ArchivedAt = &now
+			}
+
+		case BulkOpSetPriority:
+			if task.Priority != models.TaskPriority(op.Value) {
+				changes = append(changes, fmt.Sprintf("priority: %s -> %s", task.Priority, op.Value))
+				task.Priority = models.TaskPriority(op.Value)
+			}
+
+		case BulkOpSetAssignees:
+			if !equalStrings(task.Assignees, op.Values) {
+				changes = append(changes, fmt.Sprintf("assignees: %v -> %v", []string(task.Assignees), op.Values))
+				task.Assignees = models.StringArray(append([]string{}, op.Values...))
+			}
+
+		case BulkOpSetLabels:
+			if !equalStrings(task.Labels, op.Values) {
+				changes = append(changes, fmt.Sprintf("labels: %v -> %v", []string(task.Labels), op.Values))
+				task.Labels = models.StringArray(append([]string{}, op.Values...))
+			}
+
+		case BulkOpAddLabel:
+			if !containsString(task.Labels, op.Value) {
+				task.Labels = append(task.Labels, op.Value)
+				changes = append(changes, "label added: "+op.Value)
+			}
+
+		case BulkOpRemoveLabel:
+			if containsString(task.Labels, op.Value) {
+				task.Labels = removeString(task.Labels, op.Value)
+				changes = append(changes, "label removed: "+op.Value)
+			}
+
+		case BulkOpAddAssignee:
+			if !containsString(task.Assignees, op.Value) {
+				task.Assignees = append(task.Assignees, op.Value)
+				changes = append(changes, "assignee added: "+op.Value)
+			}
+
+		case BulkOpRemoveAssignee:
+			if containsString(task.Assignees, op.Value) {
+				task.Assignees = removeString(task.Assignees, op.Value)
+				changes = append(changes, "assignee removed: "+op.Value)
+			}
+
+		case BulkOpSetDueDate:
+			if op.Value == "" {
+				if task.DueDate != nil {
+					changes = append(changes, "due_date cleared")
+					task.DueDate = nil
+				}
+				continue
+			}
+			due, err := time.Parse("2006-01-02", op.Value)
+			if err != nil {
+				return nil, "", fmt.Errorf("invalid due date: %s", op.Value)
+			}
+			if task.StartDate != nil && due.Before(*task.StartDate) {
+				return nil, "", fmt.Errorf("due date %s is before start date %s", op.Value, task.StartDate.Format("2006-01-02"))
+			}
+			if task.DueDate == nil || !task.DueDate.Equal(due) {
+				changes = append(changes, "due_date: "+op.Value)
+				task.DueDate = &due
+			}
+
+		case BulkOpMoveProject:
+			if op.Value != task.ProjectID {
+				targetProjectID = op.Value
+				changes = append(changes, fmt.Sprintf("project: %s -> %s", task.ProjectID, op.Value))
+			}
+		}
+	}
+
+	// Update status-specific timestamps
+	if task.Status == models.TaskStatusDone && task.CompletedAt == nil {
+		task.CompletedAt = &now
+	}
+
+	return changes, targetProjectID, nil
+}
+
+// normalizeBulkRequest validates a bulk request and returns its operations
+func normalizeBulkRequest(req *BulkUpdateRequest) ([]BulkOperation, error) {
+	if len(req.TaskIDs) == 0 {
+		return nil, fmt.Errorf("task_ids is required")
+	}
+
+	return normalizeBulkOperations(&req.Mode, req.Operations, req.Updates)
+}
+
+// normalizeBulkOperations defaults and validates the mode and returns the operations,
+// including the ones converted from the legacy updates map
+func normalizeBulkOperations(mode *BulkMode, operations []BulkOperation, updates map[string]interface{}) ([]BulkOperation, error) {
+	if *mode == "" {
+		*mode = BulkModeBestEffort
+	}
+	if *mode != BulkModeAllOrNothing && *mode != BulkModeBestEffort {
+		return nil, fmt.Errorf("invalid mode: %s (must be one of: all_or_nothing, best_effort)", *mode)
+	}
+
+	ops := append([]BulkOperation{}, operations...)
+
+	if status, ok := updates["status"].(string); ok && status != "" {
+		ops = append(ops, BulkOperation{Op: BulkOpSetStatus, Value: status})
+	}
+	if priority, ok := updates["priority"].(string); ok && priority != "" {
+		ops = append(ops, BulkOperation{Op: BulkOpSetPriority, Value: priority})
+	}
+	if assignees, ok := updates["assignees"].([]interface{}); ok {
+		ops = append(ops, BulkOperation{Op: BulkOpSetAssignees, Values: toStrings(assignees)})
+	}
+	if labels, ok := updates["labels"].([]interface{}); ok {
+		ops = append(ops, BulkOperation{Op: BulkOpSetLabels, Values: toStrings(labels)})
+	}
+
+	if len(ops) == 0 {
+		return nil, fmt.Errorf("at least one operation is required")
+	}
+
+	for _, op := range ops {
+		if err := validateBulkOperation(op); err != nil {
+			return nil, err
+		}
+	}
+
+	return ops, nil
+}
+
+// validateBulkOperation checks that an operation is known and has the value it needs
+func validateBulkOperation(op BulkOperation) error {
+	switch op.Op {
+	case BulkOpSetStatus:
+		switch models.TaskStatus(op.Value) {
+		case models.TaskStatusOpen, models.TaskStatusInProgress, models.TaskStatusReview,
+			models.TaskStatusBlocked, models.TaskStatusDone, models.TaskStatusArchived:
+			return nil
+		}
+		return fmt.Errorf("invalid status: %s (must be one of: open, in_progress, review, blocked, done, archived)", op.Value)
+
+	case BulkOpSetPriority:
+		switch models.TaskPriority(op.Value) {
+		case models.TaskPriorityP0, models.TaskPriorityP1, models.TaskPriorityP2,
+			models.TaskPriorityP3, models.TaskPriorityP4:
+			return nil
+		}
+		return fmt.Errorf("invalid priority: %s (must be one of: P0, P1, P2, P3, P4)", op.Value)
+
+	case BulkOpAddLabel, BulkOpRemoveLabel, BulkOpAddAssignee, BulkOpRemoveAssignee, BulkOpMoveProject:
+		if op.Value == "" {
+			return fmt.Errorf("%s requires a value", op.Op)
+		}
+		return nil
+
+	case BulkOpSetDueDate:
+		if op.Value != "" {
+			if _, err := time.Parse("2006-01-02", op.Value); err != nil {
+				return fmt.Errorf("invalid due date: %s (must be YYYY-MM-DD)", op.Value)
+			}
+		}
+		return nil
+
+	case BulkOpSetAssignees, BulkOpSetLabels, BulkOpArchive, BulkOpDelete:
+		return nil
+	}
+
+	return fmt.Errorf("unsupported operation: %s", op.Op)
+}
+
+func toStrings(values []interface{}) []string {
+	result := make([]string, 0, len(values))
+	for _, v := range values {
+		if str, ok := v.(string); ok {
+			result = append(result, str)
+		}
+	}
+	return result
+}
+
+func containsString(values []string, value string) bool {
+	for _, v := range values {
+		if v == value {
+			return true
+		}
+	}
+	return false
+}
+
+func removeString(values []string, value string) []string {
+	result := make([]string, 0, len(values))
+	for _, v := range values {
+		if v != value {
+			result = append(result, v)
+		}
+	}
+	return result
+}
+
+func equalStrings(a, b []string) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if a[i] != b[i] {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,351 @@
+package service
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/tktomaru/taskai/taskai-server/internal/models"
+)
+
+func newBulkTestTask() *models.Task {
+	start := time.Date(2026, 1, 5, 0, 0, 0, 0, time.UTC)
+	return &models.Task{
+		ID:        "T-1",
+		ProjectID: "proj-a",
+		Status:    models.TaskStatusOpen,
+		Priority:  models.TaskPriorityP2,
+		Assignees: models.StringArray{"taku"},
+		Labels:    models.StringArray{"backend"},
+		StartDate: &start,
+	}
+}
+
+func TestApplyBulkOperations(t *testing.T) {
+	now := time.Date(2026, 1, 10, 0, 0, 0, 0, time.UTC)
+
+	tests := []struct {
+		name        string
+		ops         []BulkOperation
+		wantChanges int
+		wantMove    string
+		wantErr     bool
+		check       func(t *testing.T, task *models.Task)
+	}{
+		{
+			name:        "add and remove label",
+			ops:         []BulkOperation{{Op: BulkOpAddLabel, Value: "urgent"}, {Op: BulkOpRemoveLabel, Value: "backend"}},
+			wantChanges: 2,
+			check: func(t *testing.T, task *models.Task) {
+				if len(task.Labels) != 1 || task.Labels[0] != "urgent" {
+					t.Errorf("labels = %v, want [urgent]", task.Labels)
+				}
+			},
+		},
+		{
+			name:        "add existing assignee is a no-op",
+			ops:         []BulkOperation{{Op: BulkOpAddAssignee, Value: "taku"}},
+			wantChanges: 0,
+		},
+		{
+			name:        "remove assignee",
+			ops:         []BulkOperation{{Op: BulkOpRemoveAssignee, Value: "taku"}},
+			wantChanges: 1,
+			check: func(t *testing.T, task *models.Task) {
+				if len(task.Assignees) != 0 {
+					t.Errorf("assignees = %v, want empty", task.Assignees)
+				}
+			},
+		},
+		{
+			name:        "set due date",
+			ops:         []BulkOperation{{Op: BulkOpSetDueDate, Value: "2026-01-20"}},
+			wantChanges: 1,
+			check: func(t *testing.T, task *models.Task) {
+				if task.DueDate == nil || task.DueDate.Format("2006-01-02") != "2026-01-20" {
+					t.Errorf("due date = %v, want 2026-01-20", task.DueDate)
+				}
+			},
+		},
+		{
+			name:    "due date before start date",
+			ops:     []BulkOperation{{Op: BulkOpSetDueDate, Value: "2026-01-01"}},
+			wantErr: true,
+		},
+		{
+			name:        "move project",
+			ops:         []BulkOperation{{Op: BulkOpMoveProject, Value: "proj-b"}},
+			wantChanges: 1,
+			wantMove:    "proj-b",
+		},
+		{
+			name:        "move to same project is a no-op",
+			ops:         []BulkOperation{{Op: BulkOpMoveProject, Value: "proj-a"}},
+			wantChanges: 0,
+		},
+		{
+			name:        "archive",
+			ops:         []BulkOperation{{Op: BulkOpArchive}},
+			wantChanges: 1,
+			check: func(t *testing.T, task *models.Task) {
+				if task.Status != models.TaskStatusArchived {
+					t.Errorf("status = %v, want archived", task.Status)
+				}
+				if task.ArchivedAt == nil {
+					t.Error("archived_at is not set")
+				}
+			},
+		},
+		{
+			name:        "done sets completed_at",
+			ops:         []BulkOperation{{Op: BulkOpSetStatus, Value: "done"}},
+			wantChanges: 1,
+			check: func(t *testing.T, task *models.Task) {
+				if task.CompletedAt == nil || !task.CompletedAt.Equal(now) {
+					t.Errorf("completed_at = %v, want %v", task.CompletedAt, now)
+				}
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			task := newBulkTestTask()
+			changes, move, err := applyBulkOperations(task, tt.ops, now)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("applyBulkOperations() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if tt.wantErr {
+				return
+			}
+			if len(changes) != tt.wantChanges {
+				t.Errorf("changes = %v, want %d changes", changes, tt.wantChanges)
+			}
+			if move != tt.wantMove {
+				t.Errorf("target project = %q, want %q", move, tt.wantMove)
+			}
+			if tt.check != nil {
+				tt.check(t, task)
+			}
+		})
+	}
+}
+
+func TestNormalizeBulkRequest(t *testing.T) {
+	tests := []struct {
+		name    string
+		req     *BulkUpdateRequest
+		wantOps int
+		wantErr bool
+	}{
+		{
+			name: "legacy updates",
+			req: &BulkUpdateRequest{
+				TaskIDs: []string{"T-1"},
+				Updates: map[string]interface{}{"status": "done", "labels": []interface{}{"a", "b"}},
+			},
+			wantOps: 2,
+		},
+		{
+			name: "operations",
+			req: &BulkUpdateRequest{
+				TaskIDs:    []string{"T-1"},
+				Operations: []BulkOperation{{Op: BulkOpAddLabel, Value: "x"}, {Op: BulkOpDelete}},
+				Mode:       BulkModeAllOrNothing,
+			},
+			wantOps: 2,
+		},
+		{
+			name:    "no task IDs",
+			req:     &BulkUpdateRequest{Operations: []BulkOperation{{Op: BulkOpArchive}}},
+			wantErr: true,
+		},
+		{
+			name:    "no operations",
+			req:     &BulkUpdateRequest{TaskIDs: []string{"T-1"}},
+			wantErr: true,
+		},
+		{
+			name:    "unknown operation",
+			req:     &BulkUpdateRequest{TaskIDs: []string{"T-1"}, Operations: []BulkOperation{{Op: "rename"}}},
+			wantErr: true,
+		},
+		{
+			name:    "invalid mode",
+			req:     &BulkUpdateRequest{TaskIDs: []string{"T-1"}, Operations: []BulkOperation{{Op: BulkOpArchive}}, Mode: "sometimes"},
+			wantErr: true,
+		},
+		{
+			name:    "label without value",
+			req:     &BulkUpdateRequest{TaskIDs: []string{"T-1"}, Operations: []BulkOperation{{Op: BulkOpAddLabel}}},
+			wantErr: true,
+		},
+		{
+			name:    "invalid priority",
+			req:     &BulkUpdateRequest{TaskIDs: []string{"T-1"}, Operations: []BulkOperation{{Op: BulkOpSetPriority, Value: "P9"}}},
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ops, err := normalizeBulkRequest(tt.req)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("normalizeBulkRequest() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if !tt.wantErr && len(ops) != tt.wantOps {
+				t.Errorf("normalizeBulkRequest() ops = %v, want %d", ops, tt.wantOps)
+			}
+			if !tt.wantErr && tt.req.Mode == "" {
+				t.Errorf("normalizeBulkRequest() did not default the mode")
+			}
+		})
+	}
+}
+
+func TestBulkAuditEntry(t *testing.T) {
+	req := &BulkByQueryRequest{
+		ViewID:    "proj-a-view-legacy",
+		Mode:      BulkModeBestEffort,
+		UpdatedBy: "taku",
+	}
+	ops := []BulkOperation{{Op: BulkOpSetPriority, Value: "P3"}}
+	result := &BulkByQueryResult{
+		BulkUpdateResult: &BulkUpdateResult{
+			FailedCount: 1,
+			Results: []*BulkTaskResult{
+				{TaskID: "T-1", Status: BulkResultUpdated, Changes: []string{"priority: P2 -> P3"}},
+				{TaskID: "T-2", Status: BulkResultFailed, Error: "task not found"},
+			},
+		},
+		Matched:     2,
+		MaxAffected: DefaultMaxAffected,
+	}
+
+	entry := bulkAuditEntry("proj-a", req, "priority:P2 label:legacy", ops, result)
+
+	if entry.Action != models.AuditActionTaskBulkUpdate {
+		t.Errorf("action = %v, want %v", entry.Action, models.AuditActionTaskBulkUpdate)
+	}
+	if entry.TargetType != "project" || entry.TargetID != "proj-a" {
+		t.Errorf("target = %s/%s, want project/proj-a", entry.TargetType, entry.TargetID)
+	}
+	if entry.ActorUserID == nil || *entry.ActorUserID != "taku" {
+		t.Errorf("actor = %v, want taku", entry.ActorUserID)
+	}
+	if entry.Detail["query"] != "priority:P2 label:legacy" || entry.Detail["view_id"] != "proj-a-view-legacy" {
+		t.Errorf("detail = %v, want query and view_id", entry.Detail)
+	}
+	results, ok := entry.Detail["results"].([]map[string]interface{})
+	if !ok || len(results) != 2 {
+		t.Fatalf("detail results = %v, want 2 entries", entry.Detail["results"])
+	}
+	if results[1]["error"] != "task not found" {
+		t.Errorf("failed result = %v, want error", results[1])
+	}
+}
+
+func TestBuildBulkQuery_NoLimit(t *testing.T) {
+	for _, rawQuery := range []string{"status:open", "status:open limit:10"} {
+		built, err := buildBulkQuery("proj-a", rawQuery, nil)
+		if err != nil {
+			t.Fatalf("buildBulkQuery(%q) error: %v", rawQuery, err)
+		}
+		if strings.Contains(built.SQL, "LIMIT") {
+			t.Errorf("buildBulkQuery(%q) SQL = %s, want no LIMIT", rawQuery, built.SQL)
+		}
+	}
+}
+
+func TestBulkByQueryResult_Match(t *testing.T) {
+	matched := make([]*models.Task, 150)
+	for i := range matched {
+		matched[i] = &models.Task{ID: fmt.Sprintf("T-%d", i+1), Status: models.TaskStatusOpen}
+	}
+
+	tests := []struct {
+		maxAffected  int
+		wantExceeded bool
+	}{
+		{100, true},
+		{149, true},
+		{150, false},
+		{500, false},
+	}
+
+	for _, tt := range tests {
+		result := &BulkByQueryResult{MaxAffected: tt.maxAffected}
+		taskIDs := result.match(matched)
+
+		if len(taskIDs) != 150 || result.Matched != 150 || len(result.Tasks) != 150 {
+			t.Errorf("match() with max %d = %d IDs, matched %d, want 150", tt.maxAffected, len(taskIDs), result.Matched)
+		}
+		if result.Exceeded != tt.wantExceeded {
+			t.Errorf("match() with max %d exceeded = %v, want %v", tt.maxAffected, result.Exceeded, tt.wantExceeded)
+		}
+	}
+}
+
+func TestBulkAddAssigneeNotifiesNewAssignee(t *testing.T) {
+	directory := newMemberDirectory([]*models.User{
+		{ID: "u-taku", Email: "taku@example.com", Name: "Taku"},
+		{ID: "u-alice", Email: "alice@example.com", Name: "Alice"},
+	})
+
+	task := newBulkTestTask()
+	task.Assignees = make(models.StringArray, 1, 4)
+	task.Assignees[0] = "taku"
+	previous := snapshotTask(task)
+
+	ops := []BulkOperation{{Op: BulkOpAddAssignee, Value: "alice"}}
+	if _, _, err := applyBulkOperations(task, ops, time.Now()); err != nil {
+		t.Fatalf("applyBulkOperations() error: %v", err)
+	}
+
+	if len(previous.Assignees) != 1 {
+		t.Fatalf("snapshot assignees = %v, want [taku]", previous.Assignees)
+	}
+
+	var got []string
+	for _, n := range planTaskNotifications(previous, task, directory, nil, "u-taku") {
+		got = append(got, n.UserID+" "+string(n.Type))
+	}
+	if len(got) != 1 || got[0] != "u-alice assigned" {
+		t.Errorf("planTaskNotifications() = %v, want [u-alice assigned]", got)
+	}
+}
+
+func TestRemovedTaskIDs(t *testing.T) {
+	archived := newBulkTestTask()
+	archived.ID = "T-2"
+	if _, _, err := applyBulkOperations(archived, []BulkOperation{{Op: BulkOpArchive}}, time.Now()); err != nil {
+		t.Fatalf("applyBulkOperations() error: %v", err)
+	}
+	updated := newBulkTestTask()
+	updated.ID = "T-3"
+
+	results := func() *BulkUpdateResult {
+		return &BulkUpdateResult{Results: []*BulkTaskResult{
+			{TaskID: "T-1", Status: BulkResultDeleted},
+			{TaskID: "T-2", Status: BulkResultUpdated, Task: archived},
+			{TaskID: "T-3", Status: BulkResultUpdated, Task: updated},
+			{TaskID: "T-4", Status: BulkResultRolledBack},
+			{TaskID: "T-5", Status: BulkResultFailed, Error: "task not found"},
+		}}
+	}
+
+	tests := map[string]*BulkUpdateResult{
+		"bulk update":   results(),
+		"bulk by query": (&BulkByQueryResult{BulkUpdateResult: results(), Query: "status:open"}).BulkUpdateResult,
+	}
+
+	for name, result := range tests {
+		t.Run(name, func(t *testing.T) {
+			got := removedTaskIDs(result)
+			if strings.Join(got, ",") != "T-1,T-2" {
+				t.Errorf("removedTaskIDs() = %v, want [T-1 T-2]", got)
+			}
+		})
+	}
+}
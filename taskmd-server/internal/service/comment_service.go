@@ -0,0 +1,92 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"math/rand"
+	"strings"
+	"time"
+
+	"github.com/tktomaru/taskai/taskai-server/internal/models"
+	"github.com/tktomaru/taskai/taskai-server/internal/repository"
+)
+
+// generateCommentID generates a unique comment ID
+func generateCommentID() string {
+	const charset = "abcdefghijklmnopqrstuvwxyz0123456789"
+	timestamp := time.Now().Unix()
+
+	b := make([]byte, 6)
+	for i := range b {
+		b[i] = charset[rand.Intn(len(charset))]
+	}
+
+	return fmt.Sprintf("comment-%d-%s", timestamp, string(b))
+}
+
+// CreateCommentRequest represents a request to comment on a task
+type CreateCommentRequest struct {
+	MarkdownBody string `json:"markdown_body" binding:"required"`
+	AuthorUserID string `json:"-"`
+}
+
+// CommentService handles task comments
+type CommentService struct {
+	taskRepo      *repository.TaskRepository
+	commentRepo   *repository.CommentRepository
+	notifications *NotificationService
+}
+
+// NewCommentService creates a new comment service
+func NewCommentService(taskRepo *repository.TaskRepository, commentRepo *repository.CommentRepository) *CommentService {
+	return &CommentService{
+		taskRepo:    taskRepo,
+		commentRepo: commentRepo,
+	}
+}
+
+// SetNotifications enables notifications for mentions in comments and comments on watched tasks
+func (s *CommentService) SetNotifications(notifications *NotificationService) {
+	s.notifications = notifications
+}
+
+// Create adds a comment to a task
+func (s *CommentService) Create(ctx context.Context, projectID, taskID string, req *CreateCommentRequest) (*models.TaskComment, error) {
+	if strings.TrimSpace(req.MarkdownBody) == "" {
+		return nil, fmt.Errorf("comment body is required")
+	}
+
+	task, err := s.taskRepo.GetByID(ctx, projectID, taskID)
+	if err != nil {
+		return nil, err
+	}
+
+	comment := &models.TaskComment{
+		ID:           generateCommentID(),
+		TaskID:       task.ID,
+		MarkdownBody: req.MarkdownBody,
+		AuthorUserID: req.AuthorUserID,
+	}
+
+	if err := s.commentRepo.Create(ctx, comment); err != nil {
+		return nil, err
+	}
+
+	if s.notifications != nil {
+		if err := s.notifications.NotifyComment(ctx, task, comment); err != nil {
+			log.Printf("WARNING: Failed to send notifications for comment %s: %v", comment.ID, err)
+		}
+	}
+
+	return comment, nil
+}
+
+// List retrieves the comments of a task
+func (s *CommentService) List(ctx context.Context, projectID, taskID string) ([]*models.TaskComment, error) {
+	if _, err := s.taskRepo.GetByID(ctx, projectID, taskID); err != nil {
+		return nil, err
+	}
+
+	return s.commentRepo.ListByTask(ctx, taskID)
+}
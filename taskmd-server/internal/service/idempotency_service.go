@@ -0,0 +1,141 @@
+package service
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/tktomaru/taskai/taskai-server/internal/models"
+	"github.com/tktomaru/taskai/taskai-server/internal/repository"
+)
+
+const (
+	// maxIdempotencyKeyLength bounds the length of an Idempotency-Key header
+	maxIdempotencyKeyLength = 255
+
+	// IdempotencyLease is how long a key stays reserved for a request that has not finished.
+	// A key left behind by a crashed process can be used again once its lease has passed.
+	IdempotencyLease = 5 * time.Minute
+)
+
+var (
+	// ErrIdempotencyInProgress is returned while the first request with a key has not finished
+	ErrIdempotencyInProgress = errors.New("a request with this idempotency key is still being processed")
+
+	// ErrIdempotencyKeyReused is returned when a key is sent again with a different request
+	ErrIdempotencyKeyReused = errors.New("idempotency key was already used for a different request")
+)
+
+// IdempotencyService stores the responses of requests sent with an Idempotency-Key
+// so that retries within the replay window return the original response
+type IdempotencyService struct {
+	repo *repository.IdempotencyRepository
+	ttl  time.Duration
+}
+
+// NewIdempotencyService creates a new idempotency service whose responses are kept for ttl
+func NewIdempotencyService(repo *repository.IdempotencyRepository, ttl time.Duration) *IdempotencyService {
+	return &IdempotencyService{
+		repo: repo,
+		ttl:  ttl,
+	}
+}
+
+// Begin reserves a key for a request. It returns nil when the request should be processed
+// and the stored record when its response should be replayed.
+func (s *IdempotencyService) Begin(ctx context.Context, scope, key string, request []byte) (*models.IdempotencyKey, error) {
+	requestHash := hashIdempotentRequest(request)
+
+	// A record can expire or be released between Reserve and Get; try again once
+	for i := 0; i < 2; i++ {
+		reserved, err := s.repo.Reserve(ctx, &models.IdempotencyKey{
+			Scope:       scope,
+			Key:         key,
+			RequestHash: requestHash,
+			ExpiresAt:   time.Now().Add(IdempotencyLease),
+		})
+		if err != nil {
+			return nil, err
+		}
+		if reserved {
+			return nil, nil
+		}
+
+		record, err := s.repo.Get(ctx, scope, key)
+		if err != nil {
+			continue
+		}
+
+		if err := checkIdempotencyRecord(record, requestHash); err != nil {
+			return nil, err
+		}
+		return record, nil
+	}
+
+	return nil, ErrIdempotencyInProgress
+}
+
+// Complete stores the response of a reserved key for the replay window
+func (s *IdempotencyService) Complete(ctx context.Context, scope, key string, statusCode int, contentType string, body []byte) error {
+	return s.repo.Complete(ctx, scope, key, statusCode, contentType, body, time.Now().Add(s.ttl))
+}
+
+// Release gives up a reserved key without storing a response, so that the request can be retried
+func (s *IdempotencyService) Release(ctx context.Context, scope, key string) error {
+	return s.repo.Release(ctx, scope, key)
+}
+
+// Cleanup removes stored responses whose replay window has passed
+func (s *IdempotencyService) Cleanup(ctx context.Context, now time.Time) (int64, error) {
+	return s.repo.DeleteExpired(ctx, now)
+}
+
+// ValidateIdempotencyKey checks that a key is 1 to 255 printable ASCII characters
+func ValidateIdempotencyKey(key string) error {
+	if key == "" || len(key) > maxIdempotencyKeyLength {
+		return fmt.Errorf("idempotency key must be 1 to %d characters", maxIdempotencyKeyLength)
+	}
+
+	for _, r := range key {
+		if r < 0x21 || r > 0x7e {
+			return fmt.Errorf("idempotency key must consist of printable ASCII characters")
+		}
+	}
+
+	return nil
+}
+
+// IdempotencyScope identifies the requests a key is unique among: those sent by the same
+// caller to the same endpoint
+func IdempotencyScope(method, path, caller string) string {
+	return method + " " + path + " " + caller
+}
+
+// IdempotencyCaller identifies the sender of a request: its user, or its client IP address
+// for anonymous requests, so that anonymous clients cannot replay each other's responses
+func IdempotencyCaller(userID, clientIP string) string {
+	if userID != "" {
+		return "user:" + userID
+	}
+	return "ip:" + clientIP
+}
+
+// checkIdempotencyRecord decides whether a stored record can be replayed for a request
+func checkIdempotencyRecord(record *models.IdempotencyKey, requestHash string) error {
+	if record.RequestHash != requestHash {
+		return ErrIdempotencyKeyReused
+	}
+	if record.StatusCode == nil {
+		return ErrIdempotencyInProgress
+	}
+	return nil
+}
+
+// hashIdempotentRequest hashes a request so that a reused key can be detected
+func hashIdempotentRequest(request []byte) string {
+	sum := sha256.Sum256(request)
+	return hex.EncodeToString(sum[:])
+}
@@ -0,0 +1,74 @@
+package service
+
+import (
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/tktomaru/taskai/taskai-server/internal/models"
+)
+
+func TestCheckIdempotencyRecord(t *testing.T) {
+	request := hashIdempotentRequest([]byte(`{"markdown_body":"## T-1: Task"}`))
+	created := 201
+
+	tests := []struct {
+		name    string
+		record  *models.IdempotencyKey
+		hash    string
+		wantErr error
+	}{
+		{"completed request is replayed", &models.IdempotencyKey{RequestHash: request, StatusCode: &created}, request, nil},
+		{"unfinished request is in progress", &models.IdempotencyKey{RequestHash: request}, request, ErrIdempotencyInProgress},
+		{"different request reuses the key", &models.IdempotencyKey{RequestHash: request, StatusCode: &created}, hashIdempotentRequest([]byte(`{}`)), ErrIdempotencyKeyReused},
+		{"reuse is reported before progress", &models.IdempotencyKey{RequestHash: request}, hashIdempotentRequest([]byte(`{}`)), ErrIdempotencyKeyReused},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := checkIdempotencyRecord(tt.record, tt.hash)
+			if !errors.Is(err, tt.wantErr) {
+				t.Errorf("checkIdempotencyRecord() = %v, want %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestValidateIdempotencyKey(t *testing.T) {
+	tests := []struct {
+		key     string
+		wantErr bool
+	}{
+		{"8e03978e-40d5-43e8-bc93-6894a57f9324", false},
+		{"create-task:T-42", false},
+		{"", true},
+		{"has space", true},
+		{"キー", true},
+		{strings.Repeat("k", 255), false},
+		{strings.Repeat("k", 256), true},
+	}
+
+	for _, tt := range tests {
+		err := ValidateIdempotencyKey(tt.key)
+		if (err != nil) != tt.wantErr {
+			t.Errorf("ValidateIdempotencyKey(%q) error = %v, wantErr %v", tt.key, err, tt.wantErr)
+		}
+	}
+}
+
+func TestIdempotencyScope(t *testing.T) {
+	user := IdempotencyScope("POST", "/api/v1/projects/p1/tasks", IdempotencyCaller("u1", "203.0.113.7"))
+	if user != "POST /api/v1/projects/p1/tasks user:u1" {
+		t.Errorf("scope of a user = %q", user)
+	}
+
+	// Anonymous callers are told apart by their address
+	first := IdempotencyScope("POST", "/api/v1/task-packs", IdempotencyCaller("", "203.0.113.7"))
+	second := IdempotencyScope("POST", "/api/v1/task-packs", IdempotencyCaller("", "198.51.100.2"))
+	if first == second {
+		t.Errorf("anonymous callers share scope %q", first)
+	}
+	if first == IdempotencyScope("POST", "/api/v1/task-packs", IdempotencyCaller("203.0.113.7", "")) {
+		t.Errorf("anonymous scope %q collides with a user ID", first)
+	}
+}
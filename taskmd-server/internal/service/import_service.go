@@ -0,0 +1,267 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"strings"
+
+	"github.com/jmoiron/sqlx"
+	"github.com/tktomaru/taskai/taskai-server/internal/calendar"
+	"github.com/tktomaru/taskai/taskai-server/internal/models"
+	"github.com/tktomaru/taskai/taskai-server/internal/parser"
+	"github.com/tktomaru/taskai/taskai-server/internal/repository"
+)
+
+// ImportMarkdownRequest represents a request to import every task section of a Markdown document
+type ImportMarkdownRequest struct {
+	Markdown   string   `json:"markdown"`
+	Mode       BulkMode `json:"mode"`
+	DryRun     bool     `json:"dry_run"`
+	ImportedBy string   `json:"imported_by,omitempty"`
+}
+
+// ImportTaskResult represents the outcome of an import for one task section
+type ImportTaskResult struct {
+	TaskID    string           `json:"task_id"`
+	Title     string           `json:"title,omitempty"`
+	StartLine int              `json:"start_line"`
+	EndLine   int              `json:"end_line"`
+	Status    BulkResultStatus `json:"status"`
+	Error     string           `json:"error,omitempty"`
+	Task      *models.Task     `json:"task,omitempty"`
+
+	recurrence string
+	completed  bool
+	previous   *models.Task
+}
+
+// ImportMarkdownResult represents the outcome of a Markdown import
+type ImportMarkdownResult struct {
+	Mode         BulkMode            `json:"mode"`
+	DryRun       bool                `json:"dry_run"`
+	Committed    bool                `json:"committed"`
+	CreatedCount int                 `json:"created_count"`
+	UpdatedCount int                 `json:"updated_count"`
+	FailedCount  int                 `json:"failed_count"`
+	Results      []*ImportTaskResult `json:"results"`
+}
+
+// ImportMarkdown creates or updates every task section of a multi-task document
+// in a single transaction. Sections whose task already exists are updated;
+// the others are created.
+func (s *BulkService) ImportMarkdown(ctx context.Context, projectID string, req *ImportMarkdownRequest, cal *calendar.Calendar) (*ImportMarkdownResult, error) {
+	if req.Mode == "" {
+		req.Mode = BulkModeBestEffort
+	}
+	if req.Mode != BulkModeAllOrNothing && req.Mode != BulkModeBestEffort {
+		return nil, fmt.Errorf("invalid mode: %s (must be one of: all_or_nothing, best_effort)", req.Mode)
+	}
+
+	if strings.TrimSpace(req.Markdown) == "" {
+		return nil, fmt.Errorf("markdown is required")
+	}
+
+	sections := parser.NewMarkdownParser().ParseMany(req.Markdown)
+	if len(sections) == 0 {
+		return nil, fmt.Errorf("no task sections found (expected headings such as ## ID: Title)")
+	}
+
+	result := &ImportMarkdownResult{
+		Mode:    req.Mode,
+		DryRun:  req.DryRun,
+		Results: make([]*ImportTaskResult, 0, len(sections)),
+	}
+
+	err := s.db.Transaction(ctx, func(tx *sqlx.Tx) error {
+		taskRepo := repository.NewTaskRepository(s.db.DB).WithTx(tx)
+		taskService := NewTaskService(taskRepo)
+		taskService.SetCalendar(cal)
+
+		for _, section := range sections {
+			if _, err := tx.ExecContext(ctx, "SAVEPOINT import_task"); err != nil {
+				return fmt.Errorf("failed to create savepoint: %w", err)
+			}
+
+			sectionResult := importSection(ctx, taskService, taskRepo, projectID, section, req.ImportedBy)
+			result.Results = append(result.Results, sectionResult)
+
+			if sectionResult.Status == BulkResultFailed {
+				result.FailedCount++
+				if _, err := tx.ExecContext(ctx, "ROLLBACK TO SAVEPOINT import_task"); err != nil {
+					return fmt.Errorf("failed to roll back savepoint: %w", err)
+				}
+				continue
+			}
+
+			if _, err := tx.ExecContext(ctx, "RELEASE SAVEPOINT import_task"); err != nil {
+				return fmt.Errorf("failed to release savepoint: %w", err)
+			}
+		}
+
+		if req.DryRun || (req.Mode == BulkModeAllOrNothing && result.FailedCount > 0) {
+			return errBulkRollback
+		}
+
+		return nil
+	})
+
+	switch {
+	case err == nil:
+		result.Committed = true
+	case errors.Is(err, errBulkRollback):
+		result.Committed = false
+	default:
+		return nil, err
+	}
+
+	for _, r := range result.Results {
+		if r.Status != BulkResultCreated && r.Status != BulkResultUpdated {
+			continue
+		}
+		// A failed all-or-nothing import discards the changes of successful sections
+		if !result.Committed && !result.DryRun {
+			r.Status = BulkResultRolledBack
+			continue
+		}
+		if r.Status == BulkResultCreated {
+			result.CreatedCount++
+		} else {
+			result.UpdatedCount++
+		}
+	}
+
+	if result.Committed {
+		s.syncImportedSeries(ctx, result, req.ImportedBy)
+		s.syncImportedReferences(ctx, result, req.ImportedBy)
+		s.notifyImported(ctx, result, req.ImportedBy)
+	}
+
+	return result, nil
+}
+
+// importSection creates or updates the task of a single section
+func importSection(ctx context.Context, taskService *TaskService, taskRepo *repository.TaskRepository, projectID string, section *parser.TaskSection, importedBy string) *ImportTaskResult {
+	result := &ImportTaskResult{
+		TaskID:    section.TaskID,
+		StartLine: section.StartLine,
+		EndLine:   section.EndLine,
+	}
+
+	fail := func(err error) *ImportTaskResult {
+		result.Status = BulkResultFailed
+		result.Error = err.Error()
+		result.Task = nil
+		return result
+	}
+
+	if section.Err != nil {
+		return fail(section.Err)
+	}
+
+	result.Title = section.Task.Title
+	result.recurrence = section.Task.Metadata.Recurrence
+
+	existing, err := taskRepo.GetByIDForUpdate(ctx, projectID, section.TaskID)
+	if err != nil && !errors.Is(err, repository.ErrTaskNotFound) {
+		return fail(err)
+	}
+
+	if existing == nil {
+		task, err := taskService.Create(ctx, projectID, &CreateTaskRequest{
+			MarkdownBody: section.Markdown,
+			CreatedBy:    importedBy,
+		})
+		if err != nil {
+			return fail(err)
+		}
+		result.Status = BulkResultCreated
+		result.Task = task
+		return result
+	}
+
+	if existing.MarkdownBody == section.Markdown {
+		result.Status = BulkResultUnchanged
+		result.Task = existing
+		return result
+	}
+
+	task, err := taskService.Update(ctx, projectID, section.TaskID, &UpdateTaskRequest{
+		MarkdownBody: section.Markdown,
+		UpdatedBy:    importedBy,
+	})
+	if err != nil {
+		return fail(err)
+	}
+	result.Status = BulkResultUpdated
+	result.Task = task
+	result.previous = existing
+	result.completed = task.Status == models.TaskStatusDone && existing.Status != models.TaskStatusDone
+	return result
+}
+
+// syncImportedSeries syncs recurring series of imported tasks after the import is committed
+func (s *BulkService) syncImportedSeries(ctx context.Context, result *ImportMarkdownResult, importedBy string) {
+	if s.recurrence == nil {
+		return
+	}
+
+	for _, r := range result.Results {
+		if r.Status != BulkResultCreated && r.Status != BulkResultUpdated {
+			continue
+		}
+
+		// Updates always sync so that removing a rule stops the series, like TaskService.Update
+		if r.Status == BulkResultUpdated || r.recurrence != "" {
+			if err := s.recurrence.SyncSeries(ctx, r.Task, r.recurrence, importedBy); err != nil {
+				log.Printf("WARNING: Failed to sync series for task %s: %v", r.TaskID, err)
+			}
+		}
+
+		if r.completed {
+			if _, err := s.recurrence.HandleCompletion(ctx, r.Task); err != nil {
+				log.Printf("WARNING: Failed to generate next instance for task %s: %v", r.TaskID, err)
+			}
+		}
+	}
+}
+
+// syncImportedReferences syncs the task references of imported tasks after the import is committed.
+// Every section is stored by then, so sections can refer to each other in any order.
+func (s *BulkService) syncImportedReferences(ctx context.Context, result *ImportMarkdownResult, importedBy string) {
+	if s.references == nil {
+		return
+	}
+
+	for _, r := range result.Results {
+		var err error
+		switch r.Status {
+		case BulkResultCreated:
+			err = s.references.HandleCreated(ctx, r.Task, importedBy)
+		case BulkResultUpdated:
+			err = s.references.SyncReferences(ctx, r.Task, importedBy)
+		default:
+			continue
+		}
+		if err != nil {
+			log.Printf("WARNING: Failed to sync references for task %s: %v", r.TaskID, err)
+		}
+	}
+}
+
+// notifyImported sends the notifications of imported tasks after the import is committed
+func (s *BulkService) notifyImported(ctx context.Context, result *ImportMarkdownResult, importedBy string) {
+	if s.notifications == nil {
+		return
+	}
+
+	for _, r := range result.Results {
+		if r.Status != BulkResultCreated && r.Status != BulkResultUpdated {
+			continue
+		}
+		if err := s.notifications.NotifyTaskSaved(ctx, r.previous, r.Task, importedBy); err != nil {
+			log.Printf("WARNING: Failed to send notifications for task %s: %v", r.TaskID, err)
+		}
+	}
+}
@@ -0,0 +1,496 @@
+package service
+
+import (
+	"bytes"
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	mathrand "math/rand"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/tktomaru/taskai/taskai-server/internal/models"
+	"github.com/tktomaru/taskai/taskai-server/internal/parser"
+	"github.com/tktomaru/taskai/taskai-server/internal/repository"
+)
+
+// IdempotencyKeyField is the extra_meta field that identifies the event a task was created from
+const IdempotencyKeyField = "idempotency_key"
+
+// Ingestion results
+const (
+	IngestResultCreated   = "created"
+	IngestResultCommented = "commented"
+)
+
+// defaultIngestMapping maps template variables to JSON payload paths when a token has no mapping
+var defaultIngestMapping = map[string]string{
+	"title":             "title",
+	"body":              "body",
+	IdempotencyKeyField: IdempotencyKeyField,
+}
+
+// defaultIngestTemplate renders JSON payloads for tokens without a task template
+const defaultIngestTemplate = "## {{id}}: {{title}}\n\n" +
+	"```yaml\n" +
+	"id: {{id}}\n" +
+	"status: open\n" +
+	"priority: P2\n" +
+	"labels: [ingested]\n" +
+	"```\n\n" +
+	"{{body}}\n"
+
+// generateIngestTokenID generates a unique ingestion token ID
+func generateIngestTokenID() string {
+	const charset = "abcdefghijklmnopqrstuvwxyz0123456789"
+	timestamp := time.Now().Unix()
+
+	b := make([]byte, 6)
+	for i := range b {
+		b[i] = charset[mathrand.Intn(len(charset))]
+	}
+
+	return fmt.Sprintf("ing-%d-%s", timestamp, string(b))
+}
+
+// generateIngestSecret generates the secret part of an ingestion URL
+func generateIngestSecret() (string, error) {
+	b := make([]byte, 24)
+	if _, err := rand.Read(b); err != nil {
+		return "", fmt.Errorf("failed to generate token: %w", err)
+	}
+
+	return "ing_" + hex.EncodeToString(b), nil
+}
+
+// hashIngestToken hashes an ingestion token for storage and lookup
+func hashIngestToken(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:])
+}
+
+// CreateIngestTokenRequest represents a request to create an ingestion token
+type CreateIngestTokenRequest struct {
+	Name       string            `json:"name" binding:"required"`
+	TemplateID string            `json:"template_id,omitempty"`
+	Mapping    map[string]string `json:"mapping,omitempty"`
+	IDPrefix   string            `json:"id_prefix,omitempty"`
+	CreatedBy  string            `json:"-"`
+}
+
+// UpdateIngestTokenRequest represents a request to update an ingestion token. Omitted fields are kept.
+type UpdateIngestTokenRequest struct {
+	Name       *string           `json:"name,omitempty"`
+	TemplateID *string           `json:"template_id,omitempty"`
+	Mapping    map[string]string `json:"mapping,omitempty"`
+	IDPrefix   *string           `json:"id_prefix,omitempty"`
+}
+
+// IngestResult represents the outcome of an ingested event
+type IngestResult struct {
+	Result  string              `json:"result"`
+	Task    *models.Task        `json:"task"`
+	Comment *models.TaskComment `json:"comment,omitempty"`
+}
+
+// IngestService turns events posted by external systems into tasks.
+// Events with the idempotency key of a live task are added to it as comments.
+type IngestService struct {
+	repo      *repository.IngestRepository
+	taskRepo  *repository.TaskRepository
+	templates *TemplateService
+	comments  *CommentService
+
+	// TasksFor returns the task service used to create tasks in a project
+	TasksFor func(ctx context.Context, projectID string) *TaskService
+}
+
+// NewIngestService creates a new ingestion service
+func NewIngestService(repo *repository.IngestRepository, taskRepo *repository.TaskRepository, templates *TemplateService, comments *CommentService) *IngestService {
+	return &IngestService{
+		repo:      repo,
+		taskRepo:  taskRepo,
+		templates: templates,
+		comments:  comments,
+		TasksFor: func(ctx context.Context, projectID string) *TaskService {
+			return NewTaskService(taskRepo)
+		},
+	}
+}
+
+// CreateToken creates an ingestion token. The token is only returned here.
+func (s *IngestService) CreateToken(ctx context.Context, projectID string, req *CreateIngestTokenRequest) (*models.IngestToken, error) {
+	token := &models.IngestToken{
+		ID:        generateIngestTokenID(),
+		ProjectID: projectID,
+		Name:      strings.TrimSpace(req.Name),
+		Mapping:   mappingToJSONB(req.Mapping),
+		IDPrefix:  req.IDPrefix,
+	}
+	if req.TemplateID != "" {
+		token.TemplateID = &req.TemplateID
+	}
+	if req.CreatedBy != "" {
+		token.CreatedBy = &req.CreatedBy
+	}
+	if token.IDPrefix == "" {
+		token.IDPrefix = "T"
+	}
+
+	if err := s.validateToken(ctx, token); err != nil {
+		return nil, err
+	}
+
+	secret, err := generateIngestSecret()
+	if err != nil {
+		return nil, err
+	}
+	token.TokenHash = hashIngestToken(secret)
+
+	if err := s.repo.Create(ctx, token); err != nil {
+		return nil, err
+	}
+
+	token.Token = secret
+	return token, nil
+}
+
+// ListTokens retrieves the ingestion tokens of a project
+func (s *IngestService) ListTokens(ctx context.Context, projectID string) ([]*models.IngestToken, error) {
+	return s.repo.List(ctx, projectID)
+}
+
+// UpdateToken updates the name and payload handling of an ingestion token
+func (s *IngestService) UpdateToken(ctx context.Context, projectID, tokenID string, req *UpdateIngestTokenRequest) (*models.IngestToken, error) {
+	token, err := s.repo.GetByID(ctx, projectID, tokenID)
+	if err != nil {
+		return nil, err
+	}
+
+	if req.Name != nil {
+		token.Name = strings.TrimSpace(*req.Name)
+	}
+	if req.TemplateID != nil {
+		token.TemplateID = req.TemplateID
+		if *req.TemplateID == "" {
+			token.TemplateID = nil
+		}
+	}
+	if req.Mapping != nil {
+		token.Mapping = mappingToJSONB(req.Mapping)
+	}
+	if req.IDPrefix != nil {
+		token.IDPrefix = *req.IDPrefix
+	}
+
+	if err := s.validateToken(ctx, token); err != nil {
+		return nil, err
+	}
+
+	if err := s.repo.Update(ctx, token); err != nil {
+		return nil, err
+	}
+
+	return s.repo.GetByID(ctx, projectID, tokenID)
+}
+
+// DeleteToken revokes an ingestion token
+func (s *IngestService) DeleteToken(ctx context.Context, projectID, tokenID string) error {
+	return s.repo.Delete(ctx, projectID, tokenID)
+}
+
+// Authenticate resolves the token of an ingestion URL
+func (s *IngestService) Authenticate(ctx context.Context, secret string) (*models.IngestToken, error) {
+	return s.repo.GetByHash(ctx, hashIngestToken(secret))
+}
+
+// IngestMarkdown creates a task from a task Markdown document.
+// The document may use {{id}} where the new task ID belongs, plus {{today}} and {{project}}.
+func (s *IngestService) IngestMarkdown(ctx context.Context, token *models.IngestToken, markdown string) (*IngestResult, error) {
+	taskID, err := s.taskRepo.NextID(ctx, token.IDPrefix)
+	if err != nil {
+		return nil, err
+	}
+
+	markdown = renderKnownPlaceholders(markdown, map[string]string{
+		"id":      taskID,
+		"today":   time.Now().Format("2006-01-02"),
+		"project": token.ProjectID,
+	})
+
+	parsed, err := parser.NewMarkdownParser().Parse(markdown)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse markdown: %w", err)
+	}
+
+	key := ""
+	if value, ok := parsed.Metadata.ExtraMeta[IdempotencyKeyField]; ok && value != nil {
+		key = fmt.Sprint(value)
+	}
+
+	return s.createOrComment(ctx, token, key, parser.ExtractBody(markdown), func() (string, error) {
+		return markdown, nil
+	})
+}
+
+// IngestJSON creates a task from a JSON payload. The token's mapping selects the template
+// variables from payload paths; "title" is required and "idempotency_key" de-duplicates events.
+func (s *IngestService) IngestJSON(ctx context.Context, token *models.IngestToken, payload []byte) (*IngestResult, error) {
+	decoder := json.NewDecoder(bytes.NewReader(payload))
+	decoder.UseNumber()
+
+	var document interface{}
+	if err := decoder.Decode(&document); err != nil {
+		return nil, fmt.Errorf("invalid JSON payload: %w", err)
+	}
+
+	mapping := mappingFromJSONB(token.Mapping)
+	if len(mapping) == 0 {
+		mapping = defaultIngestMapping
+	}
+
+	vars := applyIngestMapping(document, mapping)
+	title := vars["title"]
+	if title == "" {
+		return nil, fmt.Errorf("payload has no title (mapped from %q)", mapping["title"])
+	}
+	key := vars[IdempotencyKeyField]
+
+	if _, ok := vars["body"]; !ok {
+		vars["body"] = ""
+	}
+	if _, ok := vars["assignee"]; !ok {
+		vars["assignee"] = ""
+	}
+	indented, _ := json.MarshalIndent(document, "", "  ")
+	vars["payload"] = string(indented)
+
+	commentBody := vars["body"]
+	if strings.TrimSpace(commentBody) == "" {
+		commentBody = "```json\n" + vars["payload"] + "\n```"
+	}
+
+	return s.createOrComment(ctx, token, key, commentBody, func() (string, error) {
+		markdown, err := s.renderJSON(ctx, token, title, vars)
+		if err != nil {
+			return "", err
+		}
+		if key == "" {
+			return markdown, nil
+		}
+		return parser.PatchFrontmatter(markdown, map[string]interface{}{
+			"extra_meta": map[string]interface{}{IdempotencyKeyField: key},
+		})
+	})
+}
+
+// renderJSON renders the task Markdown of a JSON payload with the token's template
+func (s *IngestService) renderJSON(ctx context.Context, token *models.IngestToken, title string, vars map[string]string) (string, error) {
+	if token.TemplateID != nil {
+		return s.templates.Render(ctx, token.ProjectID, *token.TemplateID, &RenderTemplateRequest{
+			Title:     title,
+			Assignee:  vars["assignee"],
+			Variables: vars,
+		})
+	}
+
+	taskID, err := s.taskRepo.NextID(ctx, token.IDPrefix)
+	if err != nil {
+		return "", err
+	}
+
+	vars["id"] = taskID
+	vars["title"] = title
+	vars["today"] = time.Now().Format("2006-01-02")
+	vars["project"] = token.ProjectID
+
+	return RenderTemplate(defaultIngestTemplate, vars)
+}
+
+// createOrComment comments on the live task with the idempotency key, or creates a task
+// from the rendered Markdown. A task created concurrently for the same key is commented on.
+func (s *IngestService) createOrComment(ctx context.Context, token *models.IngestToken, key, commentBody string, render func() (string, error)) (*IngestResult, error) {
+	actor := "system"
+	if token.CreatedBy != nil {
+		actor = *token.CreatedBy
+	}
+
+	if key != "" {
+		result, err := s.commentOnDuplicate(ctx, token, key, commentBody, actor)
+		if result != nil || err != nil {
+			return result, err
+		}
+	}
+
+	markdown, err := render()
+	if err != nil {
+		return nil, err
+	}
+
+	task, err := s.TasksFor(ctx, token.ProjectID).Create(ctx, token.ProjectID, &CreateTaskRequest{
+		MarkdownBody: markdown,
+		CreatedBy:    actor,
+	})
+	if err != nil {
+		if key != "" && errors.Is(err, repository.ErrDuplicateIdempotencyKey) {
+			if result, err := s.commentOnDuplicate(ctx, token, key, commentBody, actor); result != nil || err != nil {
+				return result, err
+			}
+		}
+		return nil, err
+	}
+
+	return &IngestResult{Result: IngestResultCreated, Task: task}, nil
+}
+
+// commentOnDuplicate adds an event to the live task with the idempotency key.
+// It returns nil without error when there is no such task.
+func (s *IngestService) commentOnDuplicate(ctx context.Context, token *models.IngestToken, key, commentBody, actor string) (*IngestResult, error) {
+	task, err := s.taskRepo.GetByIdempotencyKey(ctx, token.ProjectID, key)
+	if errors.Is(err, repository.ErrTaskNotFound) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	if strings.TrimSpace(commentBody) == "" {
+		commentBody = "_(no details)_"
+	}
+
+	comment, err := s.comments.Create(ctx, token.ProjectID, task.ID, &CreateCommentRequest{
+		MarkdownBody: fmt.Sprintf("Received again via **%s**:\n\n%s", token.Name, commentBody),
+		AuthorUserID: actor,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return &IngestResult{Result: IngestResultCommented, Task: task, Comment: comment}, nil
+}
+
+// validateToken checks the name, ID prefix and template of a token
+func (s *IngestService) validateToken(ctx context.Context, token *models.IngestToken) error {
+	if token.Name == "" {
+		return fmt.Errorf("token name is required")
+	}
+
+	if !idPrefixPattern.MatchString(token.IDPrefix) {
+		return fmt.Errorf("invalid id_prefix: %s (must be uppercase letters)", token.IDPrefix)
+	}
+
+	if token.TemplateID != nil {
+		if _, err := s.templates.GetByID(ctx, token.ProjectID, *token.TemplateID); err != nil {
+			return fmt.Errorf("invalid template_id: %w", err)
+		}
+	}
+
+	return nil
+}
+
+// applyIngestMapping resolves template variables from a decoded JSON document.
+// Paths use dots for object keys and numbers for array indexes (e.g. "alerts.0.labels.severity").
+// Variables whose path is missing are left out.
+func applyIngestMapping(document interface{}, mapping map[string]string) map[string]string {
+	vars := make(map[string]string, len(mapping))
+
+	for name, path := range mapping {
+		value, ok := lookupJSONPath(document, path)
+		if !ok {
+			continue
+		}
+		vars[name] = jsonValueString(value)
+	}
+
+	// Titles end up in a heading and must be a single line
+	if title, ok := vars["title"]; ok {
+		vars["title"] = strings.Join(strings.Fields(title), " ")
+	}
+
+	return vars
+}
+
+// lookupJSONPath returns the value at a dotted path of a decoded JSON document
+func lookupJSONPath(document interface{}, path string) (interface{}, bool) {
+	current := document
+	if path == "" {
+		return current, true
+	}
+
+	for _, segment := range strings.Split(path, ".") {
+		switch node := current.(type) {
+		case map[string]interface{}:
+			value, ok := node[segment]
+			if !ok {
+				return nil, false
+			}
+			current = value
+		case []interface{}:
+			index, err := strconv.Atoi(segment)
+			if err != nil || index < 0 || index >= len(node) {
+				return nil, false
+			}
+			current = node[index]
+		default:
+			return nil, false
+		}
+	}
+
+	return current, true
+}
+
+// jsonValueString formats a JSON value as a template variable
+func jsonValueString(value interface{}) string {
+	switch v := value.(type) {
+	case nil:
+		return ""
+	case string:
+		return v
+	case json.Number:
+		return v.String()
+	case bool:
+		return strconv.FormatBool(v)
+	default:
+		encoded, err := json.Marshal(v)
+		if err != nil {
+			return fmt.Sprint(v)
+		}
+		return string(encoded)
+	}
+}
+
+// renderKnownPlaceholders replaces the given {{name}} placeholders and leaves any other text alone
+func renderKnownPlaceholders(markdown string, vars map[string]string) string {
+	return placeholderPattern.ReplaceAllStringFunc(markdown, func(match string) string {
+		name := placeholderPattern.FindStringSubmatch(match)[1]
+		if value, ok := vars[name]; ok {
+			return value
+		}
+		return match
+	})
+}
+
+// mappingToJSONB stores a variable mapping in a JSONB column
+func mappingToJSONB(mapping map[string]string) models.JSONB {
+	result := make(models.JSONB, len(mapping))
+	for name, path := range mapping {
+		result[name] = path
+	}
+	return result
+}
+
+// mappingFromJSONB reads a variable mapping from a JSONB column
+func mappingFromJSONB(value models.JSONB) map[string]string {
+	mapping := make(map[string]string, len(value))
+	for name, path := range value {
+		if s, ok := path.(string); ok {
+			mapping[name] = s
+		}
+	}
+	return mapping
+}
@@ -0,0 +1,96 @@
+package service
+
+import (
+	"bytes"
+	"encoding/json"
+	"testing"
+)
+
+func decodeTestPayload(t *testing.T, payload string) interface{} {
+	t.Helper()
+
+	decoder := json.NewDecoder(bytes.NewReader([]byte(payload)))
+	decoder.UseNumber()
+
+	var document interface{}
+	if err := decoder.Decode(&document); err != nil {
+		t.Fatalf("invalid test payload: %v", err)
+	}
+	return document
+}
+
+func TestApplyIngestMapping(t *testing.T) {
+	document := decodeTestPayload(t, `{
+		"alert": {"name": "Disk\nfull", "fingerprint": "abc123"},
+		"alerts": [{"labels": {"severity": "critical"}, "value": 97.5, "firing": true}],
+		"tags": ["disk", "prod"],
+		"note": null
+	}`)
+
+	tests := []struct {
+		name    string
+		mapping map[string]string
+		want    map[string]string
+	}{
+		{
+			"nested keys and array indexes",
+			map[string]string{"title": "alert.name", "idempotency_key": "alert.fingerprint", "severity": "alerts.0.labels.severity"},
+			map[string]string{"title": "Disk full", "idempotency_key": "abc123", "severity": "critical"},
+		},
+		{
+			"numbers, booleans and null",
+			map[string]string{"value": "alerts.0.value", "firing": "alerts.0.firing", "note": "note"},
+			map[string]string{"value": "97.5", "firing": "true", "note": ""},
+		},
+		{
+			"arrays and objects are encoded as JSON",
+			map[string]string{"tags": "tags", "labels": "alerts.0.labels"},
+			map[string]string{"tags": `["disk","prod"]`, "labels": `{"severity":"critical"}`},
+		},
+		{
+			"missing paths are left out",
+			map[string]string{"title": "alert.summary", "severity": "alerts.1.labels.severity", "bad": "tags.x"},
+			map[string]string{},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := applyIngestMapping(document, tt.mapping)
+			if len(got) != len(tt.want) {
+				t.Fatalf("applyIngestMapping() = %v, want %v", got, tt.want)
+			}
+			for name, want := range tt.want {
+				if got[name] != want {
+					t.Errorf("%s = %q, want %q", name, got[name], want)
+				}
+			}
+		})
+	}
+}
+
+func TestRenderKnownPlaceholders(t *testing.T) {
+	markdown := "## {{id}}: Deploy {{ project }}\n\nKeep {{unknown}} and {{ today}} as given"
+
+	got := renderKnownPlaceholders(markdown, map[string]string{
+		"id":      "T-42",
+		"project": "web",
+	})
+
+	want := "## T-42: Deploy web\n\nKeep {{unknown}} and {{ today}} as given"
+	if got != want {
+		t.Errorf("renderKnownPlaceholders() = %q, want %q", got, want)
+	}
+}
+
+func TestHashIngestToken(t *testing.T) {
+	if hashIngestToken("ing_a") == hashIngestToken("ing_b") {
+		t.Error("different tokens must have different hashes")
+	}
+	if hashIngestToken("ing_a") != hashIngestToken("ing_a") {
+		t.Error("hashing must be deterministic")
+	}
+	if len(hashIngestToken("ing_a")) != 64 {
+		t.Errorf("hash length = %d, want 64 hex characters", len(hashIngestToken("ing_a")))
+	}
+}
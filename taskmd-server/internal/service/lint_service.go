@@ -0,0 +1,107 @@
+package service
+
+import (
+	"context"
+	"log"
+	"strings"
+
+	"github.com/tktomaru/taskai/taskai-server/internal/parser"
+	"github.com/tktomaru/taskai/taskai-server/internal/repository"
+)
+
+// ValidateTaskRequest represents a request to validate task markdown
+type ValidateTaskRequest struct {
+	MarkdownBody string `json:"markdown_body"`
+}
+
+// ValidationResult represents the diagnostics of a task document
+type ValidationResult struct {
+	Valid        bool                `json:"valid"`
+	ErrorCount   int                 `json:"error_count"`
+	WarningCount int                 `json:"warning_count"`
+	Diagnostics  []parser.Diagnostic `json:"diagnostics"`
+}
+
+// LintService checks task markdown against the project it belongs to
+type LintService struct {
+	taskRepo    *repository.TaskRepository
+	projectRepo *repository.ProjectRepository
+}
+
+// NewLintService creates a new lint service
+func NewLintService(taskRepo *repository.TaskRepository, projectRepo *repository.ProjectRepository) *LintService {
+	return &LintService{
+		taskRepo:    taskRepo,
+		projectRepo: projectRepo,
+	}
+}
+
+// Validate returns the diagnostics of a task document.
+// A document is valid when it has no error diagnostics; warnings do not block saving.
+func (s *LintService) Validate(ctx context.Context, projectID, markdown string) (*ValidationResult, error) {
+	opts, err := s.lintOptions(ctx, projectID)
+	if err != nil {
+		return nil, err
+	}
+
+	diagnostics := parser.Lint(markdown, opts)
+	if diagnostics == nil {
+		diagnostics = []parser.Diagnostic{}
+	}
+
+	result := &ValidationResult{Diagnostics: diagnostics}
+	for _, d := range diagnostics {
+		if d.Severity == parser.SeverityError {
+			result.ErrorCount++
+		} else {
+			result.WarningCount++
+		}
+	}
+	result.Valid = result.ErrorCount == 0
+
+	return result, nil
+}
+
+// lintOptions loads the project members and task lookups used by the checks
+func (s *LintService) lintOptions(ctx context.Context, projectID string) (*parser.LintOptions, error) {
+	opts := &parser.LintOptions{}
+
+	members, err := s.projectRepo.ListMembers(ctx, projectID)
+	if err != nil {
+		return nil, err
+	}
+
+	// Projects without members (e.g. single-user setups) skip the assignee check
+	if len(members) > 0 {
+		opts.Members = make(map[string]bool)
+		for _, member := range members {
+			opts.Members[member.ID] = true
+			opts.Members[member.Name] = true
+			opts.Members[member.Email] = true
+			if at := strings.Index(member.Email, "@"); at > 0 {
+				opts.Members[member.Email[:at]] = true
+			}
+		}
+	}
+
+	prefixes, err := s.taskRepo.ListIDPrefixes(ctx)
+	if err != nil {
+		return nil, err
+	}
+	opts.TaskPrefixes = make(map[string]bool)
+	for _, prefix := range prefixes {
+		opts.TaskPrefixes[prefix] = true
+	}
+
+	opts.TaskExists = func(taskID string) bool {
+		exists, err := s.taskRepo.Exists(ctx, taskID)
+		if err != nil {
+			// Do not report references that could not be checked
+			log.Printf("WARNING: Failed to check task %s: %v", taskID, err)
+			return true
+		}
+		return exists
+	}
+
+	return opts, nil
+}
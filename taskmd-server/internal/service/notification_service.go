@@ -0,0 +1,516 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/tktomaru/taskai/taskai-server/internal/models"
+	"github.com/tktomaru/taskai/taskai-server/internal/parser"
+	"github.com/tktomaru/taskai/taskai-server/internal/repository"
+)
+
+// NotificationInbox represents a page of a user's notifications
+type NotificationInbox struct {
+	Notifications []*models.Notification `json:"notifications"`
+	UnreadCount   int                    `json:"unread_count"`
+}
+
+// NotificationService creates inbox entries for mentions, assignments and events on watched targets
+type NotificationService struct {
+	notificationRepo *repository.NotificationRepository
+	projectRepo      *repository.ProjectRepository
+	subscriptionRepo *repository.SubscriptionRepository
+	viewWatch        *ViewWatchService
+
+	// OnCreated is called for every notification stored in an inbox
+	OnCreated func(notification *models.Notification)
+
+	// QueueViewRefresh schedules the refresh of watched views after a task changed;
+	// without it views are refreshed before the change is reported as done
+	QueueViewRefresh func(projectID, actorID string)
+}
+
+// NewNotificationService creates a new notification service
+func NewNotificationService(notificationRepo *repository.NotificationRepository, projectRepo *repository.ProjectRepository, subscriptionRepo *repository.SubscriptionRepository) *NotificationService {
+	return &NotificationService{
+		notificationRepo: notificationRepo,
+		projectRepo:      projectRepo,
+		subscriptionRepo: subscriptionRepo,
+	}
+}
+
+// SetViewWatch enables alerts for tasks entering or leaving watched views
+func (s *NotificationService) SetViewWatch(viewWatch *ViewWatchService) {
+	s.viewWatch = viewWatch
+}
+
+// NotifyTaskSaved notifies the users mentioned in or assigned to a task and the
+// watchers of the task and its project. before is nil for new tasks. Only mentions
+// and assignees added by this save are notified. The creator and new assignees
+// are subscribed to the task.
+func (s *NotificationService) NotifyTaskSaved(ctx context.Context, before, after *models.Task, actorID string) error {
+	directory, err := s.memberDirectory(ctx, after.ProjectID)
+	if err != nil {
+		return err
+	}
+
+	for _, sub := range automaticSubscriptions(before, after, directory) {
+		if err := s.subscriptionRepo.Subscribe(ctx, sub); err != nil {
+			return err
+		}
+	}
+
+	watchers, err := s.subscriptionRepo.ListTaskWatchers(ctx, after.ProjectID, after.ID)
+	if err != nil {
+		return err
+	}
+
+	if err := s.deliver(ctx, planTaskNotifications(before, after, directory, watchers, actorID)); err != nil {
+		return err
+	}
+
+	return s.refreshViews(ctx, after.ProjectID, actorID)
+}
+
+// NotifyTaskDeleted notifies the watchers of a deleted task and its project
+func (s *NotificationService) NotifyTaskDeleted(ctx context.Context, task *models.Task, actorID string) error {
+	watchers, err := s.subscriptionRepo.ListTaskWatchers(ctx, task.ProjectID, task.ID)
+	if err != nil {
+		return err
+	}
+
+	plan := newNotificationPlan(actorID)
+	label := fmt.Sprintf("%s: %s", task.ID, task.Title)
+	for _, w := range uniqueWatchers(watchers) {
+		plan.addForWatcher(w, taskNotification(w.userID, models.NotificationTypeTaskDeleted, task,
+			label+" was deleted", nil))
+	}
+
+	if err := s.deliver(ctx, plan.notifications); err != nil {
+		return err
+	}
+
+	return s.refreshViews(ctx, task.ProjectID, actorID)
+}
+
+// NotifyComment notifies the users mentioned in a comment and the watchers of the task
+func (s *NotificationService) NotifyComment(ctx context.Context, task *models.Task, comment *models.TaskComment) error {
+	directory, err := s.memberDirectory(ctx, task.ProjectID)
+	if err != nil {
+		return err
+	}
+
+	watchers, err := s.subscriptionRepo.ListTaskWatchers(ctx, task.ProjectID, task.ID)
+	if err != nil {
+		return err
+	}
+
+	return s.deliver(ctx, planCommentNotifications(task, comment, directory, watchers))
+}
+
+// Inbox retrieves the notifications of a user together with the unread count
+func (s *NotificationService) Inbox(ctx context.Context, userID string, filters *repository.NotificationFilters) (*NotificationInbox, error) {
+	if filters.Limit <= 0 || filters.Limit > 200 {
+		filters.Limit = 50
+	}
+
+	notifications, err := s.notificationRepo.ListByUser(ctx, userID, filters)
+	if err != nil {
+		return nil, err
+	}
+
+	unread, err := s.notificationRepo.CountUnread(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	return &NotificationInbox{
+		Notifications: notifications,
+		UnreadCount:   unread,
+	}, nil
+}
+
+// SetRead marks a notification of a user as read or unread
+func (s *NotificationService) SetRead(ctx context.Context, userID string, notificationID int64, read bool) error {
+	return s.notificationRepo.SetRead(ctx, userID, notificationID, read)
+}
+
+// MarkAllRead marks every notification of a user as read
+func (s *NotificationService) MarkAllRead(ctx context.Context, userID string) (int64, error) {
+	return s.notificationRepo.MarkAllRead(ctx, userID)
+}
+
+// CompileDigests collects the pending notifications of every user whose oldest
+// pending notification is older than the interval into a single digest notification.
+// It returns the number of digests created.
+func (s *NotificationService) CompileDigests(ctx context.Context, now time.Time, interval time.Duration) (int, error) {
+	userIDs, err := s.notificationRepo.ListDigestUsers(ctx, now.Add(-interval))
+	if err != nil {
+		return 0, err
+	}
+
+	created := 0
+	for _, userID := range userIDs {
+		pending, err := s.notificationRepo.ListPendingDigest(ctx, userID)
+		if err != nil {
+			return created, err
+		}
+		if len(pending) == 0 {
+			continue
+		}
+
+		if err := s.deliver(ctx, []*models.Notification{buildDigest(userID, pending)}); err != nil {
+			return created, err
+		}
+		if err := s.notificationRepo.ClearDigestPending(ctx, userID, pending[len(pending)-1].ID); err != nil {
+			return created, err
+		}
+		created++
+	}
+
+	return created, nil
+}
+
+// deliver stores notifications and hands them to OnCreated for live delivery
+func (s *NotificationService) deliver(ctx context.Context, notifications []*models.Notification) error {
+	for _, notification := range notifications {
+		if err := s.notificationRepo.Create(ctx, notification); err != nil {
+			return fmt.Errorf("failed to notify %s: %w", notification.UserID, err)
+		}
+		if s.OnCreated != nil {
+			s.OnCreated(notification)
+		}
+	}
+
+	return nil
+}
+
+// refreshViews checks the watched views of a project after a task changed, in the
+// background when QueueViewRefresh is set
+func (s *NotificationService) refreshViews(ctx context.Context, projectID, actorID string) error {
+	if s.viewWatch == nil {
+		return nil
+	}
+	if s.QueueViewRefresh != nil {
+		s.QueueViewRefresh(projectID, actorID)
+		return nil
+	}
+
+	return s.viewWatch.Refresh(ctx, projectID, actorID)
+}
+
+// memberDirectory loads the members of a project for resolving @handles and assignees
+func (s *NotificationService) memberDirectory(ctx context.Context, projectID string) (memberDirectory, error) {
+	members, err := s.projectRepo.ListMembers(ctx, projectID)
+	if err != nil {
+		return nil, err
+	}
+
+	return newMemberDirectory(members), nil
+}
+
+// memberDirectory maps lowercased handles (ID, email, email local part, name) to user IDs
+type memberDirectory map[string]string
+
+// newMemberDirectory creates a member directory. Handles of earlier members win on collisions.
+func newMemberDirectory(members []*models.User) memberDirectory {
+	directory := make(memberDirectory)
+
+	add := func(handle, userID string) {
+		handle = strings.ToLower(strings.TrimSpace(handle))
+		if handle == "" {
+			return
+		}
+		if _, exists := directory[handle]; !exists {
+			directory[handle] = userID
+		}
+	}
+
+	// IDs and emails are unique, so they take precedence over names
+	for _, member := range members {
+		add(member.ID, member.ID)
+		add(member.Email, member.ID)
+	}
+	for _, member := range members {
+		if at := strings.Index(member.Email, "@"); at > 0 {
+			add(member.Email[:at], member.ID)
+		}
+		add(member.Name, member.ID)
+		add(strings.ReplaceAll(member.Name, " ", ""), member.ID)
+	}
+
+	return directory
+}
+
+// resolve returns the user ID of a handle such as "alice" or "alice@example.com"
+func (d memberDirectory) resolve(handle string) (string, bool) {
+	userID, ok := d[strings.ToLower(strings.TrimPrefix(handle, "@"))]
+	return userID, ok
+}
+
+// watcher is a user subscribed to a task directly or through its project
+type watcher struct {
+	userID string
+	digest bool
+}
+
+// uniqueWatchers merges the subscriptions of each user; a user gets a digest if any subscription asks for one
+func uniqueWatchers(subs []*models.Subscription) []watcher {
+	var watchers []watcher
+	index := make(map[string]int)
+
+	for _, sub := range subs {
+		if i, ok := index[sub.UserID]; ok {
+			watchers[i].digest = watchers[i].digest || sub.Digest
+			continue
+		}
+		index[sub.UserID] = len(watchers)
+		watchers = append(watchers, watcher{userID: sub.UserID, digest: sub.Digest})
+	}
+
+	return watchers
+}
+
+// automaticSubscriptions returns the task subscriptions of the creator of a new task and of new assignees.
+// Only project members are subscribed.
+func automaticSubscriptions(before, after *models.Task, directory memberDirectory) []*models.Subscription {
+	var subs []*models.Subscription
+	seen := make(map[string]bool)
+
+	add := func(handle, reason string) {
+		userID, ok := directory.resolve(handle)
+		if !ok || seen[userID] {
+			return
+		}
+		seen[userID] = true
+		subs = append(subs, &models.Subscription{
+			UserID:     userID,
+			TargetType: models.SubscriptionTargetTask,
+			TargetID:   after.ID,
+			ProjectID:  after.ProjectID,
+			Reason:     reason,
+		})
+	}
+
+	if before == nil && after.CreatedBy != nil {
+		add(*after.CreatedBy, models.SubscriptionReasonCreator)
+	}
+
+	var previousAssignees []string
+	if before != nil {
+		previousAssignees = before.Assignees
+	}
+	for _, assignee := range after.Assignees {
+		if !containsString(previousAssignees, assignee) {
+			add(assignee, models.SubscriptionReasonAssignee)
+		}
+	}
+
+	return subs
+}
+
+// notificationPlan collects at most one notification per user for a single event
+type notificationPlan struct {
+	actorID       string
+	notifications []*models.Notification
+	notified      map[string]bool
+}
+
+func newNotificationPlan(actorID string) *notificationPlan {
+	return &notificationPlan{
+		actorID:  actorID,
+		notified: make(map[string]bool),
+	}
+}
+
+// add adds a notification unless the user caused the event or was already notified
+func (p *notificationPlan) add(notification *models.Notification) {
+	if notification.UserID == p.actorID || p.notified[notification.UserID] {
+		return
+	}
+	p.notified[notification.UserID] = true
+
+	if p.actorID != "" {
+		actorID := p.actorID
+		notification.ActorUserID = &actorID
+	}
+	if notification.Detail == nil {
+		notification.Detail = models.JSONB{}
+	}
+
+	p.notifications = append(p.notifications, notification)
+}
+
+// addForWatcher adds a notification caused by a subscription
+func (p *notificationPlan) addForWatcher(w watcher, notification *models.Notification) {
+	notification.DigestPending = w.digest
+	p.add(notification)
+}
+
+// planTaskNotifications decides who is notified about a saved task.
+// Mentions come first, then new assignees, then the watchers of the task and its project.
+func planTaskNotifications(before, after *models.Task, directory memberDirectory, watchers []*models.Subscription, actorID string) []*models.Notification {
+	plan := newNotificationPlan(actorID)
+	label := fmt.Sprintf("%s: %s", after.ID, after.Title)
+
+	previousMentions := make(map[string]bool)
+	if before != nil {
+		for _, handle := range parser.MentionHandles(before.MarkdownBody) {
+			previousMentions[handle] = true
+		}
+	}
+	for _, handle := range parser.MentionHandles(after.MarkdownBody) {
+		if previousMentions[handle] {
+			continue
+		}
+		if userID, ok := directory.resolve(handle); ok {
+			plan.add(taskNotification(userID, models.NotificationTypeMention, after,
+				"You were mentioned in "+label, models.JSONB{"handle": handle}))
+		}
+	}
+
+	var previousAssignees []string
+	if before != nil {
+		previousAssignees = before.Assignees
+	}
+	for _, assignee := range after.Assignees {
+		if containsString(previousAssignees, assignee) {
+			continue
+		}
+		if userID, ok := directory.resolve(assignee); ok {
+			plan.add(taskNotification(userID, models.NotificationTypeAssigned, after,
+				"You were assigned to "+label, models.JSONB{"assignee": assignee}))
+		}
+	}
+
+	if before == nil {
+		for _, w := range uniqueWatchers(watchers) {
+			plan.addForWatcher(w, taskNotification(w.userID, models.NotificationTypeTaskCreated, after,
+				label+" was created", nil))
+		}
+		return plan.notifications
+	}
+
+	changes := taskChanges(before, after)
+	if len(changes) == 0 {
+		return plan.notifications
+	}
+	for _, w := range uniqueWatchers(watchers) {
+		plan.addForWatcher(w, taskNotification(w.userID, models.NotificationTypeTaskUpdated, after,
+			label+" was updated", models.JSONB{"changes": changes}))
+	}
+
+	return plan.notifications
+}
+
+// planCommentNotifications decides who is notified about a new comment
+func planCommentNotifications(task *models.Task, comment *models.TaskComment, directory memberDirectory, watchers []*models.Subscription) []*models.Notification {
+	plan := newNotificationPlan(comment.AuthorUserID)
+	label := fmt.Sprintf("%s: %s", task.ID, task.Title)
+
+	withComment := func(notification *models.Notification) *models.Notification {
+		commentID := comment.ID
+		notification.CommentID = &commentID
+		return notification
+	}
+
+	for _, handle := range parser.MentionHandles(comment.MarkdownBody) {
+		if userID, ok := directory.resolve(handle); ok {
+			plan.add(withComment(taskNotification(userID, models.NotificationTypeMention, task,
+				"You were mentioned in a comment on "+label, models.JSONB{"handle": handle})))
+		}
+	}
+
+	for _, w := range uniqueWatchers(watchers) {
+		plan.addForWatcher(w, withComment(taskNotification(w.userID, models.NotificationTypeTaskCommented, task,
+			"New comment on "+label, nil)))
+	}
+
+	return plan.notifications
+}
+
+// buildDigest summarizes pending notifications into a single digest notification
+func buildDigest(userID string, pending []*models.Notification) *models.Notification {
+	items := make([]interface{}, 0, len(pending))
+	for _, n := range pending {
+		item := map[string]interface{}{
+			"notification_id": n.ID,
+			"type":            n.Type,
+			"title":           n.Title,
+			"created_at":      n.CreatedAt,
+		}
+		if n.TaskID != nil {
+			item["task_id"] = *n.TaskID
+		}
+		items = append(items, item)
+	}
+
+	title := "1 update on watched items"
+	if len(pending) != 1 {
+		title = fmt.Sprintf("%d updates on watched items", len(pending))
+	}
+
+	return &models.Notification{
+		UserID: userID,
+		Type:   models.NotificationTypeDigest,
+		Title:  title,
+		Detail: models.JSONB{"items": items},
+	}
+}
+
+// taskChanges lists the fields that differ between two versions of a task
+func taskChanges(before, after *models.Task) []string {
+	var changes []string
+
+	if before.Title != after.Title {
+		changes = append(changes, "title")
+	}
+	if before.Status != after.Status {
+		changes = append(changes, "status")
+	}
+	if before.Priority != after.Priority {
+		changes = append(changes, "priority")
+	}
+	if !equalStrings(before.Assignees, after.Assignees) {
+		changes = append(changes, "assignees")
+	}
+	if !equalStrings(before.Labels, after.Labels) {
+		changes = append(changes, "labels")
+	}
+	if !sameDate(before.StartDate, after.StartDate) {
+		changes = append(changes, "start_date")
+	}
+	if !sameDate(before.DueDate, after.DueDate) {
+		changes = append(changes, "due_date")
+	}
+	if before.MarkdownBody != after.MarkdownBody && len(changes) == 0 {
+		changes = append(changes, "body")
+	}
+
+	return changes
+}
+
+// taskNotification creates a notification about a task
+func taskNotification(userID string, notificationType models.NotificationType, task *models.Task, title string, detail models.JSONB) *models.Notification {
+	projectID := task.ProjectID
+	taskID := task.ID
+
+	return &models.Notification{
+		UserID:    userID,
+		Type:      notificationType,
+		ProjectID: &projectID,
+		TaskID:    &taskID,
+		Title:     title,
+		Detail:    detail,
+	}
+}
+
+// sameDate reports whether two optional dates fall on the same day
+func sameDate(a, b *time.Time) bool {
+	if a == nil || b == nil {
+		return a == nil && b == nil
+	}
+	return a.Format("2006-01-02") == b.Format("2006-01-02")
+}
@@ -0,0 +1,216 @@
+package service
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/tktomaru/taskai/taskai-server/internal/models"
+)
+
+func TestPlanTaskNotifications(t *testing.T) {
+	directory := newMemberDirectory([]*models.User{
+		{ID: "u-alice", Email: "alice@example.com", Name: "Alice Smith"},
+		{ID: "u-bob", Email: "bob@example.com", Name: "Bob"},
+		{ID: "u-carol", Email: "carol@example.com", Name: "Carol"},
+		{ID: "u-dave", Email: "dave@example.com", Name: "Dave"},
+	})
+
+	creator := "u-carol"
+	watchers := []*models.Subscription{
+		{UserID: "u-carol", TargetType: models.SubscriptionTargetTask, TargetID: "T-1"},
+		{UserID: "u-bob", TargetType: models.SubscriptionTargetTask, TargetID: "T-1", Digest: true},
+		{UserID: "u-bob", TargetType: models.SubscriptionTargetProject, TargetID: "p1"},
+	}
+	before := &models.Task{
+		ID:           "T-1",
+		ProjectID:    "p1",
+		Title:        "Login page",
+		Status:       models.TaskStatusOpen,
+		Assignees:    models.StringArray{"bob"},
+		MarkdownBody: "Ask @bob about the design.",
+		CreatedBy:    &creator,
+	}
+
+	tests := []struct {
+		name   string
+		before *models.Task
+		after  *models.Task
+		actor  string
+		want   []string
+	}{
+		{
+			name:   "new task notifies mentions, assignees and watchers",
+			before: nil,
+			after: &models.Task{
+				ID: "T-1", ProjectID: "p1", Title: "Login page",
+				Assignees:    models.StringArray{"bob", "ghost"},
+				MarkdownBody: "Ask @AliceSmith and @unknown about the design, cc @dave",
+				CreatedBy:    &creator,
+			},
+			actor: "u-dave",
+			want:  []string{"u-alice mention", "u-bob assigned", "u-carol task_created"},
+		},
+		{
+			name:   "only new mentions and assignees are notified, watchers get updates",
+			before: before,
+			after: &models.Task{
+				ID: "T-1", ProjectID: "p1", Title: "Login page",
+				Status:       models.TaskStatusInProgress,
+				Assignees:    models.StringArray{"bob", "alice@example.com"},
+				MarkdownBody: "Ask @bob about the design.",
+				CreatedBy:    &creator,
+			},
+			actor: "u-dave",
+			want:  []string{"u-alice assigned", "u-carol task_updated", "u-bob task_updated"},
+		},
+		{
+			name:   "the actor is never notified",
+			before: before,
+			after: &models.Task{
+				ID: "T-1", ProjectID: "p1", Title: "Login page",
+				Status:       models.TaskStatusDone,
+				Assignees:    models.StringArray{"bob"},
+				MarkdownBody: "Ask @bob about the design.",
+				CreatedBy:    &creator,
+			},
+			actor: "u-carol",
+			want:  []string{"u-bob task_updated"},
+		},
+		{
+			name:   "no changes, no notifications",
+			before: before,
+			after:  before,
+			actor:  "u-dave",
+			want:   nil,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var got []string
+			for _, n := range planTaskNotifications(tt.before, tt.after, directory, watchers, tt.actor) {
+				got = append(got, n.UserID+" "+string(n.Type))
+				// Only watched events of subscriptions with digest enabled wait for the digest
+				wantDigest := n.UserID == "u-bob" && n.Type == models.NotificationTypeTaskUpdated
+				if n.DigestPending != wantDigest {
+					t.Errorf("notification %s %s has digest pending %v, want %v", n.UserID, n.Type, n.DigestPending, wantDigest)
+				}
+				if n.ActorUserID == nil || *n.ActorUserID != tt.actor {
+					t.Errorf("notification for %s has actor %v, want %s", n.UserID, n.ActorUserID, tt.actor)
+				}
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("planTaskNotifications() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestPlanCommentNotifications(t *testing.T) {
+	directory := newMemberDirectory([]*models.User{
+		{ID: "u-alice", Email: "alice@example.com", Name: "Alice"},
+		{ID: "u-bob", Email: "bob@example.com", Name: "Bob"},
+		{ID: "u-carol", Email: "carol@example.com", Name: "Carol"},
+	})
+
+	creator := "u-carol"
+	task := &models.Task{
+		ID: "T-1", ProjectID: "p1", Title: "Login page",
+		Assignees: models.StringArray{"bob"},
+		CreatedBy: &creator,
+	}
+	comment := &models.TaskComment{
+		ID:           "comment-1",
+		TaskID:       "T-1",
+		MarkdownBody: "@alice @bob can you take a look?",
+		AuthorUserID: "u-carol",
+	}
+
+	watchers := []*models.Subscription{
+		{UserID: "u-carol", TargetType: models.SubscriptionTargetTask, TargetID: "T-1"},
+		{UserID: "u-bob", TargetType: models.SubscriptionTargetTask, TargetID: "T-1"},
+		{UserID: "u-dave", TargetType: models.SubscriptionTargetProject, TargetID: "p1"},
+	}
+
+	var got []string
+	for _, n := range planCommentNotifications(task, comment, directory, watchers) {
+		got = append(got, n.UserID+" "+string(n.Type))
+		if n.CommentID == nil || *n.CommentID != "comment-1" {
+			t.Errorf("notification for %s has comment %v, want comment-1", n.UserID, n.CommentID)
+		}
+	}
+
+	if want := []string{"u-alice mention", "u-bob mention", "u-dave task_commented"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("planCommentNotifications() = %v, want %v", got, want)
+	}
+}
+
+func TestAutomaticSubscriptions(t *testing.T) {
+	directory := newMemberDirectory([]*models.User{
+		{ID: "u-alice", Email: "alice@example.com", Name: "Alice"},
+		{ID: "u-bob", Email: "bob@example.com", Name: "Bob"},
+	})
+
+	creator := "u-alice"
+	outsider := "u-ghost"
+
+	tests := []struct {
+		name   string
+		before *models.Task
+		after  *models.Task
+		want   []string
+	}{
+		{
+			name: "creator and assignees of a new task",
+			after: &models.Task{
+				ID: "T-1", ProjectID: "p1",
+				Assignees: models.StringArray{"bob", "alice", "nobody"},
+				CreatedBy: &creator,
+			},
+			want: []string{"u-alice creator", "u-bob assignee"},
+		},
+		{
+			name:   "only new assignees of an updated task",
+			before: &models.Task{ID: "T-1", ProjectID: "p1", Assignees: models.StringArray{"bob"}, CreatedBy: &creator},
+			after:  &models.Task{ID: "T-1", ProjectID: "p1", Assignees: models.StringArray{"bob", "alice"}, CreatedBy: &creator},
+			want:   []string{"u-alice assignee"},
+		},
+		{
+			name:  "creators outside the project are not subscribed",
+			after: &models.Task{ID: "T-1", ProjectID: "p1", CreatedBy: &outsider},
+			want:  nil,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var got []string
+			for _, sub := range automaticSubscriptions(tt.before, tt.after, directory) {
+				got = append(got, sub.UserID+" "+sub.Reason)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("automaticSubscriptions() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestBuildDigest(t *testing.T) {
+	taskID := "T-1"
+	pending := []*models.Notification{
+		{ID: 3, Type: models.NotificationTypeTaskUpdated, TaskID: &taskID, Title: "T-1: Login page was updated"},
+		{ID: 5, Type: models.NotificationTypeViewEntered, Title: "2 tasks entered view \"Overdue\""},
+	}
+
+	digest := buildDigest("u-alice", pending)
+
+	if digest.Type != models.NotificationTypeDigest || digest.UserID != "u-alice" {
+		t.Errorf("buildDigest() = %s for %s, want digest for u-alice", digest.Type, digest.UserID)
+	}
+	if digest.Title != "2 updates on watched items" {
+		t.Errorf("buildDigest() title = %q", digest.Title)
+	}
+	if items, ok := digest.Detail["items"].([]interface{}); !ok || len(items) != 2 {
+		t.Errorf("buildDigest() items = %v, want 2 items", digest.Detail["items"])
+	}
+}
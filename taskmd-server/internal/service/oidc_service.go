@@ -0,0 +1,191 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"math/rand"
+	"strings"
+	"time"
+
+	"github.com/tktomaru/taskai/taskai-server/internal/models"
+	"github.com/tktomaru/taskai/taskai-server/internal/oidc"
+	"github.com/tktomaru/taskai/taskai-server/internal/repository"
+)
+
+// generateUserID generates a unique ID for a user created on first login
+func generateUserID() string {
+	const charset = "abcdefghijklmnopqrstuvwxyz0123456789"
+	timestamp := time.Now().Unix()
+
+	b := make([]byte, 6)
+	for i := range b {
+		b[i] = charset[rand.Intn(len(charset))]
+	}
+
+	return fmt.Sprintf("user-%d-%s", timestamp, string(b))
+}
+
+// OIDCLogin is the outcome of a completed OIDC login
+type OIDCLogin struct {
+	*AuthResponse
+	RedirectTo string `json:"redirect_to,omitempty"`
+}
+
+// OIDCService logs users in with an OIDC issuer. Users are created on their first login
+// and linked to the issuer's subject; project roles can be granted from group claims.
+type OIDCService struct {
+	client      *oidc.Client
+	userRepo    *repository.UserRepository
+	projectRepo *repository.ProjectRepository
+	auth        *AuthService
+	flowSecret  string
+	groupRoles  []oidc.GroupRole
+}
+
+// NewOIDCService creates a new OIDC login service. Login flows are sealed with flowSecret.
+func NewOIDCService(client *oidc.Client, userRepo *repository.UserRepository, projectRepo *repository.ProjectRepository, auth *AuthService, flowSecret string, groupRoles []oidc.GroupRole) *OIDCService {
+	return &OIDCService{
+		client:      client,
+		userRepo:    userRepo,
+		projectRepo: projectRepo,
+		auth:        auth,
+		flowSecret:  flowSecret,
+		groupRoles:  groupRoles,
+	}
+}
+
+// Begin starts a login. It returns the issuer URL to send the browser to and the sealed
+// flow to keep in a cookie until the callback.
+func (s *OIDCService) Begin(redirectTo string) (authURL, sealedFlow string, err error) {
+	flow, err := oidc.NewFlow(safeRedirect(redirectTo))
+	if err != nil {
+		return "", "", err
+	}
+
+	sealedFlow, err = flow.Seal(s.flowSecret, time.Now())
+	if err != nil {
+		return "", "", err
+	}
+
+	return s.client.AuthCodeURL(flow.State, flow.Nonce, flow.CodeVerifier), sealedFlow, nil
+}
+
+// Complete handles the issuer's callback: it redeems the code, verifies the ID token
+// and signs the user in
+func (s *OIDCService) Complete(ctx context.Context, sealedFlow, state, code string, client ClientInfo) (*OIDCLogin, error) {
+	flow, err := oidc.OpenFlow(s.flowSecret, sealedFlow, state, time.Now())
+	if err != nil {
+		return nil, err
+	}
+
+	token, err := s.client.Exchange(ctx, code, flow.CodeVerifier)
+	if err != nil {
+		return nil, err
+	}
+
+	identity, err := s.client.VerifyIDToken(ctx, token.IDToken, flow.Nonce)
+	if err != nil {
+		return nil, err
+	}
+
+	user, err := s.resolveUser(ctx, identity)
+	if err != nil {
+		return nil, err
+	}
+
+	for projectID, role := range oidc.ProjectRoles(identity.Groups, s.groupRoles) {
+		if err := s.projectRepo.GrantMemberRole(ctx, projectID, user.ID, role); err != nil {
+			return nil, fmt.Errorf("failed to grant %s role in project %s: %w", role, projectID, err)
+		}
+	}
+
+	response, err := s.auth.IssueToken(ctx, user, client)
+	if err != nil {
+		return nil, err
+	}
+
+	return &OIDCLogin{AuthResponse: response, RedirectTo: flow.RedirectTo}, nil
+}
+
+// resolveUser finds the user linked to the identity's subject, links an existing user
+// with the same verified email, or creates a new user
+func (s *OIDCService) resolveUser(ctx context.Context, identity *oidc.Identity) (*models.User, error) {
+	if identity.Email == "" {
+		return nil, fmt.Errorf("the identity provider did not return an email address")
+	}
+
+	user, err := s.userRepo.GetByOIDCSubject(ctx, identity.Issuer, identity.Subject)
+	if err == nil {
+		applyIdentity(user, identity)
+		if err := s.userRepo.UpdateOIDCProfile(ctx, user); err != nil {
+			return nil, err
+		}
+		return user, nil
+	}
+
+	existing, _ := s.userRepo.GetByEmail(ctx, identity.Email)
+	if existing != nil {
+		if err := linkable(existing, identity); err != nil {
+			return nil, err
+		}
+		applyIdentity(existing, identity)
+		if err := s.userRepo.UpdateOIDCProfile(ctx, existing); err != nil {
+			return nil, err
+		}
+		return existing, nil
+	}
+
+	user = &models.User{
+		ID:          generateUserID(),
+		Preferences: make(models.JSONB),
+		CreatedAt:   time.Now(),
+		UpdatedAt:   time.Now(),
+	}
+	applyIdentity(user, identity)
+
+	if err := s.userRepo.Create(ctx, user); err != nil {
+		return nil, fmt.Errorf("failed to create user: %w", err)
+	}
+
+	return user, nil
+}
+
+// linkable checks that an existing user with the identity's email may be linked to it.
+// Only verified emails are trusted, and a user is linked to at most one subject.
+func linkable(user *models.User, identity *oidc.Identity) error {
+	if !identity.EmailVerified {
+		return fmt.Errorf("a user with email %s already exists and the identity provider has not verified it", identity.Email)
+	}
+	if user.OIDCSubject != nil {
+		return fmt.Errorf("the user with email %s is already linked to another identity", identity.Email)
+	}
+	return nil
+}
+
+// applyIdentity copies the subject and profile claims of an identity to a user
+func applyIdentity(user *models.User, identity *oidc.Identity) {
+	provider := identity.Issuer
+	subject := identity.Subject
+	user.OIDCProvider = &provider
+	user.OIDCSubject = &subject
+	user.Email = identity.Email
+
+	if identity.Name != "" {
+		user.Name = identity.Name
+	} else if user.Name == "" {
+		user.Name = strings.SplitN(identity.Email, "@", 2)[0]
+	}
+
+	if identity.Picture != "" {
+		picture := identity.Picture
+		user.AvatarURL = &picture
+	}
+}
+
+// safeRedirect accepts only local paths, so that the login cannot be used as an open redirect
+func safeRedirect(redirectTo string) string {
+	if !strings.HasPrefix(redirectTo, "/") || strings.HasPrefix(redirectTo, "//") || strings.Contains(redirectTo, "\\") {
+		return ""
+	}
+	return redirectTo
+}
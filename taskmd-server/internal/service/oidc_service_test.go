@@ -0,0 +1,79 @@
+package service
+
+import (
+	"testing"
+
+	"github.com/tktomaru/taskai/taskai-server/internal/models"
+	"github.com/tktomaru/taskai/taskai-server/internal/oidc"
+)
+
+func TestSafeRedirect(t *testing.T) {
+	tests := []struct {
+		in   string
+		want string
+	}{
+		{"/projects/web", "/projects/web"},
+		{"/", "/"},
+		{"", ""},
+		{"https://evil.example.com", ""},
+		{"//evil.example.com", ""},
+		{"/\\evil.example.com", ""},
+		{"projects/web", ""},
+	}
+
+	for _, tt := range tests {
+		if got := safeRedirect(tt.in); got != tt.want {
+			t.Errorf("safeRedirect(%q) = %q, want %q", tt.in, got, tt.want)
+		}
+	}
+}
+
+func TestLinkable(t *testing.T) {
+	linked := "issuer-subject"
+
+	tests := []struct {
+		name     string
+		user     *models.User
+		verified bool
+		wantErr  bool
+	}{
+		{"verified email links an unlinked user", &models.User{}, true, false},
+		{"unverified email does not link", &models.User{}, false, true},
+		{"user linked to another subject", &models.User{OIDCSubject: &linked}, true, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := linkable(tt.user, &oidc.Identity{Email: "alice@example.com", EmailVerified: tt.verified})
+			if (err != nil) != tt.wantErr {
+				t.Errorf("linkable() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestApplyIdentity(t *testing.T) {
+	user := &models.User{Name: "Alice (old)"}
+	applyIdentity(user, &oidc.Identity{
+		Issuer:  "https://id.example.com",
+		Subject: "248289761001",
+		Email:   "alice@example.com",
+		Picture: "https://id.example.com/alice.png",
+	})
+
+	if *user.OIDCProvider != "https://id.example.com" || *user.OIDCSubject != "248289761001" {
+		t.Errorf("user linked to %s/%s", *user.OIDCProvider, *user.OIDCSubject)
+	}
+	if user.Name != "Alice (old)" {
+		t.Errorf("name = %q, want the existing name when the issuer has none", user.Name)
+	}
+	if user.AvatarURL == nil || *user.AvatarURL != "https://id.example.com/alice.png" {
+		t.Errorf("avatar = %v", user.AvatarURL)
+	}
+
+	created := &models.User{}
+	applyIdentity(created, &oidc.Identity{Issuer: "i", Subject: "s", Email: "bob@example.com"})
+	if created.Name != "bob" {
+		t.Errorf("name = %q, want the local part of the email", created.Name)
+	}
+}
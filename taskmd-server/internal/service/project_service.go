@@ -3,9 +3,11 @@ package service
 import (
 	"context"
 	"fmt"
+	"io"
 	"math/rand"
 	"time"
 
+	"github.com/tktomaru/taskai/taskai-server/internal/calendar"
 	"github.com/tktomaru/taskai/taskai-server/internal/models"
 	"github.com/tktomaru/taskai/taskai-server/internal/repository"
 )
@@ -47,6 +49,47 @@ type UpdateProjectRequest struct {
 	Name        string                  `json:"name"`
 	Description string                  `json:"description"`
 	Visibility  models.ProjectVisibility `json:"visibility"`
+
+	// RequireTwoFactor, when set, changes whether members must use two-factor authentication
+	RequireTwoFactor *bool `json:"require_two_factor,omitempty"`
+}
+
+// AuthorizeWrite checks that a user may change the tasks of a project; user is nil for
+// anonymous requests. Members need a role above viewer, and projects without members stay
+// open to everyone, as they were before project roles.
+func (s *ProjectService) AuthorizeWrite(ctx context.Context, projectID string, user *models.User) error {
+	if _, err := s.repo.GetByID(ctx, projectID); err != nil {
+		return fmt.Errorf("project not found: %s", projectID)
+	}
+
+	userID := ""
+	if user != nil {
+		if user.ServiceProjectID != nil && *user.ServiceProjectID != projectID {
+			return ErrProjectAccessDenied
+		}
+		userID = user.ID
+	}
+
+	_, hasMembers, err := s.repo.Membership(ctx, projectID, userID)
+	if err != nil {
+		return err
+	}
+	if !hasMembers {
+		return nil
+	}
+
+	role := ""
+	if userID != "" {
+		if role, err = s.repo.MemberRole(ctx, projectID, userID); err != nil {
+			return err
+		}
+	}
+	switch role {
+	case "owner", "maintainer", "member":
+		return nil
+	default:
+		return ErrProjectAccessDenied
+	}
 }
 
 // Create creates a new project
@@ -106,6 +149,12 @@ func (s *ProjectService) Update(ctx context.Context, projectID string, req *Upda
 	project.Name = req.Name
 	project.Description = &req.Description
 	project.Visibility = req.Visibility
+	if req.RequireTwoFactor != nil {
+		if project.Settings == nil {
+			project.Settings = make(models.JSONB)
+		}
+		project.Settings[RequireTwoFactorSetting] = *req.RequireTwoFactor
+	}
 
 	if err := s.repo.Update(ctx, project); err != nil {
 		return nil, err
@@ -118,3 +167,70 @@ func (s *ProjectService) Update(ctx context.Context, projectID string, req *Upda
 func (s *ProjectService) Delete(ctx context.Context, projectID string) error {
 	return s.repo.Delete(ctx, projectID)
 }
+
+// GetCalendar returns the working-day calendar of a project
+func (s *ProjectService) GetCalendar(ctx context.Context, projectID string) (*calendar.Calendar, error) {
+	project, err := s.repo.GetByID(ctx, projectID)
+	if err != nil {
+		return nil, err
+	}
+
+	return calendar.FromProjectSettings(project.Settings)
+}
+
+// UpdateCalendar replaces the working-day calendar of a project
+func (s *ProjectService) UpdateCalendar(ctx context.Context, projectID string, settings *calendar.Settings) (*calendar.Calendar, error) {
+	cal, err := calendar.FromSettings(settings)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := s.saveCalendar(ctx, projectID, cal); err != nil {
+		return nil, err
+	}
+
+	return cal, nil
+}
+
+// ImportHolidays adds the holidays of an iCal document to a project calendar.
+// When replace is true, existing holidays are removed first.
+func (s *ProjectService) ImportHolidays(ctx context.Context, projectID string, ical io.Reader, replace bool) (*calendar.Calendar, int, error) {
+	holidays, err := calendar.ParseICal(ical)
+	if err != nil {
+		return nil, 0, fmt.Errorf("failed to parse iCal: %w", err)
+	}
+
+	cal, err := s.GetCalendar(ctx, projectID)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	if replace {
+		cal.Holidays = make(map[string]string)
+	}
+
+	for _, h := range holidays {
+		cal.Holidays[h.Date] = h.Name
+	}
+
+	if err := s.saveCalendar(ctx, projectID, cal); err != nil {
+		return nil, 0, err
+	}
+
+	return cal, len(holidays), nil
+}
+
+// saveCalendar stores a calendar in the project settings
+func (s *ProjectService) saveCalendar(ctx context.Context, projectID string, cal *calendar.Calendar) error {
+	project, err := s.repo.GetByID(ctx, projectID)
+	if err != nil {
+		return err
+	}
+
+	if project.Settings == nil {
+		project.Settings = make(models.JSONB)
+	}
+	project.Settings[calendar.SettingsKey] = cal.ToSettings()
+
+	return s.repo.Update(ctx, project)
+}
@@ -0,0 +1,200 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/tktomaru/taskai/taskai-server/internal/auth"
+	"github.com/tktomaru/taskai/taskai-server/internal/models"
+	"github.com/tktomaru/taskai/taskai-server/internal/repository"
+)
+
+// webSocketTicketPrefix marks WebSocket tickets
+const webSocketTicketPrefix = "wst_"
+
+// Live connection errors
+var (
+	ErrProjectAccessDenied = errors.New("you do not have access to this project")
+	ErrCredentialRevoked   = errors.New("the session or token of the connection is no longer valid")
+)
+
+// RealtimeService decides who may follow a project over a WebSocket, issues one-time
+// tickets for browsers and re-checks open connections
+type RealtimeService struct {
+	projectRepo     *repository.ProjectRepository
+	userRepo        *repository.UserRepository
+	sessionRepo     *repository.SessionRepository
+	accessTokenRepo *repository.AccessTokenRepository
+	ticketRepo      *repository.WebSocketTicketRepository
+	ticketTTL       time.Duration
+}
+
+// NewRealtimeService creates a new realtime service
+func NewRealtimeService(projectRepo *repository.ProjectRepository, userRepo *repository.UserRepository, sessionRepo *repository.SessionRepository, accessTokenRepo *repository.AccessTokenRepository, ticketRepo *repository.WebSocketTicketRepository, ticketTTL time.Duration) *RealtimeService {
+	return &RealtimeService{
+		projectRepo:     projectRepo,
+		userRepo:        userRepo,
+		sessionRepo:     sessionRepo,
+		accessTokenRepo: accessTokenRepo,
+		ticketRepo:      ticketRepo,
+		ticketTTL:       ticketTTL,
+	}
+}
+
+// Connection describes the credentials a live connection was opened with
+type Connection struct {
+	UserID        string
+	SessionID     string
+	AccessTokenID string
+	ProjectID     string
+}
+
+// WebSocketTicket is a ticket returned to the client
+type WebSocketTicket struct {
+	Ticket    string    `json:"ticket"`
+	ProjectID string    `json:"project_id,omitempty"`
+	ExpiresAt time.Time `json:"expires_at"`
+}
+
+// AuthorizeProject checks that a user may follow the live events of a project
+func (s *RealtimeService) AuthorizeProject(ctx context.Context, projectID string, user *models.User) error {
+	project, err := s.projectRepo.GetByID(ctx, projectID)
+	if err != nil {
+		return ErrProjectAccessDenied
+	}
+
+	member, hasMembers, err := s.projectRepo.Membership(ctx, projectID, user.ID)
+	if err != nil {
+		return err
+	}
+
+	if !canViewProject(project, user, member, hasMembers) {
+		return ErrProjectAccessDenied
+	}
+
+	return nil
+}
+
+// IssueTicket creates a one-time ticket for the session of a user, optionally limited to a project
+func (s *RealtimeService) IssueTicket(ctx context.Context, user *models.User, sessionID, projectID string) (*WebSocketTicket, error) {
+	if projectID != "" {
+		if err := s.AuthorizeProject(ctx, projectID, user); err != nil {
+			return nil, err
+		}
+	}
+
+	secret, err := auth.GenerateOpaqueToken(webSocketTicketPrefix)
+	if err != nil {
+		return nil, err
+	}
+
+	ticket := &models.WebSocketTicket{
+		TicketHash: auth.HashToken(secret),
+		UserID:     user.ID,
+		SessionID:  sessionID,
+		ProjectID:  optionalString(projectID),
+		ExpiresAt:  time.Now().Add(s.ticketTTL),
+	}
+	if err := s.ticketRepo.Create(ctx, ticket); err != nil {
+		return nil, err
+	}
+
+	return &WebSocketTicket{
+		Ticket:    secret,
+		ProjectID: projectID,
+		ExpiresAt: ticket.ExpiresAt,
+	}, nil
+}
+
+// RedeemTicket uses up a ticket and returns its user and the session it was issued from
+func (s *RealtimeService) RedeemTicket(ctx context.Context, secret string) (*models.User, *models.WebSocketTicket, error) {
+	ticket, err := s.ticketRepo.Consume(ctx, auth.HashToken(secret))
+	if err != nil {
+		return nil, nil, err
+	}
+
+	active, err := s.sessionRepo.IsActive(ctx, ticket.SessionID)
+	if err != nil {
+		return nil, nil, err
+	}
+	if !active {
+		return nil, nil, repository.ErrWebSocketTicketNotFound
+	}
+
+	user, err := s.userRepo.GetByID(ctx, ticket.UserID)
+	if err != nil {
+		return nil, nil, repository.ErrWebSocketTicketNotFound
+	}
+
+	// Remove password hash
+	user.PasswordHash = nil
+
+	return user, ticket, nil
+}
+
+// Revalidate checks that the credentials of an open connection are still valid and that
+// its user may still follow its project
+func (s *RealtimeService) Revalidate(ctx context.Context, conn Connection) error {
+	user, err := s.userRepo.GetByID(ctx, conn.UserID)
+	if err != nil {
+		return ErrCredentialRevoked
+	}
+
+	if conn.SessionID != "" {
+		active, err := s.sessionRepo.IsActive(ctx, conn.SessionID)
+		if err != nil {
+			return err
+		}
+		if !active {
+			return ErrCredentialRevoked
+		}
+	}
+
+	if conn.AccessTokenID != "" {
+		active, err := s.accessTokenRepo.IsActive(ctx, conn.AccessTokenID)
+		if err != nil {
+			return err
+		}
+		if !active {
+			return ErrCredentialRevoked
+		}
+	}
+
+	if conn.ProjectID != "" {
+		return s.AuthorizeProject(ctx, conn.ProjectID, user)
+	}
+
+	return nil
+}
+
+// Cleanup removes expired tickets
+func (s *RealtimeService) Cleanup(ctx context.Context, now time.Time) (int64, error) {
+	return s.ticketRepo.DeleteExpired(ctx, now)
+}
+
+// canViewProject decides whether a user may see a project.
+// Members always may. Team and public projects are open to every signed-in user, and so
+// are private projects nobody has been added to yet, which predate project membership.
+// A project that requires two-factor authentication excludes users without it.
+func canViewProject(project *models.Project, user *models.User, member, hasMembers bool) bool {
+	// Service accounts only ever see their own project, where they are exempt from two-factor authentication
+	if user.ServiceProjectID != nil {
+		return *user.ServiceProjectID == project.ID
+	}
+
+	if ProjectRequiresTwoFactor(project) && user.TOTPEnabledAt == nil {
+		return false
+	}
+
+	if member {
+		return true
+	}
+
+	switch project.Visibility {
+	case models.ProjectVisibilityTeam, models.ProjectVisibilityPublic:
+		return true
+	default:
+		return !hasMembers
+	}
+}
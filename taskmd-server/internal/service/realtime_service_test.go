@@ -0,0 +1,44 @@
+package service
+
+import (
+	"testing"
+	"time"
+
+	"github.com/tktomaru/taskai/taskai-server/internal/models"
+)
+
+func TestCanViewProject(t *testing.T) {
+	enabledAt := time.Now()
+	ownProject := "proj-1"
+	otherProject := "proj-2"
+
+	tests := []struct {
+		name       string
+		visibility models.ProjectVisibility
+		settings   models.JSONB
+		user       *models.User
+		member     bool
+		hasMembers bool
+		want       bool
+	}{
+		{"member of private project", models.ProjectVisibilityPrivate, nil, &models.User{}, true, true, true},
+		{"non-member of private project", models.ProjectVisibilityPrivate, nil, &models.User{}, false, true, false},
+		{"private project without members", models.ProjectVisibilityPrivate, nil, &models.User{}, false, false, true},
+		{"non-member of team project", models.ProjectVisibilityTeam, nil, &models.User{}, false, true, true},
+		{"non-member of public project", models.ProjectVisibilityPublic, nil, &models.User{}, false, true, true},
+		{"service account of the project", models.ProjectVisibilityPublic, nil, &models.User{ServiceProjectID: &ownProject}, true, true, true},
+		{"service account of another project", models.ProjectVisibilityPublic, nil, &models.User{ServiceProjectID: &otherProject}, false, true, false},
+		{"two-factor required, not enabled", models.ProjectVisibilityPrivate, models.JSONB{RequireTwoFactorSetting: true}, &models.User{}, true, true, false},
+		{"two-factor required, enabled", models.ProjectVisibilityPrivate, models.JSONB{RequireTwoFactorSetting: true}, &models.User{TOTPEnabledAt: &enabledAt}, true, true, true},
+		{"two-factor required, service account", models.ProjectVisibilityPrivate, models.JSONB{RequireTwoFactorSetting: true}, &models.User{ServiceProjectID: &ownProject}, true, true, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			project := &models.Project{ID: ownProject, Visibility: tt.visibility, Settings: tt.settings}
+			if got := canViewProject(project, tt.user, tt.member, tt.hasMembers); got != tt.want {
+				t.Errorf("canViewProject() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,379 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"math/rand"
+	"strings"
+	"time"
+
+	"github.com/jmoiron/sqlx"
+	"github.com/tktomaru/taskai/taskai-server/internal/database"
+	"github.com/tktomaru/taskai/taskai-server/internal/models"
+	"github.com/tktomaru/taskai/taskai-server/internal/parser"
+	"github.com/tktomaru/taskai/taskai-server/internal/recurrence"
+	"github.com/tktomaru/taskai/taskai-server/internal/repository"
+)
+
+// generateSeriesID generates a unique series ID
+func generateSeriesID() string {
+	const charset = "abcdefghijklmnopqrstuvwxyz0123456789"
+	timestamp := time.Now().Unix()
+
+	b := make([]byte, 6)
+	for i := range b {
+		b[i] = charset[rand.Intn(len(charset))]
+	}
+
+	return fmt.Sprintf("series-%d-%s", timestamp, string(b))
+}
+
+// RecurrenceService handles recurring task series
+type RecurrenceService struct {
+	db         *database.DB
+	taskRepo   *repository.TaskRepository
+	seriesRepo *repository.SeriesRepository
+	parser     *parser.MarkdownParser
+
+	// OnInstanceCreated is called for every task generated from a series
+	OnInstanceCreated func(task *models.Task)
+}
+
+// NewRecurrenceService creates a new recurrence service
+func NewRecurrenceService(db *database.DB) *RecurrenceService {
+	return &RecurrenceService{
+		db:         db,
+		taskRepo:   repository.NewTaskRepository(db.DB),
+		seriesRepo: repository.NewSeriesRepository(db.DB),
+		parser:     parser.NewMarkdownParser(),
+	}
+}
+
+// SeriesDetail represents a series together with its generated tasks
+type SeriesDetail struct {
+	Series    *models.TaskSeries `json:"series"`
+	Instances []*models.Task     `json:"instances"`
+}
+
+// SyncSeries creates, updates or cancels the series declared by a task's recurrence rule.
+// Tasks generated from a series never declare a series of their own.
+func (s *RecurrenceService) SyncSeries(ctx context.Context, task *models.Task, rule string, userID string) error {
+	series, err := s.seriesRepo.GetByTemplateTask(ctx, task.ID)
+	if err != nil && !errors.Is(err, repository.ErrSeriesNotFound) {
+		return err
+	}
+
+	if rule == "" {
+		// Removing the rule from the template stops the series
+		if series != nil && series.Status == models.SeriesStatusActive {
+			return s.cancel(ctx, series)
+		}
+		return nil
+	}
+
+	parsedRule, err := recurrence.Parse(rule)
+	if err != nil {
+		return fmt.Errorf("invalid recurrence: %w", err)
+	}
+
+	if series == nil {
+		if task.SeriesID != nil {
+			return nil
+		}
+		return s.createSeries(ctx, task, parsedRule, userID)
+	}
+
+	// Refresh the template; recompute the schedule when the rule changed or the series is restarted
+	series.TemplateMarkdown = task.MarkdownBody
+	if parsedRule.String() != series.Recurrence || series.Status != models.SeriesStatusActive {
+		series.Recurrence = parsedRule.String()
+		series.Status = models.SeriesStatusActive
+		series.CancelledAt = nil
+
+		today := truncateDate(time.Now())
+		after := anchorDate(task, series.CreatedAt)
+		if today.After(after) {
+			after = today.AddDate(0, 0, -1)
+		}
+		series.NextRunAt = nil
+		if next, ok := parsedRule.Next(anchorDate(task, series.CreatedAt), after); ok {
+			series.NextRunAt = &next
+		} else {
+			series.Status = models.SeriesStatusFinished
+		}
+	}
+
+	return s.seriesRepo.Update(ctx, series)
+}
+
+// HandleCompletion generates the next instance when the latest task of a series is done.
+// It returns nil when no instance was generated.
+func (s *RecurrenceService) HandleCompletion(ctx context.Context, task *models.Task) (*models.Task, error) {
+	if task.SeriesID == nil {
+		return nil, nil
+	}
+
+	series, err := s.seriesRepo.GetByID(ctx, task.ProjectID, *task.SeriesID)
+	if err != nil {
+		return nil, err
+	}
+
+	if series.Status != models.SeriesStatusActive {
+		return nil, nil
+	}
+
+	// Only the newest instance advances the series
+	if series.LastInstanceID != nil && *series.LastInstanceID != task.ID {
+		return nil, nil
+	}
+
+	return s.generate(ctx, series)
+}
+
+// Tick generates instances for every active series whose next occurrence has arrived
+func (s *RecurrenceService) Tick(ctx context.Context, now time.Time) ([]*models.Task, error) {
+	due, err := s.seriesRepo.ListDue(ctx, now)
+	if err != nil {
+		return nil, err
+	}
+
+	var created []*models.Task
+	for _, series := range due {
+		task, err := s.generate(ctx, series)
+		if err != nil {
+			log.Printf("ERROR: Failed to generate instance for series %s: %v", series.ID, err)
+			continue
+		}
+		if task != nil {
+			created = append(created, task)
+		}
+	}
+
+	return created, nil
+}
+
+// List retrieves all series for a project
+func (s *RecurrenceService) List(ctx context.Context, projectID string) ([]*models.TaskSeries, error) {
+	return s.seriesRepo.List(ctx, projectID)
+}
+
+// Get retrieves a series with its generated tasks
+func (s *RecurrenceService) Get(ctx context.Context, projectID, seriesID string) (*SeriesDetail, error) {
+	series, err := s.seriesRepo.GetByID(ctx, projectID, seriesID)
+	if err != nil {
+		return nil, err
+	}
+
+	instances, err := s.taskRepo.ListBySeries(ctx, projectID, seriesID)
+	if err != nil {
+		return nil, err
+	}
+
+	return &SeriesDetail{
+		Series:    series,
+		Instances: instances,
+	}, nil
+}
+
+// Cancel stops a series from generating further instances
+func (s *RecurrenceService) Cancel(ctx context.Context, projectID, seriesID string) (*models.TaskSeries, error) {
+	series, err := s.seriesRepo.GetByID(ctx, projectID, seriesID)
+	if err != nil {
+		return nil, err
+	}
+
+	if series.Status != models.SeriesStatusActive {
+		return nil, fmt.Errorf("series is already %s", series.Status)
+	}
+
+	if err := s.cancel(ctx, series); err != nil {
+		return nil, err
+	}
+
+	return series, nil
+}
+
+// createSeries creates a series with the task as its template and first instance
+func (s *RecurrenceService) createSeries(ctx context.Context, task *models.Task, rule *recurrence.Rule, userID string) error {
+	anchor := anchorDate(task, task.CreatedAt)
+
+	series := &models.TaskSeries{
+		ID:               generateSeriesID(),
+		ProjectID:        task.ProjectID,
+		TemplateTaskID:   task.ID,
+		Recurrence:       rule.String(),
+		TemplateMarkdown: task.MarkdownBody,
+		Status:           models.SeriesStatusActive,
+		OccurrenceCount:  1,
+		LastInstanceID:   &task.ID,
+		CreatedAt:        time.Now(),
+		UpdatedAt:        time.Now(),
+	}
+	if userID != "" {
+		series.CreatedBy = &userID
+	}
+
+	if next, ok := rule.Next(anchor, anchor); ok && (rule.Count == 0 || rule.Count > 1) {
+		series.NextRunAt = &next
+	} else {
+		series.Status = models.SeriesStatusFinished
+	}
+
+	if err := s.seriesRepo.Create(ctx, series); err != nil {
+		return err
+	}
+
+	task.SeriesID = &series.ID
+	return s.taskRepo.Update(ctx, task)
+}
+
+// generate creates the task for the next occurrence of a series
+func (s *RecurrenceService) generate(ctx context.Context, series *models.TaskSeries) (*models.Task, error) {
+	if series.NextRunAt == nil {
+		return nil, nil
+	}
+
+	rule, err := recurrence.Parse(series.Recurrence)
+	if err != nil {
+		return nil, fmt.Errorf("invalid recurrence: %w", err)
+	}
+
+	parsed, err := s.parser.Parse(series.TemplateMarkdown)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse template: %w", err)
+	}
+
+	template, err := parsed.ToTask(series.ProjectID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to convert template: %w", err)
+	}
+
+	occurrence := truncateDate(*series.NextRunAt)
+
+	// Advance the series and create the task in one transaction, so that a concurrent
+	// completion and scheduler tick cannot both generate the same occurrence and a
+	// failed create does not skip it
+	status := models.SeriesStatusActive
+	next, ok := rule.Next(anchorDate(template, series.CreatedAt), occurrence)
+	var nextRunAt *time.Time
+	if ok && (rule.Count == 0 || series.OccurrenceCount+1 < rule.Count) {
+		nextRunAt = &next
+	} else {
+		status = models.SeriesStatusFinished
+	}
+
+	var instance *models.Task
+	err = s.db.Transaction(ctx, func(tx *sqlx.Tx) error {
+		taskRepo := s.taskRepo.WithTx(tx)
+		seriesRepo := s.seriesRepo.WithTx(tx)
+
+		claimed, err := seriesRepo.ClaimOccurrence(ctx, series.ID, occurrence, nextRunAt, status)
+		if err != nil || !claimed {
+			return err
+		}
+
+		// The ID prefix stays locked until the task is committed
+		created, err := s.buildInstance(ctx, taskRepo, template, series, occurrence)
+		if err != nil {
+			return err
+		}
+
+		if err := taskRepo.Create(ctx, created); err != nil {
+			return fmt.Errorf("failed to create instance: %w", err)
+		}
+
+		if err := seriesRepo.SetLastInstance(ctx, series.ID, created.ID); err != nil {
+			return err
+		}
+
+		instance = created
+		return nil
+	})
+	if err != nil || instance == nil {
+		return nil, err
+	}
+
+	if s.OnInstanceCreated != nil {
+		s.OnInstanceCreated(instance)
+	}
+
+	return instance, nil
+}
+
+// buildInstance builds a new open task from the template with dates shifted to the occurrence
+func (s *RecurrenceService) buildInstance(ctx context.Context, taskRepo *repository.TaskRepository, template *models.Task, series *models.TaskSeries, occurrence time.Time) (*models.Task, error) {
+	id, err := taskRepo.NextID(ctx, idPrefix(template.ID))
+	if err != nil {
+		return nil, err
+	}
+
+	instance := *template
+	instance.ID = id
+	instance.Status = models.TaskStatusOpen
+	instance.SeriesID = &series.ID
+	instance.CompletedAt = nil
+	instance.CreatedBy = series.CreatedBy
+	instance.UpdatedBy = series.CreatedBy
+	instance.CreatedAt = time.Now()
+	instance.UpdatedAt = time.Now()
+
+	switch {
+	case template.StartDate != nil:
+		start := occurrence
+		instance.StartDate = &start
+		if template.DueDate != nil {
+			due := occurrence.Add(template.DueDate.Sub(*template.StartDate))
+			instance.DueDate = &due
+		}
+	case template.DueDate != nil:
+		due := occurrence
+		instance.DueDate = &due
+	}
+
+	// Regenerate Markdown so the instance carries its own ID, status and dates.
+	// The recurrence rule is dropped so the instance does not start a new series.
+	markdown, err := parser.PatchFrontmatter(parser.GenerateMarkdown(&instance), map[string]interface{}{"recurrence": nil})
+	if err != nil {
+		return nil, fmt.Errorf("failed to generate instance: %w", err)
+	}
+	instance.MarkdownBody = markdown
+
+	return &instance, nil
+}
+
+// cancel marks a series as cancelled
+func (s *RecurrenceService) cancel(ctx context.Context, series *models.TaskSeries) error {
+	now := time.Now()
+	series.Status = models.SeriesStatusCancelled
+	series.CancelledAt = &now
+	series.NextRunAt = nil
+
+	return s.seriesRepo.Update(ctx, series)
+}
+
+// anchorDate returns the date a series is anchored at: the start date,
+// then the due date, then the fallback
+func anchorDate(task *models.Task, fallback time.Time) time.Time {
+	if task.StartDate != nil {
+		return truncateDate(*task.StartDate)
+	}
+	if task.DueDate != nil {
+		return truncateDate(*task.DueDate)
+	}
+	return truncateDate(fallback)
+}
+
+// idPrefix returns the prefix of a task ID (e.g. "T-1042" -> "T")
+func idPrefix(taskID string) string {
+	if idx := strings.LastIndex(taskID, "-"); idx > 0 {
+		return taskID[:idx]
+	}
+	return taskID
+}
+
+// truncateDate strips the time of day in UTC, matching how DATE columns are scanned
+func truncateDate(t time.Time) time.Time {
+	return time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, time.UTC)
+}
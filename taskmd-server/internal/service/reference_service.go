@@ -0,0 +1,112 @@
+package service
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/jmoiron/sqlx"
+	"github.com/tktomaru/taskai/taskai-server/internal/database"
+	"github.com/tktomaru/taskai/taskai-server/internal/models"
+	"github.com/tktomaru/taskai/taskai-server/internal/parser"
+	"github.com/tktomaru/taskai/taskai-server/internal/repository"
+)
+
+// ReferenceService keeps the relations extracted from task Markdown bodies in sync
+type ReferenceService struct {
+	db *database.DB
+}
+
+// NewReferenceService creates a new reference service
+func NewReferenceService(db *database.DB) *ReferenceService {
+	return &ReferenceService{db: db}
+}
+
+// SyncReferences replaces the relations extracted from a task's Markdown body.
+// References to tasks that do not exist or were deleted are recorded as dangling.
+func (s *ReferenceService) SyncReferences(ctx context.Context, task *models.Task, userID string) error {
+	refs := uniqueReferences(task.ID, parser.ExtractTaskReferences(task.MarkdownBody))
+
+	var createdBy *string
+	if userID != "" {
+		createdBy = &userID
+	}
+
+	return s.db.Transaction(ctx, func(tx *sqlx.Tx) error {
+		repo := repository.NewRelationRepository(s.db.DB).WithTx(tx)
+
+		if err := repo.ClearMarkdownReferences(ctx, task.ID); err != nil {
+			return err
+		}
+
+		for _, ref := range refs {
+			if _, err := repo.AddMarkdownReference(ctx, task.ID, ref.TaskID, ref.RelationType, createdBy); err != nil {
+				return fmt.Errorf("failed to store reference to %s: %w", ref.TaskID, err)
+			}
+		}
+
+		return nil
+	})
+}
+
+// HandleCreated resolves the dangling references to a new task and syncs its own references
+func (s *ReferenceService) HandleCreated(ctx context.Context, task *models.Task, userID string) error {
+	repo := repository.NewRelationRepository(s.db.DB)
+	if _, err := repo.ResolveMissing(ctx, task.ID); err != nil {
+		return err
+	}
+
+	return s.SyncReferences(ctx, task, userID)
+}
+
+// HandleDeleted flags the references to a deleted task as dangling
+func (s *ReferenceService) HandleDeleted(ctx context.Context, taskID string) error {
+	repo := repository.NewRelationRepository(s.db.DB)
+	_, err := repo.MarkTargetDeleted(ctx, taskID)
+	return err
+}
+
+// Backlinks retrieves the tasks of a project that refer to a task
+func (s *ReferenceService) Backlinks(ctx context.Context, projectID, taskID string) ([]*models.TaskBacklink, error) {
+	return repository.NewRelationRepository(s.db.DB).ListBacklinks(ctx, projectID, taskID)
+}
+
+// ListDangling retrieves the references to missing or deleted tasks in a project
+func (s *ReferenceService) ListDangling(ctx context.Context, projectID string) ([]*models.DanglingReference, error) {
+	return repository.NewRelationRepository(s.db.DB).ListDangling(ctx, projectID)
+}
+
+// uniqueReferences removes self references and repeated mentions of a task.
+// A typed reference (e.g. "blocked by T-2") makes plain mentions of the same task redundant.
+func uniqueReferences(taskID string, refs []parser.TaskReference) []parser.TaskReference {
+	typed := make(map[string]bool)
+	for _, ref := range refs {
+		if ref.RelationType != models.RelationTypeRelated {
+			typed[ref.TaskID] = true
+		}
+	}
+
+	type key struct {
+		taskID       string
+		relationType models.RelationType
+	}
+	seen := make(map[key]bool)
+
+	var unique []parser.TaskReference
+	for _, ref := range refs {
+		if ref.TaskID == taskID {
+			continue
+		}
+		if ref.RelationType == models.RelationTypeRelated && typed[ref.TaskID] {
+			continue
+		}
+
+		k := key{ref.TaskID, ref.RelationType}
+		if seen[k] {
+			continue
+		}
+		seen[k] = true
+		unique = append(unique, ref)
+	}
+
+	return unique
+}
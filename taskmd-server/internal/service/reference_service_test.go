@@ -0,0 +1,37 @@
+package service
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/tktomaru/taskai/taskai-server/internal/models"
+	"github.com/tktomaru/taskai/taskai-server/internal/parser"
+)
+
+func TestUniqueReferences(t *testing.T) {
+	markdown := "## T-1: Checkout\n\n" +
+		"Blocked by T-2. See T-2 and T-3 for details, and T-3 again.\n" +
+		"This task (T-1) duplicates T-4.\n"
+
+	got := uniqueReferences("T-1", parser.ExtractTaskReferences(markdown))
+
+	type reference struct {
+		taskID       string
+		relationType models.RelationType
+	}
+
+	want := []reference{
+		{"T-2", models.RelationTypeBlockedBy},
+		{"T-3", models.RelationTypeRelated},
+		{"T-4", models.RelationTypeDuplicates},
+	}
+
+	var gotRefs []reference
+	for _, ref := range got {
+		gotRefs = append(gotRefs, reference{ref.TaskID, ref.RelationType})
+	}
+
+	if !reflect.DeepEqual(gotRefs, want) {
+		t.Errorf("uniqueReferences() = %+v, want %+v", gotRefs, want)
+	}
+}
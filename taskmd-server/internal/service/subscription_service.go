@@ -0,0 +1,107 @@
+package service
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/tktomaru/taskai/taskai-server/internal/models"
+	"github.com/tktomaru/taskai/taskai-server/internal/repository"
+)
+
+// WatchRequest represents a request to watch a task, view or project
+type WatchRequest struct {
+	Digest bool `json:"digest"`
+}
+
+// SubscriptionService lets users watch and unwatch tasks, saved views and projects
+type SubscriptionService struct {
+	subscriptionRepo *repository.SubscriptionRepository
+	taskRepo         *repository.TaskRepository
+	viewRepo         *repository.ViewRepository
+	projectRepo      *repository.ProjectRepository
+	viewWatch        *ViewWatchService
+}
+
+// NewSubscriptionService creates a new subscription service
+func NewSubscriptionService(subscriptionRepo *repository.SubscriptionRepository, taskRepo *repository.TaskRepository, viewRepo *repository.ViewRepository, projectRepo *repository.ProjectRepository) *SubscriptionService {
+	return &SubscriptionService{
+		subscriptionRepo: subscriptionRepo,
+		taskRepo:         taskRepo,
+		viewRepo:         viewRepo,
+		projectRepo:      projectRepo,
+	}
+}
+
+// SetViewWatch enables recording the result set of a view when it is first watched
+func (s *SubscriptionService) SetViewWatch(viewWatch *ViewWatchService) {
+	s.viewWatch = viewWatch
+}
+
+// Watch subscribes a user to a target. Watching again updates the digest setting.
+func (s *SubscriptionService) Watch(ctx context.Context, userID string, targetType models.SubscriptionTarget, projectID, targetID string, req *WatchRequest) (*models.Subscription, error) {
+	view, err := s.checkTarget(ctx, targetType, projectID, targetID)
+	if err != nil {
+		return nil, err
+	}
+
+	sub := &models.Subscription{
+		UserID:     userID,
+		TargetType: targetType,
+		TargetID:   targetID,
+		ProjectID:  projectID,
+		Reason:     models.SubscriptionReasonManual,
+		Digest:     req.Digest,
+	}
+	if err := s.subscriptionRepo.Watch(ctx, sub); err != nil {
+		return nil, err
+	}
+
+	// Alerts are relative to the result set at the time the view was first watched
+	if view != nil && s.viewWatch != nil {
+		if _, found, err := s.subscriptionRepo.GetViewResults(ctx, view.ID); err != nil {
+			return nil, err
+		} else if !found {
+			if err := s.viewWatch.Snapshot(ctx, view); err != nil {
+				return nil, err
+			}
+		}
+	}
+
+	return s.subscriptionRepo.Get(ctx, userID, targetType, targetID)
+}
+
+// Unwatch unsubscribes a user from a target
+func (s *SubscriptionService) Unwatch(ctx context.Context, userID string, targetType models.SubscriptionTarget, projectID, targetID string) error {
+	if _, err := s.checkTarget(ctx, targetType, projectID, targetID); err != nil {
+		return err
+	}
+
+	return s.subscriptionRepo.Unwatch(ctx, &models.Subscription{
+		UserID:     userID,
+		TargetType: targetType,
+		TargetID:   targetID,
+		ProjectID:  projectID,
+		Reason:     models.SubscriptionReasonManual,
+	})
+}
+
+// List retrieves the active subscriptions of a user
+func (s *SubscriptionService) List(ctx context.Context, userID string) ([]*models.Subscription, error) {
+	return s.subscriptionRepo.ListByUser(ctx, userID)
+}
+
+// checkTarget verifies that a target exists in a project. The view is returned for view targets.
+func (s *SubscriptionService) checkTarget(ctx context.Context, targetType models.SubscriptionTarget, projectID, targetID string) (*models.SavedView, error) {
+	switch targetType {
+	case models.SubscriptionTargetTask:
+		_, err := s.taskRepo.GetByID(ctx, projectID, targetID)
+		return nil, err
+	case models.SubscriptionTargetView:
+		return s.viewRepo.GetByID(ctx, projectID, targetID)
+	case models.SubscriptionTargetProject:
+		_, err := s.projectRepo.GetByID(ctx, targetID)
+		return nil, err
+	default:
+		return nil, fmt.Errorf("invalid subscription target: %s", targetType)
+	}
+}
@@ -3,8 +3,10 @@ package service
 import (
 	"context"
 	"fmt"
+	"log"
 	"time"
 
+	"github.com/tktomaru/taskai/taskai-server/internal/calendar"
 	"github.com/tktomaru/taskai/taskai-server/internal/models"
 	"github.com/tktomaru/taskai/taskai-server/internal/parser"
 	"github.com/tktomaru/taskai/taskai-server/internal/repository"
@@ -12,18 +14,45 @@ import (
 
 // TaskService handles task business logic
 type TaskService struct {
-	repo   *repository.TaskRepository
-	parser *parser.MarkdownParser
+	repo          *repository.TaskRepository
+	parser        *parser.MarkdownParser
+	calendar      *calendar.Calendar
+	recurrence    *RecurrenceService
+	references    *ReferenceService
+	notifications *NotificationService
 }
 
 // NewTaskService creates a new task service
 func NewTaskService(repo *repository.TaskRepository) *TaskService {
 	return &TaskService{
-		repo:   repo,
-		parser: parser.NewMarkdownParser(),
+		repo:     repo,
+		parser:   parser.NewMarkdownParser(),
+		calendar: calendar.Default(),
 	}
 }
 
+// SetCalendar sets the project calendar used for date roll-forward and SLA calculations
+func (s *TaskService) SetCalendar(cal *calendar.Calendar) {
+	if cal != nil {
+		s.calendar = cal
+	}
+}
+
+// SetRecurrence enables recurring task series for tasks declaring a recurrence rule
+func (s *TaskService) SetRecurrence(recurrence *RecurrenceService) {
+	s.recurrence = recurrence
+}
+
+// SetReferences enables syncing of the task references mentioned in Markdown bodies
+func (s *TaskService) SetReferences(references *ReferenceService) {
+	s.references = references
+}
+
+// SetNotifications enables notifications for mentions, new assignees and changes to watched tasks
+func (s *TaskService) SetNotifications(notifications *NotificationService) {
+	s.notifications = notifications
+}
+
 // CreateTaskRequest represents a request to create a task
 type CreateTaskRequest struct {
 	MarkdownBody string `json:"markdown_body"`
@@ -36,11 +65,14 @@ type UpdateTaskRequest struct {
 	UpdatedBy    string `json:"updated_by,omitempty"`
 }
 
-// BulkUpdateRequest represents a request to bulk update tasks
-type BulkUpdateRequest struct {
-	TaskIDs  []string               `json:"task_ids"`
-	Updates  map[string]interface{} `json:"updates"`
-	UpdatedBy string                 `json:"updated_by,omitempty"`
+// TaskSchedule represents business-day scheduling information for a task
+type TaskSchedule struct {
+	TaskID              string     `json:"task_id"`
+	DueDate             *time.Time `json:"due_date,omitempty"`
+	BusinessDaysLeft    *int       `json:"business_days_left,omitempty"`
+	OverdueBusinessDays int        `json:"overdue_business_days"`
+	SLADueDate          *time.Time `json:"sla_due_date,omitempty"`
+	SLABreached         bool       `json:"sla_breached"`
 }
 
 // Create creates a new task from markdown
@@ -56,6 +88,9 @@ func (s *TaskService) Create(ctx context.Context, projectID string, req *CreateT
 	if err != nil {
 		return nil, fmt.Errorf("failed to convert to task: %w", err)
 	}
+	if err := s.rollForwardDates(task); err != nil {
+		return nil, fmt.Errorf("failed to roll forward dates: %w", err)
+	}
 
 	// Set metadata
 	task.CreatedBy = &req.CreatedBy
@@ -68,6 +103,25 @@ func (s *TaskService) Create(ctx context.Context, projectID string, req *CreateT
 		return nil, fmt.Errorf("failed to create task: %w", err)
 	}
 
+	// Start a series when the task declares a recurrence rule
+	if s.recurrence != nil && parsed.Metadata.Recurrence != "" {
+		if err := s.recurrence.SyncSeries(ctx, task, parsed.Metadata.Recurrence, req.CreatedBy); err != nil {
+			log.Printf("WARNING: Failed to create series for task %s: %v", task.ID, err)
+		}
+	}
+
+	if s.references != nil {
+		if err := s.references.HandleCreated(ctx, task, req.CreatedBy); err != nil {
+			log.Printf("WARNING: Failed to sync references for task %s: %v", task.ID, err)
+		}
+	}
+
+	if s.notifications != nil {
+		if err := s.notifications.NotifyTaskSaved(ctx, nil, task, req.CreatedBy); err != nil {
+			log.Printf("WARNING: Failed to send notifications for task %s: %v", task.ID, err)
+		}
+	}
+
 	return task, nil
 }
 
@@ -110,6 +164,9 @@ func (s *TaskService) Update(ctx context.Context, projectID, taskID string, req
 	if err != nil {
 		return nil, fmt.Errorf("failed to convert to task: %w", err)
 	}
+	if err := s.rollForwardDates(updatedTask); err != nil {
+		return nil, fmt.Errorf("failed to roll forward dates: %w", err)
+	}
 
 	// Verify ID hasn't changed
 	if updatedTask.ID != taskID {
@@ -120,6 +177,7 @@ func (s *TaskService) Update(ctx context.Context, projectID, taskID string, req
 	updatedTask.CreatedAt = existingTask.CreatedAt
 	updatedTask.CreatedBy = existingTask.CreatedBy
 	updatedTask.UpdatedBy = &req.UpdatedBy
+	updatedTask.SeriesID = existingTask.SeriesID
 
 	// Update status-specific timestamps
 	if updatedTask.Status == models.TaskStatusDone && existingTask.Status != models.TaskStatusDone {
@@ -132,69 +190,83 @@ func (s *TaskService) Update(ctx context.Context, projectID, taskID string, req
 		return nil, fmt.Errorf("failed to update task: %w", err)
 	}
 
-	return updatedTask, nil
-}
-
-// BulkUpdate updates multiple tasks with the same field changes
-func (s *TaskService) BulkUpdate(ctx context.Context, projectID string, req *BulkUpdateRequest) (int, error) {
-	updatedCount := 0
-
-	for _, taskID := range req.TaskIDs {
-		// Get existing task
-		task, err := s.repo.GetByID(ctx, projectID, taskID)
-		if err != nil {
-			// Skip tasks that don't exist
-			continue
+	if s.recurrence != nil {
+		if err := s.recurrence.SyncSeries(ctx, updatedTask, parsed.Metadata.Recurrence, req.UpdatedBy); err != nil {
+			log.Printf("WARNING: Failed to sync series for task %s: %v", updatedTask.ID, err)
 		}
 
-		// Apply updates
-		if status, ok := req.Updates["status"].(string); ok && status != "" {
-			task.Status = models.TaskStatus(status)
-		}
-		if priority, ok := req.Updates["priority"].(string); ok && priority != "" {
-			task.Priority = models.TaskPriority(priority)
-		}
-		if assignees, ok := req.Updates["assignees"].([]interface{}); ok {
-			task.Assignees = make([]string, len(assignees))
-			for i, a := range assignees {
-				if str, ok := a.(string); ok {
-					task.Assignees[i] = str
-				}
+		// Completing the latest instance of a series generates the next one
+		if updatedTask.Status == models.TaskStatusDone && existingTask.Status != models.TaskStatusDone {
+			if _, err := s.recurrence.HandleCompletion(ctx, updatedTask); err != nil {
+				log.Printf("WARNING: Failed to generate next instance for task %s: %v", updatedTask.ID, err)
 			}
 		}
-		if labels, ok := req.Updates["labels"].([]interface{}); ok {
-			task.Labels = make([]string, len(labels))
-			for i, l := range labels {
-				if str, ok := l.(string); ok {
-					task.Labels[i] = str
-				}
-			}
-		}
-
-		// Set updated metadata
-		task.UpdatedBy = &req.UpdatedBy
-		now := time.Now()
-		task.UpdatedAt = now
+	}
 
-		// Update status-specific timestamps
-		if task.Status == models.TaskStatusDone && task.CompletedAt == nil {
-			task.CompletedAt = &now
+	if s.references != nil {
+		if err := s.references.SyncReferences(ctx, updatedTask, req.UpdatedBy); err != nil {
+			log.Printf("WARNING: Failed to sync references for task %s: %v", updatedTask.ID, err)
 		}
+	}
 
-		// Update in repository
-		if err := s.repo.Update(ctx, task); err != nil {
-			continue
+	if s.notifications != nil {
+		if err := s.notifications.NotifyTaskSaved(ctx, existingTask, updatedTask, req.UpdatedBy); err != nil {
+			log.Printf("WARNING: Failed to send notifications for task %s: %v", updatedTask.ID, err)
 		}
+	}
+
+	return updatedTask, nil
+}
 
-		updatedCount++
+// Patch applies a JSON merge patch to the frontmatter of a task.
+// Only the YAML block is rewritten; the rest of the markdown is kept as is.
+func (s *TaskService) Patch(ctx context.Context, projectID, taskID string, patch map[string]interface{}, updatedBy string) (*models.Task, error) {
+	existingTask, err := s.repo.GetByID(ctx, projectID, taskID)
+	if err != nil {
+		return nil, err
 	}
 
-	return updatedCount, nil
+	markdown, err := parser.PatchFrontmatter(existingTask.MarkdownBody, patch)
+	if err != nil {
+		return nil, fmt.Errorf("failed to patch task: %w", err)
+	}
+
+	return s.Update(ctx, projectID, taskID, &UpdateTaskRequest{
+		MarkdownBody: markdown,
+		UpdatedBy:    updatedBy,
+	})
 }
 
 // Delete deletes a task
-func (s *TaskService) Delete(ctx context.Context, projectID, taskID string) error {
-	return s.repo.Delete(ctx, projectID, taskID)
+func (s *TaskService) Delete(ctx context.Context, projectID, taskID, deletedBy string) error {
+	// Watchers are notified with the task as it was before deletion
+	var task *models.Task
+	if s.notifications != nil {
+		existingTask, err := s.repo.GetByID(ctx, projectID, taskID)
+		if err != nil {
+			return err
+		}
+		task = existingTask
+	}
+
+	if err := s.repo.Delete(ctx, projectID, taskID); err != nil {
+		return err
+	}
+
+	// References to the deleted task are kept but flagged as dangling
+	if s.references != nil {
+		if err := s.references.HandleDeleted(ctx, taskID); err != nil {
+			log.Printf("WARNING: Failed to flag references to task %s: %v", taskID, err)
+		}
+	}
+
+	if task != nil {
+		if err := s.notifications.NotifyTaskDeleted(ctx, task, deletedBy); err != nil {
+			log.Printf("WARNING: Failed to send notifications for task %s: %v", taskID, err)
+		}
+	}
+
+	return nil
 }
 
 // Search performs full-text search
@@ -206,6 +278,79 @@ func (s *TaskService) Search(ctx context.Context, projectID, query string, limit
 	return s.repo.Search(ctx, projectID, query, limit)
 }
 
+// Schedule calculates business-day due date and SLA information for a task
+func (s *TaskService) Schedule(task *models.Task, now time.Time) *TaskSchedule {
+	schedule := &TaskSchedule{
+		TaskID:  task.ID,
+		DueDate: task.DueDate,
+	}
+
+	done := task.Status == models.TaskStatusDone || task.Status == models.TaskStatusArchived
+
+	if task.DueDate != nil {
+		left := s.calendar.BusinessDaysBetween(now, *task.DueDate)
+		schedule.BusinessDaysLeft = &left
+		if !done {
+			schedule.OverdueBusinessDays = s.calendar.OverdueBusinessDays(*task.DueDate, now)
+		}
+	}
+
+	// SLA is counted from the start date, falling back to creation time
+	start := task.CreatedAt
+	if task.StartDate != nil {
+		start = *task.StartDate
+	}
+	if deadline, ok := s.calendar.SLADueDate(string(task.Priority), start); ok {
+		schedule.SLADueDate = &deadline
+
+		end := now
+		if task.CompletedAt != nil {
+			end = *task.CompletedAt
+		}
+		schedule.SLABreached = s.calendar.BusinessDaysBetween(deadline, end) > 0
+	}
+
+	return schedule
+}
+
+// rollForwardDates moves start and due dates off non-working days
+// when the project calendar has roll-forward enabled, rewriting the
+// frontmatter so that the stored Markdown matches the task fields
+func (s *TaskService) rollForwardDates(task *models.Task) error {
+	if !s.calendar.RollForward {
+		return nil
+	}
+
+	patch := make(map[string]interface{})
+	if task.StartDate != nil {
+		start := s.calendar.NextWorkingDay(*task.StartDate)
+		if !start.Equal(*task.StartDate) {
+			patch["start_date"] = start.Format("2006-01-02")
+		}
+		task.StartDate = &start
+	}
+
+	if task.DueDate != nil {
+		due := s.calendar.NextWorkingDay(*task.DueDate)
+		if !due.Equal(*task.DueDate) {
+			patch["due_date"] = due.Format("2006-01-02")
+		}
+		task.DueDate = &due
+	}
+
+	if len(patch) == 0 {
+		return nil
+	}
+
+	markdown, err := parser.PatchFrontmatter(task.MarkdownBody, patch)
+	if err != nil {
+		return err
+	}
+	task.MarkdownBody = markdown
+
+	return nil
+}
+
 // GetAcceptanceCriteria extracts acceptance criteria from a task
 func (s *TaskService) GetAcceptanceCriteria(task *models.Task) []string {
 	return parser.ExtractAcceptanceCriteria(task.MarkdownBody)
@@ -0,0 +1,59 @@
+package service
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/tktomaru/taskai/taskai-server/internal/calendar"
+	"github.com/tktomaru/taskai/taskai-server/internal/parser"
+)
+
+func TestRollForwardDates(t *testing.T) {
+	markdown := "## T-1: Release\n\n```yaml\nid: T-1\nstatus: open\npriority: P2\nstart_date: 2026-01-02\ndue_date: 2026-01-03\n```\n\nShip it.\n"
+
+	tests := []struct {
+		name        string
+		rollForward bool
+		wantStart   string
+		wantDue     string
+	}{
+		{name: "roll forward enabled", rollForward: true, wantStart: "2026-01-02", wantDue: "2026-01-05"},
+		{name: "roll forward disabled", rollForward: false, wantStart: "2026-01-02", wantDue: "2026-01-03"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			parsed, err := parser.NewMarkdownParser().Parse(markdown)
+			if err != nil {
+				t.Fatalf("Parse() error = %v", err)
+			}
+			task, err := parsed.ToTask("project-1")
+			if err != nil {
+				t.Fatalf("ToTask() error = %v", err)
+			}
+
+			cal := calendar.Default()
+			cal.RollForward = tt.rollForward
+			s := &TaskService{calendar: cal}
+			if err := s.rollForwardDates(task); err != nil {
+				t.Fatalf("rollForwardDates() error = %v", err)
+			}
+
+			if got := task.StartDate.Format("2006-01-02"); got != tt.wantStart {
+				t.Errorf("StartDate = %s, want %s", got, tt.wantStart)
+			}
+			if got := task.DueDate.Format("2006-01-02"); got != tt.wantDue {
+				t.Errorf("DueDate = %s, want %s", got, tt.wantDue)
+			}
+			if !strings.Contains(task.MarkdownBody, "start_date: "+tt.wantStart) {
+				t.Errorf("MarkdownBody missing start_date %s:\n%s", tt.wantStart, task.MarkdownBody)
+			}
+			if !strings.Contains(task.MarkdownBody, "due_date: "+tt.wantDue) {
+				t.Errorf("MarkdownBody missing due_date %s:\n%s", tt.wantDue, task.MarkdownBody)
+			}
+			if !strings.HasSuffix(task.MarkdownBody, "Ship it.\n") {
+				t.Errorf("MarkdownBody body changed:\n%s", task.MarkdownBody)
+			}
+		})
+	}
+}
@@ -0,0 +1,317 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"math/rand"
+	"regexp"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/tktomaru/taskai/taskai-server/internal/models"
+	"github.com/tktomaru/taskai/taskai-server/internal/parser"
+	"github.com/tktomaru/taskai/taskai-server/internal/repository"
+)
+
+// placeholderPattern matches template placeholders such as {{title}} or {{ today }}
+var placeholderPattern = regexp.MustCompile(`\{\{\s*([a-zA-Z_][a-zA-Z0-9_]*)\s*\}\}`)
+
+// plainScalarPattern matches values that can be written unquoted in the frontmatter,
+// including inside flow sequences such as [{{assignee}}]
+var plainScalarPattern = regexp.MustCompile(`^[A-Za-z0-9_][A-Za-z0-9_.@/-]*$`)
+
+// idPrefixPattern matches the task ID prefixes accepted by the Markdown parser
+var idPrefixPattern = regexp.MustCompile(`^[A-Z]+$`)
+
+// generateTemplateID generates a unique template ID
+func generateTemplateID(projectID string) string {
+	const charset = "abcdefghijklmnopqrstuvwxyz0123456789"
+
+	b := make([]byte, 6)
+	for i := range b {
+		b[i] = charset[rand.Intn(len(charset))]
+	}
+
+	return fmt.Sprintf("%s-tmpl-%s", projectID, string(b))
+}
+
+// TemplateService handles task template business logic
+type TemplateService struct {
+	repo     *repository.TemplateRepository
+	taskRepo *repository.TaskRepository
+}
+
+// NewTemplateService creates a new template service
+func NewTemplateService(repo *repository.TemplateRepository, taskRepo *repository.TaskRepository) *TemplateService {
+	return &TemplateService{
+		repo:     repo,
+		taskRepo: taskRepo,
+	}
+}
+
+// CreateTemplateRequest represents a request to create a template
+type CreateTemplateRequest struct {
+	ID          string `json:"id"`
+	Name        string `json:"name"`
+	Description string `json:"description"`
+	Markdown    string `json:"markdown"`
+	IDPrefix    string `json:"id_prefix"`
+	CreatedBy   string `json:"created_by,omitempty"`
+}
+
+// UpdateTemplateRequest represents a request to update a template
+type UpdateTemplateRequest struct {
+	Name        string `json:"name"`
+	Description string `json:"description"`
+	Markdown    string `json:"markdown"`
+	IDPrefix    string `json:"id_prefix"`
+}
+
+// RenderTemplateRequest represents the values used to render a template
+type RenderTemplateRequest struct {
+	Title     string            `json:"title"`
+	Assignee  string            `json:"assignee"`
+	Variables map[string]string `json:"variables"`
+}
+
+// Create creates a new template
+func (s *TemplateService) Create(ctx context.Context, projectID string, req *CreateTemplateRequest) (*models.TaskTemplate, error) {
+	if err := validateTemplate(req.Name, req.Markdown, req.IDPrefix); err != nil {
+		return nil, err
+	}
+
+	templateID := req.ID
+	if templateID == "" {
+		templateID = generateTemplateID(projectID)
+	}
+
+	prefix := req.IDPrefix
+	if prefix == "" {
+		prefix = "T"
+	}
+
+	template := &models.TaskTemplate{
+		ID:          templateID,
+		ProjectID:   projectID,
+		Name:        req.Name,
+		Description: &req.Description,
+		Markdown:    req.Markdown,
+		IDPrefix:    prefix,
+		CreatedAt:   time.Now(),
+		UpdatedAt:   time.Now(),
+	}
+	if req.CreatedBy != "" {
+		template.CreatedBy = &req.CreatedBy
+	}
+
+	if err := s.repo.Create(ctx, template); err != nil {
+		return nil, err
+	}
+
+	return template, nil
+}
+
+// GetByID retrieves a template by ID
+func (s *TemplateService) GetByID(ctx context.Context, projectID, templateID string) (*models.TaskTemplate, error) {
+	return s.repo.GetByID(ctx, projectID, templateID)
+}
+
+// List retrieves all templates for a project
+func (s *TemplateService) List(ctx context.Context, projectID string) ([]*models.TaskTemplate, error) {
+	return s.repo.List(ctx, projectID)
+}
+
+// Update updates an existing template
+func (s *TemplateService) Update(ctx context.Context, projectID, templateID string, req *UpdateTemplateRequest) (*models.TaskTemplate, error) {
+	if err := validateTemplate(req.Name, req.Markdown, req.IDPrefix); err != nil {
+		return nil, err
+	}
+
+	template, err := s.repo.GetByID(ctx, projectID, templateID)
+	if err != nil {
+		return nil, err
+	}
+
+	template.Name = req.Name
+	template.Description = &req.Description
+	template.Markdown = req.Markdown
+	if req.IDPrefix != "" {
+		template.IDPrefix = req.IDPrefix
+	}
+
+	if err := s.repo.Update(ctx, template); err != nil {
+		return nil, err
+	}
+
+	return template, nil
+}
+
+// Delete deletes a template
+func (s *TemplateService) Delete(ctx context.Context, projectID, templateID string) error {
+	return s.repo.Delete(ctx, projectID, templateID)
+}
+
+// RestoreDefaults recreates any missing default templates of a project
+func (s *TemplateService) RestoreDefaults(ctx context.Context, projectID string) ([]*models.TaskTemplate, error) {
+	if err := s.repo.CreateDefaults(ctx, projectID); err != nil {
+		return nil, err
+	}
+
+	return s.repo.List(ctx, projectID)
+}
+
+// Render renders a template into task Markdown.
+// A new task ID is allocated from the template's ID prefix.
+func (s *TemplateService) Render(ctx context.Context, projectID, templateID string, req *RenderTemplateRequest) (string, error) {
+	if strings.TrimSpace(req.Title) == "" {
+		return "", fmt.Errorf("title is required")
+	}
+
+	template, err := s.repo.GetByID(ctx, projectID, templateID)
+	if err != nil {
+		return "", err
+	}
+
+	taskID, err := s.taskRepo.NextID(ctx, template.IDPrefix)
+	if err != nil {
+		return "", err
+	}
+
+	vars := make(map[string]string, len(req.Variables)+5)
+	for k, v := range req.Variables {
+		vars[k] = v
+	}
+	vars["id"] = taskID
+	vars["title"] = strings.TrimSpace(req.Title)
+	vars["assignee"] = req.Assignee
+	vars["today"] = time.Now().Format("2006-01-02")
+	vars["project"] = projectID
+
+	return RenderTemplate(template.Markdown, vars)
+}
+
+// RenderTemplate replaces {{name}} placeholders with the given values.
+// Values are escaped for where they appear: in the frontmatter they become a single scalar,
+// and in headings they stay on the heading line. It fails if the template uses a
+// placeholder without a value.
+func RenderTemplate(markdown string, vars map[string]string) (string, error) {
+	missing := make(map[string]bool)
+	dialect, metaStart, metaEnd, hasMeta := parser.FrontmatterBounds(markdown)
+
+	var sb strings.Builder
+	last := 0
+	for _, loc := range placeholderPattern.FindAllStringSubmatchIndex(markdown, -1) {
+		sb.WriteString(markdown[last:loc[0]])
+		last = loc[1]
+
+		name := markdown[loc[2]:loc[3]]
+		value, ok := vars[name]
+		if !ok {
+			missing[name] = true
+			sb.WriteString(markdown[loc[0]:loc[1]])
+			continue
+		}
+
+		switch {
+		case hasMeta && loc[0] >= metaStart && loc[1] <= metaEnd:
+			escaped, err := metadataValue(dialect, value, markdown[:loc[0]], markdown[loc[1]:])
+			if err != nil {
+				return "", fmt.Errorf("template variable %s: %w", name, err)
+			}
+			value = escaped
+		case isHeadingLine(markdown[:loc[0]]):
+			value = singleLine(value)
+		}
+		sb.WriteString(value)
+	}
+	sb.WriteString(markdown[last:])
+	rendered := sb.String()
+
+	if len(missing) > 0 {
+		names := make([]string, 0, len(missing))
+		for name := range missing {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+		return "", fmt.Errorf("missing template variables: %s", strings.Join(names, ", "))
+	}
+
+	return rendered, nil
+}
+
+// metadataValue escapes a value for the frontmatter, so that it stays one scalar.
+// before and after are the template text around the placeholder, used to tell whether the
+// template already quotes it.
+func metadataValue(dialect parser.FrontmatterDialect, value, before, after string) (string, error) {
+	switch {
+	case strings.HasSuffix(before, `"`) && strings.HasPrefix(after, `"`):
+		return escapeDoubleQuoted(value), nil
+	case strings.HasSuffix(before, "'") && strings.HasPrefix(after, "'"):
+		if dialect == parser.DialectTOML {
+			if strings.ContainsAny(value, "'\r\n") {
+				return "", fmt.Errorf("value cannot be written in a TOML literal string")
+			}
+			return value, nil
+		}
+		return strings.ReplaceAll(singleLine(value), "'", "''"), nil
+	case value == "" || plainScalarPattern.MatchString(value):
+		return value, nil
+	default:
+		return `"` + escapeDoubleQuoted(value) + `"`, nil
+	}
+}
+
+// escapeDoubleQuoted escapes a value for a double-quoted YAML or TOML string
+func escapeDoubleQuoted(value string) string {
+	var sb strings.Builder
+	for _, r := range value {
+		switch r {
+		case '\\':
+			sb.WriteString(`\\`)
+		case '"':
+			sb.WriteString(`\"`)
+		case '\n':
+			sb.WriteString(`\n`)
+		case '\r':
+			sb.WriteString(`\r`)
+		case '\t':
+			sb.WriteString(`\t`)
+		default:
+			if r < 0x20 || r == 0x7f {
+				sb.WriteString(fmt.Sprintf(`\u%04x`, r))
+			} else {
+				sb.WriteRune(r)
+			}
+		}
+	}
+	return sb.String()
+}
+
+// isHeadingLine reports whether the line that text ends on is a Markdown heading
+func isHeadingLine(text string) bool {
+	line := text[strings.LastIndex(text, "\n")+1:]
+	return strings.HasPrefix(strings.TrimLeft(line, " "), "#")
+}
+
+// singleLine replaces line breaks with spaces
+func singleLine(value string) string {
+	return strings.NewReplacer("\r\n", " ", "\r", " ", "\n", " ").Replace(value)
+}
+
+// validateTemplate validates the fields of a template
+func validateTemplate(name, markdown, idPrefix string) error {
+	if name == "" {
+		return fmt.Errorf("template name is required")
+	}
+
+	if strings.TrimSpace(markdown) == "" {
+		return fmt.Errorf("template markdown is required")
+	}
+
+	if idPrefix != "" && !idPrefixPattern.MatchString(idPrefix) {
+		return fmt.Errorf("invalid id_prefix: %s (must be uppercase letters)", idPrefix)
+	}
+
+	return nil
+}
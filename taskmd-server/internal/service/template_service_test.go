@@ -0,0 +1,159 @@
+package service
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/tktomaru/taskai/taskai-server/internal/parser"
+)
+
+func TestRenderTemplate(t *testing.T) {
+	tests := []struct {
+		name     string
+		markdown string
+		vars     map[string]string
+		want     string
+		wantErr  bool
+	}{
+		{
+			name:     "builtin variables",
+			markdown: "## {{id}}: {{title}}\nassignees: [{{assignee}}]",
+			vars:     map[string]string{"id": "T-1", "title": "Fix login", "assignee": "taku"},
+			want:     "## T-1: Fix login\nassignees: [taku]",
+		},
+		{
+			name:     "whitespace inside braces",
+			markdown: "start_date: {{ today }}",
+			vars:     map[string]string{"today": "2026-01-05"},
+			want:     "start_date: 2026-01-05",
+		},
+		{
+			name:     "empty value",
+			markdown: "assignees: [{{assignee}}]",
+			vars:     map[string]string{"assignee": ""},
+			want:     "assignees: []",
+		},
+		{
+			name:     "missing variable",
+			markdown: "component: {{component}}",
+			vars:     map[string]string{},
+			wantErr:  true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := RenderTemplate(tt.markdown, tt.vars)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("RenderTemplate() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if !tt.wantErr && got != tt.want {
+				t.Errorf("RenderTemplate() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestRenderTemplate_ParsesAsTask(t *testing.T) {
+	markdown := "## {{id}}: {{title}}\n\n" +
+		"```yaml\n" +
+		"id: {{id}}\n" +
+		"status: open\n" +
+		"priority: P1\n" +
+		"assignees: [{{assignee}}]\n" +
+		"labels: [bug]\n" +
+		"start_date: {{today}}\n" +
+		"```\n\n" +
+		"### Summary\n"
+
+	rendered, err := RenderTemplate(markdown, map[string]string{
+		"id":       "T-42",
+		"title":    "Crash on save",
+		"assignee": "",
+		"today":    "2026-01-05",
+	})
+	if err != nil {
+		t.Fatalf("RenderTemplate() error: %v", err)
+	}
+
+	parsed, err := parser.NewMarkdownParser().Parse(rendered)
+	if err != nil {
+		t.Fatalf("Parse() error: %v", err)
+	}
+
+	if parsed.Metadata.ID != "T-42" || parsed.Title != "Crash on save" {
+		t.Errorf("Parse() = %s %q, want T-42 %q", parsed.Metadata.ID, parsed.Title, "Crash on save")
+	}
+	if len(parsed.Metadata.Assignees) != 0 {
+		t.Errorf("Parse() assignees = %v, want empty", parsed.Metadata.Assignees)
+	}
+}
+
+func TestRenderTemplate_EscapesValues(t *testing.T) {
+	templates := map[string]string{
+		"fenced": "## {{id}}: {{title}}\n\n" +
+			"```yaml\n" +
+			"id: {{id}}\n" +
+			"status: open\n" +
+			"priority: P1\n" +
+			"assignees: [{{assignee}}]\n" +
+			"labels: [{{component}}]\n" +
+			"extra_meta: {note: \"{{note}}\", quote: '{{note}}'}\n" +
+			"```\n",
+		"yaml": "---\n" +
+			"id: {{id}}\n" +
+			"title: {{title}}\n" +
+			"status: open\n" +
+			"priority: P1\n" +
+			"assignees: [{{assignee}}]\n" +
+			"labels: [{{component}}]\n" +
+			"extra_meta: {note: {{note}}}\n" +
+			"---\n",
+		"toml": "+++\n" +
+			"id = \"{{id}}\"\n" +
+			"title = \"{{title}}\"\n" +
+			"status = \"open\"\n" +
+			"priority = \"P1\"\n" +
+			"assignees = [\"{{assignee}}\"]\n" +
+			"labels = [\"{{component}}\"]\n" +
+			"\n[extra_meta]\nnote = \"{{note}}\"\n" +
+			"+++\n",
+	}
+	vars := map[string]string{
+		"id":        "T-7",
+		"title":     "Crash: on save\n```yaml\nid: T-8\n```",
+		"assignee":  "alice, bob",
+		"component": "api]\nstatus: done",
+		"note":      `it's a "quoted" \ value`,
+	}
+
+	for name, markdown := range templates {
+		t.Run(name, func(t *testing.T) {
+			rendered, err := RenderTemplate(markdown, vars)
+			if err != nil {
+				t.Fatalf("RenderTemplate() error: %v", err)
+			}
+
+			parsed, err := parser.NewMarkdownParser().Parse(rendered)
+			if err != nil {
+				t.Fatalf("Parse() error: %v\n%s", err, rendered)
+			}
+
+			if parsed.Metadata.ID != "T-7" || parsed.Metadata.Status != "open" {
+				t.Errorf("Parse() = %s %s, want T-7 open\n%s", parsed.Metadata.ID, parsed.Metadata.Status, rendered)
+			}
+			if len(parsed.Metadata.Assignees) != 1 || parsed.Metadata.Assignees[0] != "alice, bob" {
+				t.Errorf("assignees = %q, want [alice, bob]", parsed.Metadata.Assignees)
+			}
+			if len(parsed.Metadata.Labels) != 1 || parsed.Metadata.Labels[0] != vars["component"] {
+				t.Errorf("labels = %q, want [%q]", parsed.Metadata.Labels, vars["component"])
+			}
+			if note := parsed.Metadata.ExtraMeta["note"]; note != vars["note"] {
+				t.Errorf("extra_meta.note = %q, want %q", note, vars["note"])
+			}
+			if !strings.HasPrefix(parsed.Title, "Crash: on save") {
+				t.Errorf("title = %q", parsed.Title)
+			}
+		})
+	}
+}
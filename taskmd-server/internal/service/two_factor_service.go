@@ -0,0 +1,260 @@
+package service
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/base32"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/tktomaru/taskai/taskai-server/internal/auth"
+	"github.com/tktomaru/taskai/taskai-server/internal/models"
+	"github.com/tktomaru/taskai/taskai-server/internal/repository"
+	"github.com/tktomaru/taskai/taskai-server/internal/totp"
+)
+
+// Two-factor authentication errors
+var (
+	ErrInvalidTwoFactorCode    = errors.New("invalid two-factor authentication code")
+	ErrTwoFactorNotEnabled     = errors.New("two-factor authentication is not enabled")
+	ErrTwoFactorAlreadyEnabled = errors.New("two-factor authentication is already enabled")
+	ErrTwoFactorNotEnrolled    = errors.New("two-factor authentication enrollment has not been started")
+)
+
+// RequireTwoFactorSetting is the project setting that requires every member to use
+// two-factor authentication
+const RequireTwoFactorSetting = "require_two_factor"
+
+// recoveryCodeCount is the number of recovery codes issued at a time
+const recoveryCodeCount = 10
+
+// recoveryCodeEncoding writes recovery codes in lower-case base32, which avoids
+// look-alike characters such as 0/O and 1/l
+var recoveryCodeEncoding = base32.NewEncoding("abcdefghijklmnopqrstuvwxyz234567").WithPadding(base32.NoPadding)
+
+// ProjectRequiresTwoFactor reports whether a project requires its members to use two-factor authentication
+func ProjectRequiresTwoFactor(project *models.Project) bool {
+	required, _ := project.Settings[RequireTwoFactorSetting].(bool)
+	return required
+}
+
+// TwoFactorService handles TOTP enrollment and the second step of logins
+type TwoFactorService struct {
+	userRepo *repository.UserRepository
+	repo     *repository.TwoFactorRepository
+
+	// issuer names the service in authenticator apps
+	issuer string
+}
+
+// NewTwoFactorService creates a new two-factor service
+func NewTwoFactorService(userRepo *repository.UserRepository, repo *repository.TwoFactorRepository, issuer string) *TwoFactorService {
+	return &TwoFactorService{
+		userRepo: userRepo,
+		repo:     repo,
+		issuer:   issuer,
+	}
+}
+
+// TwoFactorEnrollment is a TOTP secret waiting to be confirmed with a first code
+type TwoFactorEnrollment struct {
+	Secret string `json:"secret"`
+	URI    string `json:"otpauth_uri"`
+}
+
+// TwoFactorStatus describes the two-factor authentication of a user
+type TwoFactorStatus struct {
+	Enabled                bool       `json:"enabled"`
+	EnabledAt              *time.Time `json:"enabled_at,omitempty"`
+	RecoveryCodesRemaining int        `json:"recovery_codes_remaining"`
+}
+
+// Status returns whether a user has two-factor authentication enabled
+func (s *TwoFactorService) Status(ctx context.Context, userID string) (*TwoFactorStatus, error) {
+	user, err := s.userRepo.GetByID(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	status := &TwoFactorStatus{
+		Enabled:   user.TOTPEnabledAt != nil,
+		EnabledAt: user.TOTPEnabledAt,
+	}
+	if status.Enabled {
+		if status.RecoveryCodesRemaining, err = s.repo.CountRecoveryCodes(ctx, userID); err != nil {
+			return nil, err
+		}
+	}
+
+	return status, nil
+}
+
+// Enroll starts enrollment by generating a new TOTP secret. The secret only takes effect
+// once Confirm has checked a code of it; starting over replaces a pending secret.
+func (s *TwoFactorService) Enroll(ctx context.Context, userID string) (*TwoFactorEnrollment, error) {
+	user, err := s.userRepo.GetByID(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	if user.TOTPEnabledAt != nil {
+		return nil, ErrTwoFactorAlreadyEnabled
+	}
+
+	secret, err := totp.GenerateSecret()
+	if err != nil {
+		return nil, err
+	}
+
+	if err := s.repo.SetPendingSecret(ctx, user.ID, secret); err != nil {
+		if errors.Is(err, repository.ErrTwoFactorState) {
+			return nil, ErrTwoFactorAlreadyEnabled
+		}
+		return nil, err
+	}
+
+	return &TwoFactorEnrollment{
+		Secret: secret,
+		URI:    totp.URI(s.issuer, user.Email, secret),
+	}, nil
+}
+
+// Confirm enables two-factor authentication with a code of the pending secret and returns
+// the recovery codes. They are only stored hashed, so this is the only time they are shown.
+func (s *TwoFactorService) Confirm(ctx context.Context, userID, code string) ([]string, error) {
+	user, err := s.userRepo.GetByID(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	if user.TOTPEnabledAt != nil {
+		return nil, ErrTwoFactorAlreadyEnabled
+	}
+	if user.TOTPSecret == nil {
+		return nil, ErrTwoFactorNotEnrolled
+	}
+
+	step, ok := totp.Validate(*user.TOTPSecret, code, time.Now())
+	if !ok {
+		return nil, ErrInvalidTwoFactorCode
+	}
+
+	codes, hashes, err := generateRecoveryCodes()
+	if err != nil {
+		return nil, err
+	}
+
+	if err := s.repo.Enable(ctx, user.ID, step, hashes); err != nil {
+		if errors.Is(err, repository.ErrTwoFactorState) {
+			return nil, ErrTwoFactorAlreadyEnabled
+		}
+		return nil, err
+	}
+
+	return codes, nil
+}
+
+// Verify checks the second factor of a user with enabled two-factor authentication:
+// either a TOTP code, which is accepted once, or an unused recovery code, which is used up
+func (s *TwoFactorService) Verify(ctx context.Context, user *models.User, code, recoveryCode string) error {
+	if user.TOTPEnabledAt == nil || user.TOTPSecret == nil {
+		return ErrTwoFactorNotEnabled
+	}
+
+	if code != "" {
+		step, ok := totp.Validate(*user.TOTPSecret, code, time.Now())
+		if !ok {
+			return ErrInvalidTwoFactorCode
+		}
+
+		fresh, err := s.repo.UseStep(ctx, user.ID, step)
+		if err != nil {
+			return err
+		}
+		if !fresh {
+			return ErrInvalidTwoFactorCode
+		}
+
+		return nil
+	}
+
+	if recoveryCode != "" {
+		used, err := s.repo.UseRecoveryCode(ctx, user.ID, hashRecoveryCode(recoveryCode))
+		if err != nil {
+			return err
+		}
+		if !used {
+			return ErrInvalidTwoFactorCode
+		}
+
+		return nil
+	}
+
+	return ErrInvalidTwoFactorCode
+}
+
+// Disable turns off two-factor authentication after checking a code or recovery code
+func (s *TwoFactorService) Disable(ctx context.Context, userID, code, recoveryCode string) error {
+	user, err := s.userRepo.GetByID(ctx, userID)
+	if err != nil {
+		return err
+	}
+
+	if err := s.Verify(ctx, user, code, recoveryCode); err != nil {
+		return err
+	}
+
+	return s.repo.Disable(ctx, user.ID)
+}
+
+// RegenerateRecoveryCodes replaces the recovery codes of a user after checking a TOTP code
+func (s *TwoFactorService) RegenerateRecoveryCodes(ctx context.Context, userID, code string) ([]string, error) {
+	user, err := s.userRepo.GetByID(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := s.Verify(ctx, user, code, ""); err != nil {
+		return nil, err
+	}
+
+	codes, hashes, err := generateRecoveryCodes()
+	if err != nil {
+		return nil, err
+	}
+
+	if err := s.repo.ReplaceRecoveryCodes(ctx, user.ID, hashes); err != nil {
+		if errors.Is(err, repository.ErrTwoFactorState) {
+			return nil, ErrTwoFactorNotEnabled
+		}
+		return nil, err
+	}
+
+	return codes, nil
+}
+
+// generateRecoveryCodes generates a set of recovery codes and their hashes. Each code
+// carries 80 random bits and is written as four groups of four characters.
+func generateRecoveryCodes() ([]string, []string, error) {
+	codes := make([]string, recoveryCodeCount)
+	hashes := make([]string, recoveryCodeCount)
+
+	for i := range codes {
+		b := make([]byte, 10)
+		if _, err := rand.Read(b); err != nil {
+			return nil, nil, fmt.Errorf("failed to generate recovery code: %w", err)
+		}
+
+		raw := recoveryCodeEncoding.EncodeToString(b)
+		codes[i] = raw[0:4] + "-" + raw[4:8] + "-" + raw[8:12] + "-" + raw[12:16]
+		hashes[i] = hashRecoveryCode(codes[i])
+	}
+
+	return codes, hashes, nil
+}
+
+// hashRecoveryCode hashes a recovery code for storage, ignoring case, dashes and spaces
+func hashRecoveryCode(code string) string {
+	code = strings.ToLower(strings.NewReplacer("-", "", " ", "").Replace(code))
+	return auth.HashToken(code)
+}
@@ -0,0 +1,76 @@
+package service
+
+import (
+	"regexp"
+	"strings"
+	"testing"
+
+	"github.com/tktomaru/taskai/taskai-server/internal/models"
+)
+
+func TestGenerateRecoveryCodes(t *testing.T) {
+	codes, hashes, err := generateRecoveryCodes()
+	if err != nil {
+		t.Fatalf("generateRecoveryCodes() error: %v", err)
+	}
+
+	if len(codes) != recoveryCodeCount || len(hashes) != recoveryCodeCount {
+		t.Fatalf("generateRecoveryCodes() returned %d codes and %d hashes, want %d", len(codes), len(hashes), recoveryCodeCount)
+	}
+
+	format := regexp.MustCompile(`^[a-z2-7]{4}-[a-z2-7]{4}-[a-z2-7]{4}-[a-z2-7]{4}$`)
+	seen := make(map[string]bool)
+	for i, code := range codes {
+		if !format.MatchString(code) {
+			t.Errorf("recovery code %q does not match xxxx-xxxx-xxxx-xxxx", code)
+		}
+		if seen[code] {
+			t.Errorf("recovery code %q generated twice", code)
+		}
+		seen[code] = true
+
+		if hashes[i] != hashRecoveryCode(code) {
+			t.Errorf("hash of code %d does not match its code", i)
+		}
+		if strings.Contains(hashes[i], code) {
+			t.Errorf("hash of code %d contains the code", i)
+		}
+	}
+}
+
+func TestHashRecoveryCode_Normalizes(t *testing.T) {
+	want := hashRecoveryCode("abcd-efgh-ijkl-mnop")
+
+	for _, input := range []string{"ABCD-EFGH-IJKL-MNOP", "abcdefghijklmnop", "abcd efgh ijkl mnop"} {
+		if got := hashRecoveryCode(input); got != want {
+			t.Errorf("hashRecoveryCode(%q) differs from the canonical form", input)
+		}
+	}
+
+	if hashRecoveryCode("abcd-efgh-ijkl-mnoq") == want {
+		t.Error("hashRecoveryCode() returned the same hash for different codes")
+	}
+}
+
+func TestProjectRequiresTwoFactor(t *testing.T) {
+	tests := []struct {
+		name     string
+		settings models.JSONB
+		want     bool
+	}{
+		{"no settings", nil, false},
+		{"not set", models.JSONB{"calendar": map[string]interface{}{}}, false},
+		{"required", models.JSONB{RequireTwoFactorSetting: true}, true},
+		{"not required", models.JSONB{RequireTwoFactorSetting: false}, false},
+		{"wrong type", models.JSONB{RequireTwoFactorSetting: "yes"}, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			project := &models.Project{Settings: tt.settings}
+			if got := ProjectRequiresTwoFactor(project); got != tt.want {
+				t.Errorf("ProjectRequiresTwoFactor() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
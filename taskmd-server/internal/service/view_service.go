@@ -5,6 +5,7 @@ import (
 	"fmt"
 	"time"
 
+	"github.com/tktomaru/taskai/taskai-server/internal/calendar"
 	"github.com/tktomaru/taskai/taskai-server/internal/models"
 	"github.com/tktomaru/taskai/taskai-server/internal/query"
 	"github.com/tktomaru/taskai/taskai-server/internal/repository"
@@ -28,6 +29,11 @@ func NewViewService(repo *repository.ViewRepository, debug bool) *ViewService {
 	}
 }
 
+// SetCalendar sets the project calendar used to resolve business-day expressions
+func (s *ViewService) SetCalendar(cal *calendar.Calendar) {
+	s.parser.SetCalendar(cal)
+}
+
 // CreateViewRequest represents a request to create a view
 type CreateViewRequest struct {
 	ID          string           `json:"id"`
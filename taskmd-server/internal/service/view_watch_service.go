@@ -0,0 +1,299 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"sync"
+
+	"github.com/tktomaru/taskai/taskai-server/internal/calendar"
+	"github.com/tktomaru/taskai/taskai-server/internal/models"
+	"github.com/tktomaru/taskai/taskai-server/internal/query"
+	"github.com/tktomaru/taskai/taskai-server/internal/repository"
+)
+
+// ViewWatchService alerts the watchers of saved views when tasks enter or leave their result sets
+type ViewWatchService struct {
+	viewRepo         *repository.ViewRepository
+	subscriptionRepo *repository.SubscriptionRepository
+	notifications    *NotificationService
+
+	// CalendarFor returns the calendar used to resolve business-day expressions in the views of a project
+	CalendarFor func(ctx context.Context, projectID string) *calendar.Calendar
+}
+
+// NewViewWatchService creates a new view watch service
+func NewViewWatchService(viewRepo *repository.ViewRepository, subscriptionRepo *repository.SubscriptionRepository, notifications *NotificationService) *ViewWatchService {
+	return &ViewWatchService{
+		viewRepo:         viewRepo,
+		subscriptionRepo: subscriptionRepo,
+		notifications:    notifications,
+	}
+}
+
+// Snapshot stores the current result set of a view without sending alerts
+func (s *ViewWatchService) Snapshot(ctx context.Context, view *models.SavedView) error {
+	taskIDs, err := s.Evaluate(ctx, view)
+	if err != nil {
+		return err
+	}
+
+	return s.subscriptionRepo.SaveViewResults(ctx, view.ID, taskIDs)
+}
+
+// Refresh re-evaluates the watched views of a project and alerts their watchers about
+// tasks that entered or left a view since the last check. The actor is not alerted.
+func (s *ViewWatchService) Refresh(ctx context.Context, projectID, actorID string) error {
+	subs, err := s.subscriptionRepo.ListViewWatchers(ctx, projectID)
+	if err != nil {
+		return err
+	}
+
+	// Subscriptions are ordered by view
+	var viewIDs []string
+	watchers := make(map[string][]*models.Subscription)
+	for _, sub := range subs {
+		if _, ok := watchers[sub.TargetID]; !ok {
+			viewIDs = append(viewIDs, sub.TargetID)
+		}
+		watchers[sub.TargetID] = append(watchers[sub.TargetID], sub)
+	}
+
+	for _, viewID := range viewIDs {
+		if err := s.refreshView(ctx, projectID, viewID, watchers[viewID], actorID); err != nil {
+			log.Printf("WARNING: Failed to refresh watched view %s: %v", viewID, err)
+		}
+	}
+
+	return nil
+}
+
+// RefreshAll re-evaluates the watched views of every project
+func (s *ViewWatchService) RefreshAll(ctx context.Context) error {
+	projectIDs, err := s.subscriptionRepo.ListProjectsWithWatchedViews(ctx)
+	if err != nil {
+		return err
+	}
+
+	for _, projectID := range projectIDs {
+		if err := s.Refresh(ctx, projectID, ""); err != nil {
+			log.Printf("WARNING: Failed to refresh watched views of project %s: %v", projectID, err)
+		}
+	}
+
+	return nil
+}
+
+// refreshView compares the result set of a view with its last snapshot
+func (s *ViewWatchService) refreshView(ctx context.Context, projectID, viewID string, subs []*models.Subscription, actorID string) error {
+	view, err := s.viewRepo.GetByID(ctx, projectID, viewID)
+	if err != nil {
+		return err
+	}
+
+	current, err := s.Evaluate(ctx, view)
+	if err != nil {
+		return err
+	}
+
+	previous, found, err := s.subscriptionRepo.GetViewResults(ctx, viewID)
+	if err != nil {
+		return err
+	}
+
+	// The first check only records the result set
+	if !found {
+		return s.subscriptionRepo.SaveViewResults(ctx, viewID, current)
+	}
+
+	entered, left := diffTaskIDs(previous, current)
+	if len(entered) == 0 && len(left) == 0 {
+		return nil
+	}
+
+	if err := s.subscriptionRepo.SaveViewResults(ctx, viewID, current); err != nil {
+		return err
+	}
+
+	watchers := uniqueWatchers(subs)
+	notifications := planViewNotifications(view, models.NotificationTypeViewEntered, entered, watchers, actorID)
+	notifications = append(notifications, planViewNotifications(view, models.NotificationTypeViewLeft, left, watchers, actorID)...)
+
+	return s.notifications.deliver(ctx, notifications)
+}
+
+// Evaluate runs the query of a view and returns the IDs of the matching tasks
+func (s *ViewWatchService) Evaluate(ctx context.Context, view *models.SavedView) ([]string, error) {
+	parser := query.NewQueryParser()
+	if s.CalendarFor != nil {
+		parser.SetCalendar(s.CalendarFor(ctx, view.ProjectID))
+	}
+
+	parsed, err := parser.Parse(view.RawQuery)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse query: %w", err)
+	}
+
+	// The whole result set is compared, not the first page shown in the view
+	parsed.Limit = 0
+
+	buildResult, err := query.NewSQLBuilder().Build(view.ProjectID, parsed)
+	if err != nil {
+		return nil, fmt.Errorf("failed to build SQL: %w", err)
+	}
+
+	tasks, err := s.viewRepo.ExecuteQuery(ctx, buildResult.SQL, buildResult.Args)
+	if err != nil {
+		return nil, fmt.Errorf("failed to execute query: %w", err)
+	}
+
+	taskIDs := make([]string, 0, len(tasks))
+	for _, task := range tasks {
+		taskIDs = append(taskIDs, task.ID)
+	}
+
+	return taskIDs, nil
+}
+
+// ViewRefreshQueue re-evaluates the watched views of projects in the background, one
+// project at a time, so that saving a task does not wait for them. A project queued
+// again before its refresh started is refreshed once.
+type ViewRefreshQueue struct {
+	refresh func(ctx context.Context, projectID, actorID string) error
+
+	mu      sync.Mutex
+	pending map[string]string
+	order   []string
+	wake    chan struct{}
+}
+
+// NewViewRefreshQueue creates a queue that refreshes the views of a project with refresh
+func NewViewRefreshQueue(refresh func(ctx context.Context, projectID, actorID string) error) *ViewRefreshQueue {
+	return &ViewRefreshQueue{
+		refresh: refresh,
+		pending: make(map[string]string),
+		wake:    make(chan struct{}, 1),
+	}
+}
+
+// Enqueue schedules a refresh of the watched views of a project after the actor changed a task
+func (q *ViewRefreshQueue) Enqueue(projectID, actorID string) {
+	q.mu.Lock()
+	if previous, ok := q.pending[projectID]; !ok {
+		q.pending[projectID] = actorID
+		q.order = append(q.order, projectID)
+	} else if previous != actorID {
+		// Changes of several users are merged, so none of them is left out of the alerts
+		q.pending[projectID] = ""
+	}
+	q.mu.Unlock()
+
+	select {
+	case q.wake <- struct{}{}:
+	default:
+	}
+}
+
+// next removes the oldest queued project
+func (q *ViewRefreshQueue) next() (string, string, bool) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	if len(q.order) == 0 {
+		return "", "", false
+	}
+
+	projectID := q.order[0]
+	q.order = q.order[1:]
+	actorID := q.pending[projectID]
+	delete(q.pending, projectID)
+
+	return projectID, actorID, true
+}
+
+// Run refreshes queued projects until the context is cancelled
+func (q *ViewRefreshQueue) Run(ctx context.Context) {
+	for {
+		for ctx.Err() == nil {
+			projectID, actorID, ok := q.next()
+			if !ok {
+				break
+			}
+			if err := q.refresh(ctx, projectID, actorID); err != nil {
+				log.Printf("WARNING: Failed to refresh watched views of project %s: %v", projectID, err)
+			}
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-q.wake:
+		}
+	}
+}
+
+// diffTaskIDs returns the task IDs that were added to and removed from a result set, in result order
+func diffTaskIDs(previous, current []string) (entered, left []string) {
+	before := make(map[string]bool, len(previous))
+	for _, id := range previous {
+		before[id] = true
+	}
+	after := make(map[string]bool, len(current))
+	for _, id := range current {
+		after[id] = true
+	}
+
+	for _, id := range current {
+		if !before[id] {
+			entered = append(entered, id)
+		}
+	}
+	for _, id := range previous {
+		if !after[id] {
+			left = append(left, id)
+		}
+	}
+
+	return entered, left
+}
+
+// planViewNotifications creates one alert per watcher for the tasks that entered or left a view
+func planViewNotifications(view *models.SavedView, notificationType models.NotificationType, taskIDs []string, watchers []watcher, actorID string) []*models.Notification {
+	if len(taskIDs) == 0 {
+		return nil
+	}
+
+	verb := "entered"
+	if notificationType == models.NotificationTypeViewLeft {
+		verb = "left"
+	}
+
+	subject := fmt.Sprintf("%d tasks", len(taskIDs))
+	if len(taskIDs) == 1 {
+		subject = taskIDs[0]
+	}
+	title := fmt.Sprintf("%s %s view %q", subject, verb, view.Name)
+
+	plan := newNotificationPlan(actorID)
+	for _, w := range watchers {
+		projectID := view.ProjectID
+		notification := &models.Notification{
+			UserID:    w.userID,
+			Type:      notificationType,
+			ProjectID: &projectID,
+			Title:     title,
+			Detail: models.JSONB{
+				"view_id":   view.ID,
+				"view_name": view.Name,
+				"task_ids":  taskIDs,
+			},
+		}
+		if len(taskIDs) == 1 {
+			taskID := taskIDs[0]
+			notification.TaskID = &taskID
+		}
+		plan.addForWatcher(w, notification)
+	}
+
+	return plan.notifications
+}
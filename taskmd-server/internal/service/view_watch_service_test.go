@@ -0,0 +1,121 @@
+package service
+
+import (
+	"context"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/tktomaru/taskai/taskai-server/internal/models"
+)
+
+func TestDiffTaskIDs(t *testing.T) {
+	tests := []struct {
+		name        string
+		previous    []string
+		current     []string
+		wantEntered []string
+		wantLeft    []string
+	}{
+		{
+			name:        "tasks entering and leaving",
+			previous:    []string{"T-1", "T-2", "T-3"},
+			current:     []string{"T-4", "T-2", "T-1"},
+			wantEntered: []string{"T-4"},
+			wantLeft:    []string{"T-3"},
+		},
+		{
+			name:     "reordering is not a change",
+			previous: []string{"T-1", "T-2"},
+			current:  []string{"T-2", "T-1"},
+		},
+		{
+			name:        "empty previous result",
+			previous:    []string{},
+			current:     []string{"T-1"},
+			wantEntered: []string{"T-1"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			entered, left := diffTaskIDs(tt.previous, tt.current)
+			if !reflect.DeepEqual(entered, tt.wantEntered) || !reflect.DeepEqual(left, tt.wantLeft) {
+				t.Errorf("diffTaskIDs() = %v, %v, want %v, %v", entered, left, tt.wantEntered, tt.wantLeft)
+			}
+		})
+	}
+}
+
+func TestPlanViewNotifications(t *testing.T) {
+	view := &models.SavedView{ID: "overdue", ProjectID: "p1", Name: "Overdue"}
+	watchers := []watcher{{userID: "u-alice"}, {userID: "u-bob", digest: true}}
+
+	single := planViewNotifications(view, models.NotificationTypeViewEntered, []string{"T-1"}, watchers, "u-alice")
+	if len(single) != 1 || single[0].UserID != "u-bob" {
+		t.Fatalf("planViewNotifications() = %v, want one alert for u-bob", single)
+	}
+	if single[0].Title != `T-1 entered view "Overdue"` || single[0].TaskID == nil || *single[0].TaskID != "T-1" {
+		t.Errorf("planViewNotifications() = %q for task %v", single[0].Title, single[0].TaskID)
+	}
+	if !single[0].DigestPending {
+		t.Errorf("planViewNotifications() did not mark the alert for the digest")
+	}
+
+	several := planViewNotifications(view, models.NotificationTypeViewLeft, []string{"T-1", "T-2"}, watchers, "")
+	if len(several) != 2 || several[0].Title != `2 tasks left view "Overdue"` || several[0].TaskID != nil {
+		t.Errorf("planViewNotifications() = %+v", several)
+	}
+
+	if none := planViewNotifications(view, models.NotificationTypeViewLeft, nil, watchers, ""); none != nil {
+		t.Errorf("planViewNotifications() without tasks = %v, want nil", none)
+	}
+}
+
+func TestViewRefreshQueue(t *testing.T) {
+	type call struct{ projectID, actorID string }
+	calls := make(chan call, 10)
+	queue := NewViewRefreshQueue(func(ctx context.Context, projectID, actorID string) error {
+		calls <- call{projectID, actorID}
+		return nil
+	})
+
+	// Requests queued before the worker runs are merged per project
+	queue.Enqueue("proj-a", "taku")
+	queue.Enqueue("proj-b", "hana")
+	queue.Enqueue("proj-a", "taku")
+	queue.Enqueue("proj-b", "ken")
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go queue.Run(ctx)
+
+	want := []call{{"proj-a", "taku"}, {"proj-b", ""}}
+	for _, w := range want {
+		select {
+		case got := <-calls:
+			if got != w {
+				t.Errorf("refresh(%v), want %v", got, w)
+			}
+		case <-time.After(time.Second):
+			t.Fatalf("refresh of %v did not run", w)
+		}
+	}
+
+	// Later requests wake the worker up
+	queue.Enqueue("proj-c", "taku")
+	select {
+	case got := <-calls:
+		if got != (call{"proj-c", "taku"}) {
+			t.Errorf("refresh(%v), want proj-c by taku", got)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("refresh of proj-c did not run")
+	}
+
+	select {
+	case got := <-calls:
+		t.Errorf("unexpected refresh(%v)", got)
+	case <-time.After(20 * time.Millisecond):
+	}
+}
@@ -0,0 +1,381 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"math/rand"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/tktomaru/taskai/taskai-server/internal/models"
+	"github.com/tktomaru/taskai/taskai-server/internal/repository"
+	"github.com/tktomaru/taskai/taskai-server/internal/webhook"
+	"github.com/tktomaru/taskai/taskai-server/internal/websocket"
+)
+
+// webhookLease is how long a claimed delivery is hidden from other workers.
+// It must be longer than the request timeout.
+const webhookLease = 5 * time.Minute
+
+// generateWebhookID generates a unique webhook ID
+func generateWebhookID() string {
+	const charset = "abcdefghijklmnopqrstuvwxyz0123456789"
+	timestamp := time.Now().Unix()
+
+	b := make([]byte, 6)
+	for i := range b {
+		b[i] = charset[rand.Intn(len(charset))]
+	}
+
+	return fmt.Sprintf("wh-%d-%s", timestamp, string(b))
+}
+
+// CreateWebhookRequest represents a request to register a webhook.
+// A secret is generated when none is given; it is only returned on creation.
+type CreateWebhookRequest struct {
+	URL         string   `json:"url" binding:"required"`
+	Events      []string `json:"events" binding:"required"`
+	Secret      string   `json:"secret,omitempty"`
+	Description string   `json:"description,omitempty"`
+	CreatedBy   string   `json:"-"`
+}
+
+// UpdateWebhookRequest represents a request to update a webhook. Omitted fields are kept.
+type UpdateWebhookRequest struct {
+	URL         *string  `json:"url,omitempty"`
+	Events      []string `json:"events,omitempty"`
+	Secret      *string  `json:"secret,omitempty"`
+	Description *string  `json:"description,omitempty"`
+	Active      *bool    `json:"active,omitempty"`
+}
+
+// WebhookService manages project webhooks and delivers queued events to them
+type WebhookService struct {
+	repo        *repository.WebhookRepository
+	sender      *webhook.Sender
+	maxAttempts int
+}
+
+// NewWebhookService creates a new webhook service. A delivery is given up after maxAttempts attempts.
+func NewWebhookService(repo *repository.WebhookRepository, sender *webhook.Sender, maxAttempts int) *WebhookService {
+	if maxAttempts < 1 {
+		maxAttempts = 1
+	}
+
+	return &WebhookService{
+		repo:        repo,
+		sender:      sender,
+		maxAttempts: maxAttempts,
+	}
+}
+
+// Create registers a webhook for a project
+func (s *WebhookService) Create(ctx context.Context, projectID string, req *CreateWebhookRequest) (*models.Webhook, error) {
+	if err := validateWebhook(req.URL, req.Events); err != nil {
+		return nil, err
+	}
+
+	secret := req.Secret
+	if secret == "" {
+		generated, err := webhook.GenerateSecret()
+		if err != nil {
+			return nil, err
+		}
+		secret = generated
+	}
+
+	wh := &models.Webhook{
+		ID:        generateWebhookID(),
+		ProjectID: projectID,
+		URL:       req.URL,
+		Events:    models.StringArray(req.Events),
+		Secret:    secret,
+		Active:    true,
+	}
+	if req.Description != "" {
+		wh.Description = &req.Description
+	}
+	if req.CreatedBy != "" {
+		wh.CreatedBy = &req.CreatedBy
+	}
+
+	if err := s.repo.Create(ctx, wh); err != nil {
+		return nil, err
+	}
+
+	return wh, nil
+}
+
+// Get retrieves a webhook without its secret
+func (s *WebhookService) Get(ctx context.Context, projectID, webhookID string) (*models.Webhook, error) {
+	wh, err := s.repo.GetByID(ctx, projectID, webhookID)
+	if err != nil {
+		return nil, err
+	}
+
+	wh.Secret = ""
+	return wh, nil
+}
+
+// List retrieves the webhooks of a project without their secrets
+func (s *WebhookService) List(ctx context.Context, projectID string) ([]*models.Webhook, error) {
+	webhooks, err := s.repo.List(ctx, projectID)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, wh := range webhooks {
+		wh.Secret = ""
+	}
+	return webhooks, nil
+}
+
+// Update updates a webhook. Pending deliveries are sent with the new URL and secret.
+func (s *WebhookService) Update(ctx context.Context, projectID, webhookID string, req *UpdateWebhookRequest) (*models.Webhook, error) {
+	wh, err := s.repo.GetByID(ctx, projectID, webhookID)
+	if err != nil {
+		return nil, err
+	}
+
+	if req.URL != nil {
+		wh.URL = *req.URL
+	}
+	if req.Events != nil {
+		wh.Events = models.StringArray(req.Events)
+	}
+	if req.Secret != nil && *req.Secret != "" {
+		wh.Secret = *req.Secret
+	}
+	if req.Description != nil {
+		wh.Description = req.Description
+	}
+	if req.Active != nil {
+		wh.Active = *req.Active
+	}
+
+	if err := validateWebhook(wh.URL, wh.Events); err != nil {
+		return nil, err
+	}
+
+	if err := s.repo.Update(ctx, wh); err != nil {
+		return nil, err
+	}
+
+	return s.Get(ctx, projectID, webhookID)
+}
+
+// Delete deletes a webhook and its delivery log
+func (s *WebhookService) Delete(ctx context.Context, projectID, webhookID string) error {
+	return s.repo.Delete(ctx, projectID, webhookID)
+}
+
+// Enqueue queues an event for every active webhook of the project that subscribes to it.
+// It returns the number of queued deliveries.
+func (s *WebhookService) Enqueue(ctx context.Context, eventType websocket.EventType, projectID, taskID string, data interface{}) (int, error) {
+	if !webhook.IsEvent(string(eventType)) {
+		return 0, nil
+	}
+
+	webhooks, err := s.repo.ListSubscribed(ctx, projectID, string(eventType))
+	if err != nil || len(webhooks) == 0 {
+		return 0, err
+	}
+
+	payload, err := webhook.NewPayload(eventType, projectID, taskID, data, time.Now())
+	if err != nil {
+		return 0, err
+	}
+
+	for i, wh := range webhooks {
+		delivery := &models.WebhookDelivery{
+			WebhookID: wh.ID,
+			EventType: string(eventType),
+			Payload:   payload,
+		}
+		if err := s.repo.Enqueue(ctx, delivery); err != nil {
+			return i, err
+		}
+	}
+
+	return len(webhooks), nil
+}
+
+// ProcessDue sends up to limit due deliveries. It returns the number of deliveries attempted.
+func (s *WebhookService) ProcessDue(ctx context.Context, limit int) (int, error) {
+	deliveries, err := s.repo.ClaimDue(ctx, limit, webhookLease)
+	if err != nil {
+		return 0, err
+	}
+
+	webhooks := make(map[string]*models.Webhook)
+	for _, delivery := range deliveries {
+		wh, ok := webhooks[delivery.WebhookID]
+		if !ok {
+			wh, err = s.repo.GetWebhook(ctx, delivery.WebhookID)
+			if err != nil {
+				// The delivery is retried once its lease expires
+				log.Printf("ERROR: Failed to get webhook %s of delivery %d: %v", delivery.WebhookID, delivery.ID, err)
+				continue
+			}
+			webhooks[delivery.WebhookID] = wh
+		}
+
+		if err := s.attempt(ctx, wh, delivery); err != nil {
+			log.Printf("ERROR: Failed to record attempt of webhook delivery %d: %v", delivery.ID, err)
+		}
+	}
+
+	return len(deliveries), nil
+}
+
+// Deliveries retrieves the delivery queue and history of a webhook, newest first
+func (s *WebhookService) Deliveries(ctx context.Context, projectID, webhookID string, limit, offset int) ([]*models.WebhookDelivery, error) {
+	if _, err := s.repo.GetByID(ctx, projectID, webhookID); err != nil {
+		return nil, err
+	}
+
+	if limit <= 0 || limit > 200 {
+		limit = 50
+	}
+	if offset < 0 {
+		offset = 0
+	}
+
+	return s.repo.ListDeliveries(ctx, webhookID, limit, offset)
+}
+
+// Delivery retrieves a delivery together with the log of its attempts
+func (s *WebhookService) Delivery(ctx context.Context, projectID, webhookID string, deliveryID int64) (*models.WebhookDelivery, error) {
+	if _, err := s.repo.GetByID(ctx, projectID, webhookID); err != nil {
+		return nil, err
+	}
+
+	delivery, err := s.repo.GetDelivery(ctx, webhookID, deliveryID)
+	if err != nil {
+		return nil, err
+	}
+
+	delivery.Log, err = s.repo.ListAttempts(ctx, deliveryID)
+	if err != nil {
+		return nil, err
+	}
+
+	return delivery, nil
+}
+
+// Redeliver queues a new delivery with the payload of an earlier one
+func (s *WebhookService) Redeliver(ctx context.Context, projectID, webhookID string, deliveryID int64) (*models.WebhookDelivery, error) {
+	if _, err := s.repo.GetByID(ctx, projectID, webhookID); err != nil {
+		return nil, err
+	}
+
+	original, err := s.repo.GetDelivery(ctx, webhookID, deliveryID)
+	if err != nil {
+		return nil, err
+	}
+
+	redelivery := &models.WebhookDelivery{
+		WebhookID:    webhookID,
+		EventType:    original.EventType,
+		Payload:      original.Payload,
+		RedeliveryOf: &original.ID,
+	}
+	if err := s.repo.Enqueue(ctx, redelivery); err != nil {
+		return nil, err
+	}
+
+	return redelivery, nil
+}
+
+// attempt sends a delivery once and records the outcome
+func (s *WebhookService) attempt(ctx context.Context, wh *models.Webhook, delivery *models.WebhookDelivery) error {
+	result := s.sender.Send(ctx, &webhook.Request{
+		URL:        wh.URL,
+		Secret:     wh.Secret,
+		Event:      delivery.EventType,
+		DeliveryID: delivery.ID,
+		Body:       delivery.Payload,
+	})
+
+	attempt := deliveryAttempt(delivery, result)
+	applyDeliveryResult(delivery, result, s.maxAttempts, time.Now())
+
+	if delivery.Status == models.WebhookDeliveryFailed {
+		log.Printf("WARNING: Giving up webhook delivery %d to %s after %d attempts: %v", delivery.ID, wh.ID, attempt.Attempt, result.Err)
+	}
+
+	return s.repo.RecordAttempt(ctx, delivery, attempt)
+}
+
+// deliveryAttempt creates the delivery log entry of an attempt
+func deliveryAttempt(delivery *models.WebhookDelivery, result *webhook.Result) *models.WebhookDeliveryAttempt {
+	attempt := &models.WebhookDeliveryAttempt{
+		DeliveryID: delivery.ID,
+		Attempt:    delivery.Attempts + 1,
+		DurationMs: int(result.Duration.Milliseconds()),
+	}
+	if result.StatusCode != 0 {
+		status := result.StatusCode
+		attempt.ResponseStatus = &status
+		body := result.Body
+		attempt.ResponseBody = &body
+	}
+	if result.Err != nil {
+		message := result.Err.Error()
+		attempt.Error = &message
+	}
+
+	return attempt
+}
+
+// applyDeliveryResult moves a delivery to its next state: succeeded, retried with
+// exponential backoff, or failed once the maximum number of attempts is reached
+func applyDeliveryResult(delivery *models.WebhookDelivery, result *webhook.Result, maxAttempts int, now time.Time) {
+	attempts := delivery.Attempts + 1
+
+	switch {
+	case result.OK():
+		delivery.Status = models.WebhookDeliverySucceeded
+		delivery.NextAttemptAt = now
+		delivery.DeliveredAt = &now
+	case attempts >= maxAttempts:
+		delivery.Status = models.WebhookDeliveryFailed
+		delivery.NextAttemptAt = now
+	default:
+		delivery.Status = models.WebhookDeliveryPending
+		delivery.NextAttemptAt = now.Add(webhook.Backoff(attempts))
+	}
+}
+
+// validateWebhook checks the URL and event types of a webhook
+func validateWebhook(rawURL string, events []string) error {
+	u, err := url.Parse(rawURL)
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		return fmt.Errorf("invalid webhook URL: %q (must be an absolute http or https URL)", rawURL)
+	}
+	if err := webhook.CheckURL(rawURL); err != nil {
+		return err
+	}
+
+	if len(events) == 0 {
+		return fmt.Errorf("at least one event is required")
+	}
+
+	var unknown []string
+	for _, event := range events {
+		if !webhook.IsEvent(event) {
+			unknown = append(unknown, event)
+		}
+	}
+	if len(unknown) > 0 {
+		valid := make([]string, 0, len(webhook.Events))
+		for _, event := range webhook.Events {
+			valid = append(valid, string(event))
+		}
+		return fmt.Errorf("unknown events: %s (valid: %s)", strings.Join(unknown, ", "), strings.Join(valid, ", "))
+	}
+
+	return nil
+}
@@ -0,0 +1,96 @@
+package service
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/tktomaru/taskai/taskai-server/internal/models"
+	"github.com/tktomaru/taskai/taskai-server/internal/webhook"
+)
+
+func TestApplyDeliveryResult(t *testing.T) {
+	now := time.Date(2026, 3, 2, 10, 0, 0, 0, time.UTC)
+	failed := &webhook.Result{StatusCode: 503, Err: errors.New("receiver responded with status 503")}
+
+	tests := []struct {
+		name        string
+		attempts    int
+		result      *webhook.Result
+		wantStatus  models.WebhookDeliveryStatus
+		wantNext    time.Time
+		wantDeliver bool
+	}{
+		{"success", 0, &webhook.Result{StatusCode: 204}, models.WebhookDeliverySucceeded, now, true},
+		{"first failure is retried after the base backoff", 0, failed, models.WebhookDeliveryPending, now.Add(30 * time.Second), false},
+		{"backoff doubles", 2, failed, models.WebhookDeliveryPending, now.Add(2 * time.Minute), false},
+		{"last attempt fails the delivery", 4, failed, models.WebhookDeliveryFailed, now, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			delivery := &models.WebhookDelivery{ID: 1, Attempts: tt.attempts}
+			applyDeliveryResult(delivery, tt.result, 5, now)
+
+			if delivery.Status != tt.wantStatus {
+				t.Errorf("status = %s, want %s", delivery.Status, tt.wantStatus)
+			}
+			if !delivery.NextAttemptAt.Equal(tt.wantNext) {
+				t.Errorf("next attempt = %v, want %v", delivery.NextAttemptAt, tt.wantNext)
+			}
+			if (delivery.DeliveredAt != nil) != tt.wantDeliver {
+				t.Errorf("delivered at = %v, want delivered %v", delivery.DeliveredAt, tt.wantDeliver)
+			}
+		})
+	}
+}
+
+func TestDeliveryAttempt(t *testing.T) {
+	delivery := &models.WebhookDelivery{ID: 7, Attempts: 2}
+
+	attempt := deliveryAttempt(delivery, &webhook.Result{
+		StatusCode: 500,
+		Body:       "boom",
+		Duration:   1500 * time.Millisecond,
+		Err:        errors.New("receiver responded with status 500"),
+	})
+	if attempt.Attempt != 3 || attempt.DurationMs != 1500 {
+		t.Errorf("attempt = %d, duration = %dms, want 3, 1500ms", attempt.Attempt, attempt.DurationMs)
+	}
+	if attempt.ResponseStatus == nil || *attempt.ResponseStatus != 500 || *attempt.ResponseBody != "boom" || attempt.Error == nil {
+		t.Errorf("deliveryAttempt() = %+v", attempt)
+	}
+
+	// Connection errors have no response
+	attempt = deliveryAttempt(delivery, &webhook.Result{Err: errors.New("connection refused")})
+	if attempt.ResponseStatus != nil || attempt.ResponseBody != nil || attempt.Error == nil {
+		t.Errorf("deliveryAttempt() without response = %+v", attempt)
+	}
+}
+
+func TestValidateWebhook(t *testing.T) {
+	tests := []struct {
+		name    string
+		url     string
+		events  []string
+		wantErr bool
+	}{
+		{"valid", "https://ci.example.com/hooks/taskmd", []string{"task.created", "task.updated"}, false},
+		{"plain http", "http://hooks.example.com:9000/hook", []string{"task.deleted"}, false},
+		{"loopback", "http://localhost:9000/hook", []string{"task.deleted"}, true},
+		{"metadata address", "http://169.254.169.254/latest/meta-data/", []string{"task.created"}, true},
+		{"relative URL", "/hook", []string{"task.created"}, true},
+		{"unsupported scheme", "ftp://example.com/hook", []string{"task.created"}, true},
+		{"no events", "https://example.com/hook", nil, true},
+		{"unknown event", "https://example.com/hook", []string{"task.created", "notification.created"}, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := validateWebhook(tt.url, tt.events)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("validateWebhook() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
@@ -0,0 +1,118 @@
+package totp
+
+import (
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+)
+
+// rfcSecret is the SHA-1 key of the RFC 4226 and RFC 6238 test vectors
+var rfcSecret = encoding.EncodeToString([]byte("12345678901234567890"))
+
+func TestHOTP_RFC4226(t *testing.T) {
+	want := []string{"755224", "287082", "359152", "969429", "338314", "254676", "287922", "162583", "399871", "520489"}
+
+	for counter, code := range want {
+		got, err := Code(rfcSecret, int64(counter))
+		if err != nil {
+			t.Fatalf("Code() error: %v", err)
+		}
+		if got != code {
+			t.Errorf("Code(counter %d) = %s, want %s", counter, got, code)
+		}
+	}
+}
+
+func TestTOTP_RFC6238(t *testing.T) {
+	tests := []struct {
+		unix int64
+		want string
+	}{
+		{59, "94287082"},
+		{1111111109, "07081804"},
+		{1111111111, "14050471"},
+		{1234567890, "89005924"},
+		{2000000000, "69279037"},
+		{20000000000, "65353130"},
+	}
+
+	key, _ := decodeSecret(rfcSecret)
+	for _, tt := range tests {
+		got := hotp(key, Step(time.Unix(tt.unix, 0)), 8)
+		if got != tt.want {
+			t.Errorf("TOTP at %d = %s, want %s", tt.unix, got, tt.want)
+		}
+	}
+}
+
+func TestValidate(t *testing.T) {
+	now := time.Unix(1111111111, 0)
+	step := Step(now)
+
+	current, _ := Code(rfcSecret, step)
+	previous, _ := Code(rfcSecret, step-1)
+	stale, _ := Code(rfcSecret, step-2)
+
+	tests := []struct {
+		name     string
+		code     string
+		wantStep int64
+		wantOK   bool
+	}{
+		{"current code", current, step, true},
+		{"code with spaces", current[:3] + " " + current[3:], step, true},
+		{"previous code within skew", previous, step - 1, true},
+		{"code outside skew", stale, 0, false},
+		{"wrong length", current + "0", 0, false},
+		{"empty", "", 0, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			gotStep, ok := Validate(rfcSecret, tt.code, now)
+			if ok != tt.wantOK || gotStep != tt.wantStep {
+				t.Errorf("Validate(%q) = (%d, %v), want (%d, %v)", tt.code, gotStep, ok, tt.wantStep, tt.wantOK)
+			}
+		})
+	}
+}
+
+func TestGenerateSecret(t *testing.T) {
+	secret, err := GenerateSecret()
+	if err != nil {
+		t.Fatalf("GenerateSecret() error: %v", err)
+	}
+
+	key, err := decodeSecret(secret)
+	if err != nil {
+		t.Fatalf("generated secret %q does not decode: %v", secret, err)
+	}
+	if len(key) != secretSize {
+		t.Errorf("secret has %d bytes, want %d", len(key), secretSize)
+	}
+
+	other, _ := GenerateSecret()
+	if other == secret {
+		t.Error("GenerateSecret() returned the same secret twice")
+	}
+}
+
+func TestURI(t *testing.T) {
+	uri := URI("TaskMD", "alice@example.com", "JBSWY3DPEHPK3PXP")
+
+	if !strings.HasPrefix(uri, "otpauth://totp/TaskMD:alice@example.com?") {
+		t.Fatalf("URI() = %s, want otpauth label with issuer and account", uri)
+	}
+
+	parsed, err := url.Parse(uri)
+	if err != nil {
+		t.Fatalf("URI() is not a valid URL: %v", err)
+	}
+	query := parsed.Query()
+	for key, want := range map[string]string{"secret": "JBSWY3DPEHPK3PXP", "issuer": "TaskMD", "digits": "6", "period": "30"} {
+		if got := query.Get(key); got != want {
+			t.Errorf("URI() %s = %q, want %q", key, got, want)
+		}
+	}
+}
@@ -0,0 +1,276 @@
+package webhook
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"syscall"
+	"time"
+
+	"github.com/tktomaru/taskai/taskai-server/internal/websocket"
+)
+
+// Request headers sent with every delivery
+const (
+	HeaderEvent     = "X-Taskmd-Event"
+	HeaderDelivery  = "X-Taskmd-Delivery"
+	HeaderTimestamp = "X-Taskmd-Timestamp"
+	HeaderSignature = "X-Taskmd-Signature-256"
+)
+
+const (
+	// BaseBackoff is the delay before the first retry; it doubles with every attempt
+	BaseBackoff = 30 * time.Second
+
+	// MaxBackoff caps the delay between retries
+	MaxBackoff = time.Hour
+
+	// maxResponseBody bounds the part of a response body kept in the delivery log
+	maxResponseBody = 4096
+)
+
+// Events lists the event types that can be delivered to webhooks
+var Events = []websocket.EventType{
+	websocket.EventTaskCreated,
+	websocket.EventTaskUpdated,
+	websocket.EventTaskDeleted,
+	websocket.EventProjectUpdated,
+}
+
+// IsEvent reports whether an event type can be delivered to webhooks
+func IsEvent(eventType string) bool {
+	for _, event := range Events {
+		if string(event) == eventType {
+			return true
+		}
+	}
+	return false
+}
+
+// Payload is the JSON body of a delivery
+type Payload struct {
+	Event      string      `json:"event"`
+	ProjectID  string      `json:"project_id"`
+	TaskID     string      `json:"task_id,omitempty"`
+	OccurredAt time.Time   `json:"occurred_at"`
+	Data       interface{} `json:"data,omitempty"`
+}
+
+// NewPayload encodes the body of a delivery
+func NewPayload(eventType websocket.EventType, projectID, taskID string, data interface{}, occurredAt time.Time) ([]byte, error) {
+	body, err := json.Marshal(&Payload{
+		Event:      string(eventType),
+		ProjectID:  projectID,
+		TaskID:     taskID,
+		OccurredAt: occurredAt.UTC(),
+		Data:       data,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to encode webhook payload: %w", err)
+	}
+
+	return body, nil
+}
+
+// GenerateSecret generates a random signing secret
+func GenerateSecret() (string, error) {
+	b := make([]byte, 32)
+	if _, err := rand.Read(b); err != nil {
+		return "", fmt.Errorf("failed to generate secret: %w", err)
+	}
+
+	return "whsec_" + hex.EncodeToString(b), nil
+}
+
+// Sign computes the signature header value for a body sent at the given Unix time.
+// The signed message is "<timestamp>.<body>" so that a captured request cannot be replayed later.
+func Sign(secret string, timestamp int64, body []byte) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte(strconv.FormatInt(timestamp, 10)))
+	mac.Write([]byte("."))
+	mac.Write(body)
+
+	return "sha256=" + hex.EncodeToString(mac.Sum(nil))
+}
+
+// Verify checks a signature header value in constant time. Receivers can use it as a reference.
+func Verify(secret string, timestamp int64, body []byte, signature string) bool {
+	return hmac.Equal([]byte(Sign(secret, timestamp, body)), []byte(signature))
+}
+
+// Backoff returns the delay before the next attempt after the given number of failed attempts
+func Backoff(attempts int) time.Duration {
+	if attempts < 1 {
+		return 0
+	}
+
+	delay := BaseBackoff
+	for i := 1; i < attempts; i++ {
+		delay *= 2
+		if delay >= MaxBackoff {
+			return MaxBackoff
+		}
+	}
+
+	return delay
+}
+
+// Request is a single delivery attempt
+type Request struct {
+	URL        string
+	Secret     string
+	Event      string
+	DeliveryID int64
+	Body       []byte
+}
+
+// Result is the outcome of a delivery attempt
+type Result struct {
+	StatusCode int
+	Body       string
+	Duration   time.Duration
+	Err        error
+}
+
+// OK reports whether the receiver accepted the delivery with a 2xx response
+func (r *Result) OK() bool {
+	return r.Err == nil && r.StatusCode >= 200 && r.StatusCode < 300
+}
+
+// ErrForbiddenAddress is returned for webhook URLs on loopback, private, link-local or
+// unspecified addresses, which would let webhooks reach services inside the network
+var ErrForbiddenAddress = errors.New("webhook URLs must not point to loopback, private, link-local or unspecified addresses")
+
+// forbiddenIP reports whether deliveries to an address are refused
+func forbiddenIP(ip net.IP) bool {
+	return ip.IsLoopback() || ip.IsPrivate() || ip.IsUnspecified() ||
+		ip.IsLinkLocalUnicast() || ip.IsLinkLocalMulticast() || ip.IsInterfaceLocalMulticast()
+}
+
+// CheckURL rejects webhook URLs whose host is localhost or a forbidden IP address.
+// Host names are resolved when a delivery is sent, and checked again then.
+func CheckURL(rawURL string) error {
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		return fmt.Errorf("invalid webhook URL: %w", err)
+	}
+
+	host := strings.TrimSuffix(strings.ToLower(u.Hostname()), ".")
+	if host == "localhost" || strings.HasSuffix(host, ".localhost") {
+		return ErrForbiddenAddress
+	}
+	if ip := net.ParseIP(host); ip != nil && forbiddenIP(ip) {
+		return ErrForbiddenAddress
+	}
+
+	return nil
+}
+
+// checkDial refuses connections to forbidden addresses. It runs after name resolution,
+// so a host name cannot be pointed at an internal address once the webhook was accepted.
+func checkDial(network, address string, _ syscall.RawConn) error {
+	host, _, err := net.SplitHostPort(address)
+	if err != nil {
+		return fmt.Errorf("invalid webhook address %q: %w", address, err)
+	}
+
+	ip := net.ParseIP(host)
+	if ip == nil || forbiddenIP(ip) {
+		return fmt.Errorf("refusing to connect to %s: %w", host, ErrForbiddenAddress)
+	}
+
+	return nil
+}
+
+// Sender sends signed deliveries over HTTP
+type Sender struct {
+	client *http.Client
+	now    func() time.Time
+}
+
+// NewSender creates a sender whose requests time out after the given duration.
+// Redirects are not followed; a 3xx response is a failed attempt. Connections to
+// loopback, private, link-local and unspecified addresses are refused.
+func NewSender(timeout time.Duration) *Sender {
+	return newSender(timeout, checkDial)
+}
+
+// newSender creates a sender that checks the addresses it connects to with control
+func newSender(timeout time.Duration, control func(network, address string, c syscall.RawConn) error) *Sender {
+	dialer := &net.Dialer{
+		Timeout:   timeout,
+		KeepAlive: 30 * time.Second,
+		Control:   control,
+	}
+
+	// No proxy, so that the checked address is the one the request goes to
+	transport := &http.Transport{
+		DialContext:           dialer.DialContext,
+		ForceAttemptHTTP2:     true,
+		MaxIdleConns:          100,
+		IdleConnTimeout:       90 * time.Second,
+		TLSHandshakeTimeout:   10 * time.Second,
+		ExpectContinueTimeout: time.Second,
+	}
+
+	return &Sender{
+		client: &http.Client{
+			Timeout:   timeout,
+			Transport: transport,
+			CheckRedirect: func(req *http.Request, via []*http.Request) error {
+				return http.ErrUseLastResponse
+			},
+		},
+		now: time.Now,
+	}
+}
+
+// Send posts a delivery to its URL
+func (s *Sender) Send(ctx context.Context, req *Request) *Result {
+	start := s.now()
+	result := &Result{}
+
+	httpReq, err := http.NewRequestWithContext(ctx, http.MethodPost, req.URL, bytes.NewReader(req.Body))
+	if err != nil {
+		result.Err = fmt.Errorf("invalid webhook request: %w", err)
+		return result
+	}
+
+	timestamp := start.Unix()
+	httpReq.Header.Set("Content-Type", "application/json")
+	httpReq.Header.Set("User-Agent", "taskmd-webhook/1.0")
+	httpReq.Header.Set(HeaderEvent, req.Event)
+	httpReq.Header.Set(HeaderDelivery, strconv.FormatInt(req.DeliveryID, 10))
+	httpReq.Header.Set(HeaderTimestamp, strconv.FormatInt(timestamp, 10))
+	httpReq.Header.Set(HeaderSignature, Sign(req.Secret, timestamp, req.Body))
+
+	resp, err := s.client.Do(httpReq)
+	result.Duration = s.now().Sub(start)
+	if err != nil {
+		result.Err = err
+		return result
+	}
+	defer resp.Body.Close()
+
+	body, _ := io.ReadAll(io.LimitReader(resp.Body, maxResponseBody))
+	result.StatusCode = resp.StatusCode
+	result.Body = string(body)
+
+	if !result.OK() {
+		result.Err = fmt.Errorf("receiver responded with status %d", resp.StatusCode)
+	}
+
+	return result
+}
@@ -0,0 +1,183 @@
+package webhook
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/tktomaru/taskai/taskai-server/internal/websocket"
+)
+
+func TestSender_Send(t *testing.T) {
+	secret := "whsec_test"
+	body, err := NewPayload(websocket.EventTaskUpdated, "p1", "T-1", map[string]string{"status": "done"}, time.Now())
+	if err != nil {
+		t.Fatalf("NewPayload() error: %v", err)
+	}
+
+	var received *http.Request
+	var receivedBody []byte
+	receiver := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		received = r
+		receivedBody, _ = io.ReadAll(r.Body)
+		w.WriteHeader(http.StatusAccepted)
+		_, _ = w.Write([]byte("ok"))
+	}))
+	defer receiver.Close()
+
+	result := newSender(5*time.Second, nil).Send(context.Background(), &Request{
+		URL:        receiver.URL,
+		Secret:     secret,
+		Event:      string(websocket.EventTaskUpdated),
+		DeliveryID: 42,
+		Body:       body,
+	})
+
+	if !result.OK() {
+		t.Fatalf("Send() = %+v, want OK", result)
+	}
+	if result.StatusCode != http.StatusAccepted || result.Body != "ok" {
+		t.Errorf("Send() = status %d body %q, want 202 \"ok\"", result.StatusCode, result.Body)
+	}
+
+	if got := received.Header.Get(HeaderEvent); got != "task.updated" {
+		t.Errorf("%s = %q, want task.updated", HeaderEvent, got)
+	}
+	if got := received.Header.Get(HeaderDelivery); got != "42" {
+		t.Errorf("%s = %q, want 42", HeaderDelivery, got)
+	}
+	if string(receivedBody) != string(body) {
+		t.Errorf("received body %s, want %s", receivedBody, body)
+	}
+
+	timestamp, err := strconv.ParseInt(received.Header.Get(HeaderTimestamp), 10, 64)
+	if err != nil {
+		t.Fatalf("invalid %s: %v", HeaderTimestamp, err)
+	}
+	signature := received.Header.Get(HeaderSignature)
+	if !Verify(secret, timestamp, receivedBody, signature) {
+		t.Errorf("signature %q does not verify", signature)
+	}
+	if Verify("other-secret", timestamp, receivedBody, signature) {
+		t.Error("signature verifies with a different secret")
+	}
+	if Verify(secret, timestamp+1, receivedBody, signature) {
+		t.Error("signature verifies with a different timestamp")
+	}
+}
+
+func TestSender_SendFailures(t *testing.T) {
+	failing := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		http.Error(w, "boom", http.StatusInternalServerError)
+	}))
+	defer failing.Close()
+
+	redirecting := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		http.Redirect(w, r, failing.URL, http.StatusFound)
+	}))
+	defer redirecting.Close()
+
+	slow := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		time.Sleep(200 * time.Millisecond)
+	}))
+	defer slow.Close()
+
+	tests := []struct {
+		name       string
+		url        string
+		wantStatus int
+	}{
+		{"server error", failing.URL, http.StatusInternalServerError},
+		{"redirect is not followed", redirecting.URL, http.StatusFound},
+		{"timeout", slow.URL, 0},
+	}
+
+	sender := newSender(50*time.Millisecond, nil)
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			result := sender.Send(context.Background(), &Request{URL: tt.url, Secret: "s", Body: []byte("{}")})
+			if result.OK() || result.Err == nil {
+				t.Errorf("Send() = %+v, want failure", result)
+			}
+			if result.StatusCode != tt.wantStatus {
+				t.Errorf("Send() status = %d, want %d", result.StatusCode, tt.wantStatus)
+			}
+		})
+	}
+}
+
+func TestSender_RefusesInternalAddresses(t *testing.T) {
+	receiver := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		t.Error("request reached a loopback receiver")
+	}))
+	defer receiver.Close()
+
+	result := NewSender(5*time.Second).Send(context.Background(), &Request{URL: receiver.URL, Secret: "s", Body: []byte("{}")})
+	if !errors.Is(result.Err, ErrForbiddenAddress) {
+		t.Errorf("Send() error = %v, want ErrForbiddenAddress", result.Err)
+	}
+	if result.StatusCode != 0 {
+		t.Errorf("Send() status = %d, want 0", result.StatusCode)
+	}
+}
+
+func TestCheckURL(t *testing.T) {
+	tests := []struct {
+		url     string
+		wantErr bool
+	}{
+		{"https://ci.example.com/hooks/taskmd", false},
+		{"http://93.184.216.34:8080/hook", false},
+		{"http://localhost:9000/hook", true},
+		{"http://api.localhost/hook", true},
+		{"http://127.0.0.1/hook", true},
+		{"http://10.0.0.5/hook", true},
+		{"http://192.168.1.1/hook", true},
+		{"http://169.254.169.254/latest/meta-data/", true},
+		{"http://0.0.0.0/hook", true},
+		{"http://[::1]/hook", true},
+		{"http://[fd00::1]/hook", true},
+		{"http://[::ffff:127.0.0.1]/hook", true},
+	}
+
+	for _, tt := range tests {
+		if err := CheckURL(tt.url); (err != nil) != tt.wantErr {
+			t.Errorf("CheckURL(%q) error = %v, wantErr %v", tt.url, err, tt.wantErr)
+		}
+	}
+}
+
+func TestBackoff(t *testing.T) {
+	tests := []struct {
+		attempts int
+		want     time.Duration
+	}{
+		{0, 0},
+		{1, 30 * time.Second},
+		{2, time.Minute},
+		{3, 2 * time.Minute},
+		{7, 32 * time.Minute},
+		{8, time.Hour},
+		{50, time.Hour},
+	}
+
+	for _, tt := range tests {
+		if got := Backoff(tt.attempts); got != tt.want {
+			t.Errorf("Backoff(%d) = %v, want %v", tt.attempts, got, tt.want)
+		}
+	}
+}
+
+func TestIsEvent(t *testing.T) {
+	if !IsEvent("task.created") || !IsEvent("project.updated") {
+		t.Error("IsEvent() rejected a project event")
+	}
+	if IsEvent("notification.created") || IsEvent("") {
+		t.Error("IsEvent() accepted a non-webhook event")
+	}
+}
@@ -0,0 +1,126 @@
+package websocket
+
+import (
+	"context"
+	"log"
+	"time"
+)
+
+// backendRetryDelay is the wait before listening again after the backend failed
+const backendRetryDelay = 5 * time.Second
+
+// Backend carries the messages of a hub to the hubs of all server replicas
+type Backend interface {
+	// Publish sends a message to the hubs of all replicas, this one included. Project
+	// messages are numbered by the backend, so that sequence numbers are the same everywhere.
+	Publish(ctx context.Context, message *Message) error
+
+	// LastSeq returns the sequence number of the last message of a project
+	LastSeq(ctx context.Context, projectID string) (uint64, error)
+
+	// Listen passes the published messages to deliver, each once and in publishing order,
+	// until the context is cancelled or the backend fails. It calls deliver with nil
+	// whenever it starts listening, as messages may have been missed before.
+	Listen(ctx context.Context, deliver func(*Message)) error
+}
+
+// publish sends a message through the backend, or straight to the local clients without one
+func (h *Hub) publish(message *Message) {
+	if h.Backend == nil {
+		h.broadcastMessage(message)
+		return
+	}
+
+	if err := h.Backend.Publish(context.Background(), message); err != nil {
+		log.Printf("ERROR: Failed to publish WebSocket %s message: %v", message.Type, err)
+	}
+}
+
+// Listen delivers the messages published through the backend to the local clients until
+// the context is cancelled. It must be running whenever Backend is set.
+func (h *Hub) Listen(ctx context.Context) {
+	for {
+		err := h.Backend.Listen(ctx, func(message *Message) {
+			if message == nil {
+				h.resync(ctx)
+				return
+			}
+			h.broadcastMessage(message)
+		})
+		if ctx.Err() != nil {
+			return
+		}
+		log.Printf("ERROR: WebSocket backend stopped listening: %v", err)
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-time.After(backendRetryDelay):
+		}
+	}
+}
+
+// loadStream starts the stream of a project from the last sequence number of the
+// backend, unless the hub already follows it
+func (h *Hub) loadStream(projectID string) {
+	h.mu.RLock()
+	_, ok := h.streams[projectID]
+	h.mu.RUnlock()
+	if ok {
+		return
+	}
+
+	seq, err := h.Backend.LastSeq(context.Background(), projectID)
+	if err != nil {
+		// The stream starts from zero and catches up with the first message
+		log.Printf("ERROR: Failed to load WebSocket stream of project %s: %v", projectID, err)
+		return
+	}
+
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	if h.streams[projectID] == nil {
+		h.streams[projectID] = &stream{seq: seq}
+	}
+}
+
+// resync catches up with the streams the hub follows after messages may have been lost,
+// telling their clients to resync if any were
+func (h *Hub) resync(ctx context.Context) {
+	h.mu.RLock()
+	projectIDs := make([]string, 0, len(h.streams))
+	for projectID := range h.streams {
+		projectIDs = append(projectIDs, projectID)
+	}
+	h.mu.RUnlock()
+
+	for _, projectID := range projectIDs {
+		seq, err := h.Backend.LastSeq(ctx, projectID)
+		if err != nil {
+			log.Printf("ERROR: Failed to resync WebSocket stream of project %s: %v", projectID, err)
+			continue
+		}
+
+		h.mu.Lock()
+		if seq > h.streams[projectID].seq {
+			h.skipTo(projectID, seq)
+		}
+		h.mu.Unlock()
+	}
+}
+
+// skipTo moves the stream of a project past messages that were lost. The replay buffer
+// is emptied, so its clients are told to resync. The caller must hold the write lock.
+func (h *Hub) skipTo(projectID string, seq uint64) {
+	log.Printf("WARNING: Lost WebSocket messages of project %s up to seq %d", projectID, seq)
+
+	stream := h.stream(projectID)
+	stream.seq = seq
+	stream.recent = nil
+
+	for client := range h.clients[projectID] {
+		client.lagging.Store(true)
+		h.catchUpLocked(client)
+	}
+}
@@ -2,6 +2,7 @@ package websocket
 
 import (
 	"log"
+	"sync/atomic"
 	"time"
 
 	"github.com/gorilla/websocket"
@@ -18,7 +19,7 @@ const (
 	pingPeriod = (pongWait * 9) / 10
 
 	// Maximum message size allowed from peer
-	maxMessageSize = 512
+	maxMessageSize = 8192
 )
 
 // Client represents a WebSocket client connection
@@ -35,8 +36,33 @@ type Client struct {
 	// Project ID this client is subscribed to
 	ProjectID string
 
-	// User ID (optional, for future use)
+	// User ID of the authenticated user
 	UserID string
+
+	// Credentials the connection was opened with, so that it can be closed when they are revoked
+	SessionID     string
+	AccessTokenID string
+
+	// Close frame sent when the hub removes the client, e.g. because its access was revoked
+	closeMessage []byte
+
+	// Sequence number of the last project message queued for the client, guarded by the hub's lock
+	lastSeq uint64
+
+	// Whether the client resumes after lastSeq instead of starting from the current message
+	resume bool
+
+	// Set when project messages did not fit in the send buffer; the client is sent the
+	// missed messages from the replay buffer once it has drained
+	lagging atomic.Bool
+
+	// Tasks and saved views the client subscribed to, or nil for the whole project.
+	// Guarded by the hub's lock.
+	subscription *subscription
+
+	// Tasks the user is viewing and editing on this connection, guarded by the hub's lock
+	viewing map[string]bool
+	editing map[string]bool
 }
 
 // NewClient creates a new WebSocket client
@@ -50,7 +76,14 @@ func NewClient(hub *Hub, conn *websocket.Conn, projectID string, userID string)
 	}
 }
 
-// readPump pumps messages from the WebSocket connection to the hub
+// Resume makes the client receive the project messages after seq when it registers,
+// or a resync message if they are no longer available. It must be called before registration.
+func (c *Client) Resume(seq uint64) {
+	c.lastSeq = seq
+	c.resume = true
+}
+
+// readPump pumps commands from the WebSocket connection to the hub
 func (c *Client) readPump() {
 	defer func() {
 		c.hub.Unregister <- c
@@ -65,15 +98,15 @@ func (c *Client) readPump() {
 	})
 
 	for {
-		_, _, err := c.conn.ReadMessage()
+		_, data, err := c.conn.ReadMessage()
 		if err != nil {
 			if websocket.IsUnexpectedCloseError(err, websocket.CloseGoingAway, websocket.CloseAbnormalClosure) {
 				log.Printf("WebSocket error: %v", err)
 			}
 			break
 		}
-		// We don't process incoming messages from clients for now
-		// This is a broadcast-only WebSocket
+
+		c.hub.handleCommand(c, data)
 	}
 }
 
@@ -91,7 +124,7 @@ func (c *Client) writePump() {
 			c.conn.SetWriteDeadline(time.Now().Add(writeWait))
 			if !ok {
 				// The hub closed the channel
-				c.conn.WriteMessage(websocket.CloseMessage, []byte{})
+				c.conn.WriteMessage(websocket.CloseMessage, c.closeMessage)
 				return
 			}
 
@@ -112,6 +145,11 @@ func (c *Client) writePump() {
 				return
 			}
 
+			// Refill the drained buffer with the messages the client missed
+			if c.lagging.Load() {
+				c.hub.catchUp(c)
+			}
+
 		case <-ticker.C:
 			c.conn.SetWriteDeadline(time.Now().Add(writeWait))
 			if err := c.conn.WriteMessage(websocket.PingMessage, nil); err != nil {
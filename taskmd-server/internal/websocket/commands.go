@@ -0,0 +1,549 @@
+package websocket
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log"
+	"regexp"
+	"sort"
+)
+
+// Commands clients send over a project connection
+const (
+	// CommandSubscribe limits the project messages a client receives to tasks and saved
+	// views, adding to its earlier subscriptions. With "project" it receives everything again.
+	CommandSubscribe = "subscribe"
+
+	// CommandUnsubscribe removes tasks and saved views from the subscriptions of a client
+	CommandUnsubscribe = "unsubscribe"
+
+	// CommandView and CommandLeave tell others that the user opened or closed a task
+	CommandView  = "view"
+	CommandLeave = "leave"
+
+	// CommandEditing and CommandStopEditing tell others that the user started or stopped editing a task
+	CommandEditing     = "editing"
+	CommandStopEditing = "stop_editing"
+)
+
+// Events sent in reply to commands
+const (
+	EventSubscriptionUpdated EventType = "subscription.updated"
+	EventPresenceUpdated     EventType = "presence.updated"
+	EventCommandError        EventType = "command.error"
+
+	// EventViewChanged tells the subscribers of a saved view which tasks entered or left it
+	EventViewChanged EventType = "view.changed"
+)
+
+const (
+	// maxSubscriptions caps the tasks and views a client can subscribe to
+	maxSubscriptions = 200
+
+	// maxPresence caps the tasks a client can be viewing or editing at once
+	maxPresence = 20
+)
+
+// taskIDPattern is the format of task IDs accepted in commands
+var taskIDPattern = regexp.MustCompile(`^[A-Za-z0-9_-]{1,64}$`)
+
+// Command is a message sent by a client
+type Command struct {
+	Type    string   `json:"type"`
+	TaskID  string   `json:"task_id,omitempty"`
+	Tasks   []string `json:"tasks,omitempty"`
+	Views   []string `json:"views,omitempty"`
+	Project bool     `json:"project,omitempty"`
+}
+
+// Subscription lists what a client receives. Messages that are not about a task, such
+// as project updates, are sent to every client of the project.
+type Subscription struct {
+	Project bool     `json:"project"`
+	Tasks   []string `json:"tasks"`
+	Views   []string `json:"views"`
+}
+
+// ViewChange lists the tasks that entered and left a saved view
+type ViewChange struct {
+	ViewID  string   `json:"view_id"`
+	Entered []string `json:"entered"`
+	Left    []string `json:"left"`
+}
+
+// Presence lists the users viewing and editing a task
+type Presence struct {
+	Viewers []string `json:"viewers"`
+	Editors []string `json:"editors"`
+}
+
+// subscription holds the tasks and saved views a client subscribed to
+type subscription struct {
+	tasks map[string]bool
+	views map[string]bool
+}
+
+// wants reports whether a client with the subscription receives a message about a task
+// that belongs to the given views. A nil subscription receives the whole project.
+func (s *subscription) wants(taskID string, views map[string]bool) bool {
+	if s == nil || taskID == "" || s.tasks[taskID] {
+		return true
+	}
+
+	for viewID := range s.views {
+		if views[viewID] {
+			return true
+		}
+	}
+
+	return false
+}
+
+// viewMembers holds the tasks of a saved view some client subscribed to
+type viewMembers struct {
+	tasks       map[string]bool
+	subscribers int
+
+	// userID the view is resolved for. Private views can only be subscribed to by their
+	// owner, so any subscriber will do.
+	userID string
+}
+
+// handleCommand carries out a command sent by a client and replies to it
+func (h *Hub) handleCommand(client *Client, data []byte) {
+	var cmd Command
+	if err := json.Unmarshal(data, &cmd); err != nil {
+		h.replyError(client, "invalid command: "+err.Error())
+		return
+	}
+
+	if client.ProjectID == "" {
+		h.replyError(client, "commands are only accepted on project connections")
+		return
+	}
+
+	switch cmd.Type {
+	case CommandSubscribe:
+		h.subscribe(client, &cmd)
+	case CommandUnsubscribe:
+		h.unsubscribe(client, &cmd)
+	case CommandView, CommandLeave, CommandEditing, CommandStopEditing:
+		if cmd.TaskID == "" {
+			h.replyError(client, "task_id is required")
+			return
+		}
+		if !taskIDPattern.MatchString(cmd.TaskID) {
+			h.replyError(client, "invalid task_id")
+			return
+		}
+		if h.CheckTask != nil && cmd.Type != CommandLeave && cmd.Type != CommandStopEditing {
+			if err := h.CheckTask(context.Background(), client.ProjectID, cmd.TaskID); err != nil {
+				h.replyError(client, fmt.Sprintf("task %s not found", cmd.TaskID))
+				return
+			}
+		}
+		h.setPresence(client, cmd.Type, cmd.TaskID)
+	default:
+		h.replyError(client, fmt.Sprintf("unknown command %q", cmd.Type))
+	}
+}
+
+// subscribe adds tasks and views to the subscriptions of a client. The tasks of the
+// views are resolved for the user of the client before taking the lock.
+func (h *Hub) subscribe(client *Client, cmd *Command) {
+	resolved := make(map[string][]string)
+	if !cmd.Project {
+		for _, viewID := range cmd.Views {
+			if h.ResolveView == nil {
+				h.replyError(client, "view subscriptions are not supported")
+				return
+			}
+
+			taskIDs, err := h.ResolveView(context.Background(), client.ProjectID, viewID, client.UserID)
+			if err != nil {
+				h.replyError(client, fmt.Sprintf("view %s not found", viewID))
+				return
+			}
+			resolved[viewID] = taskIDs
+		}
+	}
+
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	if !h.clients[client.ProjectID][client] {
+		return
+	}
+
+	if cmd.Project {
+		h.dropViews(client)
+		client.subscription = nil
+		h.replySubscription(client)
+		return
+	}
+
+	sub := client.subscription
+	if sub == nil {
+		sub = &subscription{tasks: make(map[string]bool), views: make(map[string]bool)}
+	}
+	if len(sub.tasks)+len(sub.views)+len(cmd.Tasks)+len(cmd.Views) > maxSubscriptions {
+		h.replyErrorLocked(client, fmt.Sprintf("at most %d tasks and views can be subscribed to", maxSubscriptions))
+		return
+	}
+
+	for _, taskID := range cmd.Tasks {
+		sub.tasks[taskID] = true
+	}
+	for _, viewID := range cmd.Views {
+		if sub.views[viewID] {
+			continue
+		}
+		sub.views[viewID] = true
+
+		if h.views[client.ProjectID] == nil {
+			h.views[client.ProjectID] = make(map[string]*viewMembers)
+		}
+		members := h.views[client.ProjectID][viewID]
+		if members == nil {
+			members = &viewMembers{userID: client.UserID}
+			h.views[client.ProjectID][viewID] = members
+		}
+		members.tasks = taskSet(resolved[viewID])
+		members.subscribers++
+	}
+
+	client.subscription = sub
+	h.replySubscription(client)
+}
+
+// unsubscribe removes tasks and views from the subscriptions of a client
+func (h *Hub) unsubscribe(client *Client, cmd *Command) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	if !h.clients[client.ProjectID][client] {
+		return
+	}
+
+	if sub := client.subscription; sub != nil {
+		for _, taskID := range cmd.Tasks {
+			delete(sub.tasks, taskID)
+		}
+		for _, viewID := range cmd.Views {
+			if sub.views[viewID] {
+				delete(sub.views, viewID)
+				h.releaseView(client.ProjectID, viewID)
+			}
+		}
+	}
+
+	h.replySubscription(client)
+}
+
+// dropViews releases the view subscriptions of a client. The caller must hold the write lock.
+func (h *Hub) dropViews(client *Client) {
+	if client.subscription == nil {
+		return
+	}
+
+	for viewID := range client.subscription.views {
+		h.releaseView(client.ProjectID, viewID)
+	}
+	client.subscription.views = make(map[string]bool)
+}
+
+// releaseView forgets the tasks of a view once nobody subscribes to it. The caller must hold the write lock.
+func (h *Hub) releaseView(projectID, viewID string) {
+	if members := h.views[projectID][viewID]; members != nil {
+		members.subscribers--
+		if members.subscribers <= 0 {
+			delete(h.views[projectID], viewID)
+			if len(h.views[projectID]) == 0 {
+				delete(h.views, projectID)
+			}
+		}
+	}
+}
+
+// matchViews returns the subscribed views a task belonged to at their last refresh, and
+// queues a refresh of the views of the project, as the change may move the task in or
+// out of them. The caller must hold the write lock.
+func (h *Hub) matchViews(projectID, taskID string) map[string]bool {
+	if taskID == "" || len(h.views[projectID]) == 0 {
+		return nil
+	}
+
+	var views map[string]bool
+	for viewID, members := range h.views[projectID] {
+		if members.tasks[taskID] {
+			if views == nil {
+				views = make(map[string]bool)
+			}
+			views[viewID] = true
+		}
+	}
+
+	if !h.viewsDirty[projectID] {
+		h.viewsDirty[projectID] = true
+		select {
+		case h.viewsWake <- struct{}{}:
+		default:
+		}
+	}
+
+	return views
+}
+
+// runViewRefresh refreshes the subscribed views of projects whose tasks changed, outside
+// of message delivery. It runs with Run.
+func (h *Hub) runViewRefresh() {
+	for range h.viewsWake {
+		h.refreshViews(context.Background())
+	}
+}
+
+// refreshViews resolves the subscribed views of the projects whose tasks changed, and tells
+// their subscribers about the tasks that entered or left them
+func (h *Hub) refreshViews(ctx context.Context) {
+	if h.ResolveView == nil {
+		return
+	}
+
+	type pendingView struct {
+		projectID, viewID, userID string
+	}
+
+	h.mu.Lock()
+	var pending []pendingView
+	for projectID := range h.viewsDirty {
+		for viewID, members := range h.views[projectID] {
+			pending = append(pending, pendingView{projectID, viewID, members.userID})
+		}
+	}
+	h.viewsDirty = make(map[string]bool)
+	h.mu.Unlock()
+
+	for _, view := range pending {
+		taskIDs, err := h.ResolveView(ctx, view.projectID, view.viewID, view.userID)
+		if err != nil {
+			log.Printf("WARNING: Failed to resolve subscribed view %s: %v", view.viewID, err)
+			continue
+		}
+
+		h.updateView(view.projectID, view.viewID, taskSet(taskIDs))
+	}
+}
+
+// updateView replaces the tasks of a subscribed view and tells its subscribers what changed
+func (h *Hub) updateView(projectID, viewID string, current map[string]bool) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	members := h.views[projectID][viewID]
+	if members == nil {
+		return
+	}
+
+	change := ViewChange{ViewID: viewID, Entered: []string{}, Left: []string{}}
+	for taskID := range current {
+		if !members.tasks[taskID] {
+			change.Entered = append(change.Entered, taskID)
+		}
+	}
+	for taskID := range members.tasks {
+		if !current[taskID] {
+			change.Left = append(change.Left, taskID)
+		}
+	}
+	members.tasks = current
+
+	if len(change.Entered) == 0 && len(change.Left) == 0 {
+		return
+	}
+	sort.Strings(change.Entered)
+	sort.Strings(change.Left)
+
+	// Like presence, view changes are not numbered or replayed
+	for client := range h.clients[projectID] {
+		if client.subscription != nil && client.subscription.views[viewID] {
+			h.reply(client, &Message{Type: EventViewChanged, ProjectID: projectID, Data: change})
+		}
+	}
+}
+
+// setPresence records that the user of a client is viewing or editing a task, or stopped,
+// and tells the clients following the task
+func (h *Hub) setPresence(client *Client, command, taskID string) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	if !h.clients[client.ProjectID][client] {
+		return
+	}
+
+	if client.viewing == nil {
+		client.viewing = make(map[string]bool)
+		client.editing = make(map[string]bool)
+	}
+
+	switch command {
+	case CommandView, CommandEditing:
+		if !client.viewing[taskID] && len(client.viewing) >= maxPresence {
+			h.replyErrorLocked(client, fmt.Sprintf("at most %d tasks can be viewed at once", maxPresence))
+			return
+		}
+		client.viewing[taskID] = true
+		if command == CommandEditing {
+			client.editing[taskID] = true
+		}
+	case CommandLeave:
+		delete(client.viewing, taskID)
+		delete(client.editing, taskID)
+	case CommandStopEditing:
+		delete(client.editing, taskID)
+	}
+
+	h.sendPresence(client.ProjectID, taskID)
+}
+
+// clearPresence forgets what a removed client was viewing and editing and tells the
+// clients following those tasks. The caller must hold the write lock.
+func (h *Hub) clearPresence(client *Client) {
+	taskIDs := client.viewing
+	client.viewing = nil
+	client.editing = nil
+
+	for taskID := range taskIDs {
+		h.sendPresence(client.ProjectID, taskID)
+	}
+}
+
+// presence returns who is viewing and editing a task. The caller must hold the lock.
+func (h *Hub) presence(projectID, taskID string) *Presence {
+	viewers := make(map[string]bool)
+	editors := make(map[string]bool)
+	for client := range h.clients[projectID] {
+		if client.viewing[taskID] {
+			viewers[client.UserID] = true
+		}
+		if client.editing[taskID] {
+			editors[client.UserID] = true
+		}
+	}
+
+	return &Presence{Viewers: sortedKeys(viewers), Editors: sortedKeys(editors)}
+}
+
+// sendPresence tells the clients following a task who is viewing and editing it.
+// Presence is not numbered or replayed; a client that misses an update gets the next one.
+// The caller must hold the write lock.
+func (h *Hub) sendPresence(projectID, taskID string) {
+	data, err := json.Marshal(&Message{
+		Type:      EventPresenceUpdated,
+		ProjectID: projectID,
+		TaskID:    taskID,
+		Data:      h.presence(projectID, taskID),
+	})
+	if err != nil {
+		log.Printf("Error marshaling message: %v", err)
+		return
+	}
+
+	views := make(map[string]bool)
+	for viewID, members := range h.views[projectID] {
+		if members.tasks[taskID] {
+			views[viewID] = true
+		}
+	}
+
+	for client := range h.clients[projectID] {
+		if !client.subscription.wants(taskID, views) && !client.viewing[taskID] {
+			continue
+		}
+
+		select {
+		case client.send <- data:
+		default:
+		}
+	}
+}
+
+// Presence returns who is viewing and editing each task of a project that someone is viewing
+func (h *Hub) Presence(projectID string) map[string]*Presence {
+	h.mu.RLock()
+	defer h.mu.RUnlock()
+
+	presence := make(map[string]*Presence)
+	for client := range h.clients[projectID] {
+		for taskID := range client.viewing {
+			if presence[taskID] == nil {
+				presence[taskID] = h.presence(projectID, taskID)
+			}
+		}
+	}
+
+	return presence
+}
+
+// replySubscription sends a client its subscriptions. The caller must hold the write lock.
+func (h *Hub) replySubscription(client *Client) {
+	reply := Subscription{Project: true, Tasks: []string{}, Views: []string{}}
+	if sub := client.subscription; sub != nil {
+		reply = Subscription{Tasks: sortedKeys(sub.tasks), Views: sortedKeys(sub.views)}
+	}
+
+	h.reply(client, &Message{Type: EventSubscriptionUpdated, ProjectID: client.ProjectID, Data: reply})
+}
+
+// replyError tells a client that its command failed
+func (h *Hub) replyError(client *Client, message string) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	h.replyErrorLocked(client, message)
+}
+
+// replyErrorLocked tells a client that its command failed. The caller must hold the write lock.
+func (h *Hub) replyErrorLocked(client *Client, message string) {
+	h.reply(client, &Message{Type: EventCommandError, ProjectID: client.ProjectID, Data: map[string]string{"message": message}})
+}
+
+// reply queues a message for a registered client. The caller must hold the write lock.
+func (h *Hub) reply(client *Client, message *Message) {
+	if !h.clients[client.ProjectID][client] && !h.userClients[client.UserID][client] {
+		return
+	}
+
+	data, err := json.Marshal(message)
+	if err != nil {
+		log.Printf("Error marshaling message: %v", err)
+		return
+	}
+
+	select {
+	case client.send <- data:
+	default:
+	}
+}
+
+// taskSet turns a list of task IDs into a set
+func taskSet(taskIDs []string) map[string]bool {
+	set := make(map[string]bool, len(taskIDs))
+	for _, id := range taskIDs {
+		set[id] = true
+	}
+
+	return set
+}
+
+// sortedKeys returns the keys of a set in order
+func sortedKeys(set map[string]bool) []string {
+	keys := make([]string, 0, len(set))
+	for key := range set {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	return keys
+}
@@ -0,0 +1,284 @@
+package websocket
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"reflect"
+	"testing"
+)
+
+// command sends a command from a client to the hub
+func command(t *testing.T, hub *Hub, client *Client, cmd Command) {
+	t.Helper()
+
+	data, err := json.Marshal(cmd)
+	if err != nil {
+		t.Fatalf("Failed to encode command: %v", err)
+	}
+	hub.handleCommand(client, data)
+}
+
+// viewChanges returns the view changes a client received
+func viewChanges(t *testing.T, messages []Message) []ViewChange {
+	t.Helper()
+
+	var changes []ViewChange
+	for _, message := range messages {
+		if message.Type != EventViewChanged {
+			continue
+		}
+		data, _ := json.Marshal(message.Data)
+		var change ViewChange
+		if err := json.Unmarshal(data, &change); err != nil {
+			t.Fatalf("Failed to decode view change: %v", err)
+		}
+		changes = append(changes, change)
+	}
+	return changes
+}
+
+// taskIDs returns the task IDs of the task messages a client received
+func taskIDs(messages []Message) []string {
+	var ids []string
+	for _, message := range messages {
+		if message.Type == EventTaskUpdated {
+			ids = append(ids, message.TaskID)
+		}
+	}
+	return ids
+}
+
+func TestHub_SubscribeToTasksAndViews(t *testing.T) {
+	hub := NewHub()
+	view := []string{"T-2"}
+	hub.ResolveView = func(ctx context.Context, projectID, viewID, userID string) ([]string, error) {
+		return view, nil
+	}
+
+	client := &Client{ProjectID: "project-1", UserID: "user-1", send: make(chan []byte, 32)}
+	hub.registerClient(client)
+	expectSyncReady(t, client)
+
+	command(t, hub, client, Command{Type: CommandSubscribe, Tasks: []string{"T-1"}, Views: []string{"V-1"}})
+	messages := receive(t, client)
+	if len(messages) != 1 || messages[0].Type != EventSubscriptionUpdated {
+		t.Fatalf("received %+v, want subscription.updated", messages)
+	}
+
+	for _, id := range []string{"T-1", "T-2", "T-3"} {
+		hub.Broadcast(EventTaskUpdated, "project-1", id, nil)
+	}
+	hub.Broadcast(EventProjectUpdated, "project-1", "", nil)
+
+	messages = receive(t, client)
+	if got := taskIDs(messages); !reflect.DeepEqual(got, []string{"T-1", "T-2"}) {
+		t.Errorf("received tasks %v, want [T-1 T-2]", got)
+	}
+	if last := messages[len(messages)-1]; last.Type != EventProjectUpdated {
+		t.Errorf("last message = %v, want project.updated", last.Type)
+	}
+
+	// A task that leaves the view is still reported once, then no longer
+	view = nil
+	hub.Broadcast(EventTaskUpdated, "project-1", "T-2", nil)
+	hub.refreshViews(context.Background())
+	hub.Broadcast(EventTaskUpdated, "project-1", "T-2", nil)
+	messages = receive(t, client)
+	if got := taskIDs(messages); !reflect.DeepEqual(got, []string{"T-2"}) {
+		t.Errorf("received tasks %v after T-2 left the view, want [T-2]", got)
+	}
+	want := []ViewChange{{ViewID: "V-1", Entered: []string{}, Left: []string{"T-2"}}}
+	if got := viewChanges(t, messages); !reflect.DeepEqual(got, want) {
+		t.Errorf("view changes = %+v, want %+v", got, want)
+	}
+
+	// A task that enters the view is announced once the view is resolved again, then reported
+	view = []string{"T-3"}
+	hub.Broadcast(EventTaskUpdated, "project-1", "T-3", nil)
+	hub.refreshViews(context.Background())
+	hub.Broadcast(EventTaskUpdated, "project-1", "T-3", nil)
+	messages = receive(t, client)
+	if got := taskIDs(messages); !reflect.DeepEqual(got, []string{"T-3"}) {
+		t.Errorf("received tasks %v after T-3 entered the view, want [T-3]", got)
+	}
+	want = []ViewChange{{ViewID: "V-1", Entered: []string{"T-3"}, Left: []string{}}}
+	if got := viewChanges(t, messages); !reflect.DeepEqual(got, want) {
+		t.Errorf("view changes = %+v, want %+v", got, want)
+	}
+
+	// Nothing changed since
+	hub.refreshViews(context.Background())
+	if messages := receive(t, client); len(messages) != 0 {
+		t.Errorf("received %+v without changes", messages)
+	}
+
+	// Resubscribing to the project receives everything again
+	command(t, hub, client, Command{Type: CommandSubscribe, Project: true})
+	receive(t, client)
+	if len(hub.views) != 0 {
+		t.Errorf("hub still follows %d projects with subscribed views", len(hub.views))
+	}
+	hub.Broadcast(EventTaskUpdated, "project-1", "T-4", nil)
+	if got := taskIDs(receive(t, client)); !reflect.DeepEqual(got, []string{"T-4"}) {
+		t.Errorf("received tasks %v, want [T-4]", got)
+	}
+}
+
+func TestHub_ReplayRespectsSubscriptions(t *testing.T) {
+	hub := NewHub()
+
+	client := &Client{ProjectID: "project-1", send: make(chan []byte, 3)}
+	hub.registerClient(client)
+	expectSyncReady(t, client)
+	command(t, hub, client, Command{Type: CommandSubscribe, Tasks: []string{"T-1"}})
+	receive(t, client)
+
+	for i := 0; i < 4; i++ {
+		hub.Broadcast(EventTaskUpdated, "project-1", "T-1", nil)
+		hub.Broadcast(EventTaskUpdated, "project-1", "T-2", nil)
+	}
+
+	var got []string
+	for i := 0; i < 5; i++ {
+		got = append(got, taskIDs(receive(t, client))...)
+		hub.catchUp(client)
+	}
+	if !reflect.DeepEqual(got, []string{"T-1", "T-1", "T-1", "T-1"}) {
+		t.Errorf("received tasks %v, want T-1 four times", got)
+	}
+}
+
+func TestHub_Presence(t *testing.T) {
+	hub := NewHub()
+
+	alice := &Client{ProjectID: "project-1", UserID: "alice", send: make(chan []byte, 32)}
+	bob := &Client{ProjectID: "project-1", UserID: "bob", send: make(chan []byte, 32)}
+	hub.registerClient(alice)
+	hub.registerClient(bob)
+	expectSyncReady(t, alice, bob)
+
+	command(t, hub, alice, Command{Type: CommandView, TaskID: "T-12"})
+	command(t, hub, alice, Command{Type: CommandEditing, TaskID: "T-12"})
+	command(t, hub, bob, Command{Type: CommandView, TaskID: "T-12"})
+
+	want := &Presence{Viewers: []string{"alice", "bob"}, Editors: []string{"alice"}}
+	if got := hub.Presence("project-1")["T-12"]; !reflect.DeepEqual(got, want) {
+		t.Errorf("Presence() = %+v, want %+v", got, want)
+	}
+
+	messages := receive(t, bob)
+	if len(messages) != 3 || messages[2].Type != EventPresenceUpdated || messages[2].TaskID != "T-12" {
+		t.Fatalf("bob received %+v, want three presence updates", messages)
+	}
+	receive(t, alice)
+
+	// Disconnecting clears the presence of the client
+	hub.unregisterClient(alice)
+	want = &Presence{Viewers: []string{"bob"}, Editors: []string{}}
+	if got := hub.Presence("project-1")["T-12"]; !reflect.DeepEqual(got, want) {
+		t.Errorf("Presence() after disconnect = %+v, want %+v", got, want)
+	}
+
+	messages = receive(t, bob)
+	if len(messages) != 1 || messages[0].Type != EventPresenceUpdated {
+		t.Fatalf("bob received %+v, want a presence update", messages)
+	}
+	data, _ := json.Marshal(messages[0].Data)
+	var presence Presence
+	if err := json.Unmarshal(data, &presence); err != nil || !reflect.DeepEqual(&presence, want) {
+		t.Errorf("presence update = %s, want %+v", data, want)
+	}
+
+	command(t, hub, bob, Command{Type: CommandLeave, TaskID: "T-12"})
+	if got := hub.Presence("project-1"); len(got) != 0 {
+		t.Errorf("Presence() after leaving = %+v, want none", got)
+	}
+}
+
+func TestHub_InvalidCommands(t *testing.T) {
+	hub := NewHub()
+
+	client := &Client{ProjectID: "project-1", UserID: "user-1", send: make(chan []byte, 32)}
+	inbox := &Client{UserID: "user-1", send: make(chan []byte, 32)}
+	hub.registerClient(client)
+	hub.registerClient(inbox)
+	expectSyncReady(t, client)
+
+	tests := []struct {
+		name   string
+		client *Client
+		data   string
+	}{
+		{"not json", client, "hello"},
+		{"unknown command", client, `{"type":"shout"}`},
+		{"missing task", client, `{"type":"view"}`},
+		{"views without resolver", client, `{"type":"subscribe","views":["V-1"]}`},
+		{"user connection", inbox, `{"type":"view","task_id":"T-1"}`},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			hub.handleCommand(tt.client, []byte(tt.data))
+
+			messages := receive(t, tt.client)
+			if len(messages) != 1 || messages[0].Type != EventCommandError {
+				t.Errorf("received %+v, want command.error", messages)
+			}
+		})
+	}
+}
+
+func TestHub_CommandsAreChecked(t *testing.T) {
+	hub := NewHub()
+	hub.ResolveView = func(ctx context.Context, projectID, viewID, userID string) ([]string, error) {
+		if viewID == "V-private" && userID != "owner" {
+			return nil, errors.New("view not found")
+		}
+		return nil, nil
+	}
+	hub.CheckTask = func(ctx context.Context, projectID, taskID string) error {
+		if taskID != "T-1" {
+			return errors.New("task not found")
+		}
+		return nil
+	}
+
+	owner := &Client{ProjectID: "project-1", UserID: "owner", send: make(chan []byte, 32)}
+	other := &Client{ProjectID: "project-1", UserID: "other", send: make(chan []byte, 32)}
+	hub.registerClient(owner)
+	hub.registerClient(other)
+	expectSyncReady(t, owner, other)
+
+	tests := []struct {
+		name    string
+		client  *Client
+		cmd     Command
+		wantErr bool
+	}{
+		{"own private view", owner, Command{Type: CommandSubscribe, Views: []string{"V-private"}}, false},
+		{"private view of another user", other, Command{Type: CommandSubscribe, Views: []string{"V-private"}}, true},
+		{"task in the project", other, Command{Type: CommandView, TaskID: "T-1"}, false},
+		{"unknown task", other, Command{Type: CommandView, TaskID: "T-404"}, true},
+		{"malformed task", other, Command{Type: CommandEditing, TaskID: "<script>"}, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			command(t, hub, tt.client, tt.cmd)
+
+			messages := receive(t, tt.client)
+			if len(messages) == 0 {
+				t.Fatal("received no reply")
+			}
+			if gotErr := messages[0].Type == EventCommandError; gotErr != tt.wantErr {
+				t.Errorf("received %+v, want error %v", messages, tt.wantErr)
+			}
+		})
+	}
+
+	receive(t, owner)
+	if got := hub.Presence("project-1"); len(got) != 1 || got["T-1"] == nil {
+		t.Errorf("Presence() = %+v, want only T-1", got)
+	}
+}
@@ -1,9 +1,13 @@
 package websocket
 
 import (
+	"context"
 	"encoding/json"
 	"log"
 	"sync"
+	"time"
+
+	"github.com/gorilla/websocket"
 )
 
 // EventType represents the type of WebSocket event
@@ -14,6 +18,15 @@ const (
 	EventTaskUpdated EventType = "task.updated"
 	EventTaskDeleted EventType = "task.deleted"
 	EventProjectUpdated EventType = "project.updated"
+	EventNotificationCreated EventType = "notification.created"
+
+	// EventSyncReady is the first message of a project connection. Its sequence number is
+	// that of the last project message; the messages a resuming client missed follow it.
+	EventSyncReady EventType = "sync.ready"
+
+	// EventResyncRequired tells a client that messages it missed are no longer available.
+	// It must reload the project; later messages follow the sequence number of this one.
+	EventResyncRequired EventType = "sync.resync_required"
 )
 
 // Message represents a WebSocket message
@@ -22,6 +35,15 @@ type Message struct {
 	ProjectID string      `json:"project_id,omitempty"`
 	TaskID    string      `json:"task_id,omitempty"`
 	Data      interface{} `json:"data,omitempty"`
+
+	// Seq numbers the messages of a project, without gaps
+	Seq uint64 `json:"seq,omitempty"`
+
+	// UserID addresses the message to the connections of a single user instead of a project
+	UserID string `json:"-"`
+
+	// Subscribed views the task of the message belongs to
+	views map[string]bool
 }
 
 // Hub maintains the set of active clients and broadcasts messages to them
@@ -29,31 +51,75 @@ type Hub struct {
 	// Registered clients by project ID
 	clients map[string]map[*Client]bool
 
+	// Registered clients by user ID (per-user channel for notifications)
+	userClients map[string]map[*Client]bool
+
 	// Register requests from clients
 	Register chan *Client
 
 	// Unregister requests from clients
 	Unregister chan *Client
 
-	// Broadcast messages to clients (internal use)
-	broadcast chan *Message
+	// Sequence and recent messages by project ID
+	streams map[string]*stream
+
+	// Tasks of the saved views clients subscribed to, by project ID and view ID
+	views map[string]map[string]*viewMembers
+
+	// Projects whose subscribed views need to be resolved again, and the signal to do so
+	viewsDirty map[string]bool
+	viewsWake  chan struct{}
+
+	// First sequence number of every stream without a backend. Streams start from the time
+	// the hub was created, so that sequence numbers keep increasing across restarts.
+	epoch uint64
 
 	// Mutex for thread-safe access
 	mu sync.RWMutex
+
+	// ReplayLimit is the number of recent messages kept per project for clients that
+	// resume after a reconnect or fall behind. It must be set before the hub is used.
+	ReplayLimit int
+
+	// OnBroadcast is called for every project message, e.g. to queue webhook deliveries.
+	// It must be set before the hub is used.
+	OnBroadcast func(eventType EventType, projectID, taskID string, data interface{})
+
+	// Backend carries messages to the hubs of other server replicas, which then deliver
+	// them to their clients. Without one, messages only reach the clients of this hub.
+	// It must be set before the hub is used.
+	Backend Backend
+
+	// ResolveView returns the IDs of all tasks in a saved view of a project, for clients
+	// that subscribe to views. It fails for views the user cannot see, such as the private
+	// views of other users. It must be set before the hub is used.
+	ResolveView func(ctx context.Context, projectID, viewID, userID string) ([]string, error)
+
+	// CheckTask fails for tasks that are not in a project, so that clients cannot report
+	// presence on them. It must be set before the hub is used.
+	CheckTask func(ctx context.Context, projectID, taskID string) error
 }
 
 // NewHub creates a new WebSocket hub
 func NewHub() *Hub {
 	return &Hub{
 		clients:    make(map[string]map[*Client]bool),
+		userClients: make(map[string]map[*Client]bool),
 		Register:   make(chan *Client),
 		Unregister: make(chan *Client),
-		broadcast:  make(chan *Message, 256),
+		streams:    make(map[string]*stream),
+		views:      make(map[string]map[string]*viewMembers),
+		viewsDirty: make(map[string]bool),
+		viewsWake:  make(chan struct{}, 1),
+		epoch:      uint64(time.Now().UnixMicro()),
+		ReplayLimit: DefaultReplayLimit,
 	}
 }
 
 // Run starts the hub's main loop
 func (h *Hub) Run() {
+	go h.runViewRefresh()
+
 	for {
 		select {
 		case client := <-h.Register:
@@ -61,75 +127,239 @@ func (h *Hub) Run() {
 
 		case client := <-h.Unregister:
 			h.unregisterClient(client)
-
-		case message := <-h.broadcast:
-			h.broadcastMessage(message)
 		}
 	}
 }
 
 // registerClient registers a new client
 func (h *Hub) registerClient(client *Client) {
+	if h.Backend != nil && client.ProjectID != "" {
+		h.loadStream(client.ProjectID)
+	}
+
 	h.mu.Lock()
 	defer h.mu.Unlock()
 
+	// Authenticated clients also receive the messages addressed to their user
+	if client.UserID != "" {
+		if h.userClients[client.UserID] == nil {
+			h.userClients[client.UserID] = make(map[*Client]bool)
+		}
+		h.userClients[client.UserID][client] = true
+	}
+
+	// Clients of the per-user channel are not subscribed to a project
+	if client.ProjectID == "" {
+		log.Printf("Client registered for user %s (total: %d)", client.UserID, len(h.userClients[client.UserID]))
+		return
+	}
+
 	if h.clients[client.ProjectID] == nil {
 		h.clients[client.ProjectID] = make(map[*Client]bool)
 	}
 	h.clients[client.ProjectID][client] = true
 
+	// Tell the client where the stream is, then send what it missed if it is resuming
+	stream := h.stream(client.ProjectID)
+	if !client.resume {
+		client.lastSeq = stream.seq
+	}
+	if _, ok := stream.since(client.lastSeq); ok {
+		h.sendControl(client, EventSyncReady, stream.seq)
+		client.lagging.Store(client.lastSeq != stream.seq)
+		h.catchUpLocked(client)
+	} else {
+		h.sendControl(client, EventResyncRequired, stream.seq)
+		client.lastSeq = stream.seq
+	}
+
 	log.Printf("Client registered for project %s (total: %d)", client.ProjectID, len(h.clients[client.ProjectID]))
 }
 
+// stream returns the stream of a project, creating it if needed. The caller must hold the write lock.
+func (h *Hub) stream(projectID string) *stream {
+	if h.streams[projectID] == nil {
+		h.streams[projectID] = &stream{}
+		if h.Backend == nil {
+			h.streams[projectID].seq = h.epoch
+		}
+	}
+
+	return h.streams[projectID]
+}
+
+// sendControl queues a synchronization message for a project client. The caller must hold the write lock.
+func (h *Hub) sendControl(client *Client, eventType EventType, seq uint64) bool {
+	data, err := json.Marshal(&Message{Type: eventType, ProjectID: client.ProjectID, Seq: seq})
+	if err != nil {
+		log.Printf("Error marshaling message: %v", err)
+		return false
+	}
+
+	select {
+	case client.send <- data:
+		return true
+	default:
+		return false
+	}
+}
+
+// catchUp queues the project messages a lagging client has missed
+func (h *Hub) catchUp(client *Client) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	h.catchUpLocked(client)
+}
+
+// catchUpLocked queues as many missed messages as fit in the send buffer of a lagging
+// client, which stays lagging until it has received them all. A client that missed more
+// than the replay buffer holds is told to resync. The caller must hold the write lock.
+func (h *Hub) catchUpLocked(client *Client) {
+	if !client.lagging.Load() || !h.clients[client.ProjectID][client] {
+		return
+	}
+
+	stream := h.stream(client.ProjectID)
+	missed, ok := stream.since(client.lastSeq)
+	if !ok {
+		if h.sendControl(client, EventResyncRequired, stream.seq) {
+			client.lastSeq = stream.seq
+			client.lagging.Store(false)
+		}
+		return
+	}
+
+	for _, message := range missed {
+		if !client.subscription.wants(message.taskID, message.views) {
+			client.lastSeq = message.seq
+			continue
+		}
+
+		select {
+		case client.send <- message.data:
+			client.lastSeq = message.seq
+		default:
+			return
+		}
+	}
+
+	client.lagging.Store(false)
+}
+
 // unregisterClient unregisters a client
 func (h *Hub) unregisterClient(client *Client) {
 	h.mu.Lock()
 	defer h.mu.Unlock()
 
-	if clients, ok := h.clients[client.ProjectID]; ok {
-		if _, ok := clients[client]; ok {
-			delete(clients, client)
-			close(client.send)
+	if h.removeClient(client) {
+		log.Printf("Client unregistered from project %s (remaining: %d)", client.ProjectID, len(h.clients[client.ProjectID]))
+	}
+}
 
-			if len(clients) == 0 {
-				delete(h.clients, client.ProjectID)
-			}
+// removeClient removes a client from the project and user indexes and closes its send channel.
+// It reports whether the client was registered. The caller must hold the write lock.
+func (h *Hub) removeClient(client *Client) bool {
+	registered := false
+
+	if clients, ok := h.clients[client.ProjectID]; ok && clients[client] {
+		registered = true
+		delete(clients, client)
+		if len(clients) == 0 {
+			delete(h.clients, client.ProjectID)
+		}
+
+		// Others stop seeing the user on the tasks the client had open
+		h.dropViews(client)
+		h.clearPresence(client)
+	}
 
-			log.Printf("Client unregistered from project %s (remaining: %d)", client.ProjectID, len(clients))
+	if clients, ok := h.userClients[client.UserID]; ok && clients[client] {
+		registered = true
+		delete(clients, client)
+		if len(clients) == 0 {
+			delete(h.userClients, client.UserID)
 		}
 	}
+
+	if registered {
+		close(client.send)
+	}
+
+	return registered
 }
 
-// broadcastMessage broadcasts a message to all clients in the project
+// broadcastMessage broadcasts a message to all clients in the project,
+// or to all connections of a user for messages addressed to a user.
+// Project messages are numbered, unless the backend numbered them, and kept for replay.
+// A client whose send buffer is full is not sent any more of them until it has caught up
+// with what it missed.
 func (h *Hub) broadcastMessage(message *Message) {
-	h.mu.RLock()
-	defer h.mu.RUnlock()
+	h.mu.Lock()
+	defer h.mu.Unlock()
 
-	projectClients := h.clients[message.ProjectID]
-	if projectClients == nil {
+	if message.UserID != "" {
+		h.sendToUser(message)
 		return
 	}
 
+	stream := h.stream(message.ProjectID)
+	switch {
+	case message.Seq == 0:
+		message.Seq = stream.seq + 1
+	case message.Seq <= stream.seq:
+		// Already delivered
+		return
+	case message.Seq > stream.seq+1:
+		h.skipTo(message.ProjectID, message.Seq-1)
+	}
+	message.views = h.matchViews(message.ProjectID, message.TaskID)
+
 	// Convert message to JSON once
 	data, err := json.Marshal(message)
 	if err != nil {
 		log.Printf("Error marshaling message: %v", err)
 		return
 	}
+	stream.append(sequencedMessage{seq: message.Seq, data: data, taskID: message.TaskID, views: message.views}, h.ReplayLimit)
 
 	// Send to all clients in the project
-	for client := range projectClients {
+	for client := range h.clients[message.ProjectID] {
+		if client.lagging.Load() {
+			continue
+		}
+		if !client.subscription.wants(message.TaskID, message.views) {
+			client.lastSeq = message.Seq
+			continue
+		}
+
 		select {
 		case client.send <- data:
+			client.lastSeq = message.Seq
 		default:
-			// Client's send channel is full, close it
-			close(client.send)
-			delete(projectClients, client)
+			// The client catches up from the replay buffer once its send buffer has drained
+			client.lagging.Store(true)
+			log.Printf("WARNING: WebSocket client of user %s fell behind on project %s", client.UserID, client.ProjectID)
 		}
 	}
+}
 
-	if len(projectClients) == 0 {
-		delete(h.clients, message.ProjectID)
+// sendToUser sends a message to all connections of a user. Messages to a user are not
+// numbered: a client whose send buffer is full misses them and reloads them over the API,
+// e.g. from the notification inbox. The caller must hold the write lock.
+func (h *Hub) sendToUser(message *Message) {
+	data, err := json.Marshal(message)
+	if err != nil {
+		log.Printf("Error marshaling message: %v", err)
+		return
+	}
+
+	for client := range h.userClients[message.UserID] {
+		select {
+		case client.send <- data:
+		default:
+			log.Printf("WARNING: Dropped %s message for slow WebSocket client of user %s", message.Type, message.UserID)
+		}
 	}
 }
 
@@ -142,11 +372,71 @@ func (h *Hub) Broadcast(eventType EventType, projectID string, taskID string, da
 		Data:      data,
 	}
 
-	select {
-	case h.broadcast <- message:
-	default:
-		log.Printf("Broadcast channel full, dropping message")
+	if h.OnBroadcast != nil {
+		h.OnBroadcast(eventType, projectID, taskID, data)
+	}
+
+	h.publish(message)
+}
+
+// SendToUser sends a message to all connections of a user
+func (h *Hub) SendToUser(eventType EventType, userID string, data interface{}) {
+	message := &Message{
+		Type:   eventType,
+		UserID: userID,
+		Data:   data,
 	}
+
+	h.publish(message)
+}
+
+// Clients returns a snapshot of all registered clients
+func (h *Hub) Clients() []*Client {
+	h.mu.RLock()
+	defer h.mu.RUnlock()
+
+	return h.matching(func(*Client) bool { return true })
+}
+
+// Revoke closes the connections of all clients that match, telling them why with a
+// policy violation close frame, and returns how many were closed
+func (h *Hub) Revoke(match func(*Client) bool, reason string) int {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	closeMessage := websocket.FormatCloseMessage(websocket.ClosePolicyViolation, reason)
+
+	revoked := 0
+	for _, client := range h.matching(match) {
+		client.closeMessage = closeMessage
+		if h.removeClient(client) {
+			revoked++
+		}
+	}
+
+	if revoked > 0 {
+		log.Printf("Revoked %d WebSocket connection(s): %s", revoked, reason)
+	}
+
+	return revoked
+}
+
+// matching returns the registered clients that match. The caller must hold the lock.
+func (h *Hub) matching(match func(*Client) bool) []*Client {
+	seen := make(map[*Client]bool)
+	var clients []*Client
+	for _, index := range []map[string]map[*Client]bool{h.clients, h.userClients} {
+		for _, set := range index {
+			for client := range set {
+				if !seen[client] && match(client) {
+					seen[client] = true
+					clients = append(clients, client)
+				}
+			}
+		}
+	}
+
+	return clients
 }
 
 // GetClientCount returns the number of connected clients for a project
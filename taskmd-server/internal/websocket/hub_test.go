@@ -1,11 +1,32 @@
 package websocket
 
 import (
+	"context"
 	"encoding/json"
 	"testing"
 	"time"
 )
 
+// expectSyncReady reads the first message of project clients, which tells them where the stream is
+func expectSyncReady(t *testing.T, clients ...*Client) {
+	t.Helper()
+
+	for _, client := range clients {
+		select {
+		case msg := <-client.send:
+			var received Message
+			if err := json.Unmarshal(msg, &received); err != nil {
+				t.Fatalf("Failed to unmarshal message: %v", err)
+			}
+			if received.Type != EventSyncReady {
+				t.Fatalf("first message type = %v, want %v", received.Type, EventSyncReady)
+			}
+		default:
+			t.Fatal("Client did not receive sync.ready")
+		}
+	}
+}
+
 func TestNewHub(t *testing.T) {
 	hub := NewHub()
 
@@ -25,8 +46,8 @@ func TestNewHub(t *testing.T) {
 		t.Error("Hub Unregister channel not initialized")
 	}
 
-	if hub.broadcast == nil {
-		t.Error("Hub broadcast channel not initialized")
+	if hub.streams == nil {
+		t.Error("Hub streams map not initialized")
 	}
 }
 
@@ -103,6 +124,7 @@ func TestHub_BroadcastMessage(t *testing.T) {
 	// Register both clients
 	hub.registerClient(client1)
 	hub.registerClient(client2)
+	expectSyncReady(t, client1, client2)
 
 	// Create a message
 	message := &Message{
@@ -163,6 +185,7 @@ func TestHub_BroadcastToSpecificProject(t *testing.T) {
 
 	hub.registerClient(client1)
 	hub.registerClient(client2)
+	expectSyncReady(t, client1, client2)
 
 	// Broadcast to project-1 only
 	message := &Message{
@@ -203,6 +226,7 @@ func TestHub_Broadcast(t *testing.T) {
 	}
 
 	hub.registerClient(client)
+	expectSyncReady(t, client)
 
 	// Use the public Broadcast method
 	hub.Broadcast(EventTaskUpdated, "test-project", "TASK-001", map[string]string{
@@ -296,3 +320,334 @@ func TestHub_ConcurrentRegistration(t *testing.T) {
 		t.Errorf("GetClientCount() = %d, want %d", count, clientCount)
 	}
 }
+
+func TestHub_SendToUser(t *testing.T) {
+	hub := NewHub()
+
+	// A project connection and a per-user connection of the same user, and another user
+	projectClient := &Client{
+		ProjectID: "project-1",
+		UserID:    "user1",
+		send:      make(chan []byte, 256),
+	}
+
+	inboxClient := &Client{
+		UserID: "user1",
+		send:   make(chan []byte, 256),
+	}
+
+	otherClient := &Client{
+		ProjectID: "project-1",
+		UserID:    "user2",
+		send:      make(chan []byte, 256),
+	}
+
+	hub.registerClient(projectClient)
+	hub.registerClient(inboxClient)
+	hub.registerClient(otherClient)
+	expectSyncReady(t, projectClient, otherClient)
+
+	// Per-user connections are not subscribed to a project
+	if count := hub.GetClientCount("project-1"); count != 2 {
+		t.Errorf("GetClientCount() = %d, want 2", count)
+	}
+
+	hub.broadcastMessage(&Message{
+		Type:   EventNotificationCreated,
+		UserID: "user1",
+		Data:   map[string]interface{}{"title": "You were mentioned"},
+	})
+
+	for name, client := range map[string]*Client{"project": projectClient, "inbox": inboxClient} {
+		select {
+		case msg := <-client.send:
+			var received Message
+			if err := json.Unmarshal(msg, &received); err != nil {
+				t.Errorf("Failed to unmarshal message: %v", err)
+			}
+			if received.Type != EventNotificationCreated {
+				t.Errorf("%s client received wrong event type: %v", name, received.Type)
+			}
+		case <-time.After(100 * time.Millisecond):
+			t.Errorf("%s client did not receive message for their user", name)
+		}
+	}
+
+	select {
+	case <-otherClient.send:
+		t.Error("Other user received message for a different user")
+	case <-time.After(50 * time.Millisecond):
+		// Success - no message received
+	}
+
+	// Unregistering removes the client from the user index
+	hub.unregisterClient(inboxClient)
+	if hub.userClients["user1"][inboxClient] {
+		t.Error("Client still registered for user after unregister")
+	}
+}
+
+func TestHub_Revoke(t *testing.T) {
+	hub := NewHub()
+
+	revoked := &Client{ProjectID: "project-1", UserID: "user-1", SessionID: "sess-1", send: make(chan []byte, 1)}
+	otherSession := &Client{ProjectID: "project-1", UserID: "user-1", SessionID: "sess-2", send: make(chan []byte, 1)}
+	userChannel := &Client{UserID: "user-1", SessionID: "sess-1", send: make(chan []byte, 1)}
+	for _, client := range []*Client{revoked, otherSession, userChannel} {
+		hub.registerClient(client)
+	}
+	expectSyncReady(t, revoked, otherSession)
+
+	count := hub.Revoke(func(c *Client) bool { return c.SessionID == "sess-1" }, "session revoked")
+	if count != 2 {
+		t.Fatalf("Revoke() = %d, want 2", count)
+	}
+
+	for _, client := range []*Client{revoked, userChannel} {
+		if _, ok := <-client.send; ok {
+			t.Error("send channel of a revoked client is still open")
+		}
+		if len(client.closeMessage) == 0 {
+			t.Error("revoked client has no close frame")
+		}
+	}
+
+	if hub.GetClientCount("project-1") != 1 {
+		t.Errorf("GetClientCount() = %d, want 1", hub.GetClientCount("project-1"))
+	}
+	if clients := hub.Clients(); len(clients) != 1 || clients[0] != otherSession {
+		t.Errorf("Clients() = %v, want only the client of the other session", clients)
+	}
+
+	// Revoking again finds nothing
+	if count := hub.Revoke(func(c *Client) bool { return c.SessionID == "sess-1" }, "session revoked"); count != 0 {
+		t.Errorf("second Revoke() = %d, want 0", count)
+	}
+}
+
+// receive reads the queued messages of a client
+func receive(t *testing.T, client *Client) []Message {
+	t.Helper()
+
+	var messages []Message
+	for {
+		select {
+		case msg := <-client.send:
+			var received Message
+			if err := json.Unmarshal(msg, &received); err != nil {
+				t.Fatalf("Failed to unmarshal message: %v", err)
+			}
+			messages = append(messages, received)
+		default:
+			return messages
+		}
+	}
+}
+
+func TestHub_SequenceAndResume(t *testing.T) {
+	hub := NewHub()
+
+	for i := 0; i < 3; i++ {
+		hub.Broadcast(EventTaskUpdated, "project-1", "TASK-001", nil)
+	}
+	hub.Broadcast(EventTaskUpdated, "project-2", "TASK-002", nil)
+
+	// A new client learns the current sequence number
+	fresh := &Client{ProjectID: "project-1", send: make(chan []byte, 16)}
+	hub.registerClient(fresh)
+	messages := receive(t, fresh)
+	if len(messages) != 1 || messages[0].Type != EventSyncReady || messages[0].Seq != hub.epoch+3 {
+		t.Fatalf("fresh client received %+v, want sync.ready at %d", messages, hub.epoch+3)
+	}
+
+	// A resuming client is sent the messages it missed
+	resumed := &Client{ProjectID: "project-1", send: make(chan []byte, 16)}
+	resumed.Resume(hub.epoch + 1)
+	hub.registerClient(resumed)
+	messages = receive(t, resumed)
+	if len(messages) != 3 || messages[0].Type != EventSyncReady {
+		t.Fatalf("resumed client received %+v, want sync.ready and 2 messages", messages)
+	}
+	for i, message := range messages[1:] {
+		if want := hub.epoch + uint64(i) + 2; message.Seq != want || message.Type != EventTaskUpdated {
+			t.Errorf("replayed message %d = %+v, want task.updated at %d", i, message, want)
+		}
+	}
+
+	// Live messages continue the sequence
+	hub.Broadcast(EventTaskDeleted, "project-1", "TASK-001", nil)
+	for _, client := range []*Client{fresh, resumed} {
+		messages = receive(t, client)
+		if len(messages) != 1 || messages[0].Seq != hub.epoch+4 {
+			t.Errorf("live messages = %+v, want one at %d", messages, hub.epoch+4)
+		}
+	}
+}
+
+func TestHub_ResumeBeyondReplayBuffer(t *testing.T) {
+	hub := NewHub()
+	hub.ReplayLimit = 2
+
+	for i := 0; i < 5; i++ {
+		hub.Broadcast(EventTaskUpdated, "project-1", "TASK-001", nil)
+	}
+
+	tests := []struct {
+		name  string
+		since uint64
+	}{
+		{"evicted", hub.epoch + 1},
+		{"before restart", 42},
+		{"unknown", hub.epoch + 100},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			client := &Client{ProjectID: "project-1", send: make(chan []byte, 16)}
+			client.Resume(tt.since)
+			hub.registerClient(client)
+
+			messages := receive(t, client)
+			if len(messages) != 1 || messages[0].Type != EventResyncRequired || messages[0].Seq != hub.epoch+5 {
+				t.Fatalf("received %+v, want sync.resync_required at %d", messages, hub.epoch+5)
+			}
+		})
+	}
+}
+
+func TestHub_SlowClientCatchesUp(t *testing.T) {
+	hub := NewHub()
+
+	client := &Client{ProjectID: "project-1", send: make(chan []byte, 2)}
+	hub.registerClient(client)
+	expectSyncReady(t, client)
+
+	// The send buffer overflows, but the client stays connected
+	for i := 0; i < 5; i++ {
+		hub.Broadcast(EventTaskUpdated, "project-1", "TASK-001", nil)
+	}
+	if hub.GetClientCount("project-1") != 1 {
+		t.Fatal("slow client was disconnected")
+	}
+	if !client.lagging.Load() {
+		t.Fatal("slow client is not marked as lagging")
+	}
+
+	// Draining the buffer lets it catch up without gaps
+	var seqs []uint64
+	for len(seqs) < 5 {
+		messages := receive(t, client)
+		if len(messages) == 0 {
+			t.Fatalf("client stopped receiving after %v", seqs)
+		}
+		for _, message := range messages {
+			seqs = append(seqs, message.Seq)
+		}
+		hub.catchUp(client)
+	}
+	for i, seq := range seqs {
+		if want := hub.epoch + uint64(i) + 1; seq != want {
+			t.Errorf("message %d has seq %d, want %d", i, seq, want)
+		}
+	}
+	if client.lagging.Load() {
+		t.Error("client is still lagging after catching up")
+	}
+}
+
+func TestHub_SlowClientResyncs(t *testing.T) {
+	hub := NewHub()
+	hub.ReplayLimit = 3
+
+	client := &Client{ProjectID: "project-1", send: make(chan []byte, 2)}
+	hub.registerClient(client)
+	expectSyncReady(t, client)
+
+	for i := 0; i < 10; i++ {
+		hub.Broadcast(EventTaskUpdated, "project-1", "TASK-001", nil)
+	}
+
+	receive(t, client)
+	hub.catchUp(client)
+
+	messages := receive(t, client)
+	if len(messages) != 1 || messages[0].Type != EventResyncRequired || messages[0].Seq != hub.epoch+10 {
+		t.Fatalf("received %+v, want sync.resync_required at %d", messages, hub.epoch+10)
+	}
+
+	hub.Broadcast(EventTaskUpdated, "project-1", "TASK-001", nil)
+	messages = receive(t, client)
+	if len(messages) != 1 || messages[0].Seq != hub.epoch+11 {
+		t.Errorf("received %+v after resync, want the message at %d", messages, hub.epoch+11)
+	}
+}
+
+// memoryBackend numbers messages like a shared backend and keeps them for the test to deliver
+type memoryBackend struct {
+	seqs      map[string]uint64
+	published []*Message
+}
+
+func (b *memoryBackend) Publish(ctx context.Context, message *Message) error {
+	if message.UserID == "" {
+		b.seqs[message.ProjectID]++
+		message.Seq = b.seqs[message.ProjectID]
+	}
+	b.published = append(b.published, message)
+	return nil
+}
+
+func (b *memoryBackend) LastSeq(ctx context.Context, projectID string) (uint64, error) {
+	return b.seqs[projectID], nil
+}
+
+func (b *memoryBackend) Listen(ctx context.Context, deliver func(*Message)) error {
+	<-ctx.Done()
+	return ctx.Err()
+}
+
+func TestHub_Backend(t *testing.T) {
+	backend := &memoryBackend{seqs: map[string]uint64{"project-1": 41}}
+	hub := NewHub()
+	hub.Backend = backend
+
+	// Streams start from the sequence shared by all replicas
+	client := &Client{ProjectID: "project-1", send: make(chan []byte, 16)}
+	hub.registerClient(client)
+	messages := receive(t, client)
+	if len(messages) != 1 || messages[0].Type != EventSyncReady || messages[0].Seq != 41 {
+		t.Fatalf("received %+v, want sync.ready at 41", messages)
+	}
+
+	// Published messages reach clients when the backend delivers them, once
+	hub.Broadcast(EventTaskUpdated, "project-1", "TASK-001", nil)
+	if messages := receive(t, client); len(messages) != 0 {
+		t.Fatalf("received %+v before the backend delivered the message", messages)
+	}
+	hub.broadcastMessage(backend.published[0])
+	hub.broadcastMessage(backend.published[0])
+	messages = receive(t, client)
+	if len(messages) != 1 || messages[0].Seq != 42 {
+		t.Fatalf("received %+v, want the message at 42 once", messages)
+	}
+
+	// A message after a gap makes the client resync before receiving it
+	hub.broadcastMessage(&Message{Type: EventTaskUpdated, ProjectID: "project-1", Seq: 45})
+	messages = receive(t, client)
+	if len(messages) != 2 || messages[0].Type != EventResyncRequired || messages[0].Seq != 44 || messages[1].Seq != 45 {
+		t.Fatalf("received %+v, want sync.resync_required at 44 and the message at 45", messages)
+	}
+
+	// After an interruption, streams that moved on are resynced
+	backend.seqs["project-1"] = 47
+	hub.resync(context.Background())
+	messages = receive(t, client)
+	if len(messages) != 1 || messages[0].Type != EventResyncRequired || messages[0].Seq != 47 {
+		t.Fatalf("received %+v, want sync.resync_required at 47", messages)
+	}
+
+	hub.resync(context.Background())
+	if messages := receive(t, client); len(messages) != 0 {
+		t.Errorf("received %+v after a resync without lost messages", messages)
+	}
+}
@@ -0,0 +1,244 @@
+package websocket
+
+import (
+	"context"
+	"crypto/rand"
+	"database/sql"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"log"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/jmoiron/sqlx"
+	"github.com/lib/pq"
+	"github.com/tktomaru/taskai/taskai-server/internal/database"
+)
+
+const (
+	// PostgresChannel is the channel replicas exchange messages on
+	PostgresChannel = "taskmd_ws"
+
+	// maxNotifyPayload keeps notifications below the 8000 byte limit of Postgres;
+	// larger messages are stored in ws_payloads and sent by reference
+	maxNotifyPayload = 7800
+
+	// payloadRetention is how long stored payloads are kept for replicas to fetch
+	payloadRetention = time.Hour
+
+	// listenerPingInterval is how often an idle listener checks its connection
+	listenerPingInterval = 90 * time.Second
+
+	// receivedLimit is the number of recent notifications remembered to drop duplicates
+	receivedLimit = 4096
+)
+
+// envelope is the payload of a notification
+type envelope struct {
+	// Origin identifies the publishing replica and N numbers its notifications, so that
+	// a notification received twice is delivered once. Transactions of a replica may
+	// commit out of order, so N is not a sequence.
+	Origin string `json:"o"`
+	N      uint64 `json:"n"`
+
+	// The message, or the id of its stored payload when it is too large for a notification
+	Message *Message `json:"m,omitempty"`
+	Ref     int64    `json:"ref,omitempty"`
+
+	// UserID of messages addressed to a user, which the message itself does not encode
+	UserID string `json:"u,omitempty"`
+}
+
+// PostgresBackend carries messages between replicas with Postgres LISTEN/NOTIFY.
+// Messages are numbered in ws_streams within the transaction that sends the notification;
+// the lock on the stream row makes notifications of a project arrive in sequence order.
+type PostgresBackend struct {
+	db     *database.DB
+	dsn    string
+	origin string
+	sent   atomic.Uint64
+
+	// Recently received notifications, oldest first
+	mu       sync.Mutex
+	received map[string]bool
+	order    []string
+}
+
+// NewPostgresBackend creates a backend that publishes with db and listens on a
+// separate connection opened with dsn
+func NewPostgresBackend(db *database.DB, dsn string) (*PostgresBackend, error) {
+	origin := make([]byte, 8)
+	if _, err := rand.Read(origin); err != nil {
+		return nil, fmt.Errorf("failed to generate replica id: %w", err)
+	}
+
+	return &PostgresBackend{
+		db:       db,
+		dsn:      dsn,
+		origin:   hex.EncodeToString(origin),
+		received: make(map[string]bool),
+	}, nil
+}
+
+// Publish numbers a project message and notifies all replicas
+func (b *PostgresBackend) Publish(ctx context.Context, message *Message) error {
+	return b.db.Transaction(ctx, func(tx *sqlx.Tx) error {
+		if message.UserID == "" {
+			var seq uint64
+			err := tx.GetContext(ctx, &seq, `
+				INSERT INTO ws_streams (project_id, seq) VALUES ($1, 1)
+				ON CONFLICT (project_id) DO UPDATE SET seq = ws_streams.seq + 1
+				RETURNING seq
+			`, message.ProjectID)
+			if err != nil {
+				return fmt.Errorf("failed to number WebSocket message: %w", err)
+			}
+			message.Seq = seq
+		}
+
+		env := envelope{Origin: b.origin, N: b.sent.Add(1), Message: message, UserID: message.UserID}
+		payload, err := json.Marshal(env)
+		if err != nil {
+			return fmt.Errorf("failed to encode WebSocket message: %w", err)
+		}
+
+		if len(payload) > maxNotifyPayload {
+			data, err := json.Marshal(message)
+			if err != nil {
+				return fmt.Errorf("failed to encode WebSocket message: %w", err)
+			}
+
+			if _, err := tx.ExecContext(ctx, `DELETE FROM ws_payloads WHERE created_at < $1`, time.Now().Add(-payloadRetention)); err != nil {
+				return fmt.Errorf("failed to delete old WebSocket payloads: %w", err)
+			}
+			if err := tx.GetContext(ctx, &env.Ref, `INSERT INTO ws_payloads (payload) VALUES ($1) RETURNING id`, string(data)); err != nil {
+				return fmt.Errorf("failed to store WebSocket payload: %w", err)
+			}
+
+			env.Message = nil
+			if payload, err = json.Marshal(env); err != nil {
+				return fmt.Errorf("failed to encode WebSocket message: %w", err)
+			}
+		}
+
+		if _, err := tx.ExecContext(ctx, `SELECT pg_notify($1, $2)`, PostgresChannel, string(payload)); err != nil {
+			return fmt.Errorf("failed to notify WebSocket message: %w", err)
+		}
+
+		return nil
+	})
+}
+
+// LastSeq returns the sequence number of the last message of a project
+func (b *PostgresBackend) LastSeq(ctx context.Context, projectID string) (uint64, error) {
+	var seq uint64
+	err := b.db.GetContext(ctx, &seq, `SELECT seq FROM ws_streams WHERE project_id = $1`, projectID)
+	if err == sql.ErrNoRows {
+		return 0, nil
+	}
+	if err != nil {
+		return 0, fmt.Errorf("failed to get WebSocket stream: %w", err)
+	}
+
+	return seq, nil
+}
+
+// Listen receives the notifications of all replicas until the context is cancelled
+func (b *PostgresBackend) Listen(ctx context.Context, deliver func(*Message)) error {
+	listener := pq.NewListener(b.dsn, time.Second, time.Minute, func(event pq.ListenerEventType, err error) {
+		if err != nil {
+			log.Printf("WARNING: WebSocket listener: %v", err)
+		}
+	})
+	defer listener.Close()
+
+	if err := listener.Listen(PostgresChannel); err != nil {
+		return fmt.Errorf("failed to listen on %s: %w", PostgresChannel, err)
+	}
+	deliver(nil)
+
+	ticker := time.NewTicker(listenerPingInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+
+		case notification := <-listener.Notify:
+			// A nil notification follows a reconnect, during which notifications are lost
+			if notification == nil {
+				deliver(nil)
+				continue
+			}
+
+			message, err := b.receive(ctx, notification.Extra)
+			if err != nil {
+				log.Printf("ERROR: Failed to receive WebSocket message: %v", err)
+				continue
+			}
+			if message != nil {
+				deliver(message)
+			}
+
+		case <-ticker.C:
+			if err := listener.Ping(); err != nil {
+				log.Printf("WARNING: WebSocket listener ping failed: %v", err)
+			}
+		}
+	}
+}
+
+// receive decodes a notification, fetching its payload if it was sent by reference.
+// It returns nil for a notification that was already received.
+func (b *PostgresBackend) receive(ctx context.Context, payload string) (*Message, error) {
+	var env envelope
+	if err := json.Unmarshal([]byte(payload), &env); err != nil {
+		return nil, fmt.Errorf("failed to decode notification: %w", err)
+	}
+
+	if !b.accept(&env) {
+		return nil, nil
+	}
+
+	message := env.Message
+	if env.Ref != 0 {
+		var data []byte
+		if err := b.db.GetContext(ctx, &data, `SELECT payload FROM ws_payloads WHERE id = $1`, env.Ref); err != nil {
+			return nil, fmt.Errorf("failed to get WebSocket payload %d: %w", env.Ref, err)
+		}
+
+		message = &Message{}
+		if err := json.Unmarshal(data, message); err != nil {
+			return nil, fmt.Errorf("failed to decode WebSocket payload %d: %w", env.Ref, err)
+		}
+	}
+	if message == nil {
+		return nil, fmt.Errorf("notification %s/%d has no message", env.Origin, env.N)
+	}
+	message.UserID = env.UserID
+
+	return message, nil
+}
+
+// accept reports whether a notification has not been received recently
+func (b *PostgresBackend) accept(env *envelope) bool {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	key := fmt.Sprintf("%s/%d", env.Origin, env.N)
+	if b.received[key] {
+		return false
+	}
+
+	b.received[key] = true
+	b.order = append(b.order, key)
+	if len(b.order) > receivedLimit {
+		delete(b.received, b.order[0])
+		b.order = b.order[1:]
+	}
+
+	return true
+}
@@ -0,0 +1,66 @@
+package websocket
+
+import (
+	"context"
+	"encoding/json"
+	"testing"
+)
+
+func TestPostgresBackend_Receive(t *testing.T) {
+	backend, err := NewPostgresBackend(nil, "")
+	if err != nil {
+		t.Fatalf("NewPostgresBackend() error: %v", err)
+	}
+
+	payload, err := json.Marshal(envelope{
+		Origin:  "replica-a",
+		N:       1,
+		Message: &Message{Type: EventNotificationCreated, Data: map[string]interface{}{"title": "You were mentioned"}},
+		UserID:  "user-1",
+	})
+	if err != nil {
+		t.Fatalf("Failed to encode envelope: %v", err)
+	}
+
+	message, err := backend.receive(context.Background(), string(payload))
+	if err != nil {
+		t.Fatalf("receive() error: %v", err)
+	}
+	if message == nil || message.Type != EventNotificationCreated || message.UserID != "user-1" {
+		t.Fatalf("receive() = %+v, want the notification for user-1", message)
+	}
+
+	// The same notification again is dropped
+	if message, err := backend.receive(context.Background(), string(payload)); err != nil || message != nil {
+		t.Errorf("receive() of a duplicate = %+v, %v, want nil", message, err)
+	}
+
+	if _, err := backend.receive(context.Background(), "not json"); err == nil {
+		t.Error("receive() accepted an invalid payload")
+	}
+}
+
+func TestPostgresBackend_Accept(t *testing.T) {
+	backend, err := NewPostgresBackend(nil, "")
+	if err != nil {
+		t.Fatalf("NewPostgresBackend() error: %v", err)
+	}
+
+	// Notifications of a replica may arrive out of order
+	for _, env := range []envelope{{Origin: "a", N: 2}, {Origin: "a", N: 1}, {Origin: "b", N: 1}} {
+		if !backend.accept(&env) {
+			t.Errorf("accept(%s/%d) = false for a new notification", env.Origin, env.N)
+		}
+	}
+	if backend.accept(&envelope{Origin: "a", N: 2}) {
+		t.Error("accept() = true for a duplicate")
+	}
+
+	// Only recent notifications are remembered
+	for n := uint64(3); n < receivedLimit+3; n++ {
+		backend.accept(&envelope{Origin: "a", N: n})
+	}
+	if len(backend.received) != receivedLimit || len(backend.order) != receivedLimit {
+		t.Errorf("remembered %d notifications, want %d", len(backend.received), receivedLimit)
+	}
+}
@@ -0,0 +1,50 @@
+package websocket
+
+// DefaultReplayLimit is the number of recent messages kept per project by default
+const DefaultReplayLimit = 1000
+
+// sequencedMessage is an encoded project message with its sequence number, and the task
+// and subscribed views it is about so that it can be replayed to subscribed clients
+type sequencedMessage struct {
+	seq    uint64
+	data   []byte
+	taskID string
+	views  map[string]bool
+}
+
+// stream holds the sequence of a project and its most recent messages, so that clients
+// that reconnect or fall behind can be sent what they missed
+type stream struct {
+	// Sequence number of the last message
+	seq uint64
+
+	// Recent messages in sequence order, without gaps
+	recent []sequencedMessage
+}
+
+// append records the next message of the stream, keeping at most limit messages
+func (s *stream) append(message sequencedMessage, limit int) {
+	s.seq = message.seq
+	if limit <= 0 {
+		s.recent = nil
+		return
+	}
+
+	s.recent = append(s.recent, message)
+	if len(s.recent) > limit {
+		s.recent = s.recent[len(s.recent)-limit:]
+	}
+}
+
+// since returns the messages after seq. It reports false when some of them are no
+// longer kept, or when seq is not a sequence number of this stream.
+func (s *stream) since(seq uint64) ([]sequencedMessage, bool) {
+	if seq == s.seq {
+		return nil, true
+	}
+	if seq > s.seq || len(s.recent) == 0 || s.recent[0].seq > seq+1 {
+		return nil, false
+	}
+
+	return s.recent[seq+1-s.recent[0].seq:], true
+}